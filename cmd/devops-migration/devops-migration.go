@@ -0,0 +1,249 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+
+	"kubesphere.io/kubesphere/pkg/apis"
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/db"
+	devopsmodel "kubesphere.io/kubesphere/pkg/models/devops"
+	"kubesphere.io/kubesphere/pkg/simple/client/admin_jenkins"
+	"kubesphere.io/kubesphere/pkg/simple/client/devops_mysql"
+)
+
+var (
+	masterURL  string
+	kubeconfig string
+	namespace  string
+	dryRun     bool
+
+	log = logf.Log.WithName("devops-migration")
+
+	invalidNameChars = regexp.MustCompile("[^a-z0-9.-]+")
+)
+
+func init() {
+	flag.StringVar(&masterURL, "master-url", "", "only need if out of cluster")
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "only need if out of cluster")
+	flag.StringVar(&namespace, "namespace", "kubesphere-devops-system", "namespace of the migrated pipelines and credentials")
+	flag.BoolVar(&dryRun, "dry-run", false, "only log the resources which would be created")
+}
+
+// devops-migration imports the devops projects, members and credentials in the database and the pipelines in Jenkins
+// as DevOpsProject, Credential and Pipeline resources. Resources which already exist are left as is, so it can be
+// run again until everything is imported.
+func main() {
+	flag.Parse()
+
+	logf.SetLogger(logf.ZapLogger(false))
+
+	cfg, err := clientcmd.BuildConfigFromFlags(masterURL, kubeconfig)
+	if err != nil {
+		log.Error(err, "failed to build kubeconfig")
+		os.Exit(1)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := apis.AddToScheme(scheme); err != nil {
+		log.Error(err, "failed to add apis to scheme")
+		os.Exit(1)
+	}
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		log.Error(err, "failed to create client")
+		os.Exit(1)
+	}
+
+	dbconn := devops_mysql.OpenDatabase()
+
+	projects := make([]*devopsmodel.DevOpsProject, 0)
+	_, err = dbconn.Select(devopsmodel.DevOpsProjectColumns...).
+		From(devopsmodel.DevOpsProjectTableName).
+		Where(db.Eq(devopsmodel.StatusColumn, devopsmodel.StatusActive)).
+		Load(&projects)
+	if err != nil {
+		log.Error(err, "failed to list devops projects")
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, project := range projects {
+		if err := migrateProject(c, project); err != nil {
+			log.Error(err, "failed to migrate devops project", "projectId", project.ProjectId)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func migrateProject(c client.Client, project *devopsmodel.DevOpsProject) error {
+	dbconn := devops_mysql.OpenDatabase()
+
+	memberships := make([]*devopsmodel.DevOpsProjectMembership, 0)
+	_, err := dbconn.Select(devopsmodel.DevOpsProjectMembershipColumns...).
+		From(devopsmodel.DevOpsProjectMembershipTableName).
+		Where(db.Eq(devopsmodel.DevOpsProjectMembershipProjectIdColumn, project.ProjectId)).
+		Load(&memberships)
+	if err != nil {
+		return err
+	}
+
+	name := strings.ToLower(project.ProjectId)
+	devopsProject := &devopsv1alpha1.DevOpsProject{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: devopsv1alpha1.DevOpsProjectSpec{
+			ProjectId:   project.ProjectId,
+			DisplayName: project.Name,
+			Description: project.Description,
+			Workspace:   project.Workspace,
+			Namespace:   namespace,
+			Creator:     project.Creator,
+		},
+	}
+	for _, membership := range memberships {
+		devopsProject.Spec.Members = append(devopsProject.Spec.Members,
+			devopsv1alpha1.ProjectMember{Username: membership.Username, Role: membership.Role})
+	}
+	if err := create(c, devopsProject); err != nil {
+		return err
+	}
+
+	jenkins := admin_jenkins.Client()
+	if jenkins == nil {
+		return fmt.Errorf("could not connect to jenkins")
+	}
+
+	credentials := make([]*devopsmodel.ProjectCredential, 0)
+	_, err = dbconn.Select(devopsmodel.ProjectCredentialColumns...).
+		From(devopsmodel.ProjectCredentialTableName).
+		Where(db.Eq(devopsmodel.ProjectCredentialProjectIdColumn, project.ProjectId)).
+		Load(&credentials)
+	if err != nil {
+		return err
+	}
+
+	for _, credential := range credentials {
+		response, err := jenkins.GetCredentialInFolder(credential.Domain, credential.CredentialId, project.ProjectId)
+		if err != nil {
+			log.Error(err, "failed to get credential", "projectId", project.ProjectId, "credentialId", credential.CredentialId)
+			continue
+		}
+		credentialType, ok := devopsmodel.CredentialTypeMap[response.TypeName]
+		if !ok {
+			log.Info("skip credential of unsupported type", "projectId", project.ProjectId, "credentialId", credential.CredentialId, "type", response.TypeName)
+			continue
+		}
+		// the content of the credential can't be read back from Jenkins, it's left as is without a secret
+		if err := create(c, &devopsv1alpha1.Credential{
+			ObjectMeta: adoptingObjectMeta(resourceName(name, credential.CredentialId)),
+			Spec: devopsv1alpha1.CredentialSpec{
+				Project:     name,
+				Id:          credential.CredentialId,
+				Type:        credentialType,
+				Description: response.Description,
+				Domain:      credential.Domain,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	folder, err := jenkins.GetJob(project.ProjectId)
+	if err != nil {
+		return err
+	}
+	for _, job := range folder.Raw.Jobs {
+		pipeline, err := devopsmodel.GetProjectPipeline(project.ProjectId, job.Name)
+		if err != nil {
+			log.Error(err, "failed to get pipeline", "projectId", project.ProjectId, "pipeline", job.Name)
+			continue
+		}
+		spec := devopsmodel.PipelineSpecFromProjectPipeline(name, pipeline)
+		spec.Name = job.Name
+		if err := create(c, &devopsv1alpha1.Pipeline{
+			ObjectMeta: adoptingObjectMeta(resourceName(name, job.Name)),
+			Spec:       spec,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resourceName returns the name of the resource of a credential or a pipeline in the project,
+// ids in Jenkins may contain characters which aren't allowed in names of resources
+func resourceName(project, id string) string {
+	return project + "-" + strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(id), "-"), "-.")
+}
+
+// adoptingObjectMeta returns the metadata of an imported resource, which takes over the credential or job
+// already existing in Jenkins
+func adoptingObjectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:   namespace,
+		Name:        name,
+		Annotations: map[string]string{devopsv1alpha1.AdoptAnnotation: "true"},
+	}
+}
+
+func create(c client.Client, obj interface {
+	runtime.Object
+	metav1.Object
+}) error {
+	if errs := validation.IsDNS1123Subdomain(obj.GetName()); len(errs) > 0 {
+		log.Info("skip resource with invalid name", "name", obj.GetName(), "errors", errs)
+		return nil
+	}
+
+	kind := reflect.TypeOf(obj).Elem().Name()
+	if dryRun {
+		log.Info("would create", "kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+		return nil
+	}
+
+	err := c.Create(context.TODO(), obj)
+	if errors.IsAlreadyExists(err) {
+		log.Info("already exists", "kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+		return nil
+	}
+	if err == nil {
+		log.Info("created", "kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+	}
+	return err
+}
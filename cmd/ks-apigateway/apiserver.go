@@ -18,9 +18,13 @@
 package main
 
 import (
+	"flag"
+
 	"github.com/mholt/caddy/caddy/caddymain"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+
 	// Install apis
 	_ "kubesphere.io/kubesphere/pkg/apigateway/caddy-plugin/authenticate"
 	_ "kubesphere.io/kubesphere/pkg/apigateway/caddy-plugin/authentication"
@@ -31,5 +35,6 @@ func main() {
 	httpserver.RegisterDevDirective("authenticate", "jwt")
 	httpserver.RegisterDevDirective("authentication", "jwt")
 	httpserver.RegisterDevDirective("swagger", "jwt")
+	k8s.AddFlags(flag.CommandLine)
 	caddymain.Run()
 }
@@ -1,6 +1,8 @@
 package options
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
 	genericoptions "kubesphere.io/kubesphere/pkg/options"
 )
@@ -22,6 +24,30 @@ type ServerRunOptions struct {
 
 	// openpitrix service token
 	OpenPitrixProxyToken string
+
+	// resources can be searched with dynamic informers, e.g. virtualservices.networking.istio.io, *.servicemesh.kubesphere.io
+	DynamicResources []string
+
+	// name of the host cluster in multi-cluster mode
+	HostClusterName string
+
+	// interval of deleting expired log indices of workspaces
+	LogRetentionCurateInterval time.Duration
+
+	// interval of checking devops projects left only in jenkins or only in the database
+	DevOpsOrphanCheckInterval time.Duration
+
+	// interval of polling pipeline runs of devops projects for events
+	PipelineRunWatchInterval time.Duration
+
+	// secrets of webhooks of scms, such as gitlab
+	SCMWebhookSecrets map[string]string
+
+	// servers of scms which credentials of devops projects are sent to, such as gitlab=https://gitlab.com
+	SCMServers []string
+
+	// origins of the console which websockets could be opened from
+	ConsoleOrigins []string
 }
 
 func NewServerRunOptions() *ServerRunOptions {
@@ -42,4 +68,16 @@ func (s *ServerRunOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.IstioPilotServiceURL, "istio-pilot-service-url", "http://istio-pilot.istio-system.svc:8080/version", "istio pilot discovery service url")
 	fs.StringVar(&s.JaegerQueryServiceUrl, "jaeger-query-service-url", "http://jaeger-query.istio-system.svc:16686/jaeger", "jaeger query service url")
 	fs.StringVar(&s.ServicemeshPrometheusServiceUrl, "servicemesh-prometheus-service-url", "http://prometheus-k8s-system.kubesphere-monitoring-system.svc:9090", "prometheus service for servicemesh")
+	fs.StringSliceVar(&s.DynamicResources, "dynamic-resources", []string{}, "allow list of the resources that can be listed by the resources api besides the built-in resources, including custom resources, "+
+		"in the form of <resource>.<group>, *.<group> for all resources in the group or * for all resources, e.g. virtualservices.networking.istio.io,*.servicemesh.kubesphere.io")
+	fs.StringVar(&s.HostClusterName, "host-cluster-name", "", "name of the host cluster, member clusters are registered by Cluster resources and requests are dispatched to them with the prefix /kapis/clusters/{cluster}, multi-cluster is disabled if empty")
+	fs.DurationVar(&s.LogRetentionCurateInterval, "log-retention-curate-interval", time.Hour, "interval of deleting log indices of workspaces exceeding their retention days or max size, the curator is disabled if 0")
+	fs.DurationVar(&s.DevOpsOrphanCheckInterval, "devops-orphan-check-interval", 10*time.Minute, "interval of checking folders and roles of devops projects left only in jenkins and projects whose folders are missing, the check is disabled if 0")
+	fs.DurationVar(&s.PipelineRunWatchInterval, "pipeline-run-watch-interval", 10*time.Second, "interval of polling runs of pipelines in devops projects with event watchers or notification receivers, the events are disabled if 0")
+	fs.StringToStringVar(&s.SCMWebhookSecrets, "scm-webhook-secrets", map[string]string{}, "secrets of webhooks of scms in the form of <scm>=<secret>, "+
+		"the secret is the token of gitlab and the key of signatures of bitbucket-server and gitea, webhooks of scms without secrets are rejected")
+	fs.StringSliceVar(&s.SCMServers, "scm-servers", []string{"gitlab=https://gitlab.com"}, "api urls of servers of scms in the form of <scm>=<url>, "+
+		"organizations and repositories of gitlab, bitbucket-server and gitea are only listed of these servers with credentials of devops projects")
+	fs.StringSliceVar(&s.ConsoleOrigins, "console-origins", []string{}, "origins of the console, e.g. https://console.example.com, "+
+		"websockets opened by browsers are only accepted from these origins and the host of ks-apiserver")
 }
@@ -26,16 +26,20 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"kubesphere.io/kubesphere/cmd/ks-apiserver/app/options"
+	clusterdispatcher "kubesphere.io/kubesphere/pkg/apiserver/clusters"
 	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
 	"kubesphere.io/kubesphere/pkg/apiserver/servicemesh/tracing"
 	"kubesphere.io/kubesphere/pkg/filter"
 	"kubesphere.io/kubesphere/pkg/informers"
+	"kubesphere.io/kubesphere/pkg/models/clusters"
 	"kubesphere.io/kubesphere/pkg/models/devops"
 	logging "kubesphere.io/kubesphere/pkg/models/log"
+	"kubesphere.io/kubesphere/pkg/models/resources"
 	"kubesphere.io/kubesphere/pkg/server"
 	"kubesphere.io/kubesphere/pkg/signals"
 	"kubesphere.io/kubesphere/pkg/simple/client/admin_jenkins"
 	"kubesphere.io/kubesphere/pkg/simple/client/devops_mysql"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
 	"log"
 	"net/http"
 )
@@ -56,6 +60,7 @@ cluster's shared state through which all other components interact.`,
 	}
 
 	s.AddFlags(cmd.Flags())
+	k8s.AddFlags(goflag.CommandLine)
 	cmd.Flags().AddGoFlagSet(goflag.CommandLine)
 	glog.CopyStandardLogTo("INFO")
 
@@ -70,7 +75,7 @@ func Run(s *options.ServerRunOptions) error {
 
 	var err error
 
-	waitForResourceSync()
+	waitForResourceSync(s)
 
 	container := runtime.Container
 	container.DoNotRecover(false)
@@ -87,14 +92,26 @@ func Run(s *options.ServerRunOptions) error {
 	initializeESClientConfig()
 	initializeServicemeshConfig(s)
 
+	if err := devops.SetSCMServers(s.SCMServers); err != nil {
+		return err
+	}
+
+	runtime.SetAllowedOrigins(s.ConsoleOrigins)
+
+	var handler http.Handler = container
+
+	if s.HostClusterName != "" {
+		handler = clusterdispatcher.NewDispatcher(container, k8s.Client())
+	}
+
 	if s.GenericServerRunOptions.InsecurePort != 0 {
 		log.Printf("Server listening on %d.", s.GenericServerRunOptions.InsecurePort)
-		err = http.ListenAndServe(fmt.Sprintf("%s:%d", s.GenericServerRunOptions.BindAddress, s.GenericServerRunOptions.InsecurePort), container)
+		err = http.ListenAndServe(fmt.Sprintf("%s:%d", s.GenericServerRunOptions.BindAddress, s.GenericServerRunOptions.InsecurePort), handler)
 	}
 
 	if s.GenericServerRunOptions.SecurePort != 0 && len(s.GenericServerRunOptions.TlsCertFile) > 0 && len(s.GenericServerRunOptions.TlsPrivateKey) > 0 {
 		log.Printf("Server listening on %d.", s.GenericServerRunOptions.SecurePort)
-		err = http.ListenAndServeTLS(fmt.Sprintf("%s:%d", s.GenericServerRunOptions.BindAddress, s.GenericServerRunOptions.SecurePort), s.GenericServerRunOptions.TlsCertFile, s.GenericServerRunOptions.TlsPrivateKey, container)
+		err = http.ListenAndServeTLS(fmt.Sprintf("%s:%d", s.GenericServerRunOptions.BindAddress, s.GenericServerRunOptions.SecurePort), s.GenericServerRunOptions.TlsCertFile, s.GenericServerRunOptions.TlsPrivateKey, handler)
 	}
 
 	return err
@@ -132,7 +149,7 @@ func initializeServicemeshConfig(s *options.ServerRunOptions) {
 func initializeESClientConfig() {
 
 	// List all outputs
-	outputs, err := logging.GetFluentbitOutputFromConfigMap()
+	outputs, err := logging.GetFluentbitOutputs()
 	if err != nil {
 		glog.Errorln(err)
 		return
@@ -147,7 +164,7 @@ func initializeESClientConfig() {
 	}
 }
 
-func waitForResourceSync() {
+func waitForResourceSync(s *options.ServerRunOptions) {
 	stopChan := signals.SetupSignalHandler()
 
 	informerFactory := informers.SharedInformerFactory()
@@ -194,5 +211,17 @@ func waitForResourceSync() {
 	ksInformerFactory.Start(stopChan)
 	ksInformerFactory.WaitForCacheSync(stopChan)
 
+	resources.StartDynamicResourceDiscovery(s.DynamicResources, stopChan)
+
+	if s.HostClusterName != "" {
+		clusters.StartClusterRegistry(s.HostClusterName, stopChan)
+	}
+
+	logging.StartLogRetentionCurator(s.LogRetentionCurateInterval, stopChan)
+
+	devops.StartProjectOrphanReconciler(s.DevOpsOrphanCheckInterval, stopChan)
+	devops.StartPipelineRunWatcher(s.PipelineRunWatchInterval, stopChan)
+	devops.SetSCMWebhookSecrets(s.SCMWebhookSecrets)
+
 	log.Println("resources sync success")
 }
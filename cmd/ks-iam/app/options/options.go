@@ -19,6 +19,7 @@ package options
 
 import (
 	"github.com/spf13/pflag"
+	"kubesphere.io/kubesphere/pkg/models/iam/identityprovider"
 	genericoptions "kubesphere.io/kubesphere/pkg/options"
 )
 
@@ -27,8 +28,13 @@ type ServerRunOptions struct {
 	AdminEmail              string
 	AdminPassword           string
 	TokenExpireTime         string
+	RefreshTokenExpireTime  string
 	JWTSecret               string
+	JWTSigningKey           string
+	JWTVerificationKeys     []string
+	JWTSecretMigration      bool
 	AuthRateLimit           string
+	OIDC                    identityprovider.OIDCOptions
 }
 
 func NewServerRunOptions() *ServerRunOptions {
@@ -42,7 +48,20 @@ func (s *ServerRunOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.AdminEmail, "admin-email", "admin@kubesphere.io", "default administrator's email")
 	fs.StringVar(&s.AdminPassword, "admin-password", "passw0rd", "default administrator's password")
 	fs.StringVar(&s.TokenExpireTime, "token-expire-time", "2h", "token expire time,valid time units are \"ns\",\"us\",\"ms\",\"s\",\"m\",\"h\"")
+	fs.StringVar(&s.RefreshTokenExpireTime, "refresh-token-expire-time", "24h", "refresh token expire time,valid time units are \"ns\",\"us\",\"ms\",\"s\",\"m\",\"h\"")
 	fs.StringVar(&s.JWTSecret, "jwt-secret", "", "jwt secret")
+	fs.StringVar(&s.JWTSigningKey, "jwt-signing-key", "", "path to the PEM encoded RSA or ECDSA private key used to sign tokens, tokens are signed with jwt secret if empty")
+	fs.StringSliceVar(&s.JWTVerificationKeys, "jwt-verification-keys", []string{}, "paths to PEM encoded public keys of retired signing keys, tokens signed by them are still accepted during key rotation")
+	fs.BoolVar(&s.JWTSecretMigration, "jwt-secret-migration", false, "keep accepting tokens signed with jwt secret when jwt signing key is set, until tokens issued before the migration expire")
 	fs.StringVar(&s.AuthRateLimit, "auth-rate-limit", "5/30m", "specifies the maximum number of authentication attempts permitted and time interval,valid time units are \"s\",\"m\",\"h\"")
+	fs.StringVar(&s.OIDC.Name, "oidc-provider-name", "oidc", "name of the oidc identity provider, used in the login url")
+	fs.StringVar(&s.OIDC.Issuer, "oidc-issuer-url", "", "url of the oidc issuer, oidc login is disabled if empty")
+	fs.StringVar(&s.OIDC.ClientID, "oidc-client-id", "", "oidc client id")
+	fs.StringVar(&s.OIDC.ClientSecret, "oidc-client-secret", "", "oidc client secret")
+	fs.StringVar(&s.OIDC.RedirectURL, "oidc-redirect-url", "", "oidc redirect url, e.g. http://ks-console/kapis/iam.kubesphere.io/v1alpha2/login/oidc/callback")
+	fs.StringSliceVar(&s.OIDC.Scopes, "oidc-scopes", []string{"openid", "email", "profile"}, "oidc scopes to request")
+	fs.StringVar(&s.OIDC.UsernameClaim, "oidc-username-claim", "preferred_username", "id token claim used as username")
+	fs.StringVar(&s.OIDC.EmailClaim, "oidc-email-claim", "email", "id token claim used as email")
+	fs.StringVar(&s.OIDC.GroupsClaim, "oidc-groups-claim", "", "id token claim used as user groups, the groups are prefixed with oidc:<idp>: to match RBAC Group subjects and system: groups are ignored")
 	s.GenericServerRunOptions.AddFlags(fs)
 }
@@ -28,10 +28,12 @@ import (
 	"kubesphere.io/kubesphere/pkg/filter"
 	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/models/iam"
+	"kubesphere.io/kubesphere/pkg/models/iam/identityprovider"
 	"kubesphere.io/kubesphere/pkg/server"
 	"kubesphere.io/kubesphere/pkg/signals"
 	"kubesphere.io/kubesphere/pkg/simple/client/admin_jenkins"
 	"kubesphere.io/kubesphere/pkg/simple/client/devops_mysql"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
 	"kubesphere.io/kubesphere/pkg/utils/jwtutil"
 	"log"
 	"net/http"
@@ -51,6 +53,7 @@ cluster's shared state through which all other components interact.`,
 		},
 	}
 	s.AddFlags(cmd.Flags())
+	k8s.AddFlags(goflag.CommandLine)
 	cmd.Flags().AddGoFlagSet(goflag.CommandLine)
 	glog.CopyStandardLogTo("INFO")
 
@@ -70,18 +73,46 @@ func Run(s *options.ServerRunOptions) error {
 		return err
 	}
 
+	refreshExpireTime, err := time.ParseDuration(s.RefreshTokenExpireTime)
+
+	if err != nil {
+		return err
+	}
+
 	waitForResourceSync()
 
 	initializeAdminJenkins()
 	initializeDevOpsDatabase()
 
-	err = iam.Init(s.AdminEmail, s.AdminPassword, expireTime, s.AuthRateLimit)
-	jwtutil.Setup(s.JWTSecret)
+	err = iam.Init(s.AdminEmail, s.AdminPassword, expireTime, refreshExpireTime, s.AuthRateLimit)
 
 	if err != nil {
 		return err
 	}
 
+	iam.WatchRoleBindings()
+
+	// tokens signed with the HMAC secret are not accepted once a signing key is configured,
+	// unless they are migrated explicitly
+	if s.JWTSigningKey == "" || s.JWTSecretMigration {
+		jwtutil.Setup(s.JWTSecret)
+	}
+
+	if s.JWTSigningKey != "" {
+		err = jwtutil.SetupSigningKeys(s.JWTSigningKey, s.JWTVerificationKeys)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.OIDC.Issuer != "" {
+		provider, err := identityprovider.NewOIDCProvider(s.OIDC)
+		if err != nil {
+			return err
+		}
+		identityprovider.Register(provider)
+	}
+
 	container := runtime.Container
 	container.Filter(filter.Logging)
 	container.DoNotRecover(false)
@@ -27,18 +27,26 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/go-redis/redis"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"kubesphere.io/kubesphere/pkg/utils/jwtutil"
 )
 
 type Auth struct {
-	Rule Rule
-	Next httpserver.Handler
+	Rule        Rule
+	Next        httpserver.Handler
+	keySet      *keySetCache
+	redisClient *redis.Client
 }
 
 type Rule struct {
 	Secret       []byte
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	RedisOptions *redis.Options
 	Path         string
 	ExceptedPath []string
 }
@@ -75,6 +83,12 @@ func (h Auth) ServeHTTP(resp http.ResponseWriter, req *http.Request) (int, error
 			return h.HandleUnauthorized(resp, err), nil
 		}
 
+		err = h.CheckRevocation(token)
+
+		if err != nil {
+			return h.HandleUnauthorized(resp, err), nil
+		}
+
 		req, err = h.InjectContext(req, token)
 
 		if err != nil {
@@ -123,8 +137,20 @@ func (h Auth) InjectContext(req *http.Request, token *jwt.Token) (*http.Request,
 		}
 	}
 
-	groups, ok := payLoad["groups"].([]string)
-	if ok && len(groups) > 0 {
+	groups := make([]string, 0)
+	req.Header.Del("X-Token-Groups")
+
+	// claims decoded from JSON hold arrays as []interface{},
+	// system: groups such as system:masters are assigned by kubernetes only
+	if values, ok := payLoad["groups"].([]interface{}); ok {
+		for _, value := range values {
+			if group, ok := value.(string); ok && group != "" && !strings.HasPrefix(group, "system:") {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	if len(groups) > 0 {
 		req.Header.Set("X-Token-Groups", strings.Join(groups, ","))
 		usr.Groups = groups
 	}
@@ -138,12 +164,17 @@ func (h Auth) InjectContext(req *http.Request, token *jwt.Token) (*http.Request,
 
 	context := request.WithUser(req.Context(), usr)
 
-	requestInfo, err := requestInfoFactory.NewRequestInfo(req)
+	// requests dispatched to clusters, e.g. /kapis/clusters/{cluster}/resources.kubesphere.io/...,
+	// are authorized by ks-apiserver against the RBAC of the target cluster, no requestInfo is
+	// injected so that they are not authorized against the RBAC of the host cluster
+	if !isClusterRequest(req) {
+		requestInfo, err := requestInfoFactory.NewRequestInfo(req)
 
-	if err == nil {
-		context = request.WithRequestInfo(context, requestInfo)
-	} else {
-		return nil, err
+		if err == nil {
+			context = request.WithRequestInfo(context, requestInfo)
+		} else {
+			return nil, err
+		}
 	}
 
 	req = req.WithContext(context)
@@ -151,6 +182,11 @@ func (h Auth) InjectContext(req *http.Request, token *jwt.Token) (*http.Request,
 	return req, nil
 }
 
+func isClusterRequest(req *http.Request) bool {
+	parts := strings.SplitN(req.URL.Path, "/", 5)
+	return len(parts) == 5 && parts[1] == "kapis" && parts[2] == "clusters"
+}
+
 func (h Auth) Validate(uToken string) (*jwt.Token, error) {
 
 	if len(uToken) == 0 {
@@ -166,6 +202,35 @@ func (h Auth) Validate(uToken string) (*jwt.Token, error) {
 	return token, nil
 }
 
+// CheckRevocation rejects refresh tokens and tokens revoked by ks-iam
+func (h Auth) CheckRevocation(token *jwt.Token) error {
+	claims, ok := token.Claims.(jwt.MapClaims)
+
+	if !ok {
+		return errors.New("invalid payload")
+	}
+
+	if jwtutil.IsRefreshToken(claims) {
+		return errors.New("refresh token can not be used as access token")
+	}
+
+	if h.redisClient == nil {
+		return nil
+	}
+
+	revoked, err := jwtutil.IsRevoked(h.redisClient, claims)
+
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return errors.New("token has been revoked")
+	}
+
+	return nil
+}
+
 func (h Auth) HandleUnauthorized(w http.ResponseWriter, err error) int {
 	message := fmt.Sprintf("Unauthorized,%v", err)
 	w.Header().Add("WWW-Authenticate", message)
@@ -197,9 +262,19 @@ func (h Auth) ExtractToken(r *http.Request) (string, error) {
 }
 
 func (h Auth) ProvideKey(token *jwt.Token) (interface{}, error) {
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(h.Rule.Secret) == 0 {
+			return nil, fmt.Errorf("token signed with HMAC is not accepted")
+		}
 		return h.Rule.Secret, nil
-	} else {
-		return nil, fmt.Errorf("expect token signed with HMAC but got %v", token.Header["alg"])
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		if h.keySet == nil {
+			return nil, fmt.Errorf("token signed with %v is not accepted", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return h.keySet.Key(kid, token.Method.Alg())
+	default:
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
 	}
 }
@@ -19,8 +19,11 @@ package authenticate
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-redis/redis"
 	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
@@ -45,8 +48,23 @@ func Setup(c *caddy.Controller) error {
 		return nil
 	})
 
+	var keySet *keySetCache
+
+	if rule.JWKSURL != "" {
+		keySet = newKeySetCache(rule.JWKSURL, rule.JWKSCacheTTL)
+	}
+
+	var redisClient *redis.Client
+
+	if rule.RedisOptions != nil {
+		redisClient = redis.NewClient(rule.RedisOptions)
+		c.OnShutdown(func() error {
+			return redisClient.Close()
+		})
+	}
+
 	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
-		return &Auth{Next: next, Rule: rule}
+		return &Auth{Next: next, Rule: rule, keySet: keySet, redisClient: redisClient}
 	})
 
 	return nil
@@ -81,6 +99,52 @@ func parse(c *caddy.Controller) (Rule, error) {
 					if c.NextArg() {
 						return rule, c.ArgErr()
 					}
+				case "jwks":
+					if !c.NextArg() {
+						return rule, c.ArgErr()
+					}
+
+					rule.JWKSURL = c.Val()
+
+					if c.NextArg() {
+						return rule, c.ArgErr()
+					}
+				case "jwks_cache_ttl":
+					if !c.NextArg() {
+						return rule, c.ArgErr()
+					}
+
+					ttl, err := time.ParseDuration(c.Val())
+
+					if err != nil {
+						return rule, c.Err(err.Error())
+					}
+
+					rule.JWKSCacheTTL = ttl
+
+					if c.NextArg() {
+						return rule, c.ArgErr()
+					}
+				case "redis":
+					args := c.RemainingArgs()
+
+					if len(args) == 0 || len(args) > 3 {
+						return rule, c.ArgErr()
+					}
+
+					rule.RedisOptions = &redis.Options{Addr: args[0]}
+
+					if len(args) > 1 {
+						rule.RedisOptions.Password = args[1]
+					}
+
+					if len(args) > 2 {
+						db, err := strconv.Atoi(args[2])
+						if err != nil {
+							return rule, c.Err(err.Error())
+						}
+						rule.RedisOptions.DB = db
+					}
 				case "except":
 					if !c.NextArg() {
 						return rule, c.ArgErr()
@@ -0,0 +1,132 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package authenticate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	defaultKeySetCacheTTL = 10 * time.Minute
+	// minimum interval between two fetches triggered by unknown key ids
+	minKeySetRefreshInterval = 10 * time.Second
+)
+
+// keySetCache holds the JSON Web Key Set published by ks-iam, the set is
+// refreshed periodically and whenever a token signed by an unknown key arrives.
+type keySetCache struct {
+	url         string
+	ttl         time.Duration
+	client      *http.Client
+	mutex       sync.RWMutex
+	keySet      jose.JSONWebKeySet
+	lastRefresh time.Time
+}
+
+func newKeySetCache(url string, ttl time.Duration) *keySetCache {
+	if ttl <= 0 {
+		ttl = defaultKeySetCacheTTL
+	}
+	return &keySetCache{url: url, ttl: ttl, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (c *keySetCache) Key(kid, alg string) (interface{}, error) {
+	c.mutex.RLock()
+	key, found := lookupKey(c.keySet, kid)
+	expired := time.Since(c.lastRefresh) > c.ttl
+	c.mutex.RUnlock()
+
+	if !found || expired {
+		if err := c.refresh(!found); err != nil {
+			// keep serving cached keys if the key set endpoint is temporarily unavailable
+			if !found {
+				return nil, err
+			}
+		} else {
+			c.mutex.RLock()
+			key, found = lookupKey(c.keySet, kid)
+			c.mutex.RUnlock()
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("verification key %s not found", kid)
+	}
+
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return nil, fmt.Errorf("key %s can not be used with %s", kid, alg)
+	}
+
+	return key.Key, nil
+}
+
+func (c *keySetCache) refresh(force bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	sinceLastRefresh := time.Since(c.lastRefresh)
+
+	if sinceLastRefresh < minKeySetRefreshInterval || (!force && sinceLastRefresh <= c.ttl) {
+		return nil
+	}
+
+	resp, err := c.client.Get(c.url)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks from %s failed: %s", c.url, resp.Status)
+	}
+
+	var keySet jose.JSONWebKeySet
+
+	if err := json.Unmarshal(data, &keySet); err != nil {
+		return err
+	}
+
+	c.keySet = keySet
+	c.lastRefresh = time.Now()
+
+	return nil
+}
+
+func lookupKey(keySet jose.JSONWebKeySet, kid string) (jose.JSONWebKey, bool) {
+	for _, key := range keySet.Keys {
+		if key.KeyID == kid && (key.Use == "" || key.Use == "sig") {
+			return key, true
+		}
+	}
+	return jose.JSONWebKey{}, false
+}
@@ -23,7 +23,6 @@ import (
 	"fmt"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
 	"k8s.io/apiserver/pkg/endpoints/request"
-	"kubesphere.io/kubesphere/pkg/utils/k8sutil"
 	"log"
 	"net/http"
 	"strings"
@@ -31,15 +30,14 @@ import (
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 	"k8s.io/api/rbac/v1"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 )
 
 type Authentication struct {
-	Rule Rule
-	Next httpserver.Handler
+	Rule      Rule
+	Next      httpserver.Handler
+	ruleCache *ruleCache
 }
 
 type Rule struct {
@@ -64,7 +62,7 @@ func (c Authentication) ServeHTTP(w http.ResponseWriter, r *http.Request) (int,
 			return c.Next.ServeHTTP(w, r)
 		}
 
-		permitted, err := permissionValidate(attrs)
+		permitted, err := c.permissionValidate(attrs)
 
 		if err != nil {
 			return http.StatusInternalServerError, err
@@ -87,32 +85,37 @@ func handleForbidden(w http.ResponseWriter, err error) int {
 	return http.StatusForbidden
 }
 
-func permissionValidate(attrs authorizer.Attributes) (bool, error) {
+func (c Authentication) permissionValidate(attrs authorizer.Attributes) (bool, error) {
 
 	if attrs.GetResource() == "users" && attrs.GetUser().GetName() == attrs.GetName() {
 		return true, nil
 	}
 
-	permitted, err := clusterRoleValidate(attrs)
+	// users can review their own access, reviews of other users are authorized by the iam api
+	if attrs.GetAPIGroup() == "iam.kubesphere.io" && attrs.GetResource() == "accessreviews" && attrs.GetVerb() == "create" {
+		return true, nil
+	}
+
+	clusterRules, err := c.ruleCache.ClusterRules(attrs.GetUser())
 
 	if err != nil {
 		log.Println("lister error", err)
 		return false, err
 	}
 
-	if permitted {
+	if rulesMatchesAttributes(clusterRules, attrs) {
 		return true, nil
 	}
 
 	if attrs.GetNamespace() != "" {
-		permitted, err = roleValidate(attrs)
+		namespaceRules, err := c.ruleCache.NamespaceRules(attrs.GetUser(), attrs.GetNamespace())
 
 		if err != nil {
 			log.Println("lister error", err)
 			return false, err
 		}
 
-		if permitted {
+		if attrs.IsResourceRequest() && rulesMatchesAttributes(namespaceRules, attrs) {
 			return true, nil
 		}
 	}
@@ -120,79 +123,19 @@ func permissionValidate(attrs authorizer.Attributes) (bool, error) {
 	return false, nil
 }
 
-func roleValidate(attrs authorizer.Attributes) (bool, error) {
-	roleBindingLister := informers.SharedInformerFactory().Rbac().V1().RoleBindings().Lister()
-	roleLister := informers.SharedInformerFactory().Rbac().V1().Roles().Lister()
-	roleBindings, err := roleBindingLister.RoleBindings(attrs.GetNamespace()).List(labels.Everything())
-
-	if err != nil {
-		return false, err
-	}
-
-	fullSource := attrs.GetResource()
-
-	if attrs.GetSubresource() != "" {
-		fullSource = fullSource + "/" + attrs.GetSubresource()
-	}
-
-	for _, roleBinding := range roleBindings {
-		if k8sutil.ContainsUser(roleBinding.Subjects, attrs.GetUser().GetName()) {
-			role, err := roleLister.Roles(attrs.GetNamespace()).Get(roleBinding.RoleRef.Name)
-
-			if err != nil {
-				if k8serr.IsNotFound(err) {
-					continue
-				}
-				return false, err
-			}
-
-			for _, rule := range role.Rules {
-				if ruleMatchesRequest(rule, attrs.GetAPIGroup(), "", attrs.GetResource(), attrs.GetSubresource(), attrs.GetName(), attrs.GetVerb()) {
-					return true, nil
-				}
-			}
-		}
-	}
-
-	return false, nil
-}
-
-func clusterRoleValidate(attrs authorizer.Attributes) (bool, error) {
-	clusterRoleBindingLister := informers.SharedInformerFactory().Rbac().V1().ClusterRoleBindings().Lister()
-	clusterRoleBindings, err := clusterRoleBindingLister.List(labels.Everything())
-	clusterRoleLister := informers.SharedInformerFactory().Rbac().V1().ClusterRoles().Lister()
-	if err != nil {
-		return false, err
-	}
-
-	for _, clusterRoleBinding := range clusterRoleBindings {
-
-		if k8sutil.ContainsUser(clusterRoleBinding.Subjects, attrs.GetUser().GetName()) {
-			clusterRole, err := clusterRoleLister.Get(clusterRoleBinding.RoleRef.Name)
-
-			if err != nil {
-				if k8serr.IsNotFound(err) {
-					continue
-				}
-				return false, err
+func rulesMatchesAttributes(rules []v1.PolicyRule, attrs authorizer.Attributes) bool {
+	for _, rule := range rules {
+		if attrs.IsResourceRequest() {
+			if ruleMatchesRequest(rule, attrs.GetAPIGroup(), "", attrs.GetResource(), attrs.GetSubresource(), attrs.GetName(), attrs.GetVerb()) {
+				return true
 			}
-
-			for _, rule := range clusterRole.Rules {
-				if attrs.IsResourceRequest() {
-					if ruleMatchesRequest(rule, attrs.GetAPIGroup(), "", attrs.GetResource(), attrs.GetSubresource(), attrs.GetName(), attrs.GetVerb()) {
-						return true, nil
-					}
-				} else {
-					if ruleMatchesRequest(rule, "", attrs.GetPath(), "", "", "", attrs.GetVerb()) {
-						return true, nil
-					}
-				}
+		} else {
+			if ruleMatchesRequest(rule, "", attrs.GetPath(), "", "", "", attrs.GetVerb()) {
+				return true
 			}
-
 		}
 	}
-
-	return false, nil
+	return false
 }
 
 func ruleMatchesResources(rule v1.PolicyRule, apiGroup string, resource string, subresource string, resourceName string) bool {
@@ -0,0 +1,424 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package authentication
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/api/rbac/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+const (
+	subjectIndex = "subject"
+	// all authenticated users belong to this group
+	authenticatedGroup = "system:authenticated"
+)
+
+// workspace roles are propagated to the namespaces of the workspace as role bindings
+// by the namespace controller, the gateway honours them without waiting for the propagation
+var workspaceNamespaceRoles = map[string]string{
+	"admin":  "admin",
+	"viewer": "viewer",
+}
+
+// ruleCache resolves the policy rules granted to a user, the result is cached per
+// user and namespace, any change of RBAC resources or namespaces invalidates the cache.
+type ruleCache struct {
+	informerFactory informers.SharedInformerFactory
+
+	mutex      sync.RWMutex
+	generation uint64
+	entries    map[string]*userRules
+}
+
+type userRules struct {
+	cluster         []v1.PolicyRule
+	clusterResolved bool
+	namespaces      map[string][]v1.PolicyRule
+}
+
+func newRuleCache(informerFactory informers.SharedInformerFactory) (*ruleCache, error) {
+	c := &ruleCache{informerFactory: informerFactory, entries: make(map[string]*userRules)}
+
+	rbacInformers := informerFactory.Rbac().V1()
+
+	if err := rbacInformers.RoleBindings().Informer().AddIndexers(cache.Indexers{subjectIndex: roleBindingSubjectIndexFunc}); err != nil {
+		return nil, err
+	}
+
+	if err := rbacInformers.ClusterRoleBindings().Informer().AddIndexers(cache.Indexers{subjectIndex: clusterRoleBindingSubjectIndexFunc}); err != nil {
+		return nil, err
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.invalidate() },
+		UpdateFunc: func(old, new interface{}) {
+			oldMeta, err1 := meta(old)
+			newMeta, err2 := meta(new)
+			// skip periodic resync
+			if err1 == nil && err2 == nil && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+			c.invalidate()
+		},
+		DeleteFunc: func(obj interface{}) { c.invalidate() },
+	}
+
+	rbacInformers.Roles().Informer().AddEventHandler(handler)
+	rbacInformers.RoleBindings().Informer().AddEventHandler(handler)
+	rbacInformers.ClusterRoles().Informer().AddEventHandler(handler)
+	rbacInformers.ClusterRoleBindings().Informer().AddEventHandler(handler)
+	informerFactory.Core().V1().Namespaces().Informer().AddEventHandler(handler)
+
+	return c, nil
+}
+
+func meta(obj interface{}) (metav1.Object, error) {
+	if object, ok := obj.(metav1.Object); ok {
+		return object, nil
+	}
+	return nil, fmt.Errorf("unexpected object %T", obj)
+}
+
+func (c *ruleCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	c.entries = make(map[string]*userRules)
+}
+
+// ClusterRules returns the rules granted to the user by cluster role bindings
+func (c *ruleCache) ClusterRules(u user.Info) ([]v1.PolicyRule, error) {
+	key := userKey(u)
+
+	c.mutex.RLock()
+	entry, ok := c.entries[key]
+	if ok && entry.clusterResolved {
+		c.mutex.RUnlock()
+		return entry.cluster, nil
+	}
+	generation := c.generation
+	c.mutex.RUnlock()
+
+	rules, err := c.resolveClusterRules(u)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// the rules are stale if the cache was invalidated in the meantime
+	if generation == c.generation {
+		if entry, ok = c.entries[key]; !ok {
+			entry = &userRules{namespaces: make(map[string][]v1.PolicyRule)}
+			c.entries[key] = entry
+		}
+		entry.cluster = rules
+		entry.clusterResolved = true
+	}
+
+	return rules, nil
+}
+
+// NamespaceRules returns the rules granted to the user in the namespace by role bindings
+// and by the workspace roles of the workspace that the namespace belongs to.
+func (c *ruleCache) NamespaceRules(u user.Info, namespace string) ([]v1.PolicyRule, error) {
+	key := userKey(u)
+
+	c.mutex.RLock()
+	generation := c.generation
+	if entry, ok := c.entries[key]; ok {
+		if rules, ok := entry.namespaces[namespace]; ok {
+			c.mutex.RUnlock()
+			return rules, nil
+		}
+	}
+	c.mutex.RUnlock()
+
+	rules, err := c.resolveNamespaceRules(u, namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation == c.generation {
+		entry, ok := c.entries[key]
+		if !ok {
+			entry = &userRules{namespaces: make(map[string][]v1.PolicyRule)}
+			c.entries[key] = entry
+		}
+		entry.namespaces[namespace] = rules
+	}
+
+	return rules, nil
+}
+
+func (c *ruleCache) resolveClusterRules(u user.Info) ([]v1.PolicyRule, error) {
+	indexer := c.informerFactory.Rbac().V1().ClusterRoleBindings().Informer().GetIndexer()
+
+	rules := make([]v1.PolicyRule, 0)
+
+	for _, subject := range subjectKeys(u) {
+		objs, err := indexer.ByIndex(subjectIndex, subject)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range objs {
+			clusterRoleBinding := obj.(*v1.ClusterRoleBinding)
+
+			clusterRoleRules, err := c.clusterRoleRules(clusterRoleBinding.RoleRef.Name)
+
+			if err != nil {
+				return nil, err
+			}
+
+			rules = append(rules, clusterRoleRules...)
+		}
+	}
+
+	return rules, nil
+}
+
+func (c *ruleCache) resolveNamespaceRules(u user.Info, namespace string) ([]v1.PolicyRule, error) {
+	indexer := c.informerFactory.Rbac().V1().RoleBindings().Informer().GetIndexer()
+
+	rules := make([]v1.PolicyRule, 0)
+
+	for _, subject := range subjectKeys(u) {
+		objs, err := indexer.ByIndex(subjectIndex, subject)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range objs {
+			roleBinding := obj.(*v1.RoleBinding)
+
+			if roleBinding.Namespace != namespace {
+				continue
+			}
+
+			roleRules, err := c.roleRefRules(namespace, roleBinding.RoleRef)
+
+			if err != nil {
+				return nil, err
+			}
+
+			rules = append(rules, roleRules...)
+		}
+	}
+
+	workspaceRules, err := c.workspaceRules(u, namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(rules, workspaceRules...), nil
+}
+
+func (c *ruleCache) workspaceRules(u user.Info, namespace string) ([]v1.PolicyRule, error) {
+	ns, err := c.informerFactory.Core().V1().Namespaces().Lister().Get(namespace)
+
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	workspace := ns.Labels[constants.WorkspaceLabelKey]
+
+	if workspace == "" {
+		return nil, nil
+	}
+
+	clusterRoleBindingLister := c.informerFactory.Rbac().V1().ClusterRoleBindings().Lister()
+
+	rules := make([]v1.PolicyRule, 0)
+
+	for workspaceRole, namespaceRole := range workspaceNamespaceRoles {
+		workspaceRoleBinding, err := clusterRoleBindingLister.Get(fmt.Sprintf("workspace:%s:%s", workspace, workspaceRole))
+
+		if err != nil {
+			if k8serr.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if !subjectsMatch(workspaceRoleBinding.Subjects, u, "") {
+			continue
+		}
+
+		roleRules, err := c.roleRefRules(namespace, v1.RoleRef{Kind: "Role", Name: namespaceRole})
+
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, roleRules...)
+	}
+
+	return rules, nil
+}
+
+func (c *ruleCache) roleRefRules(namespace string, roleRef v1.RoleRef) ([]v1.PolicyRule, error) {
+	if roleRef.Kind == "ClusterRole" {
+		return c.clusterRoleRules(roleRef.Name)
+	}
+
+	role, err := c.informerFactory.Rbac().V1().Roles().Lister().Roles(namespace).Get(roleRef.Name)
+
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return role.Rules, nil
+}
+
+// clusterRoleRules returns the rules of the cluster role, rules of aggregated
+// cluster roles are included even if the aggregation controller has not synced them yet.
+func (c *ruleCache) clusterRoleRules(name string) ([]v1.PolicyRule, error) {
+	clusterRoleLister := c.informerFactory.Rbac().V1().ClusterRoles().Lister()
+
+	clusterRole, err := clusterRoleLister.Get(name)
+
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if clusterRole.AggregationRule == nil {
+		return clusterRole.Rules, nil
+	}
+
+	rules := append(make([]v1.PolicyRule, 0), clusterRole.Rules...)
+
+	for _, clusterRoleSelector := range clusterRole.AggregationRule.ClusterRoleSelectors {
+		selector, err := metav1.LabelSelectorAsSelector(&clusterRoleSelector)
+
+		if err != nil {
+			continue
+		}
+
+		aggregated, err := clusterRoleLister.List(selector)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range aggregated {
+			if item.Name != clusterRole.Name {
+				rules = append(rules, item.Rules...)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+func roleBindingSubjectIndexFunc(obj interface{}) ([]string, error) {
+	roleBinding, ok := obj.(*v1.RoleBinding)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T", obj)
+	}
+	return subjectsIndexKeys(roleBinding.Subjects, roleBinding.Namespace), nil
+}
+
+func clusterRoleBindingSubjectIndexFunc(obj interface{}) ([]string, error) {
+	clusterRoleBinding, ok := obj.(*v1.ClusterRoleBinding)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T", obj)
+	}
+	return subjectsIndexKeys(clusterRoleBinding.Subjects, ""), nil
+}
+
+func subjectsIndexKeys(subjects []v1.Subject, bindingNamespace string) []string {
+	keys := make([]string, 0, len(subjects))
+	for _, subject := range subjects {
+		switch subject.Kind {
+		case v1.UserKind, v1.GroupKind:
+			keys = append(keys, subject.Kind+":"+subject.Name)
+		case v1.ServiceAccountKind:
+			namespace := subject.Namespace
+			if namespace == "" {
+				namespace = bindingNamespace
+			}
+			keys = append(keys, serviceaccount.MakeUsername(namespace, subject.Name))
+		}
+	}
+	return keys
+}
+
+// subjectKeys returns the index keys of all subjects that the user matches
+func subjectKeys(u user.Info) []string {
+	keys := []string{v1.UserKind + ":" + u.GetName(), v1.GroupKind + ":" + authenticatedGroup}
+
+	if namespace, name, err := serviceaccount.SplitUsername(u.GetName()); err == nil {
+		keys = append(keys, serviceaccount.MakeUsername(namespace, name))
+	}
+
+	for _, group := range u.GetGroups() {
+		if group != authenticatedGroup {
+			keys = append(keys, v1.GroupKind+":"+group)
+		}
+	}
+
+	return keys
+}
+
+func subjectsMatch(subjects []v1.Subject, u user.Info, bindingNamespace string) bool {
+	userKeys := subjectKeys(u)
+	for _, key := range subjectsIndexKeys(subjects, bindingNamespace) {
+		for _, userKey := range userKeys {
+			if key == userKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func userKey(u user.Info) string {
+	groups := append(make([]string, 0, len(u.GetGroups())), u.GetGroups()...)
+	sort.Strings(groups)
+	return u.GetName() + "/" + strings.Join(groups, ",")
+}
@@ -43,12 +43,22 @@ func Setup(c *caddy.Controller) error {
 		return err
 	}
 	stopChan := make(chan struct{}, 0)
+
+	informerFactory := informers.SharedInformerFactory()
+
+	// indexers must be added before the informers start
+	ruleCache, err := newRuleCache(informerFactory)
+
+	if err != nil {
+		return err
+	}
+
 	c.OnStartup(func() error {
-		informerFactory := informers.SharedInformerFactory()
 		informerFactory.Rbac().V1().Roles().Lister()
 		informerFactory.Rbac().V1().RoleBindings().Lister()
 		informerFactory.Rbac().V1().ClusterRoles().Lister()
 		informerFactory.Rbac().V1().ClusterRoleBindings().Lister()
+		informerFactory.Core().V1().Namespaces().Lister()
 		informerFactory.Start(stopChan)
 		informerFactory.WaitForCacheSync(stopChan)
 		fmt.Println("Authentication middleware is initiated")
@@ -61,7 +71,7 @@ func Setup(c *caddy.Controller) error {
 	})
 
 	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
-		return &Authentication{Next: next, Rule: rule}
+		return &Authentication{Next: next, Rule: rule, ruleCache: ruleCache}
 	})
 	return nil
 }
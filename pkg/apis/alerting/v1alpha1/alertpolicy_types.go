@@ -0,0 +1,137 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// levels of AlertPolicy
+	AlertLevelNamespace = "namespace"
+	AlertLevelWorkload  = "workload"
+	AlertLevelPod       = "pod"
+
+	// levels of ClusterAlertPolicy
+	AlertLevelNode      = "node"
+	AlertLevelWorkspace = "workspace"
+)
+
+// AlertCondition is true when the built-in metric compared with the threshold is true, e.g. pod_cpu_usage > 0.8
+type AlertCondition struct {
+	// Metric is a built-in metric of the level of the policy, e.g. pod_cpu_usage
+	Metric string `json:"metric"`
+
+	// Operator is one of >, >=, <, <=, ==, !=
+	Operator string `json:"operator"`
+
+	Threshold float64 `json:"threshold"`
+
+	// For is how long the condition must be true before the alert fires, it fires at once if empty
+	For *metav1.Duration `json:"for,omitempty"`
+}
+
+// AlertPolicySpec defines the desired state of AlertPolicy and ClusterAlertPolicy
+type AlertPolicySpec struct {
+	// Level of the resources the conditions are evaluated against, one of namespace, workload, pod for AlertPolicy,
+	// one of node, workspace for ClusterAlertPolicy
+	Level string `json:"level"`
+
+	// Resources are names of pods, workloads, nodes or workspaces the conditions are evaluated against,
+	// all resources of the level in scope if empty. Workspaces must be set for the workspace level.
+	// Names must be DNS-1123 subdomains.
+	Resources []string `json:"resources,omitempty"`
+
+	// WorkloadKind is one of deployment, statefulset, daemonset, workloads of all kinds if empty
+	WorkloadKind string `json:"workloadKind,omitempty"`
+
+	// Conditions are evaluated separately, an alert fires for every condition and resource which is true
+	Conditions []AlertCondition `json:"conditions"`
+
+	// Severity is one of critical, warning, info, warning by default
+	Severity string `json:"severity,omitempty"`
+
+	// Message of alerts, the condition is described if empty
+	Message string `json:"message,omitempty"`
+
+	// Disabled policies are not evaluated
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// AlertPolicyStatus defines the observed state of AlertPolicy and ClusterAlertPolicy
+type AlertPolicyStatus struct {
+	// ObservedGeneration is the generation of the policy compiled into the rule
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Rule is the namespace/name of the PrometheusRule compiled from the policy
+	Rule string `json:"rule,omitempty"`
+
+	// Error of the last compilation
+	Error string `json:"error,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AlertPolicy fires alerts when metrics of namespaces, workloads or pods in its namespace meet the conditions,
+// it's compiled into a PrometheusRule in the same namespace
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type AlertPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              AlertPolicySpec   `json:"spec,omitempty"`
+	Status            AlertPolicyStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AlertPolicyList contains a list of AlertPolicy
+type AlertPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AlertPolicy `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterAlertPolicy fires alerts when metrics of nodes or workspaces meet the conditions,
+// it's compiled into a PrometheusRule in the kubesphere-monitoring-system namespace
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type ClusterAlertPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              AlertPolicySpec   `json:"spec,omitempty"`
+	Status            AlertPolicyStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterAlertPolicyList contains a list of ClusterAlertPolicy
+type ClusterAlertPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterAlertPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AlertPolicy{}, &AlertPolicyList{}, &ClusterAlertPolicy{}, &ClusterAlertPolicyList{})
+}
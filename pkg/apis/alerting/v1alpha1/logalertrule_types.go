@@ -0,0 +1,131 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+
+	// StateInactive means the rule has never fired
+	StateInactive = "inactive"
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// WebhookReceiver posts alerts as json to the url
+type WebhookReceiver struct {
+	// URL is an http or https url
+	URL string `json:"url"`
+}
+
+// EmailReceiver sends alerts by the smtp server configured in the controller manager
+type EmailReceiver struct {
+	To []string `json:"to"`
+}
+
+// SlackReceiver posts alerts to the incoming webhook of Slack or compatible services
+type SlackReceiver struct {
+	// URL is an http or https url
+	URL     string `json:"url"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// Receiver is notified when the rule fires or resolves, only one of the receivers should be set
+type Receiver struct {
+	Webhook *WebhookReceiver `json:"webhook,omitempty"`
+	Email   *EmailReceiver   `json:"email,omitempty"`
+	Slack   *SlackReceiver   `json:"slack,omitempty"`
+}
+
+// LogAlertRuleSpec defines the desired state of LogAlertRule
+type LogAlertRuleSpec struct {
+	// Workloads whose logs are counted, all workloads in the namespace of the rule if empty
+	Workloads []string `json:"workloads,omitempty"`
+
+	// Containers whose logs are counted, all containers if empty
+	Containers []string `json:"containers,omitempty"`
+
+	// LogQuery is keywords separated by comma, logs containing at least one keyword are counted
+	LogQuery string `json:"logQuery,omitempty"`
+
+	// Filter of structured fields, e.g. level=error AND http.status>=500
+	Filter string `json:"filter,omitempty"`
+
+	// Threshold of the number of matched logs in the window, the rule fires once it's reached
+	Threshold int64 `json:"threshold"`
+
+	// Window of time logs are counted in
+	Window metav1.Duration `json:"window"`
+
+	// Interval between evaluations, 1m by default
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Severity is one of critical, warning, info, warning by default
+	Severity string `json:"severity,omitempty"`
+
+	Receivers []Receiver `json:"receivers,omitempty"`
+}
+
+// LogAlertRuleStatus defines the observed state of LogAlertRule
+type LogAlertRuleStatus struct {
+	// State is one of inactive, firing, resolved
+	State string `json:"state,omitempty"`
+
+	// Count of matched logs in the window at the last evaluation
+	Count int64 `json:"count"`
+
+	LastEvaluationTime *metav1.Time `json:"lastEvaluationTime,omitempty"`
+
+	// LastTransitionTime is the last time the rule fired or resolved
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Error of the last evaluation
+	Error string `json:"error,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// LogAlertRule counts logs of workloads in its namespace periodically and notifies receivers
+// when the count reaches the threshold
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type LogAlertRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              LogAlertRuleSpec   `json:"spec,omitempty"`
+	Status            LogAlertRuleStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// LogAlertRuleList contains a list of LogAlertRule
+type LogAlertRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LogAlertRule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LogAlertRule{}, &LogAlertRuleList{})
+}
@@ -0,0 +1,151 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package v1alpha2
+
+import (
+	"github.com/emicklei/go-restful"
+	"github.com/emicklei/go-restful-openapi"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"kubesphere.io/kubesphere/pkg/apiserver/alerting"
+	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/errors"
+	alertingmodel "kubesphere.io/kubesphere/pkg/models/alerting"
+	"net/http"
+)
+
+const (
+	GroupName = "alerting.kubesphere.io"
+	RespOK    = "ok"
+)
+
+var GroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha2"}
+
+var (
+	WebServiceBuilder = runtime.NewContainerBuilder(addWebService)
+	AddToContainer    = WebServiceBuilder.AddToContainer
+)
+
+func addWebService(c *restful.Container) error {
+	ws := runtime.NewWebService(GroupVersion)
+
+	ws.Route(ws.GET("/alerts").To(alerting.ListAlerts).
+		Doc("List active alerts of cluster alert policies.").
+		Param(ws.QueryParameter("policy", "Name of the cluster alert policy, all cluster alert policies by default.").DataType("string").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "cluster"}).
+		Writes(alertingmodel.AlertsResult{}).
+		Returns(http.StatusOK, RespOK, alertingmodel.AlertsResult{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/alerts/history").To(alerting.ListAlertHistory).
+		Doc("List alerts fired by cluster alert policies in a range of time, newest first.").
+		Param(ws.QueryParameter("policy", "Name of the cluster alert policy, all cluster alert policies by default.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("start", "Start of the history. This option accepts epoch_second format, eg. 1559762729. Default to 24 hours before end.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end", "End of the history. This option accepts epoch_second format, eg. 1559762729. Default to now.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("step", "Resolution of the history, alerts firing less than a step may be missed, eg. 30s.").DataType("string").DefaultValue("1m").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "cluster"}).
+		Writes(alertingmodel.AlertsResult{}).
+		Returns(http.StatusOK, RespOK, alertingmodel.AlertsResult{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/silences").To(alerting.ListAlertSilences).
+		Doc("List silences of cluster alert policies, expired silences included.").
+		Param(ws.QueryParameter("policy", "Name of the cluster alert policy, all cluster alert policies by default.").DataType("string").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "cluster"}).
+		Writes(alertingmodel.AlertSilencesResult{}).
+		Returns(http.StatusOK, RespOK, alertingmodel.AlertSilencesResult{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.POST("/clusteralertpolicies/{alertpolicy}/silences").To(alerting.CreateAlertSilence).
+		Doc("Silence notifications of alerts of the cluster alert policy for a while.").
+		Param(ws.PathParameter("alertpolicy", "Name of the cluster alert policy.").DataType("string").Required(true)).
+		Reads(alertingmodel.AlertSilence{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "cluster"}).
+		Writes(alertingmodel.AlertSilenceCreateResult{}).
+		Returns(http.StatusOK, RespOK, alertingmodel.AlertSilenceCreateResult{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.DELETE("/silences/{silence}").To(alerting.ExpireAlertSilence).
+		Doc("Expire the silence of a cluster alert policy.").
+		Param(ws.PathParameter("silence", "ID of the silence.").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "cluster"}).
+		Writes(errors.Error{}).
+		Returns(http.StatusOK, RespOK, errors.Error{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/namespaces/{namespace}/alerts").To(alerting.ListAlerts).
+		Doc("List active alerts of alert policies in the namespace.").
+		Param(ws.PathParameter("namespace", "Specify the target namespace.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("policy", "Name of the alert policy, all alert policies by default.").DataType("string").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "namespace"}).
+		Writes(alertingmodel.AlertsResult{}).
+		Returns(http.StatusOK, RespOK, alertingmodel.AlertsResult{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/namespaces/{namespace}/alerts/history").To(alerting.ListAlertHistory).
+		Doc("List alerts fired by alert policies in the namespace in a range of time, newest first.").
+		Param(ws.PathParameter("namespace", "Specify the target namespace.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("policy", "Name of the alert policy, all alert policies by default.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("start", "Start of the history. This option accepts epoch_second format, eg. 1559762729. Default to 24 hours before end.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end", "End of the history. This option accepts epoch_second format, eg. 1559762729. Default to now.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("step", "Resolution of the history, alerts firing less than a step may be missed, eg. 30s.").DataType("string").DefaultValue("1m").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "namespace"}).
+		Writes(alertingmodel.AlertsResult{}).
+		Returns(http.StatusOK, RespOK, alertingmodel.AlertsResult{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/namespaces/{namespace}/silences").To(alerting.ListAlertSilences).
+		Doc("List silences of alert policies in the namespace, expired silences included.").
+		Param(ws.PathParameter("namespace", "Specify the target namespace.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("policy", "Name of the alert policy, all alert policies by default.").DataType("string").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "namespace"}).
+		Writes(alertingmodel.AlertSilencesResult{}).
+		Returns(http.StatusOK, RespOK, alertingmodel.AlertSilencesResult{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.POST("/namespaces/{namespace}/alertpolicies/{alertpolicy}/silences").To(alerting.CreateAlertSilence).
+		Doc("Silence notifications of alerts of the alert policy for a while.").
+		Param(ws.PathParameter("namespace", "Specify the target namespace.").DataType("string").Required(true)).
+		Param(ws.PathParameter("alertpolicy", "Name of the alert policy.").DataType("string").Required(true)).
+		Reads(alertingmodel.AlertSilence{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "namespace"}).
+		Writes(alertingmodel.AlertSilenceCreateResult{}).
+		Returns(http.StatusOK, RespOK, alertingmodel.AlertSilenceCreateResult{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.DELETE("/namespaces/{namespace}/silences/{silence}").To(alerting.ExpireAlertSilence).
+		Doc("Expire the silence of a alert policy.").
+		Param(ws.PathParameter("namespace", "Specify the target namespace.").DataType("string").Required(true)).
+		Param(ws.PathParameter("silence", "ID of the silence.").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Alerting", "namespace"}).
+		Writes(errors.Error{}).
+		Returns(http.StatusOK, RespOK, errors.Error{})).
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	c.Add(ws)
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConnectionTypeDirect connects to the member cluster with the kubeconfig,
+	// requests to ks-apiserver are proxied by the kube-apiserver of the member cluster
+	ConnectionTypeDirect = "direct"
+	// ConnectionTypeProxy connects to the member cluster through the endpoints exposed by the cluster agent
+	ConnectionTypeProxy = "proxy"
+
+	// KubeConfigSecretKey is the key of the kubeconfig in the secret referenced by the connection
+	KubeConfigSecretKey = "kubeconfig"
+)
+
+type Connection struct {
+	// Type is direct or proxy, direct by default
+	Type string `json:"type,omitempty"`
+
+	// KubeConfigSecretRef references the secret holding the kubeconfig of the member cluster
+	// in the key kubeconfig, the secret is in kubesphere-system if namespace is not specified
+	KubeConfigSecretRef *corev1.SecretReference `json:"kubeconfigSecretRef"`
+
+	// KubernetesAPIEndpoint is the kube-apiserver endpoint exposed by the agent, it
+	// overrides the server in the kubeconfig for proxy connections
+	KubernetesAPIEndpoint string `json:"kubernetesAPIEndpoint,omitempty"`
+
+	// KubeSphereAPIEndpoint is the ks-apiserver endpoint exposed by the agent, used for proxy connections
+	KubeSphereAPIEndpoint string `json:"kubesphereAPIEndpoint,omitempty"`
+}
+
+// ClusterSpec defines the desired state of Cluster
+type ClusterSpec struct {
+	Connection Connection `json:"connection"`
+}
+
+// ClusterStatus defines the observed state of Cluster
+type ClusterStatus struct {
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient:nonNamespaced
+
+// Cluster is a member cluster managed by the host cluster
+// +k8s:openapi-gen=true
+type Cluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ClusterSpec   `json:"spec,omitempty"`
+	Status            ClusterStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient:nonNamespaced
+
+// ClusterList contains a list of Cluster
+type ClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Cluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Cluster{}, &ClusterList{})
+}
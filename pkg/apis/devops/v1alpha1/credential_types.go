@@ -0,0 +1,105 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	CredentialTypeUsernamePassword = "username_password"
+	CredentialTypeSsh              = "ssh"
+	CredentialTypeSecretText       = "secret_text"
+	CredentialTypeKubeConfig       = "kubeconfig"
+
+	// keys of the data of credential secrets
+	SecretUsernameKey   = "username"
+	SecretPasswordKey   = "password"
+	SecretPassphraseKey = "passphrase"
+	SecretPrivateKeyKey = "private_key"
+	SecretTextKey       = "secret"
+	SecretKubeconfigKey = "content"
+)
+
+// CredentialSpec defines the desired state of Credential
+type CredentialSpec struct {
+	// Project is the name of the DevOpsProject the credential belongs to
+	Project string `json:"project"`
+
+	// Id of the credential in Jenkins, the name of the Credential by default
+	Id string `json:"id,omitempty"`
+
+	// Type is one of username_password, ssh, secret_text, kubeconfig
+	Type string `json:"type"`
+
+	Description string `json:"description,omitempty"`
+
+	// Domain of the credential in Jenkins, _ by default
+	Domain string `json:"domain,omitempty"`
+
+	// SecretRef is a secret in the namespace of the Credential, keys of its data depend on the type:
+	// username and password for username_password, username, passphrase and private_key for ssh,
+	// secret for secret_text, content for kubeconfig.
+	// The content of the credential in Jenkins is left as is if it's not set, e.g. for credentials migrated from
+	// the database whose content can't be read back from Jenkins.
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// CredentialStatus defines the observed state of Credential
+type CredentialStatus struct {
+	// ObservedGeneration is the generation of the credential applied to Jenkins
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SecretResourceVersion is the resourceVersion of the secret applied to Jenkins
+	SecretResourceVersion string `json:"secretResourceVersion,omitempty"`
+
+	// ProjectId is the folder of the credential created or adopted in Jenkins
+	ProjectId string `json:"projectId,omitempty"`
+
+	// Id of the credential created or adopted in Jenkins, only this credential is updated and deleted
+	Id string `json:"id,omitempty"`
+
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Credential is a credential of a DevOpsProject in Jenkins, its content is read from a secret
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type Credential struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              CredentialSpec   `json:"spec,omitempty"`
+	Status            CredentialStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CredentialList contains a list of Credential
+type CredentialList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Credential `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Credential{}, &CredentialList{})
+}
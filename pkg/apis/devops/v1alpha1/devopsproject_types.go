@@ -0,0 +1,128 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// roles of project members
+	ProjectOwner      = "owner"
+	ProjectMaintainer = "maintainer"
+	ProjectDeveloper  = "developer"
+	ProjectReporter   = "reporter"
+
+	// ConditionReady is true when the resource is applied to Jenkins
+	ConditionReady = "Ready"
+
+	// AdoptAnnotation set to "true" lets a Credential or Pipeline take over the credential or job which already
+	// exists in Jenkins, e.g. those imported by devops-migration. Existing ones are left alone otherwise.
+	AdoptAnnotation = "devops.kubesphere.io/adopt"
+)
+
+// Condition describes the state of the resource in Jenkins
+type Condition struct {
+	// Type of the condition, Ready
+	Type string `json:"type"`
+
+	// Status is one of True, False, Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// Reason is a brief CamelCase reason of the last transition, e.g. JenkinsUnavailable
+	Reason string `json:"reason,omitempty"`
+
+	Message string `json:"message,omitempty"`
+
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// ProjectMember grants the role of the project to the user
+type ProjectMember struct {
+	Username string `json:"username"`
+
+	// Role is one of owner, maintainer, developer, reporter
+	Role string `json:"role"`
+}
+
+// DevOpsProjectSpec defines the desired state of DevOpsProject
+type DevOpsProjectSpec struct {
+	// ProjectId is the name of the folder in Jenkins, the name of the DevOpsProject by default.
+	// It can't be changed once the folder is created.
+	ProjectId string `json:"projectId,omitempty"`
+
+	// DisplayName of the project
+	DisplayName string `json:"displayName,omitempty"`
+
+	Description string `json:"description,omitempty"`
+
+	// Workspace the project belongs to
+	Workspace string `json:"workspace"`
+
+	// Namespace of the Credentials and Pipelines of the project, those in other namespaces referring to
+	// the project aren't applied to Jenkins
+	Namespace string `json:"namespace,omitempty"`
+
+	Creator string `json:"creator,omitempty"`
+
+	// Members of the project, the roles of the project in Jenkins are granted to them
+	Members []ProjectMember `json:"members,omitempty"`
+}
+
+// DevOpsProjectStatus defines the observed state of DevOpsProject
+type DevOpsProjectStatus struct {
+	// ObservedGeneration is the generation of the project applied to Jenkins
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ProjectId is the name of the folder in Jenkins
+	ProjectId string `json:"projectId,omitempty"`
+
+	// Members granted roles in Jenkins, roles of members removed from the spec are revoked
+	Members []ProjectMember `json:"members,omitempty"`
+
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DevOpsProject is a folder of pipelines and credentials in Jenkins, with roles of the project granted to its members
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type DevOpsProject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              DevOpsProjectSpec   `json:"spec,omitempty"`
+	Status            DevOpsProjectStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient:nonNamespaced
+
+// DevOpsProjectList contains a list of DevOpsProject
+type DevOpsProjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DevOpsProject `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DevOpsProject{}, &DevOpsProjectList{})
+}
@@ -0,0 +1,238 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	NoScmPipelineType       = "pipeline"
+	MultiBranchPipelineType = "multi-branch-pipeline"
+
+	// source types of multi-branch pipelines
+	SourceTypeGit             = "git"
+	SourceTypeGitHub          = "github"
+	SourceTypeGitlab          = "gitlab"
+	SourceTypeBitbucketServer = "bitbucket_server"
+	SourceTypeGitea           = "gitea"
+	SourceTypeSvn             = "svn"
+	SourceTypeSingleSvn       = "single_svn"
+)
+
+type DiscarderProperty struct {
+	DaysToKeep string `json:"daysToKeep,omitempty"`
+	NumToKeep  string `json:"numToKeep,omitempty"`
+}
+
+type Parameter struct {
+	Name         string `json:"name"`
+	DefaultValue string `json:"defaultValue,omitempty"`
+
+	// Type is one of string, choice, text, boolean, file, password
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+type TimerTrigger struct {
+	// Cron triggers pipelines
+	Cron string `json:"cron,omitempty"`
+
+	// Interval in milliseconds triggers scans of multi-branch pipelines
+	Interval string `json:"interval,omitempty"`
+}
+
+type RemoteTrigger struct {
+	Token string `json:"token,omitempty"`
+}
+
+type GitCloneOption struct {
+	Shallow bool `json:"shallow,omitempty"`
+
+	// Timeout of clones in minutes
+	Timeout int `json:"timeout,omitempty"`
+	Depth   int `json:"depth,omitempty"`
+}
+
+type GitSource struct {
+	Url              string          `json:"url,omitempty"`
+	CredentialId     string          `json:"credentialId,omitempty"`
+	DiscoverBranches bool            `json:"discoverBranches,omitempty"`
+	CloneOption      *GitCloneOption `json:"cloneOption,omitempty"`
+	RegexFilter      string          `json:"regexFilter,omitempty"`
+}
+
+type GithubDiscoverPRFromForks struct {
+	Strategy int `json:"strategy,omitempty"`
+	Trust    int `json:"trust,omitempty"`
+}
+
+type GithubSource struct {
+	Owner                string                     `json:"owner,omitempty"`
+	Repo                 string                     `json:"repo,omitempty"`
+	CredentialId         string                     `json:"credentialId,omitempty"`
+	ApiUri               string                     `json:"apiUri,omitempty"`
+	DiscoverBranches     int                        `json:"discoverBranches,omitempty"`
+	DiscoverPRFromOrigin int                        `json:"discoverPRFromOrigin,omitempty"`
+	DiscoverPRFromForks  *GithubDiscoverPRFromForks `json:"discoverPRFromForks,omitempty"`
+	CloneOption          *GitCloneOption            `json:"cloneOption,omitempty"`
+	RegexFilter          string                     `json:"regexFilter,omitempty"`
+}
+
+type GitlabSource struct {
+	// ServerName is the name of the GitLab server configured in Jenkins
+	ServerName           string `json:"serverName,omitempty"`
+	Owner                string `json:"owner,omitempty"`
+	Repo                 string `json:"repo,omitempty"`
+	CredentialId         string `json:"credentialId,omitempty"`
+	DiscoverBranches     int    `json:"discoverBranches,omitempty"`
+	DiscoverMRFromOrigin int    `json:"discoverMRFromOrigin,omitempty"`
+
+	// Trust of fork merge requests is one of 1 members, 2 everyone, 3 permission, 4 nobody
+	DiscoverMRFromForks *GithubDiscoverPRFromForks `json:"discoverMRFromForks,omitempty"`
+	CloneOption         *GitCloneOption            `json:"cloneOption,omitempty"`
+	RegexFilter         string                     `json:"regexFilter,omitempty"`
+}
+
+type BitbucketServerSource struct {
+	ApiUri string `json:"apiUri,omitempty"`
+
+	// Owner is the key of the Bitbucket project
+	Owner                string `json:"owner,omitempty"`
+	Repo                 string `json:"repo,omitempty"`
+	CredentialId         string `json:"credentialId,omitempty"`
+	DiscoverBranches     int    `json:"discoverBranches,omitempty"`
+	DiscoverPRFromOrigin int    `json:"discoverPRFromOrigin,omitempty"`
+
+	// Trust of fork pull requests is one of 1 team forks, 2 everyone, 4 nobody
+	DiscoverPRFromForks *GithubDiscoverPRFromForks `json:"discoverPRFromForks,omitempty"`
+	CloneOption         *GitCloneOption            `json:"cloneOption,omitempty"`
+	RegexFilter         string                     `json:"regexFilter,omitempty"`
+}
+
+type GiteaSource struct {
+	ApiUri               string `json:"apiUri,omitempty"`
+	Owner                string `json:"owner,omitempty"`
+	Repo                 string `json:"repo,omitempty"`
+	CredentialId         string `json:"credentialId,omitempty"`
+	DiscoverBranches     int    `json:"discoverBranches,omitempty"`
+	DiscoverPRFromOrigin int    `json:"discoverPRFromOrigin,omitempty"`
+
+	// Trust of fork pull requests is one of 1 contributors, 2 everyone, 4 nobody
+	DiscoverPRFromForks *GithubDiscoverPRFromForks `json:"discoverPRFromForks,omitempty"`
+	CloneOption         *GitCloneOption            `json:"cloneOption,omitempty"`
+	RegexFilter         string                     `json:"regexFilter,omitempty"`
+}
+
+type SvnSource struct {
+	Remote       string `json:"remote,omitempty"`
+	CredentialId string `json:"credentialId,omitempty"`
+	Includes     string `json:"includes,omitempty"`
+	Excludes     string `json:"excludes,omitempty"`
+}
+
+type SingleSvnSource struct {
+	Remote       string `json:"remote,omitempty"`
+	CredentialId string `json:"credentialId,omitempty"`
+}
+
+// NoScmPipeline is a pipeline of a Jenkinsfile
+type NoScmPipeline struct {
+	Discarder         *DiscarderProperty `json:"discarder,omitempty"`
+	Parameters        []Parameter        `json:"parameters,omitempty"`
+	DisableConcurrent bool               `json:"disableConcurrent,omitempty"`
+	TimerTrigger      *TimerTrigger      `json:"timerTrigger,omitempty"`
+	RemoteTrigger     *RemoteTrigger     `json:"remoteTrigger,omitempty"`
+	Jenkinsfile       string             `json:"jenkinsfile,omitempty"`
+}
+
+// MultiBranchPipeline runs the script of every branch of the source
+type MultiBranchPipeline struct {
+	Discarder    *DiscarderProperty `json:"discarder,omitempty"`
+	TimerTrigger *TimerTrigger      `json:"timerTrigger,omitempty"`
+
+	// SourceType is one of git, github, gitlab, bitbucket_server, gitea, svn, single_svn
+	SourceType            string                 `json:"sourceType"`
+	GitSource             *GitSource             `json:"gitSource,omitempty"`
+	GitHubSource          *GithubSource          `json:"githubSource,omitempty"`
+	GitlabSource          *GitlabSource          `json:"gitlabSource,omitempty"`
+	BitbucketServerSource *BitbucketServerSource `json:"bitbucketServerSource,omitempty"`
+	GiteaSource           *GiteaSource           `json:"giteaSource,omitempty"`
+	SvnSource             *SvnSource             `json:"svnSource,omitempty"`
+	SingleSvnSource       *SingleSvnSource       `json:"singleSvnSource,omitempty"`
+
+	// ScriptPath is the path of the Jenkinsfile in the source
+	ScriptPath string `json:"scriptPath"`
+}
+
+// PipelineSpec defines the desired state of Pipeline
+type PipelineSpec struct {
+	// Project is the name of the DevOpsProject the pipeline belongs to
+	Project string `json:"project"`
+
+	// Name of the job in Jenkins, the name of the Pipeline by default
+	Name string `json:"name,omitempty"`
+
+	Description string `json:"description,omitempty"`
+
+	// Type is one of pipeline, multi-branch-pipeline
+	Type string `json:"type"`
+
+	Pipeline            *NoScmPipeline       `json:"pipeline,omitempty"`
+	MultiBranchPipeline *MultiBranchPipeline `json:"multiBranchPipeline,omitempty"`
+}
+
+// PipelineStatus defines the observed state of Pipeline
+type PipelineStatus struct {
+	// ObservedGeneration is the generation of the pipeline applied to Jenkins
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ProjectId is the folder of the job created or adopted in Jenkins
+	ProjectId string `json:"projectId,omitempty"`
+
+	// Name of the job created or adopted in Jenkins, only this job is updated and deleted
+	Name string `json:"name,omitempty"`
+
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Pipeline is a job of a DevOpsProject in Jenkins
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type Pipeline struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              PipelineSpec   `json:"spec,omitempty"`
+	Status            PipelineStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PipelineList contains a list of Pipeline
+type PipelineList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Pipeline `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Pipeline{}, &PipelineList{})
+}
@@ -0,0 +1,92 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TemplateParameter is a value given when pipelines are created from the template
+type TemplateParameter struct {
+	// Name of the parameter, the string fields of the template refer to it by {{ .name }}, values are inserted
+	// as is, {{ groovy .name }} inserts the value as a Groovy string literal into the Jenkinsfile
+	Name string `json:"name"`
+
+	// Type is one of string, text, boolean, choice
+	Type string `json:"type"`
+
+	Description string `json:"description,omitempty"`
+
+	// DefaultValue is used if the value is not given
+	DefaultValue string `json:"defaultValue,omitempty"`
+
+	// Required parameters can't be empty
+	Required bool `json:"required,omitempty"`
+
+	// Choices of choice parameters
+	Choices []string `json:"choices,omitempty"`
+}
+
+// PipelineTemplateSpec defines the desired state of PipelineTemplate
+type PipelineTemplateSpec struct {
+	DisplayName string `json:"displayName,omitempty"`
+
+	Description string `json:"description,omitempty"`
+
+	// Version is recorded by the pipelines created from the template, pipelines of older versions are
+	// upgraded when the template is rolled out
+	Version string `json:"version"`
+
+	Parameters []TemplateParameter `json:"parameters,omitempty"`
+
+	// Type is one of pipeline, multi-branch-pipeline
+	Type string `json:"type"`
+
+	// Pipeline and MultiBranchPipeline are rendered as go templates with the values of the parameters,
+	// e.g. the Jenkinsfile or the url of the git source
+	Pipeline            *NoScmPipeline       `json:"pipeline,omitempty"`
+	MultiBranchPipeline *MultiBranchPipeline `json:"multiBranchPipeline,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PipelineTemplate is a pipeline with parameters that pipelines of devops projects are created from. Templates labelled
+// with kubesphere.io/workspace are available to the devops projects of the workspace and are managed by workspace
+// admins through the tenant api, templates without the label are available to all projects.
+// +k8s:openapi-gen=true
+type PipelineTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              PipelineTemplateSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient:nonNamespaced
+
+// PipelineTemplateList contains a list of PipelineTemplate
+type PipelineTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PipelineTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PipelineTemplate{}, &PipelineTemplateList{})
+}
@@ -48,6 +48,36 @@ func addWebService(c *restful.Container) error {
 
 	tags := []string{"DevOps"}
 
+	webservice.Route(webservice.GET("/devops/orphans").
+		To(devopsapi.GetDevOpsProjectOrphansHandler).
+		Doc("Get the folders and roles of DevOps projects left only in Jenkins and the projects whose folders are missing in Jenkins, only orphans found by the last two checks are reported. Admin only.").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Returns(http.StatusOK, RespOK, devops.ProjectOrphans{}).
+		Writes(devops.ProjectOrphans{}))
+
+	webservice.Route(webservice.POST("/devops/orphans/repair").
+		To(devopsapi.RepairDevOpsProjectOrphansHandler).
+		Doc("Delete the orphan folders and roles in Jenkins and create the missing folders and roles of DevOps projects again, returns the repaired orphans. Admin only.").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Returns(http.StatusOK, RespOK, devops.ProjectOrphans{}).
+		Writes(devops.ProjectOrphans{}))
+
+	webservice.Route(webservice.GET("/devops/pipelinetemplates/{template}/pipelines").
+		To(devopsapi.GetAllTemplatePipelinesHandler).
+		Doc("Get the pipelines created from the pipeline template in all DevOps projects. Admin only.").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("template", "name of the pipeline template, e.g. build-push-deploy")).
+		Returns(http.StatusOK, RespOK, []devops.TemplatePipeline{}).
+		Writes([]devops.TemplatePipeline{}))
+
+	webservice.Route(webservice.POST("/devops/pipelinetemplates/{template}/rollout").
+		To(devopsapi.RolloutPipelineTemplateHandler).
+		Doc("Render the pipelines created from older versions of the pipeline template with the current version, returns the upgraded pipelines, pipelines failed to upgrade are returned with their errors. Pipelines modified after they are rendered and pipelines of projects the template isn't available to are not upgraded. Admin only.").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("template", "name of the pipeline template, e.g. build-push-deploy")).
+		Returns(http.StatusOK, RespOK, []devops.TemplatePipeline{}).
+		Writes([]devops.TemplatePipeline{}))
+
 	webservice.Route(webservice.GET("/devops/{devops}").
 		To(devopsapi.GetDevOpsProjectHandler).
 		Doc("Get the specified DevOps Project").
@@ -220,6 +250,104 @@ Some encrypted fields, such as the password of the username password type creden
 		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
 		Returns(http.StatusOK, RespOK, []devops.JenkinsCredential{}))
 
+	webservice.Route(webservice.GET("/devops/{devops}/pipelinetemplates").
+		To(devopsapi.GetPipelineTemplatesHandler).
+		Doc("Get the pipeline templates available to the DevOps project, templates of the workspace of the project and templates of all workspaces").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Returns(http.StatusOK, RespOK, []devops.PipelineTemplate{}).
+		Writes([]devops.PipelineTemplate{}))
+
+	webservice.Route(webservice.GET("/devops/{devops}/pipelinetemplates/{template}").
+		To(devopsapi.GetPipelineTemplateHandler).
+		Doc("Get the specified pipeline template available to the DevOps project").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Param(webservice.PathParameter("template", "name of the pipeline template, e.g. build-push-deploy")).
+		Returns(http.StatusOK, RespOK, devops.PipelineTemplate{}).
+		Writes(devops.PipelineTemplate{}))
+
+	webservice.Route(webservice.POST("/devops/{devops}/pipelinetemplates/{template}/pipelines").
+		To(devopsapi.CreateTemplatePipelineHandler).
+		Doc("Create a pipeline in the DevOps project from the pipeline template, the Jenkinsfile and other fields of the template are rendered with the values of parameters").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Param(webservice.PathParameter("template", "name of the pipeline template, e.g. build-push-deploy")).
+		Reads(devops.TemplatePipelineRequest{}).
+		Returns(http.StatusOK, RespOK, devops.TemplatePipeline{}).
+		Writes(devops.TemplatePipeline{}))
+
+	webservice.Route(webservice.GET("/devops/{devops}/pipelinetemplates/{template}/pipelines").
+		To(devopsapi.GetTemplatePipelinesHandler).
+		Doc("Get the pipelines of the DevOps project created from the pipeline template, with the template versions they are rendered from").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Param(webservice.PathParameter("template", "name of the pipeline template, e.g. build-push-deploy")).
+		Returns(http.StatusOK, RespOK, []devops.TemplatePipeline{}).
+		Writes([]devops.TemplatePipeline{}))
+
+	webservice.Route(webservice.PUT("/devops/{devops}/pipelinetemplates/{template}/pipelines/{pipeline}").
+		To(devopsapi.UpgradeTemplatePipelineHandler).
+		Doc("Render the pipeline with the current version of the pipeline template again, the values of parameters of the last rendering are used if parameters are absent. Pipelines modified after they are rendered are not upgraded unless force is true, 409 is returned otherwise.").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Param(webservice.PathParameter("template", "name of the pipeline template, e.g. build-push-deploy")).
+		Param(webservice.PathParameter("pipeline", "the name of pipeline, e.g. sample-pipeline")).
+		Param(webservice.QueryParameter("force", "overwrite the changes of the pipeline made after it's rendered, true or false").
+			Required(false).
+			DefaultValue("false")).
+		Reads(devops.TemplatePipelineRequest{}).
+		Returns(http.StatusOK, RespOK, devops.TemplatePipeline{}).
+		Writes(devops.TemplatePipeline{}))
+
+	webservice.Route(webservice.POST("/devops/{devops}/notificationreceivers").
+		To(devopsapi.CreateDevOpsProjectNotificationReceiverHandler).
+		Doc("Create a notification receiver of pipeline run events in the specified DevOps project").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Reads(devops.NotificationReceiver{}).
+		Returns(http.StatusOK, RespOK, devops.NotificationReceiver{}))
+
+	webservice.Route(webservice.PUT("/devops/{devops}/notificationreceivers/{receiver}").
+		To(devopsapi.UpdateDevOpsProjectNotificationReceiverHandler).
+		Doc("Update the specified notification receiver of the DevOps project").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Param(webservice.PathParameter("receiver", "name of the notification receiver, e.g. slack-dev")).
+		Reads(devops.NotificationReceiver{}).
+		Returns(http.StatusOK, RespOK, devops.NotificationReceiver{}))
+
+	webservice.Route(webservice.DELETE("/devops/{devops}/notificationreceivers/{receiver}").
+		To(devopsapi.DeleteDevOpsProjectNotificationReceiverHandler).
+		Doc("Delete the specified notification receiver of the DevOps project").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Param(webservice.PathParameter("receiver", "name of the notification receiver, e.g. slack-dev")))
+
+	webservice.Route(webservice.GET("/devops/{devops}/notificationreceivers/{receiver}").
+		To(devopsapi.GetDevOpsProjectNotificationReceiverHandler).
+		Doc("Get the specified notification receiver of the DevOps project").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Param(webservice.PathParameter("receiver", "name of the notification receiver, e.g. slack-dev")).
+		Returns(http.StatusOK, RespOK, devops.NotificationReceiver{}))
+
+	webservice.Route(webservice.GET("/devops/{devops}/notificationreceivers").
+		To(devopsapi.GetDevOpsProjectNotificationReceiversHandler).
+		Doc("Get all notification receivers of the specified DevOps project").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Returns(http.StatusOK, RespOK, []devops.NotificationReceiver{}))
+
+	webservice.Route(webservice.GET("/devops/{devops}/pipelineruns/events").
+		To(devopsapi.WatchPipelineRunEventsHandler).
+		Doc("Watch the run, stage and step events of pipelines in the DevOps project. Events are sent as websocket messages if the connection is upgraded, or as server-sent events named <kind>.<type>, e.g. run.failed.").
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Param(webservice.PathParameter("devops", "DevOps project's ID, e.g. project-RRRRAzLBlLEm")).
+		Param(webservice.QueryParameter("pipeline", "only watch the events of the pipeline, e.g. sample-pipeline").Required(false)).
+		Produces("text/event-stream", restful.MIME_JSON).
+		Returns(http.StatusOK, RespOK, devops.PipelineRunEvent{}))
+
 	// match Jenkisn api "/blue/rest/organizations/jenkins/pipelines/{devops}/{pipeline}"
 	webservice.Route(webservice.GET("/devops/{devops}/pipelines/{pipeline}").
 		To(devopsapi.GetPipeline).
@@ -343,11 +471,24 @@ Some encrypted fields, such as the password of the username password type creden
 	webservice.Route(webservice.GET("/scms/{scm}/organizations").
 		To(devopsapi.GetSCMOrg).
 		Metadata(restfulspec.KeyOpenAPITags, tags).
-		Doc("List all organizations of the specified source configuration management (SCM) such as Github.").
+		Doc("List all organizations of the specified source configuration management (SCM) such as Github. "+
+			"Organizations of gitlab, bitbucket-server and gitea are listed with the credential of the devops project.").
 		Param(webservice.PathParameter("scm", "the id of the source configuration management (SCM).")).
 		Param(webservice.QueryParameter("credentialId", "credential id for source configuration management (SCM).").
 			Required(true).
 			DataFormat("credentialId=%s")).
+		Param(webservice.QueryParameter("devops", "DevOps project's ID of the credential, required by gitlab, bitbucket-server and gitea.").
+			Required(false).
+			DataFormat("devops=%s")).
+		Param(webservice.QueryParameter("apiUrl", "url of the server of gitlab, bitbucket-server and gitea, one of the servers configured by --scm-servers, the first one by default.").
+			Required(false).
+			DataFormat("apiUrl=%s")).
+		Param(webservice.QueryParameter("pageNumber", "page number of gitlab, bitbucket-server and gitea organizations, 1 by default.").
+			Required(false).
+			DataFormat("pageNumber=%d")).
+		Param(webservice.QueryParameter("pageSize", "the item count of one page of gitlab, bitbucket-server and gitea organizations, 20 by default.").
+			Required(false).
+			DataFormat("pageSize=%d")).
 		Returns(http.StatusOK, RespOK, []devops.SCMOrg{}).
 		Writes([]devops.SCMOrg{}))
 
@@ -361,6 +502,12 @@ Some encrypted fields, such as the password of the username password type creden
 		Param(webservice.QueryParameter("credentialId", "credential id for SCM.").
 			Required(true).
 			DataFormat("credentialId=%s")).
+		Param(webservice.QueryParameter("devops", "DevOps project's ID of the credential, required by gitlab, bitbucket-server and gitea.").
+			Required(false).
+			DataFormat("devops=%s")).
+		Param(webservice.QueryParameter("apiUrl", "url of the server of gitlab, bitbucket-server and gitea, one of the servers configured by --scm-servers, the first one by default.").
+			Required(false).
+			DataFormat("apiUrl=%s")).
 		Param(webservice.QueryParameter("pageNumber", "page number.").
 			Required(true).
 			DataFormat("pageNumber=%d")).
@@ -725,6 +872,13 @@ Some encrypted fields, such as the password of the username password type creden
 		Metadata(restfulspec.KeyOpenAPITags, tags).
 		Doc("Get commit notification. Github webhook will request here."))
 
+	webservice.Route(webservice.POST("/webhook/{scm}").
+		To(devopsapi.SCMWebhook).
+		Metadata(restfulspec.KeyOpenAPITags, tags).
+		Doc("Get push and pull request notifications of gitlab, bitbucket-server or gitea, which trigger scans of multi-branch pipelines of the repository. " +
+			"Webhooks are verified by X-Gitlab-Token of gitlab, X-Hub-Signature of bitbucket-server and X-Gitea-Signature of gitea with the secret of the scm.").
+		Param(webservice.PathParameter("scm", "the id of the source configuration management (SCM), one of gitlab, bitbucket-server and gitea.")))
+
 	// in scm get all steps in nodes.
 	webservice.Route(webservice.GET("/devops/{devops}/pipelines/{pipeline}/branches/{branch}/runs/{run}/nodesdetail").
 		To(devopsapi.GetBranchNodesDetail).
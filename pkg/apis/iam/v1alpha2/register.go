@@ -20,6 +20,7 @@ package v1alpha2
 import (
 	"github.com/emicklei/go-restful"
 	"github.com/emicklei/go-restful-openapi"
+	"gopkg.in/square/go-jose.v2"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"kubesphere.io/kubesphere/pkg/apiserver/iam"
@@ -27,6 +28,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/constants"
 	"kubesphere.io/kubesphere/pkg/errors"
 	"kubesphere.io/kubesphere/pkg/models"
+	iammodel "kubesphere.io/kubesphere/pkg/models/iam"
 	"kubesphere.io/kubesphere/pkg/models/iam/policy"
 	"net/http"
 	"time"
@@ -121,6 +123,42 @@ func addWebService(c *restful.Container) error {
 		Reads(iam.LoginRequest{}).
 		Returns(http.StatusOK, ok, models.Token{}).
 		Metadata(restfulspec.KeyOpenAPITags, []string{constants.IdentityManagementTag}))
+	ws.Route(ws.POST("/logout").
+		To(iam.Logout).
+		Doc("Revoke the access token in the Authorization header, the refresh token in the request body will also be revoked if present, even if the access token has expired.").
+		Reads(iam.RefreshTokenRequest{}).
+		Returns(http.StatusOK, ok, errors.Error{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.IdentityManagementTag}))
+	ws.Route(ws.POST("/token/refresh").
+		To(iam.RefreshToken).
+		Doc("Exchange a refresh token for a new access token and refresh token, the refresh token can only be used once.").
+		Reads(iam.RefreshTokenRequest{}).
+		Returns(http.StatusOK, ok, models.Token{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.IdentityManagementTag}))
+	ws.Route(ws.GET("/jwks").
+		To(iam.JSONWebKeySet).
+		Doc("Retrieve the JSON Web Key Set (RFC 7517) containing the public keys used to verify tokens issued by ks-iam.").
+		Returns(http.StatusOK, ok, jose.JSONWebKeySet{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.IdentityManagementTag}))
+	ws.Route(ws.GET("/identityproviders").
+		To(iam.ListIdentityProviders).
+		Doc("List the names of the external identity providers that can be used to login.").
+		Returns(http.StatusOK, ok, []string{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.IdentityManagementTag}))
+	ws.Route(ws.GET("/login/{idp}").
+		To(iam.OAuthAuthorize).
+		Doc("Redirect to the login page of the specified external identity provider (OAuth2 authorization code flow).").
+		Param(ws.PathParameter("idp", "identity provider name")).
+		Returns(http.StatusFound, "redirect to identity provider", nil).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.IdentityManagementTag}))
+	ws.Route(ws.GET("/login/{idp}/callback").
+		To(iam.OAuthCallback).
+		Doc("Callback of the OAuth2 authorization code flow, the user will be created on first login and an authentication token is returned.").
+		Param(ws.PathParameter("idp", "identity provider name")).
+		Param(ws.QueryParameter("code", "authorization code issued by the identity provider").Required(true)).
+		Param(ws.QueryParameter("state", "opaque value used to maintain state between the request and the callback").Required(true)).
+		Returns(http.StatusOK, ok, models.Token{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.IdentityManagementTag}))
 	ws.Route(ws.GET("/users/{user}").
 		To(iam.DescribeUser).
 		Doc("Describe the specified user.").
@@ -223,6 +261,28 @@ func addWebService(c *restful.Container) error {
 		Doc("Get the mapping relationships between namespaced roles and policy rules.").
 		Returns(http.StatusOK, ok, policy.RoleRuleMapping).
 		Metadata(restfulspec.KeyOpenAPITags, []string{constants.AccessManagementTag}))
+	ws.Route(ws.POST("/accessreviews").
+		To(iam.CreateAccessReview).
+		Doc("Review whether the user is allowed to perform the action in the cluster, workspace, namespace or devops project, the current user and the groups of the current user are reviewed if the user is not specified. "+
+			"Roles granted to the groups of the user and to service accounts named system:serviceaccount:<namespace>:<name> are included. "+
+			"Users can always review their own access, reviewing the access of other users requires the permission to get the users.").
+		Reads(iammodel.AccessReview{}).
+		Returns(http.StatusOK, ok, iammodel.AccessReview{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.AccessManagementTag}))
+	ws.Route(ws.GET("/whocan").
+		To(iam.WhoCan).
+		Doc("List the users, service accounts and groups which are allowed to perform the action and the roles that allow it.").
+		Param(ws.QueryParameter("verb", "kubernetes verb e.g. get, list, delete, or devops action e.g. trigger").Required(true)).
+		Param(ws.QueryParameter("apigroup", "api group of the resource, empty for core group").Required(false)).
+		Param(ws.QueryParameter("resource", "resource type e.g. deployments").Required(false)).
+		Param(ws.QueryParameter("subresource", "subresource e.g. log").Required(false)).
+		Param(ws.QueryParameter("name", "resource name").Required(false)).
+		Param(ws.QueryParameter("nonresourceurl", "non resource url e.g. /healthz, only available in cluster scope").Required(false)).
+		Param(ws.QueryParameter("workspace", "workspace name").Required(false)).
+		Param(ws.QueryParameter("namespace", "namespace name").Required(false)).
+		Param(ws.QueryParameter("devops", "devops project id").Required(false)).
+		Returns(http.StatusOK, ok, []iammodel.SubjectAccess{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.AccessManagementTag}))
 	ws.Route(ws.GET("/workspaces/{workspace}/roles").
 		To(iam.ListWorkspaceRoles).
 		Doc("List all workspace roles.").
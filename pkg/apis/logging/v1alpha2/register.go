@@ -25,8 +25,9 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
 	"kubesphere.io/kubesphere/pkg/filter"
 	"kubesphere.io/kubesphere/pkg/models/log"
-	esclient "kubesphere.io/kubesphere/pkg/simple/client/elasticsearch"
+	"kubesphere.io/kubesphere/pkg/params"
 	fluentbitclient "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
+	loggingclient "kubesphere.io/kubesphere/pkg/simple/client/logging"
 	"net/http"
 )
 
@@ -48,7 +49,10 @@ func addWebService(c *restful.Container) error {
 	ws.Route(ws.GET("/cluster").To(logging.LoggingQueryCluster).
 		Filter(filter.Logging).
 		Doc("Query logs against the cluster.").
-		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram, export. Export downloads all logs matching the filters as a file in ascending order of time unless sort is given, from and size are ignored. If an error occurs after logs were written, the file ends with an error record and the error is sent in the X-Export-Error trailer.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("format", "Format of exported logs. One of ndjson, csv, text. Valid only if operation is export.").DataType("string").DefaultValue("ndjson").Required(false)).
+		Param(ws.QueryParameter("gzip", "Compress exported logs with gzip. Valid only if operation is export.").DataType("boolean").DefaultValue("false").Required(false)).
+		Param(ws.QueryParameter("follow", "Stream new logs matching the filters since start_time (default to now) over websocket or chunked http, one log record per message or line. Logs of a single pod are streamed from the kubelet if Elasticsearch is not configured.").DataType("boolean").DefaultValue("false").Required(false)).
 		Param(ws.QueryParameter("workspaces", "List of workspaces, separated by comma, the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("workspace_query", "List of keywords, separated by comma, for filtering workspaces. Workspaces whose name contains at least one keyword will be matched for query. Non case-sensitive matching.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("namespaces", "List of namespaces, separated by comma, the query will perform against.").DataType("string").Required(false)).
@@ -60,15 +64,16 @@ func addWebService(c *restful.Container) error {
 		Param(ws.QueryParameter("containers", "List of containers, separated by comma, the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("container_query", "List of keywords, separated by comma, for filtering containers. Containers whose name contains at least one keyword will be matched for query. Non case-sensitive matching.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("log_query", "List of keywords, separated by comma, for filtering logs. The query returns log containing at least one keyword. Non case-sensitive matching. eg. err,INFO.").DataType("string").Required(false)).
+		Param(ws.QueryParameter(params.FilterParam, "Filter logs on structured fields parsed by Fluent Bit parsers and on namespace, pod, container, host, log. Supports AND(&&), OR(||), NOT(!), parentheses and operators =, !=, ~ (contains), !~, =~ (regular expression), >, >=, <, <=, in (...), notin (...), a field without operator checks its existence. eg. level in (error, fatal) AND http.status>=500.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("interval", "Count logs at intervals. Valid only if operation is histogram. The unit can be ms(milliseconds), s(seconds), m(minutes), h(hours), d(days), w(weeks), M(months), q(quarters), y(years). eg. 30m.").DataType("string").Required(false)).
-		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
-		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
+		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("sort", "Sort logs by timestamp. One of acs, desc.").DataType("string").DefaultValue("desc").Required(false)).
 		Param(ws.QueryParameter("from", "Beginning index of result to return. Use this option together with size.").DataType("integer").DefaultValue("0").Required(false)).
 		Param(ws.QueryParameter("size", "Size of result to return.").DataType("integer").DefaultValue("10").Required(false)).
 		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "query"}).
-		Writes(esclient.QueryResult{}).
-		Returns(http.StatusOK, RespOK, esclient.QueryResult{})).
+		Writes(loggingclient.QueryResult{}).
+		Returns(http.StatusOK, RespOK, loggingclient.QueryResult{})).
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON)
 
@@ -76,7 +81,10 @@ func addWebService(c *restful.Container) error {
 		Filter(filter.Logging).
 		Doc("Query logs against a specific workspace.").
 		Param(ws.PathParameter("workspace", "Perform query against a specific workspace.").DataType("string").Required(true)).
-		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram, export. Export downloads all logs matching the filters as a file in ascending order of time unless sort is given, from and size are ignored. If an error occurs after logs were written, the file ends with an error record and the error is sent in the X-Export-Error trailer.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("format", "Format of exported logs. One of ndjson, csv, text. Valid only if operation is export.").DataType("string").DefaultValue("ndjson").Required(false)).
+		Param(ws.QueryParameter("gzip", "Compress exported logs with gzip. Valid only if operation is export.").DataType("boolean").DefaultValue("false").Required(false)).
+		Param(ws.QueryParameter("follow", "Stream new logs matching the filters since start_time (default to now) over websocket or chunked http, one log record per message or line. Logs of a single pod are streamed from the kubelet if Elasticsearch is not configured.").DataType("boolean").DefaultValue("false").Required(false)).
 		Param(ws.QueryParameter("namespaces", "List of namespaces, separated by comma, the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("namespace_query", "List of keywords, separated by comma, for filtering namespaces. Namespaces whose name contains at least one keyword will be matched for query. Non case-sensitive matching.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("workloads", "List of workloads, separated by comma, the query will perform against.").DataType("string").Required(false)).
@@ -86,15 +94,16 @@ func addWebService(c *restful.Container) error {
 		Param(ws.QueryParameter("containers", "List of containers, separated by comma, the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("container_query", "List of keywords, separated by comma, for filtering containers. Containers whose name contains at least one keyword will be matched for query. Non case-sensitive matching.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("log_query", "List of keywords, separated by comma, for filtering logs. The query returns log containing at least one keyword. Non case-sensitive matching. eg. err,INFO.").DataType("string").Required(false)).
+		Param(ws.QueryParameter(params.FilterParam, "Filter logs on structured fields parsed by Fluent Bit parsers and on namespace, pod, container, host, log. Supports AND(&&), OR(||), NOT(!), parentheses and operators =, !=, ~ (contains), !~, =~ (regular expression), >, >=, <, <=, in (...), notin (...), a field without operator checks its existence. eg. level in (error, fatal) AND http.status>=500.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("interval", "Count logs at intervals. Valid only if operation is histogram. The unit can be ms(milliseconds), s(seconds), m(minutes), h(hours), d(days), w(weeks), M(months), q(quarters), y(years). eg. 30m.").DataType("string").Required(false)).
-		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
-		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
+		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("sort", "Sort logs by timestamp. One of acs, desc.").DataType("string").DefaultValue("desc").Required(false)).
 		Param(ws.QueryParameter("from", "Beginning index of result to return. Use this option together with size.").DataType("integer").DefaultValue("0").Required(false)).
 		Param(ws.QueryParameter("size", "Size of result to return.").DataType("integer").DefaultValue("10").Required(false)).
 		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "query"}).
-		Writes(esclient.QueryResult{}).
-		Returns(http.StatusOK, RespOK, esclient.QueryResult{})).
+		Writes(loggingclient.QueryResult{}).
+		Returns(http.StatusOK, RespOK, loggingclient.QueryResult{})).
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON)
 
@@ -102,7 +111,10 @@ func addWebService(c *restful.Container) error {
 		Filter(filter.Logging).
 		Doc("Query logs against a specific namespace.").
 		Param(ws.PathParameter("namespace", "Perform query against a specific namespace.").DataType("string").Required(true)).
-		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram, export. Export downloads all logs matching the filters as a file in ascending order of time unless sort is given, from and size are ignored. If an error occurs after logs were written, the file ends with an error record and the error is sent in the X-Export-Error trailer.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("format", "Format of exported logs. One of ndjson, csv, text. Valid only if operation is export.").DataType("string").DefaultValue("ndjson").Required(false)).
+		Param(ws.QueryParameter("gzip", "Compress exported logs with gzip. Valid only if operation is export.").DataType("boolean").DefaultValue("false").Required(false)).
+		Param(ws.QueryParameter("follow", "Stream new logs matching the filters since start_time (default to now) over websocket or chunked http, one log record per message or line. Logs of a single pod are streamed from the kubelet if Elasticsearch is not configured.").DataType("boolean").DefaultValue("false").Required(false)).
 		Param(ws.QueryParameter("workloads", "List of workloads, separated by comma, the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("workload_query", "List of keywords, separated by comma, for filtering workloads. Workloads whose name contains at least one keyword will be matched for query. Non case-sensitive matching.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("pods", "List of pods, separated by comma, the query will perform against.").DataType("string").Required(false)).
@@ -110,15 +122,16 @@ func addWebService(c *restful.Container) error {
 		Param(ws.QueryParameter("containers", "List of containers, separated by comma, the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("container_query", "List of keywords, separated by comma, for filtering containers. Containers whose name contains at least one keyword will be matched for query. Non case-sensitive matching.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("log_query", "List of keywords, separated by comma, for filtering logs. The query returns log containing at least one keyword. Non case-sensitive matching. eg. err,INFO.").DataType("string").Required(false)).
+		Param(ws.QueryParameter(params.FilterParam, "Filter logs on structured fields parsed by Fluent Bit parsers and on namespace, pod, container, host, log. Supports AND(&&), OR(||), NOT(!), parentheses and operators =, !=, ~ (contains), !~, =~ (regular expression), >, >=, <, <=, in (...), notin (...), a field without operator checks its existence. eg. level in (error, fatal) AND http.status>=500.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("interval", "Count logs at intervals. Valid only if operation is histogram. The unit can be ms(milliseconds), s(seconds), m(minutes), h(hours), d(days), w(weeks), M(months), q(quarters), y(years). eg. 30m.").DataType("string").Required(false)).
-		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
-		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
+		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("sort", "Sort logs by timestamp. One of acs, desc.").DataType("string").DefaultValue("desc").Required(false)).
 		Param(ws.QueryParameter("from", "Beginning index of result to return. Use this option together with size.").DataType("integer").DefaultValue("0").Required(false)).
 		Param(ws.QueryParameter("size", "Size of result to return.").DataType("integer").DefaultValue("10").Required(false)).
 		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "query"}).
-		Writes(esclient.QueryResult{}).
-		Returns(http.StatusOK, RespOK, esclient.QueryResult{})).
+		Writes(loggingclient.QueryResult{}).
+		Returns(http.StatusOK, RespOK, loggingclient.QueryResult{})).
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON)
 
@@ -127,21 +140,25 @@ func addWebService(c *restful.Container) error {
 		Doc("Query logs against a specific workload.").
 		Param(ws.PathParameter("namespace", "Specify the namespace of the workload.").DataType("string").Required(true)).
 		Param(ws.PathParameter("workload", "Perform query against a specific workload.").DataType("string").Required(true)).
-		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram, export. Export downloads all logs matching the filters as a file in ascending order of time unless sort is given, from and size are ignored. If an error occurs after logs were written, the file ends with an error record and the error is sent in the X-Export-Error trailer.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("format", "Format of exported logs. One of ndjson, csv, text. Valid only if operation is export.").DataType("string").DefaultValue("ndjson").Required(false)).
+		Param(ws.QueryParameter("gzip", "Compress exported logs with gzip. Valid only if operation is export.").DataType("boolean").DefaultValue("false").Required(false)).
+		Param(ws.QueryParameter("follow", "Stream new logs matching the filters since start_time (default to now) over websocket or chunked http, one log record per message or line. Logs of a single pod are streamed from the kubelet if Elasticsearch is not configured.").DataType("boolean").DefaultValue("false").Required(false)).
 		Param(ws.QueryParameter("pods", "List of pods, separated by comma, the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("pod_query", "List of keywords, separated by comma, for filtering pods. Pods whose name contains at least one keyword will be matched for query. Non case-sensitive matching.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("containers", "List of containers, separated by comma, the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("container_query", "List of keywords, separated by comma, for filtering containers. Containers whose name contains at least one keyword will be matched for query. Non case-sensitive matching.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("log_query", "List of keywords, separated by comma, for filtering logs. The query returns log containing at least one keyword. Non case-sensitive matching. eg. err,INFO.").DataType("string").Required(false)).
+		Param(ws.QueryParameter(params.FilterParam, "Filter logs on structured fields parsed by Fluent Bit parsers and on namespace, pod, container, host, log. Supports AND(&&), OR(||), NOT(!), parentheses and operators =, !=, ~ (contains), !~, =~ (regular expression), >, >=, <, <=, in (...), notin (...), a field without operator checks its existence. eg. level in (error, fatal) AND http.status>=500.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("interval", "Count logs at intervals. Valid only if operation is histogram. The unit can be ms(milliseconds), s(seconds), m(minutes), h(hours), d(days), w(weeks), M(months), q(quarters), y(years). eg. 30m.").DataType("string").Required(false)).
-		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
-		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
+		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("sort", "Sort logs by timestamp. One of acs, desc.").DataType("string").DefaultValue("desc").Required(false)).
 		Param(ws.QueryParameter("from", "Beginning index of result to return. Use this option together with size.").DataType("integer").DefaultValue("0").Required(false)).
 		Param(ws.QueryParameter("size", "Size of result to return.").DataType("integer").DefaultValue("10").Required(false)).
 		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "query"}).
-		Writes(esclient.QueryResult{}).
-		Returns(http.StatusOK, RespOK, esclient.QueryResult{})).
+		Writes(loggingclient.QueryResult{}).
+		Returns(http.StatusOK, RespOK, loggingclient.QueryResult{})).
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON)
 
@@ -150,19 +167,23 @@ func addWebService(c *restful.Container) error {
 		Doc("Query logs against a specific pod.").
 		Param(ws.PathParameter("namespace", "Specify the namespace of the pod.").DataType("string").Required(true)).
 		Param(ws.PathParameter("pod", "Perform query against a specific pod.").DataType("string").Required(true)).
-		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram, export. Export downloads all logs matching the filters as a file in ascending order of time unless sort is given, from and size are ignored. If an error occurs after logs were written, the file ends with an error record and the error is sent in the X-Export-Error trailer.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("format", "Format of exported logs. One of ndjson, csv, text. Valid only if operation is export.").DataType("string").DefaultValue("ndjson").Required(false)).
+		Param(ws.QueryParameter("gzip", "Compress exported logs with gzip. Valid only if operation is export.").DataType("boolean").DefaultValue("false").Required(false)).
+		Param(ws.QueryParameter("follow", "Stream new logs matching the filters since start_time (default to now) over websocket or chunked http, one log record per message or line. Logs of a single pod are streamed from the kubelet if Elasticsearch is not configured.").DataType("boolean").DefaultValue("false").Required(false)).
 		Param(ws.QueryParameter("containers", "List of containers, separated by comma, the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("container_query", "List of keywords, separated by comma, for filtering containers. Containers whose name contains at least one keyword will be matched for query. Non case-sensitive matching.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("log_query", "List of keywords, separated by comma, for filtering logs. The query returns log containing at least one keyword. Non case-sensitive matching. eg. err,INFO.").DataType("string").Required(false)).
+		Param(ws.QueryParameter(params.FilterParam, "Filter logs on structured fields parsed by Fluent Bit parsers and on namespace, pod, container, host, log. Supports AND(&&), OR(||), NOT(!), parentheses and operators =, !=, ~ (contains), !~, =~ (regular expression), >, >=, <, <=, in (...), notin (...), a field without operator checks its existence. eg. level in (error, fatal) AND http.status>=500.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("interval", "Count logs at intervals. Valid only if operation is histogram. The unit can be ms(milliseconds), s(seconds), m(minutes), h(hours), d(days), w(weeks), M(months), q(quarters), y(years). eg. 30m.").DataType("string").Required(false)).
-		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
-		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
+		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("sort", "Sort logs by timestamp. One of acs, desc.").DataType("string").DefaultValue("desc").Required(false)).
 		Param(ws.QueryParameter("from", "Beginning index of result to return. Use this option together with size.").DataType("integer").DefaultValue("0").Required(false)).
 		Param(ws.QueryParameter("size", "Size of result to return.").DataType("integer").DefaultValue("10").Required(false)).
 		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "query"}).
-		Writes(esclient.QueryResult{}).
-		Returns(http.StatusOK, RespOK, esclient.QueryResult{})).
+		Writes(loggingclient.QueryResult{}).
+		Returns(http.StatusOK, RespOK, loggingclient.QueryResult{})).
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON)
 
@@ -172,17 +193,42 @@ func addWebService(c *restful.Container) error {
 		Param(ws.PathParameter("namespace", "Specify the namespace of the pod.").DataType("string").Required(true)).
 		Param(ws.PathParameter("pod", "Specify the pod of the container.").DataType("string").Required(true)).
 		Param(ws.PathParameter("container", "Perform query against a specific container.").DataType("string").Required(true)).
-		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram, export. Export downloads all logs matching the filters as a file in ascending order of time unless sort is given, from and size are ignored. If an error occurs after logs were written, the file ends with an error record and the error is sent in the X-Export-Error trailer.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("format", "Format of exported logs. One of ndjson, csv, text. Valid only if operation is export.").DataType("string").DefaultValue("ndjson").Required(false)).
+		Param(ws.QueryParameter("gzip", "Compress exported logs with gzip. Valid only if operation is export.").DataType("boolean").DefaultValue("false").Required(false)).
+		Param(ws.QueryParameter("follow", "Stream new logs matching the filters since start_time (default to now) over websocket or chunked http, one log record per message or line. Logs of a single pod are streamed from the kubelet if Elasticsearch is not configured.").DataType("boolean").DefaultValue("false").Required(false)).
 		Param(ws.QueryParameter("log_query", "List of keywords, separated by comma, for filtering logs. The query returns log containing at least one keyword. Non case-sensitive matching. eg. err,INFO.").DataType("string").Required(false)).
+		Param(ws.QueryParameter(params.FilterParam, "Filter logs on structured fields parsed by Fluent Bit parsers and on namespace, pod, container, host, log. Supports AND(&&), OR(||), NOT(!), parentheses and operators =, !=, ~ (contains), !~, =~ (regular expression), >, >=, <, <=, in (...), notin (...), a field without operator checks its existence. eg. level in (error, fatal) AND http.status>=500.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("interval", "Count logs at intervals. Valid only if operation is histogram. The unit can be ms(milliseconds), s(seconds), m(minutes), h(hours), d(days), w(weeks), M(months), q(quarters), y(years). eg. 30m.").DataType("string").Required(false)).
-		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
-		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000).").DataType("string").Required(false)).
+		Param(ws.QueryParameter("start_time", "Start of query range. Default to 0. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end_time", "End of query range. Default to now. This option accepts built-in formats supported by Elasticsearch, such as epoch_millis (eg. 1559664000000). With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("sort", "Sort logs by timestamp. One of acs, desc.").DataType("string").DefaultValue("desc").Required(false)).
 		Param(ws.QueryParameter("from", "Beginning index of result to return. Use this option together with size.").DataType("integer").DefaultValue("0").Required(false)).
 		Param(ws.QueryParameter("size", "Size of result to return.").DataType("integer").DefaultValue("10").Required(false)).
 		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "query"}).
-		Writes(esclient.QueryResult{}).
-		Returns(http.StatusOK, RespOK, esclient.QueryResult{})).
+		Writes(loggingclient.QueryResult{}).
+		Returns(http.StatusOK, RespOK, loggingclient.QueryResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/fluentbit/pipeline").To(logging.LoggingQueryFluentbitPipeline).
+		Filter(filter.Logging).
+		Doc("Get the Fluent bit pipeline of inputs, parsers, filters and outputs, with the Fluent bit configuration rendered from it.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Writes(log.FluentbitPipelineResult{}).
+		Returns(http.StatusOK, RespOK, log.FluentbitPipelineResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.PUT("/fluentbit/pipeline").To(logging.LoggingUpdateFluentbitPipeline).
+		Filter(filter.Logging).
+		Doc("Replace the Fluent bit pipeline. Each input, filter and output specifies exactly one typed plugin, which is validated before the Fluent bit configuration is rendered and applied. "+
+			"Before the pipeline is saved for the first time, it's imported from the legacy outputs and parsers ConfigMaps, changes are refused with 409 if settings of the ConfigMaps can't be imported.").
+		Param(ws.QueryParameter("dry_run", "Validate the pipeline and return the rendered Fluent bit configuration without applying it.").DataType("boolean").DefaultValue("false").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Reads(log.FluentbitPipeline{}).
+		Writes(log.FluentbitPipelineResult{}).
+		Returns(http.StatusOK, RespOK, log.FluentbitPipelineResult{})).
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON)
 
@@ -197,7 +243,7 @@ func addWebService(c *restful.Container) error {
 
 	ws.Route(ws.POST("/fluentbit/outputs").To(logging.LoggingInsertFluentbitOutput).
 		Filter(filter.Logging).
-		Doc("Add a new Fluent bit output plugin.").
+		Doc("Add a new Fluent bit output plugin. The parameters must be supported by one of the typed outputs of the pipeline: es, kafka, forward, http, stdout.").
 		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
 		Reads(fluentbitclient.OutputPlugin{}).
 		Writes(log.FluentbitOutputsResult{}).
@@ -226,6 +272,86 @@ func addWebService(c *restful.Container) error {
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON)
 
+	ws.Route(ws.GET("/fluentbit/parsers").To(logging.LoggingQueryFluentbitParsers).
+		Filter(filter.Logging).
+		Doc("List all Fluent bit parsers of structured logs.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Writes(log.FluentbitParsersResult{}).
+		Returns(http.StatusOK, RespOK, log.FluentbitParsersResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.POST("/fluentbit/parsers").To(logging.LoggingInsertFluentbitParser).
+		Filter(filter.Logging).
+		Doc("Add a new Fluent bit parser, logs of the workload are parsed into fields which can be queried with the filter parameter.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Reads(log.FluentbitParser{}).
+		Writes(log.FluentbitParsersResult{}).
+		Returns(http.StatusOK, RespOK, log.FluentbitParsersResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.PUT("/fluentbit/parsers/{parser}").To(logging.LoggingUpdateFluentbitParser).
+		Filter(filter.Logging).
+		Doc("Update a Fluent bit parser.").
+		Param(ws.PathParameter("parser", "Name of the parser to update.").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Reads(log.FluentbitParser{}).
+		Writes(log.FluentbitParsersResult{}).
+		Returns(http.StatusOK, RespOK, log.FluentbitParsersResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.DELETE("/fluentbit/parsers/{parser}").To(logging.LoggingDeleteFluentbitParser).
+		Filter(filter.Logging).
+		Doc("Delete a Fluent bit parser.").
+		Param(ws.PathParameter("parser", "Name of the parser to delete.").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Writes(log.FluentbitParsersResult{}).
+		Returns(http.StatusOK, RespOK, log.FluentbitParsersResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/retentions").To(logging.LoggingQueryRetentions).
+		Filter(filter.Logging).
+		Doc("List log retentions of all workspaces, with the number of indices and storage consumed by each workspace.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Writes(log.LogRetentionsResult{}).
+		Returns(http.StatusOK, RespOK, log.LogRetentionsResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/workspaces/{workspace}/retention").To(logging.LoggingQueryRetention).
+		Filter(filter.Logging).
+		Doc("Get the log retention of the workspace, with the number of indices and storage consumed.").
+		Param(ws.PathParameter("workspace", "Workspace name.").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Writes(log.LogRetentionsResult{}).
+		Returns(http.StatusOK, RespOK, log.LogRetentionsResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.PUT("/workspaces/{workspace}/retention").To(logging.LoggingUpdateRetention).
+		Filter(filter.Logging).
+		Doc("Set the log retention of the workspace. Logs of namespaces in the workspace are written into separate indices prefixed with <index>-ws-<workspace> by the enabled Elasticsearch output, indices older than days or exceeding max size are deleted periodically. Other outputs matching all tags also receive these logs.").
+		Param(ws.PathParameter("workspace", "Workspace name.").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Reads(log.LogRetention{}).
+		Writes(log.LogRetentionsResult{}).
+		Returns(http.StatusOK, RespOK, log.LogRetentionsResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.DELETE("/workspaces/{workspace}/retention").To(logging.LoggingDeleteRetention).
+		Filter(filter.Logging).
+		Doc("Remove the log retention of the workspace. Logs are written into the default indices again, existing indices of the workspace are kept.").
+		Param(ws.PathParameter("workspace", "Workspace name.").DataType("string").Required(true)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Logging", "setting"}).
+		Writes(log.LogRetentionsResult{}).
+		Returns(http.StatusOK, RespOK, log.LogRetentionsResult{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
 	c.Add(ws)
 	return nil
 }
@@ -0,0 +1,114 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	PanelTypeLine  = "line"
+	PanelTypeBar   = "bar"
+	PanelTypeGauge = "gauge"
+	PanelTypeTable = "table"
+)
+
+// PanelMetric is a series of a panel, only one of Name and Expr should be set
+type PanelMetric struct {
+	// Name of a built-in metric, e.g. namespace_cpu_usage
+	Name string `json:"name,omitempty"`
+
+	// Expr is a PromQL expression, it's scoped to the namespaces of the dashboard when evaluated
+	Expr string `json:"expr,omitempty"`
+
+	// Legend of the series, the metric name or expression by default
+	Legend string `json:"legend,omitempty"`
+}
+
+// Panel is a chart of metrics in the dashboard
+type Panel struct {
+	Title string `json:"title"`
+
+	// Type is one of line, bar, gauge, table, line by default
+	Type string `json:"type,omitempty"`
+
+	// Unit of values, e.g. bytes, cores, percent
+	Unit string `json:"unit,omitempty"`
+
+	Metrics []PanelMetric `json:"metrics"`
+}
+
+// DashboardSpec defines the desired state of Dashboard
+type DashboardSpec struct {
+	Title       string  `json:"title,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Panels      []Panel `json:"panels,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Dashboard is a set of panels charting metrics of its namespace
+// +k8s:openapi-gen=true
+type Dashboard struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              DashboardSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DashboardList contains a list of Dashboard
+type DashboardList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Dashboard `json:"items"`
+}
+
+// WorkspaceDashboardSpec defines the desired state of WorkspaceDashboard
+type WorkspaceDashboardSpec struct {
+	// Workspace whose namespaces are charted
+	Workspace string `json:"workspace"`
+
+	DashboardSpec `json:",inline"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// WorkspaceDashboard is a set of panels charting metrics of all namespaces in a workspace
+// +k8s:openapi-gen=true
+type WorkspaceDashboard struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              WorkspaceDashboardSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// WorkspaceDashboardList contains a list of WorkspaceDashboard
+type WorkspaceDashboardList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkspaceDashboard `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Dashboard{}, &DashboardList{}, &WorkspaceDashboard{}, &WorkspaceDashboardList{})
+}
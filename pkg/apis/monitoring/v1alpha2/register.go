@@ -323,6 +323,64 @@ func addWebService(c *restful.Container) error {
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON)
 
+	ws.Route(ws.GET("/namespaces/{namespace}/custom").To(monitoring.MonitorNamespaceCustomMetric).
+		Doc("Evaluate a PromQL expression against the namespace. Every vector selector of the expression is restricted to series of the namespace.").
+		Param(ws.PathParameter("namespace", "Specify the target namespace.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("query", "PromQL expression, eg. sum(rate(http_requests_total[5m])) by (code).").DataType("string").Required(true)).
+		Param(ws.QueryParameter("metrics_name", "Name of the metric in the result, default to custom.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("step", "Used to get metrics over a range of time. Query resolution step, eg. 10m, refer to Prometheus duration strings of the form [0-9]+[smhdwy].").DataType("string").DefaultValue("10m").Required(false)).
+		Param(ws.QueryParameter("start", "Used to get metrics over a range of time. Start of query range. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729. No default value. Must use start in pair with end.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end", "Used to get metrics over a range of time. End of query range. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729. No default value. Must use end in pair with start.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("time", "Used to get metrics at a given time point. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729.").DataType("string").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Monitoring", "namespace"}).
+		Writes(metrics.FormatedMetric{}).
+		Returns(http.StatusOK, RespOK, metrics.FormatedMetric{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/workspaces/{workspace}/custom").To(monitoring.MonitorWorkspaceCustomMetric).
+		Doc("Evaluate a PromQL expression against the workspace. Every vector selector of the expression is restricted to series of the namespaces in the workspace.").
+		Param(ws.PathParameter("workspace", "Specify the target workspace.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("query", "PromQL expression, eg. sum(rate(http_requests_total[5m])) by (code).").DataType("string").Required(true)).
+		Param(ws.QueryParameter("metrics_name", "Name of the metric in the result, default to custom.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("step", "Used to get metrics over a range of time. Query resolution step, eg. 10m, refer to Prometheus duration strings of the form [0-9]+[smhdwy].").DataType("string").DefaultValue("10m").Required(false)).
+		Param(ws.QueryParameter("start", "Used to get metrics over a range of time. Start of query range. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729. No default value. Must use start in pair with end.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end", "Used to get metrics over a range of time. End of query range. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729. No default value. Must use end in pair with start.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("time", "Used to get metrics at a given time point. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729.").DataType("string").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Monitoring", "workspace"}).
+		Writes(metrics.FormatedMetric{}).
+		Returns(http.StatusOK, RespOK, metrics.FormatedMetric{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/namespaces/{namespace}/dashboards/{dashboard}").To(monitoring.MonitorNamespaceDashboard).
+		Doc("Evaluate metrics of all panels of the Dashboard resource.").
+		Param(ws.PathParameter("namespace", "Specify the target namespace.").DataType("string").Required(true)).
+		Param(ws.PathParameter("dashboard", "Name of the Dashboard resource.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("step", "Used to get metrics over a range of time. Query resolution step, eg. 10m, refer to Prometheus duration strings of the form [0-9]+[smhdwy].").DataType("string").DefaultValue("10m").Required(false)).
+		Param(ws.QueryParameter("start", "Used to get metrics over a range of time. Start of query range. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729. No default value. Must use start in pair with end.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end", "Used to get metrics over a range of time. End of query range. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729. No default value. Must use end in pair with start.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("time", "Used to get metrics at a given time point. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729.").DataType("string").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Monitoring", "dashboard"}).
+		Writes(metrics.DashboardMetrics{}).
+		Returns(http.StatusOK, RespOK, metrics.DashboardMetrics{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/workspaces/{workspace}/dashboards/{dashboard}").To(monitoring.MonitorWorkspaceDashboard).
+		Doc("Evaluate metrics of all panels of the WorkspaceDashboard resource.").
+		Param(ws.PathParameter("workspace", "Specify the target workspace.").DataType("string").Required(true)).
+		Param(ws.PathParameter("dashboard", "Name of the WorkspaceDashboard resource.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("step", "Used to get metrics over a range of time. Query resolution step, eg. 10m, refer to Prometheus duration strings of the form [0-9]+[smhdwy].").DataType("string").DefaultValue("10m").Required(false)).
+		Param(ws.QueryParameter("start", "Used to get metrics over a range of time. Start of query range. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729. No default value. Must use start in pair with end.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("end", "Used to get metrics over a range of time. End of query range. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729. No default value. Must use end in pair with start.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("time", "Used to get metrics at a given time point. This option accepts epoch_second format, the number of seconds since the epoch, eg. 1559762729.").DataType("string").Required(false)).
+		Metadata(restfulspec.KeyOpenAPITags, []string{"Monitoring", "dashboard"}).
+		Writes(metrics.DashboardMetrics{}).
+		Returns(http.StatusOK, RespOK, metrics.DashboardMetrics{})).
+		Consumes(restful.MIME_JSON, restful.MIME_XML).
+		Produces(restful.MIME_JSON)
+
 	c.Add(ws)
 	return nil
 }
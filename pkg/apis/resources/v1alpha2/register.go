@@ -39,6 +39,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/models/applications"
 	gitmodel "kubesphere.io/kubesphere/pkg/models/git"
 	registriesmodel "kubesphere.io/kubesphere/pkg/models/registries"
+	resourcesmodel "kubesphere.io/kubesphere/pkg/models/resources"
 	"kubesphere.io/kubesphere/pkg/models/status"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/simple/client/openpitrix"
@@ -65,16 +66,31 @@ func addWebService(c *restful.Container) error {
 		Metadata(restfulspec.KeyOpenAPITags, []string{constants.NamespaceResourcesTag}).
 		Doc("Namespace level resource query").
 		Param(webservice.PathParameter("namespace", "which namespace")).
-		Param(webservice.PathParameter("resources", "namespace level resource type, e.g. pods,jobs,configmaps,services.")).
+		Param(webservice.PathParameter("resources", "namespace level resource type, e.g. pods,jobs,configmaps,services, or resources in the dynamic resources allow list e.g. virtualservices.networking.istio.io")).
 		Param(webservice.QueryParameter(params.ConditionsParam, "query conditions,connect multiple conditions with commas, equal symbol for exact query, wave symbol for fuzzy query e.g. name~a").
 			Required(false).
 			DataFormat("key=%s,key~%s")).
+		Param(webservice.QueryParameter(params.FilterParam, "filter expression, supports AND(&&), OR(||), NOT(!), parentheses and operators =, !=, ~, !~, =~ (regular expression), >, >=, <, <=, in (...), notin (...) on field paths, a field without operator checks its existence, e.g. (status=running OR spec.replicas>=3) AND createTime>2019-01-01 AND labels[app.kubernetes.io/name] in (nginx, redis)").
+			Required(false)).
 		Param(webservice.QueryParameter(params.PagingParam, "paging query, e.g. limit=100,page=1").
 			Required(false).
 			DataFormat("limit=%d,page=%d").
 			DefaultValue("limit=10,page=1")).
 		Param(webservice.QueryParameter(params.ReverseParam, "sort parameters, e.g. reverse=true")).
 		Param(webservice.QueryParameter(params.OrderByParam, "sort parameters, e.g. orderBy=createTime")).
+		Param(webservice.QueryParameter(params.LimitParam, "page size of continue token pagination, paging is ignored if limit or continue is specified").
+			Required(false).
+			DataType("integer")).
+		Param(webservice.QueryParameter(params.ContinueParam, "continue token in the previous response to retrieve the next page of the listing, continued requests should have the conditions and ordering of the first listing, 410 is returned if the position of the next page is lost").
+			Required(false)).
+		Param(webservice.QueryParameter(params.WatchParam, "watch the changes of the filtered resources as ADDED, MODIFIED and DELETED events over chunked http or websocket, e.g. watch=true").
+			Required(false).
+			DataType("boolean")).
+		Param(webservice.QueryParameter(params.ResourceVersionParam, "resource version of a previous listing to start the watch from, all resources are sent as ADDED events first if empty, 410 is returned if the changes after the resource version are no longer kept").
+			Required(false)).
+		Param(webservice.QueryParameter(params.TimeoutParam, "timeout of the watch in seconds").
+			Required(false).
+			DataType("integer")).
 		Returns(http.StatusOK, ok, models.PageableResponse{}))
 
 	webservice.Route(webservice.POST("/namespaces/{namespace}/jobs/{job}").
@@ -91,17 +107,32 @@ func addWebService(c *restful.Container) error {
 		Returns(http.StatusOK, ok, models.PageableResponse{}).
 		Metadata(restfulspec.KeyOpenAPITags, []string{constants.ClusterResourcesTag}).
 		Doc("Cluster level resource query").
-		Param(webservice.PathParameter("resources", "cluster level resource type, e.g. nodes,workspaces,storageclasses,clusterroles.")).
+		Param(webservice.PathParameter("resources", "cluster level resource type, e.g. nodes,workspaces,storageclasses,clusterroles, or resources in the dynamic resources allow list")).
 		Param(webservice.QueryParameter(params.ConditionsParam, "query conditions, connect multiple conditions with commas, equal symbol for exact query, wave symbol for fuzzy query e.g. name~a").
 			Required(false).
 			DataFormat("key=value,key~value").
 			DefaultValue("")).
+		Param(webservice.QueryParameter(params.FilterParam, "filter expression, supports AND(&&), OR(||), NOT(!), parentheses and operators =, !=, ~, !~, =~ (regular expression), >, >=, <, <=, in (...), notin (...) on field paths, a field without operator checks its existence, e.g. (status=running OR spec.replicas>=3) AND createTime>2019-01-01 AND labels[app.kubernetes.io/name] in (nginx, redis)").
+			Required(false)).
 		Param(webservice.QueryParameter(params.PagingParam, "paging query, e.g. limit=100,page=1").
 			Required(false).
 			DataFormat("limit=%d,page=%d").
 			DefaultValue("limit=10,page=1")).
 		Param(webservice.QueryParameter(params.ReverseParam, "sort parameters, e.g. reverse=true")).
-		Param(webservice.QueryParameter(params.OrderByParam, "sort parameters, e.g. orderBy=createTime")))
+		Param(webservice.QueryParameter(params.OrderByParam, "sort parameters, e.g. orderBy=createTime")).
+		Param(webservice.QueryParameter(params.LimitParam, "page size of continue token pagination, paging is ignored if limit or continue is specified").
+			Required(false).
+			DataType("integer")).
+		Param(webservice.QueryParameter(params.ContinueParam, "continue token in the previous response to retrieve the next page of the listing, continued requests should have the conditions and ordering of the first listing, 410 is returned if the position of the next page is lost").
+			Required(false)).
+		Param(webservice.QueryParameter(params.WatchParam, "watch the changes of the filtered resources as ADDED, MODIFIED and DELETED events over chunked http or websocket, e.g. watch=true").
+			Required(false).
+			DataType("boolean")).
+		Param(webservice.QueryParameter(params.ResourceVersionParam, "resource version of a previous listing to start the watch from, all resources are sent as ADDED events first if empty, 410 is returned if the changes after the resource version are no longer kept").
+			Required(false)).
+		Param(webservice.QueryParameter(params.TimeoutParam, "timeout of the watch in seconds").
+			Required(false).
+			DataType("integer")))
 
 	webservice.Route(webservice.POST("/nodes/{node}/drainage").
 		To(operations.DrainNode).
@@ -280,6 +311,36 @@ func addWebService(c *restful.Container) error {
 		Metadata(restfulspec.KeyOpenAPITags, []string{constants.NamespaceResourcesTag}).
 		Param(webservice.PathParameter("namespace", "name of the project")))
 
+	webservice.Route(webservice.GET("/aggregations").
+		To(resources.AggregateResources).
+		Doc("Count resources of the whole cluster grouped by the facets").
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.ClusterResourcesTag}).
+		Param(webservice.QueryParameter("resources", "comma separated resource types to aggregate, e.g. deployments,statefulsets,nodes, 404 is returned if any of them is not found and 403 if the user is not allowed to list any of them").
+			Required(true)).
+		Param(webservice.QueryParameter("facets", "comma separated facets to group by, supported facets are status, ownerKind, node, namespace, workspace and label:<key>, e.g. status,label:app").
+			Required(false)).
+		Param(webservice.QueryParameter(params.ConditionsParam, "query conditions, connect multiple conditions with commas, equal symbol for exact query, wave symbol for fuzzy query e.g. name~a").
+			Required(false).
+			DataFormat("key=value,key~value")).
+		Param(webservice.QueryParameter(params.FilterParam, "filter expression, e.g. status!=running AND createTime>2019-01-01").
+			Required(false)).
+		Returns(http.StatusOK, ok, map[string]resourcesmodel.Aggregation{}))
+	webservice.Route(webservice.GET("/namespaces/{namespace}/aggregations").
+		To(resources.AggregateNamespacedResources).
+		Doc("Count resources of the specified namespace grouped by the facets").
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.NamespaceResourcesTag}).
+		Param(webservice.PathParameter("namespace", "the name of namespace")).
+		Param(webservice.QueryParameter("resources", "comma separated resource types to aggregate, e.g. deployments,statefulsets,pods, 404 is returned if any of them is not found in the namespace and 403 if the user is not allowed to list any of them in the namespace").
+			Required(true)).
+		Param(webservice.QueryParameter("facets", "comma separated facets to group by, supported facets are status, ownerKind, node, namespace, workspace and label:<key>, e.g. status,label:app").
+			Required(false)).
+		Param(webservice.QueryParameter(params.ConditionsParam, "query conditions, connect multiple conditions with commas, equal symbol for exact query, wave symbol for fuzzy query e.g. name~a").
+			Required(false).
+			DataFormat("key=value,key~value")).
+		Param(webservice.QueryParameter(params.FilterParam, "filter expression, e.g. status!=running AND createTime>2019-01-01").
+			Required(false)).
+		Returns(http.StatusOK, ok, map[string]resourcesmodel.Aggregation{}))
+
 	webservice.Route(webservice.GET("/abnormalworkloads").
 		Doc("get abnormal workloads' count of whole cluster").
 		Metadata(restfulspec.KeyOpenAPITags, []string{constants.ClusterResourcesTag}).
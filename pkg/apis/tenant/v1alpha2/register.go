@@ -26,9 +26,10 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
 	"kubesphere.io/kubesphere/pkg/apiserver/tenant"
 	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/clusters"
 	"kubesphere.io/kubesphere/pkg/models/devops"
 	"kubesphere.io/kubesphere/pkg/params"
-	"kubesphere.io/kubesphere/pkg/simple/client/elasticsearch"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
 
 	"kubesphere.io/kubesphere/pkg/errors"
 	"kubesphere.io/kubesphere/pkg/models"
@@ -108,6 +109,18 @@ func addWebService(c *restful.Container) error {
 		Returns(http.StatusOK, ok, errors.Error{}).
 		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}))
 
+	ws.Route(ws.GET("/clusters").
+		To(tenant.ListClusters).
+		Doc("List the host cluster and the connected member clusters, requests can be sent to the clusters with the prefix /kapis/clusters/{cluster}").
+		Returns(http.StatusOK, ok, []string{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}))
+	ws.Route(ws.GET("/workspaces/{workspace}/clusters").
+		To(tenant.ListWorkspaceClusters).
+		Param(ws.PathParameter("workspace", "workspace name")).
+		Doc("List the namespaces of the workspace in each cluster").
+		Returns(http.StatusOK, ok, []clusters.ClusterNamespaces{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}))
+
 	ws.Route(ws.GET("/workspaces/{workspace}/devops").
 		To(tenant.ListDevopsProjects).
 		Param(ws.PathParameter("workspace", "workspace name")).
@@ -148,10 +161,47 @@ func addWebService(c *restful.Container) error {
 		Doc("Delete devops project").
 		Returns(http.StatusOK, RespOK, devops.DevOpsProject{}).
 		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}))
+	ws.Route(ws.GET("/workspaces/{workspace}/pipelinetemplates").
+		To(tenant.ListWorkspacePipelineTemplates).
+		Param(ws.PathParameter("workspace", "workspace name")).
+		Doc("List the pipeline templates of the workspace, templates available to all workspaces are not included").
+		Returns(http.StatusOK, RespOK, []devops.PipelineTemplate{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}))
+	ws.Route(ws.POST("/workspaces/{workspace}/pipelinetemplates").
+		To(tenant.CreateWorkspacePipelineTemplate).
+		Param(ws.PathParameter("workspace", "workspace name")).
+		Doc("Create a pipeline template available to the devops projects of the workspace").
+		Reads(devops.PipelineTemplateRequest{}).
+		Returns(http.StatusOK, RespOK, devops.PipelineTemplate{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}))
+	ws.Route(ws.PUT("/workspaces/{workspace}/pipelinetemplates/{template}").
+		To(tenant.UpdateWorkspacePipelineTemplate).
+		Param(ws.PathParameter("workspace", "workspace name")).
+		Param(ws.PathParameter("template", "name of the pipeline template")).
+		Doc("Update the pipeline template of the workspace, pipelines created from the template are upgraded by rollouts").
+		Reads(devops.PipelineTemplateRequest{}).
+		Returns(http.StatusOK, RespOK, devops.PipelineTemplate{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}))
+	ws.Route(ws.DELETE("/workspaces/{workspace}/pipelinetemplates/{template}").
+		To(tenant.DeleteWorkspacePipelineTemplate).
+		Param(ws.PathParameter("workspace", "workspace name")).
+		Param(ws.PathParameter("template", "name of the pipeline template")).
+		Doc("Delete the pipeline template of the workspace, pipelines created from the template are kept").
+		Returns(http.StatusOK, RespOK, errors.Error{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}))
+	ws.Route(ws.POST("/workspaces/{workspace}/pipelinetemplates/{template}/rollout").
+		To(tenant.RolloutWorkspacePipelineTemplate).
+		Param(ws.PathParameter("workspace", "workspace name")).
+		Param(ws.PathParameter("template", "name of the pipeline template")).
+		Doc("Render the pipelines created from older versions of the pipeline template of the workspace with the current version, pipelines failed to upgrade are returned with their errors. Pipelines modified after they are rendered are not upgraded.").
+		Returns(http.StatusOK, RespOK, []devops.TemplatePipeline{}).
+		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}))
 	ws.Route(ws.GET("/logs").
 		To(tenant.LogQuery).
 		Doc("Query cluster-level logs in a multi-tenants environment").
-		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("operation", "Query operation type. One of query, statistics, histogram, export. Export downloads all logs matching the filters as a file in ascending order of time unless sort is given, from and size are ignored. If an error occurs after logs were written, the file ends with an error record and the error is sent in the X-Export-Error trailer.").DataType("string").Required(true)).
+		Param(ws.QueryParameter("format", "Format of exported logs. One of ndjson, csv, text. Valid only if operation is export.").DataType("string").DefaultValue("ndjson").Required(false)).
+		Param(ws.QueryParameter("gzip", "Compress exported logs with gzip. Valid only if operation is export.").DataType("boolean").DefaultValue("false").Required(false)).
 		Param(ws.QueryParameter("workspaces", "List of workspaces separated by comma the query will perform against.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("workspace_query", "List of keywords for filtering workspaces. Workspaces whose name contains at least one keyword will be matched for query. Non case-sensitive matching. eg. one,two.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("namespaces", "List of namespaces the query will perform against, eg. ns-one,ns-two").DataType("string").Required(false)).
@@ -164,14 +214,14 @@ func addWebService(c *restful.Container) error {
 		Param(ws.QueryParameter("container_query", "List of keywords for filtering containers. Containers whose name contains at least one keyword will be matched for query. Non case-sensitive matching. eg. one,two.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("log_query", "List of keywords  for filtering logs. The query returns log containing at least one keyword. Non case-sensitive matching. eg. err,INFO.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("interval", "Count logs at intervals. Valid only if operation is histogram. The unit can be ms(milliseconds), s(seconds), m(minutes), h(hours), d(days), w(weeks), M(months), q(quarters), y(years). eg. 30m.").DataType("string").Required(false)).
-		Param(ws.QueryParameter("start_time", "Start time of query range, eg. 1559664000000.").DataType("string").Required(false)).
+		Param(ws.QueryParameter("start_time", "Start time of query range, eg. 1559664000000. With the Loki backend, it defaults to 30 days before end_time and 400 is returned if the range is longer than 30 days.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("end_time", "End time of query range, eg. 1559664000000.").DataType("string").Required(false)).
 		Param(ws.QueryParameter("sort", "Sort log by time. One of acs, desc.").DataType("string").DefaultValue("desc").Required(false)).
 		Param(ws.QueryParameter("from", "Beginning index of result to return. Use this option together with size.").DataType("integer").DefaultValue("0").Required(false)).
 		Param(ws.QueryParameter("size", "Size of result to return.").DataType("integer").DefaultValue("10").Required(false)).
 		Metadata(restfulspec.KeyOpenAPITags, []string{constants.TenantResourcesTag}).
-		Writes(esclient.Response{}).
-		Returns(http.StatusOK, RespOK, esclient.Response{})).
+		Writes(logging.QueryResult{}).
+		Returns(http.StatusOK, RespOK, logging.QueryResult{})).
 		Consumes(restful.MIME_JSON, restful.MIME_XML).
 		Produces(restful.MIME_JSON)
 
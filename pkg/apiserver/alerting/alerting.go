@@ -0,0 +1,133 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package alerting
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/alerting"
+)
+
+const (
+	defaultHistoryRange = 24 * time.Hour
+	defaultHistoryStep  = time.Minute
+)
+
+func ListAlerts(request *restful.Request, response *restful.Response) {
+	namespace := request.PathParameter("namespace")
+	policy := request.QueryParameter("policy")
+
+	res, err := alerting.ListAlerts(namespace, policy)
+
+	writeResult(response, res, err)
+}
+
+func ListAlertHistory(request *restful.Request, response *restful.Response) {
+	namespace := request.PathParameter("namespace")
+	policy := request.QueryParameter("policy")
+
+	end := time.Now()
+	if s := request.QueryParameter("end"); s != "" {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			response.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+			return
+		}
+		end = time.Unix(sec, 0)
+	}
+
+	start := end.Add(-defaultHistoryRange)
+	if s := request.QueryParameter("start"); s != "" {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			response.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+			return
+		}
+		start = time.Unix(sec, 0)
+	}
+
+	step := defaultHistoryStep
+	if s := request.QueryParameter("step"); s != "" {
+		var err error
+		if step, err = time.ParseDuration(s); err != nil {
+			response.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+			return
+		}
+	}
+
+	res, err := alerting.AlertHistory(request.Request.Context(), namespace, policy, start, end, step)
+
+	writeResult(response, res, err)
+}
+
+func ListAlertSilences(request *restful.Request, response *restful.Response) {
+	namespace := request.PathParameter("namespace")
+	policy := request.QueryParameter("policy")
+
+	res, err := alerting.ListAlertSilences(namespace, policy)
+
+	writeResult(response, res, err)
+}
+
+func CreateAlertSilence(request *restful.Request, response *restful.Response) {
+	namespace := request.PathParameter("namespace")
+	policy := request.PathParameter("alertpolicy")
+	username := request.HeaderParameter(constants.UserNameHeader)
+
+	silence := &alerting.AlertSilence{}
+	if err := request.ReadEntity(silence); err != nil {
+		response.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		return
+	}
+
+	id, err := alerting.CreateAlertSilence(namespace, policy, silence, username)
+
+	writeResult(response, &alerting.AlertSilenceCreateResult{ID: id}, err)
+}
+
+func ExpireAlertSilence(request *restful.Request, response *restful.Response) {
+	namespace := request.PathParameter("namespace")
+	id := request.PathParameter("silence")
+
+	err := alerting.ExpireAlertSilence(namespace, id)
+
+	writeResult(response, errors.None, err)
+}
+
+func writeResult(response *restful.Response, res interface{}, err error) {
+	if err != nil {
+		switch {
+		case k8serr.IsNotFound(err):
+			response.WriteHeaderAndEntity(http.StatusNotFound, errors.Wrap(err))
+		case k8serr.IsBadRequest(err):
+			response.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		default:
+			response.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+		}
+		return
+	}
+
+	response.WriteAsJson(res)
+}
@@ -0,0 +1,225 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package clusters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/clusters"
+	"kubesphere.io/kubesphere/pkg/models/resources"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+// Prefix of the requests to member clusters, e.g.
+// /kapis/clusters/{cluster}/resources.kubesphere.io/v1alpha2/namespaces/default/pods
+const Prefix = "/kapis/clusters/"
+
+// groups can be dispatched to member clusters
+var dispatchableGroups = []string{"resources.kubesphere.io", "monitoring.kubesphere.io", "logging.kubesphere.io", "tenant.kubesphere.io"}
+
+var requestInfoFactory = request.RequestInfoFactory{
+	APIPrefixes:          sets.NewString("api", "apis", "kapis", "kapi"),
+	GrouplessAPIPrefixes: sets.NewString("api")}
+
+type dispatcher struct {
+	handler    http.Handler
+	hostClient kubernetes.Interface
+}
+
+// NewDispatcher routes the requests with the cluster prefix to the member clusters, requests to
+// the host cluster are served by the handler without the prefix. The api gateway doesn't authorize
+// the requests with the cluster prefix, they are authorized against the RBAC of the target cluster.
+// Resources of member clusters are searched in the informers of the clusters, other requests are
+// proxied to the ks-apiserver of the member clusters.
+func NewDispatcher(handler http.Handler, hostClient kubernetes.Interface) http.Handler {
+	return &dispatcher{handler: handler, hostClient: hostClient}
+}
+
+func (d *dispatcher) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.URL.Path, Prefix) {
+		d.handler.ServeHTTP(w, req)
+		return
+	}
+
+	// cluster, group and the rest of the path
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, Prefix), "/", 3)
+
+	if len(parts) < 3 || !sliceutil.HasString(dispatchableGroups, parts[1]) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+		return
+	}
+
+	path := "/kapis/" + parts[1] + "/" + parts[2]
+
+	if parts[0] == clusters.HostCluster() {
+		if !d.authorize(w, req, d.hostClient, path) {
+			return
+		}
+		req.URL.Path = path
+		req.URL.RawPath = ""
+		d.handler.ServeHTTP(w, req)
+		return
+	}
+
+	cluster, ok := clusters.GetCluster(parts[0])
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", parts[0]))
+		return
+	}
+
+	if !d.authorize(w, req, cluster.Client, path) {
+		return
+	}
+
+	if isMemberResourcesRequest(req.Method, parts[1], parts[2]) {
+		if !cluster.Synced() {
+			writeError(w, http.StatusServiceUnavailable, fmt.Errorf("cluster %s is not synced", cluster.Name))
+			return
+		}
+		req = req.WithContext(clusters.WithCluster(req.Context(), cluster))
+		req.URL.Path = path
+		req.URL.RawPath = ""
+		d.handler.ServeHTTP(w, req)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			r.URL.Scheme = cluster.Endpoint.Scheme
+			r.URL.Host = cluster.Endpoint.Host
+			r.URL.Path = strings.TrimSuffix(cluster.Endpoint.Path, "/") + path
+			r.URL.RawPath = ""
+			r.Host = cluster.Endpoint.Host
+			// the token of the user is verified by the api gateway, requests to member clusters
+			// are authenticated with the credentials of the cluster
+			r.Header.Del("Authorization")
+		},
+		Transport: cluster.Transport,
+		// flush the responses of watch and log follow requests
+		FlushInterval: 100 * time.Millisecond,
+	}
+
+	proxy.ServeHTTP(w, req)
+}
+
+// isMemberResourcesRequest returns true if the request lists or aggregates the resources
+// searched in the informers of member clusters
+func isMemberResourcesRequest(method, group, path string) bool {
+	if method != http.MethodGet || group != "resources.kubesphere.io" {
+		return false
+	}
+
+	// version and the rest of the path
+	segments := strings.Split(path, "/")
+
+	if len(segments) == 4 && segments[1] == "namespaces" {
+		segments = []string{segments[0], segments[3]}
+	}
+
+	return len(segments) == 2 && (segments[1] == "aggregations" || resources.IsMemberResource(segments[1]))
+}
+
+// authorize checks the permission of the user with the RBAC of the cluster
+func (d *dispatcher) authorize(w http.ResponseWriter, req *http.Request, client kubernetes.Interface, path string) bool {
+	username := req.Header.Get(constants.UserNameHeader)
+
+	if username == "" {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return false
+	}
+
+	groups := []string{user.AllAuthenticated}
+
+	for _, group := range strings.Split(req.Header.Get(constants.UserGroupsHeader), ",") {
+		if group != "" {
+			groups = append(groups, group)
+		}
+	}
+
+	u := *req.URL
+	u.Path = path
+	u.RawPath = ""
+	r := *req
+	r.URL = &u
+
+	requestInfo, err := requestInfoFactory.NewRequestInfo(&r)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   username,
+			Groups: groups,
+		},
+	}
+
+	if requestInfo.IsResourceRequest {
+		review.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace:   requestInfo.Namespace,
+			Verb:        requestInfo.Verb,
+			Group:       requestInfo.APIGroup,
+			Version:     requestInfo.APIVersion,
+			Resource:    requestInfo.Resource,
+			Subresource: requestInfo.Subresource,
+			Name:        requestInfo.Name,
+		}
+	} else {
+		review.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+			Path: requestInfo.Path,
+			Verb: requestInfo.Verb,
+		}
+	}
+
+	review, err = client.AuthorizationV1().SubjectAccessReviews().Create(review)
+
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if !review.Status.Allowed {
+		writeError(w, http.StatusForbidden, fmt.Errorf("user %s is not allowed to %s %s", username, requestInfo.Verb, path))
+		return false
+	}
+
+	return true
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set(restful.HEADER_ContentType, restful.MIME_JSON)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errors.Wrap(err))
+}
@@ -0,0 +1,218 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package clusters
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	clusterv1alpha1 "kubesphere.io/kubesphere/pkg/apis/cluster/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/clusters"
+)
+
+// subjectAccessReview allows the users in the RBAC of the fake cluster
+func subjectAccessReview(users ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		review := authorizationv1.SubjectAccessReview{}
+		json.NewDecoder(r.Body).Decode(&review)
+		for _, user := range users {
+			if review.Spec.User == user {
+				review.Status.Allowed = true
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(review)
+	}
+}
+
+// newMemberCluster fakes the kube-apiserver of a member cluster, ks-apiserver is
+// served through the service proxy and echoes the requests. Credentials in kubeconfig
+// are only sent over https. Lists of other resources are empty.
+func newMemberCluster() *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "true" {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"metadata":{"resourceVersion":"1"},"items":[]}`)
+	})
+
+	mux.HandleFunc("/apis/authorization.k8s.io/v1/subjectaccessreviews", subjectAccessReview("member-admin"))
+
+	mux.HandleFunc("/api/v1/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "true" {
+			return
+		}
+		list := corev1.NamespaceList{
+			TypeMeta: metav1.TypeMeta{Kind: "NamespaceList", APIVersion: "v1"},
+			ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+			Items: []corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "member-ns", Labels: map[string]string{constants.WorkspaceLabelKey: "demo"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "other-ns"}},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	})
+
+	mux.HandleFunc("/api/v1/namespaces/kubesphere-system/services/ks-apiserver:80/proxy/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", r.URL.Path, r.Header.Get("Authorization"), r.Header.Get(constants.UserNameHeader))
+	})
+
+	return httptest.NewTLSServer(mux)
+}
+
+// newHostCluster fakes the kube-apiserver of the host cluster holding the kubeconfig of the member cluster
+func newHostCluster(kubeconfig []byte) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v1/namespaces/kubesphere-system/secrets/member-kubeconfig", func(w http.ResponseWriter, r *http.Request) {
+		secret := corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "member-kubeconfig", Namespace: "kubesphere-system", ResourceVersion: "1"},
+			Data:       map[string][]byte{clusterv1alpha1.KubeConfigSecretKey: kubeconfig},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(secret)
+	})
+
+	mux.HandleFunc("/apis/authorization.k8s.io/v1/subjectaccessreviews", subjectAccessReview("host-admin"))
+
+	return httptest.NewServer(mux)
+}
+
+func kubeconfig(server string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: member
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+users:
+- name: admin
+  user:
+    token: member-token
+contexts:
+- name: member
+  context:
+    cluster: member
+    user: admin
+current-context: member
+`, server))
+}
+
+func TestDispatcher(t *testing.T) {
+	member := newMemberCluster()
+	defer member.Close()
+
+	host := newHostCluster(kubeconfig(member.URL))
+	defer host.Close()
+
+	hostClient := kubernetes.NewForConfigOrDie(&rest.Config{Host: host.URL})
+
+	cluster := &clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "member", ResourceVersion: "1"},
+		Spec: clusterv1alpha1.ClusterSpec{Connection: clusterv1alpha1.Connection{
+			KubeConfigSecretRef: &corev1.SecretReference{Name: "member-kubeconfig"},
+		}},
+	}
+
+	if !assert.NoError(t, clusters.Connect(cluster, hostClient)) {
+		return
+	}
+	defer clusters.Disconnect(cluster.Name)
+
+	connected, _ := clusters.GetCluster(cluster.Name)
+
+	err := wait.PollImmediate(100*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return connected.Synced(), nil
+	})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	local := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cluster, ok := clusters.FromContext(r.Context()); ok {
+			fmt.Fprintf(w, "%s %s", cluster.Name, r.URL.Path)
+			return
+		}
+		fmt.Fprintf(w, "local %s", r.URL.Path)
+	})
+
+	dispatcher := NewDispatcher(local, hostClient)
+
+	for _, test := range []struct {
+		path string
+		user string
+		code int
+		body string
+	}{
+		{"/kapis/resources.kubesphere.io/v1alpha2/namespaces/default/pods", "", http.StatusOK, "local /kapis/resources.kubesphere.io/v1alpha2/namespaces/default/pods"},
+		{"/kapis/clusters/member/resources.kubesphere.io/v1alpha2/namespaces/default/pods", "member-admin", http.StatusOK,
+			"member /kapis/resources.kubesphere.io/v1alpha2/namespaces/default/pods"},
+		{"/kapis/clusters/member/resources.kubesphere.io/v1alpha2/namespaces/default/deployments/demo/revisions/1", "member-admin", http.StatusOK,
+			"/api/v1/namespaces/kubesphere-system/services/ks-apiserver:80/proxy/kapis/resources.kubesphere.io/v1alpha2/namespaces/default/deployments/demo/revisions/1 Bearer member-token member-admin"},
+		{"/kapis/clusters/member/monitoring.kubesphere.io/v1alpha2/cluster", "member-admin", http.StatusOK,
+			"/api/v1/namespaces/kubesphere-system/services/ks-apiserver:80/proxy/kapis/monitoring.kubesphere.io/v1alpha2/cluster Bearer member-token member-admin"},
+		// users are authorized against the RBAC of the member cluster
+		{"/kapis/clusters/member/resources.kubesphere.io/v1alpha2/namespaces/default/pods", "host-admin", http.StatusForbidden, ""},
+		{"/kapis/clusters/member/monitoring.kubesphere.io/v1alpha2/cluster", "host-admin", http.StatusForbidden, ""},
+		{"/kapis/clusters/member/resources.kubesphere.io/v1alpha2/namespaces/default/pods", "", http.StatusUnauthorized, ""},
+		{"/kapis/clusters/unknown/resources.kubesphere.io/v1alpha2/namespaces/default/pods", "member-admin", http.StatusNotFound, ""},
+		{"/kapis/clusters/member/iam.kubesphere.io/v1alpha2/users", "member-admin", http.StatusNotFound, ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Header.Set("Authorization", "Bearer user-token")
+		if test.user != "" {
+			req.Header.Set(constants.UserNameHeader, test.user)
+		}
+		recorder := httptest.NewRecorder()
+
+		dispatcher.ServeHTTP(recorder, req)
+
+		assert.Equal(t, test.code, recorder.Code, test.path)
+		if test.body != "" {
+			body, _ := ioutil.ReadAll(recorder.Body)
+			assert.Equal(t, test.body, string(body), test.path)
+		}
+	}
+
+	namespaces, err := clusters.WorkspaceNamespaces("demo")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, []clusters.ClusterNamespaces{{Cluster: "member", Namespaces: []string{"member-ns"}}}, namespaces)
+	}
+}
@@ -156,6 +156,10 @@ func Validate(req *restful.Request, resp *restful.Response) {
 
 func GetSCMOrg(req *restful.Request, resp *restful.Response) {
 	scmId := req.PathParameter("scm")
+	if devops.IsSCMProvider(scmId) {
+		getSCMProviderOrgs(req, resp)
+		return
+	}
 
 	res, err := devops.GetSCMOrg(scmId, req.Request)
 	if err != nil {
@@ -170,6 +174,10 @@ func GetSCMOrg(req *restful.Request, resp *restful.Response) {
 func GetOrgRepo(req *restful.Request, resp *restful.Response) {
 	scmId := req.PathParameter("scm")
 	organizationId := req.PathParameter("organization")
+	if devops.IsSCMProvider(scmId) {
+		getSCMProviderOrgRepos(req, resp)
+		return
+	}
 
 	res, err := devops.GetOrgRepo(scmId, organizationId, req.Request)
 	if err != nil {
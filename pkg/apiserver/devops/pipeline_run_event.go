@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+	"github.com/gorilla/websocket"
+	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/devops"
+)
+
+// interval of comments sent to keep idle event streams open through proxies
+var eventStreamKeepAlive = 30 * time.Second
+
+var upgrader = websocket.Upgrader{
+	// authentication is done by the api gateway, which accepts the token cookie
+	// sent by browsers, so only websockets of the console are accepted
+	CheckOrigin: runtime.CheckOrigin,
+}
+
+// WatchPipelineRunEventsHandler streams the run, stage and step events of pipelines in the project,
+// one event per websocket message or as server-sent events
+func WatchPipelineRunEventsHandler(request *restful.Request, resp *restful.Response) {
+	projectId := request.PathParameter("devops")
+	username := request.HeaderParameter(constants.UserNameHeader)
+	pipeline := request.QueryParameter("pipeline")
+
+	err := devops.CheckProjectUserInRole(username, projectId, devops.AllRoleSlice)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, err.Error()), resp)
+		return
+	}
+
+	events, cancel := devops.SubscribePipelineRunEvents(projectId)
+	defer cancel()
+
+	if websocket.IsWebSocketUpgrade(request.Request) {
+		conn, err := upgrader.Upgrade(resp.ResponseWriter, request.Request, nil)
+		if err != nil {
+			glog.Errorln("upgrade websocket", err)
+			return
+		}
+		defer conn.Close()
+
+		closed := make(chan struct{})
+
+		// messages from clients are discarded, watching is stopped once the connection is closed
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case event := <-events:
+				if pipeline != "" && event.Pipeline != pipeline {
+					continue
+				}
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}
+
+	flusher, ok := resp.ResponseWriter.(http.Flusher)
+	if !ok {
+		errors.ParseSvcErr(restful.NewError(http.StatusInternalServerError, "streaming is not supported"), resp)
+		return
+	}
+
+	resp.Header().Set(restful.HEADER_ContentType, "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			if pipeline != "" && event.Pipeline != pipeline {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				glog.Errorf("%+v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(resp, "event: %s.%s\ndata: %s\n\n", event.Kind, event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(resp, ":\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-request.Request.Context().Done():
+			return
+		}
+	}
+}
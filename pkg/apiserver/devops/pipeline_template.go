@@ -0,0 +1,173 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/devops"
+	"net/http"
+)
+
+func GetPipelineTemplatesHandler(request *restful.Request, resp *restful.Response) {
+	projectId := request.PathParameter("devops")
+	username := request.HeaderParameter(constants.UserNameHeader)
+
+	err := devops.CheckProjectUserInRole(username, projectId, devops.AllRoleSlice)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, err.Error()), resp)
+		return
+	}
+	templates, err := devops.GetPipelineTemplates(projectId)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+	resp.WriteAsJson(templates)
+}
+
+func GetPipelineTemplateHandler(request *restful.Request, resp *restful.Response) {
+	projectId := request.PathParameter("devops")
+	username := request.HeaderParameter(constants.UserNameHeader)
+	templateName := request.PathParameter("template")
+
+	err := devops.CheckProjectUserInRole(username, projectId, devops.AllRoleSlice)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, err.Error()), resp)
+		return
+	}
+	template, err := devops.GetPipelineTemplate(projectId, templateName)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+	resp.WriteAsJson(template)
+}
+
+func CreateTemplatePipelineHandler(request *restful.Request, resp *restful.Response) {
+	projectId := request.PathParameter("devops")
+	username := request.HeaderParameter(constants.UserNameHeader)
+	templateName := request.PathParameter("template")
+	var pipelineRequest *devops.TemplatePipelineRequest
+	err := request.ReadEntity(&pipelineRequest)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusBadRequest, err.Error()), resp)
+		return
+	}
+	err = devops.CheckProjectUserInRole(username, projectId, []string{devops.ProjectOwner, devops.ProjectMaintainer})
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, err.Error()), resp)
+		return
+	}
+	pipeline, err := devops.CreateTemplatePipeline(projectId, username, templateName, pipelineRequest)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+	resp.WriteAsJson(pipeline)
+}
+
+func GetTemplatePipelinesHandler(request *restful.Request, resp *restful.Response) {
+	projectId := request.PathParameter("devops")
+	username := request.HeaderParameter(constants.UserNameHeader)
+	templateName := request.PathParameter("template")
+
+	err := devops.CheckProjectUserInRole(username, projectId, []string{devops.ProjectOwner, devops.ProjectMaintainer})
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, err.Error()), resp)
+		return
+	}
+	pipelines, err := devops.GetTemplatePipelines(projectId, templateName)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+	resp.WriteAsJson(pipelines)
+}
+
+func UpgradeTemplatePipelineHandler(request *restful.Request, resp *restful.Response) {
+	projectId := request.PathParameter("devops")
+	username := request.HeaderParameter(constants.UserNameHeader)
+	templateName := request.PathParameter("template")
+	pipelineName := request.PathParameter("pipeline")
+
+	// the values of the last rendering are used without the body
+	pipelineRequest := &devops.TemplatePipelineRequest{}
+	if request.Request.ContentLength != 0 {
+		if err := request.ReadEntity(pipelineRequest); err != nil {
+			glog.Errorf("%+v", err)
+			errors.ParseSvcErr(restful.NewError(http.StatusBadRequest, err.Error()), resp)
+			return
+		}
+	}
+	err := devops.CheckProjectUserInRole(username, projectId, []string{devops.ProjectOwner, devops.ProjectMaintainer})
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, err.Error()), resp)
+		return
+	}
+	force := request.QueryParameter("force") == "true"
+	pipeline, err := devops.UpgradeTemplatePipeline(projectId, pipelineName, templateName, pipelineRequest.Parameters, force)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+	resp.WriteAsJson(pipeline)
+}
+
+func GetAllTemplatePipelinesHandler(request *restful.Request, resp *restful.Response) {
+	username := request.HeaderParameter(constants.UserNameHeader)
+	templateName := request.PathParameter("template")
+	if username != devops.KS_ADMIN {
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, "only admin can list pipelines of all devops projects"), resp)
+		return
+	}
+
+	pipelines, err := devops.GetTemplatePipelines("", templateName)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+	resp.WriteAsJson(pipelines)
+}
+
+func RolloutPipelineTemplateHandler(request *restful.Request, resp *restful.Response) {
+	username := request.HeaderParameter(constants.UserNameHeader)
+	templateName := request.PathParameter("template")
+	if username != devops.KS_ADMIN {
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, "only admin can roll out pipeline templates"), resp)
+		return
+	}
+
+	pipelines, err := devops.RolloutPipelineTemplate("", templateName)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+	resp.WriteAsJson(pipelines)
+}
@@ -80,3 +80,37 @@ func GetDevOpsProjectDefaultRoles(request *restful.Request, resp *restful.Respon
 	resp.WriteAsJson(devops.DefaultRoles)
 	return
 }
+
+func GetDevOpsProjectOrphansHandler(request *restful.Request, resp *restful.Response) {
+	username := request.HeaderParameter(constants.UserNameHeader)
+	if username != devops.KS_ADMIN {
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, "only admin can check devops project orphans"), resp)
+		return
+	}
+
+	orphans, err := devops.GetProjectOrphans()
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusServiceUnavailable, err.Error()), resp)
+		return
+	}
+
+	resp.WriteAsJson(orphans)
+}
+
+func RepairDevOpsProjectOrphansHandler(request *restful.Request, resp *restful.Response) {
+	username := request.HeaderParameter(constants.UserNameHeader)
+	if username != devops.KS_ADMIN {
+		errors.ParseSvcErr(restful.NewError(http.StatusForbidden, "only admin can repair devops project orphans"), resp)
+		return
+	}
+
+	orphans, err := devops.RepairProjectOrphans()
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusServiceUnavailable, err.Error()), resp)
+		return
+	}
+
+	resp.WriteAsJson(orphans)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/devops"
+	"net/http"
+	"strconv"
+)
+
+const defaultSCMPageSize = 20
+
+func getSCMProviderOrgs(req *restful.Request, resp *restful.Response) {
+	scm := req.PathParameter("scm")
+	projectId, pageNumber, pageSize, err := checkSCMProviderRequest(req)
+	if err != nil {
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	orgs, err := devops.GetSCMProviderOrgs(scm, projectId, req.QueryParameter("credentialId"), req.QueryParameter("apiUrl"),
+		pageNumber, pageSize)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	resp.WriteAsJson(orgs)
+}
+
+func getSCMProviderOrgRepos(req *restful.Request, resp *restful.Response) {
+	scm := req.PathParameter("scm")
+	org := req.PathParameter("organization")
+	projectId, pageNumber, pageSize, err := checkSCMProviderRequest(req)
+	if err != nil {
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	repos, err := devops.GetSCMProviderOrgRepos(scm, projectId, req.QueryParameter("credentialId"), req.QueryParameter("apiUrl"),
+		org, pageNumber, pageSize)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	resp.WriteAsJson(repos)
+}
+
+// checkSCMProviderRequest checks the user could use credentials of the project and returns the project and the page
+func checkSCMProviderRequest(req *restful.Request) (string, int, int, error) {
+	username := req.HeaderParameter(constants.UserNameHeader)
+	projectId := req.QueryParameter("devops")
+	if projectId == "" || req.QueryParameter("credentialId") == "" {
+		return "", 0, 0, restful.NewError(http.StatusBadRequest, "devops and credentialId should not be empty")
+	}
+	err := devops.CheckProjectUserInRole(username, projectId, []string{devops.ProjectOwner, devops.ProjectMaintainer})
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return "", 0, 0, restful.NewError(http.StatusForbidden, err.Error())
+	}
+
+	pageNumber, pageSize := 1, defaultSCMPageSize
+	if value := req.QueryParameter("pageNumber"); value != "" {
+		if pageNumber, err = strconv.Atoi(value); err != nil || pageNumber < 1 {
+			return "", 0, 0, restful.NewError(http.StatusBadRequest, "invalid pageNumber")
+		}
+	}
+	if value := req.QueryParameter("pageSize"); value != "" {
+		if pageSize, err = strconv.Atoi(value); err != nil || pageSize < 1 {
+			return "", 0, 0, restful.NewError(http.StatusBadRequest, "invalid pageSize")
+		}
+	}
+	return projectId, pageNumber, pageSize, nil
+}
+
+func SCMWebhook(req *restful.Request, resp *restful.Response) {
+	res, err := devops.SCMWebhook(req.PathParameter("scm"), resp.ResponseWriter, req.Request)
+	if err != nil {
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+	resp.Write(res)
+}
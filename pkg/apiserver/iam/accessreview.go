@@ -0,0 +1,104 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package iam
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/emicklei/go-restful"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/iam"
+)
+
+func CreateAccessReview(req *restful.Request, resp *restful.Response) {
+	var review iam.AccessReview
+
+	if err := req.ReadEntity(&review); err != nil {
+		resp.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		return
+	}
+
+	requester := req.HeaderParameter(constants.UserNameHeader)
+	var requesterGroups []string
+	for _, group := range strings.Split(req.HeaderParameter(constants.UserGroupsHeader), ",") {
+		if group != "" {
+			requesterGroups = append(requesterGroups, group)
+		}
+	}
+
+	allowed, err := iam.CanReviewAccess(requester, requesterGroups, review.Spec.User)
+
+	if err != nil {
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	if !allowed {
+		resp.WriteHeaderAndEntity(http.StatusForbidden, errors.New(fmt.Sprintf("user %s is not allowed to review the access of user %s", requester, review.Spec.User)))
+		return
+	}
+
+	// the groups of the current user are reviewed in self reviews
+	if review.Spec.User == "" || review.Spec.User == requester {
+		review.Spec.User = requester
+		review.Spec.Groups = requesterGroups
+	}
+
+	if review.Spec.User == "" {
+		resp.WriteHeaderAndEntity(http.StatusBadRequest, errors.New("user must be specified"))
+		return
+	}
+
+	status, err := iam.ReviewAccess(review.Spec)
+
+	if err != nil {
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	review.Status = status
+
+	resp.WriteAsJson(review)
+}
+
+func WhoCan(req *restful.Request, resp *restful.Response) {
+	attrs := iam.AccessAttributes{
+		Workspace:      req.QueryParameter("workspace"),
+		Namespace:      req.QueryParameter("namespace"),
+		DevOps:         req.QueryParameter("devops"),
+		Verb:           req.QueryParameter("verb"),
+		APIGroup:       req.QueryParameter("apigroup"),
+		Resource:       req.QueryParameter("resource"),
+		Subresource:    req.QueryParameter("subresource"),
+		Name:           req.QueryParameter("name"),
+		NonResourceURL: req.QueryParameter("nonresourceurl"),
+	}
+
+	subjects, err := iam.WhoCan(attrs)
+
+	if err != nil {
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	resp.WriteAsJson(subjects)
+}
@@ -21,12 +21,16 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/emicklei/go-restful"
 	"github.com/golang/glog"
+	"kubesphere.io/kubesphere/pkg/simple/client/redis"
 	"kubesphere.io/kubesphere/pkg/utils/iputil"
 	"kubesphere.io/kubesphere/pkg/utils/jwtutil"
 	"net/http"
+	"sort"
+	"strings"
 
 	"kubesphere.io/kubesphere/pkg/errors"
 	"kubesphere.io/kubesphere/pkg/models/iam"
+	"kubesphere.io/kubesphere/pkg/models/iam/identityprovider"
 )
 
 type Spec struct {
@@ -50,6 +54,10 @@ type LoginRequest struct {
 	Password string `json:"password" description:"password"`
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" description:"refresh token"`
+}
+
 const (
 	APIVersion      = "authentication.k8s.io/v1beta1"
 	KindTokenReview = "TokenReview"
@@ -81,6 +89,121 @@ func Login(req *restful.Request, resp *restful.Response) {
 	resp.WriteAsJson(token)
 }
 
+func RefreshToken(req *restful.Request, resp *restful.Response) {
+	var refreshTokenRequest RefreshTokenRequest
+
+	err := req.ReadEntity(&refreshTokenRequest)
+
+	if err != nil || refreshTokenRequest.RefreshToken == "" {
+		resp.WriteHeaderAndEntity(http.StatusUnauthorized, errors.New("invalid refresh token"))
+		return
+	}
+
+	token, err := iam.RefreshToken(refreshTokenRequest.RefreshToken)
+
+	if err != nil {
+		if serviceError, ok := err.(restful.ServiceError); ok {
+			resp.WriteHeaderAndEntity(serviceError.Code, errors.New(serviceError.Message))
+			return
+		}
+		resp.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+		return
+	}
+
+	resp.WriteAsJson(token)
+}
+
+func Logout(req *restful.Request, resp *restful.Response) {
+	var refreshTokenRequest RefreshTokenRequest
+
+	// refresh token is optional
+	req.ReadEntity(&refreshTokenRequest)
+
+	accessToken := extractToken(req.Request)
+
+	if accessToken == "" && refreshTokenRequest.RefreshToken == "" {
+		resp.WriteHeaderAndEntity(http.StatusUnauthorized, errors.New("no token found"))
+		return
+	}
+
+	err := iam.Logout(accessToken, refreshTokenRequest.RefreshToken)
+
+	if err != nil {
+		if serviceError, ok := err.(restful.ServiceError); ok {
+			resp.WriteHeaderAndEntity(serviceError.Code, errors.New(serviceError.Message))
+			return
+		}
+		resp.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+		return
+	}
+
+	resp.WriteAsJson(errors.None)
+}
+
+func extractToken(req *http.Request) string {
+	authorization := strings.Split(req.Header.Get("Authorization"), " ")
+
+	if len(authorization) == 2 && authorization[0] == "Bearer" {
+		return authorization[1]
+	}
+
+	if cookie, err := req.Cookie("token"); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+func OAuthAuthorize(req *restful.Request, resp *restful.Response) {
+	idp := req.PathParameter("idp")
+
+	authorizeURL, err := iam.OAuthAuthorizeURL(idp)
+
+	if err != nil {
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	http.Redirect(resp.ResponseWriter, req.Request, authorizeURL, http.StatusFound)
+}
+
+func OAuthCallback(req *restful.Request, resp *restful.Response) {
+	idp := req.PathParameter("idp")
+	code := req.QueryParameter("code")
+	state := req.QueryParameter("state")
+
+	if code == "" || state == "" {
+		resp.WriteHeaderAndEntity(http.StatusUnauthorized, errors.New(req.QueryParameter("error_description")))
+		return
+	}
+
+	ip := iputil.RemoteIp(req.Request)
+
+	token, err := iam.OAuthLogin(idp, code, state, ip)
+
+	if err != nil {
+		if serviceError, ok := err.(restful.ServiceError); ok {
+			resp.WriteHeaderAndEntity(serviceError.Code, errors.New(serviceError.Message))
+			return
+		}
+		resp.WriteHeaderAndEntity(http.StatusUnauthorized, errors.Wrap(err))
+		return
+	}
+
+	resp.WriteAsJson(token)
+}
+
+func ListIdentityProviders(req *restful.Request, resp *restful.Response) {
+	providers := identityprovider.List()
+	sort.Strings(providers)
+	resp.WriteAsJson(providers)
+}
+
+// JSON Web Key Set used to verify tokens issued by ks-iam
+func JSONWebKeySet(req *restful.Request, resp *restful.Response) {
+	resp.WriteAsJson(jwtutil.JSONWebKeySet())
+}
+
 // k8s token review
 func TokenReviewHandler(req *restful.Request, resp *restful.Response) {
 	var tokenReview TokenReview
@@ -115,6 +238,23 @@ func TokenReviewHandler(req *restful.Request, resp *restful.Response) {
 
 	claims := token.Claims.(jwt.MapClaims)
 
+	if jwtutil.IsRefreshToken(claims) {
+		resp.WriteAsJson(TokenReview{APIVersion: APIVersion, Kind: KindTokenReview, Status: &Status{Authenticated: false}})
+		return
+	}
+
+	revoked, err := jwtutil.IsRevoked(redis.Client(), claims)
+
+	if err != nil {
+		resp.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+		return
+	}
+
+	if revoked {
+		resp.WriteAsJson(TokenReview{APIVersion: APIVersion, Kind: KindTokenReview, Status: &Status{Authenticated: false}})
+		return
+	}
+
 	username, ok := claims["username"].(string)
 
 	if !ok {
@@ -0,0 +1,145 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/log"
+	loggingclient "kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+// ExportErrorTrailer is the trailer of exported files carrying the error occurred after logs were written
+const ExportErrorTrailer = "X-Export-Error"
+
+// exportWriter writes headers of the exported file before the first write, so that errors
+// occurred before any logs are exported can still be responded
+type exportWriter struct {
+	response *restful.Response
+	exporter *log.LogExporter
+	gzip     bool
+
+	writer io.Writer
+	closer io.Closer
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if w.writer == nil {
+		w.start()
+	}
+	return w.writer.Write(p)
+}
+
+func (w *exportWriter) start() {
+	filename := w.exporter.Filename(time.Now())
+	if w.gzip {
+		filename += ".gz"
+	}
+
+	w.response.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.response.Header().Set("Trailer", ExportErrorTrailer)
+
+	if w.gzip {
+		w.response.Header().Set(restful.HEADER_ContentType, "application/gzip")
+		w.response.WriteHeader(http.StatusOK)
+		gz := gzip.NewWriter(w.response)
+		w.writer, w.closer = gz, gz
+	} else {
+		w.response.Header().Set(restful.HEADER_ContentType, w.exporter.ContentType())
+		w.response.WriteHeader(http.StatusOK)
+		w.writer = w.response
+	}
+}
+
+func (w *exportWriter) Close() error {
+	if w.writer == nil {
+		w.start()
+	}
+	if w.closer != nil {
+		return w.closer.Close()
+	}
+	return nil
+}
+
+// exportLogs streams all logs matching the query as a file, from and size are ignored
+func exportLogs(level log.LogQueryLevel, request *restful.Request, response *restful.Response) {
+	backend, err := log.Backend()
+	if err == loggingclient.ErrNotConfigured {
+		response.WriteHeaderAndEntity(http.StatusNotFound, errors.Wrap(err))
+		return
+	} else if err != nil {
+		response.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+		return
+	}
+
+	param, err := queryParameters(level, request)
+	if err != nil {
+		response.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		return
+	}
+
+	// logs are exported in chronological order unless specified
+	if param.Sort == "" {
+		param.Sort = "asc"
+	}
+
+	writer := &exportWriter{response: response, gzip: request.QueryParameter("gzip") == "true"}
+
+	writer.exporter, err = log.NewLogExporter(request.QueryParameter("format"), writer)
+	if err != nil {
+		response.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		return
+	}
+
+	err = backend.Export(param, writer.exporter.Write)
+	if err == nil {
+		err = writer.exporter.Flush()
+	}
+
+	if err != nil {
+		glog.Errorln("export logs", err)
+		if writer.writer == nil {
+			status := http.StatusInternalServerError
+			if err == loggingclient.ErrQueryRangeTooLong {
+				status = http.StatusBadRequest
+			}
+			response.WriteHeaderAndEntity(status, errors.Wrap(err))
+			return
+		}
+		// the exported file is incomplete if errors occurred after logs were written, the error is written
+		// at the end of the file and in the trailer
+		if err := writer.exporter.WriteError(err); err == nil {
+			writer.exporter.Flush()
+		}
+	}
+
+	writer.Close()
+
+	if err != nil {
+		response.Header().Set(ExportErrorTrailer, strings.Replace(err.Error(), "\n", " ", -1))
+	}
+}
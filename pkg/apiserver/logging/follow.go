@@ -0,0 +1,167 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+	"github.com/gorilla/websocket"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/log"
+	loggingclient "kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+var upgrader = websocket.Upgrader{
+	// authentication is done by the api gateway, which accepts the token cookie
+	// sent by browsers, so only websockets of the console are accepted
+	CheckOrigin: runtime.CheckOrigin,
+}
+
+// followLogs streams new logs matching the query to the client, one record per websocket
+// message or one json object per line in chunked http responses
+func followLogs(level log.LogQueryLevel, request *restful.Request, response *restful.Response) {
+	start := time.Now()
+	if t, ok := loggingclient.ParseTime(request.QueryParameter("start_time")); ok {
+		start = t
+	}
+
+	param, err := queryParameters(level, request)
+	if err != nil {
+		response.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		return
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	var records <-chan loggingclient.LogRecord
+
+	backend, err := log.Backend()
+
+	if err == nil {
+		startTime := strconv.FormatInt(loggingclient.Millis(start), 10)
+		records = log.FollowLogs(backend, func() loggingclient.QueryParameters {
+			// filters were validated above
+			param, _ := queryParameters(level, request)
+			param.StartTime = startTime
+			param.EndTime = ""
+			return param
+		}, stopCh)
+	} else if err != loggingclient.ErrNotConfigured {
+		response.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+		return
+	} else {
+		// logs of single pods are streamed from the kubelet without the log backend
+		var containers []string
+
+		switch level {
+		case log.QueryLevelContainer:
+			containers = []string{request.PathParameter("container")}
+		case log.QueryLevelPod:
+			_, containers = log.MatchContainer(request.QueryParameter("containers"))
+		default:
+			response.WriteHeaderAndEntity(http.StatusNotFound, errors.Wrap(err))
+			return
+		}
+
+		records, err = log.FollowPodLogs(request.PathParameter("namespace"), request.PathParameter("pod"), containers, param.LogQuery, param.Filter, start, stopCh)
+
+		if err != nil {
+			glog.Errorln(err)
+			if k8serr.IsNotFound(err) {
+				response.WriteHeaderAndEntity(http.StatusNotFound, errors.Wrap(err))
+			} else {
+				response.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+			}
+			return
+		}
+	}
+
+	if websocket.IsWebSocketUpgrade(request.Request) {
+		conn, err := upgrader.Upgrade(response.ResponseWriter, request.Request, nil)
+
+		if err != nil {
+			glog.Errorln("upgrade websocket", err)
+			return
+		}
+
+		defer conn.Close()
+
+		closed := make(chan struct{})
+
+		// messages from clients are discarded, following is stopped once the connection is closed
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case record, ok := <-records:
+				if !ok {
+					return
+				}
+				if err := conn.WriteJSON(record); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}
+
+	flusher, ok := response.ResponseWriter.(http.Flusher)
+
+	if !ok {
+		response.WriteHeaderAndEntity(http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	response.Header().Set(restful.HEADER_ContentType, restful.MIME_JSON)
+	response.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(response)
+
+	for {
+		select {
+		case record, ok := <-records:
+			if !ok {
+				return
+			}
+			if err := encoder.Encode(record); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-request.Request.Context().Done():
+			return
+		}
+	}
+}
@@ -22,13 +22,24 @@ import (
 	"github.com/emicklei/go-restful"
 	"github.com/golang/glog"
 	"kubesphere.io/kubesphere/pkg/models/log"
-	es "kubesphere.io/kubesphere/pkg/simple/client/elasticsearch"
+	"kubesphere.io/kubesphere/pkg/params"
 	fb "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
+	loggingclient "kubesphere.io/kubesphere/pkg/simple/client/logging"
 	"net/http"
 	"strconv"
 )
 
 func LoggingQueryCluster(request *restful.Request, response *restful.Response) {
+	if request.QueryParameter("follow") == "true" {
+		followLogs(log.QueryLevelCluster, request, response)
+		return
+	}
+
+	if request.QueryParameter("operation") == loggingclient.OperationExport {
+		exportLogs(log.QueryLevelCluster, request, response)
+		return
+	}
+
 	res := logQuery(log.QueryLevelCluster, request)
 
 	if res.Status != http.StatusOK {
@@ -40,6 +51,16 @@ func LoggingQueryCluster(request *restful.Request, response *restful.Response) {
 }
 
 func LoggingQueryWorkspace(request *restful.Request, response *restful.Response) {
+	if request.QueryParameter("follow") == "true" {
+		followLogs(log.QueryLevelWorkspace, request, response)
+		return
+	}
+
+	if request.QueryParameter("operation") == loggingclient.OperationExport {
+		exportLogs(log.QueryLevelWorkspace, request, response)
+		return
+	}
+
 	res := logQuery(log.QueryLevelWorkspace, request)
 
 	if res.Status != http.StatusOK {
@@ -51,6 +72,16 @@ func LoggingQueryWorkspace(request *restful.Request, response *restful.Response)
 }
 
 func LoggingQueryNamespace(request *restful.Request, response *restful.Response) {
+	if request.QueryParameter("follow") == "true" {
+		followLogs(log.QueryLevelNamespace, request, response)
+		return
+	}
+
+	if request.QueryParameter("operation") == loggingclient.OperationExport {
+		exportLogs(log.QueryLevelNamespace, request, response)
+		return
+	}
+
 	res := logQuery(log.QueryLevelNamespace, request)
 
 	if res.Status != http.StatusOK {
@@ -62,6 +93,16 @@ func LoggingQueryNamespace(request *restful.Request, response *restful.Response)
 }
 
 func LoggingQueryWorkload(request *restful.Request, response *restful.Response) {
+	if request.QueryParameter("follow") == "true" {
+		followLogs(log.QueryLevelWorkload, request, response)
+		return
+	}
+
+	if request.QueryParameter("operation") == loggingclient.OperationExport {
+		exportLogs(log.QueryLevelWorkload, request, response)
+		return
+	}
+
 	res := logQuery(log.QueryLevelWorkload, request)
 
 	if res.Status != http.StatusOK {
@@ -73,6 +114,16 @@ func LoggingQueryWorkload(request *restful.Request, response *restful.Response)
 }
 
 func LoggingQueryPod(request *restful.Request, response *restful.Response) {
+	if request.QueryParameter("follow") == "true" {
+		followLogs(log.QueryLevelPod, request, response)
+		return
+	}
+
+	if request.QueryParameter("operation") == loggingclient.OperationExport {
+		exportLogs(log.QueryLevelPod, request, response)
+		return
+	}
+
 	res := logQuery(log.QueryLevelPod, request)
 	if res.Status != http.StatusOK {
 		response.WriteHeaderAndEntity(res.Status, res.Error)
@@ -82,6 +133,16 @@ func LoggingQueryPod(request *restful.Request, response *restful.Response) {
 }
 
 func LoggingQueryContainer(request *restful.Request, response *restful.Response) {
+	if request.QueryParameter("follow") == "true" {
+		followLogs(log.QueryLevelContainer, request, response)
+		return
+	}
+
+	if request.QueryParameter("operation") == loggingclient.OperationExport {
+		exportLogs(log.QueryLevelContainer, request, response)
+		return
+	}
+
 	res := logQuery(log.QueryLevelContainer, request)
 	if res.Status != http.StatusOK {
 		response.WriteHeaderAndEntity(res.Status, res.Error)
@@ -180,8 +241,187 @@ func LoggingDeleteFluentbitOutput(request *restful.Request, response *restful.Re
 	response.WriteAsJson(res)
 }
 
-func logQuery(level log.LogQueryLevel, request *restful.Request) *es.QueryResult {
-	var param es.QueryParameters
+func LoggingQueryFluentbitPipeline(request *restful.Request, response *restful.Response) {
+	res := log.FluentbitPipelineQuery()
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+	response.WriteAsJson(res)
+}
+
+func LoggingUpdateFluentbitPipeline(request *restful.Request, response *restful.Response) {
+
+	var pipeline log.FluentbitPipeline
+
+	dryRun, err := strconv.ParseBool(request.QueryParameter("dry_run"))
+	if err != nil && request.QueryParameter("dry_run") != "" {
+		response.WriteHeaderAndEntity(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = request.ReadEntity(&pipeline)
+	if err != nil {
+		glog.Errorln(err)
+		response.WriteHeaderAndEntity(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res := log.FluentbitPipelineUpdate(pipeline, dryRun)
+
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+
+	response.WriteAsJson(res)
+}
+
+func LoggingQueryFluentbitParsers(request *restful.Request, response *restful.Response) {
+	res := log.FluentbitParsersQuery()
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+	response.WriteAsJson(res)
+}
+
+func LoggingInsertFluentbitParser(request *restful.Request, response *restful.Response) {
+
+	var parser log.FluentbitParser
+	var res *log.FluentbitParsersResult
+
+	err := request.ReadEntity(&parser)
+	if err != nil {
+		glog.Errorln(err)
+		res = &log.FluentbitParsersResult{Status: http.StatusBadRequest, Error: err.Error()}
+	} else {
+		res = log.FluentbitParserInsert(parser)
+	}
+
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+
+	response.WriteAsJson(res)
+}
+
+func LoggingUpdateFluentbitParser(request *restful.Request, response *restful.Response) {
+
+	var parser log.FluentbitParser
+
+	name := request.PathParameter("parser")
+
+	err := request.ReadEntity(&parser)
+	if err != nil {
+		glog.Errorln(err)
+		response.WriteHeaderAndEntity(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res := log.FluentbitParserUpdate(parser, name)
+
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+
+	response.WriteAsJson(res)
+}
+
+func LoggingDeleteFluentbitParser(request *restful.Request, response *restful.Response) {
+
+	name := request.PathParameter("parser")
+	res := log.FluentbitParserDelete(name)
+
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+
+	response.WriteAsJson(res)
+}
+
+func LoggingQueryRetentions(request *restful.Request, response *restful.Response) {
+	res := log.LogRetentionsQuery("")
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+	response.WriteAsJson(res)
+}
+
+func LoggingQueryRetention(request *restful.Request, response *restful.Response) {
+	workspace := request.PathParameter("workspace")
+	res := log.LogRetentionsQuery(workspace)
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+	response.WriteAsJson(res)
+}
+
+func LoggingUpdateRetention(request *restful.Request, response *restful.Response) {
+
+	var retention log.LogRetention
+
+	workspace := request.PathParameter("workspace")
+
+	err := request.ReadEntity(&retention)
+	if err != nil {
+		glog.Errorln(err)
+		response.WriteHeaderAndEntity(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res := log.LogRetentionUpdate(retention, workspace)
+
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+
+	response.WriteAsJson(res)
+}
+
+func LoggingDeleteRetention(request *restful.Request, response *restful.Response) {
+
+	workspace := request.PathParameter("workspace")
+	res := log.LogRetentionDelete(workspace)
+
+	if res.Status != http.StatusOK {
+		response.WriteHeaderAndEntity(res.Status, res.Error)
+		return
+	}
+
+	response.WriteAsJson(res)
+}
+
+func logQuery(level log.LogQueryLevel, request *restful.Request) *loggingclient.QueryResult {
+	backend, err := log.Backend()
+	if err == loggingclient.ErrNotConfigured {
+		return &loggingclient.QueryResult{Status: http.StatusNotFound, Error: err.Error()}
+	} else if err != nil {
+		return &loggingclient.QueryResult{Status: http.StatusInternalServerError, Error: err.Error()}
+	}
+
+	param, err := queryParameters(level, request)
+	if err != nil {
+		return &loggingclient.QueryResult{Status: http.StatusBadRequest, Error: err.Error()}
+	}
+
+	return backend.Query(param)
+}
+
+func queryParameters(level log.LogQueryLevel, request *restful.Request) (loggingclient.QueryParameters, error) {
+	var param loggingclient.QueryParameters
+	var err error
+
+	param.Filter, err = params.ParseFilter(request.QueryParameter(params.FilterParam))
+	if err != nil {
+		return param, err
+	}
 
 	param.Operation = request.QueryParameter("operation")
 
@@ -258,7 +498,6 @@ func logQuery(level log.LogQueryLevel, request *restful.Request) *es.QueryResult
 	param.EndTime = request.QueryParameter("end_time")
 	param.Sort = request.QueryParameter("sort")
 
-	var err error
 	param.From, err = strconv.ParseInt(request.QueryParameter("from"), 10, 64)
 	if err != nil {
 		param.From = 0
@@ -268,5 +507,5 @@ func logQuery(level log.LogQueryLevel, request *restful.Request) *es.QueryResult
 		param.Size = 10
 	}
 
-	return es.Query(param)
+	return param, nil
 }
@@ -19,8 +19,11 @@ package monitoring
 
 import (
 	"github.com/emicklei/go-restful"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"kubesphere.io/kubesphere/pkg/errors"
 	"kubesphere.io/kubesphere/pkg/models/metrics"
 	"kubesphere.io/kubesphere/pkg/simple/client/prometheus"
+	"net/http"
 )
 
 func MonitorAllPodsOfSpecificNamespace(request *restful.Request, response *restful.Response) {
@@ -48,10 +51,9 @@ func MonitorPod(request *restful.Request, response *restful.Response) {
 		queryType, params, nullRule := metrics.AssemblePodMetricRequestInfo(requestParams, metricName)
 		var res *metrics.FormatedMetric
 		if !nullRule {
-			metricsStr := prometheus.SendMonitoringRequest(prometheus.PrometheusEndpoint, queryType, params)
-			res = metrics.ReformatJson(metricsStr, metricName, map[string]string{metrics.MetricLevelPodName: ""})
+			res = metrics.QueryMetric(requestParams.Context, prometheus.Primary(), queryType, params, metricName, map[string]string{metrics.MetricLevelPodName: ""})
 		}
-		response.WriteAsJson(res)
+		writeMetric(response, res)
 
 	} else {
 		// multiple
@@ -90,7 +92,7 @@ func MonitorContainer(request *restful.Request, response *restful.Response) {
 
 	} else {
 		res := metrics.MonitorContainer(requestParams, metricName)
-		response.WriteAsJson(res)
+		writeMetric(response, res)
 	}
 
 }
@@ -202,10 +204,9 @@ func MonitorCluster(request *restful.Request, response *restful.Response) {
 	if metricName != "" {
 		// single
 		queryType, params := metrics.AssembleClusterMetricRequestInfo(requestParams, metricName)
-		metricsStr := prometheus.SendMonitoringRequest(prometheus.PrometheusEndpoint, queryType, params)
-		res := metrics.ReformatJson(metricsStr, metricName, map[string]string{metrics.MetricLevelCluster: "local"})
+		res := metrics.QueryMetric(requestParams.Context, prometheus.Primary(), queryType, params, metricName, map[string]string{metrics.MetricLevelCluster: "local"})
 
-		response.WriteAsJson(res)
+		writeMetric(response, res)
 	} else {
 		// multiple
 		res := metrics.GetClusterLevelMetrics(requestParams)
@@ -228,15 +229,14 @@ func MonitorNode(request *restful.Request, response *restful.Response) {
 	if metricName != "" {
 		// single
 		queryType, params := metrics.AssembleNodeMetricRequestInfo(requestParams, metricName)
-		metricsStr := prometheus.SendMonitoringRequest(prometheus.PrometheusEndpoint, queryType, params)
-		res := metrics.ReformatJson(metricsStr, metricName, map[string]string{metrics.MetricLevelNode: ""})
+		res := metrics.QueryMetric(requestParams.Context, prometheus.Primary(), queryType, params, metricName, map[string]string{metrics.MetricLevelNode: ""})
 		// The raw node-exporter result doesn't include ip address information
 		// Thereby, append node ip address to .data.result[].metric
 
 		nodeAddress := metrics.GetNodeAddressInfo()
 		metrics.AddNodeAddressMetric(res, nodeAddress)
 
-		response.WriteAsJson(res)
+		writeMetric(response, res)
 	} else {
 		// multiple
 		rawMetrics := metrics.GetNodeLevelMetrics(requestParams)
@@ -266,3 +266,71 @@ func MonitorComponent(request *restful.Request, response *restful.Response) {
 
 	response.WriteAsJson(rawMetrics)
 }
+
+func MonitorNamespaceCustomMetric(request *restful.Request, response *restful.Response) {
+	MonitorCustomMetric(request, response)
+}
+
+func MonitorWorkspaceCustomMetric(request *restful.Request, response *restful.Response) {
+	MonitorCustomMetric(request, response)
+}
+
+func MonitorCustomMetric(request *restful.Request, response *restful.Response) {
+	requestParams := prometheus.ParseMonitoringRequestParams(request)
+	query := request.QueryParameter("query")
+
+	if query == "" {
+		response.WriteHeaderAndEntity(http.StatusBadRequest, errors.New("query is required"))
+		return
+	}
+
+	res, err := metrics.MonitorCustomMetric(requestParams, query, requestParams.MetricsName)
+
+	if err != nil {
+		response.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		return
+	}
+
+	writeMetric(response, res)
+}
+
+func MonitorNamespaceDashboard(request *restful.Request, response *restful.Response) {
+	requestParams := prometheus.ParseMonitoringRequestParams(request)
+	name := request.PathParameter("dashboard")
+
+	res, err := metrics.MonitorNamespaceDashboard(requestParams, name)
+
+	writeDashboard(response, res, err)
+}
+
+func MonitorWorkspaceDashboard(request *restful.Request, response *restful.Response) {
+	requestParams := prometheus.ParseMonitoringRequestParams(request)
+	name := request.PathParameter("dashboard")
+
+	res, err := metrics.MonitorWorkspaceDashboard(requestParams, name)
+
+	writeDashboard(response, res, err)
+}
+
+func writeDashboard(response *restful.Response, res *metrics.DashboardMetrics, err error) {
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			response.WriteHeaderAndEntity(http.StatusNotFound, errors.Wrap(err))
+		} else {
+			response.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+		}
+		return
+	}
+
+	response.WriteAsJson(res)
+}
+
+// writeMetric responds with the status of the error type if querying the metric failed
+func writeMetric(response *restful.Response, res *metrics.FormatedMetric) {
+	if res != nil && res.Status == metrics.MetricStatusError {
+		response.WriteHeaderAndEntity(prometheus.HTTPStatus(res.ErrorType), res)
+		return
+	}
+
+	response.WriteAsJson(res)
+}
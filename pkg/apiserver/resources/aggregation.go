@@ -0,0 +1,110 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/emicklei/go-restful"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/iam"
+	"kubesphere.io/kubesphere/pkg/models/resources"
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+// reviewAccess authorizes the aggregated resources, replaced in tests
+var reviewAccess = iam.ReviewAccess
+
+func AggregateNamespacedResources(req *restful.Request, resp *restful.Response) {
+	AggregateResources(req, resp)
+}
+
+func AggregateResources(req *restful.Request, resp *restful.Response) {
+	namespace := req.PathParameter("namespace")
+	resourceNames := req.QueryParameter("resources")
+	conditions, err := params.ParseConditions(req.QueryParameter(params.ConditionsParam))
+
+	if err == nil {
+		conditions.Filter, err = params.ParseFilter(req.QueryParameter(params.FilterParam))
+	}
+
+	if err != nil {
+		resp.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		return
+	}
+
+	facets, err := resources.ParseFacets(req.QueryParameter("facets"))
+
+	if err != nil {
+		resp.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		return
+	}
+
+	if resourceNames == "" {
+		resp.WriteHeaderAndEntity(http.StatusBadRequest, errors.New("resources must be specified"))
+		return
+	}
+
+	username := req.HeaderParameter(constants.UserNameHeader)
+	var groups []string
+	for _, group := range strings.Split(req.HeaderParameter(constants.UserGroupsHeader), ",") {
+		if group != "" {
+			groups = append(groups, group)
+		}
+	}
+
+	result := make(map[string]*resources.Aggregation)
+
+	for _, resourceName := range strings.Split(resourceNames, ",") {
+		// aggregations are authorized as aggregations by the api gateway, the resources are authorized
+		// with the attributes of listing them by the resources api
+		status, err := reviewAccess(iam.AccessReviewSpec{
+			AccessAttributes: iam.AccessAttributes{Namespace: namespace, Verb: "list", APIGroup: "resources.kubesphere.io", Resource: resourceName},
+			User:             username,
+			Groups:           groups,
+		})
+
+		if err != nil {
+			resp.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+			return
+		}
+
+		if !status.Allowed {
+			resp.WriteHeaderAndEntity(http.StatusForbidden, errors.New(fmt.Sprintf("user %s is not allowed to list %s", username, resourceName)))
+			return
+		}
+
+		aggregation, err := clusterResources(req).AggregateResources(namespace, resourceName, conditions, facets)
+
+		if err == resources.ErrResourceNotFound {
+			resp.WriteHeaderAndEntity(http.StatusNotFound, errors.New(fmt.Sprintf("resource %s not found", resourceName)))
+			return
+		} else if err != nil {
+			resp.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+			return
+		}
+
+		result[resourceName] = aggregation
+	}
+
+	resp.WriteAsJson(result)
+}
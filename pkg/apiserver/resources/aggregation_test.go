@@ -0,0 +1,164 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sinformers "k8s.io/client-go/informers"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/clusters"
+	"kubesphere.io/kubesphere/pkg/models/iam"
+	"kubesphere.io/kubesphere/pkg/models/resources"
+)
+
+func TestAggregateResources(t *testing.T) {
+	factory := k8sinformers.NewSharedInformerFactory(nil, 0)
+	indexer := factory.Core().V1().ConfigMaps().Informer().GetIndexer()
+	indexer.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a", Labels: map[string]string{"app": "web"}}})
+	indexer.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "b", Labels: map[string]string{"app": "web"}}})
+	indexer.Add(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "c"}})
+
+	cluster := &clusters.Cluster{Name: "test", Informers: factory}
+
+	// alice can list all resources, bob can list configmaps in the default namespace
+	defer func(review func(iam.AccessReviewSpec) (*iam.AccessReviewStatus, error)) { reviewAccess = review }(reviewAccess)
+	reviewAccess = func(spec iam.AccessReviewSpec) (*iam.AccessReviewStatus, error) {
+		allowed := spec.User == "alice" ||
+			spec.User == "bob" && spec.Namespace == "default" && spec.Resource == "configmaps" && spec.Verb == "list" && spec.APIGroup == "resources.kubesphere.io"
+		return &iam.AccessReviewStatus{Allowed: allowed}, nil
+	}
+
+	tests := []struct {
+		name        string
+		user        string
+		namespace   string
+		query       string
+		status      int
+		aggregation map[string]*resources.Aggregation
+	}{
+		{
+			name:   "resources not specified",
+			user:   "alice",
+			query:  "facets=label:app",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid facet",
+			user:   "alice",
+			query:  "resources=configmaps&facets=unknown",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown resource",
+			user:   "alice",
+			query:  "resources=unknown",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "one of the resources unknown",
+			user:   "alice",
+			query:  "resources=configmaps,unknown",
+			status: http.StatusNotFound,
+		},
+		{
+			name:      "cluster resource in namespace",
+			user:      "alice",
+			namespace: "default",
+			query:     "resources=nodes",
+			status:    http.StatusNotFound,
+		},
+		{
+			name:   "host resource in member cluster",
+			user:   "alice",
+			query:  "resources=workspaces",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "resources of the cluster",
+			user:   "alice",
+			query:  "resources=configmaps&facets=label:app",
+			status: http.StatusOK,
+			aggregation: map[string]*resources.Aggregation{
+				"configmaps": {TotalCount: 3, Facets: map[string]map[string]int{"label:app": {"web": 2}}},
+			},
+		},
+		{
+			name:      "resources of the namespace",
+			user:      "alice",
+			namespace: "kube-system",
+			query:     "resources=configmaps&facets=label:app",
+			status:    http.StatusOK,
+			aggregation: map[string]*resources.Aggregation{
+				"configmaps": {TotalCount: 1, Facets: map[string]map[string]int{"label:app": {}}},
+			},
+		},
+		{
+			name:      "resources the user can list",
+			user:      "bob",
+			namespace: "default",
+			query:     "resources=configmaps",
+			status:    http.StatusOK,
+			aggregation: map[string]*resources.Aggregation{
+				"configmaps": {TotalCount: 2},
+			},
+		},
+		{
+			name:      "one of the resources the user can't list",
+			user:      "bob",
+			namespace: "default",
+			query:     "resources=configmaps,secrets",
+			status:    http.StatusForbidden,
+		},
+		{
+			name:   "resources of the cluster the user can't list",
+			user:   "bob",
+			query:  "resources=configmaps",
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		httpRequest := httptest.NewRequest(http.MethodGet, "/aggregations?"+test.query, nil)
+		httpRequest.Header.Set(constants.UserNameHeader, test.user)
+		httpRequest = httpRequest.WithContext(clusters.WithCluster(httpRequest.Context(), cluster))
+		request := restful.NewRequest(httpRequest)
+		request.PathParameters()["namespace"] = test.namespace
+		recorder := httptest.NewRecorder()
+		response := restful.NewResponse(recorder)
+		response.SetRequestAccepts(restful.MIME_JSON)
+
+		AggregateResources(request, response)
+
+		if !assert.Equal(t, test.status, recorder.Code, test.name) || test.aggregation == nil {
+			continue
+		}
+
+		aggregation := make(map[string]*resources.Aggregation)
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &aggregation), test.name)
+		assert.Equal(t, test.aggregation, aggregation, test.name)
+	}
+}
@@ -19,13 +19,26 @@ package resources
 
 import (
 	"github.com/emicklei/go-restful"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models"
+	"kubesphere.io/kubesphere/pkg/models/clusters"
 	"kubesphere.io/kubesphere/pkg/models/resources"
 	"net/http"
+	"strconv"
 
 	"kubesphere.io/kubesphere/pkg/errors"
 	"kubesphere.io/kubesphere/pkg/params"
 )
 
+// clusterResources returns the resources of the member cluster the request is dispatched to,
+// or the resources of the host cluster
+func clusterResources(req *restful.Request) *resources.ClusterResources {
+	if cluster, ok := clusters.FromContext(req.Request.Context()); ok {
+		return resources.MemberCluster(cluster.Name, cluster.Informers)
+	}
+	return resources.HostCluster()
+}
+
 func ListNamespacedResources(req *restful.Request, resp *restful.Response) {
 	ListResources(req, resp)
 }
@@ -43,15 +56,43 @@ func ListResources(req *restful.Request, resp *restful.Response) {
 		reverse = true
 	}
 
+	if err == nil {
+		conditions.Filter, err = params.ParseFilter(req.QueryParameter(params.FilterParam))
+	}
+
 	if err != nil {
 		resp.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
 		return
 	}
 
-	result, err := resources.ListResources(namespace, resourceName, conditions, orderBy, reverse, limit, offset)
+	if watch, _ := strconv.ParseBool(req.QueryParameter(params.WatchParam)); watch {
+		if len(conditions.Match) > 0 || len(conditions.Fuzzy) > 0 {
+			resp.WriteHeaderAndEntity(http.StatusBadRequest, errors.New("conditions are not supported in watch mode, use filter instead"))
+			return
+		}
+		watchResources(req, resp, namespace, resourceName, conditions.Filter)
+		return
+	}
+
+	var result *models.PageableResponse
+
+	if req.QueryParameter(params.LimitParam) != "" || req.QueryParameter(params.ContinueParam) != "" {
+		limit, _ := strconv.Atoi(req.QueryParameter(params.LimitParam))
+		username := req.HeaderParameter(constants.UserNameHeader)
+		result, err = clusterResources(req).ListResourcesWithContinue(namespace, resourceName, conditions, orderBy, reverse, limit, req.QueryParameter(params.ContinueParam), username)
+	} else {
+		result, err = clusterResources(req).ListResources(namespace, resourceName, conditions, orderBy, reverse, limit, offset)
+	}
 
 	if err != nil {
-		resp.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+		switch err {
+		case resources.ErrContinueTokenExpired:
+			resp.WriteHeaderAndEntity(http.StatusGone, errors.Wrap(err))
+		case resources.ErrInvalidContinueToken:
+			resp.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		default:
+			resp.WriteHeaderAndEntity(http.StatusInternalServerError, errors.Wrap(err))
+		}
 		return
 	}
 
@@ -0,0 +1,141 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+	"github.com/gorilla/websocket"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/resources"
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+var upgrader = websocket.Upgrader{
+	// authentication is done by the api gateway, which accepts the token cookie
+	// sent by browsers, so only websockets of the console are accepted
+	CheckOrigin: runtime.CheckOrigin,
+}
+
+// watchEvent is the event sent to the clients, one json object per line in chunked
+// http responses or one message in websocket connections
+type watchEvent struct {
+	Type   watch.EventType `json:"type"`
+	Object interface{}     `json:"object"`
+}
+
+func watchResources(req *restful.Request, resp *restful.Response, namespace, resource string, filter params.Expression) {
+	watcher, err := clusterResources(req).WatchResources(namespace, resource, filter, req.QueryParameter(params.ResourceVersionParam))
+
+	if err == resources.ErrResourceVersionTooOld {
+		resp.WriteHeaderAndEntity(http.StatusGone, errors.Wrap(err))
+		return
+	}
+
+	if err != nil {
+		resp.WriteHeaderAndEntity(http.StatusBadRequest, errors.Wrap(err))
+		return
+	}
+
+	defer watcher.Stop()
+
+	var timeout <-chan time.Time
+
+	if seconds, _ := strconv.Atoi(req.QueryParameter(params.TimeoutParam)); seconds > 0 {
+		timer := time.NewTimer(time.Duration(seconds) * time.Second)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	if websocket.IsWebSocketUpgrade(req.Request) {
+		conn, err := upgrader.Upgrade(resp.ResponseWriter, req.Request, nil)
+
+		if err != nil {
+			glog.Errorln("upgrade websocket", err)
+			return
+		}
+
+		defer conn.Close()
+
+		closed := make(chan struct{})
+
+		// messages from clients are discarded, the watch is stopped once the connection is closed
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case event, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+				if err := conn.WriteJSON(watchEvent{Type: event.Type, Object: event.Object}); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			case <-timeout:
+				return
+			}
+		}
+	}
+
+	flusher, ok := resp.ResponseWriter.(http.Flusher)
+
+	if !ok {
+		resp.WriteHeaderAndEntity(http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	resp.Header().Set(restful.HEADER_ContentType, restful.MIME_JSON)
+	resp.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(resp)
+
+	for {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return
+			}
+			if err := encoder.Encode(watchEvent{Type: event.Type, Object: event.Object}); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Request.Context().Done():
+			return
+		case <-timeout:
+			return
+		}
+	}
+}
@@ -0,0 +1,77 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package runtime
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// origins of the console which websockets could be opened from, browsers send the token
+// cookie with websocket requests of other sites
+var allowedOrigins = struct {
+	sync.RWMutex
+	origins []string
+}{}
+
+// SetAllowedOrigins sets the origins of the console, e.g. https://console.example.com
+func SetAllowedOrigins(origins []string) {
+	allowedOrigins.Lock()
+	defer allowedOrigins.Unlock()
+
+	allowedOrigins.origins = make([]string, 0, len(origins))
+
+	for _, origin := range origins {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			allowedOrigins.origins = append(allowedOrigins.origins, origin)
+		}
+	}
+}
+
+// CheckOrigin is the origin check of websocket upgraders, requests without Origin are not sent
+// by browsers, otherwise the origin must be the host of the request or one of the allowed origins
+func CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+
+	if err != nil {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	allowedOrigins.RLock()
+	defer allowedOrigins.RUnlock()
+
+	for _, allowed := range allowedOrigins.origins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package runtime
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	SetAllowedOrigins([]string{"https://console.example.com/", " "})
+	defer SetAllowedOrigins(nil)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"http://ks-apiserver.kubesphere-system.svc", true},
+		{"https://console.example.com", true},
+		{"https://CONSOLE.example.com", true},
+		{"https://evil.example.com", false},
+		{"http://console.example.com", false},
+		{"null", false},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "http://ks-apiserver.kubesphere-system.svc/kapis/devops.kubesphere.io/v1alpha2/devops/project/pipelineruns/events", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		if allowed := CheckOrigin(req); allowed != test.allowed {
+			t.Errorf("origin %q: expected %v, got %v", test.origin, test.allowed, allowed)
+		}
+	}
+}
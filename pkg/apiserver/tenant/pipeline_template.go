@@ -0,0 +1,126 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package tenant
+
+import (
+	"net/http"
+
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/errors"
+	"kubesphere.io/kubesphere/pkg/models/devops"
+	"kubesphere.io/kubesphere/pkg/models/tenant"
+)
+
+// pipeline templates of workspaces are authorized as the pipelinetemplates subresource of the workspace
+
+func ListWorkspacePipelineTemplates(req *restful.Request, resp *restful.Response) {
+	workspaceName := req.PathParameter("workspace")
+
+	templates, err := devops.GetWorkspacePipelineTemplates(workspaceName)
+
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	resp.WriteAsJson(templates)
+}
+
+func CreateWorkspacePipelineTemplate(req *restful.Request, resp *restful.Response) {
+	workspaceName := req.PathParameter("workspace")
+	username := req.HeaderParameter(constants.UserNameHeader)
+
+	var request devops.PipelineTemplateRequest
+
+	if err := req.ReadEntity(&request); err != nil {
+		glog.Infof("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusBadRequest, err.Error()), resp)
+		return
+	}
+
+	if _, err := tenant.GetWorkspace(workspaceName); err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusBadRequest, err.Error()), resp)
+		return
+	}
+
+	template, err := devops.CreateWorkspacePipelineTemplate(workspaceName, username, &request)
+
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	resp.WriteAsJson(template)
+}
+
+func UpdateWorkspacePipelineTemplate(req *restful.Request, resp *restful.Response) {
+	workspaceName := req.PathParameter("workspace")
+	templateName := req.PathParameter("template")
+
+	var request devops.PipelineTemplateRequest
+
+	if err := req.ReadEntity(&request); err != nil {
+		glog.Infof("%+v", err)
+		errors.ParseSvcErr(restful.NewError(http.StatusBadRequest, err.Error()), resp)
+		return
+	}
+
+	template, err := devops.UpdateWorkspacePipelineTemplate(workspaceName, templateName, &request)
+
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	resp.WriteAsJson(template)
+}
+
+func DeleteWorkspacePipelineTemplate(req *restful.Request, resp *restful.Response) {
+	workspaceName := req.PathParameter("workspace")
+	templateName := req.PathParameter("template")
+
+	if err := devops.DeleteWorkspacePipelineTemplate(workspaceName, templateName); err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	resp.WriteAsJson(errors.None)
+}
+
+func RolloutWorkspacePipelineTemplate(req *restful.Request, resp *restful.Response) {
+	workspaceName := req.PathParameter("workspace")
+	templateName := req.PathParameter("template")
+
+	pipelines, err := devops.RolloutPipelineTemplate(workspaceName, templateName)
+
+	if err != nil {
+		glog.Errorf("%+v", err)
+		errors.ParseSvcErr(err, resp)
+		return
+	}
+
+	resp.WriteAsJson(pipelines)
+}
@@ -36,7 +36,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/models/workspaces"
 	"kubesphere.io/kubesphere/pkg/params"
 
-	"kubesphere.io/kubesphere/pkg/simple/client/elasticsearch"
+	loggingclient "kubesphere.io/kubesphere/pkg/simple/client/logging"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"net/http"
 	"strings"
@@ -360,7 +360,7 @@ func LogQuery(req *restful.Request, resp *restful.Response) {
 		// if the user belongs to no namespace
 		// then no log visible
 		if len(namespaces) == 0 {
-			res := esclient.QueryResult{Status: http.StatusOK}
+			res := loggingclient.QueryResult{Status: http.StatusOK}
 			resp.WriteAsJson(res)
 			return
 		} else if len(queryNamespaces) == 1 && queryNamespaces[0] == "" {
@@ -368,7 +368,7 @@ func LogQuery(req *restful.Request, resp *restful.Response) {
 		} else {
 			inter := intersection(queryNamespaces, namespaces)
 			if len(inter) == 0 {
-				res := esclient.QueryResult{Status: http.StatusOK}
+				res := loggingclient.QueryResult{Status: http.StatusOK}
 				resp.WriteAsJson(res)
 				return
 			}
@@ -399,7 +399,7 @@ func intersection(s1, s2 []string) (inter []string) {
 	return
 }
 
-//Remove dups from slice.
+// Remove dups from slice.
 func removeDups(elements []string) (nodups []string) {
 	encountered := make(map[string]bool)
 	for _, element := range elements {
@@ -48,6 +48,8 @@ const (
 	DevopsReporter                 = "reporter"
 
 	UserNameHeader = "X-Token-Username"
+	// UserGroupsHeader is the comma-separated groups of the user set by the api gateway
+	UserGroupsHeader = "X-Token-Groups"
 
 	TenantResourcesTag    = "Tenant Resources"
 	IdentityManagementTag = "Identity Management"
@@ -0,0 +1,279 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package alertpolicy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	alertingv1alpha1 "kubesphere.io/kubesphere/pkg/apis/alerting/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/alerting"
+)
+
+var log = logf.Log.WithName("alertpolicy-controller")
+
+var (
+	prometheusRuleGroupVersionKind = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+
+	// PrometheusRules with the labels are loaded by the Prometheus of KubeSphere
+	prometheusRuleLabels = map[string]string{"prometheus": "k8s", "role": "alert-rules"}
+)
+
+// Add creates the AlertPolicy and ClusterAlertPolicy Controllers and adds them to the Manager with default RBAC. The Manager
+// will set fields on the Controllers and Start them when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	// PrometheusRules are read from the api server, the Prometheus Operator may be installed after the manager starts
+	rules, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return err
+	}
+
+	err = add(mgr, "alertpolicy-controller", &alertingv1alpha1.AlertPolicy{}, newReconciler(mgr, rules, false))
+	if err != nil {
+		return err
+	}
+
+	return add(mgr, "clusteralertpolicy-controller", &alertingv1alpha1.ClusterAlertPolicy{}, newReconciler(mgr, rules, true))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, rules client.Client, cluster bool) reconcile.Reconciler {
+	return &ReconcileAlertPolicy{Client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		recorder: mgr.GetRecorder("alertpolicy-controller"), rules: rules, cluster: cluster}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, name string, policy runtime.Object, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New(name, mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the policies
+	return c.Watch(&source.Kind{Type: policy}, &handler.EnqueueRequestForObject{})
+}
+
+var _ reconcile.Reconciler = &ReconcileAlertPolicy{}
+
+// ReconcileAlertPolicy compiles AlertPolicies or ClusterAlertPolicies into PrometheusRules of the Prometheus Operator
+type ReconcileAlertPolicy struct {
+	client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	rules    client.Client
+	// cluster is true for ClusterAlertPolicies
+	cluster bool
+}
+
+// Reconcile compiles the policy into a PrometheusRule owned by the policy, so it's garbage collected with the policy.
+// The rule is deleted if the policy is disabled or deleted, invalid policies are reported in the status and not retried.
+// +kubebuilder:rbac:groups=alerting.kubesphere.io,resources=alertpolicies;clusteralertpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=alerting.kubesphere.io,resources=alertpolicies/status;clusteralertpolicies/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
+func (r *ReconcileAlertPolicy) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	var instance runtime.Object = &alertingv1alpha1.AlertPolicy{}
+	if r.cluster {
+		instance = &alertingv1alpha1.ClusterAlertPolicy{}
+	}
+
+	err := r.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, r.deleteRule(request.Namespace, request.Name)
+		}
+		return reconcile.Result{}, err
+	}
+
+	object := instance.(metav1.Object)
+	spec, status := policyOf(instance)
+
+	if object.GetDeletionTimestamp() != nil {
+		return reconcile.Result{}, nil
+	}
+
+	newStatus := alertingv1alpha1.AlertPolicyStatus{ObservedGeneration: object.GetGeneration(), Rule: status.Rule}
+
+	group, err := alerting.CompileAlertPolicy(object.GetNamespace(), object.GetName(), spec)
+	if err != nil {
+		r.recorder.Event(instance, corev1.EventTypeWarning, "InvalidSpec", err.Error())
+		newStatus.Error = err.Error()
+		return reconcile.Result{}, r.updateStatus(instance, status, newStatus)
+	}
+
+	if spec.Disabled {
+		newStatus.Rule = ""
+		err = r.deleteRule(object.GetNamespace(), object.GetName())
+	} else {
+		newStatus.Rule, err = r.applyRule(instance, group)
+	}
+
+	if err != nil {
+		log.Error(err, "sync alert policy failed", "namespace", object.GetNamespace(), "name", object.GetName())
+		newStatus.Error = err.Error()
+		if err := r.updateStatus(instance, status, newStatus); err != nil {
+			log.Error(err, "update status of alert policy failed", "namespace", object.GetNamespace(), "name", object.GetName())
+		}
+		// retried with backoff
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, r.updateStatus(instance, status, newStatus)
+}
+
+// policyOf returns the spec and the status of the policy, ClusterAlertPolicy has the same spec and status
+func policyOf(instance runtime.Object) (*alertingv1alpha1.AlertPolicySpec, *alertingv1alpha1.AlertPolicyStatus) {
+	switch policy := instance.(type) {
+	case *alertingv1alpha1.ClusterAlertPolicy:
+		return &policy.Spec, &policy.Status
+	case *alertingv1alpha1.AlertPolicy:
+		return &policy.Spec, &policy.Status
+	default:
+		panic(fmt.Sprintf("unexpected alert policy %T", instance))
+	}
+}
+
+func (r *ReconcileAlertPolicy) updateStatus(instance runtime.Object, status *alertingv1alpha1.AlertPolicyStatus, newStatus alertingv1alpha1.AlertPolicyStatus) error {
+	if reflect.DeepEqual(*status, newStatus) {
+		return nil
+	}
+	*status = newStatus
+	return r.Status().Update(context.TODO(), instance)
+}
+
+// prometheusRuleName returns the namespace and name of the PrometheusRule of the policy
+func prometheusRuleName(policyNamespace, policyName string) types.NamespacedName {
+	if policyNamespace == "" {
+		return types.NamespacedName{Namespace: constants.KubeSphereMonitoringNamespace, Name: "clusteralertpolicy-" + policyName}
+	}
+	return types.NamespacedName{Namespace: policyNamespace, Name: "alertpolicy-" + policyName}
+}
+
+// applyRule creates or updates the PrometheusRule of the policy and returns its namespace/name,
+// rules not controlled by the policy are not overwritten
+func (r *ReconcileAlertPolicy) applyRule(instance runtime.Object, group *alerting.RuleGroup) (string, error) {
+	groupObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(group)
+	if err != nil {
+		return "", err
+	}
+
+	object := instance.(metav1.Object)
+	key := prometheusRuleName(object.GetNamespace(), object.GetName())
+	spec := map[string]interface{}{"groups": []interface{}{groupObject}}
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(prometheusRuleGroupVersionKind)
+
+	err = r.rules.Get(context.TODO(), key, existing)
+
+	if errors.IsNotFound(err) {
+		rule := &unstructured.Unstructured{}
+		rule.SetGroupVersionKind(prometheusRuleGroupVersionKind)
+		rule.SetNamespace(key.Namespace)
+		rule.SetName(key.Name)
+		rule.SetLabels(prometheusRuleLabels)
+		if err := controllerutil.SetControllerReference(object, rule, r.scheme); err != nil {
+			return "", err
+		}
+		rule.Object["spec"] = spec
+		if err := r.rules.Create(context.TODO(), rule); err != nil {
+			return "", err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, "Created", "created PrometheusRule %s", key)
+		return key.String(), nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if !metav1.IsControlledBy(existing, object) {
+		return "", fmt.Errorf("PrometheusRule %s already exists and is not controlled by the policy", key)
+	}
+
+	labels := existing.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	changed := !reflect.DeepEqual(existing.Object["spec"], spec)
+	for k, v := range prometheusRuleLabels {
+		if labels[k] != v {
+			labels[k] = v
+			changed = true
+		}
+	}
+
+	if changed {
+		existing.SetLabels(labels)
+		existing.Object["spec"] = spec
+		if err := r.rules.Update(context.TODO(), existing); err != nil {
+			return "", err
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, "Updated", "updated PrometheusRule %s", key)
+	}
+
+	return key.String(), nil
+}
+
+// deleteRule deletes the PrometheusRule of the policy if it's controlled by the policy
+func (r *ReconcileAlertPolicy) deleteRule(policyNamespace, policyName string) error {
+	key := prometheusRuleName(policyNamespace, policyName)
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(prometheusRuleGroupVersionKind)
+
+	err := r.rules.Get(context.TODO(), key, existing)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	kind := "AlertPolicy"
+	if r.cluster {
+		kind = "ClusterAlertPolicy"
+	}
+
+	ref := metav1.GetControllerOf(existing)
+	if ref == nil || ref.Kind != kind || ref.Name != policyName || ref.APIVersion != alertingv1alpha1.SchemeGroupVersion.String() {
+		return nil
+	}
+
+	err = r.rules.Delete(context.TODO(), existing)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
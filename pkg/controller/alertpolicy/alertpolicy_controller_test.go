@@ -0,0 +1,54 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package alertpolicy
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	alertingv1alpha1 "kubesphere.io/kubesphere/pkg/apis/alerting/v1alpha1"
+)
+
+func TestPrometheusRuleName(t *testing.T) {
+	tests := []struct {
+		namespace string
+		name      string
+		expected  types.NamespacedName
+	}{
+		{"demo", "web", types.NamespacedName{Namespace: "demo", Name: "alertpolicy-web"}},
+		{"", "busy", types.NamespacedName{Namespace: "kubesphere-monitoring-system", Name: "clusteralertpolicy-busy"}},
+	}
+
+	for _, test := range tests {
+		if actual := prometheusRuleName(test.namespace, test.name); actual != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, actual)
+		}
+	}
+}
+
+func TestPolicyOf(t *testing.T) {
+	policy := &alertingv1alpha1.ClusterAlertPolicy{Spec: alertingv1alpha1.AlertPolicySpec{Level: alertingv1alpha1.AlertLevelNode}}
+
+	spec, status := policyOf(policy)
+	status.Rule = "kubesphere-monitoring-system/clusteralertpolicy-busy"
+
+	if spec.Level != alertingv1alpha1.AlertLevelNode || policy.Status.Rule != status.Rule {
+		t.Errorf("spec and status of the policy are expected")
+	}
+}
@@ -0,0 +1,315 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package devops
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/gojenkins"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+// addCredential adds a new Controller to mgr with r as the reconcile.Reconciler
+func addCredential(mgr manager.Manager, r *ReconcileCredential) error {
+	c, err := controller.New("credential-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &devopsv1alpha1.Credential{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// credentials are waiting for their project to be ready
+	err = c.Watch(&source.Kind{Type: &devopsv1alpha1.DevOpsProject{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(object handler.MapObject) []reconcile.Request {
+			return r.credentialRequests(&client.ListOptions{}, func(credential *devopsv1alpha1.Credential) bool {
+				return credential.Spec.Project == object.Meta.GetName()
+			})
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the content of credentials is updated with their secrets
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(object handler.MapObject) []reconcile.Request {
+			return r.credentialRequests(client.InNamespace(object.Meta.GetNamespace()), func(credential *devopsv1alpha1.Credential) bool {
+				return credential.Spec.SecretRef != nil && credential.Spec.SecretRef.Name == object.Meta.GetName()
+			})
+		}),
+	})
+}
+
+var credentialTypes = []string{devopsv1alpha1.CredentialTypeUsernamePassword, devopsv1alpha1.CredentialTypeSsh,
+	devopsv1alpha1.CredentialTypeSecretText, devopsv1alpha1.CredentialTypeKubeConfig}
+
+var _ reconcile.Reconciler = &ReconcileCredential{}
+
+// ReconcileCredential applies Credentials to Jenkins
+type ReconcileCredential struct {
+	client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	jenkins  jenkinsClient
+}
+
+func (r *ReconcileCredential) credentialRequests(opts *client.ListOptions, match func(*devopsv1alpha1.Credential) bool) []reconcile.Request {
+	credentials := &devopsv1alpha1.CredentialList{}
+	if err := r.List(context.TODO(), opts, credentials); err != nil {
+		log.Error(err, "list credentials failed")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for i := range credentials.Items {
+		if match(&credentials.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: credentials.Items[i].Namespace, Name: credentials.Items[i].Name}})
+		}
+	}
+	return requests
+}
+
+// Reconcile creates the credential in the folder of its project with the content of its secret and updates it
+// when the spec or the secret changes, the credential is deleted from Jenkins with the Credential
+// +kubebuilder:rbac:groups=devops.kubesphere.io,resources=credentials,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=devops.kubesphere.io,resources=credentials/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+func (r *ReconcileCredential) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	instance := &devopsv1alpha1.Credential{}
+	err := r.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	project := &devopsv1alpha1.DevOpsProject{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: instance.Spec.Project}, project)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	projectFound := err == nil
+	// only credentials in the namespace of the project are applied to its folder
+	projectBound := projectFound && isBound(project, instance.Namespace)
+
+	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !sliceutil.HasString(instance.ObjectMeta.Finalizers, finalizer) {
+			instance.ObjectMeta.Finalizers = append(instance.ObjectMeta.Finalizers, finalizer)
+			return reconcile.Result{}, r.Update(context.TODO(), instance)
+		}
+	} else {
+		if sliceutil.HasString(instance.ObjectMeta.Finalizers, finalizer) {
+			// the credential has been deleted with the folder of the project,
+			// credentials which weren't created or adopted by the resource are left as is
+			if instance.Status.Id != "" && projectBound && project.Status.ProjectId == instance.Status.ProjectId &&
+				project.ObjectMeta.DeletionTimestamp.IsZero() {
+				jenkins := r.jenkins()
+				if jenkins == nil {
+					return reconcile.Result{}, fmt.Errorf("could not connect to jenkins")
+				}
+				if _, err := jenkins.DeleteCredentialInFolder(instance.Spec.Domain, instance.Status.Id, instance.Status.ProjectId); err != nil && !isNotFound(err) {
+					return reconcile.Result{}, err
+				}
+			}
+
+			instance.ObjectMeta.Finalizers = sliceutil.RemoveString(instance.ObjectMeta.Finalizers, func(item string) bool {
+				return item == finalizer
+			})
+			return reconcile.Result{}, r.Update(context.TODO(), instance)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	status := instance.Status.DeepCopy()
+
+	if projectFound && !projectBound {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonProjectForbidden,
+			fmt.Sprintf("devops project %s isn't bound to namespace %s", instance.Spec.Project, instance.Namespace))
+		return reconcile.Result{}, r.updateStatus(instance, status)
+	}
+
+	if !projectFound || !isReady(project.Status.Conditions) {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonProjectNotReady,
+			fmt.Sprintf("devops project %s is not ready", instance.Spec.Project))
+		return reconcile.Result{RequeueAfter: retryPeriod}, r.updateStatus(instance, status)
+	}
+
+	if !sliceutil.HasString(credentialTypes, instance.Spec.Type) {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonInvalidSpec,
+			fmt.Sprintf("unsupported credential type %s", instance.Spec.Type))
+		return reconcile.Result{}, r.updateStatus(instance, status)
+	}
+
+	id := credentialIdOf(instance)
+	if status.Id != "" && (status.Id != id || status.ProjectId != project.Status.ProjectId) {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonInvalidSpec,
+			fmt.Sprintf("credential can't be changed from %s/%s", status.ProjectId, status.Id))
+		return reconcile.Result{}, r.updateStatus(instance, status)
+	}
+
+	var secret *corev1.Secret
+	if instance.Spec.SecretRef != nil {
+		secret = &corev1.Secret{}
+		err := r.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: instance.Spec.SecretRef.Name}, secret)
+		if errors.IsNotFound(err) {
+			status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonSecretNotFound,
+				fmt.Sprintf("secret %s not found", instance.Spec.SecretRef.Name))
+			return reconcile.Result{RequeueAfter: retryPeriod}, r.updateStatus(instance, status)
+		} else if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	jenkins := r.jenkins()
+	if jenkins == nil {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonJenkinsUnavailable, "could not connect to jenkins")
+		return reconcile.Result{RequeueAfter: retryPeriod}, r.updateStatus(instance, status)
+	}
+
+	update := instance.Generation != status.ObservedGeneration ||
+		(secret != nil && secret.ResourceVersion != status.SecretResourceVersion)
+
+	owned := status.Id != "" || isAdopting(instance)
+	found, err := syncCredential(jenkins, project.Status.ProjectId, id, &instance.Spec, secret, owned, update)
+	if isAlreadyExists(err) {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonAlreadyExists, err.Error())
+		return reconcile.Result{}, r.updateStatus(instance, status)
+	}
+	if err != nil {
+		log.Error(err, "apply credential failed", "namespace", instance.Namespace, "name", instance.Name)
+		r.recorder.Event(instance, corev1.EventTypeWarning, ReasonJenkinsError, err.Error())
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonJenkinsError, err.Error())
+		if statusErr := r.updateStatus(instance, status); statusErr != nil {
+			return reconcile.Result{}, statusErr
+		}
+		return reconcile.Result{}, err
+	}
+	if !found {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonCredentialNotFound,
+			"credential not found in jenkins and secretRef is not set")
+		return reconcile.Result{RequeueAfter: resyncPeriod}, r.updateStatus(instance, status)
+	}
+
+	status.ProjectId = project.Status.ProjectId
+	status.Id = id
+	status.ObservedGeneration = instance.Generation
+	if secret != nil {
+		status.SecretResourceVersion = secret.ResourceVersion
+	}
+	status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionTrue, ReasonApplied, "")
+
+	return reconcile.Result{RequeueAfter: resyncPeriod}, r.updateStatus(instance, status)
+}
+
+func (r *ReconcileCredential) updateStatus(instance *devopsv1alpha1.Credential, status *devopsv1alpha1.CredentialStatus) error {
+	if reflect.DeepEqual(&instance.Status, status) {
+		return nil
+	}
+	instance.Status = *status
+	return r.Status().Update(context.TODO(), instance)
+}
+
+// credentialIdOf returns the id of the credential in Jenkins
+func credentialIdOf(credential *devopsv1alpha1.Credential) string {
+	if credential.Spec.Id != "" {
+		return credential.Spec.Id
+	}
+	return credential.Name
+}
+
+// syncCredential creates the credential in the folder of the project with the content of the secret if it doesn't
+// exist, and replaces the content of an existing credential when update is true.
+// Without a secret the credential is only checked to exist, found is false if it doesn't.
+// An existing credential which isn't owned by the resource is never touched, an alreadyExistsError is returned.
+func syncCredential(jenkins *gojenkins.Jenkins, projectId, id string, spec *devopsv1alpha1.CredentialSpec, secret *corev1.Secret, owned, update bool) (found bool, err error) {
+	_, err = jenkins.GetCredentialInFolder(spec.Domain, id, projectId)
+	if err != nil && !isNotFound(err) {
+		return false, err
+	}
+	exists := err == nil
+
+	if exists && !owned {
+		return true, &alreadyExistsError{kind: "credential", name: id}
+	}
+
+	if secret == nil {
+		return exists, nil
+	}
+	if exists && !update {
+		return true, nil
+	}
+
+	data := func(key string) string {
+		return string(secret.Data[key])
+	}
+
+	switch spec.Type {
+	case devopsv1alpha1.CredentialTypeUsernamePassword:
+		if exists {
+			_, err = jenkins.UpdateUsernamePasswordCredentialInFolder(spec.Domain, id, data(devopsv1alpha1.SecretUsernameKey),
+				data(devopsv1alpha1.SecretPasswordKey), spec.Description, projectId)
+		} else {
+			_, err = jenkins.CreateUsernamePasswordCredentialInFolder(spec.Domain, id, data(devopsv1alpha1.SecretUsernameKey),
+				data(devopsv1alpha1.SecretPasswordKey), spec.Description, projectId)
+		}
+	case devopsv1alpha1.CredentialTypeSsh:
+		if exists {
+			_, err = jenkins.UpdateSshCredentialInFolder(spec.Domain, id, data(devopsv1alpha1.SecretUsernameKey),
+				data(devopsv1alpha1.SecretPassphraseKey), data(devopsv1alpha1.SecretPrivateKeyKey), spec.Description, projectId)
+		} else {
+			_, err = jenkins.CreateSshCredentialInFolder(spec.Domain, id, data(devopsv1alpha1.SecretUsernameKey),
+				data(devopsv1alpha1.SecretPassphraseKey), data(devopsv1alpha1.SecretPrivateKeyKey), spec.Description, projectId)
+		}
+	case devopsv1alpha1.CredentialTypeSecretText:
+		if exists {
+			_, err = jenkins.UpdateSecretTextCredentialInFolder(spec.Domain, id, data(devopsv1alpha1.SecretTextKey), spec.Description, projectId)
+		} else {
+			_, err = jenkins.CreateSecretTextCredentialInFolder(spec.Domain, id, data(devopsv1alpha1.SecretTextKey), spec.Description, projectId)
+		}
+	case devopsv1alpha1.CredentialTypeKubeConfig:
+		if exists {
+			_, err = jenkins.UpdateKubeconfigCredentialInFolder(spec.Domain, id, data(devopsv1alpha1.SecretKubeconfigKey), spec.Description, projectId)
+		} else {
+			_, err = jenkins.CreateKubeconfigCredentialInFolder(spec.Domain, id, data(devopsv1alpha1.SecretKubeconfigKey), spec.Description, projectId)
+		}
+	default:
+		return exists, fmt.Errorf("unsupported credential type %s", spec.Type)
+	}
+	if err != nil {
+		return exists, err
+	}
+	return true, nil
+}
@@ -0,0 +1,117 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package devops
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/gojenkins"
+	"kubesphere.io/kubesphere/pkg/gojenkins/utils"
+)
+
+const (
+	finalizer = "finalizers.devops.kubesphere.io"
+
+	// resources are applied to Jenkins again after the period to repair drift
+	resyncPeriod = 10 * time.Minute
+
+	// retry period if Jenkins or the project isn't ready
+	retryPeriod = 30 * time.Second
+
+	ReasonJenkinsUnavailable = "JenkinsUnavailable"
+	ReasonJenkinsError       = "JenkinsError"
+	ReasonInvalidSpec        = "InvalidSpec"
+	ReasonProjectNotReady    = "ProjectNotReady"
+	ReasonProjectForbidden   = "ProjectForbidden"
+	ReasonAlreadyExists      = "AlreadyExists"
+	ReasonSecretNotFound     = "SecretNotFound"
+	ReasonCredentialNotFound = "CredentialNotFound"
+	ReasonApplied            = "Applied"
+)
+
+// jenkinsClient returns the Jenkins client or nil if Jenkins is unavailable
+type jenkinsClient func() *gojenkins.Jenkins
+
+func isNotFound(err error) bool {
+	return err != nil && utils.GetJenkinsStatusCode(err) == http.StatusNotFound
+}
+
+// alreadyExistsError is returned if a credential or job exists in Jenkins but wasn't created or adopted by the resource
+type alreadyExistsError struct {
+	kind string
+	name string
+}
+
+func (e *alreadyExistsError) Error() string {
+	return fmt.Sprintf("%s %s already exists in jenkins, annotate %s=true to adopt it", e.kind, e.name, devopsv1alpha1.AdoptAnnotation)
+}
+
+func isAlreadyExists(err error) bool {
+	_, ok := err.(*alreadyExistsError)
+	return ok
+}
+
+// isBound returns true if credentials and pipelines in the namespace may refer to the project
+func isBound(project *devopsv1alpha1.DevOpsProject, namespace string) bool {
+	return project.Spec.Namespace != "" && project.Spec.Namespace == namespace
+}
+
+// isAdopting returns true if the resource takes over the credential or job which already exists in Jenkins
+func isAdopting(object metav1.Object) bool {
+	return object.GetAnnotations()[devopsv1alpha1.AdoptAnnotation] == "true"
+}
+
+// setReadyCondition sets the Ready condition, the transition time is only updated if the status changes
+func setReadyCondition(conditions []devopsv1alpha1.Condition, status corev1.ConditionStatus, reason, message string) []devopsv1alpha1.Condition {
+	condition := devopsv1alpha1.Condition{
+		Type:               devopsv1alpha1.ConditionReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	}
+
+	for i := range conditions {
+		if conditions[i].Type != devopsv1alpha1.ConditionReady {
+			continue
+		}
+		if conditions[i].Status == status {
+			condition.LastTransitionTime = conditions[i].LastTransitionTime
+		}
+		conditions[i] = condition
+		return conditions
+	}
+
+	return append(conditions, condition)
+}
+
+// isReady returns true if the Ready condition is true
+func isReady(conditions []devopsv1alpha1.Condition) bool {
+	for _, condition := range conditions {
+		if condition.Type == devopsv1alpha1.ConditionReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,390 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package devops
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/gojenkins"
+	devopsmodel "kubesphere.io/kubesphere/pkg/models/devops"
+)
+
+type fakeRole struct {
+	pattern       string
+	permissionIds map[string]bool
+	sids          map[string]bool
+}
+
+// fakeJenkins serves the folder, job, credential and role-strategy endpoints used by the controllers
+type fakeJenkins struct {
+	sync.Mutex
+	// jobs are keyed by their path, e.g. project/pipeline
+	jobs        map[string]string
+	configPosts int
+	// credentials are keyed by project/domain/id
+	credentials map[string]string
+	roles       map[string]*fakeRole
+}
+
+func newFakeJenkins() *fakeJenkins {
+	return &fakeJenkins{jobs: map[string]string{}, credentials: map[string]string{}, roles: map[string]*fakeRole{}}
+}
+
+func (f *fakeJenkins) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	query := r.URL.Query()
+	path := r.URL.Path
+
+	if strings.HasPrefix(path, "/role-strategy/strategy/") {
+		f.serveRole(w, r, strings.Trim(strings.TrimPrefix(path, "/role-strategy/strategy/"), "/"))
+		return
+	}
+
+	if path == "/createItem" {
+		f.jobs[query.Get("name")] = "folder"
+		return
+	}
+
+	// /job/a/job/b/<action>
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var jobPath []string
+	i := 0
+	for ; i+1 < len(segments) && segments[i] == "job"; i += 2 {
+		jobPath = append(jobPath, segments[i+1])
+	}
+	if len(jobPath) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	job := strings.Join(jobPath, "/")
+	action := strings.Join(segments[i:], "/")
+
+	if strings.HasPrefix(action, "credentials/store/folder/domain/") {
+		f.serveCredential(w, r, job, strings.Split(strings.TrimPrefix(action, "credentials/store/folder/domain/"), "/"))
+		return
+	}
+
+	_, exists := f.jobs[job]
+	switch {
+	case !exists:
+		http.NotFound(w, r)
+	case action == "createItem" && r.Method == http.MethodPost:
+		body, _ := ioutil.ReadAll(r.Body)
+		f.jobs[job+"/"+query.Get("name")] = string(body)
+	case action == "api/json":
+		json.NewEncoder(w).Encode(map[string]string{"name": jobPath[len(jobPath)-1]})
+	case action == "config.xml" && r.Method == http.MethodPost:
+		body, _ := ioutil.ReadAll(r.Body)
+		f.jobs[job] = string(body)
+		f.configPosts++
+	case action == "doDelete":
+		for key := range f.jobs {
+			if key == job || strings.HasPrefix(key, job+"/") {
+				delete(f.jobs, key)
+			}
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeJenkins) serveCredential(w http.ResponseWriter, r *http.Request, project string, segments []string) {
+	domain := segments[0]
+	if len(segments) == 2 && segments[1] == "createCredentials" {
+		request := struct {
+			Credentials map[string]string `json:"credentials"`
+		}{}
+		json.Unmarshal([]byte(r.URL.Query().Get("json")), &request)
+		f.credentials[project+"/"+domain+"/"+request.Credentials["id"]] = r.URL.Query().Get("json")
+		return
+	}
+	if len(segments) < 3 || segments[1] != "credential" {
+		http.NotFound(w, r)
+		return
+	}
+
+	key := project + "/" + domain + "/" + segments[2]
+	if _, ok := f.credentials[key]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch strings.Join(segments[3:], "/") {
+	case "api/json":
+		json.NewEncoder(w).Encode(map[string]string{"id": segments[2]})
+	case "updateSubmit":
+		f.credentials[key] = r.URL.Query().Get("json")
+	case "doDelete":
+		delete(f.credentials, key)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeJenkins) serveRole(w http.ResponseWriter, r *http.Request, action string) {
+	query := r.URL.Query()
+	role := f.roles[query.Get("roleName")]
+
+	switch action {
+	case "getRole":
+		if role == nil {
+			w.Write([]byte("{}"))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"permissionIds": role.permissionIds, "pattern": role.pattern})
+	case "addRole":
+		if role != nil && query.Get("overwrite") != "true" {
+			return
+		}
+		permissionIds := map[string]bool{}
+		for _, id := range strings.Split(query.Get("permissionIds"), ",") {
+			if id != "" {
+				permissionIds[id] = true
+			}
+		}
+		// roles are recreated without sids when they're overwritten
+		f.roles[query.Get("roleName")] = &fakeRole{pattern: query.Get("pattern"), permissionIds: permissionIds, sids: map[string]bool{}}
+	case "assignRole", "unassignRole":
+		if role == nil {
+			http.Error(w, "role not found", http.StatusBadRequest)
+			return
+		}
+		role.sids[query.Get("sid")] = action == "assignRole"
+	case "removeRoles":
+		for _, name := range strings.Split(query.Get("roleNames"), ",") {
+			delete(f.roles, name)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeJenkins) assigned(role, sid string) bool {
+	f.Lock()
+	defer f.Unlock()
+	return f.roles[role] != nil && f.roles[role].sids[sid]
+}
+
+func newTestJenkins() (*fakeJenkins, *gojenkins.Jenkins, func()) {
+	fake := newFakeJenkins()
+	server := httptest.NewServer(fake)
+	return fake, gojenkins.CreateJenkins(nil, server.URL, 10, "admin", "password"), server.Close
+}
+
+func TestSyncProject(t *testing.T) {
+	fake, jenkins, stop := newTestJenkins()
+	defer stop()
+
+	projectId := "project-B6BZVN3mOPvx"
+	spec := &devopsv1alpha1.DevOpsProjectSpec{
+		Workspace: "system-workspace",
+		Members: []devopsv1alpha1.ProjectMember{
+			{Username: "admin", Role: devopsv1alpha1.ProjectOwner},
+			{Username: "dev", Role: devopsv1alpha1.ProjectDeveloper},
+		},
+	}
+
+	if err := syncProject(jenkins, projectId, spec, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.jobs[projectId] != "folder" {
+		t.Errorf("folder %s not created", projectId)
+	}
+	if len(fake.roles) != 2*len(devopsmodel.AllRoleSlice)+1 {
+		t.Errorf("expected %d roles, got %d", 2*len(devopsmodel.AllRoleSlice)+1, len(fake.roles))
+	}
+	if role := fake.roles[devopsmodel.GetPipelineRoleName(projectId, devopsv1alpha1.ProjectOwner)]; role == nil || role.pattern != devopsmodel.GetPipelineRolePattern(projectId) {
+		t.Errorf("unexpected pipeline role %+v", role)
+	}
+	for _, role := range []string{devopsmodel.JenkinsAllUserRoleName, devopsmodel.GetProjectRoleName(projectId, devopsv1alpha1.ProjectOwner),
+		devopsmodel.GetPipelineRoleName(projectId, devopsv1alpha1.ProjectOwner)} {
+		if !fake.assigned(role, "admin") {
+			t.Errorf("role %s not assigned to admin", role)
+		}
+	}
+
+	// the pattern of a role drifts, dev becomes a reporter and admin is removed
+	fake.roles[devopsmodel.GetProjectRoleName(projectId, devopsv1alpha1.ProjectReporter)].pattern = ".*"
+	applied := spec.Members
+	spec.Members = []devopsv1alpha1.ProjectMember{{Username: "dev", Role: devopsv1alpha1.ProjectReporter}}
+
+	if err := syncProject(jenkins, projectId, spec, applied); err != nil {
+		t.Fatal(err)
+	}
+
+	if role := fake.roles[devopsmodel.GetProjectRoleName(projectId, devopsv1alpha1.ProjectReporter)]; role.pattern != devopsmodel.GetProjectRolePattern(projectId) {
+		t.Errorf("role pattern not repaired, got %s", role.pattern)
+	}
+	if !fake.assigned(devopsmodel.GetProjectRoleName(projectId, devopsv1alpha1.ProjectReporter), "dev") {
+		t.Error("reporter role not assigned to dev")
+	}
+	if fake.assigned(devopsmodel.GetProjectRoleName(projectId, devopsv1alpha1.ProjectDeveloper), "dev") {
+		t.Error("developer role not unassigned from dev")
+	}
+	if fake.assigned(devopsmodel.GetPipelineRoleName(projectId, devopsv1alpha1.ProjectOwner), "admin") {
+		t.Error("owner role not unassigned from admin")
+	}
+
+	if err := deleteProject(jenkins, projectId); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.jobs[projectId]; ok {
+		t.Error("folder not deleted")
+	}
+	if len(fake.roles) != 1 {
+		t.Errorf("project roles not deleted, %d roles left", len(fake.roles))
+	}
+
+	// the folder is already gone
+	if err := deleteProject(jenkins, projectId); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSyncPipeline(t *testing.T) {
+	fake, jenkins, stop := newTestJenkins()
+	defer stop()
+
+	fake.jobs["project"] = "folder"
+
+	if err := syncPipeline(jenkins, "project", "build", "<v1/>", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if fake.jobs["project/build"] != "<v1/>" {
+		t.Errorf("unexpected config %q", fake.jobs["project/build"])
+	}
+
+	if err := syncPipeline(jenkins, "project", "build", "<v2/>", true, false); err != nil {
+		t.Fatal(err)
+	}
+	if fake.configPosts != 0 {
+		t.Errorf("config posted %d times without update", fake.configPosts)
+	}
+
+	if err := syncPipeline(jenkins, "project", "build", "<v2/>", true, true); err != nil {
+		t.Fatal(err)
+	}
+	if fake.jobs["project/build"] != "<v2/>" {
+		t.Errorf("config not updated, got %q", fake.jobs["project/build"])
+	}
+
+	// the job of another pipeline is never replaced
+	if err := syncPipeline(jenkins, "project", "build", "<v3/>", false, true); !isAlreadyExists(err) {
+		t.Errorf("expected already exists error, got %v", err)
+	}
+	if fake.jobs["project/build"] != "<v2/>" {
+		t.Errorf("config of another pipeline replaced, got %q", fake.jobs["project/build"])
+	}
+
+	if err := syncPipeline(jenkins, "missing", "build", "<v1/>", false, true); err == nil {
+		t.Error("expected error creating a pipeline in a missing folder")
+	}
+}
+
+func TestSyncCredential(t *testing.T) {
+	fake, jenkins, stop := newTestJenkins()
+	defer stop()
+
+	fake.jobs["project"] = "folder"
+	spec := &devopsv1alpha1.CredentialSpec{Project: "project", Type: devopsv1alpha1.CredentialTypeUsernamePassword}
+
+	found, err := syncCredential(jenkins, "project", "github", spec, nil, false, false)
+	if err != nil || found {
+		t.Fatalf("expected credential not found, got %v %v", found, err)
+	}
+
+	secret := &corev1.Secret{Data: map[string][]byte{
+		devopsv1alpha1.SecretUsernameKey: []byte("kubesphere"),
+		devopsv1alpha1.SecretPasswordKey: []byte("v1"),
+	}}
+	found, err = syncCredential(jenkins, "project", "github", spec, secret, false, false)
+	if err != nil || !found {
+		t.Fatalf("expected credential created, got %v %v", found, err)
+	}
+	if !strings.Contains(fake.credentials["project/_/github"], `"password":"v1"`) {
+		t.Errorf("unexpected credential %s", fake.credentials["project/_/github"])
+	}
+
+	secret.Data[devopsv1alpha1.SecretPasswordKey] = []byte("v2")
+	if _, err := syncCredential(jenkins, "project", "github", spec, secret, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(fake.credentials["project/_/github"], `"password":"v2"`) {
+		t.Error("credential updated without update")
+	}
+	if _, err := syncCredential(jenkins, "project", "github", spec, secret, true, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(fake.credentials["project/_/github"], `"password":"v2"`) {
+		t.Errorf("credential not updated, got %s", fake.credentials["project/_/github"])
+	}
+
+	// the credential of another resource is never replaced
+	secret.Data[devopsv1alpha1.SecretPasswordKey] = []byte("v3")
+	if _, err := syncCredential(jenkins, "project", "github", spec, secret, false, true); !isAlreadyExists(err) {
+		t.Errorf("expected already exists error, got %v", err)
+	}
+	if !strings.Contains(fake.credentials["project/_/github"], `"password":"v2"`) {
+		t.Errorf("credential of another resource replaced, got %s", fake.credentials["project/_/github"])
+	}
+
+	found, err = syncCredential(jenkins, "project", "github", spec, nil, true, false)
+	if err != nil || !found {
+		t.Errorf("expected credential found, got %v %v", found, err)
+	}
+}
+
+func TestIsBound(t *testing.T) {
+	project := &devopsv1alpha1.DevOpsProject{}
+	if isBound(project, "") || isBound(project, "default") {
+		t.Error("project without namespace shouldn't be bound")
+	}
+	project.Spec.Namespace = "devops"
+	if !isBound(project, "devops") || isBound(project, "default") {
+		t.Errorf("unexpected binding of project in namespace %s", project.Spec.Namespace)
+	}
+}
+
+func TestSetReadyCondition(t *testing.T) {
+	conditions := setReadyCondition(nil, corev1.ConditionFalse, ReasonJenkinsUnavailable, "")
+	if isReady(conditions) || len(conditions) != 1 {
+		t.Fatalf("unexpected conditions %+v", conditions)
+	}
+	transition := conditions[0].LastTransitionTime
+
+	conditions = setReadyCondition(conditions, corev1.ConditionFalse, ReasonJenkinsError, "error")
+	if conditions[0].Reason != ReasonJenkinsError || !conditions[0].LastTransitionTime.Equal(&transition) {
+		t.Errorf("unexpected condition %+v", conditions[0])
+	}
+
+	conditions = setReadyCondition(conditions, corev1.ConditionTrue, ReasonApplied, "")
+	if !isReady(conditions) || len(conditions) != 1 {
+		t.Errorf("unexpected conditions %+v", conditions)
+	}
+}
@@ -0,0 +1,287 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package devops
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/db"
+	"kubesphere.io/kubesphere/pkg/gojenkins"
+	devopsmodel "kubesphere.io/kubesphere/pkg/models/devops"
+	"kubesphere.io/kubesphere/pkg/simple/client/admin_jenkins"
+	"kubesphere.io/kubesphere/pkg/simple/client/devops_mysql"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+var log = logf.Log.WithName("devops-controller")
+
+// Add creates the DevOpsProject, Pipeline and Credential Controllers and adds them to the Manager with default RBAC.
+// The Manager will set fields on the Controllers and Start them when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	if err := addDevOpsProject(mgr, &ReconcileDevOpsProject{Client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		recorder: mgr.GetRecorder("devopsproject-controller"), jenkins: admin_jenkins.Client,
+		database: devops_mysql.OpenDatabase}); err != nil {
+		return err
+	}
+	if err := addPipeline(mgr, &ReconcilePipeline{Client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		recorder: mgr.GetRecorder("pipeline-controller"), jenkins: admin_jenkins.Client}); err != nil {
+		return err
+	}
+	return addCredential(mgr, &ReconcileCredential{Client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		recorder: mgr.GetRecorder("credential-controller"), jenkins: admin_jenkins.Client})
+}
+
+// addDevOpsProject adds a new Controller to mgr with r as the reconcile.Reconciler
+func addDevOpsProject(mgr manager.Manager, r reconcile.Reconciler) error {
+	c, err := controller.New("devopsproject-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	return c.Watch(&source.Kind{Type: &devopsv1alpha1.DevOpsProject{}}, &handler.EnqueueRequestForObject{})
+}
+
+var _ reconcile.Reconciler = &ReconcileDevOpsProject{}
+
+// ReconcileDevOpsProject applies DevOpsProjects to Jenkins
+type ReconcileDevOpsProject struct {
+	client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	jenkins  jenkinsClient
+	database func() *db.Database
+}
+
+// Reconcile creates the folder and roles of the project in Jenkins and grants the roles to its members,
+// the folder and roles are deleted with the project
+// +kubebuilder:rbac:groups=devops.kubesphere.io,resources=devopsprojects,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=devops.kubesphere.io,resources=devopsprojects/status,verbs=get;update;patch
+func (r *ReconcileDevOpsProject) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	instance := &devopsv1alpha1.DevOpsProject{}
+	err := r.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	projectId := devopsmodel.ResourceProjectId(instance)
+
+	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !sliceutil.HasString(instance.ObjectMeta.Finalizers, finalizer) {
+			instance.ObjectMeta.Finalizers = append(instance.ObjectMeta.Finalizers, finalizer)
+			return reconcile.Result{}, r.Update(context.TODO(), instance)
+		}
+	} else {
+		if sliceutil.HasString(instance.ObjectMeta.Finalizers, finalizer) {
+			// the folder is created with the status, nothing to clean up otherwise
+			if instance.Status.ProjectId != "" {
+				jenkins := r.jenkins()
+				if jenkins == nil {
+					return reconcile.Result{}, fmt.Errorf("could not connect to jenkins")
+				}
+				if err := deleteProject(jenkins, instance.Status.ProjectId); err != nil {
+					return reconcile.Result{}, err
+				}
+				// projects migrated from the database are deactivated, otherwise they are restored as orphans
+				if strings.HasPrefix(instance.Status.ProjectId, devopsmodel.DevOpsProjectPrefix) {
+					if err := devopsmodel.DeactivateProject(r.database(), instance.Status.ProjectId); err != nil {
+						return reconcile.Result{}, err
+					}
+				}
+			}
+
+			instance.ObjectMeta.Finalizers = sliceutil.RemoveString(instance.ObjectMeta.Finalizers, func(item string) bool {
+				return item == finalizer
+			})
+			return reconcile.Result{}, r.Update(context.TODO(), instance)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	status := instance.Status.DeepCopy()
+	result := reconcile.Result{RequeueAfter: resyncPeriod}
+
+	if status.ProjectId != "" && status.ProjectId != projectId {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonInvalidSpec,
+			fmt.Sprintf("projectId can't be changed from %s", status.ProjectId))
+		return result, r.updateStatus(instance, status)
+	}
+
+	if err := validateMembers(instance.Spec.Members); err != nil {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonInvalidSpec, err.Error())
+		return result, r.updateStatus(instance, status)
+	}
+
+	jenkins := r.jenkins()
+	if jenkins == nil {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonJenkinsUnavailable, "could not connect to jenkins")
+		return reconcile.Result{RequeueAfter: retryPeriod}, r.updateStatus(instance, status)
+	}
+
+	if err := syncProject(jenkins, projectId, &instance.Spec, status.Members); err != nil {
+		log.Error(err, "apply devops project failed", "name", instance.Name)
+		r.recorder.Event(instance, corev1.EventTypeWarning, ReasonJenkinsError, err.Error())
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonJenkinsError, err.Error())
+		if statusErr := r.updateStatus(instance, status); statusErr != nil {
+			return reconcile.Result{}, statusErr
+		}
+		return reconcile.Result{}, err
+	}
+
+	status.ProjectId = projectId
+	status.Members = instance.Spec.Members
+	status.ObservedGeneration = instance.Generation
+	status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionTrue, ReasonApplied, "")
+
+	return result, r.updateStatus(instance, status)
+}
+
+func (r *ReconcileDevOpsProject) updateStatus(instance *devopsv1alpha1.DevOpsProject, status *devopsv1alpha1.DevOpsProjectStatus) error {
+	if reflect.DeepEqual(&instance.Status, status) {
+		return nil
+	}
+	instance.Status = *status
+	return r.Status().Update(context.TODO(), instance)
+}
+
+func validateMembers(members []devopsv1alpha1.ProjectMember) error {
+	usernames := make(map[string]bool, len(members))
+	for _, member := range members {
+		if !sliceutil.HasString(devopsmodel.AllRoleSlice, member.Role) {
+			return fmt.Errorf("invalid role %s of member %s", member.Role, member.Username)
+		}
+		if usernames[member.Username] {
+			return fmt.Errorf("duplicate member %s", member.Username)
+		}
+		usernames[member.Username] = true
+	}
+	return nil
+}
+
+// syncProject creates the folder and roles of the project if they don't exist, updates the roles if their permissions
+// drift, grants the roles to the members and revokes roles of previously applied members which aren't granted anymore
+func syncProject(jenkins *gojenkins.Jenkins, projectId string, spec *devopsv1alpha1.DevOpsProjectSpec, applied []devopsv1alpha1.ProjectMember) error {
+	_, err := jenkins.GetJob(projectId)
+	if isNotFound(err) {
+		if _, err := jenkins.CreateFolder(projectId, spec.Description); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	// roles are replaced when they're updated, all members are granted roles again after that
+	for role, permission := range devopsmodel.JenkinsProjectPermissionMap {
+		if err := syncProjectRole(jenkins, devopsmodel.GetProjectRoleName(projectId, role), devopsmodel.GetProjectRolePattern(projectId), permission); err != nil {
+			return err
+		}
+	}
+	for role, permission := range devopsmodel.JenkinsPipelinePermissionMap {
+		if err := syncProjectRole(jenkins, devopsmodel.GetPipelineRoleName(projectId, role), devopsmodel.GetPipelineRolePattern(projectId), permission); err != nil {
+			return err
+		}
+	}
+
+	globalRole, err := jenkins.GetGlobalRole(devopsmodel.JenkinsAllUserRoleName)
+	if err != nil {
+		return err
+	}
+	if globalRole == nil {
+		globalRole, err = jenkins.AddGlobalRole(devopsmodel.JenkinsAllUserRoleName, gojenkins.GlobalPermissionIds{GlobalRead: true}, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	granted := make(map[string]string, len(spec.Members))
+	for _, member := range spec.Members {
+		granted[member.Username] = member.Role
+
+		if err := globalRole.AssignRole(member.Username); err != nil {
+			return err
+		}
+		if err := projectRole(jenkins, devopsmodel.GetProjectRoleName(projectId, member.Role)).AssignRole(member.Username); err != nil {
+			return err
+		}
+		if err := projectRole(jenkins, devopsmodel.GetPipelineRoleName(projectId, member.Role)).AssignRole(member.Username); err != nil {
+			return err
+		}
+	}
+
+	for _, member := range applied {
+		if role, ok := granted[member.Username]; ok && role == member.Role {
+			continue
+		}
+		if err := projectRole(jenkins, devopsmodel.GetProjectRoleName(projectId, member.Role)).UnAssignRole(member.Username); err != nil {
+			return err
+		}
+		if err := projectRole(jenkins, devopsmodel.GetPipelineRoleName(projectId, member.Role)).UnAssignRole(member.Username); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func syncProjectRole(jenkins *gojenkins.Jenkins, name, pattern string, permission gojenkins.ProjectPermissionIds) error {
+	role, err := jenkins.GetProjectRole(name)
+	if err != nil {
+		return err
+	}
+	if role != nil && role.Raw.Pattern == pattern && role.Raw.PermissionIds == permission {
+		return nil
+	}
+	_, err = jenkins.AddProjectRole(name, pattern, permission, true)
+	return err
+}
+
+func projectRole(jenkins *gojenkins.Jenkins, name string) *gojenkins.ProjectRole {
+	return &gojenkins.ProjectRole{Jenkins: jenkins, Raw: gojenkins.ProjectRoleResponse{RoleName: name}}
+}
+
+// deleteProject deletes the folder and roles of the project
+func deleteProject(jenkins *gojenkins.Jenkins, projectId string) error {
+	if _, err := jenkins.DeleteJob(projectId); err != nil && !isNotFound(err) {
+		return err
+	}
+
+	roleNames := make([]string, 0)
+	for role := range devopsmodel.JenkinsProjectPermissionMap {
+		roleNames = append(roleNames, devopsmodel.GetProjectRoleName(projectId, role))
+		roleNames = append(roleNames, devopsmodel.GetPipelineRoleName(projectId, role))
+	}
+	return jenkins.DeleteProjectRoles(roleNames...)
+}
@@ -0,0 +1,230 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package devops
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/gojenkins"
+	devopsmodel "kubesphere.io/kubesphere/pkg/models/devops"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+// addPipeline adds a new Controller to mgr with r as the reconcile.Reconciler
+func addPipeline(mgr manager.Manager, r *ReconcilePipeline) error {
+	c, err := controller.New("pipeline-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &devopsv1alpha1.Pipeline{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// pipelines are waiting for their project to be ready
+	return c.Watch(&source.Kind{Type: &devopsv1alpha1.DevOpsProject{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(object handler.MapObject) []reconcile.Request {
+			pipelines := &devopsv1alpha1.PipelineList{}
+			if err := r.List(context.TODO(), &client.ListOptions{}, pipelines); err != nil {
+				log.Error(err, "list pipelines failed")
+				return nil
+			}
+			requests := make([]reconcile.Request, 0)
+			for _, pipeline := range pipelines.Items {
+				if pipeline.Spec.Project == object.Meta.GetName() {
+					requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: pipeline.Namespace, Name: pipeline.Name}})
+				}
+			}
+			return requests
+		}),
+	})
+}
+
+var _ reconcile.Reconciler = &ReconcilePipeline{}
+
+// ReconcilePipeline applies Pipelines to Jenkins
+type ReconcilePipeline struct {
+	client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	jenkins  jenkinsClient
+}
+
+// Reconcile creates the job of the pipeline in the folder of its project and updates the config of the job
+// when the spec changes, the job is deleted with the pipeline
+// +kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelines,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelines/status,verbs=get;update;patch
+func (r *ReconcilePipeline) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	instance := &devopsv1alpha1.Pipeline{}
+	err := r.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	project := &devopsv1alpha1.DevOpsProject{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: instance.Spec.Project}, project)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	projectFound := err == nil
+	// only pipelines in the namespace of the project are applied to its folder
+	projectBound := projectFound && isBound(project, instance.Namespace)
+
+	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !sliceutil.HasString(instance.ObjectMeta.Finalizers, finalizer) {
+			instance.ObjectMeta.Finalizers = append(instance.ObjectMeta.Finalizers, finalizer)
+			return reconcile.Result{}, r.Update(context.TODO(), instance)
+		}
+	} else {
+		if sliceutil.HasString(instance.ObjectMeta.Finalizers, finalizer) {
+			// the job has been deleted with the folder of the project,
+			// jobs which weren't created or adopted by the resource are left as is
+			if instance.Status.Name != "" && projectBound && project.Status.ProjectId == instance.Status.ProjectId &&
+				project.ObjectMeta.DeletionTimestamp.IsZero() {
+				jenkins := r.jenkins()
+				if jenkins == nil {
+					return reconcile.Result{}, fmt.Errorf("could not connect to jenkins")
+				}
+				if _, err := jenkins.DeleteJob(instance.Status.Name, instance.Status.ProjectId); err != nil && !isNotFound(err) {
+					return reconcile.Result{}, err
+				}
+			}
+
+			instance.ObjectMeta.Finalizers = sliceutil.RemoveString(instance.ObjectMeta.Finalizers, func(item string) bool {
+				return item == finalizer
+			})
+			return reconcile.Result{}, r.Update(context.TODO(), instance)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	status := instance.Status.DeepCopy()
+
+	if projectFound && !projectBound {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonProjectForbidden,
+			fmt.Sprintf("devops project %s isn't bound to namespace %s", instance.Spec.Project, instance.Namespace))
+		return reconcile.Result{}, r.updateStatus(instance, status)
+	}
+
+	if !projectFound || !isReady(project.Status.Conditions) {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonProjectNotReady,
+			fmt.Sprintf("devops project %s is not ready", instance.Spec.Project))
+		return reconcile.Result{RequeueAfter: retryPeriod}, r.updateStatus(instance, status)
+	}
+
+	name := pipelineNameOf(instance)
+	if status.Name != "" && (status.Name != name || status.ProjectId != project.Status.ProjectId) {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonInvalidSpec,
+			fmt.Sprintf("pipeline can't be changed from %s/%s", status.ProjectId, status.Name))
+		return reconcile.Result{}, r.updateStatus(instance, status)
+	}
+
+	config, err := devopsmodel.PipelineConfigXml(project.Status.ProjectId, devopsmodel.ProjectPipelineFromSpec(name, &instance.Spec))
+	if err != nil {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonInvalidSpec, err.Error())
+		return reconcile.Result{}, r.updateStatus(instance, status)
+	}
+
+	jenkins := r.jenkins()
+	if jenkins == nil {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonJenkinsUnavailable, "could not connect to jenkins")
+		return reconcile.Result{RequeueAfter: retryPeriod}, r.updateStatus(instance, status)
+	}
+
+	// updating the config of a multi-branch pipeline triggers a scan of the repository,
+	// the config is only posted again when the spec changes
+	owned := status.Name != "" || isAdopting(instance)
+	err = syncPipeline(jenkins, project.Status.ProjectId, name, config, owned, instance.Generation != status.ObservedGeneration)
+	if isAlreadyExists(err) {
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonAlreadyExists, err.Error())
+		return reconcile.Result{}, r.updateStatus(instance, status)
+	}
+	if err != nil {
+		log.Error(err, "apply pipeline failed", "namespace", instance.Namespace, "name", instance.Name)
+		r.recorder.Event(instance, corev1.EventTypeWarning, ReasonJenkinsError, err.Error())
+		status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionFalse, ReasonJenkinsError, err.Error())
+		if statusErr := r.updateStatus(instance, status); statusErr != nil {
+			return reconcile.Result{}, statusErr
+		}
+		return reconcile.Result{}, err
+	}
+
+	status.ProjectId = project.Status.ProjectId
+	status.Name = name
+	status.ObservedGeneration = instance.Generation
+	status.Conditions = setReadyCondition(status.Conditions, corev1.ConditionTrue, ReasonApplied, "")
+
+	return reconcile.Result{RequeueAfter: resyncPeriod}, r.updateStatus(instance, status)
+}
+
+func (r *ReconcilePipeline) updateStatus(instance *devopsv1alpha1.Pipeline, status *devopsv1alpha1.PipelineStatus) error {
+	if reflect.DeepEqual(&instance.Status, status) {
+		return nil
+	}
+	instance.Status = *status
+	return r.Status().Update(context.TODO(), instance)
+}
+
+// pipelineNameOf returns the name of the job of the pipeline in Jenkins
+func pipelineNameOf(pipeline *devopsv1alpha1.Pipeline) string {
+	if pipeline.Spec.Name != "" {
+		return pipeline.Spec.Name
+	}
+	return pipeline.Name
+}
+
+// syncPipeline creates the job in the folder of the project if it doesn't exist, the config of an existing job
+// is replaced when update is true. An existing job which isn't owned by the resource is never touched,
+// an alreadyExistsError is returned.
+func syncPipeline(jenkins *gojenkins.Jenkins, projectId, name, config string, owned, update bool) error {
+	job, err := jenkins.GetJob(name, projectId)
+	if isNotFound(err) {
+		_, err = jenkins.CreateJobInFolder(config, name, projectId)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if !owned {
+		return &alreadyExistsError{kind: "pipeline", name: name}
+	}
+
+	if !update {
+		return nil
+	}
+	return job.UpdateConfig(config)
+}
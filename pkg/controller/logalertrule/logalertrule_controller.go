@@ -0,0 +1,251 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package logalertrule
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	alertingv1alpha1 "kubesphere.io/kubesphere/pkg/apis/alerting/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/simple/client/kubesphere"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+	"kubesphere.io/kubesphere/pkg/simple/client/notification"
+)
+
+const defaultInterval = time.Minute
+
+var log = logf.Log.WithName("logalertrule-controller")
+
+// Add creates a new LogAlertRule Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileLogAlertRule{Client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		recorder: mgr.GetRecorder("logalertrule-controller"), ksclient: kubesphere.Client()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("logalertrule-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to LogAlertRule
+	err = c.Watch(&source.Kind{Type: &alertingv1alpha1.LogAlertRule{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var _ reconcile.Reconciler = &ReconcileLogAlertRule{}
+
+// ReconcileLogAlertRule evaluates LogAlertRules periodically
+type ReconcileLogAlertRule struct {
+	client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	ksclient kubesphere.Interface
+}
+
+// Reconcile counts logs matching the rule in its window through the logging API of ks-apiserver, records
+// the state in the status and notifies receivers when the rule fires or resolves
+// +kubebuilder:rbac:groups=alerting.kubesphere.io,resources=logalertrules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=alerting.kubesphere.io,resources=logalertrules/status,verbs=get;update;patch
+func (r *ReconcileLogAlertRule) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	instance := &alertingv1alpha1.LogAlertRule{}
+	err := r.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	now := time.Now()
+	interval := defaultInterval
+	if instance.Spec.Interval != nil && instance.Spec.Interval.Duration > 0 {
+		interval = instance.Spec.Interval.Duration
+	}
+
+	// updates of the status trigger reconciliations as well, rules are evaluated once per interval
+	if last := instance.Status.LastEvaluationTime; last != nil && now.Sub(last.Time) < interval {
+		return reconcile.Result{RequeueAfter: interval - now.Sub(last.Time)}, nil
+	}
+
+	count, evalErr := r.count(instance, now)
+
+	status, transited := evaluate(instance.Status, instance.Spec.Threshold, count, evalErr, now)
+
+	instance.Status = status
+	if err := r.Status().Update(context.TODO(), instance); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if evalErr != nil {
+		log.Error(evalErr, "evaluate log alert rule failed", "namespace", instance.Namespace, "name", instance.Name)
+		r.recorder.Event(instance, corev1.EventTypeWarning, "EvaluationFailed", evalErr.Error())
+	}
+
+	if transited {
+		r.notify(instance, now)
+	}
+
+	return reconcile.Result{RequeueAfter: interval}, nil
+}
+
+// evaluate returns the status after the evaluation, transited is true if the rule fires or resolves
+func evaluate(status alertingv1alpha1.LogAlertRuleStatus, threshold, count int64, err error, now time.Time) (alertingv1alpha1.LogAlertRuleStatus, bool) {
+	status.LastEvaluationTime = &metav1.Time{Time: now}
+
+	if status.State == "" {
+		status.State = alertingv1alpha1.StateInactive
+	}
+
+	// the state is kept if logs can't be counted
+	if err != nil {
+		status.Error = err.Error()
+		return status, false
+	}
+
+	status.Error = ""
+	status.Count = count
+
+	state := status.State
+	if count >= threshold {
+		state = alertingv1alpha1.StateFiring
+	} else if status.State == alertingv1alpha1.StateFiring {
+		state = alertingv1alpha1.StateResolved
+	}
+
+	if state == status.State {
+		return status, false
+	}
+
+	status.State = state
+	status.LastTransitionTime = &metav1.Time{Time: now}
+
+	return status, true
+}
+
+func (r *ReconcileLogAlertRule) count(instance *alertingv1alpha1.LogAlertRule, now time.Time) (int64, error) {
+	values := url.Values{}
+	values.Set("operation", logging.OperationStatistics)
+	values.Set("start_time", strconv.FormatInt(logging.Millis(now.Add(-instance.Spec.Window.Duration)), 10))
+	values.Set("end_time", strconv.FormatInt(logging.Millis(now), 10))
+
+	if len(instance.Spec.Workloads) > 0 {
+		values.Set("workloads", strings.Join(instance.Spec.Workloads, ","))
+	}
+	if len(instance.Spec.Containers) > 0 {
+		values.Set("containers", strings.Join(instance.Spec.Containers, ","))
+	}
+	if instance.Spec.LogQuery != "" {
+		values.Set("log_query", instance.Spec.LogQuery)
+	}
+	if instance.Spec.Filter != "" {
+		values.Set("filter", instance.Spec.Filter)
+	}
+
+	result, err := r.ksclient.QueryNamespaceLogs(instance.Namespace, values)
+	if err != nil {
+		return 0, err
+	}
+
+	if result.Statistics == nil {
+		return 0, nil
+	}
+
+	return result.Statistics.Logs, nil
+}
+
+func (r *ReconcileLogAlertRule) notify(instance *alertingv1alpha1.LogAlertRule, now time.Time) {
+	severity := instance.Spec.Severity
+	if severity == "" {
+		severity = alertingv1alpha1.SeverityWarning
+	}
+
+	alert := notification.Alert{
+		Name:      instance.Name,
+		Namespace: instance.Namespace,
+		Severity:  severity,
+		State:     instance.Status.State,
+		Summary: fmt.Sprintf("%d logs matched in the last %s, the threshold is %d",
+			instance.Status.Count, instance.Spec.Window.Duration, instance.Spec.Threshold),
+		Time: now,
+	}
+
+	eventType := corev1.EventTypeNormal
+	if alert.State == alertingv1alpha1.StateFiring {
+		eventType = corev1.EventTypeWarning
+	}
+	r.recorder.Event(instance, eventType, strings.Title(alert.State), alert.Summary)
+
+	for _, receiver := range instance.Spec.Receivers {
+		var notifier notification.Notifier
+		var err error
+		switch {
+		case receiver.Webhook != nil:
+			if err = notification.ValidateURL(receiver.Webhook.URL); err == nil {
+				notifier = notification.NewWebhook(receiver.Webhook.URL)
+			}
+		case receiver.Email != nil:
+			notifier = notification.NewEmail(receiver.Email.To)
+		case receiver.Slack != nil:
+			if err = notification.ValidateURL(receiver.Slack.URL); err == nil {
+				notifier = notification.NewSlack(receiver.Slack.URL, receiver.Slack.Channel)
+			}
+		default:
+			continue
+		}
+
+		if err == nil {
+			err = notifier.Notify(alert)
+		}
+
+		// failed notifications are not retried, so that receivers are not notified repeatedly
+		if err != nil {
+			log.Error(err, "notify receiver failed", "namespace", instance.Namespace, "name", instance.Name)
+			r.recorder.Event(instance, corev1.EventTypeWarning, "NotificationFailed", err.Error())
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package logalertrule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	alertingv1alpha1 "kubesphere.io/kubesphere/pkg/apis/alerting/v1alpha1"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	status := alertingv1alpha1.LogAlertRuleStatus{}
+
+	tests := []struct {
+		count     int64
+		err       error
+		state     string
+		transited bool
+	}{
+		{count: 3, state: alertingv1alpha1.StateInactive},
+		{count: 10, state: alertingv1alpha1.StateFiring, transited: true},
+		{count: 20, state: alertingv1alpha1.StateFiring},
+		{err: fmt.Errorf("backend unavailable"), state: alertingv1alpha1.StateFiring},
+		{count: 0, state: alertingv1alpha1.StateResolved, transited: true},
+		{count: 1, state: alertingv1alpha1.StateResolved},
+		{count: 10, state: alertingv1alpha1.StateFiring, transited: true},
+	}
+
+	for i, test := range tests {
+		var transited bool
+		status, transited = evaluate(status, 10, test.count, test.err, now)
+
+		if status.State != test.state || transited != test.transited {
+			t.Errorf("%d: expected state %s transited %v, got %s %v", i, test.state, test.transited, status.State, transited)
+		}
+
+		if (test.err != nil) != (status.Error != "") {
+			t.Errorf("%d: unexpected error %q", i, status.Error)
+		}
+
+		if test.err == nil && status.Count != test.count {
+			t.Errorf("%d: expected count %d, got %d", i, test.count, status.Count)
+		}
+	}
+}
+
+func TestNotifyInvalidReceivers(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileLogAlertRule{recorder: recorder}
+
+	instance := &alertingv1alpha1.LogAlertRule{}
+	instance.Namespace, instance.Name = "default", "nginx-errors"
+	instance.Spec.Receivers = []alertingv1alpha1.Receiver{
+		{Webhook: &alertingv1alpha1.WebhookReceiver{URL: "file:///etc/passwd"}},
+		{Slack: &alertingv1alpha1.SlackReceiver{URL: "gopher://redis:6379/_FLUSHALL"}},
+	}
+	instance.Status.State = alertingv1alpha1.StateFiring
+
+	r.notify(instance, time.Now())
+	close(recorder.Events)
+
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+
+	expected := []string{
+		"Warning Firing 0 logs matched in the last 0s, the threshold is 0",
+		"Warning NotificationFailed invalid url [file:///etc/passwd]",
+		"Warning NotificationFailed invalid url [gopher://redis:6379/_FLUSHALL]",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE `project_notification_receiver` (
+  `project_id`  VARCHAR(50)  NOT NULL,
+  `name`        VARCHAR(255) NOT NULL,
+  `type`        VARCHAR(50)  NOT NULL,
+  `config`      TEXT         NOT NULL,
+  `creator`     VARCHAR(50)  NOT NULL,
+  `create_time` TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`project_id`, `name`)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE `project_pipeline_template` (
+  `project_id`       VARCHAR(50)  NOT NULL,
+  `pipeline`         VARCHAR(255) NOT NULL,
+  `description`      TEXT         NOT NULL,
+  `template`         VARCHAR(255) NOT NULL,
+  `template_version` VARCHAR(255) NOT NULL,
+  `parameters`       TEXT         NOT NULL,
+  `creator`          VARCHAR(50)  NOT NULL,
+  `create_time`      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time`      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`project_id`, `pipeline`),
+  KEY `template` (`template`)
+);
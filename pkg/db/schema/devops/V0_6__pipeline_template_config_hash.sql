@@ -0,0 +1,2 @@
+ALTER TABLE `project_pipeline_template`
+  ADD COLUMN `config_hash` VARCHAR(64) NOT NULL DEFAULT '' AFTER `parameters`;
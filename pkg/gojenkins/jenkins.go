@@ -983,6 +983,28 @@ func (j *Jenkins) AddGlobalRole(roleName string, ids GlobalPermissionIds, overwr
 	return responseRole, nil
 }
 
+// GetAllProjectRoles returns all project roles with the sids assigned to them
+func (j *Jenkins) GetAllProjectRoles() (map[string][]string, error) {
+	roles := make(map[string][]string)
+	stringResponse := ""
+	response, err := j.Requester.Get("/role-strategy/strategy/getAllRoles",
+		&stringResponse,
+		map[string]string{
+			"type": PROJECT_ROLE,
+		})
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(strconv.Itoa(response.StatusCode))
+	}
+	err = json.Unmarshal([]byte(stringResponse), &roles)
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
 func (j *Jenkins) DeleteProjectRoles(roleName ...string) error {
 	responseString := ""
 
@@ -0,0 +1,104 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package informers
+
+import (
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+)
+
+// DynamicInformerFactory provides shared informers of unstructured objects for any resources,
+// informers are started once they are requested and stopped once they are removed.
+type DynamicInformerFactory struct {
+	client    dynamic.Interface
+	lock      sync.Mutex
+	informers map[schema.GroupVersionResource]*dynamicInformer
+}
+
+type dynamicInformer struct {
+	informer cache.SharedIndexInformer
+	stopCh   chan struct{}
+}
+
+var (
+	dynamicOnce            sync.Once
+	dynamicInformerFactory *DynamicInformerFactory
+)
+
+func DynamicSharedInformerFactory() *DynamicInformerFactory {
+	dynamicOnce.Do(func() {
+		dynamicInformerFactory = &DynamicInformerFactory{
+			client:    k8s.DynamicClient(),
+			informers: make(map[schema.GroupVersionResource]*dynamicInformer),
+		}
+	})
+	return dynamicInformerFactory
+}
+
+// ForResource returns the running informer of the resource
+func (f *DynamicInformerFactory) ForResource(gvr schema.GroupVersionResource) cache.SharedIndexInformer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if item, ok := f.informers[gvr]; ok {
+		return item.informer
+	}
+
+	resource := f.client.Resource(gvr)
+
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return resource.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return resource.Watch(options)
+			},
+		},
+		&unstructured.Unstructured{},
+		defaultResync,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+
+	item := &dynamicInformer{informer: informer, stopCh: make(chan struct{})}
+	f.informers[gvr] = item
+
+	go informer.Run(item.stopCh)
+
+	return informer
+}
+
+// Remove stops the informer of the resource
+func (f *DynamicInformerFactory) Remove(gvr schema.GroupVersionResource) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if item, ok := f.informers[gvr]; ok {
+		close(item.stopCh)
+		delete(f.informers, gvr)
+	}
+}
@@ -0,0 +1,264 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kubesphere.io/kubesphere/pkg/apis/alerting/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/simple/client/alertmanager"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+	"kubesphere.io/kubesphere/pkg/simple/client/prometheus"
+)
+
+var (
+	AlertPolicyGroupVersionResource        = v1alpha1.SchemeGroupVersion.WithResource("alertpolicies")
+	ClusterAlertPolicyGroupVersionResource = v1alpha1.SchemeGroupVersion.WithResource("clusteralertpolicies")
+)
+
+// labels of alerts which aren't labels of the series
+var ruleLabels = map[string]bool{
+	"alertname":          true,
+	"alertstate":         true,
+	LabelPolicy:          true,
+	LabelPolicyNamespace: true,
+	LabelSeverity:        true,
+	LabelLevel:           true,
+	LabelMetric:          true,
+}
+
+// ListAlerts returns active alerts of the policies in the namespace, or alerts of cluster policies if namespace
+// is empty, only alerts of the policy if it's set
+func ListAlerts(namespace, policy string) (*AlertsResult, error) {
+	alerts, err := alertmanager.ListAlerts(policyLabels(namespace, policy))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Alert, 0)
+
+	for _, a := range alerts {
+		// alerts without the namespace label are matched too for cluster policies
+		if a.Labels[LabelPolicy] == "" || a.Labels[LabelPolicyNamespace] != namespace {
+			continue
+		}
+
+		alert := alertOf(a.Labels)
+		alert.Message = a.Annotations[AnnotationMessage]
+		alert.Summary = a.Annotations[AnnotationSummary]
+		alert.StartsAt = a.StartsAt
+		alert.SilencedBy = a.Status.SilencedBy
+		alert.Silenced = len(a.Status.SilencedBy) > 0
+		items = append(items, alert)
+	}
+
+	sortAlerts(items)
+
+	return &AlertsResult{Total: len(items), Items: items}, nil
+}
+
+// AlertHistory returns alerts fired by the policies between start and end from the ALERTS series of Prometheus,
+// step is the resolution of the series, alerts firing less than a step may be missed
+func AlertHistory(ctx context.Context, namespace, policy string, start, end time.Time, step time.Duration) (*AlertsResult, error) {
+	if !start.Before(end) {
+		return nil, errors.NewBadRequest("start must be before end")
+	}
+	if step <= 0 {
+		return nil, errors.NewBadRequest("step must be positive")
+	}
+
+	result, err := prometheus.Primary().QueryRange(ctx, alertsSelector(namespace, policy), prometheus.Range{Start: start, End: end, Step: step})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Alert, 0)
+	for _, sample := range result.Samples {
+		series := alertSeries(sample)
+		items = append(items, series.firingPeriods(end, step)...)
+	}
+
+	sortAlerts(items)
+
+	return &AlertsResult{Total: len(items), Items: items}, nil
+}
+
+// alertSeries is a series of ALERTS, it has a sample at every step while the alert is firing
+type alertSeries prometheus.Sample
+
+// firingPeriods returns an alert for every period of successive samples of the series
+func (s *alertSeries) firingPeriods(end time.Time, step time.Duration) []Alert {
+	var timestamps []time.Time
+	for _, value := range s.Values {
+		timestamps = append(timestamps, time.Unix(0, int64(value.Timestamp*float64(time.Second))))
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+
+	var alerts []Alert
+	for i := 0; i < len(timestamps); {
+		j := i
+		// samples are a step apart while firing, allow jitter of half a step
+		for j+1 < len(timestamps) && timestamps[j+1].Sub(timestamps[j]) <= step+step/2 {
+			j++
+		}
+
+		alert := alertOf(s.Metric)
+		alert.StartsAt = timestamps[i]
+		if last := timestamps[j]; last.Add(step).Before(end) {
+			alert.EndsAt = &last
+		}
+		alerts = append(alerts, alert)
+
+		i = j + 1
+	}
+
+	return alerts
+}
+
+// ListAlertSilences returns silences of the policies in the namespace, or silences of cluster policies if namespace
+// is empty, only silences of the policy if it's set
+func ListAlertSilences(namespace, policy string) (*AlertSilencesResult, error) {
+	silences, err := alertmanager.ListSilences(policyLabels(namespace, policy))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]alertmanager.Silence, 0)
+	for _, silence := range silences {
+		if isPolicySilence(&silence, namespace) {
+			items = append(items, silence)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].StartsAt.After(items[j].StartsAt) })
+
+	return &AlertSilencesResult{Total: len(items), Items: items}, nil
+}
+
+// CreateAlertSilence silences notifications of alerts of the policy from now on
+func CreateAlertSilence(namespace, policy string, silence *AlertSilence, username string) (string, error) {
+	duration, err := time.ParseDuration(silence.Duration)
+	if err != nil || duration <= 0 {
+		return "", errors.NewBadRequest(fmt.Sprintf("invalid duration %s", silence.Duration))
+	}
+
+	gvr := AlertPolicyGroupVersionResource
+	if namespace == "" {
+		gvr = ClusterAlertPolicyGroupVersionResource
+	}
+	if _, err := k8s.DynamicClient().Resource(gvr).Namespace(namespace).Get(policy, metav1.GetOptions{}); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	return alertmanager.CreateSilence(&alertmanager.Silence{
+		Matchers: []alertmanager.Matcher{
+			{Name: LabelPolicy, Value: policy},
+			{Name: LabelPolicyNamespace, Value: namespace},
+		},
+		StartsAt:  now,
+		EndsAt:    now.Add(duration),
+		CreatedBy: username,
+		Comment:   silence.Comment,
+	})
+}
+
+// ExpireAlertSilence expires the silence of a policy in the namespace, or of a cluster policy if namespace is empty
+func ExpireAlertSilence(namespace, id string) error {
+	silence, err := alertmanager.GetSilence(id)
+
+	if e, ok := err.(alertmanager.Error); ok && e.StatusCode == 404 {
+		return errors.NewNotFound(AlertPolicyGroupVersionResource.GroupResource(), id)
+	} else if err != nil {
+		return err
+	}
+
+	// silences of other namespaces are invisible
+	if !isPolicySilence(silence, namespace) {
+		return errors.NewNotFound(AlertPolicyGroupVersionResource.GroupResource(), id)
+	}
+
+	return alertmanager.ExpireSilence(id)
+}
+
+func isPolicySilence(silence *alertmanager.Silence, namespace string) bool {
+	var policy, inNamespace bool
+	for _, matcher := range silence.Matchers {
+		if matcher.IsRegex {
+			continue
+		}
+		if matcher.Name == LabelPolicy && matcher.Value != "" {
+			policy = true
+		}
+		if matcher.Name == LabelPolicyNamespace && matcher.Value == namespace {
+			inNamespace = true
+		}
+	}
+	return policy && inNamespace
+}
+
+func policyLabels(namespace, policy string) map[string]string {
+	labels := map[string]string{LabelPolicyNamespace: namespace}
+	if policy != "" {
+		labels[LabelPolicy] = policy
+	}
+	return labels
+}
+
+// alertsSelector returns the selector of the ALERTS series of firing alerts of the policies
+func alertsSelector(namespace, policy string) string {
+	matchers := []string{`alertstate="firing"`, LabelPolicyNamespace + "=" + strconv.Quote(namespace)}
+	if policy != "" {
+		matchers = append(matchers, LabelPolicy+"="+strconv.Quote(policy))
+	} else {
+		matchers = append(matchers, LabelPolicy+`!=""`)
+	}
+	return "ALERTS{" + strings.Join(matchers, ",") + "}"
+}
+
+func alertOf(labels map[string]string) Alert {
+	alert := Alert{
+		Policy:    labels[LabelPolicy],
+		Namespace: labels[LabelPolicyNamespace],
+		Severity:  labels[LabelSeverity],
+		Level:     labels[LabelLevel],
+		Metric:    labels[LabelMetric],
+		Labels:    make(map[string]string),
+	}
+	for k, v := range labels {
+		if !ruleLabels[k] {
+			alert.Labels[k] = v
+		}
+	}
+	return alert
+}
+
+// sortAlerts sorts alerts by the time they started firing, newest first
+func sortAlerts(alerts []Alert) {
+	sort.SliceStable(alerts, func(i, j int) bool { return alerts[i].StartsAt.After(alerts[j].StartsAt) })
+}
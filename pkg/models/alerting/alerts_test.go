@@ -0,0 +1,61 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/alertmanager"
+	"kubesphere.io/kubesphere/pkg/simple/client/prometheus"
+)
+
+func TestFiringPeriods(t *testing.T) {
+	series := &alertSeries{
+		Metric: map[string]string{"alertname": "web", LabelPolicy: "web", LabelPolicyNamespace: "demo", LabelMetric: "pod_cpu_usage", "pod_name": "web-0"},
+		Values: []prometheus.Point{{Timestamp: 1000, Value: "1"}, {Timestamp: 1060, Value: "1"}, {Timestamp: 1125, Value: "1"}, {Timestamp: 1500, Value: "1"}, {Timestamp: 1560, Value: "1"}},
+	}
+
+	alerts := series.firingPeriods(time.Unix(1800, 0), time.Minute)
+	assert.Len(t, alerts, 2)
+	assert.Equal(t, time.Unix(1000, 0), alerts[0].StartsAt)
+	assert.Equal(t, time.Unix(1125, 0), *alerts[0].EndsAt)
+	assert.Equal(t, map[string]string{"pod_name": "web-0"}, alerts[0].Labels)
+	assert.Equal(t, "demo", alerts[0].Namespace)
+
+	// still firing at the end
+	alerts = series.firingPeriods(time.Unix(1600, 0), time.Minute)
+	assert.Nil(t, alerts[1].EndsAt)
+}
+
+func TestAlertsSelector(t *testing.T) {
+	assert.Equal(t, `ALERTS{alertstate="firing",alertpolicy_namespace="demo",alertpolicy="web"}`, alertsSelector("demo", "web"))
+	assert.Equal(t, `ALERTS{alertstate="firing",alertpolicy_namespace="",alertpolicy!=""}`, alertsSelector("", ""))
+}
+
+func TestIsPolicySilence(t *testing.T) {
+	silence := &alertmanager.Silence{Matchers: []alertmanager.Matcher{{Name: LabelPolicy, Value: "web"}, {Name: LabelPolicyNamespace, Value: "demo"}}}
+	assert.True(t, isPolicySilence(silence, "demo"))
+	assert.False(t, isPolicySilence(silence, "prod"))
+	assert.False(t, isPolicySilence(silence, ""))
+
+	silence = &alertmanager.Silence{Matchers: []alertmanager.Matcher{{Name: LabelPolicy, Value: ".*", IsRegex: true}, {Name: LabelPolicyNamespace, Value: "demo"}}}
+	assert.False(t, isPolicySilence(silence, "demo"))
+}
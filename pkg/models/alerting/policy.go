@@ -0,0 +1,245 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package alerting
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"kubesphere.io/kubesphere/pkg/apis/alerting/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/models/metrics"
+)
+
+const (
+	// labels of alerts identifying the policy they are fired by
+	LabelPolicy          = "alertpolicy"
+	LabelPolicyNamespace = "alertpolicy_namespace"
+	LabelSeverity        = "severity"
+	LabelLevel           = "level"
+	LabelMetric          = "metric"
+	LabelWorkspace       = "workspace"
+
+	AnnotationMessage = "message"
+	AnnotationSummary = "summary"
+
+	defaultSeverity = "warning"
+)
+
+var (
+	alertOperators  = map[string]bool{">": true, ">=": true, "<": true, "<=": true, "==": true, "!=": true}
+	alertSeverities = map[string]bool{"critical": true, "warning": true, "info": true}
+	workloadKinds   = map[string]bool{"": true, "deployment": true, "statefulset": true, "daemonset": true}
+
+	// metrics which could be alerted on of every level
+	levelMetrics = map[string][]string{
+		v1alpha1.AlertLevelNode:      metrics.NodeMetricsNames,
+		v1alpha1.AlertLevelWorkspace: metrics.WorkspaceMetricsNames,
+		v1alpha1.AlertLevelNamespace: metrics.NamespaceMetricsNames,
+		v1alpha1.AlertLevelWorkload:  metrics.WorkloadMetricsNames,
+		v1alpha1.AlertLevelPod:       metrics.PodMetricsNames,
+	}
+)
+
+// RuleGroup is a rule group of PrometheusRule
+type RuleGroup struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+// Rule is an alerting rule of PrometheusRule
+type Rule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// CompileAlertPolicy returns the rule group evaluating the policy, namespace is empty for ClusterAlertPolicy
+func CompileAlertPolicy(namespace, name string, spec *v1alpha1.AlertPolicySpec) (*RuleGroup, error) {
+	if namespace == "" {
+		if spec.Level != v1alpha1.AlertLevelNode && spec.Level != v1alpha1.AlertLevelWorkspace {
+			return nil, fmt.Errorf("level of cluster alert policies must be one of node, workspace")
+		}
+	} else if spec.Level != v1alpha1.AlertLevelNamespace && spec.Level != v1alpha1.AlertLevelWorkload && spec.Level != v1alpha1.AlertLevelPod {
+		return nil, fmt.Errorf("level of alert policies must be one of namespace, workload, pod")
+	}
+
+	if !workloadKinds[spec.WorkloadKind] {
+		return nil, fmt.Errorf("invalid workload kind %s", spec.WorkloadKind)
+	}
+	if spec.Level == v1alpha1.AlertLevelWorkspace && len(spec.Resources) == 0 {
+		return nil, fmt.Errorf("workspaces are required for the workspace level")
+	}
+	if len(spec.Conditions) == 0 {
+		return nil, fmt.Errorf("no conditions")
+	}
+	// labels and annotations of alerting rules are expanded as templates by Prometheus,
+	// templates could run queries of any metrics
+	if strings.Contains(spec.Message, "{{") {
+		return nil, fmt.Errorf("templates are not allowed in the message")
+	}
+	// names of the resources are quoted in PromQL strings, only names of kubernetes objects are allowed
+	for _, resource := range spec.Resources {
+		if errs := validation.IsDNS1123Subdomain(resource); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid resource %q: %s", resource, strings.Join(errs, ", "))
+		}
+	}
+
+	severity := spec.Severity
+	if severity == "" {
+		severity = defaultSeverity
+	}
+	if !alertSeverities[severity] {
+		return nil, fmt.Errorf("invalid severity %s", severity)
+	}
+
+	group := &RuleGroup{Name: name, Rules: []Rule{}}
+	if namespace != "" {
+		group.Name = namespace + "/" + name
+	}
+
+	for i, condition := range spec.Conditions {
+		if !alertOperators[condition.Operator] {
+			return nil, fmt.Errorf("invalid operator %s of condition %d", condition.Operator, i)
+		}
+		if !contains(levelMetrics[spec.Level], condition.Metric) || metrics.RulePromQLTmplMap[condition.Metric] == "" {
+			return nil, fmt.Errorf("unknown %s metric %s of condition %d", spec.Level, condition.Metric, i)
+		}
+
+		threshold := strconv.FormatFloat(condition.Threshold, 'g', -1, 64)
+		summary := fmt.Sprintf("%s is {{ $value }}, %s %s", condition.Metric, condition.Operator, threshold)
+		message := spec.Message
+		if message == "" {
+			message = fmt.Sprintf("%s %s %s", condition.Metric, condition.Operator, threshold)
+			if condition.For != nil && condition.For.Duration > 0 {
+				message += " for " + condition.For.Duration.String()
+			}
+		}
+
+		labels := map[string]string{
+			LabelPolicy:   name,
+			LabelSeverity: severity,
+			LabelLevel:    spec.Level,
+			LabelMetric:   condition.Metric,
+		}
+		if namespace != "" {
+			labels[LabelPolicyNamespace] = namespace
+		}
+
+		var duration string
+		if condition.For != nil && condition.For.Duration > 0 {
+			duration = prometheusDuration(condition.For.Duration)
+		}
+
+		for _, selector := range levelSelectors(namespace, spec, condition.Metric) {
+			r := Rule{
+				Alert:       name,
+				Expr:        fmt.Sprintf("(%s) %s %s", selector.promql, condition.Operator, threshold),
+				For:         duration,
+				Labels:      make(map[string]string, len(labels)+1),
+				Annotations: map[string]string{AnnotationMessage: message, AnnotationSummary: summary},
+			}
+			for k, v := range labels {
+				r.Labels[k] = v
+			}
+			if selector.workspace != "" {
+				r.Labels[LabelWorkspace] = selector.workspace
+			}
+			group.Rules = append(group.Rules, r)
+		}
+	}
+
+	return group, nil
+}
+
+type levelSelector struct {
+	promql    string
+	workspace string
+}
+
+// levelSelectors returns queries of the metric of resources of the policy by the metric builders,
+// workspace metrics are aggregated so they're queried by workspaces separately
+func levelSelectors(namespace string, spec *v1alpha1.AlertPolicySpec, metric string) []levelSelector {
+	switch spec.Level {
+	case v1alpha1.AlertLevelNode:
+		return []levelSelector{{promql: metrics.MakeNodeRule("", namesFilter(spec.Resources), metric)}}
+	case v1alpha1.AlertLevelWorkspace:
+		var selectors []levelSelector
+		for _, workspace := range spec.Resources {
+			selectors = append(selectors, levelSelector{promql: metrics.MakeSpecificWorkspacePromQL(metric, ".*", quoteNames([]string{workspace})), workspace: workspace})
+		}
+		return selectors
+	case v1alpha1.AlertLevelNamespace:
+		return []levelSelector{{promql: metrics.MakeNamespacePromQL(namespace, "", metric)}}
+	case v1alpha1.AlertLevelWorkload:
+		var filter string
+		if len(spec.Resources) > 0 {
+			filter = quoteNames(spec.Resources)
+		}
+		return []levelSelector{{promql: metrics.MakeWorkloadPromQL(metric, namespace, filter, spec.WorkloadKind)}}
+	default:
+		return []levelSelector{{promql: metrics.MakePodPromQL(metric, namespace, "", "", namesFilter(spec.Resources))}}
+	}
+}
+
+// namesFilter returns the regular expression matching exactly the names, all names if empty
+func namesFilter(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return "^(" + quoteNames(names) + ")$"
+}
+
+// quoteNames returns the alternation of the names, backslashes are escaped again as it's in a PromQL string
+func quoteNames(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, strings.Replace(regexp.QuoteMeta(name), `\`, `\\`, -1))
+	}
+	return strings.Join(quoted, "|")
+}
+
+// prometheusDuration formats the duration in the largest unit of Prometheus which it's a multiple of,
+// durations of multiple units aren't supported by Prometheus 2.x before 2.11
+func prometheusDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	default:
+		return fmt.Sprintf("%dms", d/time.Millisecond)
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package alerting
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kubesphere.io/kubesphere/pkg/apis/alerting/v1alpha1"
+)
+
+func TestCompileAlertPolicy(t *testing.T) {
+	spec := &v1alpha1.AlertPolicySpec{
+		Level:     v1alpha1.AlertLevelPod,
+		Resources: []string{"web-0", "web.1"},
+		Conditions: []v1alpha1.AlertCondition{
+			{Metric: "pod_cpu_usage", Operator: ">", Threshold: 0.8, For: &metav1.Duration{Duration: 5 * time.Minute}},
+			{Metric: "pod_memory_usage", Operator: ">=", Threshold: 1073741824},
+		},
+	}
+
+	group, err := CompileAlertPolicy("demo", "web", spec)
+	assert.NoError(t, err)
+	assert.Equal(t, "demo/web", group.Name)
+	assert.Len(t, group.Rules, 2)
+
+	cpu := group.Rules[0]
+	assert.Equal(t, "web", cpu.Alert)
+	assert.Equal(t, "5m", cpu.For)
+	assert.Contains(t, cpu.Expr, `namespace="demo"`)
+	assert.Contains(t, cpu.Expr, `pod_name=~"^(web-0|web\\.1)$"`)
+	assert.True(t, strings.HasSuffix(cpu.Expr, ") > 0.8"))
+	assert.Equal(t, map[string]string{LabelPolicy: "web", LabelPolicyNamespace: "demo", LabelSeverity: "warning", LabelLevel: "pod", LabelMetric: "pod_cpu_usage"}, cpu.Labels)
+	assert.Equal(t, "pod_cpu_usage > 0.8 for 5m0s", cpu.Annotations[AnnotationMessage])
+
+	memory := group.Rules[1]
+	assert.Empty(t, memory.For)
+	assert.True(t, strings.HasSuffix(memory.Expr, ") >= 1.073741824e+09"))
+}
+
+func TestCompileClusterAlertPolicy(t *testing.T) {
+	spec := &v1alpha1.AlertPolicySpec{
+		Level:      v1alpha1.AlertLevelWorkspace,
+		Resources:  []string{"dev", "prod"},
+		Conditions: []v1alpha1.AlertCondition{{Metric: "workspace_cpu_usage", Operator: ">", Threshold: 10}},
+		Severity:   "critical",
+		Message:    "workspace is busy",
+	}
+
+	group, err := CompileAlertPolicy("", "busy", spec)
+	assert.NoError(t, err)
+	assert.Equal(t, "busy", group.Name)
+	assert.Len(t, group.Rules, 2)
+	assert.Equal(t, "prod", group.Rules[1].Labels[LabelWorkspace])
+	assert.Contains(t, group.Rules[1].Expr, `label_kubesphere_io_workspace=~"^(prod)$"`)
+	assert.NotContains(t, group.Rules[1].Labels, LabelPolicyNamespace)
+	assert.Equal(t, "workspace is busy", group.Rules[0].Annotations[AnnotationMessage])
+
+	spec.Resources = nil
+	_, err = CompileAlertPolicy("", "busy", spec)
+	assert.Error(t, err)
+}
+
+func TestCompileInvalidAlertPolicy(t *testing.T) {
+	condition := v1alpha1.AlertCondition{Metric: "pod_cpu_usage", Operator: ">", Threshold: 1}
+
+	invalid := []v1alpha1.AlertPolicySpec{
+		{Level: v1alpha1.AlertLevelNode, Conditions: []v1alpha1.AlertCondition{condition}},
+		{Level: v1alpha1.AlertLevelPod},
+		{Level: v1alpha1.AlertLevelPod, Conditions: []v1alpha1.AlertCondition{{Metric: "node_cpu_utilisation", Operator: ">", Threshold: 1}}},
+		{Level: v1alpha1.AlertLevelPod, Conditions: []v1alpha1.AlertCondition{{Metric: "pod_cpu_usage", Operator: "=~", Threshold: 1}}},
+		{Level: v1alpha1.AlertLevelPod, Conditions: []v1alpha1.AlertCondition{condition}, Severity: "fatal"},
+		{Level: v1alpha1.AlertLevelWorkload, Conditions: []v1alpha1.AlertCondition{condition}, WorkloadKind: "job"},
+		{Level: v1alpha1.AlertLevelPod, Conditions: []v1alpha1.AlertCondition{condition}, Message: `{{ query "up" }}`},
+		{Level: v1alpha1.AlertLevelPod, Conditions: []v1alpha1.AlertCondition{condition}, Resources: []string{"{{ $labels }}"}},
+		{Level: v1alpha1.AlertLevelPod, Conditions: []v1alpha1.AlertCondition{condition}, Resources: []string{"nginx", `x")$"} or on() kube_pod_info{pod=~"(`}},
+	}
+
+	for i := range invalid {
+		_, err := CompileAlertPolicy("demo", "invalid", &invalid[i])
+		assert.Error(t, err, "%d", i)
+	}
+}
+
+func TestPrometheusDuration(t *testing.T) {
+	assert.Equal(t, "2h", prometheusDuration(2*time.Hour))
+	assert.Equal(t, "90m", prometheusDuration(90*time.Minute))
+	assert.Equal(t, "45s", prometheusDuration(45*time.Second))
+	assert.Equal(t, "1500ms", prometheusDuration(1500*time.Millisecond))
+}
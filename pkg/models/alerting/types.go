@@ -0,0 +1,62 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package alerting
+
+import (
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/alertmanager"
+)
+
+type Alert struct {
+	Policy    string            `json:"policy" description:"name of the alert policy"`
+	Namespace string            `json:"namespace,omitempty" description:"namespace of the alert policy, empty for cluster alert policies"`
+	Severity  string            `json:"severity" description:"one of critical, warning, info"`
+	Level     string            `json:"level" description:"level of the alert policy, one of node, workspace, namespace, workload, pod"`
+	Metric    string            `json:"metric" description:"metric of the condition firing the alert"`
+	Labels    map[string]string `json:"labels" description:"labels of the series firing the alert, e.g. pod_name"`
+	Message   string            `json:"message,omitempty" description:"message of the alert policy, empty in the history"`
+	Summary   string            `json:"summary,omitempty" description:"current value of the metric"`
+	StartsAt  time.Time         `json:"startsAt" description:"time the alert started firing"`
+
+	// EndsAt is set for alerts in the history only
+	EndsAt *time.Time `json:"endsAt,omitempty" description:"time the alert resolved, empty if it's still firing"`
+
+	// Silenced is set for active alerts only
+	Silenced   bool     `json:"silenced,omitempty" description:"whether notifications of the alert are silenced"`
+	SilencedBy []string `json:"silencedBy,omitempty" description:"ids of the silences"`
+}
+
+type AlertsResult struct {
+	Total int     `json:"total" description:"total number of alerts"`
+	Items []Alert `json:"items" description:"alerts"`
+}
+
+type AlertSilence struct {
+	Duration string `json:"duration" description:"how long alerts of the policy are silenced from now on, e.g. 2h"`
+	Comment  string `json:"comment,omitempty" description:"reason of the silence"`
+}
+
+type AlertSilencesResult struct {
+	Total int                    `json:"total" description:"total number of silences"`
+	Items []alertmanager.Silence `json:"items" description:"silences of alert policies"`
+}
+
+type AlertSilenceCreateResult struct {
+	ID string `json:"id" description:"id of the silence"`
+}
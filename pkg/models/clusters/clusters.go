@@ -0,0 +1,397 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package clusters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	clusterv1alpha1 "kubesphere.io/kubesphere/pkg/apis/cluster/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/informers"
+	"kubesphere.io/kubesphere/pkg/models/resources"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+)
+
+const defaultResync = 600 * time.Second
+
+// ksAPIServerProxyPath is the path of ks-apiserver proxied by kube-apiserver of member clusters
+const ksAPIServerProxyPath = "/api/v1/namespaces/" + constants.KubeSphereNamespace + "/services/ks-apiserver:80/proxy"
+
+var ClusterGroupVersionResource = clusterv1alpha1.SchemeGroupVersion.WithResource("clusters")
+
+// Cluster is the connection to a member cluster
+type Cluster struct {
+	Name   string
+	Config *rest.Config
+	Client kubernetes.Interface
+	// Informers is the informer set of the member cluster, only informers of the resources
+	// searched in member clusters are started
+	Informers k8sinformers.SharedInformerFactory
+	// Endpoint is the ks-apiserver of the member cluster, requests should be sent with Transport
+	Endpoint  *url.URL
+	Transport http.RoundTripper
+
+	resourceVersion string
+	stopCh          chan struct{}
+	synced          int32
+}
+
+// Synced returns true if the informers of the member cluster are synced
+func (c *Cluster) Synced() bool {
+	return atomic.LoadInt32(&c.synced) == 1
+}
+
+type clusterKey struct{}
+
+// WithCluster returns a copy of the context with the member cluster the request is dispatched to
+func WithCluster(ctx context.Context, cluster *Cluster) context.Context {
+	return context.WithValue(ctx, clusterKey{}, cluster)
+}
+
+// FromContext returns the member cluster the request is dispatched to
+func FromContext(ctx context.Context) (*Cluster, bool) {
+	cluster, ok := ctx.Value(clusterKey{}).(*Cluster)
+	return cluster, ok
+}
+
+var registry = struct {
+	sync.RWMutex
+	host     string
+	clusters map[string]*Cluster
+}{clusters: make(map[string]*Cluster)}
+
+// HostCluster returns the name of the host cluster, requests to the host cluster are served locally
+func HostCluster() string {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.host
+}
+
+// StartClusterRegistry connects to the member clusters registered by Cluster resources
+func StartClusterRegistry(hostCluster string, stopCh <-chan struct{}) {
+	registry.Lock()
+	registry.host = hostCluster
+	registry.Unlock()
+
+	informer := informers.DynamicSharedInformerFactory().ForResource(ClusterGroupVersionResource)
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			onClusterChanged(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			onClusterChanged(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				Disconnect(u.GetName())
+			}
+		},
+	})
+
+	go func() {
+		<-stopCh
+		registry.Lock()
+		defer registry.Unlock()
+		for name, cluster := range registry.clusters {
+			close(cluster.stopCh)
+			delete(registry.clusters, name)
+		}
+	}()
+}
+
+func onClusterChanged(obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+
+	if !ok {
+		return
+	}
+
+	cluster := &clusterv1alpha1.Cluster{}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, cluster); err != nil {
+		glog.Errorln("convert cluster", u.GetName(), err)
+		return
+	}
+
+	if err := Connect(cluster, k8s.Client()); err != nil {
+		glog.Errorln("connect cluster", cluster.Name, err)
+	}
+}
+
+// Connect connects to the member cluster with the kubeconfig in the secret of the host cluster,
+// the existing connection is replaced if the cluster or the secret is changed
+func Connect(cluster *clusterv1alpha1.Cluster, hostClient kubernetes.Interface) error {
+	if cluster.Name == HostCluster() {
+		return fmt.Errorf("cluster %s conflicts with the host cluster", cluster.Name)
+	}
+
+	secretRef := cluster.Spec.Connection.KubeConfigSecretRef
+
+	if secretRef == nil || secretRef.Name == "" {
+		Disconnect(cluster.Name)
+		return fmt.Errorf("kubeconfig secret of cluster %s is not specified", cluster.Name)
+	}
+
+	namespace := secretRef.Namespace
+
+	if namespace == "" {
+		namespace = constants.KubeSphereNamespace
+	}
+
+	secret, err := hostClient.CoreV1().Secrets(namespace).Get(secretRef.Name, metav1.GetOptions{})
+
+	if err != nil {
+		Disconnect(cluster.Name)
+		return err
+	}
+
+	kubeconfig, ok := secret.Data[clusterv1alpha1.KubeConfigSecretKey]
+
+	if !ok {
+		Disconnect(cluster.Name)
+		return fmt.Errorf("secret %s/%s has no %s", namespace, secretRef.Name, clusterv1alpha1.KubeConfigSecretKey)
+	}
+
+	// the connection is changed with the cluster or the secret
+	resourceVersion := cluster.ResourceVersion + "/" + secret.ResourceVersion
+
+	registry.RLock()
+	existing, ok := registry.clusters[cluster.Name]
+	registry.RUnlock()
+
+	if ok && existing.resourceVersion == resourceVersion {
+		return nil
+	}
+
+	connection, err := newCluster(cluster, kubeconfig)
+
+	if err != nil {
+		Disconnect(cluster.Name)
+		return err
+	}
+
+	connection.resourceVersion = resourceVersion
+
+	// resources of member clusters are searched in the informers, namespaces are
+	// also used to find the namespaces of workspaces in member clusters
+	resources.RegisterMemberInformers(connection.Informers)
+	connection.Informers.Core().V1().Namespaces().Informer()
+	connection.Informers.Start(connection.stopCh)
+
+	go func() {
+		for _, synced := range connection.Informers.WaitForCacheSync(connection.stopCh) {
+			if !synced {
+				return
+			}
+		}
+		atomic.StoreInt32(&connection.synced, 1)
+		glog.Infoln("cluster synced", connection.Name)
+	}()
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if existing, ok := registry.clusters[cluster.Name]; ok {
+		close(existing.stopCh)
+	}
+
+	registry.clusters[cluster.Name] = connection
+
+	glog.Infoln("cluster connected", cluster.Name)
+
+	return nil
+}
+
+// Disconnect stops the informers of the member cluster and removes it from the registry
+func Disconnect(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if cluster, ok := registry.clusters[name]; ok {
+		close(cluster.stopCh)
+		delete(registry.clusters, name)
+		glog.Infoln("cluster disconnected", name)
+	}
+}
+
+func newCluster(cluster *clusterv1alpha1.Cluster, kubeconfig []byte) (*Cluster, error) {
+	connection := cluster.Spec.Connection
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoint *url.URL
+	var transport http.RoundTripper
+
+	switch connection.Type {
+	case clusterv1alpha1.ConnectionTypeProxy:
+		if connection.KubernetesAPIEndpoint != "" {
+			config.Host = connection.KubernetesAPIEndpoint
+		}
+
+		if connection.KubeSphereAPIEndpoint == "" {
+			return nil, fmt.Errorf("kubesphere api endpoint of cluster %s is not specified", cluster.Name)
+		}
+
+		endpoint, err = url.Parse(connection.KubeSphereAPIEndpoint)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// requests to the agent are authenticated with the credentials of the cluster
+		transport, err = rest.TransportFor(config)
+
+		if err != nil {
+			return nil, err
+		}
+	case clusterv1alpha1.ConnectionTypeDirect, "":
+		endpoint, err = url.Parse(strings.TrimSuffix(config.Host, "/") + ksAPIServerProxyPath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// requests are authenticated by the kube-apiserver of the member cluster
+		transport, err = rest.TransportFor(config)
+
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown connection type %s of cluster %s", connection.Type, cluster.Name)
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cluster{
+		Name:      cluster.Name,
+		Config:    config,
+		Client:    client,
+		Informers: k8sinformers.NewSharedInformerFactory(client, defaultResync),
+		Endpoint:  endpoint,
+		Transport: transport,
+		stopCh:    make(chan struct{}),
+	}, nil
+}
+
+// GetCluster returns the connection of the member cluster
+func GetCluster(name string) (*Cluster, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	cluster, ok := registry.clusters[name]
+	return cluster, ok
+}
+
+// ListClusters returns the names of the host cluster and the connected member clusters
+func ListClusters() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	members := make([]string, 0)
+
+	for name := range registry.clusters {
+		members = append(members, name)
+	}
+
+	sort.Strings(members)
+
+	if registry.host != "" {
+		return append([]string{registry.host}, members...)
+	}
+
+	return members
+}
+
+type ClusterNamespaces struct {
+	Cluster    string   `json:"cluster" description:"cluster name"`
+	Namespaces []string `json:"namespaces" description:"namespaces of the workspace in the cluster"`
+}
+
+// WorkspaceNamespaces returns the namespaces of the workspace in each cluster,
+// clusters without namespaces of the workspace are not returned
+func WorkspaceNamespaces(workspace string) ([]ClusterNamespaces, error) {
+	selector := labels.SelectorFromSet(labels.Set{constants.WorkspaceLabelKey: workspace})
+	result := make([]ClusterNamespaces, 0)
+
+	for _, name := range ListClusters() {
+		var factory k8sinformers.SharedInformerFactory
+
+		if name == HostCluster() {
+			factory = informers.SharedInformerFactory()
+		} else {
+			cluster, ok := GetCluster(name)
+			if !ok {
+				continue
+			}
+			factory = cluster.Informers
+		}
+
+		namespaces, err := factory.Core().V1().Namespaces().Lister().List(selector)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(namespaces) == 0 {
+			continue
+		}
+
+		item := ClusterNamespaces{Cluster: name, Namespaces: make([]string, 0)}
+
+		for _, namespace := range namespaces {
+			item.Namespaces = append(item.Namespaces, namespace.Name)
+		}
+
+		sort.Strings(item.Namespaces)
+
+		result = append(result, item)
+	}
+
+	return result, nil
+}
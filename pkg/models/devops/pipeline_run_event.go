@@ -0,0 +1,400 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"kubesphere.io/kubesphere/pkg/gojenkins"
+	"kubesphere.io/kubesphere/pkg/simple/client/admin_jenkins"
+)
+
+const (
+	PipelineRunEventKindRun   = "run"
+	PipelineRunEventKindStage = "stage"
+	PipelineRunEventKindStep  = "step"
+
+	PipelineRunEventQueued    = "queued"
+	PipelineRunEventStarted   = "started"
+	PipelineRunEventPaused    = "paused"
+	PipelineRunEventSucceeded = "succeeded"
+	PipelineRunEventFailed    = "failed"
+	PipelineRunEventAborted   = "aborted"
+)
+
+const (
+	// latest runs of each pipeline watched
+	pipelineRunWatchLimit = 10
+	// events buffered for each subscriber, events are dropped if subscribers are slower
+	pipelineRunEventBuffer = 100
+)
+
+var pipelineRunEventDescriptions = map[string]string{
+	PipelineRunEventQueued:    "is queued",
+	PipelineRunEventStarted:   "started",
+	PipelineRunEventPaused:    "is waiting for input",
+	PipelineRunEventSucceeded: "succeeded",
+	PipelineRunEventFailed:    "failed",
+	PipelineRunEventAborted:   "was aborted",
+}
+
+// PipelineRunEvent is emitted when the state of a run, a stage or a step of pipelines changes
+type PipelineRunEvent struct {
+	Kind      string    `json:"kind" description:"kind of the event, run, stage or step"`
+	Type      string    `json:"type" description:"type of the event, queued, started, paused, succeeded, failed or aborted"`
+	ProjectId string    `json:"project_id" description:"devops project id"`
+	Pipeline  string    `json:"pipeline" description:"pipeline name"`
+	Branch    string    `json:"branch,omitempty" description:"branch name of multi-branch pipelines"`
+	RunId     string    `json:"run_id" description:"pipeline run id"`
+	NodeId    string    `json:"node_id,omitempty" description:"node id of stages and steps"`
+	StepId    string    `json:"step_id,omitempty" description:"step id of steps"`
+	Name      string    `json:"name,omitempty" description:"display name of stages and steps"`
+	Message   string    `json:"message,omitempty" description:"message of the input waiting for"`
+	Time      time.Time `json:"time" description:"time when the change is observed"`
+}
+
+func (e *PipelineRunEvent) Title() string {
+	pipeline := e.ProjectId + "/" + e.Pipeline
+	if e.Branch != "" {
+		pipeline += "/" + e.Branch
+	}
+	title := fmt.Sprintf("[%s] %s #%s", strings.ToUpper(e.Type), pipeline, e.RunId)
+	if e.Kind != PipelineRunEventKindRun {
+		title += fmt.Sprintf(" %s %s", e.Kind, e.Name)
+	}
+	return title
+}
+
+func (e *PipelineRunEvent) Text() string {
+	subject := fmt.Sprintf("Run %s of pipeline %s", e.RunId, e.Pipeline)
+	if e.Branch != "" {
+		subject += fmt.Sprintf(" (branch %s)", e.Branch)
+	}
+	if e.Kind != PipelineRunEventKindRun {
+		subject = fmt.Sprintf("%s %s of %s", strings.Title(e.Kind), e.Name, strings.ToLower(subject[:1])+subject[1:])
+	}
+	text := fmt.Sprintf("%s in devops project %s %s", subject, e.ProjectId, pipelineRunEventDescriptions[e.Type])
+	if e.Message != "" {
+		text += ": " + e.Message
+	}
+	return text
+}
+
+func (e *PipelineRunEvent) Timestamp() time.Time {
+	return e.Time
+}
+
+// key identifies the run, stage or step of the event
+func (e *PipelineRunEvent) key() string {
+	return strings.Join([]string{e.Kind, e.Pipeline, e.Branch, e.RunId, e.NodeId, e.StepId}, "/")
+}
+
+func (e *PipelineRunEvent) child(kind, nodeId, stepId, name, eventType string) *PipelineRunEvent {
+	return &PipelineRunEvent{
+		Kind:      kind,
+		Type:      eventType,
+		ProjectId: e.ProjectId,
+		Pipeline:  e.Pipeline,
+		Branch:    e.Branch,
+		RunId:     e.RunId,
+		NodeId:    nodeId,
+		StepId:    stepId,
+		Name:      name,
+		Time:      e.Time,
+	}
+}
+
+// pipelineRunEventType normalizes the state and result of Blue Ocean runs, nodes and steps,
+// it's empty for skipped or not built nodes and steps
+func pipelineRunEventType(state, result string) string {
+	switch state {
+	case "QUEUED":
+		return PipelineRunEventQueued
+	case "RUNNING":
+		return PipelineRunEventStarted
+	case "PAUSED":
+		return PipelineRunEventPaused
+	case "FINISHED":
+		switch result {
+		case "SUCCESS":
+			return PipelineRunEventSucceeded
+		case "FAILURE", "UNSTABLE":
+			return PipelineRunEventFailed
+		case "ABORTED", "NOT_BUILT":
+			return PipelineRunEventAborted
+		}
+	}
+	return ""
+}
+
+func isFinishedPipelineRunEvent(eventType string) bool {
+	return eventType == PipelineRunEventSucceeded || eventType == PipelineRunEventFailed || eventType == PipelineRunEventAborted
+}
+
+// diffPipelineRunEvents returns the observed events whose types are changed since the last observation
+// and the current types by keys. Nothing is returned as changed on the first observation.
+func diffPipelineRunEvents(last map[string]string, observed []*PipelineRunEvent) ([]*PipelineRunEvent, map[string]string) {
+	changed := make([]*PipelineRunEvent, 0)
+	current := make(map[string]string, len(observed))
+	for _, event := range observed {
+		key := event.key()
+		current[key] = event.Type
+		if last != nil && last[key] != event.Type {
+			changed = append(changed, event)
+		}
+	}
+	return changed, current
+}
+
+type pipelineRunEventBroker struct {
+	lock        sync.RWMutex
+	subscribers map[string]map[chan *PipelineRunEvent]struct{}
+}
+
+var pipelineRunEvents = &pipelineRunEventBroker{subscribers: make(map[string]map[chan *PipelineRunEvent]struct{})}
+
+// SubscribePipelineRunEvents returns the events of pipelines in the project, the channel is closed once cancel is called.
+// Events are only emitted when the pipeline run watcher is started.
+func SubscribePipelineRunEvents(projectId string) (events <-chan *PipelineRunEvent, cancel func()) {
+	return pipelineRunEvents.subscribe(projectId)
+}
+
+func (b *pipelineRunEventBroker) subscribe(projectId string) (<-chan *PipelineRunEvent, func()) {
+	ch := make(chan *PipelineRunEvent, pipelineRunEventBuffer)
+
+	b.lock.Lock()
+	if b.subscribers[projectId] == nil {
+		b.subscribers[projectId] = make(map[chan *PipelineRunEvent]struct{})
+	}
+	b.subscribers[projectId][ch] = struct{}{}
+	b.lock.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.lock.Lock()
+			defer b.lock.Unlock()
+			delete(b.subscribers[projectId], ch)
+			if len(b.subscribers[projectId]) == 0 {
+				delete(b.subscribers, projectId)
+			}
+			close(ch)
+		})
+	}
+}
+
+func (b *pipelineRunEventBroker) publish(event *PipelineRunEvent) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for ch := range b.subscribers[event.ProjectId] {
+		select {
+		case ch <- event:
+		default:
+			glog.Warningf("event of pipeline %s/%s is dropped for slow subscribers", event.ProjectId, event.Pipeline)
+		}
+	}
+}
+
+// projects returns the projects with subscribers
+func (b *pipelineRunEventBroker) projects() []string {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	projects := make([]string, 0, len(b.subscribers))
+	for projectId := range b.subscribers {
+		projects = append(projects, projectId)
+	}
+	return projects
+}
+
+type pipelineRunWatcher struct {
+	// types of runs, stages and steps last observed by projects
+	states map[string]map[string]string
+}
+
+// StartPipelineRunWatcher polls the runs of pipelines in projects with event subscribers or notification receivers
+// until stopCh is closed, it's disabled if interval is 0
+func StartPipelineRunWatcher(interval time.Duration, stopCh <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+
+	w := &pipelineRunWatcher{states: make(map[string]map[string]string)}
+	go wait.Until(w.watch, interval, stopCh)
+}
+
+func (w *pipelineRunWatcher) watch() {
+	jenkins := admin_jenkins.Client()
+	if jenkins == nil {
+		glog.Error("watch pipeline runs: could not connect to jenkins")
+		return
+	}
+
+	receivers, err := listNotificationReceivers(nil)
+	if err != nil {
+		// events are still streamed to subscribers
+		glog.Errorf("list notification receivers: %+v", err)
+	}
+
+	projects := make(map[string]bool)
+	for _, projectId := range pipelineRunEvents.projects() {
+		projects[projectId] = true
+	}
+	for projectId := range receivers {
+		projects[projectId] = true
+	}
+	// projects watched again are observed from scratch
+	for projectId := range w.states {
+		if !projects[projectId] {
+			delete(w.states, projectId)
+		}
+	}
+
+	for projectId := range projects {
+		last := w.states[projectId]
+		observed, err := observePipelineRuns(jenkins, projectId, last)
+		if err != nil {
+			glog.Errorf("watch pipeline runs of devops project %s: %+v", projectId, err)
+			continue
+		}
+
+		events, current := diffPipelineRunEvents(last, observed)
+		w.states[projectId] = current
+
+		for _, event := range events {
+			pipelineRunEvents.publish(event)
+		}
+		if len(events) > 0 && len(receivers[projectId]) > 0 {
+			go notifyPipelineRunEvents(receivers[projectId], events)
+		}
+	}
+}
+
+func notifyPipelineRunEvents(receivers []*NotificationReceiver, events []*PipelineRunEvent) {
+	for _, event := range events {
+		for _, receiver := range receivers {
+			if !receiver.accepts(event) {
+				continue
+			}
+			if err := receiver.notifier().Notify(event); err != nil {
+				glog.Errorf("notify %s of devops project %s: %+v", receiver.Name, event.ProjectId, err)
+			}
+		}
+	}
+}
+
+// observePipelineRuns returns the latest runs of pipelines in the project. Stages and steps are observed only for
+// runs which are not finished or finished since the last observation.
+func observePipelineRuns(jenkins *gojenkins.Jenkins, projectId string, last map[string]string) ([]*PipelineRunEvent, error) {
+	folder, err := jenkins.GetJob(projectId)
+	if err != nil {
+		if isJenkinsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	observed := make([]*PipelineRunEvent, 0)
+	for _, job := range folder.Raw.Jobs {
+		runs := make([]*PipelineRun, 0)
+		_, err := jenkins.Requester.Get(strings.TrimSuffix(fmt.Sprintf(SearchPipelineRunUrl, projectId, job.Name), "?"), &runs,
+			map[string]string{"start": "0", "limit": strconv.Itoa(pipelineRunWatchLimit)})
+		if err != nil {
+			if isJenkinsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, run := range runs {
+			event := &PipelineRunEvent{
+				Kind:      PipelineRunEventKindRun,
+				Type:      pipelineRunEventType(run.State, run.Result),
+				ProjectId: projectId,
+				Pipeline:  job.Name,
+				RunId:     run.ID,
+				Time:      now,
+			}
+			// runs of multi-branch pipelines belong to the branch jobs
+			if run.Pipeline != "" && run.Pipeline != job.Name {
+				event.Branch = run.Pipeline
+			}
+			if event.Type == "" {
+				continue
+			}
+			observed = append(observed, event)
+
+			if isFinishedPipelineRunEvent(event.Type) && (last == nil || last[event.key()] == event.Type) {
+				continue
+			}
+			nodes, err := observePipelineRunNodes(jenkins, event, last)
+			if err != nil {
+				return nil, err
+			}
+			observed = append(observed, nodes...)
+		}
+	}
+	return observed, nil
+}
+
+func observePipelineRunNodes(jenkins *gojenkins.Jenkins, run *PipelineRunEvent, last map[string]string) ([]*PipelineRunEvent, error) {
+	var endpoint string
+	if run.Branch != "" {
+		endpoint = fmt.Sprintf(GetBranchPipeRunNodesUrl, run.ProjectId, run.Pipeline, run.Branch, run.RunId)
+	} else {
+		endpoint = fmt.Sprintf(GetPipeRunNodesUrl, run.ProjectId, run.Pipeline, run.RunId)
+	}
+	nodes := make([]*PipelineRunNodes, 0)
+	if _, err := jenkins.Requester.Get(strings.TrimSuffix(endpoint, "?"), &nodes, nil); err != nil {
+		return nil, err
+	}
+
+	observed := make([]*PipelineRunEvent, 0)
+	for _, node := range nodes {
+		stage := run.child(PipelineRunEventKindStage, node.ID, "", node.DisplayName, pipelineRunEventType(node.State, node.Result))
+		if stage.Type == "" {
+			continue
+		}
+		stage.Message = node.Input.Message
+		observed = append(observed, stage)
+
+		if isFinishedPipelineRunEvent(stage.Type) && last != nil && last[stage.key()] == stage.Type {
+			continue
+		}
+		if run.Branch != "" {
+			endpoint = fmt.Sprintf(GetBranchNodeStepsUrl, run.ProjectId, run.Pipeline, run.Branch, run.RunId, node.ID)
+		} else {
+			endpoint = fmt.Sprintf(GetNodeStepsUrl, run.ProjectId, run.Pipeline, run.RunId, node.ID)
+		}
+		steps := make([]*NodeSteps, 0)
+		if _, err := jenkins.Requester.Get(strings.TrimSuffix(endpoint, "?"), &steps, nil); err != nil {
+			return nil, err
+		}
+		for _, step := range steps {
+			event := run.child(PipelineRunEventKindStep, node.ID, step.ID, step.DisplayName, pipelineRunEventType(step.State, step.Result))
+			if event.Type == "" {
+				continue
+			}
+			event.Message = step.Input.Message
+			observed = append(observed, event)
+		}
+	}
+	return observed, nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPipelineRunEventType(t *testing.T) {
+	tests := []struct {
+		state    string
+		result   string
+		expected string
+	}{
+		{"QUEUED", "UNKNOWN", PipelineRunEventQueued},
+		{"RUNNING", "UNKNOWN", PipelineRunEventStarted},
+		{"PAUSED", "UNKNOWN", PipelineRunEventPaused},
+		{"FINISHED", "SUCCESS", PipelineRunEventSucceeded},
+		{"FINISHED", "UNSTABLE", PipelineRunEventFailed},
+		{"FINISHED", "FAILURE", PipelineRunEventFailed},
+		{"FINISHED", "ABORTED", PipelineRunEventAborted},
+		{"SKIPPED", "UNKNOWN", ""},
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		if got := pipelineRunEventType(test.state, test.result); got != test.expected {
+			t.Errorf("pipelineRunEventType(%s, %s) = %q, expected %q", test.state, test.result, got, test.expected)
+		}
+	}
+}
+
+func TestDiffPipelineRunEvents(t *testing.T) {
+	run := &PipelineRunEvent{Kind: PipelineRunEventKindRun, Type: PipelineRunEventStarted, ProjectId: "project-1", Pipeline: "build", RunId: "1"}
+	stage := run.child(PipelineRunEventKindStage, "6", "", "Deploy", PipelineRunEventPaused)
+	otherBranch := &PipelineRunEvent{Kind: PipelineRunEventKindRun, Type: PipelineRunEventStarted, ProjectId: "project-1", Pipeline: "build", Branch: "dev", RunId: "1"}
+
+	events, current := diffPipelineRunEvents(nil, []*PipelineRunEvent{run, stage})
+	if len(events) != 0 {
+		t.Errorf("expected no events on the first observation, got %v", events)
+	}
+
+	finished := *run
+	finished.Type = PipelineRunEventSucceeded
+	events, current = diffPipelineRunEvents(current, []*PipelineRunEvent{&finished, stage, otherBranch})
+	if !reflect.DeepEqual(events, []*PipelineRunEvent{&finished, otherBranch}) {
+		t.Errorf("unexpected events %v", events)
+	}
+
+	expected := map[string]string{
+		finished.key():    PipelineRunEventSucceeded,
+		stage.key():       PipelineRunEventPaused,
+		otherBranch.key(): PipelineRunEventStarted,
+	}
+	if !reflect.DeepEqual(current, expected) {
+		t.Errorf("expected current %v, got %v", expected, current)
+	}
+}
+
+func TestPipelineRunEventText(t *testing.T) {
+	run := &PipelineRunEvent{Kind: PipelineRunEventKindRun, Type: PipelineRunEventFailed, ProjectId: "project-1", Pipeline: "build", Branch: "master", RunId: "3", Time: time.Now()}
+	if title := run.Title(); title != "[FAILED] project-1/build/master #3" {
+		t.Errorf("unexpected title %q", title)
+	}
+	if text := run.Text(); text != "Run 3 of pipeline build (branch master) in devops project project-1 failed" {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	stage := run.child(PipelineRunEventKindStage, "6", "", "Deploy", PipelineRunEventPaused)
+	stage.Message = "Deploy to production?"
+	if title := stage.Title(); title != "[PAUSED] project-1/build/master #3 stage Deploy" {
+		t.Errorf("unexpected title %q", title)
+	}
+	if text := stage.Text(); text != "Stage Deploy of run 3 of pipeline build (branch master) in devops project project-1 is waiting for input: Deploy to production?" {
+		t.Errorf("unexpected text %q", text)
+	}
+}
+
+func TestPipelineRunEventBroker(t *testing.T) {
+	broker := &pipelineRunEventBroker{subscribers: make(map[string]map[chan *PipelineRunEvent]struct{})}
+
+	events, cancel := broker.subscribe("project-1")
+	_, cancelOther := broker.subscribe("project-2")
+	cancelOther()
+	cancelOther()
+
+	if projects := broker.projects(); !reflect.DeepEqual(projects, []string{"project-1"}) {
+		t.Errorf("unexpected projects %v", projects)
+	}
+
+	event := &PipelineRunEvent{Kind: PipelineRunEventKindRun, Type: PipelineRunEventQueued, ProjectId: "project-1", Pipeline: "build", RunId: "1"}
+	broker.publish(event)
+	broker.publish(&PipelineRunEvent{ProjectId: "project-2"})
+	// slow subscribers don't block publishing
+	for i := 0; i < pipelineRunEventBuffer; i++ {
+		broker.publish(event)
+	}
+
+	if got := <-events; got != event {
+		t.Errorf("unexpected event %v", got)
+	}
+
+	cancel()
+	count := 0
+	for range events {
+		count++
+	}
+	if count != pipelineRunEventBuffer-1 {
+		t.Errorf("expected %d buffered events, got %d", pipelineRunEventBuffer-1, count)
+	}
+	if projects := broker.projects(); len(projects) != 0 {
+		t.Errorf("unexpected projects %v", projects)
+	}
+}
+
+func TestNotificationReceiverAccepts(t *testing.T) {
+	run := &PipelineRunEvent{Kind: PipelineRunEventKindRun, Type: PipelineRunEventFailed, ProjectId: "project-1", Pipeline: "build", RunId: "1"}
+	started := run.child(PipelineRunEventKindStage, "6", "", "Deploy", PipelineRunEventStarted)
+
+	tests := []struct {
+		receiver NotificationReceiver
+		event    *PipelineRunEvent
+		expected bool
+	}{
+		{NotificationReceiver{}, run, true},
+		{NotificationReceiver{}, started, false},
+		{NotificationReceiver{Events: []string{"stage.*"}}, started, true},
+		{NotificationReceiver{Events: []string{"*"}}, started, true},
+		{NotificationReceiver{Events: []string{"run.succeeded"}}, run, false},
+		{NotificationReceiver{Pipelines: []string{"deploy"}}, run, false},
+		{NotificationReceiver{Pipelines: []string{"build"}, Events: []string{"run.failed"}}, run, true},
+	}
+
+	for i, test := range tests {
+		if got := test.receiver.accepts(test.event); got != test.expected {
+			t.Errorf("case %d: expected %v, got %v", i, test.expected, got)
+		}
+	}
+}
+
+func TestValidateNotificationReceiver(t *testing.T) {
+	valid := []*NotificationReceiver{
+		{Name: "hook", Type: NotificationReceiverTypeWebhook, Webhook: &WebhookReceiver{Url: "https://example.com/hook"}, Events: []string{"run.*", "step.paused"}},
+		{Name: "mail", Type: NotificationReceiverTypeEmail, Email: &EmailReceiver{To: []string{"dev@example.com"}}},
+		{Name: "chat", Type: NotificationReceiverTypeSlack, Slack: &SlackReceiver{Url: "https://hooks.slack.com/services/x"}},
+	}
+	for _, receiver := range valid {
+		if err := validateNotificationReceiver(receiver); err != nil {
+			t.Errorf("unexpected error of %s: %v", receiver.Name, err)
+		}
+	}
+
+	invalid := []*NotificationReceiver{
+		nil,
+		{Type: NotificationReceiverTypeWebhook, Webhook: &WebhookReceiver{Url: "https://example.com/hook"}},
+		{Name: "hook", Type: NotificationReceiverTypeWebhook},
+		{Name: "hook", Type: NotificationReceiverTypeWebhook, Webhook: &WebhookReceiver{Url: "example.com"}},
+		{Name: "mail", Type: NotificationReceiverTypeEmail, Email: &EmailReceiver{}},
+		{Name: "sms", Type: "sms"},
+		{Name: "hook", Type: NotificationReceiverTypeWebhook, Webhook: &WebhookReceiver{Url: "https://example.com/hook"}, Events: []string{"run.finished"}},
+	}
+	for i, receiver := range invalid {
+		if err := validateNotificationReceiver(receiver); err == nil {
+			t.Errorf("case %d: expected errors", i)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+var PipelineTemplateGroupVersionResource = devopsv1alpha1.SchemeGroupVersion.WithResource("pipelinetemplates")
+
+// types of ParameterTypeMap that can be rendered into pipelines, secrets should be kept in credentials
+var templateParameterTypes = []string{"string", "text", "boolean", "choice"}
+
+var templateParameterNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// templateFuncs are the functions available to pipeline templates besides the builtin ones
+var templateFuncs = template.FuncMap{
+	"groovy": groovyString,
+}
+
+var groovyStringReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// groovyString returns the value as a single quoted Groovy string literal, which isn't interpolated
+func groovyString(value string) string {
+	return "'" + groovyStringReplacer.Replace(value) + "'"
+}
+
+type PipelineTemplate struct {
+	Name        string                             `json:"name" description:"name of the template"`
+	Workspace   string                             `json:"workspace,omitempty" description:"workspace the template is available to, all workspaces if empty"`
+	DisplayName string                             `json:"display_name,omitempty" description:"display name of the template"`
+	Description string                             `json:"description,omitempty" description:"description of the template"`
+	Version     string                             `json:"version" description:"version of the template"`
+	Type        string                             `json:"type" description:"type of pipelines created from the template, pipeline or multi-branch-pipeline"`
+	Parameters  []devopsv1alpha1.TemplateParameter `json:"parameters,omitempty" description:"parameters given when pipelines are created from the template"`
+}
+
+type TemplatePipelineRequest struct {
+	Name        string            `json:"name" description:"name of the pipeline"`
+	Description string            `json:"description,omitempty" description:"description of the pipeline"`
+	Parameters  map[string]string `json:"parameters,omitempty" description:"values of the template parameters, default values are used for absent parameters"`
+}
+
+// TemplatePipeline is a pipeline created from a template
+type TemplatePipeline struct {
+	ProjectId       string            `json:"project_id" description:"devops project id"`
+	Pipeline        string            `json:"pipeline" description:"name of the pipeline"`
+	Description     string            `json:"description,omitempty" description:"description of the pipeline"`
+	Template        string            `json:"template" description:"name of the template"`
+	TemplateVersion string            `json:"template_version" description:"version of the template the pipeline is rendered from"`
+	Outdated        bool              `json:"outdated" description:"whether the template has a different version"`
+	Parameters      map[string]string `json:"parameters" description:"values of the template parameters"`
+	Creator         string            `json:"creator" description:"creator of the pipeline"`
+	CreateTime      time.Time         `json:"create_time" description:"create time of the pipeline"`
+	UpdateTime      time.Time         `json:"update_time" description:"last time the pipeline is rendered"`
+	Error           string            `json:"error,omitempty" description:"error of upgrading the pipeline in rollouts"`
+}
+
+// PipelineTemplateRequest creates or updates a template of the workspace
+type PipelineTemplateRequest struct {
+	Name string                              `json:"name" description:"name of the template"`
+	Spec devopsv1alpha1.PipelineTemplateSpec `json:"spec" description:"spec of the template"`
+}
+
+const (
+	ProjectPipelineTemplateTableName       = "project_pipeline_template"
+	ProjectPipelineTemplateProjectIdColumn = "project_id"
+	ProjectPipelineTemplatePipelineColumn  = "pipeline"
+	ProjectPipelineTemplateTemplateColumn  = "template"
+)
+
+// ProjectPipelineTemplate is the row of pipelines created from templates, parameters are the values in json,
+// config hash is the hash of the config of the pipeline in Jenkins after it's rendered
+type ProjectPipelineTemplate struct {
+	ProjectId       string    `json:"project_id"`
+	Pipeline        string    `json:"pipeline"`
+	Description     string    `json:"description"`
+	Template        string    `json:"template"`
+	TemplateVersion string    `json:"template_version"`
+	Parameters      string    `json:"parameters"`
+	ConfigHash      string    `json:"config_hash"`
+	Creator         string    `json:"creator"`
+	CreateTime      time.Time `json:"create_time"`
+	UpdateTime      time.Time `json:"update_time"`
+}
+
+var ProjectPipelineTemplateColumns = GetColumnsFromStruct(&ProjectPipelineTemplate{})
+
+func newPipelineTemplate(template *devopsv1alpha1.PipelineTemplate) *PipelineTemplate {
+	return &PipelineTemplate{
+		Name:        template.Name,
+		Workspace:   template.Labels[constants.WorkspaceLabelKey],
+		DisplayName: template.Spec.DisplayName,
+		Description: template.Spec.Description,
+		Version:     template.Spec.Version,
+		Type:        template.Spec.Type,
+		Parameters:  template.Spec.Parameters,
+	}
+}
+
+// templateAvailable returns whether projects of the workspace can use the template
+func templateAvailable(template *devopsv1alpha1.PipelineTemplate, workspace string) bool {
+	scope := template.Labels[constants.WorkspaceLabelKey]
+	return scope == "" || scope == workspace
+}
+
+// pipelineConfigHash returns the hash of the pipeline, pipelines read from Jenkins are compared by it
+// to find pipelines modified after they are rendered from templates
+func pipelineConfigHash(pipeline *ProjectPipeline) (string, error) {
+	data, err := json.Marshal(pipeline)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func validatePipelineTemplate(spec *devopsv1alpha1.PipelineTemplateSpec) error {
+	if spec.Version == "" {
+		return fmt.Errorf("version should not be empty")
+	}
+
+	switch spec.Type {
+	case NoScmPipelineType:
+		if spec.Pipeline == nil {
+			return fmt.Errorf("pipeline should not be nil")
+		}
+	case MultiBranchPipelineType:
+		if spec.MultiBranchPipeline == nil {
+			return fmt.Errorf("multiBranchPipeline should not be nil")
+		}
+	default:
+		return fmt.Errorf("unsupported pipeline type [%s]", spec.Type)
+	}
+
+	names := make(map[string]bool)
+	for _, parameter := range spec.Parameters {
+		if !templateParameterNameRegexp.MatchString(parameter.Name) {
+			return fmt.Errorf("invalid parameter name [%s]", parameter.Name)
+		}
+		if names[parameter.Name] {
+			return fmt.Errorf("duplicate parameter [%s]", parameter.Name)
+		}
+		names[parameter.Name] = true
+
+		if !sliceutil.HasString(templateParameterTypes, parameter.Type) {
+			return fmt.Errorf("unsupported type [%s] of parameter [%s], expected one of %v", parameter.Type, parameter.Name, templateParameterTypes)
+		}
+		if parameter.Type == "choice" && len(parameter.Choices) == 0 {
+			return fmt.Errorf("choices of parameter [%s] should not be empty", parameter.Name)
+		}
+		if parameter.DefaultValue != "" {
+			if err := validateTemplateParameterValue(&parameter, parameter.DefaultValue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateTemplateParameterValue(parameter *devopsv1alpha1.TemplateParameter, value string) error {
+	switch parameter.Type {
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("value of parameter [%s] should be true or false", parameter.Name)
+		}
+	case "choice":
+		if !sliceutil.HasString(parameter.Choices, value) {
+			return fmt.Errorf("value of parameter [%s] should be one of %v", parameter.Name, parameter.Choices)
+		}
+	}
+	return nil
+}
+
+// templateParameterValues returns the values of all parameters of the template, the default values are used for
+// absent parameters
+func templateParameterValues(spec *devopsv1alpha1.PipelineTemplateSpec, values map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(spec.Parameters))
+	for i := range spec.Parameters {
+		parameter := &spec.Parameters[i]
+		value, ok := values[parameter.Name]
+		if !ok {
+			value = parameter.DefaultValue
+		}
+		if value == "" {
+			if parameter.Required {
+				return nil, fmt.Errorf("parameter [%s] is required", parameter.Name)
+			}
+		} else if err := validateTemplateParameterValue(parameter, value); err != nil {
+			return nil, err
+		}
+		result[parameter.Name] = value
+	}
+
+	for name := range values {
+		if _, ok := result[name]; !ok {
+			return nil, fmt.Errorf("unknown parameter [%s]", name)
+		}
+	}
+	return result, nil
+}
+
+// renderPipelineTemplate renders the template with the values of parameters into the pipeline named name
+func renderPipelineTemplate(spec *devopsv1alpha1.PipelineTemplateSpec, name, description string, values map[string]string) (*ProjectPipeline, error) {
+	if err := validatePipelineTemplate(spec); err != nil {
+		return nil, err
+	}
+
+	pipelineSpec := &devopsv1alpha1.PipelineSpec{
+		Name:                name,
+		Description:         description,
+		Type:                spec.Type,
+		Pipeline:            spec.Pipeline.DeepCopy(),
+		MultiBranchPipeline: spec.MultiBranchPipeline.DeepCopy(),
+	}
+	if spec.Type == NoScmPipelineType {
+		pipelineSpec.MultiBranchPipeline = nil
+	} else {
+		pipelineSpec.Pipeline = nil
+	}
+
+	render := func(text string) (string, error) {
+		t, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, values); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	if err := renderStrings(reflect.ValueOf(pipelineSpec.Pipeline), render); err != nil {
+		return nil, err
+	}
+	if err := renderStrings(reflect.ValueOf(pipelineSpec.MultiBranchPipeline), render); err != nil {
+		return nil, err
+	}
+
+	return ProjectPipelineFromSpec(name, pipelineSpec), nil
+}
+
+// renderStrings replaces all strings reachable from v by the rendered ones
+func renderStrings(v reflect.Value, render func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return renderStrings(v.Elem(), render)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if err := renderStrings(v.Field(i), render); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := renderStrings(v.Index(i), render); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		rendered, err := render(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(rendered)
+	}
+	return nil
+}
@@ -0,0 +1,545 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/db"
+	"kubesphere.io/kubesphere/pkg/simple/client/devops_mysql"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+)
+
+// GetPipelineTemplates returns the templates available to the project
+func GetPipelineTemplates(projectId string) ([]*PipelineTemplate, error) {
+	project, err := GetProject(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := k8s.DynamicClient().Resource(PipelineTemplateGroupVersionResource).List(metav1.ListOptions{})
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	templates := make([]*PipelineTemplate, 0)
+	for _, item := range list.Items {
+		template := &devopsv1alpha1.PipelineTemplate{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, template); err != nil {
+			glog.Errorf("invalid pipeline template %s: %+v", item.GetName(), err)
+			continue
+		}
+		if templateAvailable(template, project.Workspace) {
+			templates = append(templates, newPipelineTemplate(template))
+		}
+	}
+	return templates, nil
+}
+
+func GetPipelineTemplate(projectId, name string) (*PipelineTemplate, error) {
+	template, err := getProjectPipelineTemplate(projectId, name)
+	if err != nil {
+		return nil, err
+	}
+	return newPipelineTemplate(template), nil
+}
+
+// CreateTemplatePipeline renders the template with the values of the request and creates the pipeline in the project
+func CreateTemplatePipeline(projectId, username, templateName string, request *TemplatePipelineRequest) (*TemplatePipeline, error) {
+	template, err := getProjectPipelineTemplate(projectId, templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := templateParameterValues(&template.Spec, request.Parameters)
+	if err != nil {
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+	pipeline, err := renderPipelineTemplate(&template.Spec, request.Name, request.Description, values)
+	if err != nil {
+		err := fmt.Errorf("render pipeline template [%s]: %v", templateName, err)
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := CreateProjectPipeline(projectId, pipeline); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	row := &ProjectPipelineTemplate{
+		ProjectId:       projectId,
+		Pipeline:        request.Name,
+		Description:     request.Description,
+		Template:        templateName,
+		TemplateVersion: template.Spec.Version,
+		Creator:         username,
+		CreateTime:      now,
+		UpdateTime:      now,
+	}
+	row.ConfigHash, err = jenkinsPipelineConfigHash(projectId, request.Name)
+	if err == nil {
+		err = row.setParameters(values)
+	}
+	if err == nil {
+		dbconn := devops_mysql.OpenDatabase()
+		_, err = dbconn.InsertInto(ProjectPipelineTemplateTableName).
+			Columns(ProjectPipelineTemplateColumns...).Record(row).Exec()
+	}
+	if err != nil {
+		glog.Errorf("%+v", err)
+		// the pipeline is untracked without the row
+		if _, err := DeleteProjectPipeline(projectId, request.Name); err != nil {
+			glog.Errorf("delete pipeline %s of devops project %s: %+v", request.Name, projectId, err)
+		}
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	return row.templatePipeline(template.Spec.Version)
+}
+
+// GetTemplatePipelines returns the pipelines created from the template in the project, or in all projects
+// if projectId is empty
+func GetTemplatePipelines(projectId, templateName string) ([]*TemplatePipeline, error) {
+	var template *devopsv1alpha1.PipelineTemplate
+	var err error
+	if projectId != "" {
+		template, err = getProjectPipelineTemplate(projectId, templateName)
+	} else {
+		template, err = getPipelineTemplate(templateName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := listTemplatePipelines(projectId, templateName)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	pipelines := make([]*TemplatePipeline, 0, len(rows))
+	for _, row := range rows {
+		pipeline, err := row.templatePipeline(template.Spec.Version)
+		if err != nil {
+			glog.Errorf("invalid parameters of pipeline %s of devops project %s: %+v", row.Pipeline, row.ProjectId, err)
+			continue
+		}
+		pipelines = append(pipelines, pipeline)
+	}
+	return pipelines, nil
+}
+
+// UpgradeTemplatePipeline renders the pipeline with the current version of its template again, the values of the
+// last rendering are used if values is nil. Pipelines modified after the last rendering are only overwritten if force is true.
+func UpgradeTemplatePipeline(projectId, pipeline, templateName string, values map[string]string, force bool) (*TemplatePipeline, error) {
+	template, err := getProjectPipelineTemplate(projectId, templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	row := &ProjectPipelineTemplate{}
+	dbconn := devops_mysql.OpenDatabase()
+	err = dbconn.Select(ProjectPipelineTemplateColumns...).From(ProjectPipelineTemplateTableName).
+		Where(db.And(db.Eq(ProjectPipelineTemplateProjectIdColumn, projectId),
+			db.Eq(ProjectPipelineTemplatePipelineColumn, pipeline),
+			db.Eq(ProjectPipelineTemplateTemplateColumn, templateName))).LoadOne(row)
+	if err == db.ErrNotFound {
+		err := fmt.Errorf("pipeline [%s] is not created from template [%s]", pipeline, templateName)
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := upgradeTemplatePipeline(template, row, values, force); err != nil {
+		return nil, err
+	}
+	return row.templatePipeline(template.Spec.Version)
+}
+
+// RolloutPipelineTemplate upgrades the pipelines created from older versions of the template, pipelines failed to
+// upgrade are returned with their errors. Pipelines modified after they are rendered and pipelines of projects the
+// template isn't available to are left alone and returned with errors. The template must be of the workspace unless
+// workspace is empty.
+func RolloutPipelineTemplate(workspace, templateName string) ([]*TemplatePipeline, error) {
+	var template *devopsv1alpha1.PipelineTemplate
+	var err error
+	if workspace != "" {
+		template, err = getWorkspacePipelineTemplate(workspace, templateName)
+	} else {
+		template, err = getPipelineTemplate(templateName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := listTemplatePipelines("", templateName)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	results := make([]*TemplatePipeline, 0)
+	for _, row := range rows {
+		if row.TemplateVersion == template.Spec.Version {
+			continue
+		}
+
+		project, err := GetProject(row.ProjectId)
+		if err == nil && !templateAvailable(template, project.Workspace) {
+			err = fmt.Errorf("pipeline template [%s] is not available to devops project [%s]", templateName, row.ProjectId)
+		}
+		if err == nil {
+			err = upgradeTemplatePipeline(template, row, nil, false)
+		}
+		result, _ := row.templatePipeline(template.Spec.Version)
+		if result == nil {
+			result = &TemplatePipeline{ProjectId: row.ProjectId, Pipeline: row.Pipeline, Template: row.Template, TemplateVersion: row.TemplateVersion, Outdated: true}
+		}
+		if err != nil {
+			glog.Errorf("upgrade pipeline %s of devops project %s: %+v", row.Pipeline, row.ProjectId, err)
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// deleteTemplatePipeline stops tracking the deleted pipeline
+func deleteTemplatePipeline(projectId, pipeline string) error {
+	dbconn := devops_mysql.OpenDatabase()
+	_, err := dbconn.DeleteFrom(ProjectPipelineTemplateTableName).
+		Where(db.And(db.Eq(ProjectPipelineTemplateProjectIdColumn, projectId),
+			db.Eq(ProjectPipelineTemplatePipelineColumn, pipeline))).Exec()
+	if err != nil && err != db.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func upgradeTemplatePipeline(template *devopsv1alpha1.PipelineTemplate, row *ProjectPipelineTemplate, values map[string]string, force bool) error {
+	// the hash isn't recorded for pipelines rendered before it's introduced
+	if !force && row.ConfigHash != "" {
+		hash, err := jenkinsPipelineConfigHash(row.ProjectId, row.Pipeline)
+		if err != nil {
+			return err
+		}
+		if hash != row.ConfigHash {
+			err := fmt.Errorf("pipeline [%s] is modified after it's rendered from template [%s], upgrade it with force to overwrite the changes", row.Pipeline, template.Name)
+			glog.Warning(err)
+			return restful.NewError(http.StatusConflict, err.Error())
+		}
+	}
+
+	if values == nil {
+		last, err := row.parameters()
+		if err != nil {
+			return restful.NewError(http.StatusInternalServerError, err.Error())
+		}
+		// values of parameters removed from the template are dropped
+		values = make(map[string]string)
+		for _, parameter := range template.Spec.Parameters {
+			if value, ok := last[parameter.Name]; ok {
+				values[parameter.Name] = value
+			}
+		}
+	}
+
+	values, err := templateParameterValues(&template.Spec, values)
+	if err != nil {
+		glog.Warning(err)
+		return restful.NewError(http.StatusBadRequest, err.Error())
+	}
+	pipeline, err := renderPipelineTemplate(&template.Spec, row.Pipeline, row.Description, values)
+	if err != nil {
+		err := fmt.Errorf("render pipeline template [%s]: %v", template.Name, err)
+		glog.Warning(err)
+		return restful.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := UpdateProjectPipeline(row.ProjectId, row.Pipeline, pipeline); err != nil {
+		return err
+	}
+
+	hash, err := jenkinsPipelineConfigHash(row.ProjectId, row.Pipeline)
+	if err != nil {
+		return err
+	}
+	if err := row.setParameters(values); err != nil {
+		return restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	row.TemplateVersion = template.Spec.Version
+	row.ConfigHash = hash
+	row.UpdateTime = time.Now()
+
+	dbconn := devops_mysql.OpenDatabase()
+	_, err = dbconn.Update(ProjectPipelineTemplateTableName).
+		Set("template_version", row.TemplateVersion).
+		Set("parameters", row.Parameters).
+		Set("config_hash", row.ConfigHash).
+		Set("update_time", row.UpdateTime).
+		Where(db.And(db.Eq(ProjectPipelineTemplateProjectIdColumn, row.ProjectId),
+			db.Eq(ProjectPipelineTemplatePipelineColumn, row.Pipeline))).Exec()
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// jenkinsPipelineConfigHash returns the hash of the config of the pipeline in Jenkins
+func jenkinsPipelineConfigHash(projectId, pipeline string) (string, error) {
+	p, err := GetProjectPipeline(projectId, pipeline)
+	if err != nil {
+		return "", err
+	}
+	hash, err := pipelineConfigHash(p)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return "", restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return hash, nil
+}
+
+func listTemplatePipelines(projectId, templateName string) ([]*ProjectPipelineTemplate, error) {
+	condition := db.Eq(ProjectPipelineTemplateTemplateColumn, templateName)
+	if projectId != "" {
+		condition = db.And(condition, db.Eq(ProjectPipelineTemplateProjectIdColumn, projectId))
+	}
+
+	rows := make([]*ProjectPipelineTemplate, 0)
+	dbconn := devops_mysql.OpenDatabase()
+	_, err := dbconn.Select(ProjectPipelineTemplateColumns...).From(ProjectPipelineTemplateTableName).
+		Where(condition).Load(&rows)
+	return rows, err
+}
+
+func getPipelineTemplate(name string) (*devopsv1alpha1.PipelineTemplate, error) {
+	u, err := k8s.DynamicClient().Resource(PipelineTemplateGroupVersionResource).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		err := fmt.Errorf("pipeline template [%s] not found", name)
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	template := &devopsv1alpha1.PipelineTemplate{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, template); err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return template, nil
+}
+
+// GetWorkspacePipelineTemplates returns the templates of the workspace, templates available to all workspaces
+// are not included
+func GetWorkspacePipelineTemplates(workspace string) ([]*PipelineTemplate, error) {
+	list, err := k8s.DynamicClient().Resource(PipelineTemplateGroupVersionResource).
+		List(metav1.ListOptions{LabelSelector: constants.WorkspaceLabelKey + "=" + workspace})
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	templates := make([]*PipelineTemplate, 0)
+	for _, item := range list.Items {
+		template := &devopsv1alpha1.PipelineTemplate{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, template); err != nil {
+			glog.Errorf("invalid pipeline template %s: %+v", item.GetName(), err)
+			continue
+		}
+		templates = append(templates, newPipelineTemplate(template))
+	}
+	return templates, nil
+}
+
+// CreateWorkspacePipelineTemplate creates the template labelled with the workspace
+func CreateWorkspacePipelineTemplate(workspace, username string, request *PipelineTemplateRequest) (*PipelineTemplate, error) {
+	if err := validatePipelineTemplate(&request.Spec); err != nil {
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	template := &devopsv1alpha1.PipelineTemplate{
+		TypeMeta: metav1.TypeMeta{APIVersion: devopsv1alpha1.SchemeGroupVersion.String(), Kind: "PipelineTemplate"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        request.Name,
+			Labels:      map[string]string{constants.WorkspaceLabelKey: workspace},
+			Annotations: map[string]string{constants.CreatorAnnotationKey: username},
+		},
+		Spec: request.Spec,
+	}
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(template)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	_, err = k8s.DynamicClient().Resource(PipelineTemplateGroupVersionResource).Create(&unstructured.Unstructured{Object: object}, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		err := fmt.Errorf("pipeline template [%s] already exists", request.Name)
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusConflict, err.Error())
+	}
+	if errors.IsInvalid(err) {
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return newPipelineTemplate(template), nil
+}
+
+// UpdateWorkspacePipelineTemplate updates the spec of the template of the workspace, pipelines created from the
+// template are upgraded by rollouts
+func UpdateWorkspacePipelineTemplate(workspace, name string, request *PipelineTemplateRequest) (*PipelineTemplate, error) {
+	if err := validatePipelineTemplate(&request.Spec); err != nil {
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	template, err := getWorkspacePipelineTemplate(workspace, name)
+	if err != nil {
+		return nil, err
+	}
+	template.Spec = request.Spec
+
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(template)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	_, err = k8s.DynamicClient().Resource(PipelineTemplateGroupVersionResource).Update(&unstructured.Unstructured{Object: object}, metav1.UpdateOptions{})
+	if errors.IsConflict(err) {
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusConflict, err.Error())
+	}
+	if errors.IsInvalid(err) {
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return newPipelineTemplate(template), nil
+}
+
+// DeleteWorkspacePipelineTemplate deletes the template of the workspace, pipelines created from the template are kept
+func DeleteWorkspacePipelineTemplate(workspace, name string) error {
+	if _, err := getWorkspacePipelineTemplate(workspace, name); err != nil {
+		return err
+	}
+
+	err := k8s.DynamicClient().Resource(PipelineTemplateGroupVersionResource).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		glog.Errorf("%+v", err)
+		return restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+// getWorkspacePipelineTemplate returns the template if it's labelled with the workspace, templates of other
+// workspaces and templates available to all workspaces can't be managed by workspace admins
+func getWorkspacePipelineTemplate(workspace, name string) (*devopsv1alpha1.PipelineTemplate, error) {
+	template, err := getPipelineTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	if template.Labels[constants.WorkspaceLabelKey] != workspace {
+		err := fmt.Errorf("pipeline template [%s] not found", name)
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusNotFound, err.Error())
+	}
+	return template, nil
+}
+
+// getProjectPipelineTemplate returns the template if it's available to the project
+func getProjectPipelineTemplate(projectId, name string) (*devopsv1alpha1.PipelineTemplate, error) {
+	project, err := GetProject(projectId)
+	if err != nil {
+		return nil, err
+	}
+	template, err := getPipelineTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// templates of other workspaces are invisible
+	if !templateAvailable(template, project.Workspace) {
+		err := fmt.Errorf("pipeline template [%s] not found", name)
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusNotFound, err.Error())
+	}
+	return template, nil
+}
+
+func (r *ProjectPipelineTemplate) parameters() (map[string]string, error) {
+	values := make(map[string]string)
+	if err := json.Unmarshal([]byte(r.Parameters), &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (r *ProjectPipelineTemplate) setParameters(values map[string]string) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	r.Parameters = string(data)
+	return nil
+}
+
+func (r *ProjectPipelineTemplate) templatePipeline(version string) (*TemplatePipeline, error) {
+	values, err := r.parameters()
+	if err != nil {
+		return nil, err
+	}
+	return &TemplatePipeline{
+		ProjectId:       r.ProjectId,
+		Pipeline:        r.Pipeline,
+		Description:     r.Description,
+		Template:        r.Template,
+		TemplateVersion: r.TemplateVersion,
+		Outdated:        r.TemplateVersion != version,
+		Parameters:      values,
+		Creator:         r.Creator,
+		CreateTime:      r.CreateTime,
+		UpdateTime:      r.UpdateTime,
+	}, nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+func newTestPipelineTemplateSpec() *devopsv1alpha1.PipelineTemplateSpec {
+	return &devopsv1alpha1.PipelineTemplateSpec{
+		Version: "1.0.0",
+		Type:    NoScmPipelineType,
+		Parameters: []devopsv1alpha1.TemplateParameter{
+			{Name: "image", Type: "string", Required: true},
+			{Name: "runTests", Type: "boolean", DefaultValue: "true"},
+			{Name: "env", Type: "choice", Choices: []string{"test", "prod"}, DefaultValue: "test"},
+		},
+		Pipeline: &devopsv1alpha1.NoScmPipeline{
+			DisableConcurrent: true,
+			Parameters:        []devopsv1alpha1.Parameter{{Name: "tag", Type: "string", DefaultValue: "{{ .env }}-latest"}},
+			Jenkinsfile:       "build '{{ .image }}'{{ if eq .runTests \"true\" }}\ntest{{ end }}\ndeploy '{{ .env }}'",
+		},
+	}
+}
+
+func TestValidatePipelineTemplate(t *testing.T) {
+	if err := validatePipelineTemplate(newTestPipelineTemplateSpec()); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []func(spec *devopsv1alpha1.PipelineTemplateSpec){
+		func(spec *devopsv1alpha1.PipelineTemplateSpec) { spec.Version = "" },
+		func(spec *devopsv1alpha1.PipelineTemplateSpec) { spec.Type = MultiBranchPipelineType },
+		func(spec *devopsv1alpha1.PipelineTemplateSpec) { spec.Parameters[0].Name = "image-name" },
+		func(spec *devopsv1alpha1.PipelineTemplateSpec) { spec.Parameters[1].Name = "image" },
+		func(spec *devopsv1alpha1.PipelineTemplateSpec) { spec.Parameters[0].Type = "password" },
+		func(spec *devopsv1alpha1.PipelineTemplateSpec) { spec.Parameters[1].DefaultValue = "yes" },
+		func(spec *devopsv1alpha1.PipelineTemplateSpec) { spec.Parameters[2].DefaultValue = "staging" },
+		func(spec *devopsv1alpha1.PipelineTemplateSpec) { spec.Parameters[2].Choices = nil },
+	}
+	for i, modify := range invalid {
+		spec := newTestPipelineTemplateSpec()
+		modify(spec)
+		if err := validatePipelineTemplate(spec); err == nil {
+			t.Errorf("case %d: expected errors", i)
+		}
+	}
+}
+
+func TestTemplateParameterValues(t *testing.T) {
+	spec := newTestPipelineTemplateSpec()
+
+	values, err := templateParameterValues(spec, map[string]string{"image": "nginx", "env": "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"image": "nginx", "runTests": "true", "env": "prod"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	invalid := []map[string]string{
+		{},
+		{"image": ""},
+		{"image": "nginx", "runTests": "1x"},
+		{"image": "nginx", "env": "staging"},
+		{"image": "nginx", "registry": "docker.io"},
+	}
+	for i, values := range invalid {
+		if _, err := templateParameterValues(spec, values); err == nil {
+			t.Errorf("case %d: expected errors", i)
+		}
+	}
+}
+
+func TestRenderPipelineTemplate(t *testing.T) {
+	spec := newTestPipelineTemplateSpec()
+
+	pipeline, err := renderPipelineTemplate(spec, "nginx", "nginx pipeline", map[string]string{"image": "nginx", "runTests": "false", "env": "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipeline.Type != NoScmPipelineType || pipeline.Pipeline.Name != "nginx" || pipeline.Pipeline.Description != "nginx pipeline" || !pipeline.Pipeline.DisableConcurrent {
+		t.Errorf("unexpected pipeline %+v", pipeline.Pipeline)
+	}
+	if pipeline.Pipeline.Jenkinsfile != "build 'nginx'\ndeploy 'prod'" {
+		t.Errorf("unexpected jenkinsfile %q", pipeline.Pipeline.Jenkinsfile)
+	}
+	if pipeline.Pipeline.Parameters[0].DefaultValue != "prod-latest" {
+		t.Errorf("unexpected parameters %+v", pipeline.Pipeline.Parameters[0])
+	}
+	// the template is not modified
+	if !strings.Contains(spec.Pipeline.Jenkinsfile, "{{ .image }}") {
+		t.Errorf("template is modified: %q", spec.Pipeline.Jenkinsfile)
+	}
+
+	config, err := PipelineConfigXml("project-1", pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(config, "deploy &apos;prod&apos;") && !strings.Contains(config, "deploy 'prod'") {
+		t.Errorf("jenkinsfile isn't rendered into the config: %s", config)
+	}
+
+	if _, err := renderPipelineTemplate(spec, "nginx", "", map[string]string{"image": "nginx"}); err == nil {
+		t.Errorf("expected errors of missing values")
+	}
+
+	spec.Pipeline.Jenkinsfile = "{{ .image"
+	if _, err := renderPipelineTemplate(spec, "nginx", "", map[string]string{"image": "nginx", "runTests": "true", "env": "test"}); err == nil {
+		t.Errorf("expected errors of invalid templates")
+	}
+}
+
+func TestRenderMultiBranchPipelineTemplate(t *testing.T) {
+	spec := &devopsv1alpha1.PipelineTemplateSpec{
+		Version:    "2",
+		Type:       MultiBranchPipelineType,
+		Parameters: []devopsv1alpha1.TemplateParameter{{Name: "repo", Type: "string", Required: true}},
+		// pipeline of another type is ignored
+		Pipeline: &devopsv1alpha1.NoScmPipeline{Jenkinsfile: "{{ .unknown }}"},
+		MultiBranchPipeline: &devopsv1alpha1.MultiBranchPipeline{
+			SourceType: devopsv1alpha1.SourceTypeGit,
+			GitSource:  &devopsv1alpha1.GitSource{Url: "https://github.com/kubesphere/{{ .repo }}.git", DiscoverBranches: true},
+			ScriptPath: "Jenkinsfile",
+		},
+	}
+
+	pipeline, err := renderPipelineTemplate(spec, "app", "", map[string]string{"repo": "devops-sample"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipeline.Pipeline != nil || pipeline.MultiBranchPipeline.GitSource.Url != "https://github.com/kubesphere/devops-sample.git" ||
+		pipeline.MultiBranchPipeline.Name != "app" {
+		t.Errorf("unexpected pipeline %+v", pipeline)
+	}
+}
+
+func TestTemplateAvailable(t *testing.T) {
+	template := &devopsv1alpha1.PipelineTemplate{}
+	if !templateAvailable(template, "dev") {
+		t.Errorf("templates without workspaces should be available to all workspaces")
+	}
+	template.Labels = map[string]string{constants.WorkspaceLabelKey: "ops"}
+	if templateAvailable(template, "dev") || !templateAvailable(template, "ops") {
+		t.Errorf("templates of workspaces should only be available to their workspaces")
+	}
+}
+
+func TestGroovyString(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                    `'nginx'`,
+		"it's":                     `'it\'s'`,
+		`C:\path`:                  `'C:\\path'`,
+		"a\nb":                     `'a\nb'`,
+		"${env.SECRET}":            `'${env.SECRET}'`,
+		"'; sh 'rm -rf /'; echo '": `'\'; sh \'rm -rf /\'; echo \''`,
+	}
+	for value, expected := range tests {
+		if quoted := groovyString(value); quoted != expected {
+			t.Errorf("expected %s quoted as %s, got %s", value, expected, quoted)
+		}
+	}
+
+	spec := newTestPipelineTemplateSpec()
+	spec.Pipeline.Jenkinsfile = "build {{ groovy .image }}"
+	pipeline, err := renderPipelineTemplate(spec, "nginx", "", map[string]string{"image": "nginx'\nsh 'id", "runTests": "true", "env": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pipeline.Pipeline.Jenkinsfile != `build 'nginx\'\nsh \'id'` {
+		t.Errorf("unexpected jenkinsfile %q", pipeline.Pipeline.Jenkinsfile)
+	}
+}
+
+func TestPipelineConfigHash(t *testing.T) {
+	values := map[string]string{"image": "nginx", "runTests": "true", "env": "test"}
+	render := func(values map[string]string) string {
+		pipeline, err := renderPipelineTemplate(newTestPipelineTemplateSpec(), "nginx", "", values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := pipelineConfigHash(pipeline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	hash := render(values)
+	if render(values) != hash {
+		t.Errorf("expected the same hash of the same pipeline")
+	}
+	values["image"] = "httpd"
+	if render(values) == hash {
+		t.Errorf("expected different hashes of different pipelines")
+	}
+}
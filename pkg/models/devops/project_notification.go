@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"time"
+)
+
+const (
+	NotificationReceiverTypeWebhook = "webhook"
+	NotificationReceiverTypeEmail   = "email"
+	NotificationReceiverTypeSlack   = "slack"
+)
+
+// default events of receivers without events, finished runs and stages waiting for input
+var DefaultNotificationEvents = []string{"run.succeeded", "run.failed", "run.aborted", "stage.paused"}
+
+type NotificationReceiver struct {
+	Name       string           `json:"name" description:"name of the receiver, unique in the project"`
+	Type       string           `json:"type" description:"type of the receiver, webhook, email or slack"`
+	Events     []string         `json:"events,omitempty" description:"events sent to the receiver in the form of <kind>.<type>, e.g. run.failed, stage.paused, run.* or *, run.succeeded, run.failed, run.aborted and stage.paused if empty"`
+	Pipelines  []string         `json:"pipelines,omitempty" description:"pipelines whose events are sent to the receiver, all pipelines of the project if empty"`
+	Webhook    *WebhookReceiver `json:"webhook,omitempty" description:"webhook receiver, the events are posted as json"`
+	Email      *EmailReceiver   `json:"email,omitempty" description:"email receiver, the smtp server is configured by the flags of ks-apiserver"`
+	Slack      *SlackReceiver   `json:"slack,omitempty" description:"slack receiver"`
+	Creator    string           `json:"creator,omitempty" description:"creator of the receiver"`
+	CreateTime *time.Time       `json:"create_time,omitempty" description:"create time of the receiver"`
+}
+
+type WebhookReceiver struct {
+	Url string `json:"url" description:"url the events are posted to"`
+}
+
+type EmailReceiver struct {
+	To []string `json:"to" description:"email addresses of the receivers"`
+}
+
+type SlackReceiver struct {
+	Url     string `json:"url" description:"url of the incoming webhook"`
+	Channel string `json:"channel,omitempty" description:"channel of messages, the channel of the incoming webhook if empty"`
+}
+
+const (
+	ProjectNotificationReceiverTableName       = "project_notification_receiver"
+	ProjectNotificationReceiverProjectIdColumn = "project_id"
+	ProjectNotificationReceiverNameColumn      = "name"
+	ProjectNotificationReceiverConfigColumn    = "config"
+)
+
+// ProjectNotificationReceiver is the row of receivers, config is the receiver in json
+type ProjectNotificationReceiver struct {
+	ProjectId  string    `json:"project_id"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Config     string    `json:"config"`
+	Creator    string    `json:"creator"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+var ProjectNotificationReceiverColumns = GetColumnsFromStruct(&ProjectNotificationReceiver{})
@@ -0,0 +1,304 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+	"kubesphere.io/kubesphere/pkg/db"
+	"kubesphere.io/kubesphere/pkg/simple/client/devops_mysql"
+	"kubesphere.io/kubesphere/pkg/simple/client/notification"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+func CreateProjectNotificationReceiver(projectId, username string, receiver *NotificationReceiver) (*NotificationReceiver, error) {
+	if err := validateNotificationReceiver(receiver); err != nil {
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	_, err := getProjectNotificationReceiver(projectId, receiver.Name)
+	if err == nil {
+		err := fmt.Errorf("notification receiver [%s] already exists", receiver.Name)
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusConflict, err.Error())
+	}
+	if err != db.ErrNotFound {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	now := time.Now()
+	receiver.Creator = username
+	receiver.CreateTime = &now
+	row, err := newProjectNotificationReceiver(projectId, receiver)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	dbconn := devops_mysql.OpenDatabase()
+	_, err = dbconn.InsertInto(ProjectNotificationReceiverTableName).
+		Columns(ProjectNotificationReceiverColumns...).Record(row).Exec()
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return receiver, nil
+}
+
+func UpdateProjectNotificationReceiver(projectId, name string, receiver *NotificationReceiver) (*NotificationReceiver, error) {
+	receiver.Name = name
+	if err := validateNotificationReceiver(receiver); err != nil {
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	old, err := GetProjectNotificationReceiver(projectId, name)
+	if err != nil {
+		return nil, err
+	}
+	receiver.Creator = old.Creator
+	receiver.CreateTime = old.CreateTime
+	row, err := newProjectNotificationReceiver(projectId, receiver)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	dbconn := devops_mysql.OpenDatabase()
+	_, err = dbconn.Update(ProjectNotificationReceiverTableName).
+		Set("type", row.Type).
+		Set(ProjectNotificationReceiverConfigColumn, row.Config).
+		Where(db.And(db.Eq(ProjectNotificationReceiverProjectIdColumn, projectId),
+			db.Eq(ProjectNotificationReceiverNameColumn, name))).Exec()
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return receiver, nil
+}
+
+func DeleteProjectNotificationReceiver(projectId, name string) error {
+	if _, err := GetProjectNotificationReceiver(projectId, name); err != nil {
+		return err
+	}
+
+	dbconn := devops_mysql.OpenDatabase()
+	_, err := dbconn.DeleteFrom(ProjectNotificationReceiverTableName).
+		Where(db.And(db.Eq(ProjectNotificationReceiverProjectIdColumn, projectId),
+			db.Eq(ProjectNotificationReceiverNameColumn, name))).Exec()
+	if err != nil && err != db.ErrNotFound {
+		glog.Errorf("%+v", err)
+		return restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return nil
+}
+
+func GetProjectNotificationReceiver(projectId, name string) (*NotificationReceiver, error) {
+	receiver, err := getProjectNotificationReceiver(projectId, name)
+	if err == db.ErrNotFound {
+		err := fmt.Errorf("notification receiver [%s] not found", name)
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return receiver, nil
+}
+
+func GetProjectNotificationReceivers(projectId string) ([]*NotificationReceiver, error) {
+	receivers, err := listNotificationReceivers(db.Eq(ProjectNotificationReceiverProjectIdColumn, projectId))
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return receivers[projectId], nil
+}
+
+func getProjectNotificationReceiver(projectId, name string) (*NotificationReceiver, error) {
+	dbconn := devops_mysql.OpenDatabase()
+	row := &ProjectNotificationReceiver{}
+	err := dbconn.Select(ProjectNotificationReceiverColumns...).
+		From(ProjectNotificationReceiverTableName).
+		Where(db.And(db.Eq(ProjectNotificationReceiverProjectIdColumn, projectId),
+			db.Eq(ProjectNotificationReceiverNameColumn, name))).LoadOne(row)
+	if err != nil {
+		return nil, err
+	}
+	return row.receiver()
+}
+
+// listNotificationReceivers returns the receivers matching the condition by project
+func listNotificationReceivers(condition interface{}) (map[string][]*NotificationReceiver, error) {
+	dbconn := devops_mysql.OpenDatabase()
+	rows := make([]*ProjectNotificationReceiver, 0)
+	query := dbconn.Select(ProjectNotificationReceiverColumns...).From(ProjectNotificationReceiverTableName)
+	if condition != nil {
+		query = query.Where(condition)
+	}
+	if _, err := query.OrderDir(ProjectNotificationReceiverNameColumn, true).Load(&rows); err != nil {
+		return nil, err
+	}
+
+	receivers := make(map[string][]*NotificationReceiver)
+	for _, row := range rows {
+		receiver, err := row.receiver()
+		if err != nil {
+			glog.Errorf("invalid notification receiver %s of devops project %s: %+v", row.Name, row.ProjectId, err)
+			continue
+		}
+		receivers[row.ProjectId] = append(receivers[row.ProjectId], receiver)
+	}
+	return receivers, nil
+}
+
+func newProjectNotificationReceiver(projectId string, receiver *NotificationReceiver) (*ProjectNotificationReceiver, error) {
+	config := *receiver
+	config.Creator = ""
+	config.CreateTime = nil
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ProjectNotificationReceiver{
+		ProjectId:  projectId,
+		Name:       receiver.Name,
+		Type:       receiver.Type,
+		Config:     string(data),
+		Creator:    receiver.Creator,
+		CreateTime: *receiver.CreateTime,
+	}, nil
+}
+
+func (r *ProjectNotificationReceiver) receiver() (*NotificationReceiver, error) {
+	receiver := &NotificationReceiver{}
+	if err := json.Unmarshal([]byte(r.Config), receiver); err != nil {
+		return nil, err
+	}
+	createTime := r.CreateTime
+	receiver.Name = r.Name
+	receiver.Type = r.Type
+	receiver.Creator = r.Creator
+	receiver.CreateTime = &createTime
+	return receiver, nil
+}
+
+func validateNotificationReceiver(receiver *NotificationReceiver) error {
+	if receiver == nil {
+		return fmt.Errorf("notification receiver should not be nil")
+	}
+	if receiver.Name == "" {
+		return fmt.Errorf("name of notification receiver should not be empty")
+	}
+
+	switch receiver.Type {
+	case NotificationReceiverTypeWebhook:
+		if receiver.Webhook == nil {
+			return fmt.Errorf("webhook should not be nil")
+		}
+		if err := validateNotificationUrl(receiver.Webhook.Url); err != nil {
+			return err
+		}
+	case NotificationReceiverTypeEmail:
+		if receiver.Email == nil || len(receiver.Email.To) == 0 {
+			return fmt.Errorf("email.to should not be empty")
+		}
+	case NotificationReceiverTypeSlack:
+		if receiver.Slack == nil {
+			return fmt.Errorf("slack should not be nil")
+		}
+		if err := validateNotificationUrl(receiver.Slack.Url); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported notification receiver type [%s]", receiver.Type)
+	}
+
+	for _, event := range receiver.Events {
+		if !validNotificationEvent(event) {
+			return fmt.Errorf("invalid event [%s], expected <kind>.<type>, <kind>.* or *", event)
+		}
+	}
+	return nil
+}
+
+func validateNotificationUrl(rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url [%s]", rawUrl)
+	}
+	return nil
+}
+
+func validNotificationEvent(event string) bool {
+	if event == "*" {
+		return true
+	}
+	parts := strings.Split(event, ".")
+	if len(parts) != 2 {
+		return false
+	}
+	kind, eventType := parts[0], parts[1]
+	switch kind {
+	case PipelineRunEventKindRun, PipelineRunEventKindStage, PipelineRunEventKindStep:
+	default:
+		return false
+	}
+	switch eventType {
+	case "*", PipelineRunEventQueued, PipelineRunEventStarted, PipelineRunEventPaused,
+		PipelineRunEventSucceeded, PipelineRunEventFailed, PipelineRunEventAborted:
+		return true
+	}
+	return false
+}
+
+// accepts returns whether the event should be sent to the receiver
+func (r *NotificationReceiver) accepts(event *PipelineRunEvent) bool {
+	if len(r.Pipelines) > 0 && !sliceutil.HasString(r.Pipelines, event.Pipeline) {
+		return false
+	}
+
+	events := r.Events
+	if len(events) == 0 {
+		events = DefaultNotificationEvents
+	}
+	for _, e := range events {
+		if e == "*" || e == event.Kind+".*" || e == event.Kind+"."+event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *NotificationReceiver) notifier() notification.Notifier {
+	switch r.Type {
+	case NotificationReceiverTypeWebhook:
+		return notification.NewWebhook(r.Webhook.Url)
+	case NotificationReceiverTypeEmail:
+		return notification.NewEmail(r.Email.To)
+	case NotificationReceiverTypeSlack:
+		return notification.NewSlack(r.Slack.Url, r.Slack.Channel)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package devops
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/db"
+	"kubesphere.io/kubesphere/pkg/gojenkins"
+	"kubesphere.io/kubesphere/pkg/simple/client/admin_jenkins"
+	"kubesphere.io/kubesphere/pkg/simple/client/devops_mysql"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+)
+
+// ProjectOrphans are the resources of devops projects which are left only in Jenkins or only in the database,
+// e.g. by a crash in the middle of creating or deleting a project
+type ProjectOrphans struct {
+	Folders   []string  `json:"folders" description:"folders of devops projects in jenkins without active projects in the database"`
+	Roles     []string  `json:"roles" description:"project and pipeline roles of devops projects in jenkins without active projects in the database"`
+	Projects  []string  `json:"projects" description:"active projects in the database without folders in jenkins"`
+	CheckTime time.Time `json:"check_time" description:"time of the last check"`
+}
+
+func (o *ProjectOrphans) empty() bool {
+	return len(o.Folders) == 0 && len(o.Roles) == 0 && len(o.Projects) == 0
+}
+
+var (
+	orphansLock sync.Mutex
+	// orphans found by the last check, some of them may be projects being created or deleted
+	foundOrphans *ProjectOrphans
+	// orphans found by the last two checks
+	confirmedOrphans *ProjectOrphans
+)
+
+// StartProjectOrphanReconciler checks orphans of devops projects periodically until stopCh is closed,
+// it's disabled if interval is 0
+func StartProjectOrphanReconciler(interval time.Duration, stopCh <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+
+	go wait.Until(func() {
+		if _, err := CheckProjectOrphans(); err != nil {
+			glog.Errorf("check devops project orphans: %+v", err)
+		}
+	}, interval, stopCh)
+}
+
+// CheckProjectOrphans finds orphans of devops projects. Only the orphans found by the previous check as well are
+// reported, the folder of a project being created exists without its row for a moment.
+func CheckProjectOrphans() (*ProjectOrphans, error) {
+	jenkins := admin_jenkins.Client()
+	if jenkins == nil {
+		return nil, fmt.Errorf("could not connect to jenkins")
+	}
+
+	found, err := findProjectOrphans(jenkins, devops_mysql.OpenDatabase())
+	if err != nil {
+		return nil, err
+	}
+
+	orphansLock.Lock()
+	defer orphansLock.Unlock()
+
+	confirmed := intersectProjectOrphans(foundOrphans, found)
+	foundOrphans = found
+	confirmedOrphans = confirmed
+
+	if !confirmed.empty() {
+		glog.Warningf("devops project orphans found, folders: %v, roles: %v, projects: %v",
+			confirmed.Folders, confirmed.Roles, confirmed.Projects)
+	}
+
+	return confirmed, nil
+}
+
+// GetProjectOrphans returns the orphans reported by the last check
+func GetProjectOrphans() (*ProjectOrphans, error) {
+	orphansLock.Lock()
+	orphans := confirmedOrphans
+	orphansLock.Unlock()
+
+	if orphans == nil {
+		return CheckProjectOrphans()
+	}
+	return orphans, nil
+}
+
+// RepairProjectOrphans deletes orphan folders and roles in Jenkins and creates the folders and roles of active
+// projects again. Only orphans reported by the last check which are still orphans are repaired.
+func RepairProjectOrphans() (*ProjectOrphans, error) {
+	jenkins := admin_jenkins.Client()
+	if jenkins == nil {
+		return nil, fmt.Errorf("could not connect to jenkins")
+	}
+	dbconn := devops_mysql.OpenDatabase()
+
+	orphansLock.Lock()
+	defer orphansLock.Unlock()
+
+	found, err := findProjectOrphans(jenkins, dbconn)
+	if err != nil {
+		return nil, err
+	}
+	orphans := intersectProjectOrphans(confirmedOrphans, found)
+
+	for _, folder := range orphans.Folders {
+		if err := deleteProjectFolder(jenkins, folder); err != nil {
+			return nil, err
+		}
+	}
+	if len(orphans.Roles) > 0 {
+		if err := jenkins.DeleteProjectRoles(orphans.Roles...); err != nil {
+			return nil, err
+		}
+	}
+	for _, projectId := range orphans.Projects {
+		if err := restoreProject(jenkins, dbconn, projectId); err != nil {
+			return nil, err
+		}
+	}
+
+	foundOrphans = nil
+	confirmedOrphans = nil
+
+	return orphans, nil
+}
+
+// restoreProject creates the folder and roles of the active project and grants the roles to its members
+func restoreProject(jenkins *gojenkins.Jenkins, dbconn *db.Database, projectId string) error {
+	project := &DevOpsProject{}
+	err := dbconn.Select(DevOpsProjectColumns...).
+		From(DevOpsProjectTableName).
+		Where(db.Eq(DevOpsProjectIdColumn, projectId)).
+		LoadOne(project)
+	if err != nil {
+		return err
+	}
+	memberships := make([]*DevOpsProjectMembership, 0)
+	_, err = dbconn.Select(DevOpsProjectMembershipColumns...).
+		From(DevOpsProjectMembershipTableName).
+		Where(db.Eq(DevOpsProjectMembershipProjectIdColumn, projectId)).
+		Load(&memberships)
+	if err != nil {
+		return err
+	}
+
+	if _, err := createProjectFolder(jenkins, projectId, project.Description); err != nil {
+		return err
+	}
+	if err := createProjectRoles(jenkins, projectId); err != nil {
+		return err
+	}
+	for _, membership := range memberships {
+		if err := assignProjectRoles(jenkins, projectId, membership.Username, membership.Role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findProjectOrphans compares the folders and roles of projects in Jenkins with the active projects in the database,
+// folders without the prefix of project ids and projects of DevOpsProject resources are ignored
+func findProjectOrphans(jenkins *gojenkins.Jenkins, dbconn *db.Database) (*ProjectOrphans, error) {
+	projects := make([]*DevOpsProject, 0)
+	_, err := dbconn.Select(DevOpsProjectColumns...).
+		From(DevOpsProjectTableName).
+		Where(db.Eq(DevOpsProjectTableName+"."+StatusColumn, StatusActive)).
+		Load(&projects)
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[string]bool, len(projects))
+	for _, project := range projects {
+		active[project.ProjectId] = true
+	}
+
+	managed, err := listResourceProjectIds()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := jenkins.GetAllJobNames()
+	if err != nil {
+		return nil, err
+	}
+	roles, err := jenkins.GetAllProjectRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	jobNames := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		jobNames = append(jobNames, job.Name)
+	}
+	roleNames := make([]string, 0, len(roles))
+	for roleName := range roles {
+		roleNames = append(roleNames, roleName)
+	}
+
+	return compareProjects(active, managed, jobNames, roleNames, time.Now()), nil
+}
+
+// compareProjects returns the folders and roles in Jenkins without active projects and the active projects without
+// folders, projects managed by DevOpsProject resources are left to the devops controller
+func compareProjects(active, managed map[string]bool, jobNames, roleNames []string, checkTime time.Time) *ProjectOrphans {
+	orphans := &ProjectOrphans{Folders: []string{}, Roles: []string{}, Projects: []string{}, CheckTime: checkTime}
+
+	folders := make(map[string]bool, len(jobNames))
+	for _, name := range jobNames {
+		if !strings.HasPrefix(name, DevOpsProjectPrefix) {
+			continue
+		}
+		folders[name] = true
+		if !active[name] && !managed[name] {
+			orphans.Folders = append(orphans.Folders, name)
+		}
+	}
+	for _, roleName := range roleNames {
+		if projectId := projectIdOfRole(roleName); projectId != "" && !active[projectId] && !managed[projectId] {
+			orphans.Roles = append(orphans.Roles, roleName)
+		}
+	}
+	for projectId := range active {
+		if !folders[projectId] && !managed[projectId] {
+			orphans.Projects = append(orphans.Projects, projectId)
+		}
+	}
+
+	sort.Strings(orphans.Folders)
+	sort.Strings(orphans.Roles)
+	sort.Strings(orphans.Projects)
+
+	return orphans
+}
+
+// listResourceProjectIds returns the ids of the projects applied by DevOpsProject resources and the ids they are
+// going to apply, e.g. the ids of projects migrated from the database
+func listResourceProjectIds() (map[string]bool, error) {
+	list, err := k8s.DynamicClient().Resource(DevOpsProjectGroupVersionResource).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(list.Items))
+	for _, item := range list.Items {
+		project := &devopsv1alpha1.DevOpsProject{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, project); err != nil {
+			glog.Errorf("invalid devops project %s: %+v", item.GetName(), err)
+			continue
+		}
+		for _, id := range []string{project.Status.ProjectId, ResourceProjectId(project)} {
+			if id != "" {
+				ids[id] = true
+			}
+		}
+	}
+	return ids, nil
+}
+
+// ResourceProjectId returns the name of the folder of the DevOpsProject in Jenkins, the name of the resource by default
+func ResourceProjectId(project *devopsv1alpha1.DevOpsProject) string {
+	if project.Spec.ProjectId != "" {
+		return project.Spec.ProjectId
+	}
+	return project.Name
+}
+
+// DeactivateProject marks the project deleted in the database and deletes its memberships, it's invoked when
+// the DevOpsProject applying a project migrated from the database is deleted so that the project isn't restored
+func DeactivateProject(dbconn *db.Database, projectId string) error {
+	return setProjectStatus(dbconn, projectId, StatusDeleted)
+}
+
+// projectIdOfRole returns the id of the project of a project or pipeline role, or empty if it's not a role of a project
+func projectIdOfRole(roleName string) string {
+	if !strings.HasPrefix(roleName, DevOpsProjectPrefix) {
+		return ""
+	}
+	for _, role := range AllRoleSlice {
+		for _, suffix := range []string{GetProjectRoleName("", role), GetPipelineRoleName("", role)} {
+			if strings.HasSuffix(roleName, suffix) {
+				return strings.TrimSuffix(roleName, suffix)
+			}
+		}
+	}
+	return ""
+}
+
+// intersectProjectOrphans returns the orphans found by both checks, nothing if there's no previous check
+func intersectProjectOrphans(previous, current *ProjectOrphans) *ProjectOrphans {
+	orphans := &ProjectOrphans{Folders: []string{}, Roles: []string{}, Projects: []string{}, CheckTime: current.CheckTime}
+	if previous == nil {
+		return orphans
+	}
+
+	intersect := func(a, b []string) []string {
+		set := make(map[string]bool, len(a))
+		for _, item := range a {
+			set[item] = true
+		}
+		result := []string{}
+		for _, item := range b {
+			if set[item] {
+				result = append(result, item)
+			}
+		}
+		return result
+	}
+
+	orphans.Folders = intersect(previous.Folders, current.Folders)
+	orphans.Roles = intersect(previous.Roles, current.Roles)
+	orphans.Projects = intersect(previous.Projects, current.Projects)
+	return orphans
+}
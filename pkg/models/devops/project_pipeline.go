@@ -70,16 +70,19 @@ type NoScmPipeline struct {
 }
 
 type MultiBranchPipeline struct {
-	Name            string             `json:"name" description:"name of pipeline"`
-	Description     string             `json:"descriptio,omitempty" description:"description of pipeline"`
-	Discarder       *DiscarderProperty `json:"discarder,omitempty" description:"Discarder of pipeline, managing when to drop a pipeline"`
-	TimerTrigger    *TimerTrigger      `json:"timer_trigger,omitempty" mapstructure:"timer_trigger" description:"Timer to trigger pipeline run"`
-	SourceType      string             `json:"source_type" description:"type of scm, such as github/git/svn"`
-	GitSource       *GitSource         `json:"git_source,omitempty" description:"git scm define"`
-	GitHubSource    *GithubSource      `json:"github_source,omitempty" description:"github scm define"`
-	SvnSource       *SvnSource         `json:"svn_source,omitempty" description:"multi branch svn scm define"`
-	SingleSvnSource *SingleSvnSource   `json:"single_svn_source,omitempty" description:"single branch svn scm define"`
-	ScriptPath      string             `json:"script_path" mapstructure:"script_path" description:"script path in scm"`
+	Name                  string                 `json:"name" description:"name of pipeline"`
+	Description           string                 `json:"descriptio,omitempty" description:"description of pipeline"`
+	Discarder             *DiscarderProperty     `json:"discarder,omitempty" description:"Discarder of pipeline, managing when to drop a pipeline"`
+	TimerTrigger          *TimerTrigger          `json:"timer_trigger,omitempty" mapstructure:"timer_trigger" description:"Timer to trigger pipeline run"`
+	SourceType            string                 `json:"source_type" description:"type of scm, such as github/gitlab/bitbucket_server/gitea/git/svn"`
+	GitSource             *GitSource             `json:"git_source,omitempty" description:"git scm define"`
+	GitHubSource          *GithubSource          `json:"github_source,omitempty" description:"github scm define"`
+	GitlabSource          *GitlabSource          `json:"gitlab_source,omitempty" description:"gitlab scm define"`
+	BitbucketServerSource *BitbucketServerSource `json:"bitbucket_server_source,omitempty" description:"bitbucket server scm define"`
+	GiteaSource           *GiteaSource           `json:"gitea_source,omitempty" description:"gitea scm define"`
+	SvnSource             *SvnSource             `json:"svn_source,omitempty" description:"multi branch svn scm define"`
+	SingleSvnSource       *SingleSvnSource       `json:"single_svn_source,omitempty" description:"single branch svn scm define"`
+	ScriptPath            string                 `json:"script_path" mapstructure:"script_path" description:"script path in scm"`
 }
 
 type GitSource struct {
@@ -102,6 +105,42 @@ type GithubSource struct {
 	RegexFilter          string                     `json:"regex_filter,omitempty" mapstructure:"regex_filter" description:"Regex used to match the name of the branch that needs to be run"`
 }
 
+type GitlabSource struct {
+	ServerName           string                     `json:"server_name,omitempty" mapstructure:"server_name" description:"name of the gitlab server configured in jenkins"`
+	Owner                string                     `json:"owner,omitempty" mapstructure:"owner" description:"owner of gitlab project, a user or a group"`
+	Repo                 string                     `json:"repo,omitempty" mapstructure:"repo" description:"name of gitlab project"`
+	CredentialId         string                     `json:"credential_id,omitempty" mapstructure:"credential_id" description:"credential id to access gitlab source"`
+	DiscoverBranches     int                        `json:"discover_branches,omitempty" mapstructure:"discover_branches" description:"Discover branch configuration"`
+	DiscoverMRFromOrigin int                        `json:"discover_mr_from_origin,omitempty" mapstructure:"discover_mr_from_origin" description:"Discover origin MR configuration"`
+	DiscoverMRFromForks  *GithubDiscoverPRFromForks `json:"discover_mr_from_forks,omitempty" mapstructure:"discover_mr_from_forks" description:"Discover fork MR configuration, trust is one of 1 members, 2 everyone, 3 permission, 4 nobody"`
+	CloneOption          *GitCloneOption            `json:"git_clone_option,omitempty" mapstructure:"git_clone_option" description:"advavced git clone options"`
+	RegexFilter          string                     `json:"regex_filter,omitempty" mapstructure:"regex_filter" description:"Regex used to match the name of the branch that needs to be run"`
+}
+
+type BitbucketServerSource struct {
+	ApiUri               string                     `json:"api_uri,omitempty" mapstructure:"api_uri" description:"url of the bitbucket server"`
+	Owner                string                     `json:"owner,omitempty" mapstructure:"owner" description:"key of bitbucket project"`
+	Repo                 string                     `json:"repo,omitempty" mapstructure:"repo" description:"slug of bitbucket repo"`
+	CredentialId         string                     `json:"credential_id,omitempty" mapstructure:"credential_id" description:"credential id to access bitbucket source"`
+	DiscoverBranches     int                        `json:"discover_branches,omitempty" mapstructure:"discover_branches" description:"Discover branch configuration"`
+	DiscoverPRFromOrigin int                        `json:"discover_pr_from_origin,omitempty" mapstructure:"discover_pr_from_origin" description:"Discover origin PR configuration"`
+	DiscoverPRFromForks  *GithubDiscoverPRFromForks `json:"discover_pr_from_forks,omitempty" mapstructure:"discover_pr_from_forks" description:"Discover fork PR configuration, trust is one of 1 team forks, 2 everyone, 4 nobody"`
+	CloneOption          *GitCloneOption            `json:"git_clone_option,omitempty" mapstructure:"git_clone_option" description:"advavced git clone options"`
+	RegexFilter          string                     `json:"regex_filter,omitempty" mapstructure:"regex_filter" description:"Regex used to match the name of the branch that needs to be run"`
+}
+
+type GiteaSource struct {
+	ApiUri               string                     `json:"api_uri,omitempty" mapstructure:"api_uri" description:"url of the gitea server"`
+	Owner                string                     `json:"owner,omitempty" mapstructure:"owner" description:"owner of gitea repo"`
+	Repo                 string                     `json:"repo,omitempty" mapstructure:"repo" description:"repo name of gitea repo"`
+	CredentialId         string                     `json:"credential_id,omitempty" mapstructure:"credential_id" description:"credential id to access gitea source"`
+	DiscoverBranches     int                        `json:"discover_branches,omitempty" mapstructure:"discover_branches" description:"Discover branch configuration"`
+	DiscoverPRFromOrigin int                        `json:"discover_pr_from_origin,omitempty" mapstructure:"discover_pr_from_origin" description:"Discover origin PR configuration"`
+	DiscoverPRFromForks  *GithubDiscoverPRFromForks `json:"discover_pr_from_forks,omitempty" mapstructure:"discover_pr_from_forks" description:"Discover fork PR configuration, trust is one of 1 contributors, 2 everyone, 4 nobody"`
+	CloneOption          *GitCloneOption            `json:"git_clone_option,omitempty" mapstructure:"git_clone_option" description:"advavced git clone options"`
+	RegexFilter          string                     `json:"regex_filter,omitempty" mapstructure:"regex_filter" description:"Regex used to match the name of the branch that needs to be run"`
+}
+
 type GitCloneOption struct {
 	Shallow bool `json:"shallow,omitempty" mapstructure:"shallow" description:"Whether to use git shallow clone"`
 	Timeout int  `json:"timeout,omitempty" mapstructure:"timeout" description:"git clone timeout mins"`
@@ -533,6 +572,24 @@ func createMultiBranchPipelineConfigXml(projectName string, pipeline *MultiBranc
 			regexTraits.CreateElement("regex").SetText(githubDefine.RegexFilter)
 		}
 
+	case "gitlab":
+		err := createGitlabSource(branchSource, projectName+pipeline.Name, pipeline.GitlabSource)
+		if err != nil {
+			return "", err
+		}
+
+	case "bitbucket_server":
+		err := createBitbucketServerSource(branchSource, projectName+pipeline.Name, pipeline.BitbucketServerSource)
+		if err != nil {
+			return "", err
+		}
+
+	case "gitea":
+		err := createGiteaSource(branchSource, projectName+pipeline.Name, pipeline.GiteaSource)
+		if err != nil {
+			return "", err
+		}
+
 	case "svn":
 		svnDefine := pipeline.SvnSource
 		svnSource := branchSource.CreateElement("source")
@@ -767,6 +824,27 @@ func parseMultiBranchPipelineConfigXml(config string) (*MultiBranchPipeline, err
 
 					pipeline.SourceType = "git"
 					pipeline.GitSource = gitSource
+				case gitlabSCMSourceClass:
+					gitlabSource, err := parseGitlabSource(source)
+					if err != nil {
+						return nil, err
+					}
+					pipeline.SourceType = "gitlab"
+					pipeline.GitlabSource = gitlabSource
+				case bitbucketSCMSourceClass:
+					bitbucketServerSource, err := parseBitbucketServerSource(source)
+					if err != nil {
+						return nil, err
+					}
+					pipeline.SourceType = "bitbucket_server"
+					pipeline.BitbucketServerSource = bitbucketServerSource
+				case giteaSCMSourceClass:
+					giteaSource, err := parseGiteaSource(source)
+					if err != nil {
+						return nil, err
+					}
+					pipeline.SourceType = "gitea"
+					pipeline.GiteaSource = giteaSource
 				case "jenkins.scm.impl.SingleSCMSource":
 					singleSvnSource := &SingleSvnSource{}
 
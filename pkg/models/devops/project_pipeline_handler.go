@@ -104,6 +104,10 @@ func DeleteProjectPipeline(projectId string, pipelineId string) (string, error)
 		glog.Errorf("%+v", err)
 		return "", restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
 	}
+	if err := deleteTemplatePipeline(projectId, pipelineId); err != nil {
+		glog.Errorf("%+v", err)
+		return "", restful.NewError(http.StatusInternalServerError, err.Error())
+	}
 	return pipelineId, nil
 }
 
@@ -0,0 +1,233 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"fmt"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+)
+
+// PipelineConfigXml returns the config of the job of the pipeline in the project
+func PipelineConfigXml(projectId string, pipeline *ProjectPipeline) (string, error) {
+	switch pipeline.Type {
+	case NoScmPipelineType:
+		if pipeline.Pipeline == nil {
+			return "", fmt.Errorf("pipeline should not be nil")
+		}
+		return createPipelineConfigXml(pipeline.Pipeline)
+	case MultiBranchPipelineType:
+		if pipeline.MultiBranchPipeline == nil {
+			return "", fmt.Errorf("multi_branch_pipeline should not be nil")
+		}
+		return createMultiBranchPipelineConfigXml(projectId, pipeline.MultiBranchPipeline)
+	default:
+		return "", fmt.Errorf("error unsupport job type")
+	}
+}
+
+// ProjectPipelineFromSpec converts the spec of the Pipeline resource into the pipeline named name
+func ProjectPipelineFromSpec(name string, spec *devopsv1alpha1.PipelineSpec) *ProjectPipeline {
+	pipeline := &ProjectPipeline{Type: spec.Type}
+
+	if p := spec.Pipeline; p != nil {
+		pipeline.Pipeline = &NoScmPipeline{
+			Name:              name,
+			Description:       spec.Description,
+			Discarder:         (*DiscarderProperty)(p.Discarder),
+			DisableConcurrent: p.DisableConcurrent,
+			TimerTrigger:      (*TimerTrigger)(p.TimerTrigger),
+			RemoteTrigger:     (*RemoteTrigger)(p.RemoteTrigger),
+			Jenkinsfile:       p.Jenkinsfile,
+		}
+		for i := range p.Parameters {
+			parameter := Parameter(p.Parameters[i])
+			pipeline.Pipeline.Parameters = append(pipeline.Pipeline.Parameters, &parameter)
+		}
+	}
+
+	if p := spec.MultiBranchPipeline; p != nil {
+		pipeline.MultiBranchPipeline = &MultiBranchPipeline{
+			Name:            name,
+			Description:     spec.Description,
+			Discarder:       (*DiscarderProperty)(p.Discarder),
+			TimerTrigger:    (*TimerTrigger)(p.TimerTrigger),
+			SourceType:      p.SourceType,
+			SvnSource:       (*SvnSource)(p.SvnSource),
+			SingleSvnSource: (*SingleSvnSource)(p.SingleSvnSource),
+			ScriptPath:      p.ScriptPath,
+		}
+		if s := p.GitSource; s != nil {
+			pipeline.MultiBranchPipeline.GitSource = &GitSource{
+				Url:              s.Url,
+				CredentialId:     s.CredentialId,
+				DiscoverBranches: s.DiscoverBranches,
+				CloneOption:      (*GitCloneOption)(s.CloneOption),
+				RegexFilter:      s.RegexFilter,
+			}
+		}
+		if s := p.GitHubSource; s != nil {
+			pipeline.MultiBranchPipeline.GitHubSource = &GithubSource{
+				Owner:                s.Owner,
+				Repo:                 s.Repo,
+				CredentialId:         s.CredentialId,
+				ApiUri:               s.ApiUri,
+				DiscoverBranches:     s.DiscoverBranches,
+				DiscoverPRFromOrigin: s.DiscoverPRFromOrigin,
+				DiscoverPRFromForks:  (*GithubDiscoverPRFromForks)(s.DiscoverPRFromForks),
+				CloneOption:          (*GitCloneOption)(s.CloneOption),
+				RegexFilter:          s.RegexFilter,
+			}
+		}
+		if s := p.GitlabSource; s != nil {
+			pipeline.MultiBranchPipeline.GitlabSource = &GitlabSource{
+				ServerName:           s.ServerName,
+				Owner:                s.Owner,
+				Repo:                 s.Repo,
+				CredentialId:         s.CredentialId,
+				DiscoverBranches:     s.DiscoverBranches,
+				DiscoverMRFromOrigin: s.DiscoverMRFromOrigin,
+				DiscoverMRFromForks:  (*GithubDiscoverPRFromForks)(s.DiscoverMRFromForks),
+				CloneOption:          (*GitCloneOption)(s.CloneOption),
+				RegexFilter:          s.RegexFilter,
+			}
+		}
+		if s := p.BitbucketServerSource; s != nil {
+			pipeline.MultiBranchPipeline.BitbucketServerSource = &BitbucketServerSource{
+				ApiUri:               s.ApiUri,
+				Owner:                s.Owner,
+				Repo:                 s.Repo,
+				CredentialId:         s.CredentialId,
+				DiscoverBranches:     s.DiscoverBranches,
+				DiscoverPRFromOrigin: s.DiscoverPRFromOrigin,
+				DiscoverPRFromForks:  (*GithubDiscoverPRFromForks)(s.DiscoverPRFromForks),
+				CloneOption:          (*GitCloneOption)(s.CloneOption),
+				RegexFilter:          s.RegexFilter,
+			}
+		}
+		if s := p.GiteaSource; s != nil {
+			pipeline.MultiBranchPipeline.GiteaSource = &GiteaSource{
+				ApiUri:               s.ApiUri,
+				Owner:                s.Owner,
+				Repo:                 s.Repo,
+				CredentialId:         s.CredentialId,
+				DiscoverBranches:     s.DiscoverBranches,
+				DiscoverPRFromOrigin: s.DiscoverPRFromOrigin,
+				DiscoverPRFromForks:  (*GithubDiscoverPRFromForks)(s.DiscoverPRFromForks),
+				CloneOption:          (*GitCloneOption)(s.CloneOption),
+				RegexFilter:          s.RegexFilter,
+			}
+		}
+	}
+
+	return pipeline
+}
+
+// PipelineSpecFromProjectPipeline converts the pipeline of the project into the spec of a Pipeline resource
+func PipelineSpecFromProjectPipeline(project string, pipeline *ProjectPipeline) devopsv1alpha1.PipelineSpec {
+	spec := devopsv1alpha1.PipelineSpec{Project: project, Type: pipeline.Type}
+
+	if p := pipeline.Pipeline; p != nil {
+		spec.Name = p.Name
+		spec.Description = p.Description
+		spec.Pipeline = &devopsv1alpha1.NoScmPipeline{
+			Discarder:         (*devopsv1alpha1.DiscarderProperty)(p.Discarder),
+			DisableConcurrent: p.DisableConcurrent,
+			TimerTrigger:      (*devopsv1alpha1.TimerTrigger)(p.TimerTrigger),
+			RemoteTrigger:     (*devopsv1alpha1.RemoteTrigger)(p.RemoteTrigger),
+			Jenkinsfile:       p.Jenkinsfile,
+		}
+		for _, parameter := range p.Parameters {
+			if parameter != nil {
+				spec.Pipeline.Parameters = append(spec.Pipeline.Parameters, devopsv1alpha1.Parameter(*parameter))
+			}
+		}
+	}
+
+	if p := pipeline.MultiBranchPipeline; p != nil {
+		spec.Name = p.Name
+		spec.Description = p.Description
+		spec.MultiBranchPipeline = &devopsv1alpha1.MultiBranchPipeline{
+			Discarder:       (*devopsv1alpha1.DiscarderProperty)(p.Discarder),
+			TimerTrigger:    (*devopsv1alpha1.TimerTrigger)(p.TimerTrigger),
+			SourceType:      p.SourceType,
+			SvnSource:       (*devopsv1alpha1.SvnSource)(p.SvnSource),
+			SingleSvnSource: (*devopsv1alpha1.SingleSvnSource)(p.SingleSvnSource),
+			ScriptPath:      p.ScriptPath,
+		}
+		if s := p.GitSource; s != nil {
+			spec.MultiBranchPipeline.GitSource = &devopsv1alpha1.GitSource{
+				Url:              s.Url,
+				CredentialId:     s.CredentialId,
+				DiscoverBranches: s.DiscoverBranches,
+				CloneOption:      (*devopsv1alpha1.GitCloneOption)(s.CloneOption),
+				RegexFilter:      s.RegexFilter,
+			}
+		}
+		if s := p.GitHubSource; s != nil {
+			spec.MultiBranchPipeline.GitHubSource = &devopsv1alpha1.GithubSource{
+				Owner:                s.Owner,
+				Repo:                 s.Repo,
+				CredentialId:         s.CredentialId,
+				ApiUri:               s.ApiUri,
+				DiscoverBranches:     s.DiscoverBranches,
+				DiscoverPRFromOrigin: s.DiscoverPRFromOrigin,
+				DiscoverPRFromForks:  (*devopsv1alpha1.GithubDiscoverPRFromForks)(s.DiscoverPRFromForks),
+				CloneOption:          (*devopsv1alpha1.GitCloneOption)(s.CloneOption),
+				RegexFilter:          s.RegexFilter,
+			}
+		}
+		if s := p.GitlabSource; s != nil {
+			spec.MultiBranchPipeline.GitlabSource = &devopsv1alpha1.GitlabSource{
+				ServerName:           s.ServerName,
+				Owner:                s.Owner,
+				Repo:                 s.Repo,
+				CredentialId:         s.CredentialId,
+				DiscoverBranches:     s.DiscoverBranches,
+				DiscoverMRFromOrigin: s.DiscoverMRFromOrigin,
+				DiscoverMRFromForks:  (*devopsv1alpha1.GithubDiscoverPRFromForks)(s.DiscoverMRFromForks),
+				CloneOption:          (*devopsv1alpha1.GitCloneOption)(s.CloneOption),
+				RegexFilter:          s.RegexFilter,
+			}
+		}
+		if s := p.BitbucketServerSource; s != nil {
+			spec.MultiBranchPipeline.BitbucketServerSource = &devopsv1alpha1.BitbucketServerSource{
+				ApiUri:               s.ApiUri,
+				Owner:                s.Owner,
+				Repo:                 s.Repo,
+				CredentialId:         s.CredentialId,
+				DiscoverBranches:     s.DiscoverBranches,
+				DiscoverPRFromOrigin: s.DiscoverPRFromOrigin,
+				DiscoverPRFromForks:  (*devopsv1alpha1.GithubDiscoverPRFromForks)(s.DiscoverPRFromForks),
+				CloneOption:          (*devopsv1alpha1.GitCloneOption)(s.CloneOption),
+				RegexFilter:          s.RegexFilter,
+			}
+		}
+		if s := p.GiteaSource; s != nil {
+			spec.MultiBranchPipeline.GiteaSource = &devopsv1alpha1.GiteaSource{
+				ApiUri:               s.ApiUri,
+				Owner:                s.Owner,
+				Repo:                 s.Repo,
+				CredentialId:         s.CredentialId,
+				DiscoverBranches:     s.DiscoverBranches,
+				DiscoverPRFromOrigin: s.DiscoverPRFromOrigin,
+				DiscoverPRFromForks:  (*devopsv1alpha1.GithubDiscoverPRFromForks)(s.DiscoverPRFromForks),
+				CloneOption:          (*devopsv1alpha1.GitCloneOption)(s.CloneOption),
+				RegexFilter:          s.RegexFilter,
+			}
+		}
+	}
+
+	return spec
+}
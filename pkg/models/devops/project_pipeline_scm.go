@@ -0,0 +1,326 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"fmt"
+	"github.com/beevik/etree"
+	"strconv"
+	"strings"
+)
+
+const (
+	gitlabSCMSourceClass    = "io.jenkins.plugins.gitlabbranchsource.GitLabSCMSource"
+	bitbucketSCMSourceClass = "com.cloudbees.jenkins.plugins.bitbucket.BitbucketSCMSource"
+	giteaSCMSourceClass     = "org.jenkinsci.plugin.gitea.GiteaSCMSource"
+
+	gitlabTraitPackage    = "io.jenkins.plugins.gitlabbranchsource."
+	bitbucketTraitPackage = "com.cloudbees.jenkins.plugins.bitbucket."
+	giteaTraitPackage     = "org.jenkinsci.plugin.gitea."
+)
+
+// trust choices of fork pull requests of the scm plugins, the choices are numbered the same as github,
+// choices without a counterpart in the plugin are missing
+var (
+	gitlabForkTrusts = map[int]string{
+		1: "TrustMembers",
+		2: "TrustEveryone",
+		3: "TrustPermission",
+		4: "TrustNobody",
+	}
+	bitbucketForkTrusts = map[int]string{
+		1: "TrustTeamForks",
+		2: "TrustEveryone",
+		4: "TrustNobody",
+	}
+	giteaForkTrusts = map[int]string{
+		1: "TrustContributors",
+		2: "TrustEveryone",
+		4: "TrustNobody",
+	}
+)
+
+// scmDiscoveryTraits are the discovery traits shared by the branch source plugins of github, gitlab, bitbucket
+// and gitea, gitlab calls pull requests merge requests.
+type scmDiscoveryTraits struct {
+	// package of the trait classes, e.g. org.jenkinsci.plugin.gitea.
+	pkg string
+	// simple name of pull requests in the trait classes, PullRequest or MergeRequest
+	request string
+	trusts  map[int]string
+
+	branches int
+	origin   int
+	forks    *GithubDiscoverPRFromForks
+	clone    *GitCloneOption
+	regex    string
+}
+
+func createGitlabSource(branchSource *etree.Element, id string, define *GitlabSource) error {
+	if define == nil {
+		return fmt.Errorf("gitlab source should not be nil")
+	}
+	source := branchSource.CreateElement("source")
+	source.CreateAttr("class", gitlabSCMSourceClass)
+	source.CreateAttr("plugin", "gitlab-branch-source")
+	source.CreateElement("id").SetText(id)
+	source.CreateElement("serverName").SetText(define.ServerName)
+	source.CreateElement("projectOwner").SetText(define.Owner)
+	source.CreateElement("projectPath").SetText(define.Owner + "/" + define.Repo)
+	source.CreateElement("credentialsId").SetText(define.CredentialId)
+
+	return createSCMTraits(source.CreateElement("traits"), &scmDiscoveryTraits{
+		pkg:      gitlabTraitPackage,
+		request:  "MergeRequest",
+		trusts:   gitlabForkTrusts,
+		branches: define.DiscoverBranches,
+		origin:   define.DiscoverMRFromOrigin,
+		forks:    define.DiscoverMRFromForks,
+		clone:    define.CloneOption,
+		regex:    define.RegexFilter,
+	})
+}
+
+func parseGitlabSource(source *etree.Element) (*GitlabSource, error) {
+	define := &GitlabSource{}
+	if serverName := source.SelectElement("serverName"); serverName != nil {
+		define.ServerName = serverName.Text()
+	}
+	if projectOwner := source.SelectElement("projectOwner"); projectOwner != nil {
+		define.Owner = projectOwner.Text()
+	}
+	if projectPath := source.SelectElement("projectPath"); projectPath != nil {
+		// projects may be in subgroups of the owner
+		define.Repo = strings.TrimPrefix(projectPath.Text(), define.Owner+"/")
+	}
+	if credential := source.SelectElement("credentialsId"); credential != nil {
+		define.CredentialId = credential.Text()
+	}
+
+	traits := &scmDiscoveryTraits{pkg: gitlabTraitPackage, request: "MergeRequest", trusts: gitlabForkTrusts}
+	if err := parseSCMTraits(source.SelectElement("traits"), traits); err != nil {
+		return nil, err
+	}
+	define.DiscoverBranches = traits.branches
+	define.DiscoverMRFromOrigin = traits.origin
+	define.DiscoverMRFromForks = traits.forks
+	define.CloneOption = traits.clone
+	define.RegexFilter = traits.regex
+	return define, nil
+}
+
+func createBitbucketServerSource(branchSource *etree.Element, id string, define *BitbucketServerSource) error {
+	if define == nil {
+		return fmt.Errorf("bitbucket server source should not be nil")
+	}
+	source := branchSource.CreateElement("source")
+	source.CreateAttr("class", bitbucketSCMSourceClass)
+	source.CreateAttr("plugin", "cloudbees-bitbucket-branch-source")
+	source.CreateElement("id").SetText(id)
+	source.CreateElement("serverUrl").SetText(define.ApiUri)
+	source.CreateElement("credentialsId").SetText(define.CredentialId)
+	source.CreateElement("repoOwner").SetText(define.Owner)
+	source.CreateElement("repository").SetText(define.Repo)
+
+	return createSCMTraits(source.CreateElement("traits"), &scmDiscoveryTraits{
+		pkg:      bitbucketTraitPackage,
+		request:  "PullRequest",
+		trusts:   bitbucketForkTrusts,
+		branches: define.DiscoverBranches,
+		origin:   define.DiscoverPRFromOrigin,
+		forks:    define.DiscoverPRFromForks,
+		clone:    define.CloneOption,
+		regex:    define.RegexFilter,
+	})
+}
+
+func parseBitbucketServerSource(source *etree.Element) (*BitbucketServerSource, error) {
+	define := &BitbucketServerSource{}
+	if serverUrl := source.SelectElement("serverUrl"); serverUrl != nil {
+		define.ApiUri = serverUrl.Text()
+	}
+	if repoOwner := source.SelectElement("repoOwner"); repoOwner != nil {
+		define.Owner = repoOwner.Text()
+	}
+	if repository := source.SelectElement("repository"); repository != nil {
+		define.Repo = repository.Text()
+	}
+	if credential := source.SelectElement("credentialsId"); credential != nil {
+		define.CredentialId = credential.Text()
+	}
+
+	traits := &scmDiscoveryTraits{pkg: bitbucketTraitPackage, request: "PullRequest", trusts: bitbucketForkTrusts}
+	if err := parseSCMTraits(source.SelectElement("traits"), traits); err != nil {
+		return nil, err
+	}
+	define.DiscoverBranches = traits.branches
+	define.DiscoverPRFromOrigin = traits.origin
+	define.DiscoverPRFromForks = traits.forks
+	define.CloneOption = traits.clone
+	define.RegexFilter = traits.regex
+	return define, nil
+}
+
+func createGiteaSource(branchSource *etree.Element, id string, define *GiteaSource) error {
+	if define == nil {
+		return fmt.Errorf("gitea source should not be nil")
+	}
+	source := branchSource.CreateElement("source")
+	source.CreateAttr("class", giteaSCMSourceClass)
+	source.CreateAttr("plugin", "gitea")
+	source.CreateElement("id").SetText(id)
+	source.CreateElement("serverUrl").SetText(define.ApiUri)
+	source.CreateElement("repoOwner").SetText(define.Owner)
+	source.CreateElement("repository").SetText(define.Repo)
+	source.CreateElement("credentialsId").SetText(define.CredentialId)
+
+	return createSCMTraits(source.CreateElement("traits"), &scmDiscoveryTraits{
+		pkg:      giteaTraitPackage,
+		request:  "PullRequest",
+		trusts:   giteaForkTrusts,
+		branches: define.DiscoverBranches,
+		origin:   define.DiscoverPRFromOrigin,
+		forks:    define.DiscoverPRFromForks,
+		clone:    define.CloneOption,
+		regex:    define.RegexFilter,
+	})
+}
+
+func parseGiteaSource(source *etree.Element) (*GiteaSource, error) {
+	define := &GiteaSource{}
+	if serverUrl := source.SelectElement("serverUrl"); serverUrl != nil {
+		define.ApiUri = serverUrl.Text()
+	}
+	if repoOwner := source.SelectElement("repoOwner"); repoOwner != nil {
+		define.Owner = repoOwner.Text()
+	}
+	if repository := source.SelectElement("repository"); repository != nil {
+		define.Repo = repository.Text()
+	}
+	if credential := source.SelectElement("credentialsId"); credential != nil {
+		define.CredentialId = credential.Text()
+	}
+
+	traits := &scmDiscoveryTraits{pkg: giteaTraitPackage, request: "PullRequest", trusts: giteaForkTrusts}
+	if err := parseSCMTraits(source.SelectElement("traits"), traits); err != nil {
+		return nil, err
+	}
+	define.DiscoverBranches = traits.branches
+	define.DiscoverPRFromOrigin = traits.origin
+	define.DiscoverPRFromForks = traits.forks
+	define.CloneOption = traits.clone
+	define.RegexFilter = traits.regex
+	return define, nil
+}
+
+func createSCMTraits(traits *etree.Element, define *scmDiscoveryTraits) error {
+	if define.branches != 0 {
+		traits.CreateElement(define.pkg + "BranchDiscoveryTrait").
+			CreateElement("strategyId").SetText(strconv.Itoa(define.branches))
+	}
+	if define.origin != 0 {
+		traits.CreateElement(define.pkg + "Origin" + define.request + "DiscoveryTrait").
+			CreateElement("strategyId").SetText(strconv.Itoa(define.origin))
+	}
+	if define.forks != nil {
+		trust, ok := define.trusts[define.forks.Trust]
+		if !ok {
+			return fmt.Errorf("unsupport trust choice")
+		}
+		forkTraitClass := define.pkg + "Fork" + define.request + "DiscoveryTrait"
+		forkTrait := traits.CreateElement(forkTraitClass)
+		forkTrait.CreateElement("strategyId").SetText(strconv.Itoa(define.forks.Strategy))
+		forkTrait.CreateElement("trust").CreateAttr("class", forkTraitClass+"$"+trust)
+	}
+	if define.clone != nil {
+		cloneExtension := traits.CreateElement("jenkins.plugins.git.traits.CloneOptionTrait").CreateElement("extension")
+		cloneExtension.CreateAttr("class", "hudson.plugins.git.extensions.impl.CloneOption")
+		cloneExtension.CreateElement("shallow").SetText(strconv.FormatBool(define.clone.Shallow))
+		cloneExtension.CreateElement("noTags").SetText(strconv.FormatBool(false))
+		cloneExtension.CreateElement("reference")
+		if define.clone.Timeout >= 0 {
+			cloneExtension.CreateElement("timeout").SetText(strconv.Itoa(define.clone.Timeout))
+		} else {
+			cloneExtension.CreateElement("timeout").SetText(strconv.Itoa(10))
+		}
+		if define.clone.Depth >= 0 {
+			cloneExtension.CreateElement("depth").SetText(strconv.Itoa(define.clone.Depth))
+		} else {
+			cloneExtension.CreateElement("depth").SetText(strconv.Itoa(1))
+		}
+	}
+	if define.regex != "" {
+		regexTraits := traits.CreateElement("jenkins.scm.impl.trait.RegexSCMHeadFilterTrait")
+		regexTraits.CreateAttr("plugin", "scm-api@2.4.0")
+		regexTraits.CreateElement("regex").SetText(define.regex)
+	}
+	return nil
+}
+
+func parseSCMTraits(traits *etree.Element, define *scmDiscoveryTraits) error {
+	if traits == nil {
+		return nil
+	}
+	if branchDiscoverTrait := traits.SelectElement(define.pkg + "BranchDiscoveryTrait"); branchDiscoverTrait != nil {
+		strategyId, err := strconv.Atoi(branchDiscoverTrait.SelectElement("strategyId").Text())
+		if err != nil {
+			return err
+		}
+		define.branches = strategyId
+	}
+	if originDiscoverTrait := traits.SelectElement(
+		define.pkg + "Origin" + define.request + "DiscoveryTrait"); originDiscoverTrait != nil {
+		strategyId, err := strconv.Atoi(originDiscoverTrait.SelectElement("strategyId").Text())
+		if err != nil {
+			return err
+		}
+		define.origin = strategyId
+	}
+	if forkDiscoverTrait := traits.SelectElement(
+		define.pkg + "Fork" + define.request + "DiscoveryTrait"); forkDiscoverTrait != nil {
+		strategyId, err := strconv.Atoi(forkDiscoverTrait.SelectElement("strategyId").Text())
+		if err != nil {
+			return err
+		}
+		define.forks = &GithubDiscoverPRFromForks{Strategy: strategyId}
+		if trust := forkDiscoverTrait.SelectElement("trust"); trust != nil {
+			trustClass := trust.SelectAttrValue("class", "")
+			for choice, name := range define.trusts {
+				if strings.HasSuffix(trustClass, "$"+name) {
+					define.forks.Trust = choice
+				}
+			}
+		}
+	}
+	if cloneTrait := traits.SelectElement("jenkins.plugins.git.traits.CloneOptionTrait"); cloneTrait != nil {
+		if cloneExtension := cloneTrait.SelectElement("extension"); cloneExtension != nil {
+			define.clone = &GitCloneOption{}
+			if value, err := strconv.ParseBool(cloneExtension.SelectElement("shallow").Text()); err == nil {
+				define.clone.Shallow = value
+			}
+			if value, err := strconv.ParseInt(cloneExtension.SelectElement("timeout").Text(), 10, 32); err == nil {
+				define.clone.Timeout = int(value)
+			}
+			if value, err := strconv.ParseInt(cloneExtension.SelectElement("depth").Text(), 10, 32); err == nil {
+				define.clone.Depth = int(value)
+			}
+		}
+	}
+	if regexTrait := traits.SelectElement("jenkins.scm.impl.trait.RegexSCMHeadFilterTrait"); regexTrait != nil {
+		if regex := regexTrait.SelectElement("regex"); regex != nil {
+			define.regex = regex.Text()
+		}
+	}
+	return nil
+}
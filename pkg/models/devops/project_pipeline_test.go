@@ -232,6 +232,27 @@ func Test_MultiBranchPipelineConfig(t *testing.T) {
 			SourceType:   "github",
 			GitHubSource: &GithubSource{},
 		},
+		&MultiBranchPipeline{
+			Name:         "",
+			Description:  "for test",
+			ScriptPath:   "Jenkinsfile",
+			SourceType:   "gitlab",
+			GitlabSource: &GitlabSource{},
+		},
+		&MultiBranchPipeline{
+			Name:                  "",
+			Description:           "for test",
+			ScriptPath:            "Jenkinsfile",
+			SourceType:            "bitbucket_server",
+			BitbucketServerSource: &BitbucketServerSource{},
+		},
+		&MultiBranchPipeline{
+			Name:        "",
+			Description: "for test",
+			ScriptPath:  "Jenkinsfile",
+			SourceType:  "gitea",
+			GiteaSource: &GiteaSource{},
+		},
 		&MultiBranchPipeline{
 			Name:            "",
 			Description:     "for test",
@@ -521,3 +542,104 @@ func Test_MultiBranchPipelineRegexFilter(t *testing.T) {
 	}
 
 }
+
+func Test_MultiBranchPipelineSCMSource(t *testing.T) {
+
+	inputs := []*MultiBranchPipeline{
+		&MultiBranchPipeline{
+			Name:        "",
+			Description: "for test",
+			ScriptPath:  "Jenkinsfile",
+			SourceType:  "gitlab",
+			GitlabSource: &GitlabSource{
+				ServerName:           "default",
+				Owner:                "kubesphere",
+				Repo:                 "devops/pipelines",
+				CredentialId:         "gitlab",
+				DiscoverBranches:     1,
+				DiscoverMRFromOrigin: 2,
+				DiscoverMRFromForks: &GithubDiscoverPRFromForks{
+					Strategy: 1,
+					Trust:    3,
+				},
+				CloneOption: &GitCloneOption{
+					Depth:   3,
+					Timeout: 20,
+				},
+				RegexFilter: ".*",
+			},
+		},
+		&MultiBranchPipeline{
+			Name:        "",
+			Description: "for test",
+			ScriptPath:  "Jenkinsfile",
+			SourceType:  "bitbucket_server",
+			BitbucketServerSource: &BitbucketServerSource{
+				ApiUri:               "https://bitbucket.example.com",
+				Owner:                "KS",
+				Repo:                 "devops",
+				CredentialId:         "bitbucket",
+				DiscoverBranches:     1,
+				DiscoverPRFromOrigin: 2,
+				DiscoverPRFromForks: &GithubDiscoverPRFromForks{
+					Strategy: 1,
+					Trust:    1,
+				},
+			},
+		},
+		&MultiBranchPipeline{
+			Name:        "",
+			Description: "for test",
+			ScriptPath:  "Jenkinsfile",
+			SourceType:  "gitea",
+			GiteaSource: &GiteaSource{
+				ApiUri:               "https://gitea.example.com",
+				Owner:                "kubesphere",
+				Repo:                 "devops",
+				CredentialId:         "gitea",
+				DiscoverBranches:     3,
+				DiscoverPRFromOrigin: 1,
+				DiscoverPRFromForks: &GithubDiscoverPRFromForks{
+					Strategy: 2,
+					Trust:    4,
+				},
+				RegexFilter: "master|release-.*",
+			},
+		},
+	}
+
+	for _, input := range inputs {
+		outputString, err := createMultiBranchPipelineConfigXml("", input)
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		output, err := parseMultiBranchPipelineConfigXml(outputString)
+
+		if err != nil {
+			t.Fatalf("should not get error %+v", err)
+		}
+		if !reflect.DeepEqual(input, output) {
+			t.Fatalf("input [%+v] output [%+v] should equal ", input, output)
+		}
+	}
+}
+
+func Test_MultiBranchPipelineSCMSourceUnsupportedTrust(t *testing.T) {
+	input := &MultiBranchPipeline{
+		Name:        "",
+		Description: "for test",
+		ScriptPath:  "Jenkinsfile",
+		SourceType:  "gitea",
+		GiteaSource: &GiteaSource{
+			Owner: "kubesphere",
+			Repo:  "devops",
+			DiscoverPRFromForks: &GithubDiscoverPRFromForks{
+				Strategy: 1,
+				Trust:    3,
+			},
+		},
+	}
+	if _, err := createMultiBranchPipelineConfigXml("", input); err == nil {
+		t.Fatalf("trust permission of gitea should be unsupported")
+	}
+}
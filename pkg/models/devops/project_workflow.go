@@ -0,0 +1,336 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package devops
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gocraft/dbr"
+	"github.com/golang/glog"
+	"kubesphere.io/kubesphere/pkg/db"
+	"kubesphere.io/kubesphere/pkg/gojenkins"
+	"kubesphere.io/kubesphere/pkg/gojenkins/utils"
+)
+
+// workflowStep is a step of creating or deleting a devops project, steps are idempotent so they can be retried,
+// undo compensates a completed step when a later step fails
+type workflowStep struct {
+	name string
+	do   func() error
+	undo func() error
+}
+
+const workflowStepRetries = 3
+
+var workflowRetryInterval = time.Second
+
+// runWorkflow runs the steps in order, a failed step is retried on transient errors. When a step fails the completed
+// steps are undone in reverse order, whatever can't be undone is left to the orphan reconciler.
+func runWorkflow(workflow string, steps []workflowStep) error {
+	for i, step := range steps {
+		err := retryWorkflowStep(step.do)
+		if err == nil {
+			continue
+		}
+		glog.Errorf("%s: step %s failed: %+v", workflow, step.name, err)
+
+		for j := i - 1; j >= 0; j-- {
+			if steps[j].undo == nil {
+				continue
+			}
+			if undoErr := retryWorkflowStep(steps[j].undo); undoErr != nil {
+				glog.Errorf("%s: undo step %s failed: %+v", workflow, steps[j].name, undoErr)
+			}
+		}
+		// the error is returned as is to keep the status code of Jenkins
+		return err
+	}
+	return nil
+}
+
+func retryWorkflowStep(f func() error) error {
+	var err error
+	for i := 0; i < workflowStepRetries; i++ {
+		if i > 0 {
+			time.Sleep(workflowRetryInterval)
+		}
+		err = f()
+		// requests rejected by Jenkins won't succeed on retry
+		if err == nil || utils.GetJenkinsStatusCode(err) < http.StatusInternalServerError {
+			return err
+		}
+	}
+	return err
+}
+
+func isJenkinsNotFound(err error) bool {
+	return err != nil && utils.GetJenkinsStatusCode(err) == http.StatusNotFound
+}
+
+// projectRoleNames returns the names of the project and pipeline roles of the project
+func projectRoleNames(projectId string) []string {
+	roleNames := make([]string, 0)
+	for role := range JenkinsProjectPermissionMap {
+		roleNames = append(roleNames, GetProjectRoleName(projectId, role))
+		roleNames = append(roleNames, GetPipelineRoleName(projectId, role))
+	}
+	return roleNames
+}
+
+// createProjectFolder creates the folder of the project if it doesn't exist, created is false if it exists
+func createProjectFolder(jenkins *gojenkins.Jenkins, projectId, description string) (created bool, err error) {
+	_, err = jenkins.GetJob(projectId)
+	if err == nil {
+		return false, nil
+	}
+	if !isJenkinsNotFound(err) {
+		return false, err
+	}
+	_, err = jenkins.CreateFolder(projectId, description)
+	return err == nil, err
+}
+
+func deleteProjectFolder(jenkins *gojenkins.Jenkins, projectId string) error {
+	_, err := jenkins.DeleteJob(projectId)
+	if isJenkinsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// createProjectRoles creates or overwrites the project and pipeline roles of the project concurrently
+func createProjectRoles(jenkins *gojenkins.Jenkins, projectId string) error {
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(JenkinsProjectPermissionMap)+len(JenkinsPipelinePermissionMap))
+
+	addRole := func(roleName, pattern string, permission gojenkins.ProjectPermissionIds) {
+		defer wg.Done()
+		_, err := jenkins.AddProjectRole(roleName, pattern, permission, true)
+		errCh <- err
+	}
+	for role, permission := range JenkinsProjectPermissionMap {
+		wg.Add(1)
+		go addRole(GetProjectRoleName(projectId, role), GetProjectRolePattern(projectId), permission)
+	}
+	for role, permission := range JenkinsPipelinePermissionMap {
+		wg.Add(1)
+		go addRole(GetPipelineRoleName(projectId, role), GetPipelineRolePattern(projectId), permission)
+	}
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignProjectRoles grants the global role and the project and pipeline roles of role to the user
+func assignProjectRoles(jenkins *gojenkins.Jenkins, projectId, username, role string) error {
+	globalRole, err := jenkins.GetGlobalRole(JenkinsAllUserRoleName)
+	if err != nil {
+		return err
+	}
+	if globalRole == nil {
+		globalRole, err = jenkins.AddGlobalRole(JenkinsAllUserRoleName, gojenkins.GlobalPermissionIds{
+			GlobalRead: true,
+		}, true)
+		if err != nil {
+			return err
+		}
+	}
+	if err := globalRole.AssignRole(username); err != nil {
+		return err
+	}
+
+	for _, roleName := range []string{GetProjectRoleName(projectId, role), GetPipelineRoleName(projectId, role)} {
+		projectRole, err := jenkins.GetProjectRole(roleName)
+		if err != nil {
+			return err
+		}
+		if projectRole == nil {
+			return fmt.Errorf("jenkins role %s not found", roleName)
+		}
+		if err := projectRole.AssignRole(username); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateProject creates the folder and roles of the project in Jenkins, grants the owner roles to the creator and
+// inserts the project and its owner into the database. Everything created is removed again if a step fails.
+func CreateProject(jenkins *gojenkins.Jenkins, dbconn *db.Database, project *DevOpsProject) error {
+	var folderCreated bool
+
+	return runWorkflow("create devops project "+project.ProjectId, []workflowStep{
+		{
+			name: "create jenkins folder",
+			do: func() error {
+				created, err := createProjectFolder(jenkins, project.ProjectId, project.Description)
+				folderCreated = folderCreated || created
+				return err
+			},
+			undo: func() error {
+				if !folderCreated {
+					return nil
+				}
+				return deleteProjectFolder(jenkins, project.ProjectId)
+			},
+		},
+		{
+			name: "create jenkins roles",
+			do: func() error {
+				return createProjectRoles(jenkins, project.ProjectId)
+			},
+			undo: func() error {
+				return jenkins.DeleteProjectRoles(projectRoleNames(project.ProjectId)...)
+			},
+		},
+		{
+			// the global role isn't revoked on undo, the creator may be a member of other projects,
+			// the project roles are revoked with their deletion
+			name: "assign jenkins roles",
+			do: func() error {
+				return assignProjectRoles(jenkins, project.ProjectId, project.Creator, ProjectOwner)
+			},
+		},
+		{
+			name: "insert project",
+			do: func() error {
+				return insertProject(dbconn, project, NewDevOpsProjectMemberShip(project.Creator, project.ProjectId, ProjectOwner, project.Creator))
+			},
+		},
+	})
+}
+
+// insertProject inserts the project and its memberships in a transaction, nothing is inserted if the project exists
+func insertProject(dbconn *db.Database, project *DevOpsProject, memberships ...*DevOpsProjectMembership) error {
+	tx, err := dbconn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	existing := &DevOpsProject{}
+	err = tx.Select(DevOpsProjectColumns...).
+		From(DevOpsProjectTableName).
+		Where(db.Eq(DevOpsProjectIdColumn, project.ProjectId)).
+		LoadOne(existing)
+	if err == nil {
+		return nil
+	}
+	if err != dbr.ErrNotFound {
+		return err
+	}
+
+	_, err = tx.InsertInto(DevOpsProjectTableName).
+		Columns(DevOpsProjectColumns...).Record(project).Exec()
+	if err != nil {
+		return err
+	}
+	for _, membership := range memberships {
+		_, err = tx.InsertInto(DevOpsProjectMembershipTableName).
+			Columns(DevOpsProjectMembershipColumns...).Record(membership).Exec()
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteProject marks the project deleted in the database and deletes its roles and folder in Jenkins.
+// The project, its members and roles are restored if a step fails.
+func DeleteProject(jenkins *gojenkins.Jenkins, dbconn *db.Database, projectId string) error {
+	memberships := make([]*DevOpsProjectMembership, 0)
+
+	return runWorkflow("delete devops project "+projectId, []workflowStep{
+		{
+			name: "delete project",
+			do: func() error {
+				memberships = memberships[:0]
+				_, err := dbconn.Select(DevOpsProjectMembershipColumns...).
+					From(DevOpsProjectMembershipTableName).
+					Where(db.Eq(DevOpsProjectMembershipProjectIdColumn, projectId)).
+					Load(&memberships)
+				if err != nil && err != dbr.ErrNotFound {
+					return err
+				}
+				return setProjectStatus(dbconn, projectId, StatusDeleted)
+			},
+			undo: func() error {
+				return setProjectStatus(dbconn, projectId, StatusActive, memberships...)
+			},
+		},
+		{
+			name: "delete jenkins roles",
+			do: func() error {
+				return jenkins.DeleteProjectRoles(projectRoleNames(projectId)...)
+			},
+			undo: func() error {
+				if err := createProjectRoles(jenkins, projectId); err != nil {
+					return err
+				}
+				for _, membership := range memberships {
+					if err := assignProjectRoles(jenkins, projectId, membership.Username, membership.Role); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "delete jenkins folder",
+			do: func() error {
+				return deleteProjectFolder(jenkins, projectId)
+			},
+		},
+	})
+}
+
+// setProjectStatus updates the status of the project in a transaction, memberships of a deleted project are deleted,
+// the given memberships are inserted again when it's restored
+func setProjectStatus(dbconn *db.Database, projectId, status string, memberships ...*DevOpsProjectMembership) error {
+	tx, err := dbconn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	_, err = tx.DeleteFrom(DevOpsProjectMembershipTableName).
+		Where(db.Eq(DevOpsProjectMembershipProjectIdColumn, projectId)).Exec()
+	if err != nil {
+		return err
+	}
+	for _, membership := range memberships {
+		_, err = tx.InsertInto(DevOpsProjectMembershipTableName).
+			Columns(DevOpsProjectMembershipColumns...).Record(membership).Exec()
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Update(DevOpsProjectTableName).
+		Set(StatusColumn, status).
+		Where(db.Eq(DevOpsProjectIdColumn, projectId)).Exec()
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
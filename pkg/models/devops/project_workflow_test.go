@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+package devops
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRunWorkflow(t *testing.T) {
+	workflowRetryInterval = time.Millisecond
+
+	var calls []string
+	step := func(name string, failures int, err error) workflowStep {
+		return workflowStep{
+			name: name,
+			do: func() error {
+				calls = append(calls, "do "+name)
+				if failures > 0 {
+					failures--
+					return err
+				}
+				return nil
+			},
+			undo: func() error {
+				calls = append(calls, "undo "+name)
+				return nil
+			},
+		}
+	}
+
+	// transient errors are retried
+	err := runWorkflow("test", []workflowStep{
+		step("a", 0, nil),
+		step("b", 2, errors.New(strconv.Itoa(503))),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"do a", "do b", "do b", "do b"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+
+	// completed steps are undone in reverse order, rejected requests aren't retried
+	calls = nil
+	c := step("c", 1, errors.New(strconv.Itoa(400)))
+	err = runWorkflow("test", []workflowStep{
+		step("a", 0, nil),
+		{name: "b", do: func() error { calls = append(calls, "do b"); return nil }},
+		step("x", 0, nil),
+		c,
+		step("d", 0, nil),
+	})
+	if err == nil || err.Error() != "400" {
+		t.Errorf("expected the error of the failed step, got %v", err)
+	}
+	if expected := []string{"do a", "do b", "do x", "do c", "undo x", "undo a"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+}
+
+func TestProjectIdOfRole(t *testing.T) {
+	tests := map[string]string{
+		GetProjectRoleName("project-B6BZVN3mOPvx", ProjectOwner):      "project-B6BZVN3mOPvx",
+		GetPipelineRoleName("project-B6BZVN3mOPvx", ProjectReporter):  "project-B6BZVN3mOPvx",
+		GetPipelineRoleName("project-B6BZVN3mOPvx", ProjectDeveloper): "project-B6BZVN3mOPvx",
+		GetProjectRoleName("sample", ProjectOwner):                    "",
+		JenkinsAllUserRoleName:                                        "",
+		"project-B6BZVN3mOPvx-admin":                                  "",
+	}
+
+	for roleName, expected := range tests {
+		if projectId := projectIdOfRole(roleName); projectId != expected {
+			t.Errorf("expected project %q of role %s, got %q", expected, roleName, projectId)
+		}
+	}
+}
+
+func TestIntersectProjectOrphans(t *testing.T) {
+	current := &ProjectOrphans{
+		Folders:  []string{"project-a", "project-b"},
+		Roles:    []string{"project-a-owner-project"},
+		Projects: []string{"project-c"},
+	}
+
+	if orphans := intersectProjectOrphans(nil, current); !orphans.empty() {
+		t.Errorf("expected no orphans without a previous check, got %+v", orphans)
+	}
+
+	previous := &ProjectOrphans{
+		Folders:  []string{"project-a"},
+		Roles:    []string{"project-a-owner-project", "project-d-owner-project"},
+		Projects: []string{},
+	}
+	orphans := intersectProjectOrphans(previous, current)
+	if !reflect.DeepEqual(orphans.Folders, []string{"project-a"}) ||
+		!reflect.DeepEqual(orphans.Roles, []string{"project-a-owner-project"}) ||
+		len(orphans.Projects) != 0 {
+		t.Errorf("unexpected orphans %+v", orphans)
+	}
+}
+
+func TestCompareProjects(t *testing.T) {
+	active := map[string]bool{"project-active": true, "project-missing": true, "project-migrated": true}
+	// project-migrated is migrated to a DevOpsProject, project-resource is the name of a DevOpsProject
+	managed := map[string]bool{"project-migrated": true, "project-resource": true}
+
+	jobNames := []string{"project-active", "project-orphan", "project-resource", "sample"}
+	roleNames := []string{
+		GetProjectRoleName("project-active", ProjectOwner),
+		GetProjectRoleName("project-orphan", ProjectOwner),
+		GetPipelineRoleName("project-resource", ProjectDeveloper),
+		GetProjectRoleName("project-migrated", ProjectOwner),
+		GetProjectRoleName("sample", ProjectOwner),
+	}
+
+	orphans := compareProjects(active, managed, jobNames, roleNames, time.Now())
+
+	if !reflect.DeepEqual(orphans.Folders, []string{"project-orphan"}) ||
+		!reflect.DeepEqual(orphans.Roles, []string{GetProjectRoleName("project-orphan", ProjectOwner)}) ||
+		!reflect.DeepEqual(orphans.Projects, []string{"project-missing"}) {
+		t.Errorf("unexpected orphans %+v", orphans)
+	}
+}
@@ -0,0 +1,488 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	devopsv1alpha1 "kubesphere.io/kubesphere/pkg/apis/devops/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+)
+
+var (
+	CredentialGroupVersionResource    = devopsv1alpha1.SchemeGroupVersion.WithResource("credentials")
+	DevOpsProjectGroupVersionResource = devopsv1alpha1.SchemeGroupVersion.WithResource("devopsprojects")
+)
+
+const defaultGitlabApiUrl = "https://gitlab.com"
+
+var (
+	scmServersLock sync.RWMutex
+	// credentials of projects are only sent to the servers configured by the administrator
+	scmServers = map[string][]string{SCMGitlab: {defaultGitlabApiUrl}}
+)
+
+// SetSCMServers sets the api urls of servers of scms in the form of <scm>=<url>, organizations and repositories are
+// only listed of the configured servers, the first server of the scm is used by default
+func SetSCMServers(servers []string) error {
+	parsed := make(map[string][]string)
+	for _, server := range servers {
+		parts := strings.SplitN(server, "=", 2)
+		if len(parts) != 2 || !IsSCMProvider(parts[0]) {
+			return fmt.Errorf("invalid scm server %s, expected <scm>=<url> of gitlab, bitbucket-server or gitea", server)
+		}
+		if u, err := url.Parse(parts[1]); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid url of scm server %s", server)
+		}
+		parsed[parts[0]] = append(parsed[parts[0]], strings.TrimSuffix(parts[1], "/"))
+	}
+
+	scmServersLock.Lock()
+	defer scmServersLock.Unlock()
+	scmServers = parsed
+	return nil
+}
+
+// scmServer returns the configured server of the scm with the api url, the first server of the scm if apiUrl is empty
+func scmServer(scm, apiUrl string) (string, bool) {
+	scmServersLock.RLock()
+	defer scmServersLock.RUnlock()
+	servers := scmServers[scm]
+	if apiUrl == "" && len(servers) > 0 {
+		return servers[0], true
+	}
+	for _, server := range servers {
+		if server == strings.TrimSuffix(apiUrl, "/") {
+			return server, true
+		}
+	}
+	return "", false
+}
+
+// SCMRepositories is a page of repositories of an organization, in the same form as the repositories of
+// scms listed by jenkins
+type SCMRepositories struct {
+	Repositories struct {
+		Items    []*SCMRepository `json:"items" description:"repositories"`
+		NextPage interface{}      `json:"nextPage,omitempty" description:"next page"`
+		PageSize int              `json:"pageSize,omitempty" description:"page size"`
+	} `json:"repositories"`
+}
+
+type SCMRepository struct {
+	DefaultBranch string `json:"defaultBranch,omitempty" description:"default branch"`
+	Description   string `json:"description,omitempty" description:"description"`
+	Name          string `json:"name,omitempty" description:"name, the repo of sources of multi-branch pipelines"`
+	Permissions   struct {
+		Admin bool `json:"admin,omitempty" description:"admin"`
+		Push  bool `json:"push,omitempty" description:"push action"`
+		Pull  bool `json:"pull,omitempty" description:"pull action"`
+	} `json:"permissions,omitempty"`
+	Private  bool   `json:"private,omitempty" description:"private or not"`
+	FullName string `json:"fullName,omitempty" description:"full name"`
+}
+
+// IsSCMProvider returns whether organizations and repositories of the scm are listed by ks-apiserver instead of
+// jenkins, which lists them of github and bitbucket cloud only
+func IsSCMProvider(scm string) bool {
+	return scm == SCMGitlab || scm == SCMBitbucketServer || scm == SCMGitea
+}
+
+// GetSCMProviderOrgs lists organizations of the scm, i.e. the user and groups of gitlab, the user and organizations of
+// gitea and projects of bitbucket server, with the credential of the project
+func GetSCMProviderOrgs(scm, projectId, credentialId, apiUrl string, pageNumber, pageSize int) ([]*SCMOrg, error) {
+	client, err := newProjectSCMClient(scm, projectId, credentialId, apiUrl)
+	if err != nil {
+		return nil, err
+	}
+	return client.organizations(pageNumber, pageSize)
+}
+
+// GetSCMProviderOrgRepos lists repositories of the organization of the scm with the credential of the project
+func GetSCMProviderOrgRepos(scm, projectId, credentialId, apiUrl, org string, pageNumber, pageSize int) (*SCMRepositories, error) {
+	client, err := newProjectSCMClient(scm, projectId, credentialId, apiUrl)
+	if err != nil {
+		return nil, err
+	}
+	return client.repositories(org, pageNumber, pageSize)
+}
+
+type scmClient struct {
+	scm      string
+	apiUrl   string
+	username string
+	token    string
+	client   *http.Client
+}
+
+func newProjectSCMClient(scm, projectId, credentialId, apiUrl string) (*scmClient, error) {
+	if !IsSCMProvider(scm) {
+		return nil, restful.NewError(http.StatusNotFound, fmt.Sprintf("unsupported scm %s", scm))
+	}
+	server, ok := scmServer(scm, apiUrl)
+	if !ok {
+		return nil, restful.NewError(http.StatusBadRequest, fmt.Sprintf("server [%s] of %s is not configured", apiUrl, scm))
+	}
+	username, token, err := getProjectSCMCredential(projectId, credentialId)
+	if err != nil {
+		return nil, err
+	}
+	return newSCMClient(scm, server, username, token), nil
+}
+
+func newSCMClient(scm, apiUrl, username, token string) *scmClient {
+	return &scmClient{
+		scm:      scm,
+		apiUrl:   strings.TrimSuffix(apiUrl, "/"),
+		username: username,
+		token:    token,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// getProjectSCMCredential returns the username and the token of the credential of the project, the content of
+// credentials can't be read back from jenkins so it's read from the secret of the Credential resource which
+// applied the credential, only Credentials in the namespace of the DevOpsProject are looked up
+func getProjectSCMCredential(projectId, credentialId string) (string, string, error) {
+	project, err := getDevOpsProjectResource(projectId)
+	if err != nil {
+		return "", "", err
+	}
+
+	var credential *devopsv1alpha1.Credential
+	if project != nil && project.Spec.Namespace != "" {
+		list, err := k8s.DynamicClient().Resource(CredentialGroupVersionResource).Namespace(project.Spec.Namespace).List(metav1.ListOptions{})
+		if err != nil {
+			glog.Errorf("%+v", err)
+			return "", "", restful.NewError(http.StatusInternalServerError, err.Error())
+		}
+		for _, item := range list.Items {
+			c := &devopsv1alpha1.Credential{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, c); err != nil {
+				glog.Errorf("invalid credential %s/%s: %+v", item.GetNamespace(), item.GetName(), err)
+				continue
+			}
+			if c.Spec.Project == project.Name && c.Status.ProjectId == projectId && c.Status.Id == credentialId {
+				credential = c
+				break
+			}
+		}
+	}
+	if credential == nil || credential.Spec.SecretRef == nil {
+		err := fmt.Errorf("credential [%s] of project [%s] is not found in Credential resources with secrets", credentialId, projectId)
+		glog.Warning(err)
+		return "", "", restful.NewError(http.StatusNotFound, err.Error())
+	}
+
+	secret, err := k8s.Client().CoreV1().Secrets(credential.Namespace).Get(credential.Spec.SecretRef.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		err := fmt.Errorf("secret [%s] of credential [%s] not found", credential.Spec.SecretRef.Name, credentialId)
+		glog.Warning(err)
+		return "", "", restful.NewError(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return "", "", restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+
+	switch credential.Spec.Type {
+	case devopsv1alpha1.CredentialTypeUsernamePassword:
+		return string(secret.Data[devopsv1alpha1.SecretUsernameKey]), string(secret.Data[devopsv1alpha1.SecretPasswordKey]), nil
+	case devopsv1alpha1.CredentialTypeSecretText:
+		return "", string(secret.Data[devopsv1alpha1.SecretTextKey]), nil
+	default:
+		return "", "", restful.NewError(http.StatusBadRequest,
+			fmt.Sprintf("credential [%s] of type %s can't access scm apis", credentialId, credential.Spec.Type))
+	}
+}
+
+// getDevOpsProjectResource returns the DevOpsProject which applied the folder of the project, nil if there isn't one
+func getDevOpsProjectResource(projectId string) (*devopsv1alpha1.DevOpsProject, error) {
+	list, err := k8s.DynamicClient().Resource(DevOpsProjectGroupVersionResource).List(metav1.ListOptions{})
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	for _, item := range list.Items {
+		project := &devopsv1alpha1.DevOpsProject{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, project); err != nil {
+			glog.Errorf("invalid devops project %s: %+v", item.GetName(), err)
+			continue
+		}
+		if project.Status.ProjectId == projectId {
+			return project, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *scmClient) organizations(pageNumber, pageSize int) ([]*SCMOrg, error) {
+	orgs := make([]*SCMOrg, 0)
+	switch c.scm {
+	case SCMGitlab:
+		// the namespace of the user comes before groups
+		if pageNumber <= 1 {
+			user := struct {
+				Username  string `json:"username"`
+				AvatarUrl string `json:"avatar_url"`
+			}{}
+			if _, err := c.get("/api/v4/user", nil, &user); err != nil {
+				return nil, err
+			}
+			orgs = append(orgs, &SCMOrg{Name: user.Username, Avatar: user.AvatarUrl})
+		}
+		groups := make([]struct {
+			FullPath  string `json:"full_path"`
+			AvatarUrl string `json:"avatar_url"`
+		}, 0)
+		if _, err := c.get("/api/v4/groups", c.pageQuery(pageNumber, pageSize), &groups); err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			orgs = append(orgs, &SCMOrg{Name: group.FullPath, Avatar: group.AvatarUrl})
+		}
+
+	case SCMGitea:
+		if pageNumber <= 1 {
+			user := struct {
+				Login     string `json:"login"`
+				AvatarUrl string `json:"avatar_url"`
+			}{}
+			if _, err := c.get("/api/v1/user", nil, &user); err != nil {
+				return nil, err
+			}
+			orgs = append(orgs, &SCMOrg{Name: user.Login, Avatar: user.AvatarUrl})
+		}
+		organizations := make([]struct {
+			Username  string `json:"username"`
+			AvatarUrl string `json:"avatar_url"`
+		}, 0)
+		if _, err := c.get("/api/v1/user/orgs", c.pageQuery(pageNumber, pageSize), &organizations); err != nil {
+			return nil, err
+		}
+		for _, organization := range organizations {
+			orgs = append(orgs, &SCMOrg{Name: organization.Username, Avatar: organization.AvatarUrl})
+		}
+
+	case SCMBitbucketServer:
+		projects := struct {
+			Values []struct {
+				Key string `json:"key"`
+			} `json:"values"`
+		}{}
+		if _, err := c.get("/rest/api/1.0/projects", c.pageQuery(pageNumber, pageSize), &projects); err != nil {
+			return nil, err
+		}
+		for _, project := range projects.Values {
+			orgs = append(orgs, &SCMOrg{
+				Name:   project.Key,
+				Avatar: fmt.Sprintf("%s/rest/api/1.0/projects/%s/avatar.png", c.apiUrl, project.Key),
+			})
+		}
+	}
+	return orgs, nil
+}
+
+func (c *scmClient) repositories(org string, pageNumber, pageSize int) (*SCMRepositories, error) {
+	result := &SCMRepositories{}
+	result.Repositories.Items = make([]*SCMRepository, 0)
+	result.Repositories.PageSize = pageSize
+	query := c.pageQuery(pageNumber, pageSize)
+
+	switch c.scm {
+	case SCMGitlab:
+		projects := make([]struct {
+			Path              string `json:"path"`
+			PathWithNamespace string `json:"path_with_namespace"`
+			Description       string `json:"description"`
+			DefaultBranch     string `json:"default_branch"`
+			Visibility        string `json:"visibility"`
+		}, 0)
+		query.Set("include_subgroups", "true")
+		header, err := c.get(fmt.Sprintf("/api/v4/groups/%s/projects", url.PathEscape(org)), query, &projects)
+		if isSCMNotFound(err) {
+			query.Del("include_subgroups")
+			header, err = c.get(fmt.Sprintf("/api/v4/users/%s/projects", url.PathEscape(org)), query, &projects)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			repository := &SCMRepository{
+				DefaultBranch: project.DefaultBranch,
+				Description:   project.Description,
+				// projects in subgroups are named by their path in the group
+				Name:     strings.TrimPrefix(project.PathWithNamespace, org+"/"),
+				Private:  project.Visibility == "private",
+				FullName: project.PathWithNamespace,
+			}
+			repository.Permissions.Pull = true
+			result.Repositories.Items = append(result.Repositories.Items, repository)
+		}
+		if next, err := strconv.Atoi(header.Get("X-Next-Page")); err == nil {
+			result.Repositories.NextPage = next
+		}
+
+	case SCMGitea:
+		repos := make([]struct {
+			Name          string `json:"name"`
+			FullName      string `json:"full_name"`
+			Description   string `json:"description"`
+			DefaultBranch string `json:"default_branch"`
+			Private       bool   `json:"private"`
+			Permissions   struct {
+				Admin bool `json:"admin"`
+				Push  bool `json:"push"`
+				Pull  bool `json:"pull"`
+			} `json:"permissions"`
+		}, 0)
+		_, err := c.get(fmt.Sprintf("/api/v1/orgs/%s/repos", url.PathEscape(org)), query, &repos)
+		if isSCMNotFound(err) {
+			_, err = c.get(fmt.Sprintf("/api/v1/users/%s/repos", url.PathEscape(org)), query, &repos)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			repository := &SCMRepository{
+				DefaultBranch: repo.DefaultBranch,
+				Description:   repo.Description,
+				Name:          repo.Name,
+				Private:       repo.Private,
+				FullName:      repo.FullName,
+			}
+			repository.Permissions.Admin = repo.Permissions.Admin
+			repository.Permissions.Push = repo.Permissions.Push
+			repository.Permissions.Pull = repo.Permissions.Pull
+			result.Repositories.Items = append(result.Repositories.Items, repository)
+		}
+		if len(repos) == pageSize {
+			result.Repositories.NextPage = pageNumber + 1
+		}
+
+	case SCMBitbucketServer:
+		repos := struct {
+			Values []struct {
+				Slug        string `json:"slug"`
+				Description string `json:"description"`
+				Public      bool   `json:"public"`
+			} `json:"values"`
+			IsLastPage bool `json:"isLastPage"`
+		}{}
+		_, err := c.get(fmt.Sprintf("/rest/api/1.0/projects/%s/repos", url.PathEscape(org)), query, &repos)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos.Values {
+			repository := &SCMRepository{
+				Description: repo.Description,
+				Name:        repo.Slug,
+				Private:     !repo.Public,
+				FullName:    org + "/" + repo.Slug,
+			}
+			repository.Permissions.Pull = true
+			result.Repositories.Items = append(result.Repositories.Items, repository)
+		}
+		if !repos.IsLastPage {
+			result.Repositories.NextPage = pageNumber + 1
+		}
+	}
+	return result, nil
+}
+
+func (c *scmClient) pageQuery(pageNumber, pageSize int) url.Values {
+	if pageNumber < 1 {
+		pageNumber = 1
+	}
+	query := url.Values{}
+	switch c.scm {
+	case SCMGitlab:
+		query.Set("page", strconv.Itoa(pageNumber))
+		query.Set("per_page", strconv.Itoa(pageSize))
+	case SCMGitea:
+		query.Set("page", strconv.Itoa(pageNumber))
+		query.Set("limit", strconv.Itoa(pageSize))
+	case SCMBitbucketServer:
+		query.Set("start", strconv.Itoa((pageNumber-1)*pageSize))
+		query.Set("limit", strconv.Itoa(pageSize))
+	}
+	return query
+}
+
+func (c *scmClient) get(path string, query url.Values, into interface{}) (http.Header, error) {
+	endpoint := c.apiUrl + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.scm == SCMGitlab:
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.token)
+	case c.scm == SCMGitea:
+		req.Header.Set("Authorization", "token "+c.token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		glog.Errorf("%+v", err)
+		return nil, restful.NewError(http.StatusBadGateway, err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, restful.NewError(http.StatusBadGateway, err.Error())
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		glog.Warningf("%s %s: %d %s", c.scm, path, resp.StatusCode, string(body))
+		code := resp.StatusCode
+		if code >= http.StatusInternalServerError {
+			code = http.StatusBadGateway
+		}
+		return nil, restful.NewError(code, fmt.Sprintf("%s: %s", c.scm, http.StatusText(resp.StatusCode)))
+	}
+	if err := json.Unmarshal(body, into); err != nil {
+		return nil, restful.NewError(http.StatusBadGateway, fmt.Sprintf("%s: %v", c.scm, err))
+	}
+	return resp.Header, nil
+}
+
+func isSCMNotFound(err error) bool {
+	if svcErr, ok := err.(restful.ServiceError); ok {
+		return svcErr.Code == http.StatusNotFound
+	}
+	return false
+}
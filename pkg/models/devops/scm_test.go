@@ -0,0 +1,269 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/emicklei/go-restful"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestVerifySCMWebhook(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/master"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		scm     string
+		header  map[string]string
+		success bool
+	}{
+		{SCMGitlab, map[string]string{"X-Gitlab-Token": "secret"}, true},
+		{SCMGitlab, map[string]string{"X-Gitlab-Token": "other"}, false},
+		{SCMGitlab, nil, false},
+		{SCMBitbucketServer, map[string]string{"X-Hub-Signature": "sha256=" + signature}, true},
+		{SCMBitbucketServer, map[string]string{"X-Hub-Signature": signature}, false},
+		{SCMBitbucketServer, map[string]string{"X-Hub-Signature": "sha256=00"}, false},
+		{SCMGitea, map[string]string{"X-Gitea-Signature": signature}, true},
+		{SCMGitea, map[string]string{"X-Gitea-Signature": "not hex"}, false},
+		{SCMGitea, nil, false},
+		{"github", map[string]string{"X-Hub-Signature": "sha256=" + signature}, false},
+	}
+
+	for i, test := range tests {
+		header := http.Header{}
+		for key, value := range test.header {
+			header.Set(key, value)
+		}
+		err := verifySCMWebhook(test.scm, "secret", header, body)
+		if (err == nil) != test.success {
+			t.Errorf("case %d: %s webhook with %v, expected success %v, got %v", i, test.scm, test.header, test.success, err)
+		}
+	}
+}
+
+func TestSCMWebhookWithoutSecret(t *testing.T) {
+	SetSCMWebhookSecrets(map[string]string{SCMGitlab: "secret"})
+	defer SetSCMWebhookSecrets(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/gitea", nil)
+	if _, err := SCMWebhook(SCMGitea, httptest.NewRecorder(), req); !isSCMStatus(err, http.StatusForbidden) {
+		t.Errorf("webhook of scm without secret should be forbidden, got %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/webhook/gitlab", nil)
+	req.Header.Set("X-Gitlab-Token", "other")
+	if _, err := SCMWebhook(SCMGitlab, httptest.NewRecorder(), req); !isSCMStatus(err, http.StatusUnauthorized) {
+		t.Errorf("webhook with invalid token should be unauthorized, got %v", err)
+	}
+}
+
+func TestSCMWebhookTooLarge(t *testing.T) {
+	SetSCMWebhookSecrets(map[string]string{SCMGitlab: "secret"})
+	defer SetSCMWebhookSecrets(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/gitlab", bytes.NewReader(make([]byte, maxSCMWebhookSize+1)))
+	req.Header.Set("X-Gitlab-Token", "secret")
+	if _, err := SCMWebhook(SCMGitlab, httptest.NewRecorder(), req); !isSCMStatus(err, http.StatusRequestEntityTooLarge) {
+		t.Errorf("webhook exceeding the size limit should be rejected, got %v", err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/webhook/gitlab", bytes.NewReader(make([]byte, maxSCMWebhookSize)))
+	req.Header.Set("X-Gitlab-Token", "other")
+	if _, err := SCMWebhook(SCMGitlab, httptest.NewRecorder(), req); !isSCMStatus(err, http.StatusUnauthorized) {
+		t.Errorf("webhook of the size limit should be verified, got %v", err)
+	}
+}
+
+func TestSCMClientGitlab(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v4/user":
+			w.Write([]byte(`{"username":"admin","avatar_url":"a"}`))
+		case "/api/v4/groups":
+			w.Write([]byte(`[{"full_path":"kubesphere","avatar_url":"b"},{"full_path":"kubesphere/devops","avatar_url":"c"}]`))
+		case "/api/v4/groups/kubesphere/projects":
+			w.Header().Set("X-Next-Page", "2")
+			w.Write([]byte(`[{"path":"pipelines","path_with_namespace":"kubesphere/devops/pipelines","default_branch":"master","visibility":"private"}]`))
+		case "/api/v4/groups/admin/projects":
+			w.WriteHeader(http.StatusNotFound)
+		case "/api/v4/users/admin/projects":
+			w.Write([]byte(`[{"path":"demo","path_with_namespace":"admin/demo","default_branch":"main","visibility":"public"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newSCMClient(SCMGitlab, server.URL, "", "token")
+	orgs, err := client.organizations(1, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*SCMOrg{{Name: "admin", Avatar: "a"}, {Name: "kubesphere", Avatar: "b"}, {Name: "kubesphere/devops", Avatar: "c"}}
+	if !reflect.DeepEqual(orgs, expected) {
+		t.Errorf("expected organizations %+v, got %+v", expected, orgs)
+	}
+
+	repos, err := client.repositories("kubesphere", 1, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos.Repositories.Items) != 1 || repos.Repositories.NextPage != 2 {
+		t.Fatalf("unexpected repositories %+v", repos.Repositories)
+	}
+	if repo := repos.Repositories.Items[0]; repo.Name != "devops/pipelines" || !repo.Private || repo.DefaultBranch != "master" {
+		t.Errorf("unexpected repository %+v", repo)
+	}
+
+	repos, err = client.repositories("admin", 1, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos.Repositories.Items) != 1 || repos.Repositories.Items[0].Name != "demo" || repos.Repositories.NextPage != nil {
+		t.Errorf("repositories of users should be listed, got %+v", repos.Repositories)
+	}
+
+	client = newSCMClient(SCMGitlab, server.URL, "", "other")
+	if _, err := client.organizations(1, 20); !isSCMStatus(err, http.StatusUnauthorized) {
+		t.Errorf("expected unauthorized, got %v", err)
+	}
+}
+
+func TestSCMClientGitea(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/user/orgs":
+			if r.URL.Query().Get("page") != "2" {
+				t.Errorf("expected page 2, got %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`[{"username":"kubesphere"}]`))
+		case "/api/v1/orgs/kubesphere/repos":
+			w.Write([]byte(`[{"name":"devops","full_name":"kubesphere/devops","permissions":{"admin":true,"push":true,"pull":true}}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newSCMClient(SCMGitea, server.URL+"/", "", "token")
+	orgs, err := client.organizations(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(orgs, []*SCMOrg{{Name: "kubesphere"}}) {
+		t.Errorf("users should be listed on the first page only, got %+v", orgs)
+	}
+
+	repos, err := client.repositories("kubesphere", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos.Repositories.Items) != 1 || !repos.Repositories.Items[0].Permissions.Admin || repos.Repositories.NextPage != 2 {
+		t.Errorf("unexpected repositories %+v", repos.Repositories)
+	}
+}
+
+func TestSCMClientBitbucketServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/rest/api/1.0/projects":
+			w.Write([]byte(`{"values":[{"key":"KS"}],"isLastPage":true}`))
+		case "/rest/api/1.0/projects/KS/repos":
+			if r.URL.Query().Get("start") != "10" {
+				t.Errorf("expected start 10, got %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"values":[{"slug":"devops","public":true}],"isLastPage":false}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newSCMClient(SCMBitbucketServer, server.URL, "admin", "password")
+	orgs, err := client.organizations(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orgs) != 1 || orgs[0].Name != "KS" {
+		t.Errorf("unexpected organizations %+v", orgs)
+	}
+
+	repos, err := client.repositories("KS", 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos.Repositories.Items) != 1 || repos.Repositories.Items[0].FullName != "KS/devops" ||
+		repos.Repositories.Items[0].Private || repos.Repositories.NextPage != 3 {
+		t.Errorf("unexpected repositories %+v", repos.Repositories)
+	}
+}
+
+func TestSCMServers(t *testing.T) {
+	defer SetSCMServers([]string{SCMGitlab + "=" + defaultGitlabApiUrl})
+
+	if err := SetSCMServers([]string{"github=https://api.github.com"}); err == nil {
+		t.Error("expected error setting server of unsupported scm")
+	}
+	if err := SetSCMServers([]string{"gitea=ftp://git.example.com"}); err == nil {
+		t.Error("expected error setting server with invalid url")
+	}
+	if err := SetSCMServers([]string{"gitea=https://git.example.com/", "gitea=http://gitea.local"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		scm    string
+		apiUrl string
+		server string
+	}{
+		{SCMGitea, "", "https://git.example.com"},
+		{SCMGitea, "http://gitea.local/", "http://gitea.local"},
+		{SCMGitea, "https://attacker.example.com", ""},
+		{SCMGitlab, "", ""},
+	}
+	for i, test := range tests {
+		server, ok := scmServer(test.scm, test.apiUrl)
+		if server != test.server || ok != (test.server != "") {
+			t.Errorf("case %d: expected server %q of %s with %q, got %q %v", i, test.server, test.scm, test.apiUrl, server, ok)
+		}
+	}
+
+	if _, err := newProjectSCMClient(SCMGitea, "project", "credential", "https://attacker.example.com"); !isSCMStatus(err, http.StatusBadRequest) {
+		t.Errorf("expected bad request listing with an unconfigured server, got %v", err)
+	}
+}
+
+func isSCMStatus(err error, code int) bool {
+	svcErr, ok := err.(restful.ServiceError)
+	return ok && svcErr.Code == code
+}
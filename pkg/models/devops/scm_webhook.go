@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The KubeSphere Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devops
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/emicklei/go-restful"
+	"github.com/golang/glog"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// scms with webhooks signed by a secret
+const (
+	SCMGitlab          = "gitlab"
+	SCMBitbucketServer = "bitbucket-server"
+	SCMGitea           = "gitea"
+)
+
+// maxSCMWebhookSize is the limit of the payloads of webhooks, which are read into memory to verify the signatures
+const maxSCMWebhookSize = 5 << 20
+
+var (
+	scmWebhookSecretsLock sync.RWMutex
+	scmWebhookSecrets     = make(map[string]string)
+)
+
+// SetSCMWebhookSecrets sets the secrets of webhooks by scm, webhooks of scms without secrets are rejected
+func SetSCMWebhookSecrets(secrets map[string]string) {
+	scmWebhookSecretsLock.Lock()
+	defer scmWebhookSecretsLock.Unlock()
+	scmWebhookSecrets = make(map[string]string, len(secrets))
+	for scm, secret := range secrets {
+		scmWebhookSecrets[scm] = secret
+	}
+}
+
+func scmWebhookSecret(scm string) string {
+	scmWebhookSecretsLock.RLock()
+	defer scmWebhookSecretsLock.RUnlock()
+	return scmWebhookSecrets[scm]
+}
+
+// SCMWebhook verifies the signature of the webhook of the scm and forwards it to the hook of the branch source plugin
+// in jenkins, which scans the multi-branch pipelines of the repository of the event.
+func SCMWebhook(scm string, w http.ResponseWriter, req *http.Request) ([]byte, error) {
+	var hookUrl string
+	switch scm {
+	case SCMGitlab:
+		hookUrl = GitlabWebhookUrl
+	case SCMBitbucketServer:
+		hookUrl = BitbucketWebhookUrl
+	case SCMGitea:
+		hookUrl = GiteaWebhookUrl
+	default:
+		return nil, restful.NewError(http.StatusNotFound, fmt.Sprintf("unsupported scm %s", scm))
+	}
+
+	secret := scmWebhookSecret(scm)
+	if secret == "" {
+		err := fmt.Errorf("webhook secret of %s is not configured", scm)
+		glog.Warning(err)
+		return nil, restful.NewError(http.StatusForbidden, err.Error())
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxSCMWebhookSize))
+	if err != nil {
+		// the reader fails once the limit is read
+		if len(body) >= maxSCMWebhookSize {
+			return nil, restful.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("webhook payload exceeds %d bytes", maxSCMWebhookSize))
+		}
+		return nil, restful.NewError(http.StatusBadRequest, err.Error())
+	}
+	if err := verifySCMWebhook(scm, secret, req.Header, body); err != nil {
+		glog.Warningf("reject %s webhook: %+v", scm, err)
+		return nil, restful.NewError(http.StatusUnauthorized, err.Error())
+	}
+
+	// the request is not authenticated in ks, the hooks of the plugins are open to anonymous users
+	req.Header.Del("Authorization")
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	res, err := sendJenkinsRequest(jenkins.Server+hookUrl, req)
+	if err != nil {
+		glog.Errorf("forward %s webhook: %+v", scm, err)
+		if jkErr, ok := err.(*JkError); ok {
+			return nil, restful.NewError(jkErr.Code, jkErr.Message)
+		}
+		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
+	}
+	return res, nil
+}
+
+// verifySCMWebhook checks the token of gitlab webhooks and the hmac-sha256 signatures of the body of bitbucket server
+// and gitea webhooks
+func verifySCMWebhook(scm, secret string, header http.Header, body []byte) error {
+	switch scm {
+	case SCMGitlab:
+		token := header.Get("X-Gitlab-Token")
+		if token == "" {
+			return fmt.Errorf("missing X-Gitlab-Token")
+		}
+		if !hmac.Equal([]byte(token), []byte(secret)) {
+			return fmt.Errorf("invalid X-Gitlab-Token")
+		}
+		return nil
+	case SCMBitbucketServer:
+		signature := header.Get("X-Hub-Signature")
+		if !strings.HasPrefix(signature, "sha256=") {
+			return fmt.Errorf("missing X-Hub-Signature of sha256")
+		}
+		return verifyHmacSha256(secret, strings.TrimPrefix(signature, "sha256="), body)
+	case SCMGitea:
+		signature := header.Get("X-Gitea-Signature")
+		if signature == "" {
+			return fmt.Errorf("missing X-Gitea-Signature")
+		}
+		return verifyHmacSha256(secret, signature, body)
+	default:
+		return fmt.Errorf("unsupported scm %s", scm)
+	}
+}
+
+func verifyHmacSha256(secret, signature string, body []byte) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
@@ -56,4 +56,7 @@ const (
 	ToJsonUrl                = "/pipeline-model-converter/toJson"
 	GetNotifyCommitUrl       = "/git/notifyCommit/?"
 	GithubWebhookUrl         = "/github-webhook/"
+	GitlabWebhookUrl         = "/gitlab-webhook/post"
+	BitbucketWebhookUrl      = "/bitbucket-scmsource-hook/notify"
+	GiteaWebhookUrl          = "/gitea-webhook/post"
 )
@@ -0,0 +1,448 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package iam
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/emicklei/go-restful"
+	"github.com/gocraft/dbr"
+	"github.com/golang/glog"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/db"
+	"kubesphere.io/kubesphere/pkg/informers"
+	"kubesphere.io/kubesphere/pkg/models/devops"
+	"kubesphere.io/kubesphere/pkg/simple/client/devops_mysql"
+	"kubesphere.io/kubesphere/pkg/utils/k8sutil"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+const (
+	ScopeCluster   = "cluster"
+	ScopeWorkspace = "workspace"
+	ScopeNamespace = "namespace"
+	ScopeDevOps    = "devops"
+)
+
+// AccessAttributes describes the action to be reviewed, only one of
+// workspace, namespace and devops should be specified.
+type AccessAttributes struct {
+	Workspace      string `json:"workspace,omitempty" description:"workspace name, reviews the access in the workspace"`
+	Namespace      string `json:"namespace,omitempty" description:"namespace name, reviews the access in the namespace"`
+	DevOps         string `json:"devops,omitempty" description:"devops project id, reviews the access in the devops project"`
+	Verb           string `json:"verb" description:"kubernetes verb e.g. get, list, create, delete; devops actions like trigger are also allowed in devops projects"`
+	APIGroup       string `json:"apiGroup,omitempty" description:"api group of the resource, empty for core group"`
+	Resource       string `json:"resource,omitempty" description:"resource type e.g. deployments, for devops projects the rule name e.g. pipelines"`
+	Subresource    string `json:"subresource,omitempty" description:"subresource e.g. log"`
+	Name           string `json:"name,omitempty" description:"name of the resource, empty means all resources"`
+	NonResourceURL string `json:"nonResourceURL,omitempty" description:"non resource url e.g. /healthz"`
+}
+
+type AccessReviewSpec struct {
+	AccessAttributes `json:",inline"`
+	User             string   `json:"user,omitempty" description:"username, the current user is reviewed if empty"`
+	Groups           []string `json:"groups,omitempty" description:"groups of the user, the groups of the current user are reviewed if the user is empty"`
+}
+
+type AccessReviewStatus struct {
+	Allowed bool `json:"allowed" description:"whether the user is allowed to perform the action"`
+	// Grants lists the roles that allow the action
+	Grants []SubjectAccess `json:"grants,omitempty" description:"roles that allow the action"`
+}
+
+type AccessReview struct {
+	Spec   AccessReviewSpec    `json:"spec"`
+	Status *AccessReviewStatus `json:"status,omitempty"`
+}
+
+// SubjectAccess describes a role granted to a user or a group that allows the reviewed action
+type SubjectAccess struct {
+	Username    string `json:"username,omitempty" description:"username, service accounts are named system:serviceaccount:<namespace>:<name>"`
+	Group       string `json:"group,omitempty" description:"group of the users, username is empty if the role is granted to the group"`
+	Scope       string `json:"scope" description:"scope of the role, one of cluster, workspace, namespace and devops"`
+	Workspace   string `json:"workspace,omitempty" description:"workspace of the workspace role"`
+	Namespace   string `json:"namespace,omitempty" description:"namespace of the role binding"`
+	DevOps      string `json:"devops,omitempty" description:"devops project id"`
+	Role        string `json:"role" description:"name of the role that allows the action"`
+	RoleBinding string `json:"role_binding,omitempty" description:"name of the role binding"`
+}
+
+// grant is a role bound to a user
+type grant struct {
+	SubjectAccess
+	rules []rbacv1.PolicyRule
+}
+
+// ReviewAccess checks whether the user is allowed to perform the action, roles granted to the groups of the user are included
+func ReviewAccess(spec AccessReviewSpec) (*AccessReviewStatus, error) {
+	grants, err := matchingGrants(spec.AccessAttributes, reviewedUser(spec.User, spec.Groups))
+
+	if err != nil {
+		return nil, err
+	}
+
+	status := &AccessReviewStatus{Grants: grants}
+
+	for _, grant := range grants {
+		// in a workspace only the roles that apply to the whole workspace allow the action,
+		// roles of the namespaces in the workspace are listed for reference
+		if spec.Workspace == "" || grant.Scope != ScopeNamespace {
+			status.Allowed = true
+			break
+		}
+	}
+
+	return status, nil
+}
+
+// WhoCan lists the users and groups that are allowed to perform the action and the roles allowing it
+func WhoCan(attrs AccessAttributes) ([]SubjectAccess, error) {
+	return matchingGrants(attrs, nil)
+}
+
+// reviewedUser returns the user with the groups every authenticated user or service account belongs to
+// CanReviewAccess returns whether the requester can review the access of the user, users can always review
+// their own access, reviewing other users requires the permission to get them
+func CanReviewAccess(requester string, requesterGroups []string, username string) (bool, error) {
+	if username == "" || username == requester {
+		return true, nil
+	}
+
+	status, err := ReviewAccess(AccessReviewSpec{
+		AccessAttributes: AccessAttributes{Verb: "get", APIGroup: "iam.kubesphere.io", Resource: "users", Name: username},
+		User:             requester,
+		Groups:           requesterGroups,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return status.Allowed, nil
+}
+
+func reviewedUser(username string, groups []string) user.Info {
+	u := &user.DefaultInfo{Name: username, Groups: []string{user.AllAuthenticated}}
+
+	if namespace, _, err := serviceaccount.SplitUsername(username); err == nil {
+		u.Groups = append(u.Groups, serviceaccount.MakeGroupNames(namespace)...)
+	}
+
+	for _, group := range groups {
+		if group != "" && !sliceutil.HasString(u.Groups, group) {
+			u.Groups = append(u.Groups, group)
+		}
+	}
+
+	return u
+}
+
+// subjectAccess returns the user or the group of the subject, it's false if the subject doesn't include the user,
+// all subjects are included if u is nil. Service accounts without namespaces are in the namespace of the binding.
+func subjectAccess(subject rbacv1.Subject, bindingNamespace string, u user.Info) (SubjectAccess, bool) {
+	switch subject.Kind {
+	case rbacv1.UserKind:
+		return SubjectAccess{Username: subject.Name}, u == nil || u.GetName() == subject.Name
+	case rbacv1.GroupKind:
+		return SubjectAccess{Group: subject.Name}, u == nil || sliceutil.HasString(u.GetGroups(), subject.Name)
+	case rbacv1.ServiceAccountKind:
+		namespace := subject.Namespace
+		if namespace == "" {
+			namespace = bindingNamespace
+		}
+		if namespace == "" {
+			return SubjectAccess{}, false
+		}
+		username := serviceaccount.MakeUsername(namespace, subject.Name)
+		return SubjectAccess{Username: username}, u == nil || u.GetName() == username
+	default:
+		return SubjectAccess{}, false
+	}
+}
+
+func matchingGrants(attrs AccessAttributes, u user.Info) ([]SubjectAccess, error) {
+	if err := validateAccessAttributes(attrs); err != nil {
+		return nil, err
+	}
+
+	var grants []grant
+	var err error
+
+	if attrs.DevOps != "" {
+		// members of devops projects are users
+		username := ""
+		if u != nil {
+			username = u.GetName()
+		}
+		grants, err = devopsGrants(attrs.DevOps, username)
+	} else {
+		grants, err = clusterGrants(u)
+		if err == nil && attrs.Namespace != "" {
+			var namespaced []grant
+			namespaced, err = namespaceGrants(attrs.Namespace, u)
+			grants = append(grants, namespaced...)
+		}
+		if err == nil && attrs.Workspace != "" {
+			var namespaced []grant
+			namespaced, err = workspaceNamespaceGrants(attrs.Workspace, u)
+			grants = append(grants, namespaced...)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]SubjectAccess, 0)
+
+	for _, grant := range grants {
+		if grantAllows(grant, attrs) {
+			result = append(result, grant.SubjectAccess)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Username != result[j].Username {
+			return result[i].Username < result[j].Username
+		}
+		return result[i].Group < result[j].Group
+	})
+
+	return result, nil
+}
+
+func validateAccessAttributes(attrs AccessAttributes) error {
+	scopes := 0
+	for _, scope := range []string{attrs.Workspace, attrs.Namespace, attrs.DevOps} {
+		if scope != "" {
+			scopes++
+		}
+	}
+
+	if scopes > 1 {
+		return restful.NewError(http.StatusBadRequest, "only one of workspace, namespace and devops can be specified")
+	}
+
+	if attrs.Verb == "" {
+		return restful.NewError(http.StatusBadRequest, "verb must be specified")
+	}
+
+	if attrs.Resource == "" && attrs.NonResourceURL == "" {
+		return restful.NewError(http.StatusBadRequest, "resource or nonResourceURL must be specified")
+	}
+
+	if attrs.NonResourceURL != "" && (attrs.Namespace != "" || attrs.Workspace != "" || attrs.DevOps != "") {
+		return restful.NewError(http.StatusBadRequest, "nonResourceURL can only be reviewed in cluster scope")
+	}
+
+	return nil
+}
+
+func grantAllows(grant grant, attrs AccessAttributes) bool {
+	for _, rule := range grant.rules {
+		if attrs.NonResourceURL != "" {
+			if ruleMatchesRequest(rule, "", attrs.NonResourceURL, "", "", "", attrs.Verb) {
+				return true
+			}
+		} else if ruleMatchesRequest(rule, attrs.APIGroup, "", attrs.Resource, attrs.Subresource, attrs.Name, attrs.Verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterGrants returns the cluster roles and workspace roles bound to the user,
+// all users and groups are returned if u is nil
+func clusterGrants(u user.Info) ([]grant, error) {
+	clusterRoleBindings, err := informers.SharedInformerFactory().Rbac().V1().ClusterRoleBindings().Lister().List(labels.Everything())
+
+	if err != nil {
+		glog.Errorln("get cluster role bindings", err)
+		return nil, err
+	}
+
+	grants := make([]grant, 0)
+
+	for _, clusterRoleBinding := range clusterRoleBindings {
+		rules, err := roleRefRules("", clusterRoleBinding.RoleRef)
+
+		if err != nil {
+			return nil, err
+		}
+
+		scope := ScopeCluster
+		workspace := k8sutil.GetControlledWorkspace(clusterRoleBinding.OwnerReferences)
+
+		if workspace != "" {
+			scope = ScopeWorkspace
+		}
+
+		for _, subject := range clusterRoleBinding.Subjects {
+			if access, ok := subjectAccess(subject, "", u); ok {
+				access.Scope, access.Workspace, access.Role, access.RoleBinding = scope, workspace, clusterRoleBinding.RoleRef.Name, clusterRoleBinding.Name
+				grants = append(grants, grant{SubjectAccess: access, rules: rules})
+			}
+		}
+	}
+
+	return grants, nil
+}
+
+func namespaceGrants(namespace string, u user.Info) ([]grant, error) {
+	roleBindings, err := GetRoleBindings(namespace, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]grant, 0)
+
+	for _, roleBinding := range roleBindings {
+		rules, err := roleRefRules(namespace, roleBinding.RoleRef)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, subject := range roleBinding.Subjects {
+			if access, ok := subjectAccess(subject, namespace, u); ok {
+				access.Scope, access.Namespace, access.Role, access.RoleBinding = ScopeNamespace, namespace, roleBinding.RoleRef.Name, roleBinding.Name
+				grants = append(grants, grant{SubjectAccess: access, rules: rules})
+			}
+		}
+	}
+
+	return grants, nil
+}
+
+func workspaceNamespaceGrants(workspace string, u user.Info) ([]grant, error) {
+	namespaces, err := informers.SharedInformerFactory().Core().V1().Namespaces().Lister().
+		List(labels.SelectorFromSet(labels.Set{constants.WorkspaceLabelKey: workspace}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]grant, 0)
+
+	for _, namespace := range namespaces {
+		namespaced, err := namespaceGrants(namespace.Name, u)
+
+		if err != nil {
+			return nil, err
+		}
+
+		grants = append(grants, namespaced...)
+	}
+
+	return grants, nil
+}
+
+func devopsGrants(projectId, username string) ([]grant, error) {
+	memberships := make([]*devops.DevOpsProjectMembership, 0)
+
+	conditions := []dbr.Builder{db.Eq(devops.DevOpsProjectMembershipProjectIdColumn, projectId)}
+
+	if username != "" {
+		conditions = append(conditions, db.Eq(devops.DevOpsProjectMembershipUsernameColumn, username))
+	}
+
+	_, err := devops_mysql.OpenDatabase().Select(devops.DevOpsProjectMembershipColumns...).
+		From(devops.DevOpsProjectMembershipTableName).
+		Where(db.And(conditions...)).
+		Load(&memberships)
+
+	if err != nil && err != dbr.ErrNotFound {
+		glog.Errorf("%+v", err)
+		return nil, err
+	}
+
+	if username == devops.KS_ADMIN && len(memberships) == 0 {
+		memberships = append(memberships, &devops.DevOpsProjectMembership{Username: username, ProjectId: projectId, Role: devops.ProjectOwner})
+	}
+
+	grants := make([]grant, 0)
+
+	for _, membership := range memberships {
+		grants = append(grants, grant{
+			SubjectAccess: SubjectAccess{Username: membership.Username, Scope: ScopeDevOps, DevOps: projectId, Role: membership.Role},
+			rules:         devopsRoleRules(membership.Role),
+		})
+	}
+
+	return grants, nil
+}
+
+// devopsRoleRules converts the simple rules of devops roles to policy rules, the
+// actions are available as verbs together with the equivalent kubernetes verbs
+func devopsRoleRules(role string) []rbacv1.PolicyRule {
+	rules := make([]rbacv1.PolicyRule, 0)
+
+	for _, simpleRule := range GetDevopsRoleSimpleRules(role) {
+		verbs := make([]string, 0)
+		for _, action := range simpleRule.Actions {
+			verbs = append(verbs, action)
+			verbs = append(verbs, devopsActionVerbs[action]...)
+		}
+		rules = append(rules, rbacv1.PolicyRule{Verbs: verbs, APIGroups: []string{rbacv1.APIGroupAll}, Resources: []string{simpleRule.Name, simpleRule.Name + "/*"}})
+	}
+
+	return rules
+}
+
+var devopsActionVerbs = map[string][]string{
+	"view":   {"get", "list", "watch"},
+	"create": {"create"},
+	"edit":   {"update", "patch"},
+	"delete": {"delete", "deletecollection"},
+}
+
+func roleRefRules(namespace string, roleRef rbacv1.RoleRef) ([]rbacv1.PolicyRule, error) {
+	if roleRef.Kind == ClusterRoleKind {
+		clusterRole, err := informers.SharedInformerFactory().Rbac().V1().ClusterRoles().Lister().Get(roleRef.Name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return clusterRole.Rules, nil
+	}
+
+	if namespace == "" {
+		return nil, fmt.Errorf("role %s must be namespaced", roleRef.Name)
+	}
+
+	role, err := informers.SharedInformerFactory().Rbac().V1().Roles().Lister().Roles(namespace).Get(roleRef.Name)
+
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return role.Rules, nil
+}
@@ -0,0 +1,187 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package iam
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kubesphere.io/kubesphere/pkg/informers"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: http://127.0.0.1:1
+contexts:
+- name: test
+  context:
+    cluster: test
+current-context: test
+`
+
+// setupRBAC adds the roles and bindings to the stores of the shared informers, the informers are not started
+func setupRBAC(t *testing.T, objects ...interface{}) {
+	file, err := ioutil.TempFile("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(testKubeConfig); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	flags := flag.NewFlagSet("k8s", flag.ContinueOnError)
+	k8s.AddFlags(flags)
+	if err := flags.Set("kubeconfig", file.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	rbac := informers.SharedInformerFactory().Rbac().V1()
+
+	for _, obj := range objects {
+		switch obj := obj.(type) {
+		case *rbacv1.ClusterRole:
+			rbac.ClusterRoles().Informer().GetIndexer().Add(obj)
+		case *rbacv1.ClusterRoleBinding:
+			rbac.ClusterRoleBindings().Informer().GetIndexer().Add(obj)
+		case *rbacv1.Role:
+			rbac.Roles().Informer().GetIndexer().Add(obj)
+		case *rbacv1.RoleBinding:
+			rbac.RoleBindings().Informer().GetIndexer().Add(obj)
+		}
+	}
+}
+
+func TestReviewAccessSubjects(t *testing.T) {
+	setupRBAC(t,
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployment-viewer"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"get", "list"}, APIGroups: []string{"apps"}, Resources: []string{"deployments"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployment-viewers"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: ClusterRoleKind, Name: "deployment-viewer"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.UserKind, Name: "alice"},
+				{Kind: rbacv1.GroupKind, Name: "viewers"},
+				{Kind: rbacv1.ServiceAccountKind, Name: "monitor", Namespace: "kube-system"},
+			},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-editor", Namespace: "demo"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"delete"}, APIGroups: []string{""}, Resources: []string{"pods"}}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-editors", Namespace: "demo"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "pod-editor"},
+			Subjects: []rbacv1.Subject{
+				// service accounts without namespaces are in the namespace of the binding
+				{Kind: rbacv1.ServiceAccountKind, Name: "builder"},
+				{Kind: rbacv1.GroupKind, Name: "system:serviceaccounts:ci"},
+			},
+		},
+	)
+
+	listDeployments := AccessAttributes{Verb: "list", APIGroup: "apps", Resource: "deployments"}
+	deletePods := AccessAttributes{Namespace: "demo", Verb: "delete", Resource: "pods"}
+
+	tests := []struct {
+		name    string
+		spec    AccessReviewSpec
+		allowed bool
+	}{
+		{"user", AccessReviewSpec{AccessAttributes: listDeployments, User: "alice"}, true},
+		{"group of the user", AccessReviewSpec{AccessAttributes: listDeployments, User: "bob", Groups: []string{"viewers"}}, true},
+		{"user not in the group", AccessReviewSpec{AccessAttributes: listDeployments, User: "bob", Groups: []string{"developers"}}, false},
+		{"group named as the user", AccessReviewSpec{AccessAttributes: listDeployments, User: "viewers"}, false},
+		{"service account", AccessReviewSpec{AccessAttributes: listDeployments, User: "system:serviceaccount:kube-system:monitor"}, true},
+		{"service account in other namespace", AccessReviewSpec{AccessAttributes: listDeployments, User: "system:serviceaccount:default:monitor"}, false},
+		{"service account in the namespace of the binding", AccessReviewSpec{AccessAttributes: deletePods, User: "system:serviceaccount:demo:builder"}, true},
+		{"service accounts group", AccessReviewSpec{AccessAttributes: deletePods, User: "system:serviceaccount:ci:runner"}, true},
+		{"user named as the service account", AccessReviewSpec{AccessAttributes: deletePods, User: "builder"}, false},
+	}
+
+	for _, test := range tests {
+		status, err := ReviewAccess(test.spec)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.allowed, status.Allowed, test.name)
+	}
+
+	subjects, err := WhoCan(listDeployments)
+	assert.NoError(t, err)
+	assert.Len(t, subjects, 3)
+	assert.Equal(t, SubjectAccess{Group: "viewers", Scope: ScopeCluster, Role: "deployment-viewer", RoleBinding: "deployment-viewers"}, subjects[0])
+	assert.Equal(t, "alice", subjects[1].Username)
+	assert.Equal(t, "system:serviceaccount:kube-system:monitor", subjects[2].Username)
+
+	subjects, err = WhoCan(deletePods)
+	assert.NoError(t, err)
+	assert.Len(t, subjects, 2)
+	assert.Equal(t, "system:serviceaccount:demo:builder", subjects[1].Username)
+	assert.Equal(t, "system:serviceaccounts:ci", subjects[0].Group)
+}
+
+func TestCanReviewAccess(t *testing.T) {
+	setupRBAC(t,
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "users-viewer"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"get", "list"}, APIGroups: []string{"iam.kubesphere.io"}, Resources: []string{"users"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "users-viewers"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: ClusterRoleKind, Name: "users-viewer"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.UserKind, Name: "admin"},
+				{Kind: rbacv1.GroupKind, Name: "auditors"},
+			},
+		},
+	)
+
+	tests := []struct {
+		name            string
+		requester       string
+		requesterGroups []string
+		user            string
+		allowed         bool
+	}{
+		{"current user", "carol", nil, "", true},
+		{"self review", "carol", nil, "carol", true},
+		{"other user", "carol", nil, "alice", false},
+		{"other user by a user allowed to get users", "admin", nil, "alice", true},
+		{"other user by a group allowed to get users", "dave", []string{"auditors"}, "alice", true},
+	}
+
+	for _, test := range tests {
+		allowed, err := CanReviewAccess(test.requester, test.requesterGroups, test.user)
+		if assert.NoError(t, err, test.name) {
+			assert.Equal(t, test.allowed, allowed, test.name)
+		}
+	}
+}
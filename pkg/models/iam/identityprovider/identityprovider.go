@@ -0,0 +1,98 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package identityprovider
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// reservedGroupPrefix is the prefix of groups assigned by kubernetes, e.g. system:masters,
+// which must never be asserted by an identity provider
+const reservedGroupPrefix = "system:"
+
+// Identity is the user information asserted by an external identity provider
+// after a successful authentication.
+type Identity struct {
+	// Subject identifies the user in the provider, it never changes while the username may be reassigned
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// Interface is implemented by every external identity provider that ks-iam can
+// delegate authentication to, e.g. OIDC.
+type Interface interface {
+	// Name identifies the provider in the login URL, e.g. /login/{idp}
+	Name() string
+	// AuthCodeURL returns the URL the user agent should be redirected to for authentication,
+	// the nonce is bound to the login and must be asserted by the provider
+	AuthCodeURL(state, nonce string) string
+	// Exchange redeems the authorization code and returns the authenticated identity of the login with the nonce
+	Exchange(code, nonce string) (*Identity, error)
+}
+
+var (
+	mutex     sync.RWMutex
+	providers = make(map[string]Interface)
+)
+
+func Register(provider Interface) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	providers[provider.Name()] = provider
+}
+
+func Get(name string) (Interface, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if provider, ok := providers[name]; ok {
+		return provider, nil
+	}
+	return nil, fmt.Errorf("identity provider %s not found", name)
+}
+
+func List() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// GroupPrefix returns the prefix of the groups asserted by the identity provider
+func GroupPrefix(idp string) string {
+	return fmt.Sprintf("oidc:%s:", idp)
+}
+
+// Groups namespaces the groups asserted by the identity provider, so that they can only match
+// RBAC Group subjects bound to the provider, e.g. oidc:<idp>:dev. Reserved system: groups are dropped.
+func Groups(idp string, groups []string) []string {
+	result := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if group == "" || strings.HasPrefix(group, reservedGroupPrefix) {
+			continue
+		}
+		result = append(result, GroupPrefix(idp)+group)
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package identityprovider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	tests := []struct {
+		groups   []string
+		expected []string
+	}{
+		{nil, []string{}},
+		{[]string{"dev", "ops"}, []string{"oidc:corp:dev", "oidc:corp:ops"}},
+		// groups named like kubernetes groups or existing bindings never match them
+		{[]string{"system:masters", "system:authenticated", ""}, []string{}},
+		{[]string{"cluster-admin", "workspaces-manager"}, []string{"oidc:corp:cluster-admin", "oidc:corp:workspaces-manager"}},
+	}
+
+	for _, test := range tests {
+		if got := Groups("corp", test.groups); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("groups %v: expected %v, got %v", test.groups, test.expected, got)
+		}
+	}
+}
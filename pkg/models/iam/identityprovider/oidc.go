@@ -0,0 +1,314 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package identityprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/glog"
+	"golang.org/x/oauth2"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	discoveryPath       = "/.well-known/openid-configuration"
+	defaultHTTPTimeout  = 10 * time.Second
+	jwksRefreshInterval = time.Minute
+)
+
+type OIDCOptions struct {
+	Name          string
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string
+	EmailClaim    string
+	GroupsClaim   string
+}
+
+// discovery is the subset of the OpenID Provider Metadata used by ks-iam
+// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcProvider struct {
+	options    OIDCOptions
+	discovery  discovery
+	config     oauth2.Config
+	httpClient *http.Client
+
+	mutex       sync.RWMutex
+	keySet      jose.JSONWebKeySet
+	lastRefresh time.Time
+}
+
+func NewOIDCProvider(options OIDCOptions) (Interface, error) {
+	if options.Issuer == "" || options.ClientID == "" {
+		return nil, fmt.Errorf("oidc issuer and client id must be specified")
+	}
+	if options.Name == "" {
+		options.Name = "oidc"
+	}
+	if options.UsernameClaim == "" {
+		options.UsernameClaim = "preferred_username"
+	}
+	if options.EmailClaim == "" {
+		options.EmailClaim = "email"
+	}
+	if len(options.Scopes) == 0 {
+		options.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	p := &oidcProvider{options: options, httpClient: &http.Client{Timeout: defaultHTTPTimeout}}
+
+	if err := p.getJSON(strings.TrimSuffix(options.Issuer, "/")+discoveryPath, &p.discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %s", err)
+	}
+
+	if strings.TrimSuffix(p.discovery.Issuer, "/") != strings.TrimSuffix(options.Issuer, "/") {
+		return nil, fmt.Errorf("oidc issuer mismatch, expected %s but got %s", options.Issuer, p.discovery.Issuer)
+	}
+
+	p.config = oauth2.Config{
+		ClientID:     options.ClientID,
+		ClientSecret: options.ClientSecret,
+		RedirectURL:  options.RedirectURL,
+		Scopes:       options.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.discovery.AuthorizationEndpoint,
+			TokenURL: p.discovery.TokenEndpoint,
+		},
+	}
+
+	if err := p.refreshKeySet(); err != nil {
+		glog.Warningf("fetch oidc jwks from %s failed: %s", p.discovery.JWKSURI, err)
+	}
+
+	return p, nil
+}
+
+func (p *oidcProvider) Name() string {
+	return p.options.Name
+}
+
+func (p *oidcProvider) AuthCodeURL(state, nonce string) string {
+	return p.config.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce))
+}
+
+func (p *oidcProvider) Exchange(code, nonce string) (*Identity, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, p.httpClient)
+
+	token, err := p.config.Exchange(ctx, code)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("id_token not found in token response")
+	}
+
+	claims, err := p.verify(rawIDToken)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the id token must be issued for this login, otherwise it may be replayed from another one
+	if tokenNonce, _ := claims["nonce"].(string); nonce == "" || tokenNonce != nonce {
+		return nil, fmt.Errorf("invalid id token nonce")
+	}
+
+	return p.identity(claims)
+}
+
+// verify checks the signature of the id token against the provider's JWKS
+// and validates the standard iss, aud and exp claims.
+func (p *oidcProvider) verify(rawIDToken string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, p.provideKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(p.discovery.Issuer, true) {
+		return nil, fmt.Errorf("invalid id token issuer %v", claims["iss"])
+	}
+
+	if !containsAudience(claims["aud"], p.options.ClientID) {
+		return nil, fmt.Errorf("invalid id token audience %v", claims["aud"])
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("id token is expired")
+	}
+
+	return claims, nil
+}
+
+func (p *oidcProvider) provideKey(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA, *jwt.SigningMethodRSAPSS:
+	default:
+		return nil, fmt.Errorf("unexpected id token signing method %v", token.Header["alg"])
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+
+	// the provider may have rotated its signing keys
+	if err := p.refreshKeySet(); err != nil {
+		return nil, err
+	}
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("signing key %s not found", kid)
+}
+
+func (p *oidcProvider) lookupKey(kid string) interface{} {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if kid == "" {
+		if len(p.keySet.Keys) == 1 {
+			return p.keySet.Keys[0].Key
+		}
+		return nil
+	}
+
+	for _, key := range p.keySet.Key(kid) {
+		if key.Use == "" || key.Use == "sig" {
+			return key.Key
+		}
+	}
+
+	return nil
+}
+
+func (p *oidcProvider) refreshKeySet() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if time.Since(p.lastRefresh) < jwksRefreshInterval && len(p.keySet.Keys) > 0 {
+		return nil
+	}
+
+	var keySet jose.JSONWebKeySet
+
+	if err := p.getJSON(p.discovery.JWKSURI, &keySet); err != nil {
+		return err
+	}
+
+	p.keySet = keySet
+	p.lastRefresh = time.Now()
+
+	return nil
+}
+
+func (p *oidcProvider) identity(claims jwt.MapClaims) (*Identity, error) {
+	subject, _ := claims["sub"].(string)
+
+	if subject == "" {
+		return nil, fmt.Errorf("claim sub not found in id token")
+	}
+
+	username, _ := claims[p.options.UsernameClaim].(string)
+
+	if username == "" {
+		return nil, fmt.Errorf("claim %s not found in id token", p.options.UsernameClaim)
+	}
+
+	email, _ := claims[p.options.EmailClaim].(string)
+
+	identity := &Identity{Subject: subject, Username: username, Email: email}
+
+	if p.options.GroupsClaim != "" {
+		switch groups := claims[p.options.GroupsClaim].(type) {
+		case string:
+			identity.Groups = []string{groups}
+		case []interface{}:
+			for _, group := range groups {
+				if g, ok := group.(string); ok {
+					identity.Groups = append(identity.Groups, g)
+				}
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *oidcProvider) getJSON(url string, v interface{}) error {
+	resp, err := p.httpClient.Get(url)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s %s", url, resp.Status, data)
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func containsAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, item := range aud {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
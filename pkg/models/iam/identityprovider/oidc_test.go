@@ -0,0 +1,189 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package identityprovider
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	testClientID = "kubesphere"
+	testKeyID    = "test-key"
+	testNonce    = "test-nonce"
+)
+
+type mockOIDCServer struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockOIDCServer{key: key}
+	mux := http.NewServeMux()
+	m.Server = httptest.NewServer(mux)
+
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discovery{
+			Issuer:                m.URL,
+			AuthorizationEndpoint: m.URL + "/authorize",
+			TokenEndpoint:         m.URL + "/token",
+			JWKSURI:               m.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &m.key.PublicKey, KeyID: testKeyID, Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "valid-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, m.claims)
+		token.Header["kid"] = testKeyID
+		idToken, err := token.SignedString(m.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+
+	m.claims = jwt.MapClaims{
+		"iss":                m.URL,
+		"aud":                testClientID,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"sub":                "248289761001",
+		"nonce":              testNonce,
+		"preferred_username": "jdoe",
+		"email":              "jdoe@example.com",
+		"groups":             []string{"dev", "ops"},
+	}
+
+	return m
+}
+
+func TestOIDCProvider(t *testing.T) {
+	server := newMockOIDCServer(t)
+	defer server.Close()
+
+	provider, err := NewOIDCProvider(OIDCOptions{
+		Issuer:       server.URL,
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://ks-console/callback",
+		GroupsClaim:  "groups",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authCodeURL, err := url.Parse(provider.AuthCodeURL("xyz", testNonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authCodeURL.Path != "/authorize" || authCodeURL.Query().Get("state") != "xyz" || authCodeURL.Query().Get("client_id") != testClientID ||
+		authCodeURL.Query().Get("nonce") != testNonce {
+		t.Errorf("unexpected auth code url %s", authCodeURL)
+	}
+
+	identity, err := provider.Exchange("valid-code", testNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Identity{Subject: "248289761001", Username: "jdoe", Email: "jdoe@example.com", Groups: []string{"dev", "ops"}}
+	if !reflect.DeepEqual(identity, expected) {
+		t.Errorf("expected %+v, got %+v", expected, identity)
+	}
+
+	if _, err := provider.Exchange("invalid-code", testNonce); err == nil {
+		t.Error("invalid code should be rejected")
+	}
+
+	if _, err := provider.Exchange("valid-code", "another-nonce"); err == nil {
+		t.Error("id token of another login should be rejected")
+	}
+}
+
+func TestOIDCProviderRejectsInvalidIDToken(t *testing.T) {
+	server := newMockOIDCServer(t)
+	defer server.Close()
+
+	provider, err := NewOIDCProvider(OIDCOptions{Issuer: server.URL, ClientID: testClientID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"audience", jwt.MapClaims{"aud": "another-client"}},
+		{"issuer", jwt.MapClaims{"iss": "http://evil.example.com"}},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{"username", jwt.MapClaims{"preferred_username": nil}},
+		{"subject", jwt.MapClaims{"sub": nil}},
+		{"nonce", jwt.MapClaims{"nonce": nil}},
+	}
+
+	origin := server.claims
+
+	for _, test := range tests {
+		claims := jwt.MapClaims{}
+		for k, v := range origin {
+			claims[k] = v
+		}
+		for k, v := range test.claims {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		server.claims = claims
+
+		if _, err := provider.Exchange("valid-code", testNonce); err == nil {
+			t.Errorf("id token with invalid %s should be rejected", test.name)
+		}
+	}
+}
@@ -43,7 +43,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/go-ldap/ldap"
 	"github.com/golang/glog"
 	"k8s.io/api/rbac/v1"
@@ -52,16 +51,16 @@ import (
 	ldapclient "kubesphere.io/kubesphere/pkg/simple/client/ldap"
 
 	"kubesphere.io/kubesphere/pkg/models"
-	"kubesphere.io/kubesphere/pkg/utils/jwtutil"
 )
 
 var (
-	adminEmail       string
-	adminPassword    string
-	tokenExpireTime  time.Duration
-	maxAuthFailed    int
-	authTimeInterval time.Duration
-	initUsers        []initUser
+	adminEmail             string
+	adminPassword          string
+	tokenExpireTime        time.Duration
+	refreshTokenExpireTime time.Duration
+	maxAuthFailed          int
+	authTimeInterval       time.Duration
+	initUsers              []initUser
 )
 
 type initUser struct {
@@ -76,10 +75,11 @@ const (
 	defaultAuthTimeInterval = 30 * time.Minute
 )
 
-func Init(email, password string, expireTime time.Duration, authRateLimit string) error {
+func Init(email, password string, expireTime, refreshExpireTime time.Duration, authRateLimit string) error {
 	adminEmail = email
 	adminPassword = password
 	tokenExpireTime = expireTime
+	refreshTokenExpireTime = refreshExpireTime
 	maxAuthFailed, authTimeInterval = parseAuthRateLimit(authRateLimit)
 	conn, err := ldapclient.Client()
 
@@ -283,17 +283,15 @@ func Login(username string, password string, ip string) (*models.Token, error) {
 		return nil, err
 	}
 
-	claims := jwt.MapClaims{}
+	token, err := issueToken(uid, email, nil)
 
-	claims["exp"] = time.Now().Add(tokenExpireTime).Unix()
-	claims["username"] = uid
-	claims["email"] = email
-
-	token := jwtutil.MustSigned(claims)
+	if err != nil {
+		return nil, err
+	}
 
 	loginLog(uid, ip)
 
-	return &models.Token{Token: token}, nil
+	return token, nil
 }
 
 func loginLog(uid, ip string) {
@@ -581,6 +579,10 @@ func DeleteUser(username string) error {
 		return err
 	}
 
+	if err = RevokeUserTokens(username); err != nil {
+		glog.Errorln("revoke user tokens failed", username, err)
+	}
+
 	if err = deleteRoleBindings(username); err != nil {
 		glog.Errorln("delete user role bindings failed", username, err)
 	}
@@ -726,6 +728,11 @@ func UserCreateCheck(check string) (exist bool, err error) {
 }
 
 func CreateUser(user *models.User) (*models.User, error) {
+	return createUser(user, nil)
+}
+
+// createUser creates the user with extra ldap attributes, e.g. the link to an identity provider
+func createUser(user *models.User, attributes map[string][]string) (*models.User, error) {
 	user.Username = strings.TrimSpace(user.Username)
 	user.Email = strings.TrimSpace(user.Email)
 	user.Password = strings.TrimSpace(user.Password)
@@ -783,6 +790,9 @@ func CreateUser(user *models.User) (*models.User, error) {
 	if user.Description != "" {
 		userCreateRequest.Attribute("description", []string{user.Description}) // RFC4519: descriptive information
 	}
+	for name, values := range attributes {
+		userCreateRequest.Attribute(name, values)
+	}
 
 	err = conn.Add(userCreateRequest)
 
@@ -920,6 +930,10 @@ func UpdateUser(user *models.User) (*models.User, error) {
 
 	// clear auth failed record
 	if user.Password != "" {
+		if err := RevokeUserTokens(user.Username); err != nil {
+			glog.Errorln("revoke user tokens failed", user.Username, err)
+		}
+
 		redisClient := redis.Client()
 
 		records, err := redisClient.Keys(fmt.Sprintf("kubesphere:authfailed:%s:*", user.Username)).Result()
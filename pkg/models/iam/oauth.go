@@ -0,0 +1,251 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package iam
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/go-ldap/ldap"
+	goredis "github.com/go-redis/redis"
+	"github.com/golang/glog"
+	"kubesphere.io/kubesphere/pkg/models"
+	"kubesphere.io/kubesphere/pkg/models/iam/identityprovider"
+	ldapclient "kubesphere.io/kubesphere/pkg/simple/client/ldap"
+	"kubesphere.io/kubesphere/pkg/simple/client/redis"
+)
+
+const (
+	oauthStateKey      = "kubesphere:oauth:state:%s"
+	oauthStateLifetime = 5 * time.Minute
+	usernameRegex      = "^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	emailRegex         = "^[a-z0-9]+([._\\-]*[a-z0-9])*@([a-z0-9]+[-a-z0-9]*[a-z0-9]+.){1,63}[a-z0-9]+$"
+
+	// users provisioned by identity providers are linked to the subject by the labeledURI attribute of inetOrgPerson,
+	// in the form of idp:<idp>:<subject>
+	identityLinkAttribute = "labeledURI"
+)
+
+// oauthState is stored in redis by the state of the login
+type oauthState struct {
+	IdP   string `json:"idp"`
+	Nonce string `json:"nonce"`
+}
+
+// OAuthAuthorizeURL returns the URL of the identity provider login page,
+// the state is stored in redis to prevent CSRF in the callback.
+func OAuthAuthorizeURL(idp string) (string, error) {
+	provider, err := identityprovider.Get(idp)
+
+	if err != nil {
+		return "", restful.NewError(http.StatusNotFound, err.Error())
+	}
+
+	state, err := randomString(16)
+
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := randomString(16)
+
+	if err != nil {
+		return "", err
+	}
+
+	value, err := json.Marshal(oauthState{IdP: idp, Nonce: nonce})
+
+	if err != nil {
+		return "", err
+	}
+
+	err = redis.Client().Set(fmt.Sprintf(oauthStateKey, state), value, oauthStateLifetime).Err()
+
+	if err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(state, nonce), nil
+}
+
+// consumeOAuthState gets and deletes the state in a MULTI/EXEC transaction so that it can't be used by
+// concurrent callbacks
+func consumeOAuthState(state string) (string, error) {
+	key := fmt.Sprintf(oauthStateKey, state)
+
+	var value *goredis.StringCmd
+
+	_, err := redis.Client().TxPipelined(func(pipe goredis.Pipeliner) error {
+		value = pipe.Get(key)
+		pipe.Del(key)
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return value.Val(), nil
+}
+
+// OAuthLogin exchanges the authorization code with the identity provider,
+// creates the user on first login and issues a KubeSphere token. Only users
+// provisioned by the provider are logged in, local users are never taken over.
+func OAuthLogin(idp, code, state, ip string) (*models.Token, error) {
+	provider, err := identityprovider.Get(idp)
+
+	if err != nil {
+		return nil, restful.NewError(http.StatusNotFound, err.Error())
+	}
+
+	value, err := consumeOAuthState(state)
+
+	if err != nil {
+		return nil, restful.NewError(http.StatusUnauthorized, "invalid oauth state")
+	}
+
+	var expected oauthState
+
+	if err := json.Unmarshal([]byte(value), &expected); err != nil || expected.IdP != idp {
+		return nil, restful.NewError(http.StatusUnauthorized, "invalid oauth state")
+	}
+
+	identity, err := provider.Exchange(code, expected.Nonce)
+
+	if err != nil {
+		glog.Infoln("oauth exchange failed", idp, err)
+		return nil, restful.NewError(http.StatusUnauthorized, err.Error())
+	}
+
+	identity.Username = strings.ToLower(identity.Username)
+	identity.Email = strings.ToLower(identity.Email)
+
+	if !regexp.MustCompile(usernameRegex).MatchString(identity.Username) {
+		return nil, restful.NewError(http.StatusForbidden, fmt.Sprintf("invalid username %s", identity.Username))
+	}
+
+	user, err := getIdentityUser(idp, identity.Subject)
+
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		user, err = provisionUser(idp, identity)
+	}
+
+	if err != nil {
+		glog.Errorln("oauth login", identity.Username, err)
+		return nil, err
+	}
+
+	token, err := issueToken(user.Username, user.Email, identityprovider.Groups(idp, identity.Groups))
+
+	if err != nil {
+		return nil, err
+	}
+
+	loginLog(user.Username, ip)
+
+	return token, nil
+}
+
+func identityLink(idp, subject string) string {
+	return fmt.Sprintf("idp:%s:%s", idp, url.PathEscape(subject))
+}
+
+// getIdentityUser returns the user provisioned by the identity provider for the subject
+func getIdentityUser(idp, subject string) (*models.User, error) {
+	conn, err := ldapclient.Client()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	userSearchRequest := ldap.NewSearchRequest(
+		ldapclient.UserSearchBase,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf("(&(objectClass=inetOrgPerson)(%s=%s))", identityLinkAttribute, ldap.EscapeFilter(identityLink(idp, subject))),
+		[]string{"uid"},
+		nil,
+	)
+
+	result, err := conn.Search(userSearchRequest)
+
+	if err != nil {
+		glog.Errorln("search user", err)
+		return nil, err
+	}
+
+	if len(result.Entries) != 1 {
+		return nil, ldap.NewError(ldap.LDAPResultNoSuchObject, fmt.Errorf("user of %s %s does not exist", idp, subject))
+	}
+
+	return GetUserInfo(result.Entries[0].GetAttributeValue("uid"))
+}
+
+// provisionUser creates the user authenticated by an identity provider, the random
+// password prevents the account from being used with LDAP authentication.
+// Usernames and emails of existing users are rejected, otherwise the provider
+// could log in as any local user, e.g. admin.
+func provisionUser(idp string, identity *identityprovider.Identity) (*models.User, error) {
+	if !regexp.MustCompile(emailRegex).MatchString(identity.Email) {
+		return nil, restful.NewError(http.StatusForbidden, fmt.Sprintf("invalid email %s", identity.Email))
+	}
+
+	if _, err := GetUserInfo(identity.Username); err == nil {
+		return nil, restful.NewError(http.StatusConflict, fmt.Sprintf("user %s already exists and is not provisioned by %s", identity.Username, idp))
+	} else if !ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, err
+	}
+
+	password, err := randomString(32)
+
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:    identity.Username,
+		Email:       identity.Email,
+		Password:    password,
+		Description: "Created by identity provider on first login.",
+	}
+
+	user, err = createUser(user, map[string][]string{identityLinkAttribute: {identityLink(idp, identity.Subject)}})
+
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+		return nil, restful.NewError(http.StatusConflict, err.Error())
+	}
+
+	return user, err
+}
+
+func randomString(length int) (string, error) {
+	data := make([]byte, length)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
@@ -0,0 +1,281 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package iam
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/emicklei/go-restful"
+	goredis "github.com/go-redis/redis"
+	"github.com/golang/glog"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+	"kubesphere.io/kubesphere/pkg/informers"
+	"kubesphere.io/kubesphere/pkg/models"
+	"kubesphere.io/kubesphere/pkg/simple/client/redis"
+	"kubesphere.io/kubesphere/pkg/utils/jwtutil"
+	"kubesphere.io/kubesphere/pkg/utils/k8sutil"
+)
+
+const refreshTokenKey = "kubesphere:users:%s:refresh-tokens:%s"
+
+// tokenStore returns the redis client tokens are recorded in
+var tokenStore = func() goredis.Cmdable { return redis.Client() }
+
+// issueToken signs a short-lived access token and a refresh token, the refresh token
+// is recorded in redis so that it can only be used once and can be revoked.
+func issueToken(username, email string, groups []string) (*models.Token, error) {
+	now := time.Now()
+
+	accessTokenID, err := randomString(16)
+
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokenID, err := randomString(16)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+
+	claims["exp"] = now.Add(tokenExpireTime).Unix()
+	claims["iat"] = now.Unix()
+	claims["jti"] = accessTokenID
+	claims["username"] = username
+	claims["email"] = email
+
+	if len(groups) > 0 {
+		claims["groups"] = groups
+	}
+
+	refreshClaims := jwt.MapClaims{}
+
+	refreshClaims["exp"] = now.Add(refreshTokenExpireTime).Unix()
+	refreshClaims["iat"] = now.Unix()
+	refreshClaims["jti"] = refreshTokenID
+	refreshClaims["username"] = username
+	refreshClaims[jwtutil.TokenTypeClaim] = jwtutil.RefreshTokenType
+
+	if len(groups) > 0 {
+		refreshClaims["groups"] = groups
+	}
+
+	redisClient := tokenStore()
+
+	err = redisClient.Set(fmt.Sprintf(refreshTokenKey, username, refreshTokenID), "", refreshTokenExpireTime).Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// both tokens are revoked when all of the user's tokens are revoked
+	for _, c := range []jwt.MapClaims{claims, refreshClaims} {
+		if err := jwtutil.Track(redisClient, c); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.Token{
+		Token:        jwtutil.MustSigned(claims),
+		RefreshToken: jwtutil.MustSigned(refreshClaims),
+		ExpiresIn:    int64(tokenExpireTime.Seconds()),
+	}, nil
+}
+
+// RefreshToken exchanges a refresh token for a new pair of tokens, the
+// refresh token is invalidated after use.
+func RefreshToken(refreshToken string) (*models.Token, error) {
+	token, err := jwtutil.ValidateToken(refreshToken)
+
+	if err != nil {
+		return nil, restful.NewError(http.StatusUnauthorized, err.Error())
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+
+	if !jwtutil.IsRefreshToken(claims) {
+		return nil, restful.NewError(http.StatusUnauthorized, "invalid refresh token")
+	}
+
+	username, _ := claims["username"].(string)
+	jti, _ := claims["jti"].(string)
+
+	redisClient := tokenStore()
+
+	revoked, err := jwtutil.IsRevoked(redisClient, claims)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// refresh token is deleted on first use, a replayed refresh token will not be found
+	deleted, err := redisClient.Del(fmt.Sprintf(refreshTokenKey, username, jti)).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked || deleted == 0 {
+		return nil, restful.NewError(http.StatusUnauthorized, "refresh token has been revoked")
+	}
+
+	user, err := GetUserInfo(username)
+
+	if err != nil {
+		return nil, restful.NewError(http.StatusUnauthorized, err.Error())
+	}
+
+	var groups []string
+
+	if values, ok := claims["groups"].([]interface{}); ok {
+		for _, value := range values {
+			if group, ok := value.(string); ok {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	return issueToken(user.Username, user.Email, groups)
+}
+
+// Logout revokes the access token and the refresh token if present, the refresh token
+// is revoked even if the access token has expired
+func Logout(accessToken, refreshToken string) error {
+	var username string
+	var err error
+	revoked := false
+
+	redisClient := tokenStore()
+
+	if accessToken != "" {
+		var token *jwt.Token
+		token, err = jwtutil.ValidateToken(accessToken)
+
+		if err == nil {
+			claims := token.Claims.(jwt.MapClaims)
+			username, _ = claims["username"].(string)
+
+			if err := jwtutil.Revoke(redisClient, claims); err != nil {
+				glog.Errorln("revoke access token", username, err)
+				return err
+			}
+
+			revoked = true
+		}
+	}
+
+	if refreshToken != "" {
+		var token *jwt.Token
+		token, err = jwtutil.ValidateToken(refreshToken)
+
+		if err == nil {
+			refreshClaims := token.Claims.(jwt.MapClaims)
+			refreshUsername, _ := refreshClaims["username"].(string)
+			jti, _ := refreshClaims["jti"].(string)
+
+			// the refresh token must be of the user of the access token if it is valid
+			if !jwtutil.IsRefreshToken(refreshClaims) || (username != "" && refreshUsername != username) {
+				return restful.NewError(http.StatusBadRequest, "invalid refresh token")
+			}
+
+			if err := redisClient.Del(fmt.Sprintf(refreshTokenKey, refreshUsername, jti)).Err(); err != nil {
+				glog.Errorln("revoke refresh token", refreshUsername, err)
+				return err
+			}
+
+			revoked = true
+		}
+	}
+
+	if !revoked {
+		if err == nil {
+			err = fmt.Errorf("no token found")
+		}
+		return restful.NewError(http.StatusUnauthorized, err.Error())
+	}
+
+	return nil
+}
+
+// WatchRoleBindings revokes the tokens of users who lose a role, that is who are removed from the subjects of a
+// role binding or cluster role binding, or whose binding is deleted. The bindings are watched so that roles
+// changed by workspace and namespace members APIs, controllers or kubectl are all covered.
+func WatchRoleBindings() {
+	informerFactory := informers.SharedInformerFactory()
+	informerFactory.Rbac().V1().RoleBindings().Informer().AddEventHandler(roleBindingEventHandler)
+	informerFactory.Rbac().V1().ClusterRoleBindings().Informer().AddEventHandler(roleBindingEventHandler)
+}
+
+var roleBindingEventHandler = cache.ResourceEventHandlerFuncs{
+	UpdateFunc: func(oldObj, newObj interface{}) {
+		revokeRemovedUsers(bindingSubjects(oldObj), bindingSubjects(newObj))
+	},
+	DeleteFunc: func(obj interface{}) {
+		revokeRemovedUsers(bindingSubjects(obj), nil)
+	},
+}
+
+func bindingSubjects(obj interface{}) []rbacv1.Subject {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	switch binding := obj.(type) {
+	case *rbacv1.RoleBinding:
+		return binding.Subjects
+	case *rbacv1.ClusterRoleBinding:
+		return binding.Subjects
+	}
+	return nil
+}
+
+// revokeRemovedUsers revokes the tokens of the users in subjects but not in remaining
+func revokeRemovedUsers(subjects, remaining []rbacv1.Subject) {
+	for _, subject := range subjects {
+		if subject.Kind != rbacv1.UserKind || k8sutil.ContainsUser(remaining, subject.Name) {
+			continue
+		}
+		if err := RevokeUserTokens(subject.Name); err != nil {
+			glog.Errorln("revoke user tokens failed", subject.Name, err)
+		}
+	}
+}
+
+// RevokeUserTokens revokes all of the user's access tokens and refresh tokens,
+// it is invoked when the user is deleted, changes password or loses a role.
+func RevokeUserTokens(username string) error {
+	redisClient := tokenStore()
+
+	if err := jwtutil.RevokeUserTokens(redisClient, username); err != nil {
+		glog.Errorln("revoke user tokens", username, err)
+		return err
+	}
+
+	keys, err := redisClient.Keys(fmt.Sprintf(refreshTokenKey, username, "*")).Result()
+
+	if err == nil && len(keys) > 0 {
+		redisClient.Del(keys...)
+	}
+
+	return nil
+}
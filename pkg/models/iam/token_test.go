@@ -0,0 +1,251 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package iam
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/emicklei/go-restful"
+	goredis "github.com/go-redis/redis"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/redis/fake"
+	"kubesphere.io/kubesphere/pkg/utils/jwtutil"
+)
+
+// setupTokens records tokens in a fake redis client, the returned func restores the token settings
+func setupTokens() (*fake.Client, func()) {
+	client := fake.NewClient()
+	store := tokenStore
+	tokenTTL, refreshTokenTTL := tokenExpireTime, refreshTokenExpireTime
+
+	tokenStore = func() goredis.Cmdable { return client }
+	tokenExpireTime, refreshTokenExpireTime = 2*time.Hour, 24*time.Hour
+	jwtutil.Setup("secret")
+
+	return client, func() {
+		tokenStore = store
+		tokenExpireTime, refreshTokenExpireTime = tokenTTL, refreshTokenTTL
+	}
+}
+
+func claimsOf(t *testing.T, token string) jwt.MapClaims {
+	parsed, err := jwtutil.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Claims.(jwt.MapClaims)
+}
+
+func isRevoked(t *testing.T, client *fake.Client, claims jwt.MapClaims) bool {
+	revoked, err := jwtutil.IsRevoked(client, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revoked
+}
+
+func statusOf(err error) int {
+	if serviceError, ok := err.(restful.ServiceError); ok {
+		return serviceError.Code
+	}
+	return 0
+}
+
+func TestLogout(t *testing.T) {
+	client, restore := setupTokens()
+	defer restore()
+
+	token, err := issueToken("admin", "admin@kubesphere.io", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := claimsOf(t, token.Token)
+	refreshClaims := claimsOf(t, token.RefreshToken)
+
+	if err := Logout(token.Token, token.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if !isRevoked(t, client, claims) {
+		t.Error("expected access token to be revoked")
+	}
+
+	if n := client.Exists(fmt.Sprintf(refreshTokenKey, "admin", refreshClaims["jti"])).Val(); n != 0 {
+		t.Error("expected refresh token to be revoked")
+	}
+
+	if _, err := RefreshToken(token.RefreshToken); statusOf(err) != http.StatusUnauthorized {
+		t.Errorf("expected revoked refresh token to be rejected, got %v", err)
+	}
+}
+
+func TestLogoutWithExpiredAccessToken(t *testing.T) {
+	client, restore := setupTokens()
+	defer restore()
+
+	token, err := issueToken("admin", "admin@kubesphere.io", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshClaims := claimsOf(t, token.RefreshToken)
+
+	expired := jwtutil.MustSigned(jwt.MapClaims{"username": "admin", "jti": "expired",
+		"iat": time.Now().Add(-3 * time.Hour).Unix(), "exp": time.Now().Add(-time.Hour).Unix()})
+
+	if err := Logout(expired, token.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := client.Exists(fmt.Sprintf(refreshTokenKey, "admin", refreshClaims["jti"])).Val(); n != 0 {
+		t.Error("expected refresh token to be revoked with an expired access token")
+	}
+
+	expiredRefreshToken := jwtutil.MustSigned(jwt.MapClaims{"username": "admin", "jti": "expired-refresh-token",
+		jwtutil.TokenTypeClaim: jwtutil.RefreshTokenType, "exp": time.Now().Add(-time.Hour).Unix()})
+
+	if err := Logout(expired, expiredRefreshToken); statusOf(err) != http.StatusUnauthorized {
+		t.Errorf("expected expired tokens to be rejected, got %v", err)
+	}
+}
+
+func TestLogoutWithRefreshTokenOfAnotherUser(t *testing.T) {
+	client, restore := setupTokens()
+	defer restore()
+
+	token, err := issueToken("admin", "admin@kubesphere.io", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	another, err := issueToken("tester", "tester@kubesphere.io", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anotherClaims := claimsOf(t, another.RefreshToken)
+
+	if err := Logout(token.Token, another.RefreshToken); statusOf(err) != http.StatusBadRequest {
+		t.Errorf("expected refresh token of another user to be rejected, got %v", err)
+	}
+
+	if n := client.Exists(fmt.Sprintf(refreshTokenKey, "tester", anotherClaims["jti"])).Val(); n != 1 {
+		t.Error("expected refresh token of another user not to be revoked")
+	}
+
+	// an access token is not a refresh token
+	if err := Logout("", token.Token); statusOf(err) != http.StatusBadRequest {
+		t.Errorf("expected access token to be rejected as refresh token, got %v", err)
+	}
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	client, restore := setupTokens()
+	defer restore()
+
+	token, err := issueToken("admin", "admin@kubesphere.io", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	another, err := issueToken("tester", "tester@kubesphere.io", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RevokeUserTokens("admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens issued in the same second as the revocation are revoked
+	if !isRevoked(t, client, claimsOf(t, token.Token)) || !isRevoked(t, client, claimsOf(t, token.RefreshToken)) {
+		t.Error("expected tokens of the user to be revoked")
+	}
+
+	if _, err := RefreshToken(token.RefreshToken); statusOf(err) != http.StatusUnauthorized {
+		t.Errorf("expected revoked refresh token to be rejected, got %v", err)
+	}
+
+	if isRevoked(t, client, claimsOf(t, another.Token)) || isRevoked(t, client, claimsOf(t, another.RefreshToken)) {
+		t.Error("expected tokens of another user not to be revoked")
+	}
+
+	// tokens issued after the revocation are valid
+	issued, err := issueToken("admin", "admin@kubesphere.io", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if isRevoked(t, client, claimsOf(t, issued.Token)) || isRevoked(t, client, claimsOf(t, issued.RefreshToken)) {
+		t.Error("expected tokens issued after the revocation not to be revoked")
+	}
+}
+
+func TestRoleBindingEventHandler(t *testing.T) {
+	client, restore := setupTokens()
+	defer restore()
+
+	tokens := make(map[string]string)
+	for _, username := range []string{"alice", "bob", "carol"} {
+		token, err := issueToken(username, username+"@kubesphere.io", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens[username] = token.Token
+	}
+
+	user := func(name string) rbacv1.Subject {
+		return rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: name}
+	}
+	group := rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "carol"}
+
+	old := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "admin"},
+		Subjects:   []rbacv1.Subject{user("alice"), user("bob"), group},
+	}
+	updated := old.DeepCopy()
+	updated.Subjects = []rbacv1.Subject{user("bob")}
+
+	// only users removed from the subjects lose the role
+	roleBindingEventHandler.OnUpdate(old, updated)
+
+	for username, revoked := range map[string]bool{"alice": true, "bob": false, "carol": false} {
+		if isRevoked(t, client, claimsOf(t, tokens[username])) != revoked {
+			t.Errorf("expected tokens of %s revoked: %t", username, revoked)
+		}
+	}
+
+	// deleted bindings may be tombstones if the deletion was missed
+	deleted := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "bob-cluster-admin"},
+		Subjects:   []rbacv1.Subject{user("bob")},
+	}
+	roleBindingEventHandler.OnDelete(cache.DeletedFinalStateUnknown{Key: deleted.Name, Obj: deleted})
+
+	if !isRevoked(t, client, claimsOf(t, tokens["bob"])) {
+		t.Error("expected tokens of bob to be revoked when the binding is deleted")
+	}
+}
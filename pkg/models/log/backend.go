@@ -0,0 +1,44 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"fmt"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/clickhouse"
+	es "kubesphere.io/kubesphere/pkg/simple/client/elasticsearch"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+	"kubesphere.io/kubesphere/pkg/simple/client/loki"
+)
+
+// Backend returns the log store selected by --logging-backend
+func Backend() (logging.Backend, error) {
+	switch logging.BackendType {
+	case logging.BackendElasticsearch:
+		if !es.Configured() {
+			return nil, logging.ErrNotConfigured
+		}
+		return es.Backend, nil
+	case logging.BackendLoki:
+		return loki.Backend, nil
+	case logging.BackendClickHouse:
+		return clickhouse.Backend, nil
+	default:
+		return nil, fmt.Errorf("unsupported logging backend %s", logging.BackendType)
+	}
+}
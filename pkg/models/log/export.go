@@ -0,0 +1,134 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+const (
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatCSV    = "csv"
+	ExportFormatText   = "text"
+)
+
+var exportContentTypes = map[string]string{
+	ExportFormatNDJSON: "application/x-ndjson",
+	ExportFormatCSV:    "text/csv",
+	ExportFormatText:   "text/plain",
+}
+
+var csvHeader = []string{"time", "namespace", "pod", "container", "host", "log"}
+
+// LogExporter encodes exported logs, one record per line in ndjson and csv, or log messages only in text
+type LogExporter struct {
+	format string
+	writer *bufio.Writer
+	json   *json.Encoder
+	csv    *csv.Writer
+}
+
+func NewLogExporter(format string, w io.Writer) (*LogExporter, error) {
+	if format == "" {
+		format = ExportFormatNDJSON
+	}
+
+	if _, ok := exportContentTypes[format]; !ok {
+		return nil, fmt.Errorf("unsupported export format %s, must be one of ndjson, csv, text", format)
+	}
+
+	exporter := &LogExporter{format: format, writer: bufio.NewWriter(w)}
+
+	switch format {
+	case ExportFormatNDJSON:
+		exporter.json = json.NewEncoder(exporter.writer)
+	case ExportFormatCSV:
+		exporter.csv = csv.NewWriter(exporter.writer)
+		if err := exporter.csv.Write(csvHeader); err != nil {
+			return nil, err
+		}
+	}
+
+	return exporter, nil
+}
+
+func (e *LogExporter) Write(record logging.LogRecord) error {
+	switch e.format {
+	case ExportFormatNDJSON:
+		return e.json.Encode(record)
+	case ExportFormatCSV:
+		return e.csv.Write([]string{formatTime(record.Time), record.Namespace, record.Pod, record.Container, record.Host, strings.TrimSuffix(record.Log, "\n")})
+	default:
+		_, err := e.writer.WriteString(strings.TrimSuffix(record.Log, "\n") + "\n")
+		return err
+	}
+}
+
+// WriteError marks the exported file as incomplete after the records, in the format of the records so that the file can
+// still be parsed: {"error":"<error>"} in ndjson, a row with error in the time column and the error in the log column
+// in csv, and a line of "# export error: <error>" in text
+func (e *LogExporter) WriteError(exportErr error) error {
+	switch e.format {
+	case ExportFormatNDJSON:
+		return e.json.Encode(map[string]string{"error": exportErr.Error()})
+	case ExportFormatCSV:
+		return e.csv.Write([]string{"error", "", "", "", "", exportErr.Error()})
+	default:
+		_, err := e.writer.WriteString("# export error: " + strings.Replace(exportErr.Error(), "\n", " ", -1) + "\n")
+		return err
+	}
+}
+
+// Flush writes buffered records to the underlying writer
+func (e *LogExporter) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return e.writer.Flush()
+}
+
+func (e *LogExporter) ContentType() string {
+	return exportContentTypes[e.format]
+}
+
+// Filename returns the name of exported files, e.g. logs-20190601000000.ndjson
+func (e *LogExporter) Filename(t time.Time) string {
+	extension := e.format
+	if e.format == ExportFormatText {
+		extension = "log"
+	}
+	return fmt.Sprintf("logs-%s.%s", t.Format("20060102150405"), extension)
+}
+
+func formatTime(millis int64) string {
+	if millis == 0 {
+		return ""
+	}
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
@@ -0,0 +1,83 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+func TestLogExporter(t *testing.T) {
+	records := []logging.LogRecord{
+		{Time: 1559347200000, Namespace: "default", Pod: "nginx", Container: "nginx", Host: "node1", Log: "GET / 200\n"},
+		{Time: 1559347200123, Namespace: "default", Pod: "nginx", Container: "nginx", Host: "node1", Log: `say "hi", bye`},
+	}
+
+	expected := map[string]string{
+		ExportFormatNDJSON: `{"time":1559347200000,"log":"GET / 200\n","namespace":"default","pod":"nginx","container":"nginx","host":"node1","highlight":{}}
+{"time":1559347200123,"log":"say \"hi\", bye","namespace":"default","pod":"nginx","container":"nginx","host":"node1","highlight":{}}
+`,
+		ExportFormatCSV: `time,namespace,pod,container,host,log
+2019-06-01T00:00:00.000Z,default,nginx,nginx,node1,GET / 200
+2019-06-01T00:00:00.123Z,default,nginx,nginx,node1,"say ""hi"", bye"
+`,
+		ExportFormatText: `GET / 200
+say "hi", bye
+`,
+	}
+
+	for format, output := range expected {
+		var buf bytes.Buffer
+		exporter, err := NewLogExporter(format, &buf)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		for _, record := range records {
+			assert.NoError(t, exporter.Write(record))
+		}
+		assert.NoError(t, exporter.Flush())
+		assert.Equal(t, output, buf.String(), format)
+	}
+
+	_, err := NewLogExporter("xml", &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestLogExporterError(t *testing.T) {
+	expected := map[string]string{
+		ExportFormatNDJSON: `{"error":"search timeout"}` + "\n",
+		ExportFormatCSV:    "time,namespace,pod,container,host,log\nerror,,,,,search timeout\n",
+		ExportFormatText:   "# export error: search timeout\n",
+	}
+
+	for format, output := range expected {
+		var buf bytes.Buffer
+		exporter, err := NewLogExporter(format, &buf)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.NoError(t, exporter.WriteError(fmt.Errorf("search timeout")))
+		assert.NoError(t, exporter.Flush())
+		assert.Equal(t, output, buf.String(), format)
+	}
+}
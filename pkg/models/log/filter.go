@@ -0,0 +1,51 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"encoding/json"
+
+	"kubesphere.io/kubesphere/pkg/params"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+// logResolver resolves fields of logs streamed from the kubelet, structured fields are parsed
+// from json logs
+func logResolver(record logging.LogRecord) params.FieldResolver {
+	var structured map[string]interface{}
+	json.Unmarshal([]byte(record.Log), &structured)
+
+	return func(field string) (interface{}, bool) {
+		switch field {
+		case "namespace":
+			return record.Namespace, true
+		case "pod":
+			return record.Pod, true
+		case "container":
+			return record.Container, true
+		case "host":
+			return record.Host, true
+		case "log":
+			return record.Log, true
+		}
+		if structured == nil {
+			return nil, false
+		}
+		return params.LookupField(structured, params.SplitFieldPath(field))
+	}
+}
@@ -0,0 +1,42 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kubesphere.io/kubesphere/pkg/params"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+func TestLogResolver(t *testing.T) {
+	record := logging.LogRecord{Namespace: "default", Pod: "nginx-0", Log: `{"level":"error","http":{"status":502}}`}
+
+	for filter, matched := range map[string]bool{
+		"level=error AND http.status>=500": true,
+		"namespace=default AND level=info": false,
+		"pod=~'^nginx-'":                   true,
+	} {
+		expr, err := params.ParseFilter(filter)
+		if assert.NoError(t, err, filter) {
+			assert.Equal(t, matched, expr.Evaluate(logResolver(record)), filter)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kubesphere.io/kubesphere/pkg/informers"
+	"kubesphere.io/kubesphere/pkg/params"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+const (
+	// interval of polling the log backend for new logs
+	followInterval = 2 * time.Second
+	// max number of logs returned by each poll
+	followBatchSize = 1000
+)
+
+// FollowLogs polls the backend for new logs until stopCh is closed. Query parameters are
+// built before each poll, so that logs of the pods created later are followed as well.
+func FollowLogs(backend logging.Backend, build func() logging.QueryParameters, stopCh <-chan struct{}) <-chan logging.LogRecord {
+	records := make(chan logging.LogRecord)
+
+	go func() {
+		defer close(records)
+
+		var cursor []interface{}
+
+		for {
+			param := build()
+			param.Size = followBatchSize
+
+			batch, next, err := backend.Tail(param, cursor)
+
+			if err != nil {
+				glog.Errorln("follow logs", err)
+			}
+
+			cursor = next
+
+			for _, record := range batch {
+				select {
+				case records <- record:
+				case <-stopCh:
+					return
+				}
+			}
+
+			// more logs may be waiting
+			if len(batch) == followBatchSize {
+				continue
+			}
+
+			select {
+			case <-time.After(followInterval):
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	return records
+}
+
+// FollowPodLogs streams logs of the pod from the kubelet until stopCh is closed, logs of all
+// containers are followed if containers is empty. It's used when the log backend is not configured.
+func FollowPodLogs(namespace, name string, containers []string, logQuery string, filter params.Expression, since time.Time, stopCh <-chan struct{}) (<-chan logging.LogRecord, error) {
+	pod, err := informers.SharedInformerFactory().Core().V1().Pods().Lister().Pods(namespace).Get(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(containers) == 0 {
+		for _, container := range pod.Spec.Containers {
+			containers = append(containers, container.Name)
+		}
+	}
+
+	sinceTime := metav1.NewTime(since)
+	streams := make(map[string]io.ReadCloser)
+
+	for _, container := range containers {
+		options := &corev1.PodLogOptions{Container: container, Follow: true, Timestamps: true, SinceTime: &sinceTime}
+		stream, err := k8s.Client().CoreV1().Pods(namespace).GetLogs(name, options).Stream()
+
+		if err != nil {
+			for _, opened := range streams {
+				opened.Close()
+			}
+			return nil, err
+		}
+
+		streams[container] = stream
+	}
+
+	var keywords []string
+	if logQuery != "" {
+		keywords = strings.Split(strings.ToLower(strings.Replace(logQuery, ",", " ", -1)), " ")
+	}
+
+	records := make(chan logging.LogRecord)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for container, stream := range streams {
+		wg.Add(1)
+		go func(container string, stream io.Reader) {
+			defer wg.Done()
+
+			scanner := bufio.NewScanner(stream)
+
+			for scanner.Scan() {
+				record := logging.LogRecord{Namespace: namespace, Pod: name, Container: container, Host: pod.Spec.NodeName}
+				record.Time, record.Log = parseKubeletLog(scanner.Text())
+
+				if len(keywords) > 0 && !queryLabel(strings.ToLower(record.Log), keywords) {
+					continue
+				}
+
+				if filter != nil && !filter.Evaluate(logResolver(record)) {
+					continue
+				}
+
+				select {
+				case records <- record:
+				case <-stopCh:
+					return
+				}
+			}
+		}(container, stream)
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+		close(records)
+	}()
+
+	// reading goroutines are blocked on streams until they are closed
+	go func() {
+		select {
+		case <-stopCh:
+		case <-done:
+		}
+		for _, stream := range streams {
+			stream.Close()
+		}
+	}()
+
+	return records, nil
+}
+
+// parseKubeletLog splits the log line with timestamp into milliseconds and message
+func parseKubeletLog(line string) (int64, string) {
+	parts := strings.SplitN(line, " ", 2)
+
+	if len(parts) == 2 {
+		if t, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+			return t.UnixNano() / int64(time.Millisecond), parts[1]
+		}
+	}
+
+	return time.Now().UnixNano() / int64(time.Millisecond), line
+}
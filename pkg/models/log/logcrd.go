@@ -17,16 +17,12 @@ limitations under the License.
 package log
 
 import (
+	"fmt"
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/golang/glog"
 	"github.com/google/uuid"
 	"github.com/json-iterator/go"
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"kubesphere.io/kubesphere/pkg/informers"
 	es "kubesphere.io/kubesphere/pkg/simple/client/elasticsearch"
 	fb "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
 	"net/http"
@@ -37,9 +33,14 @@ import (
 var jsonIter = jsoniter.ConfigCompatibleWithStandardLibrary
 
 const (
+	// ConfigMapName stored outputs before they were managed by the pipeline, it's only read to import them
 	ConfigMapName    = "fluent-bit-output-config"
 	ConfigMapData    = "outputs"
 	LoggingNamespace = "kubesphere-logging-system"
+
+	// name prefixes of the grep filters managed by the filters api in the pipeline
+	legacyRegexFilterPrefix   = "input-regex-"
+	legacyExcludeFilterPrefix = "input-exclude-"
 )
 
 func createCRDClientSet() (*rest.RESTClient, *runtime.Scheme, error) {
@@ -86,151 +87,94 @@ func getFilters(result *FluentbitFiltersResult, Filters []fb.Plugin) {
 func FluentbitFiltersQuery() *FluentbitFiltersResult {
 	var result FluentbitFiltersResult
 
-	crdcs, scheme, err := createCRDClientSet()
+	pipeline, _, _, err := getFluentbitPipeline()
 	if err != nil {
 		result.Status = http.StatusInternalServerError
 		return &result
 	}
 
-	// Create a CRD client interface
-	crdclient := fb.CrdClient(crdcs, scheme, LoggingNamespace)
-
-	item, err := crdclient.Get("fluent-bit")
-	if err != nil {
-		result.Status = http.StatusInternalServerError
-		return &result
+	for _, filter := range pipeline.Filters {
+		if !isLegacyFilter(filter) {
+			continue
+		}
+		for _, rule := range filter.Grep.Regex {
+			result.Filters = append(result.Filters, FluentbitFilter{"Regex", legacyFilterField(rule.Key), rule.Value})
+		}
+		for _, rule := range filter.Grep.Exclude {
+			result.Filters = append(result.Filters, FluentbitFilter{"Exclude", legacyFilterField(rule.Key), rule.Value})
+		}
 	}
 
-	getFilters(&result, item.Spec.Filter)
-
 	result.Status = http.StatusOK
 
 	return &result
 }
 
+// FluentbitFiltersUpdate replaces the grep filters on kubernetes fields, other filters of the pipeline are kept
 func FluentbitFiltersUpdate(filters *[]FluentbitFilter) *FluentbitFiltersResult {
 	var result FluentbitFiltersResult
 
-	//Generate filter plugin config
-	var filter []fb.Plugin
-
-	var para_kubernetes []fb.Parameter
-	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Name", Value: "kubernetes"})
-	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Match", Value: "kube.*"})
-	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Kube_URL", Value: "https://kubernetes.default.svc:443"})
-	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Kube_CA_File", Value: "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"})
-	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Kube_Token_File", Value: "/var/run/secrets/kubernetes.io/serviceaccount/token"})
-	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-kubernetes", Parameters: para_kubernetes})
-
-	var para_lift []fb.Parameter
-	para_lift = append(para_lift, fb.Parameter{Name: "Name", Value: "nest"})
-	para_lift = append(para_lift, fb.Parameter{Name: "Match", Value: "kube.*"})
-	para_lift = append(para_lift, fb.Parameter{Name: "Operation", Value: "lift"})
-	para_lift = append(para_lift, fb.Parameter{Name: "Nested_under", Value: "kubernetes"})
-	para_lift = append(para_lift, fb.Parameter{Name: "Prefix_with", Value: "kubernetes_"})
-	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-lift", Parameters: para_lift})
-
-	var para_remove_stream []fb.Parameter
-	para_remove_stream = append(para_remove_stream, fb.Parameter{Name: "Name", Value: "modify"})
-	para_remove_stream = append(para_remove_stream, fb.Parameter{Name: "Match", Value: "kube.*"})
-	para_remove_stream = append(para_remove_stream, fb.Parameter{Name: "Remove", Value: "stream"})
-	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-remove-stream", Parameters: para_remove_stream})
-
-	var para_remove_labels []fb.Parameter
-	para_remove_labels = append(para_remove_labels, fb.Parameter{Name: "Name", Value: "modify"})
-	para_remove_labels = append(para_remove_labels, fb.Parameter{Name: "Match", Value: "kube.*"})
-	para_remove_labels = append(para_remove_labels, fb.Parameter{Name: "Remove", Value: "kubernetes_labels"})
-	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-remove-labels", Parameters: para_remove_labels})
-
-	var para_remove_annotations []fb.Parameter
-	para_remove_annotations = append(para_remove_annotations, fb.Parameter{Name: "Name", Value: "modify"})
-	para_remove_annotations = append(para_remove_annotations, fb.Parameter{Name: "Match", Value: "kube.*"})
-	para_remove_annotations = append(para_remove_annotations, fb.Parameter{Name: "Remove", Value: "kubernetes_annotations"})
-	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-remove-annotations", Parameters: para_remove_annotations})
-
-	var para_remove_pod_id []fb.Parameter
-	para_remove_pod_id = append(para_remove_pod_id, fb.Parameter{Name: "Name", Value: "modify"})
-	para_remove_pod_id = append(para_remove_pod_id, fb.Parameter{Name: "Match", Value: "kube.*"})
-	para_remove_pod_id = append(para_remove_pod_id, fb.Parameter{Name: "Remove", Value: "kubernetes_pod_id"})
-	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-remove-podid", Parameters: para_remove_pod_id})
-
-	var para_remove_docker_id []fb.Parameter
-	para_remove_docker_id = append(para_remove_docker_id, fb.Parameter{Name: "Name", Value: "modify"})
-	para_remove_docker_id = append(para_remove_docker_id, fb.Parameter{Name: "Match", Value: "kube.*"})
-	para_remove_docker_id = append(para_remove_docker_id, fb.Parameter{Name: "Remove", Value: "kubernetes_docker_id"})
-	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-remove-dockerid", Parameters: para_remove_docker_id})
-
-	if len(*filters) > 0 {
-		for _, item := range *filters {
-			if strings.Compare(item.Type, "Regex") == 0 {
-				field := "kubernetes_" + strings.TrimSpace(item.Field) + "_name"
-				expression := strings.TrimSpace(item.Expression)
-
-				var para_regex []fb.Parameter
-				para_regex = append(para_regex, fb.Parameter{Name: "Name", Value: "grep"})
-				para_regex = append(para_regex, fb.Parameter{Name: "Match", Value: "kube.*"})
-				para_regex = append(para_regex, fb.Parameter{Name: "Regex", Value: field + " " + expression})
-				filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-regex", Parameters: para_regex})
-			}
-
-			if strings.Compare(item.Type, "Exclude") == 0 {
-				field := "kubernetes_" + strings.TrimSpace(item.Field) + "_name"
-				expression := strings.TrimSpace(item.Expression)
-
-				var para_exclude []fb.Parameter
-				para_exclude = append(para_exclude, fb.Parameter{Name: "Name", Value: "grep"})
-				para_exclude = append(para_exclude, fb.Parameter{Name: "Match", Value: "kube.*"})
-				para_exclude = append(para_exclude, fb.Parameter{Name: "Exclude", Value: field + " " + expression})
-				filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-exclude", Parameters: para_exclude})
+	_, err := updateFluentbitPipeline(func(pipeline *FluentbitPipeline) error {
+		merged := legacyFilters(*filters)
+		for _, filter := range pipeline.Filters {
+			if !isLegacyFilter(filter) {
+				merged = append(merged, filter)
 			}
 		}
-	}
-
-	var para_nest []fb.Parameter
-	para_nest = append(para_nest, fb.Parameter{Name: "Name", Value: "nest"})
-	para_nest = append(para_nest, fb.Parameter{Name: "Match", Value: "kube.*"})
-	para_nest = append(para_nest, fb.Parameter{Name: "Operation", Value: "nest"})
-	para_nest = append(para_nest, fb.Parameter{Name: "Wildcard", Value: "kubernetes_*"})
-	para_nest = append(para_nest, fb.Parameter{Name: "Nested_under", Value: "kubernetes"})
-	para_nest = append(para_nest, fb.Parameter{Name: "Remove_prefix", Value: "kubernetes_"})
-	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-nest", Parameters: para_nest})
-
-	crdcs, scheme, err := createCRDClientSet()
+		pipeline.Filters = merged
+		return nil
+	}, false)
 	if err != nil {
-		result.Status = http.StatusInternalServerError
+		result.Status = errorStatus(err)
 		return &result
 	}
 
-	// Create a CRD client interface
-	crdclient := fb.CrdClient(crdcs, scheme, LoggingNamespace)
+	result.Filters = *filters
+	result.Status = http.StatusOK
 
-	var item *fb.FluentBit
-	var err_read error
+	return &result
+}
 
-	item, err_read = crdclient.Get("fluent-bit")
-	if err_read != nil {
-		result.Status = http.StatusInternalServerError
-		return &result
-	}
+// legacyFilters converts filters on kubernetes fields to grep filters of the pipeline
+func legacyFilters(filters []FluentbitFilter) []PipelineFilter {
+	var converted []PipelineFilter
 
-	item.Spec.Filter = filter
+	for i, item := range filters {
+		field := "kubernetes_" + strings.TrimSpace(item.Field) + "_name"
+		rule := PipelineKeyValue{Key: field, Value: strings.TrimSpace(item.Expression)}
 
-	itemnew, err := crdclient.Update("fluent-bit", item)
-	if err != nil {
-		result.Status = http.StatusInternalServerError
-		return &result
+		if strings.Compare(item.Type, "Regex") == 0 {
+			converted = append(converted, PipelineFilter{
+				Name:  fmt.Sprintf("%s%d", legacyRegexFilterPrefix, i),
+				Match: "kube.*",
+				Grep:  &GrepFilter{Regex: []PipelineKeyValue{rule}},
+			})
+		}
+
+		if strings.Compare(item.Type, "Exclude") == 0 {
+			converted = append(converted, PipelineFilter{
+				Name:  fmt.Sprintf("%s%d", legacyExcludeFilterPrefix, i),
+				Match: "kube.*",
+				Grep:  &GrepFilter{Exclude: []PipelineKeyValue{rule}},
+			})
+		}
 	}
 
-	getFilters(&result, itemnew.Spec.Filter)
-	result.Status = http.StatusOK
+	return converted
+}
 
-	return &result
+func isLegacyFilter(filter PipelineFilter) bool {
+	return filter.Grep != nil && (strings.HasPrefix(filter.Name, legacyRegexFilterPrefix) || strings.HasPrefix(filter.Name, legacyExcludeFilterPrefix))
+}
+
+func legacyFilterField(key string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(key, "kubernetes_"), "_name")
 }
 
 func FluentbitOutputsQuery() *FluentbitOutputsResult {
 	var result FluentbitOutputsResult
 
-	outputs, err := GetFluentbitOutputFromConfigMap()
+	outputs, err := GetFluentbitOutputs()
 	if err != nil {
 		result.Status = http.StatusNotFound
 		result.Error = err.Error()
@@ -246,42 +190,32 @@ func FluentbitOutputsQuery() *FluentbitOutputsResult {
 func FluentbitOutputInsert(output fb.OutputPlugin) *FluentbitOutputsResult {
 	var result FluentbitOutputsResult
 
-	// 1. Update ConfigMap
-	var outputs []fb.OutputPlugin
-	outputs, err := GetFluentbitOutputFromConfigMap()
-	if err != nil {
-		// If the ConfigMap doesn't exist, a new one will be created later
-		glog.Errorln(err)
-	}
+	_, err := updateFluentbitPipeline(func(pipeline *FluentbitPipeline) error {
+		item, err := legacyOutput(output)
+		if err != nil {
+			return err
+		}
 
-	// When adding a new output for the first time, one should always set it enabled
-	output.Enable = true
-	output.Id = uuid.New().String()
-	output.Updatetime = time.Now()
+		for _, existing := range pipeline.Outputs {
+			if existing.Name == item.Name {
+				return pipelineError(http.StatusConflict, fmt.Sprintf("The output %s already exists.", item.Name))
+			}
+		}
 
-	outputs = append(outputs, output)
+		// When adding a new output for the first time, one should always set it enabled
+		item.Enable = true
+		item.Id = uuid.New().String()
+		item.Updatetime = time.Now()
 
-	err = updateFluentbitOutputConfigMap(outputs)
-	if err != nil {
-		result.Status = http.StatusInternalServerError
-		result.Error = err.Error()
-		return &result
-	}
-
-	// 2. Keep CRD in inline with ConfigMap
-	err = syncFluentbitCRDOutputWithConfigMap(outputs)
+		pipeline.Outputs = append(pipeline.Outputs, item)
+		return nil
+	}, false)
 	if err != nil {
-		result.Status = http.StatusInternalServerError
+		result.Status = errorStatus(err)
 		result.Error = err.Error()
 		return &result
 	}
 
-	// 3. If it's an configs output added, reset configs client configs
-	configs := ParseEsOutputParams(output.Parameters)
-	if configs != nil {
-		configs.WriteESConfigs()
-	}
-
 	result.Status = http.StatusOK
 	return &result
 }
@@ -289,52 +223,30 @@ func FluentbitOutputInsert(output fb.OutputPlugin) *FluentbitOutputsResult {
 func FluentbitOutputUpdate(output fb.OutputPlugin, id string) *FluentbitOutputsResult {
 	var result FluentbitOutputsResult
 
-	// 1. Update ConfigMap
-	var outputs []fb.OutputPlugin
-	outputs, err := GetFluentbitOutputFromConfigMap()
-	if err != nil {
-		// If the ConfigMap doesn't exist, a new one will be created later
-		glog.Errorln(err)
-	}
-
-	index := 0
-	for _, output := range outputs {
-		if output.Id == id {
-			break
+	_, err := updateFluentbitPipeline(func(pipeline *FluentbitPipeline) error {
+		index := indexOfOutput(pipeline.Outputs, id)
+		if index < 0 {
+			return pipelineError(http.StatusNotFound, "The output plugin to update doesn't exist. Please check the output id you provide.")
 		}
-		index++
-	}
 
-	if index >= len(outputs) {
-		result.Status = http.StatusNotFound
-		result.Error = "The output plugin to update doesn't exist. Please check the output id you provide."
-		return &result
-	}
+		item, err := legacyOutput(output)
+		if err != nil {
+			return err
+		}
 
-	output.Updatetime = time.Now()
-	outputs = append(append(outputs[:index], outputs[index+1:]...), output)
+		item.Id = id
+		item.Enable = output.Enable
+		item.Updatetime = time.Now()
 
-	err = updateFluentbitOutputConfigMap(outputs)
-	if err != nil {
-		result.Status = http.StatusInternalServerError
-		result.Error = err.Error()
-		return &result
-	}
-
-	// 2. Keep CRD in inline with ConfigMap
-	err = syncFluentbitCRDOutputWithConfigMap(outputs)
+		pipeline.Outputs[index] = item
+		return nil
+	}, false)
 	if err != nil {
-		result.Status = http.StatusInternalServerError
+		result.Status = errorStatus(err)
 		result.Error = err.Error()
 		return &result
 	}
 
-	// 3. If it's an configs output updated, reset configs client configs
-	configs := ParseEsOutputParams(output.Parameters)
-	if configs != nil {
-		configs.WriteESConfigs()
-	}
-
 	result.Status = http.StatusOK
 	return &result
 }
@@ -342,37 +254,17 @@ func FluentbitOutputUpdate(output fb.OutputPlugin, id string) *FluentbitOutputsR
 func FluentbitOutputDelete(id string) *FluentbitOutputsResult {
 	var result FluentbitOutputsResult
 
-	// 1. Update ConfigMap
-	// If the ConfigMap doesn't exist, a new one will be created
-	outputs, _ := GetFluentbitOutputFromConfigMap()
-
-	index := 0
-	for _, output := range outputs {
-		if output.Id == id {
-			break
+	_, err := updateFluentbitPipeline(func(pipeline *FluentbitPipeline) error {
+		index := indexOfOutput(pipeline.Outputs, id)
+		if index < 0 {
+			return pipelineError(http.StatusNotFound, "The output plugin to delete doesn't exist. Please check the output id you provide.")
 		}
-		index++
-	}
 
-	if index >= len(outputs) {
-		result.Status = http.StatusNotFound
-		result.Error = "The output plugin to delete doesn't exist. Please check the output id you provide."
-		return &result
-	}
-
-	outputs = append(outputs[:index], outputs[index+1:]...)
-
-	err := updateFluentbitOutputConfigMap(outputs)
-	if err != nil {
-		result.Status = http.StatusInternalServerError
-		result.Error = err.Error()
-		return &result
-	}
-
-	// 2. Keep CRD in inline with DB
-	err = syncFluentbitCRDOutputWithConfigMap(outputs)
+		pipeline.Outputs = append(pipeline.Outputs[:index], pipeline.Outputs[index+1:]...)
+		return nil
+	}, false)
 	if err != nil {
-		result.Status = http.StatusInternalServerError
+		result.Status = errorStatus(err)
 		result.Error = err.Error()
 		return &result
 	}
@@ -381,129 +273,35 @@ func FluentbitOutputDelete(id string) *FluentbitOutputsResult {
 	return &result
 }
 
-func GetFluentbitOutputFromConfigMap() ([]fb.OutputPlugin, error) {
-	configMap, err := informers.SharedInformerFactory().Core().V1().ConfigMaps().Lister().ConfigMaps(LoggingNamespace).Get(ConfigMapName)
+// legacyOutput converts an output plugin with arbitrary parameters to a typed output of the pipeline
+func legacyOutput(output fb.OutputPlugin) (PipelineOutput, error) {
+	item, unsupported, err := outputFromPlugin(output.Plugin)
 	if err != nil {
-		return nil, err
+		return item, pipelineError(http.StatusBadRequest, err.Error())
 	}
-
-	data := configMap.Data[ConfigMapData]
-
-	var outputs []fb.OutputPlugin
-	if err = jsonIter.UnmarshalFromString(data, &outputs); err != nil {
-		return nil, err
+	if len(unsupported) > 0 {
+		return item, pipelineError(http.StatusBadRequest, fmt.Sprintf("unsupported parameters %s", strings.Join(unsupported, ", ")))
 	}
-
-	return outputs, nil
+	return item, nil
 }
 
-func updateFluentbitOutputConfigMap(outputs []fb.OutputPlugin) error {
-
-	var data string
-	data, err := jsonIter.MarshalToString(outputs)
-	if err != nil {
-		glog.Errorln(err)
-		return err
-	}
-
-	// Update the ConfigMap
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		glog.Errorln(err)
-		return err
-	}
-
-	// Creates the clientset
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		glog.Errorln(err)
-		return err
-	}
-
-	configMapClient := clientset.CoreV1().ConfigMaps(LoggingNamespace)
-
-	configMap, err := configMapClient.Get(ConfigMapName, metav1.GetOptions{})
-	if err != nil {
-
-		// If the ConfigMap doesn't exist, create a new one
-		newConfigMap := &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: ConfigMapName,
-			},
-			Data: map[string]string{ConfigMapData: data},
-		}
-
-		_, err = configMapClient.Create(newConfigMap)
-		if err != nil {
-			glog.Errorln(err)
-			return err
-		}
-	} else {
-
-		// update
-		configMap.Data = map[string]string{ConfigMapData: data}
-		_, err = configMapClient.Update(configMap)
-		if err != nil {
-			glog.Errorln(err)
-			return err
+func indexOfOutput(outputs []PipelineOutput, id string) int {
+	for i, output := range outputs {
+		if output.Id == id {
+			return i
 		}
 	}
-
-	return nil
+	return -1
 }
 
-func syncFluentbitCRDOutputWithConfigMap(outputs []fb.OutputPlugin) error {
-
-	var enabledOutputs []fb.Plugin
-	for _, output := range outputs {
-		if output.Enable {
-			enabledOutputs = append(enabledOutputs, fb.Plugin{Type: output.Type, Name: output.Name, Parameters: output.Parameters})
-		}
-	}
-
-	// Empty output is not allowed, must specify a null-type output
-	if len(enabledOutputs) == 0 {
-		enabledOutputs = []fb.Plugin{
-			{
-				Type: "fluentbit_output",
-				Name: "fluentbit-output-null",
-				Parameters: []fb.Parameter{
-					{
-						Name:  "Name",
-						Value: "null",
-					},
-					{
-						Name:  "Match",
-						Value: "*",
-					},
-				},
-			},
-		}
-	}
-
-	crdcs, scheme, err := createCRDClientSet()
+// GetFluentbitOutputs returns the outputs of the pipeline rendered into plugins
+func GetFluentbitOutputs() ([]fb.OutputPlugin, error) {
+	pipeline, _, _, err := getFluentbitPipeline()
 	if err != nil {
-		glog.Errorln(err)
-		return err
-	}
-
-	// Create a CRD client interface
-	crdclient := fb.CrdClient(crdcs, scheme, LoggingNamespace)
-
-	fluentbit, err := crdclient.Get("fluent-bit")
-	if err != nil {
-		glog.Errorln(err)
-		return err
-	}
-
-	fluentbit.Spec.Output = enabledOutputs
-	_, err = crdclient.Update("fluent-bit", fluentbit)
-	if err != nil {
-		glog.Errorln(err)
-		return err
+		return nil, err
 	}
 
-	return nil
+	return outputPlugins(pipeline.Outputs), nil
 }
 
 // Parse es host, port and index
@@ -0,0 +1,291 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	fb "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+)
+
+const (
+	// ParsersConfigMapName stores the parsers file rendered from the pipeline, the ConfigMap is mounted
+	// into Fluent Bit at ParsersFile. Parsers stored under ParsersConfigMapData before they were managed
+	// by the pipeline are imported once.
+	ParsersConfigMapName = "fluent-bit-parsers-config"
+	ParsersConfigMapData = "parsers"
+	ParsersConfigFile    = "parsers_custom.conf"
+	ParsersFile          = "/fluent-bit/parsers/" + ParsersConfigFile
+
+	ParserFormatJSON  = "json"
+	ParserFormatRegex = "regex"
+
+	// name prefix of parser filter plugins in the Fluent Bit CRD
+	parserFilterPrefix = "fluentbit-filter-parser-"
+	// parser filters are inserted before the filter nesting kubernetes fields
+	nestFilterName = "fluentbit-filter-input-nest"
+)
+
+func FluentbitParsersQuery() *FluentbitParsersResult {
+	var result FluentbitParsersResult
+
+	pipeline, _, _, err := getFluentbitPipeline()
+	if err != nil {
+		result.Status = http.StatusInternalServerError
+		result.Error = err.Error()
+		return &result
+	}
+
+	result.Parsers = pipeline.Parsers
+	result.Status = http.StatusOK
+
+	return &result
+}
+
+func FluentbitParserInsert(parser FluentbitParser) *FluentbitParsersResult {
+	return updateFluentbitParsers(func(parsers []FluentbitParser) ([]FluentbitParser, error) {
+		if indexOfParser(parsers, parser.Name) >= 0 {
+			return nil, pipelineError(http.StatusConflict, fmt.Sprintf("The parser %s already exists.", parser.Name))
+		}
+
+		parser.Updatetime = time.Now()
+		return append(parsers, parser), nil
+	})
+}
+
+func FluentbitParserUpdate(parser FluentbitParser, name string) *FluentbitParsersResult {
+	return updateFluentbitParsers(func(parsers []FluentbitParser) ([]FluentbitParser, error) {
+		index := indexOfParser(parsers, name)
+		if index < 0 {
+			return nil, pipelineError(http.StatusNotFound, "The parser to update doesn't exist. Please check the parser name you provide.")
+		}
+
+		parser.Name = name
+		parser.Updatetime = time.Now()
+		parsers[index] = parser
+		return parsers, nil
+	})
+}
+
+func FluentbitParserDelete(name string) *FluentbitParsersResult {
+	return updateFluentbitParsers(func(parsers []FluentbitParser) ([]FluentbitParser, error) {
+		index := indexOfParser(parsers, name)
+		if index < 0 {
+			return nil, pipelineError(http.StatusNotFound, "The parser to delete doesn't exist. Please check the parser name you provide.")
+		}
+
+		return append(parsers[:index], parsers[index+1:]...), nil
+	})
+}
+
+func updateFluentbitParsers(change func(parsers []FluentbitParser) ([]FluentbitParser, error)) *FluentbitParsersResult {
+	var result FluentbitParsersResult
+
+	_, err := updateFluentbitPipeline(func(pipeline *FluentbitPipeline) error {
+		parsers, err := change(pipeline.Parsers)
+		if err != nil {
+			return err
+		}
+		pipeline.Parsers = parsers
+		result.Parsers = parsers
+		return nil
+	}, false)
+	if err != nil {
+		result.Parsers = nil
+		result.Status = errorStatus(err)
+		result.Error = err.Error()
+		return &result
+	}
+
+	result.Status = http.StatusOK
+	return &result
+}
+
+func indexOfParser(parsers []FluentbitParser, name string) int {
+	for i, parser := range parsers {
+		if parser.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// named groups of fluent bit (onigmo) regular expressions
+var namedGroup = regexp.MustCompile(`\(\?<([A-Za-z_][A-Za-z0-9_]*)>`)
+
+func validateParser(parser FluentbitParser) error {
+	if errs := validation.IsDNS1123Label(parser.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid parser name %s: %s", parser.Name, strings.Join(errs, ", "))
+	}
+
+	if errs := validation.IsDNS1123Label(parser.Namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace %s: %s", parser.Namespace, strings.Join(errs, ", "))
+	}
+
+	if parser.Workload != "" {
+		if errs := validation.IsDNS1123Subdomain(parser.Workload); len(errs) > 0 {
+			return fmt.Errorf("invalid workload %s: %s", parser.Workload, strings.Join(errs, ", "))
+		}
+	}
+
+	if parser.Container != "" {
+		if errs := validation.IsDNS1123Label(parser.Container); len(errs) > 0 {
+			return fmt.Errorf("invalid container %s: %s", parser.Container, strings.Join(errs, ", "))
+		}
+	}
+
+	if err := validateSingleLine("parser settings", parser.Regex, parser.TimeKey, parser.TimeFormat); err != nil {
+		return err
+	}
+
+	switch parser.Format {
+	case ParserFormatJSON:
+	case ParserFormatRegex:
+		if !namedGroup.MatchString(parser.Regex) {
+			return fmt.Errorf("regex of the parser must contain named groups, eg. (?<level>[A-Z]+)")
+		}
+		if _, err := regexp.Compile(namedGroup.ReplaceAllString(parser.Regex, "(?P<$1>")); err != nil {
+			return fmt.Errorf("invalid regex: %s", err)
+		}
+	default:
+		return fmt.Errorf("unknown parser format %s, one of json, regex", parser.Format)
+	}
+
+	return nil
+}
+
+// renderParsers renders the parsers in the format of Fluent Bit parsers file
+func renderParsers(parsers []FluentbitParser) string {
+	var conf strings.Builder
+
+	for _, parser := range parsers {
+		conf.WriteString("[PARSER]\n")
+		fmt.Fprintf(&conf, "    Name        %s\n", parserName(parser))
+		fmt.Fprintf(&conf, "    Format      %s\n", parser.Format)
+		if parser.Format == ParserFormatRegex {
+			fmt.Fprintf(&conf, "    Regex       %s\n", parser.Regex)
+		}
+		if parser.TimeKey != "" {
+			fmt.Fprintf(&conf, "    Time_Key    %s\n", parser.TimeKey)
+			// keep the field so that it's searchable
+			conf.WriteString("    Time_Keep   On\n")
+		}
+		if parser.TimeFormat != "" {
+			fmt.Fprintf(&conf, "    Time_Format %s\n", parser.TimeFormat)
+		}
+		conf.WriteString("\n")
+	}
+
+	return conf.String()
+}
+
+func parserName(parser FluentbitParser) string {
+	return "ks-" + parser.Name
+}
+
+// characters of the random suffixes of generated names, e.g. pod-template-hash and pod names of replica sets
+const generatedNameChars = "[bcdfghjklmnpqrstvwxz2456789]"
+
+// podNameSuffix matches the suffixes of the pods of a workload: <pod-template-hash>-<random> of deployments,
+// <random> of daemon sets, jobs and replica sets, <ordinal> of stateful sets and <scheduled time>-<random> of cron jobs
+var podNameSuffix = fmt.Sprintf("(%[1]s{1,10}-%[1]s{5}|%[1]s{5}|[0-9]+|[0-9]+-%[1]s{5})", generatedNameChars)
+
+// parserFilter parses logs of the workload, tags of container logs are
+// kube.var.log.containers.<pod>_<namespace>_<container>-<container id>.log,
+// pods of other workloads prefixed with the name of the workload are not matched
+func parserFilter(parser FluentbitParser) fb.Plugin {
+	pod, container := "[^_]+", "[^_]+"
+	if parser.Workload != "" {
+		pod = regexp.QuoteMeta(parser.Workload) + "-" + podNameSuffix
+	}
+	if parser.Container != "" {
+		container = regexp.QuoteMeta(parser.Container)
+	}
+
+	return fb.Plugin{
+		Type: "fluentbit_filter",
+		Name: parserFilterPrefix + parser.Name,
+		Parameters: []fb.Parameter{
+			{Name: "Name", Value: "parser"},
+			{Name: "Match_Regex", Value: fmt.Sprintf(`^kube\.var\.log\.containers\.%s_%s_%s-[0-9a-f]{64}\.log$`, pod, regexp.QuoteMeta(parser.Namespace), container)},
+			{Name: "Key_Name", Value: "log"},
+			{Name: "Parser", Value: parserName(parser)},
+			{Name: "Reserve_Data", Value: "On"},
+			{Name: "Preserve_Key", Value: "On"},
+		},
+	}
+}
+
+// updateFluentbitParsersFile writes the parsers file mounted into Fluent Bit
+func updateFluentbitParsersFile(parsers []FluentbitParser) error {
+	conf := renderParsers(parsers)
+
+	configMapClient := k8s.Client().CoreV1().ConfigMaps(LoggingNamespace)
+
+	configMap, err := configMapClient.Get(ParsersConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: ParsersConfigMapName,
+			},
+			Data: map[string]string{ParsersConfigFile: conf},
+		}
+
+		_, err = configMapClient.Create(configMap)
+	} else if err == nil {
+		if len(configMap.Data) == 1 && configMap.Data[ParsersConfigFile] == conf {
+			return nil
+		}
+		configMap.Data = map[string]string{ParsersConfigFile: conf}
+		_, err = configMapClient.Update(configMap)
+	}
+
+	if err != nil {
+		glog.Errorln(err)
+		return err
+	}
+
+	return nil
+}
+
+// withParsersFile makes sure the service section loads the parsers file
+func withParsersFile(service []fb.Plugin) []fb.Plugin {
+	for i := range service {
+		found := false
+		for _, parameter := range service[i].Parameters {
+			if parameter.Name == "Parsers_File" && parameter.Value == ParsersFile {
+				found = true
+			}
+		}
+		if !found {
+			service[i].Parameters = append(service[i].Parameters, fb.Parameter{Name: "Parsers_File", Value: ParsersFile})
+		}
+	}
+	return service
+}
@@ -0,0 +1,61 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	fb "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
+)
+
+func TestParserFilters(t *testing.T) {
+	parser := FluentbitParser{Name: "nginx", Format: ParserFormatJSON, Namespace: "default", Workload: "nginx"}
+
+	assert.NoError(t, validateParser(parser))
+	assert.Error(t, validateParser(FluentbitParser{Name: "nginx", Format: ParserFormatRegex, Namespace: "default", Regex: "^(.*)$"}))
+
+	spec := renderFluentbitSpec(&FluentbitPipeline{Parsers: []FluentbitParser{parser}}, fb.FluentBitSpec{Service: []fb.Plugin{{Name: "fluentbit-service"}}}, nil)
+
+	filters := spec.Filter[len(spec.Filter)-2:]
+	assert.Equal(t, parserFilterPrefix+"nginx", filters[0].Name)
+	assert.Equal(t, nestFilterName, filters[1].Name)
+	assert.Equal(t, ParsersFile, getParameterValue(spec.Service[0].Parameters, "Parsers_File"))
+
+	match := regexp.MustCompile(getParameterValue(filters[0].Parameters, "Match_Regex"))
+	containerID := strings.Repeat("0a", 32)
+	for tag, matched := range map[string]bool{
+		"kube.var.log.containers.nginx-5c6b8f9d4-x7k2p_default_nginx-" + containerID + ".log":     true,
+		"kube.var.log.containers.nginx-x7k2p_default_nginx-" + containerID + ".log":               true,
+		"kube.var.log.containers.nginx-0_default_sidecar-" + containerID + ".log":                 true,
+		"kube.var.log.containers.nginx-1577836800-x7k2p_default_nginx-" + containerID + ".log":    true,
+		"kube.var.log.containers.nginx-api-5c6b8f9d4-x7k2p_default_nginx-" + containerID + ".log": false,
+		"kube.var.log.containers.nginx-ingress-x7k2p_default_nginx-" + containerID + ".log":       false,
+		"kube.var.log.containers.nginx-0_kube-system_nginx-" + containerID + ".log":               false,
+	} {
+		assert.Equal(t, matched, match.MatchString(tag), tag)
+	}
+
+	parser.Container = "app"
+	match = regexp.MustCompile(getParameterValue(parserFilter(parser).Parameters, "Match_Regex"))
+	assert.True(t, match.MatchString("kube.var.log.containers.nginx-0_default_app-"+containerID+".log"))
+	assert.False(t, match.MatchString("kube.var.log.containers.nginx-0_default_app-sidecar-"+containerID+".log"))
+}
@@ -0,0 +1,1113 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"kubesphere.io/kubesphere/pkg/informers"
+	fb "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
+)
+
+const (
+	// PipelineAnnotation of the Fluent Bit CRD stores the pipeline the CRD is rendered from
+	PipelineAnnotation = "logging.kubesphere.io/pipeline"
+
+	fluentbitName = "fluent-bit"
+
+	// name prefixes of plugins rendered from the pipeline in the Fluent Bit CRD
+	inputPluginPrefix  = "fluentbit-input-"
+	filterPluginPrefix = "fluentbit-filter-custom-"
+	outputPluginPrefix = "fluentbit-output-"
+)
+
+// FluentbitPipelineQuery returns the pipeline and the Fluent Bit configuration rendered from it
+func FluentbitPipelineQuery() *FluentbitPipelineResult {
+	var result FluentbitPipelineResult
+
+	pipeline, fluentbit, unmigrated, err := getFluentbitPipeline()
+	if err != nil {
+		result.Status = http.StatusInternalServerError
+		result.Error = err.Error()
+		return &result
+	}
+
+	result.Pipeline = pipeline
+	result.Unmigrated = unmigrated
+	result.Config = renderFluentbitConfig(fluentbit.Spec)
+	result.ParsersConfig = renderParsers(pipeline.Parsers)
+	result.Status = http.StatusOK
+
+	return &result
+}
+
+// FluentbitPipelineUpdate replaces the pipeline, the Fluent Bit configuration is rendered and validated
+// without being applied if dryRun is true
+func FluentbitPipelineUpdate(pipeline FluentbitPipeline, dryRun bool) *FluentbitPipelineResult {
+	var result FluentbitPipelineResult
+
+	spec, err := updateFluentbitPipeline(func(current *FluentbitPipeline) error {
+		now := time.Now()
+		for i := range pipeline.Outputs {
+			if pipeline.Outputs[i].Id == "" {
+				pipeline.Outputs[i].Id = uuid.New().String()
+			}
+			if pipeline.Outputs[i].Updatetime.IsZero() {
+				pipeline.Outputs[i].Updatetime = now
+			}
+		}
+		for i := range pipeline.Parsers {
+			if pipeline.Parsers[i].Updatetime.IsZero() {
+				pipeline.Parsers[i].Updatetime = now
+			}
+		}
+
+		*current = pipeline
+		return nil
+	}, dryRun)
+	if err != nil {
+		result.Status = errorStatus(err)
+		result.Error = err.Error()
+		return &result
+	}
+
+	result.Pipeline = &pipeline
+	result.Config = renderFluentbitConfig(spec)
+	result.ParsersConfig = renderParsers(pipeline.Parsers)
+	result.Status = http.StatusOK
+
+	return &result
+}
+
+// pipelineError is returned by changes of the pipeline, carrying the response status
+func pipelineError(status int, message string) error {
+	return &errors.StatusError{ErrStatus: metav1.Status{Status: metav1.StatusFailure, Code: int32(status), Message: message}}
+}
+
+func errorStatus(err error) int {
+	if status, ok := err.(errors.APIStatus); ok {
+		return int(status.Status().Code)
+	}
+	return http.StatusInternalServerError
+}
+
+// getFluentbitPipeline returns the pipeline, the Fluent Bit CRD and the settings of the ConfigMaps which can't be imported
+func getFluentbitPipeline() (*FluentbitPipeline, *fb.FluentBit, []string, error) {
+	crdcs, scheme, err := createCRDClientSet()
+	if err != nil {
+		glog.Errorln(err)
+		return nil, nil, nil, err
+	}
+
+	// Create a CRD client interface
+	crdclient := fb.CrdClient(crdcs, scheme, LoggingNamespace)
+
+	fluentbit, err := crdclient.Get(fluentbitName)
+	if err != nil {
+		glog.Errorln(err)
+		return nil, nil, nil, err
+	}
+
+	pipeline, unmigrated, err := loadFluentbitPipeline(fluentbit)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pipeline, fluentbit, unmigrated, nil
+}
+
+// updateFluentbitPipeline applies the change to the pipeline, then renders and updates the Fluent Bit CRD along
+// with the pipeline in a single write. Errors of the change should be api errors carrying the response status.
+func updateFluentbitPipeline(change func(pipeline *FluentbitPipeline) error, dryRun bool) (fb.FluentBitSpec, error) {
+	crdcs, scheme, err := createCRDClientSet()
+	if err != nil {
+		glog.Errorln(err)
+		return fb.FluentBitSpec{}, err
+	}
+
+	// Create a CRD client interface
+	crdclient := fb.CrdClient(crdcs, scheme, LoggingNamespace)
+
+	fluentbit, err := crdclient.Get(fluentbitName)
+	if err != nil {
+		glog.Errorln(err)
+		return fb.FluentBitSpec{}, err
+	}
+
+	pipeline, unmigrated, err := loadFluentbitPipeline(fluentbit)
+	if err != nil {
+		return fb.FluentBitSpec{}, err
+	}
+
+	// settings of the ConfigMaps would be lost once the imported pipeline is saved
+	if len(unmigrated) > 0 {
+		return fb.FluentBitSpec{}, pipelineError(http.StatusConflict, fmt.Sprintf("settings of ConfigMaps in %s can't be imported into the pipeline, remove them before changing the pipeline: %s",
+			LoggingNamespace, strings.Join(unmigrated, "; ")))
+	}
+
+	if err := change(pipeline); err != nil {
+		return fb.FluentBitSpec{}, err
+	}
+
+	if err := validatePipeline(pipeline); err != nil {
+		return fb.FluentBitSpec{}, pipelineError(http.StatusBadRequest, err.Error())
+	}
+
+	// If the ConfigMap doesn't exist, retentions are not set
+	retentions, err := getLogRetentions()
+	if err != nil && !errors.IsNotFound(err) {
+		glog.Errorln(err)
+		return fb.FluentBitSpec{}, err
+	}
+
+	spec := renderFluentbitSpec(pipeline, fluentbit.Spec, retentions)
+	if dryRun {
+		return spec, nil
+	}
+
+	data, err := jsonIter.MarshalToString(pipeline)
+	if err != nil {
+		return fb.FluentBitSpec{}, err
+	}
+
+	// parsers must be loaded before the filters referring them are applied
+	if err := updateFluentbitParsersFile(pipeline.Parsers); err != nil {
+		return fb.FluentBitSpec{}, err
+	}
+
+	if fluentbit.Annotations == nil {
+		fluentbit.Annotations = make(map[string]string)
+	}
+	fluentbit.Annotations[PipelineAnnotation] = data
+	fluentbit.Spec = spec
+
+	if _, err = crdclient.Update(fluentbitName, fluentbit); err != nil {
+		glog.Errorln(err)
+		return fb.FluentBitSpec{}, err
+	}
+
+	// reset the es client configs with the enabled es output
+	for _, output := range outputPlugins(pipeline.Outputs) {
+		if configs := ParseEsOutputParams(output.Parameters); output.Enable && configs != nil {
+			configs.WriteESConfigs()
+			break
+		}
+	}
+
+	return spec, nil
+}
+
+// loadFluentbitPipeline reads the pipeline from the Fluent Bit CRD. Before the pipeline is saved for the first time,
+// it's imported from the outputs and parsers ConfigMaps and the inputs and filters of the CRD, which were managed
+// separately. Settings which can't be imported are returned, the pipeline can't be saved until they are removed.
+func loadFluentbitPipeline(fluentbit *fb.FluentBit) (*FluentbitPipeline, []string, error) {
+	var pipeline FluentbitPipeline
+	var unmigrated []string
+
+	if data, ok := fluentbit.Annotations[PipelineAnnotation]; ok {
+		if err := jsonIter.UnmarshalFromString(data, &pipeline); err != nil {
+			glog.Errorln(err)
+			return nil, nil, err
+		}
+		return &pipeline, nil, nil
+	}
+
+	lister := informers.SharedInformerFactory().Core().V1().ConfigMaps().Lister().ConfigMaps(LoggingNamespace)
+
+	if configMap, err := lister.Get(ConfigMapName); err == nil {
+		var outputs []fb.OutputPlugin
+		if err := jsonIter.UnmarshalFromString(configMap.Data[ConfigMapData], &outputs); err != nil {
+			glog.Errorln(err)
+			unmigrated = append(unmigrated, fmt.Sprintf("outputs of ConfigMap %s: %s", ConfigMapName, err))
+		}
+		var dropped []string
+		pipeline.Outputs, dropped = legacyOutputs(outputs)
+		unmigrated = append(unmigrated, dropped...)
+	}
+
+	if configMap, err := lister.Get(ParsersConfigMapName); err == nil {
+		if err := jsonIter.UnmarshalFromString(configMap.Data[ParsersConfigMapData], &pipeline.Parsers); err != nil {
+			glog.Errorln(err)
+			unmigrated = append(unmigrated, fmt.Sprintf("parsers of ConfigMap %s: %s", ParsersConfigMapName, err))
+		}
+	}
+
+	var dropped []string
+	pipeline.Inputs, dropped = legacyInputs(fluentbit.Spec.Input)
+	unmigrated = append(unmigrated, dropped...)
+
+	var legacy FluentbitFiltersResult
+	getFilters(&legacy, fluentbit.Spec.Filter)
+	pipeline.Filters = legacyFilters(legacy.Filters)
+
+	return &pipeline, unmigrated, nil
+}
+
+// legacyOutputs converts the outputs of the ConfigMap, outputs and parameters not supported by the pipeline are returned
+func legacyOutputs(outputs []fb.OutputPlugin) ([]PipelineOutput, []string) {
+	var converted []PipelineOutput
+	var unmigrated []string
+
+	for i, plugin := range outputs {
+		output, unsupported, err := outputFromPlugin(plugin.Plugin)
+		if err != nil {
+			glog.Errorf("import output %s: %s", plugin.Name, err)
+			unmigrated = append(unmigrated, fmt.Sprintf("output %s: %s", plugin.Name, err))
+			continue
+		}
+		if len(unsupported) > 0 {
+			glog.Warningf("unsupported parameters %s of output %s can't be imported", strings.Join(unsupported, ", "), plugin.Name)
+			unmigrated = append(unmigrated, fmt.Sprintf("parameters %s of output %s", strings.Join(unsupported, ", "), plugin.Name))
+		}
+		if len(validation.IsDNS1123Label(output.Name)) > 0 {
+			output.Name = fmt.Sprintf("output-%d", i)
+		}
+		output.Id = plugin.Id
+		output.Enable = plugin.Enable
+		output.Updatetime = plugin.Updatetime
+		converted = append(converted, output)
+	}
+
+	return converted, unmigrated
+}
+
+func validatePipeline(pipeline *FluentbitPipeline) error {
+	names := make(map[string]bool)
+	for _, input := range pipeline.Inputs {
+		if err := validatePipelineName("input", input.Name, names); err != nil {
+			return err
+		}
+		if input.Tag == "" || strings.ContainsAny(input.Tag, " \t"+lineBreaks) {
+			return fmt.Errorf("invalid tag %q of input %s", input.Tag, input.Name)
+		}
+		if _, err := input.plugin(); err != nil {
+			return err
+		}
+	}
+
+	names = make(map[string]bool)
+	for _, parser := range pipeline.Parsers {
+		if names[parser.Name] {
+			return fmt.Errorf("duplicated parser %s", parser.Name)
+		}
+		names[parser.Name] = true
+		if err := validateParser(parser); err != nil {
+			return err
+		}
+	}
+
+	names = make(map[string]bool)
+	for _, filter := range pipeline.Filters {
+		if err := validatePipelineName("filter", filter.Name, names); err != nil {
+			return err
+		}
+		if filter.Match == "" || strings.ContainsAny(filter.Match, " \t"+lineBreaks) {
+			return fmt.Errorf("invalid match %q of filter %s", filter.Match, filter.Name)
+		}
+		if _, err := filter.plugin(); err != nil {
+			return err
+		}
+	}
+
+	names = make(map[string]bool)
+	ids := make(map[string]bool)
+	for _, output := range pipeline.Outputs {
+		if err := validatePipelineName("output", output.Name, names); err != nil {
+			return err
+		}
+		if output.Id != "" {
+			if ids[output.Id] {
+				return fmt.Errorf("duplicated output id %s", output.Id)
+			}
+			ids[output.Id] = true
+		}
+		if output.Match == "" || strings.ContainsAny(output.Match, " \t"+lineBreaks) {
+			return fmt.Errorf("invalid match %q of output %s", output.Match, output.Name)
+		}
+		if output.RetryLimit != "" && !strings.EqualFold(output.RetryLimit, "False") {
+			if limit, err := strconv.Atoi(output.RetryLimit); err != nil || limit <= 0 {
+				return fmt.Errorf("invalid retry limit %s of output %s, a positive number or False", output.RetryLimit, output.Name)
+			}
+		}
+		if _, err := output.plugin(); err != nil {
+			return err
+		}
+	}
+
+	// values are written into the configuration file line by line
+	spec := renderFluentbitSpec(pipeline, fb.FluentBitSpec{}, nil)
+	for _, plugins := range [][]fb.Plugin{spec.Input, spec.Filter, spec.Output} {
+		for _, plugin := range plugins {
+			for _, parameter := range plugin.Parameters {
+				if strings.ContainsAny(parameter.Value, lineBreaks) {
+					return fmt.Errorf("parameter %s of %s must be in a single line", parameter.Name, plugin.Name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func validatePipelineName(kind, name string, names map[string]bool) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid %s name %s: %s", kind, name, strings.Join(errs, ", "))
+	}
+	if names[name] {
+		return fmt.Errorf("duplicated %s %s", kind, name)
+	}
+	names[name] = true
+	return nil
+}
+
+// renderFluentbitSpec renders the Fluent Bit CRD from the pipeline, filters and outputs routing logs of
+// workspaces with retentions are kept
+func renderFluentbitSpec(pipeline *FluentbitPipeline, spec fb.FluentBitSpec, retentions []LogRetention) fb.FluentBitSpec {
+	if len(pipeline.Inputs) > 0 {
+		spec.Input = make([]fb.Plugin, 0, len(pipeline.Inputs))
+		for _, input := range pipeline.Inputs {
+			plugin, err := input.plugin()
+			if err != nil {
+				continue
+			}
+			spec.Input = append(spec.Input, fb.Plugin{
+				Type:       "fluentbit_input",
+				Name:       inputPluginPrefix + input.Name,
+				Parameters: append([]fb.Parameter{{Name: "Name", Value: plugin.name()}, {Name: "Tag", Value: input.Tag}}, pluginParameters(plugin)...),
+			})
+		}
+	}
+
+	filters := kubernetesFilters()
+	for _, filter := range pipeline.Filters {
+		plugin, err := filter.plugin()
+		if err != nil {
+			continue
+		}
+		filters = append(filters, fb.Plugin{
+			Type:       "fluentbit_filter",
+			Name:       filterPluginPrefix + filter.Name,
+			Parameters: append([]fb.Parameter{{Name: "Name", Value: plugin.name()}, {Name: "Match", Value: filter.Match}}, pluginParameters(plugin)...),
+		})
+	}
+	for _, parser := range pipeline.Parsers {
+		filters = append(filters, parserFilter(parser))
+	}
+	filters = append(filters, nestFilter())
+	spec.Filter = mergeRetentionPlugins(filters, retentionPluginsOf(spec.Filter))
+
+	var outputs []fb.Plugin
+	for _, output := range outputPlugins(pipeline.Outputs) {
+		if output.Enable {
+			outputs = append(outputs, output.Plugin)
+		}
+	}
+
+	// Empty output is not allowed, must specify a null-type output
+	if len(outputs) == 0 {
+		outputs = []fb.Plugin{
+			{
+				Type: "fluentbit_output",
+				Name: "fluentbit-output-null",
+				Parameters: []fb.Parameter{
+					{
+						Name:  "Name",
+						Value: "null",
+					},
+					{
+						Name:  "Match",
+						Value: "*",
+					},
+				},
+			},
+		}
+	}
+	spec.Output = mergeRetentionPlugins(outputs, retentionOutputs(retentions, outputPlugins(pipeline.Outputs)))
+
+	if len(pipeline.Parsers) > 0 {
+		spec.Service = withParsersFile(spec.Service)
+	}
+
+	return spec
+}
+
+// outputPlugins renders outputs of the pipeline
+func outputPlugins(outputs []PipelineOutput) []fb.OutputPlugin {
+	plugins := make([]fb.OutputPlugin, 0, len(outputs))
+	for _, output := range outputs {
+		plugin, err := output.plugin()
+		if err != nil {
+			continue
+		}
+
+		parameters := []fb.Parameter{{Name: "Name", Value: plugin.name()}, {Name: "Match", Value: output.Match}}
+		if output.RetryLimit != "" {
+			parameters = append(parameters, fb.Parameter{Name: "Retry_Limit", Value: output.RetryLimit})
+		}
+
+		plugins = append(plugins, fb.OutputPlugin{
+			Plugin: fb.Plugin{
+				Type:       "fluentbit_output",
+				Name:       outputPluginPrefix + output.Name,
+				Parameters: append(parameters, pluginParameters(plugin)...),
+			},
+			Id:         output.Id,
+			Enable:     output.Enable,
+			Updatetime: output.Updatetime,
+		})
+	}
+	return plugins
+}
+
+// outputFromPlugin converts the parameters of an output plugin to a typed output, parameters not supported
+// by the output are returned
+// legacyInputs converts the inputs of the CRD, inputs and parameters not supported by the pipeline are returned
+func legacyInputs(inputs []fb.Plugin) ([]PipelineInput, []string) {
+	var converted []PipelineInput
+	var unmigrated []string
+
+	for i, plugin := range inputs {
+		input, unsupported, err := inputFromPlugin(plugin)
+		if err != nil {
+			glog.Errorf("import input %s: %s", plugin.Name, err)
+			unmigrated = append(unmigrated, fmt.Sprintf("input %s: %s", plugin.Name, err))
+			continue
+		}
+		if len(unsupported) > 0 {
+			glog.Warningf("unsupported parameters %s of input %s can't be imported", strings.Join(unsupported, ", "), plugin.Name)
+			unmigrated = append(unmigrated, fmt.Sprintf("parameters %s of input %s", strings.Join(unsupported, ", "), plugin.Name))
+		}
+		if len(validation.IsDNS1123Label(input.Name)) > 0 {
+			input.Name = fmt.Sprintf("input-%d", i)
+		}
+		converted = append(converted, input)
+	}
+
+	return converted, unmigrated
+}
+
+func inputFromPlugin(plugin fb.Plugin) (PipelineInput, []string, error) {
+	input := PipelineInput{Name: strings.TrimPrefix(plugin.Name, inputPluginPrefix)}
+
+	var spec pipelinePlugin
+	switch name := getParameterValue(plugin.Parameters, "Name"); name {
+	case "tail":
+		input.Tail = &TailInput{}
+		spec = input.Tail
+	case "systemd":
+		input.Systemd = &SystemdInput{}
+		spec = input.Systemd
+	default:
+		return input, nil, fmt.Errorf("unsupported input %s, one of tail, systemd", name)
+	}
+
+	parameters := make([]fb.Parameter, 0, len(plugin.Parameters))
+	for _, parameter := range plugin.Parameters {
+		switch strings.ToLower(parameter.Name) {
+		case "name":
+		case "tag":
+			input.Tag = parameter.Value
+		default:
+			parameters = append(parameters, parameter)
+		}
+	}
+
+	unsupported, err := setPluginParameters(spec, parameters)
+	return input, unsupported, err
+}
+
+func outputFromPlugin(plugin fb.Plugin) (PipelineOutput, []string, error) {
+	output := PipelineOutput{Name: strings.TrimPrefix(plugin.Name, outputPluginPrefix)}
+
+	var spec pipelinePlugin
+	switch name := getParameterValue(plugin.Parameters, "Name"); name {
+	case "es":
+		output.Es = &ESOutput{}
+		spec = output.Es
+	case "kafka":
+		output.Kafka = &KafkaOutput{}
+		spec = output.Kafka
+	case "forward":
+		output.Forward = &ForwardOutput{}
+		spec = output.Forward
+	case "http":
+		output.HTTP = &HTTPOutput{}
+		spec = output.HTTP
+	case "stdout":
+		output.Stdout = &StdoutOutput{}
+		spec = output.Stdout
+	default:
+		return output, nil, fmt.Errorf("unsupported output %s, one of es, kafka, forward, http, stdout", name)
+	}
+
+	parameters := make([]fb.Parameter, 0, len(plugin.Parameters))
+	for _, parameter := range plugin.Parameters {
+		switch strings.ToLower(parameter.Name) {
+		case "name":
+		case "match":
+			output.Match = parameter.Value
+		case "retry_limit":
+			output.RetryLimit = parameter.Value
+		default:
+			parameters = append(parameters, parameter)
+		}
+	}
+
+	unsupported, err := setPluginParameters(spec, parameters)
+	return output, unsupported, err
+}
+
+// renderFluentbitConfig renders the Fluent Bit CRD in the format of Fluent Bit configuration file
+func renderFluentbitConfig(spec fb.FluentBitSpec) string {
+	var conf strings.Builder
+
+	sections := []struct {
+		name    string
+		plugins []fb.Plugin
+	}{
+		{"SERVICE", spec.Service},
+		{"INPUT", spec.Input},
+		{"FILTER", spec.Filter},
+		{"OUTPUT", spec.Output},
+	}
+
+	for _, section := range sections {
+		for _, plugin := range section.plugins {
+			fmt.Fprintf(&conf, "[%s]\n", section.name)
+			for _, parameter := range plugin.Parameters {
+				value := parameter.Value
+				if parameter.ValueFrom != nil {
+					ref := parameter.ValueFrom.SecretKeyRef
+					value = fmt.Sprintf("<secret %s/%s key %s>", ref.Namespace, ref.Name, ref.Key)
+				}
+				fmt.Fprintf(&conf, "    %-16s %s\n", parameter.Name, value)
+			}
+			conf.WriteString("\n")
+		}
+	}
+
+	return conf.String()
+}
+
+// kubernetesFilters enrich container logs with kubernetes metadata, which is lifted into kubernetes_* fields
+// so that filters can refer to them
+func kubernetesFilters() []fb.Plugin {
+	var filter []fb.Plugin
+
+	var para_kubernetes []fb.Parameter
+	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Name", Value: "kubernetes"})
+	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Match", Value: "kube.*"})
+	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Kube_URL", Value: "https://kubernetes.default.svc:443"})
+	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Kube_CA_File", Value: "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"})
+	para_kubernetes = append(para_kubernetes, fb.Parameter{Name: "Kube_Token_File", Value: "/var/run/secrets/kubernetes.io/serviceaccount/token"})
+	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-kubernetes", Parameters: para_kubernetes})
+
+	var para_lift []fb.Parameter
+	para_lift = append(para_lift, fb.Parameter{Name: "Name", Value: "nest"})
+	para_lift = append(para_lift, fb.Parameter{Name: "Match", Value: "kube.*"})
+	para_lift = append(para_lift, fb.Parameter{Name: "Operation", Value: "lift"})
+	para_lift = append(para_lift, fb.Parameter{Name: "Nested_under", Value: "kubernetes"})
+	para_lift = append(para_lift, fb.Parameter{Name: "Prefix_with", Value: "kubernetes_"})
+	filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-lift", Parameters: para_lift})
+
+	for _, field := range []struct{ name, key string }{
+		{"stream", "stream"},
+		{"labels", "kubernetes_labels"},
+		{"annotations", "kubernetes_annotations"},
+		{"podid", "kubernetes_pod_id"},
+		{"dockerid", "kubernetes_docker_id"},
+	} {
+		var para_remove []fb.Parameter
+		para_remove = append(para_remove, fb.Parameter{Name: "Name", Value: "modify"})
+		para_remove = append(para_remove, fb.Parameter{Name: "Match", Value: "kube.*"})
+		para_remove = append(para_remove, fb.Parameter{Name: "Remove", Value: field.key})
+		filter = append(filter, fb.Plugin{Type: "fluentbit_filter", Name: "fluentbit-filter-input-remove-" + field.name, Parameters: para_remove})
+	}
+
+	return filter
+}
+
+// nestFilter nests the kubernetes_* fields under kubernetes again, it's the last filter of container logs
+func nestFilter() fb.Plugin {
+	var para_nest []fb.Parameter
+	para_nest = append(para_nest, fb.Parameter{Name: "Name", Value: "nest"})
+	para_nest = append(para_nest, fb.Parameter{Name: "Match", Value: "kube.*"})
+	para_nest = append(para_nest, fb.Parameter{Name: "Operation", Value: "nest"})
+	para_nest = append(para_nest, fb.Parameter{Name: "Wildcard", Value: "kubernetes_*"})
+	para_nest = append(para_nest, fb.Parameter{Name: "Nested_under", Value: "kubernetes"})
+	para_nest = append(para_nest, fb.Parameter{Name: "Remove_prefix", Value: "kubernetes_"})
+	return fb.Plugin{Type: "fluentbit_filter", Name: nestFilterName, Parameters: para_nest}
+}
+
+// pipelinePlugin is the typed configuration of a Fluent Bit plugin, fields tagged with fluentbit are
+// rendered into parameters
+type pipelinePlugin interface {
+	// name of the Fluent Bit plugin
+	name() string
+	validate() error
+}
+
+func (input PipelineInput) plugin() (pipelinePlugin, error) {
+	plugin, err := onlyPlugin(input.Tail, input.Systemd)
+	if err != nil {
+		return nil, fmt.Errorf("input %s must specify exactly one of tail, systemd", input.Name)
+	}
+	if err := plugin.validate(); err != nil {
+		return nil, fmt.Errorf("invalid input %s: %s", input.Name, err)
+	}
+	return plugin, nil
+}
+
+func (filter PipelineFilter) plugin() (pipelinePlugin, error) {
+	plugin, err := onlyPlugin(filter.Grep, filter.Modify, filter.RecordModifier, filter.Lua, filter.Throttle)
+	if err != nil {
+		return nil, fmt.Errorf("filter %s must specify exactly one of grep, modify, recordModifier, lua, throttle", filter.Name)
+	}
+	if err := plugin.validate(); err != nil {
+		return nil, fmt.Errorf("invalid filter %s: %s", filter.Name, err)
+	}
+	return plugin, nil
+}
+
+func (output PipelineOutput) plugin() (pipelinePlugin, error) {
+	plugin, err := onlyPlugin(output.Es, output.Kafka, output.Forward, output.HTTP, output.Stdout)
+	if err != nil {
+		return nil, fmt.Errorf("output %s must specify exactly one of es, kafka, forward, http, stdout", output.Name)
+	}
+	if err := plugin.validate(); err != nil {
+		return nil, fmt.Errorf("invalid output %s: %s", output.Name, err)
+	}
+	return plugin, nil
+}
+
+func onlyPlugin(plugins ...pipelinePlugin) (pipelinePlugin, error) {
+	var only pipelinePlugin
+	for _, plugin := range plugins {
+		if reflect.ValueOf(plugin).IsNil() {
+			continue
+		}
+		if only != nil {
+			return nil, fmt.Errorf("multiple plugins")
+		}
+		only = plugin
+	}
+	if only == nil {
+		return nil, fmt.Errorf("no plugin")
+	}
+	return only, nil
+}
+
+var (
+	// size of Fluent Bit, eg. 5MB, 512k
+	sizePattern = regexp.MustCompile(`^[0-9]+([kKmMgG][bB]?)?$`)
+	// time of Fluent Bit, eg. 1s, 1m
+	intervalPattern   = regexp.MustCompile(`^[0-9]+[smhd]?$`)
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// lineBreaks must not be in any value, the configuration files are written line by line
+const lineBreaks = "\r\n"
+
+func (*TailInput) name() string { return "tail" }
+
+func (in *TailInput) validate() error {
+	if in.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if err := validateSingleLine("tail settings", in.Path, in.ExcludePath, in.Parser, in.DB); err != nil {
+		return err
+	}
+	if in.RefreshInterval < 0 {
+		return fmt.Errorf("refresh interval must not be negative")
+	}
+	if in.MemBufLimit != "" && !sizePattern.MatchString(in.MemBufLimit) {
+		return fmt.Errorf("invalid mem buf limit %s, eg. 5MB", in.MemBufLimit)
+	}
+	return nil
+}
+
+func (*SystemdInput) name() string { return "systemd" }
+
+func (in *SystemdInput) validate() error {
+	for _, filter := range in.SystemdFilter {
+		if !strings.Contains(filter, "=") {
+			return fmt.Errorf("invalid systemd filter %s, eg. _SYSTEMD_UNIT=kubelet.service", filter)
+		}
+	}
+	return validateSingleLine("systemd settings", append([]string{in.Path, in.DB}, in.SystemdFilter...)...)
+}
+
+func (*GrepFilter) name() string { return "grep" }
+
+func (f *GrepFilter) validate() error {
+	if len(f.Regex)+len(f.Exclude) == 0 {
+		return fmt.Errorf("at least one of regex, exclude is required")
+	}
+	for _, rule := range append(append([]PipelineKeyValue{}, f.Regex...), f.Exclude...) {
+		if err := validateField(rule.Key); err != nil {
+			return err
+		}
+		if err := validateSingleLine("regex of "+rule.Key, rule.Value); err != nil {
+			return err
+		}
+		if _, err := regexp.Compile(namedGroup.ReplaceAllString(rule.Value, "(?P<$1>")); err != nil {
+			return fmt.Errorf("invalid regex of %s: %s", rule.Key, err)
+		}
+	}
+	return nil
+}
+
+func (*ModifyFilter) name() string { return "modify" }
+
+func (f *ModifyFilter) validate() error {
+	if len(f.Set)+len(f.Add)+len(f.Rename)+len(f.Copy)+len(f.Remove) == 0 {
+		return fmt.Errorf("at least one of set, add, rename, copy, remove is required")
+	}
+	for _, pairs := range [][]PipelineKeyValue{f.Set, f.Add} {
+		if err := validatePairs(pairs, false); err != nil {
+			return err
+		}
+	}
+	for _, pairs := range [][]PipelineKeyValue{f.Rename, f.Copy} {
+		if err := validatePairs(pairs, true); err != nil {
+			return err
+		}
+	}
+	return validateFields(f.Remove)
+}
+
+func (*RecordModifierFilter) name() string { return "record_modifier" }
+
+func (f *RecordModifierFilter) validate() error {
+	if len(f.Records)+len(f.RemoveKeys)+len(f.WhitelistKeys) == 0 {
+		return fmt.Errorf("at least one of records, removeKeys, whitelistKeys is required")
+	}
+	if err := validatePairs(f.Records, false); err != nil {
+		return err
+	}
+	if err := validateFields(f.RemoveKeys); err != nil {
+		return err
+	}
+	return validateFields(f.WhitelistKeys)
+}
+
+func (*LuaFilter) name() string { return "lua" }
+
+func (f *LuaFilter) validate() error {
+	if !strings.HasPrefix(f.Script, "/") || strings.ContainsAny(f.Script, lineBreaks) {
+		return fmt.Errorf("script must be an absolute path")
+	}
+	if !identifierPattern.MatchString(f.Call) {
+		return fmt.Errorf("invalid lua function %s", f.Call)
+	}
+	return nil
+}
+
+func (*ThrottleFilter) name() string { return "throttle" }
+
+func (f *ThrottleFilter) validate() error {
+	if f.Rate <= 0 || f.Window <= 0 {
+		return fmt.Errorf("rate and window must be positive")
+	}
+	if !intervalPattern.MatchString(f.Interval) {
+		return fmt.Errorf("invalid interval %s, eg. 1s, 1m", f.Interval)
+	}
+	return nil
+}
+
+func (*ESOutput) name() string { return "es" }
+
+func (o *ESOutput) validate() error {
+	if err := validateHost(o.Host, o.Port); err != nil {
+		return err
+	}
+	if o.BufferSize != "" && !strings.EqualFold(o.BufferSize, "False") && !sizePattern.MatchString(o.BufferSize) {
+		return fmt.Errorf("invalid buffer size %s, eg. 512KB", o.BufferSize)
+	}
+	return nil
+}
+
+func (*KafkaOutput) name() string { return "kafka" }
+
+func (o *KafkaOutput) validate() error {
+	if len(o.Brokers) == 0 || len(o.Topics) == 0 {
+		return fmt.Errorf("brokers and topics are required")
+	}
+	for _, broker := range o.Brokers {
+		host, port, err := splitHostPort(broker)
+		if err != nil {
+			return fmt.Errorf("invalid broker %s, eg. kafka-0:9092", broker)
+		}
+		if err := validateHost(host, port); err != nil {
+			return err
+		}
+	}
+	for _, topic := range o.Topics {
+		if topic == "" || strings.ContainsAny(topic, " ,"+lineBreaks) {
+			return fmt.Errorf("invalid topic %q", topic)
+		}
+	}
+	return validateOneOf("format", o.Format, "json", "msgpack", "gelf")
+}
+
+func (*ForwardOutput) name() string { return "forward" }
+
+func (o *ForwardOutput) validate() error {
+	return validateHost(o.Host, o.Port)
+}
+
+func (*HTTPOutput) name() string { return "http" }
+
+func (o *HTTPOutput) validate() error {
+	if err := validateHost(o.Host, o.Port); err != nil {
+		return err
+	}
+	if o.URI != "" && !strings.HasPrefix(o.URI, "/") {
+		return fmt.Errorf("uri must start with /")
+	}
+	if err := validateSingleLine("uri", o.URI); err != nil {
+		return err
+	}
+	if err := validatePairs(o.Headers, false); err != nil {
+		return err
+	}
+	if err := validateOneOf("format", o.Format, "msgpack", "json", "json_lines", "json_stream", "gelf"); err != nil {
+		return err
+	}
+	return validateOneOf("json date format", o.JSONDateFormat, "double", "iso8601", "epoch")
+}
+
+func (*StdoutOutput) name() string { return "stdout" }
+
+func (o *StdoutOutput) validate() error {
+	if err := validateOneOf("format", o.Format, "msgpack", "json", "json_lines", "json_stream"); err != nil {
+		return err
+	}
+	return validateOneOf("json date format", o.JSONDateFormat, "double", "iso8601", "epoch")
+}
+
+func validateHost(host string, port int) error {
+	if host == "" || strings.ContainsAny(host, " \t/"+lineBreaks) {
+		return fmt.Errorf("invalid host %q", host)
+	}
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("invalid port %d", port)
+	}
+	return nil
+}
+
+func splitHostPort(address string) (string, int, error) {
+	i := strings.LastIndex(address, ":")
+	if i < 0 {
+		return "", 0, fmt.Errorf("missing port")
+	}
+	port, err := strconv.Atoi(address[i+1:])
+	if err != nil || port == 0 {
+		return "", 0, fmt.Errorf("invalid port")
+	}
+	return address[:i], port, nil
+}
+
+func validateOneOf(name, value string, values ...string) error {
+	if value == "" {
+		return nil
+	}
+	for _, v := range values {
+		if value == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s %s, one of %s", name, value, strings.Join(values, ", "))
+}
+
+func validateField(field string) error {
+	if field == "" || strings.ContainsAny(field, " \t"+lineBreaks) {
+		return fmt.Errorf("invalid field %q", field)
+	}
+	return nil
+}
+
+func validateFields(fields []string) error {
+	for _, field := range fields {
+		if err := validateField(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePairs validates fields and values, values are field names as well if fieldValue is true
+func validatePairs(pairs []PipelineKeyValue, fieldValue bool) error {
+	for _, pair := range pairs {
+		if err := validateField(pair.Key); err != nil {
+			return err
+		}
+		if fieldValue {
+			if err := validateField(pair.Value); err != nil {
+				return err
+			}
+		} else if pair.Value == "" {
+			return fmt.Errorf("value of %s is required", pair.Key)
+		} else if err := validateSingleLine("value of "+pair.Key, pair.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSingleLine(name string, values ...string) error {
+	for _, value := range values {
+		if strings.ContainsAny(value, lineBreaks) {
+			return fmt.Errorf("%s must be in a single line", name)
+		}
+	}
+	return nil
+}
+
+// pluginParameters renders the fields of the plugin tagged with fluentbit:"<parameter>[,repeat|comma]", lists are
+// rendered into a parameter per item with repeat, or a comma separated parameter with comma, pairs are rendered as
+// "<key> <value>" and empty fields are omitted
+func pluginParameters(plugin pipelinePlugin) []fb.Parameter {
+	var parameters []fb.Parameter
+
+	v := reflect.Indirect(reflect.ValueOf(plugin))
+	for i := 0; i < v.NumField(); i++ {
+		name, option := parseFluentbitTag(v.Type().Field(i).Tag.Get("fluentbit"))
+		if name == "" {
+			continue
+		}
+
+		switch value := v.Field(i).Interface().(type) {
+		case string:
+			if value != "" {
+				parameters = append(parameters, fb.Parameter{Name: name, Value: value})
+			}
+		case int:
+			if value != 0 {
+				parameters = append(parameters, fb.Parameter{Name: name, Value: strconv.Itoa(value)})
+			}
+		case bool:
+			if value {
+				parameters = append(parameters, fb.Parameter{Name: name, Value: "On"})
+			}
+		case []string:
+			if option == "comma" {
+				if len(value) > 0 {
+					parameters = append(parameters, fb.Parameter{Name: name, Value: strings.Join(value, ",")})
+				}
+				continue
+			}
+			for _, item := range value {
+				parameters = append(parameters, fb.Parameter{Name: name, Value: item})
+			}
+		case []PipelineKeyValue:
+			for _, pair := range value {
+				parameters = append(parameters, fb.Parameter{Name: name, Value: pair.Key + " " + pair.Value})
+			}
+		case *SecretValue:
+			if value != nil {
+				parameters = append(parameters, fb.Parameter{Name: name, Value: value.Value, ValueFrom: value.ValueFrom})
+			}
+		}
+	}
+
+	return parameters
+}
+
+// setPluginParameters sets the fields of the plugin from parameters, which are matched case-insensitively as
+// Fluent Bit does. Parameters not supported by the plugin are returned.
+func setPluginParameters(plugin pipelinePlugin, parameters []fb.Parameter) ([]string, error) {
+	var unsupported []string
+
+	v := reflect.ValueOf(plugin).Elem()
+
+	for _, parameter := range parameters {
+		found := false
+
+		for i := 0; i < v.NumField(); i++ {
+			name, option := parseFluentbitTag(v.Type().Field(i).Tag.Get("fluentbit"))
+			if name == "" || !strings.EqualFold(name, parameter.Name) {
+				continue
+			}
+			found = true
+
+			field := v.Field(i)
+			switch field.Interface().(type) {
+			case string:
+				field.SetString(parameter.Value)
+			case int:
+				value, err := strconv.Atoi(parameter.Value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s %s: %s", parameter.Name, parameter.Value, err)
+				}
+				field.SetInt(int64(value))
+			case bool:
+				switch strings.ToLower(parameter.Value) {
+				case "on", "true", "yes", "1":
+					field.SetBool(true)
+				case "off", "false", "no", "0":
+					field.SetBool(false)
+				default:
+					return nil, fmt.Errorf("invalid %s %s, one of On, Off", parameter.Name, parameter.Value)
+				}
+			case []string:
+				values := []string{parameter.Value}
+				if option == "comma" {
+					values = strings.Split(parameter.Value, ",")
+					for j := range values {
+						values[j] = strings.TrimSpace(values[j])
+					}
+				}
+				field.Set(reflect.AppendSlice(field, reflect.ValueOf(values)))
+			case []PipelineKeyValue:
+				pair := strings.SplitN(strings.TrimSpace(parameter.Value), " ", 2)
+				if len(pair) != 2 {
+					return nil, fmt.Errorf("invalid %s %s, eg. <key> <value>", parameter.Name, parameter.Value)
+				}
+				field.Set(reflect.Append(field, reflect.ValueOf(PipelineKeyValue{Key: pair[0], Value: strings.TrimSpace(pair[1])})))
+			case *SecretValue:
+				field.Set(reflect.ValueOf(&SecretValue{Value: parameter.Value, ValueFrom: parameter.ValueFrom}))
+			}
+		}
+
+		if !found {
+			unsupported = append(unsupported, parameter.Name)
+		}
+	}
+
+	return unsupported, nil
+}
+
+func parseFluentbitTag(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
@@ -0,0 +1,235 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	fb "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
+)
+
+func TestRenderPipeline(t *testing.T) {
+	pipeline := &FluentbitPipeline{
+		Inputs: []PipelineInput{
+			{Name: "containers", Tag: "kube.*", Tail: &TailInput{Path: "/var/log/containers/*.log", Parser: "docker", RefreshInterval: 10, SkipLongLines: true}},
+		},
+		Filters: []PipelineFilter{
+			{Name: "errors", Match: "kube.*", Grep: &GrepFilter{Regex: []PipelineKeyValue{{Key: "log", Value: "(?<level>ERROR|WARN)"}}}},
+			{Name: "cluster", Match: "kube.*", RecordModifier: &RecordModifierFilter{Records: []PipelineKeyValue{{Key: "cluster", Value: "host"}}, RemoveKeys: []string{"time"}}},
+		},
+		Outputs: []PipelineOutput{
+			{Name: "kafka", Match: "kube.*", Enable: true, RetryLimit: "False", Kafka: &KafkaOutput{Brokers: []string{"kafka-0:9092", "kafka-1:9092"}, Topics: []string{"logs"}}},
+			{Name: "debug", Match: "*", Stdout: &StdoutOutput{}},
+		},
+	}
+
+	assert.NoError(t, validatePipeline(pipeline))
+
+	spec := renderFluentbitSpec(pipeline, fb.FluentBitSpec{Filter: []fb.Plugin{{Name: retentionPluginPrefix + "demo"}}}, nil)
+
+	assert.Len(t, spec.Input, 1)
+	assert.Equal(t, []fb.Parameter{
+		{Name: "Name", Value: "tail"},
+		{Name: "Tag", Value: "kube.*"},
+		{Name: "Path", Value: "/var/log/containers/*.log"},
+		{Name: "Parser", Value: "docker"},
+		{Name: "Refresh_Interval", Value: "10"},
+		{Name: "Skip_Long_Lines", Value: "On"},
+	}, spec.Input[0].Parameters)
+
+	names := make([]string, 0)
+	for _, filter := range spec.Filter {
+		names = append(names, filter.Name)
+	}
+	assert.Equal(t, filterPluginPrefix+"errors", names[len(names)-4])
+	assert.Equal(t, filterPluginPrefix+"cluster", names[len(names)-3])
+	assert.Equal(t, nestFilterName, names[len(names)-2])
+	assert.Equal(t, retentionPluginPrefix+"demo", names[len(names)-1])
+
+	// disabled outputs are not rendered
+	assert.Len(t, spec.Output, 1)
+	assert.Equal(t, "kafka-0:9092,kafka-1:9092", getParameterValue(spec.Output[0].Parameters, "Brokers"))
+	assert.Equal(t, "False", getParameterValue(spec.Output[0].Parameters, "Retry_Limit"))
+
+	conf := renderFluentbitConfig(spec)
+	assert.Contains(t, conf, "[INPUT]\n    Name             tail\n")
+	assert.Contains(t, conf, "    Record           cluster host\n")
+	assert.Equal(t, 1, strings.Count(conf, "[OUTPUT]"))
+}
+
+func TestValidatePipeline(t *testing.T) {
+	tests := []FluentbitPipeline{
+		{Filters: []PipelineFilter{{Name: "both", Match: "kube.*", Grep: &GrepFilter{Exclude: []PipelineKeyValue{{Key: "log", Value: "debug"}}}, Modify: &ModifyFilter{Remove: []string{"log"}}}}},
+		{Filters: []PipelineFilter{{Name: "none", Match: "kube.*"}}},
+		{Filters: []PipelineFilter{{Name: "regex", Match: "kube.*", Grep: &GrepFilter{Regex: []PipelineKeyValue{{Key: "log", Value: "(error"}}}}}},
+		{Filters: []PipelineFilter{{Name: "lua", Match: "kube.*", Lua: &LuaFilter{Script: "/fluent-bit/scripts/filter.lua", Call: "os.exit()"}}}},
+		{Filters: []PipelineFilter{{Name: "throttle", Match: "kube.*", Throttle: &ThrottleFilter{Rate: 100, Window: 5, Interval: "1 second"}}}},
+		{Filters: []PipelineFilter{{Name: "Upper", Match: "kube.*", Modify: &ModifyFilter{Remove: []string{"log"}}}}},
+		{Filters: []PipelineFilter{{Name: "newline", Match: "kube.*", Modify: &ModifyFilter{Set: []PipelineKeyValue{{Key: "a", Value: "b\n[OUTPUT]"}}}}}},
+		{Outputs: []PipelineOutput{{Name: "es", Match: "kube.*", Es: &ESOutput{Host: "elasticsearch", Port: 92000}}}},
+		{Outputs: []PipelineOutput{{Name: "kafka", Match: "kube.*", Kafka: &KafkaOutput{Brokers: []string{"kafka-0"}, Topics: []string{"logs"}}}}},
+		{Outputs: []PipelineOutput{{Name: "http", Match: "kube.*", HTTP: &HTTPOutput{Host: "collector", Format: "xml"}}}},
+		{Outputs: []PipelineOutput{{Name: "stdout", Stdout: &StdoutOutput{}}}},
+		{Outputs: []PipelineOutput{{Name: "stdout", Match: "*", Stdout: &StdoutOutput{}}, {Name: "stdout", Match: "*", Stdout: &StdoutOutput{}}}},
+		{Inputs: []PipelineInput{{Name: "tail", Tag: "kube.*", Tail: &TailInput{Path: "/var/log/*.log", MemBufLimit: "5 MB"}}}},
+	}
+
+	// line breaks would start new sections or parameters in the configuration files
+	lineBreaks := []FluentbitPipeline{
+		{Inputs: []PipelineInput{{Name: "tag", Tag: "kube.*\n", Tail: &TailInput{Path: "/var/log/*.log"}}}},
+		{Inputs: []PipelineInput{{Name: "tail", Tag: "kube.*", Tail: &TailInput{Path: "/var/log/*.log\r\n[OUTPUT]"}}}},
+		{Inputs: []PipelineInput{{Name: "systemd", Tag: "service.*", Systemd: &SystemdInput{SystemdFilter: []string{"_SYSTEMD_UNIT=kubelet.service\nName stdout"}}}}},
+		{Filters: []PipelineFilter{{Name: "match", Match: "kube.*\r", Modify: &ModifyFilter{Remove: []string{"log"}}}}},
+		{Filters: []PipelineFilter{{Name: "field", Match: "kube.*", Modify: &ModifyFilter{Remove: []string{"log\n[OUTPUT]"}}}}},
+		{Filters: []PipelineFilter{{Name: "regex", Match: "kube.*", Grep: &GrepFilter{Regex: []PipelineKeyValue{{Key: "log", Value: "error\n"}}}}}},
+		{Filters: []PipelineFilter{{Name: "record", Match: "kube.*", RecordModifier: &RecordModifierFilter{Records: []PipelineKeyValue{{Key: "cluster", Value: "host\r"}}}}}},
+		{Outputs: []PipelineOutput{{Name: "match", Match: "*\n", Stdout: &StdoutOutput{}}}},
+		{Outputs: []PipelineOutput{{Name: "host", Match: "*", Forward: &ForwardOutput{Host: "fluentd\n", Port: 24224}}}},
+		{Outputs: []PipelineOutput{{Name: "header", Match: "*", HTTP: &HTTPOutput{Host: "collector", Headers: []PipelineKeyValue{{Key: "X-Cluster", Value: "host\r\n"}}}}}},
+	}
+
+	for i, test := range lineBreaks {
+		err := validatePipeline(&test)
+		// rejected by the validators of the fields rather than the check of the rendered parameters
+		if assert.Error(t, err, "case %d", i) {
+			assert.False(t, strings.HasPrefix(err.Error(), "parameter "), "case %d: %s", i, err)
+		}
+	}
+
+	for i, test := range tests {
+		assert.Error(t, validatePipeline(&test), "case %d", i)
+	}
+}
+
+func TestOutputFromPlugin(t *testing.T) {
+	plugin := fb.Plugin{
+		Type: "fluentbit_output",
+		Name: "fluentbit-output-es",
+		Parameters: []fb.Parameter{
+			{Name: "Name", Value: "es"},
+			{Name: "Match", Value: "kube.*"},
+			{Name: "Host", Value: "elasticsearch-logging-data.kubesphere-logging-system.svc"},
+			{Name: "Port", Value: "9200"},
+			{Name: "Logstash_Format", Value: "On"},
+			{Name: "Replace_Dots", Value: "on"},
+			{Name: "Retry_Limit", Value: "False"},
+			{Name: "Type", Value: "flb_type"},
+			{Name: "Time_Key", Value: "@timestamp"},
+			{Name: "Logstash_Prefix", Value: "ks-logstash-log"},
+			{Name: "HTTP_Passwd", ValueFrom: &fb.ValueFrom{SecretKeyRef: fb.KubernetesSecret{Name: "es", Key: "password"}}},
+			{Name: "Trace_Output", Value: "On"},
+		},
+	}
+
+	output, unsupported, err := outputFromPlugin(plugin)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Trace_Output"}, unsupported)
+	assert.Equal(t, "es", output.Name)
+	assert.Equal(t, "kube.*", output.Match)
+	assert.Equal(t, "False", output.RetryLimit)
+	assert.Equal(t, &ESOutput{
+		Host:           "elasticsearch-logging-data.kubesphere-logging-system.svc",
+		Port:           9200,
+		Type:           "flb_type",
+		LogstashFormat: true,
+		LogstashPrefix: "ks-logstash-log",
+		TimeKey:        "@timestamp",
+		HTTPPasswd:     &SecretValue{ValueFrom: &fb.ValueFrom{SecretKeyRef: fb.KubernetesSecret{Name: "es", Key: "password"}}},
+		ReplaceDots:    true,
+	}, output.Es)
+
+	output.Enable = true
+	rendered := outputPlugins([]PipelineOutput{output})
+	assert.Len(t, rendered, 1)
+	assert.Equal(t, "fluentbit-output-es", rendered[0].Name)
+	assert.Equal(t, "ks-logstash-log", ParseEsOutputParams(rendered[0].Parameters).Index)
+
+	_, _, err = outputFromPlugin(fb.Plugin{Parameters: []fb.Parameter{{Name: "Name", Value: "s3"}}})
+	assert.Error(t, err)
+}
+
+func TestLegacyFilters(t *testing.T) {
+	filters := legacyFilters([]FluentbitFilter{{Type: "Regex", Field: "namespace", Expression: "^kubesphere"}, {Type: "Exclude", Field: "pod", Expression: "debug"}})
+
+	assert.Len(t, filters, 2)
+	assert.True(t, isLegacyFilter(filters[0]))
+	assert.Equal(t, []PipelineKeyValue{{Key: "kubernetes_namespace_name", Value: "^kubesphere"}}, filters[0].Grep.Regex)
+	assert.Equal(t, []PipelineKeyValue{{Key: "kubernetes_pod_name", Value: "debug"}}, filters[1].Grep.Exclude)
+	assert.NoError(t, validatePipeline(&FluentbitPipeline{Filters: filters}))
+}
+
+func TestLegacyOutputs(t *testing.T) {
+	outputs, unmigrated := legacyOutputs([]fb.OutputPlugin{
+		{Plugin: fb.Plugin{Name: "fluentbit-output-stdout", Parameters: []fb.Parameter{{Name: "Name", Value: "stdout"}, {Name: "Match", Value: "*"}}}, Id: "1", Enable: true},
+		{Plugin: fb.Plugin{Name: "fluentbit-output-es", Parameters: []fb.Parameter{{Name: "Name", Value: "es"}, {Name: "Match", Value: "kube.*"}, {Name: "Host", Value: "es"}, {Name: "Port", Value: "9200"}, {Name: "Trace_Output", Value: "On"}}}, Id: "2"},
+		{Plugin: fb.Plugin{Name: "fluentbit-output-s3", Parameters: []fb.Parameter{{Name: "Name", Value: "s3"}}}, Id: "3"},
+	})
+
+	assert.Len(t, outputs, 2)
+	assert.Equal(t, "1", outputs[0].Id)
+	assert.True(t, outputs[0].Enable)
+	assert.Len(t, unmigrated, 2)
+	assert.Equal(t, "parameters Trace_Output of output fluentbit-output-es", unmigrated[0])
+	assert.Contains(t, unmigrated[1], "output fluentbit-output-s3")
+}
+
+func TestLegacyInputs(t *testing.T) {
+	inputs, unmigrated := legacyInputs([]fb.Plugin{
+		{Type: "fluentbit_input", Name: "fluentbit-input-tail", Parameters: []fb.Parameter{
+			{Name: "Name", Value: "tail"},
+			{Name: "Path", Value: "/var/log/containers/*.log"},
+			{Name: "Parser", Value: "docker"},
+			{Name: "Tag", Value: "kube.*"},
+			{Name: "Refresh_Interval", Value: "10"},
+			{Name: "Skip_Long_Lines", Value: "true"},
+			{Name: "DB", Value: "/fluent-bit/tail/pos.db"},
+			{Name: "Mem_Buf_Limit", Value: "5MB"},
+		}},
+		{Type: "fluentbit_input", Name: "fluentbit-input-systemd", Parameters: []fb.Parameter{
+			{Name: "Name", Value: "systemd"},
+			{Name: "Tag", Value: "service.*"},
+			{Name: "Systemd_Filter", Value: "_SYSTEMD_UNIT=docker.service"},
+			{Name: "Systemd_Filter", Value: "_SYSTEMD_UNIT=kubelet.service"},
+			{Name: "Max_Entries", Value: "1000"},
+		}},
+		{Type: "fluentbit_input", Name: "fluentbit-input-cpu", Parameters: []fb.Parameter{{Name: "Name", Value: "cpu"}}},
+	})
+
+	assert.Len(t, inputs, 2)
+	assert.Equal(t, PipelineInput{
+		Name: "tail",
+		Tag:  "kube.*",
+		Tail: &TailInput{Path: "/var/log/containers/*.log", Parser: "docker", DB: "/fluent-bit/tail/pos.db", RefreshInterval: 10, MemBufLimit: "5MB", SkipLongLines: true},
+	}, inputs[0])
+	assert.Equal(t, "systemd", inputs[1].Name)
+	assert.Equal(t, []string{"_SYSTEMD_UNIT=docker.service", "_SYSTEMD_UNIT=kubelet.service"}, inputs[1].Systemd.SystemdFilter)
+	assert.Len(t, unmigrated, 2)
+	assert.Equal(t, "parameters Max_Entries of input fluentbit-input-systemd", unmigrated[0])
+	assert.Contains(t, unmigrated[1], "input fluentbit-input-cpu")
+
+	// saving the imported inputs keeps them in the CRD
+	pipeline := &FluentbitPipeline{Inputs: inputs[:1]}
+	assert.NoError(t, validatePipeline(pipeline))
+	spec := renderFluentbitSpec(pipeline, fb.FluentBitSpec{}, nil)
+	assert.Len(t, spec.Input, 1)
+	assert.Equal(t, "fluentbit-input-tail", spec.Input[0].Name)
+	assert.Equal(t, "/fluent-bit/tail/pos.db", getParameterValue(spec.Input[0].Parameters, "DB"))
+}
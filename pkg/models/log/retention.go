@@ -0,0 +1,609 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/informers"
+	es "kubesphere.io/kubesphere/pkg/simple/client/elasticsearch"
+	fb "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+)
+
+const (
+	// RetentionConfigMapName stores the log retentions of workspaces set through the api
+	RetentionConfigMapName = "fluent-bit-retention-config"
+	RetentionConfigMapData = "retentions"
+
+	// name prefix of the filter and output plugins routing logs of workspaces in the Fluent Bit CRD
+	retentionPluginPrefix = "fluentbit-retention-ws-"
+	// logs of workspaces are retagged as ws.<workspace>.<tag>
+	workspaceTagPrefix = "ws."
+	// index date suffix of the es output with Logstash_Format on
+	indexDateFormat = "2006.01.02"
+)
+
+// LogRetentionsQuery returns the retentions and storage of workspaces, all workspaces with retentions if workspace is empty
+func LogRetentionsQuery(workspace string) *LogRetentionsResult {
+	var result LogRetentionsResult
+
+	retentions, err := getLogRetentions()
+	if err != nil && !errors.IsNotFound(err) {
+		result.Status = http.StatusInternalServerError
+		result.Error = err.Error()
+		return &result
+	}
+
+	prefix, _ := indexPrefix()
+
+	for _, retention := range retentions {
+		if workspace != "" && retention.Workspace != workspace {
+			continue
+		}
+
+		status := LogRetentionStatus{LogRetention: retention}
+
+		if prefix != "" {
+			status.IndexPrefix = workspaceIndexPrefix(prefix, retention.Workspace)
+			indices, err := es.Indices(status.IndexPrefix + "-*")
+			if err != nil {
+				glog.Errorln(err)
+			}
+			for _, index := range workspaceIndices(indices, status.IndexPrefix) {
+				status.Indices++
+				status.Storage += index.Size
+			}
+		}
+
+		result.Retentions = append(result.Retentions, status)
+	}
+
+	if workspace != "" && len(result.Retentions) == 0 {
+		result.Status = http.StatusNotFound
+		result.Error = fmt.Sprintf("The log retention of workspace %s is not set.", workspace)
+		return &result
+	}
+
+	result.Status = http.StatusOK
+
+	return &result
+}
+
+// LogRetentionUpdate sets the retention of the workspace, logs of the workspace are written into separate indices
+func LogRetentionUpdate(retention LogRetention, workspace string) *LogRetentionsResult {
+	var result LogRetentionsResult
+
+	retention.Workspace = workspace
+
+	if err := validateRetention(retention); err != nil {
+		result.Status = http.StatusBadRequest
+		result.Error = err.Error()
+		return &result
+	}
+
+	_, err := informers.KsSharedInformerFactory().Tenant().V1alpha1().Workspaces().Lister().Get(workspace)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			result.Status = http.StatusNotFound
+		} else {
+			result.Status = http.StatusInternalServerError
+		}
+		result.Error = err.Error()
+		return &result
+	}
+
+	retentions, err := updateLogRetentions(func(retentions []LogRetention) ([]LogRetention, error) {
+		retention.Updatetime = time.Now()
+
+		if index := indexOfRetention(retentions, workspace); index < 0 {
+			retentions = append(retentions, retention)
+		} else {
+			retentions[index] = retention
+		}
+
+		return retentions, nil
+	})
+
+	return saveLogRetentions(retentions, err)
+}
+
+// LogRetentionDelete removes the retention of the workspace, logs of the workspace are written into the
+// default indices again and existing indices are kept
+func LogRetentionDelete(workspace string) *LogRetentionsResult {
+	retentions, err := updateLogRetentions(func(retentions []LogRetention) ([]LogRetention, error) {
+		index := indexOfRetention(retentions, workspace)
+		if index < 0 {
+			return nil, pipelineError(http.StatusNotFound, fmt.Sprintf("The log retention of workspace %s is not set.", workspace))
+		}
+
+		return append(retentions[:index], retentions[index+1:]...), nil
+	})
+
+	return saveLogRetentions(retentions, err)
+}
+
+// StartLogRetentionCurator deletes expired indices of workspaces and refreshes the routing of namespaces
+// periodically until stopCh is closed, it's disabled if interval is 0
+func StartLogRetentionCurator(interval time.Duration, stopCh <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+
+	go wait.Until(curateLogRetentions, interval, stopCh)
+}
+
+func curateLogRetentions() {
+	retentions, err := getLogRetentions()
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			glog.Errorln(err)
+		}
+		return
+	}
+
+	if len(retentions) == 0 {
+		return
+	}
+
+	// namespaces may be created in or moved between workspaces since the last run
+	if err := syncFluentbitCRDRetentions(retentions); err != nil {
+		glog.Errorln("sync log retentions", err)
+	}
+
+	prefix, err := indexPrefix()
+	if err != nil {
+		glog.Errorln(err)
+		return
+	}
+
+	now := time.Now()
+
+	for _, retention := range retentions {
+		wsPrefix := workspaceIndexPrefix(prefix, retention.Workspace)
+		indices, err := es.Indices(wsPrefix + "-*")
+		if err != nil {
+			glog.Errorln(err)
+			continue
+		}
+
+		for _, index := range expiredIndices(workspaceIndices(indices, wsPrefix), retention, now) {
+			glog.Infof("deleting index %s of workspace %s", index, retention.Workspace)
+			if err := es.DeleteIndex(index); err != nil {
+				glog.Errorln(err)
+			}
+		}
+	}
+}
+
+// expiredIndices returns indices older than the retention days, and the oldest indices exceeding the max size,
+// the latest index is always kept
+func expiredIndices(indices []es.Index, retention LogRetention, now time.Time) []string {
+	sort.Slice(indices, func(i, j int) bool {
+		return indices[i].Name < indices[j].Name
+	})
+
+	var total int64
+	for _, index := range indices {
+		total += index.Size
+	}
+
+	var maxSize int64
+	if retention.MaxSize != "" {
+		if quantity, err := resource.ParseQuantity(retention.MaxSize); err == nil {
+			maxSize = quantity.Value()
+		}
+	}
+
+	expired := make([]string, 0)
+	for i := 0; i < len(indices)-1; i++ {
+		index := indices[i]
+
+		outdated := false
+		if retention.Days > 0 {
+			if date, err := indexDate(index.Name); err == nil {
+				outdated = date.AddDate(0, 0, retention.Days+1).Before(now)
+			}
+		}
+
+		if !outdated && (maxSize == 0 || total <= maxSize) {
+			break
+		}
+
+		expired = append(expired, index.Name)
+		total -= index.Size
+	}
+
+	return expired
+}
+
+// workspaceIndices drops indices of other workspaces matching the prefix, e.g. logstash-ws-demo-dev-* matches logstash-ws-demo-*
+func workspaceIndices(indices []es.Index, prefix string) []es.Index {
+	filtered := make([]es.Index, 0, len(indices))
+	for _, index := range indices {
+		if !strings.HasPrefix(index.Name, prefix+"-") {
+			continue
+		}
+		if _, err := time.Parse(indexDateFormat, index.Name[len(prefix)+1:]); err == nil {
+			filtered = append(filtered, index)
+		}
+	}
+	return filtered
+}
+
+func indexDate(name string) (time.Time, error) {
+	return time.Parse(indexDateFormat, name[strings.LastIndex(name, "-")+1:])
+}
+
+func validateRetention(retention LogRetention) error {
+	if retention.Days < 0 {
+		return fmt.Errorf("days must not be negative")
+	}
+
+	if retention.MaxSize != "" {
+		quantity, err := resource.ParseQuantity(retention.MaxSize)
+		if err != nil {
+			return fmt.Errorf("invalid max size %s: %s", retention.MaxSize, err)
+		}
+		if quantity.Sign() <= 0 {
+			return fmt.Errorf("max size must be positive")
+		}
+	}
+
+	if retention.Days == 0 && retention.MaxSize == "" {
+		return fmt.Errorf("either days or max size must be set")
+	}
+
+	return nil
+}
+
+func indexOfRetention(retentions []LogRetention, workspace string) int {
+	for i, retention := range retentions {
+		if retention.Workspace == workspace {
+			return i
+		}
+	}
+	return -1
+}
+
+func getLogRetentions() ([]LogRetention, error) {
+	configMap, err := informers.SharedInformerFactory().Core().V1().ConfigMaps().Lister().ConfigMaps(LoggingNamespace).Get(RetentionConfigMapName)
+	if err != nil {
+		return nil, err
+	}
+
+	var retentions []LogRetention
+	if err = jsonIter.UnmarshalFromString(configMap.Data[RetentionConfigMapData], &retentions); err != nil {
+		return nil, err
+	}
+
+	return retentions, nil
+}
+
+// saveLogRetentions routes logs of workspaces with the retentions saved by updateLogRetentions
+func saveLogRetentions(retentions []LogRetention, err error) *LogRetentionsResult {
+	var result LogRetentionsResult
+
+	if err != nil {
+		result.Status = errorStatus(err)
+		result.Error = err.Error()
+		return &result
+	}
+
+	// Keep CRD in inline with ConfigMap
+	err = syncFluentbitCRDRetentions(retentions)
+	if err != nil {
+		result.Status = http.StatusInternalServerError
+		result.Error = err.Error()
+		return &result
+	}
+
+	for _, retention := range retentions {
+		result.Retentions = append(result.Retentions, LogRetentionStatus{LogRetention: retention})
+	}
+	result.Status = http.StatusOK
+	return &result
+}
+
+// retentionConfigMaps returns the client of ConfigMaps storing the retentions, replaced in tests
+var retentionConfigMaps = func() typedcorev1.ConfigMapInterface {
+	return k8s.Client().CoreV1().ConfigMaps(LoggingNamespace)
+}
+
+// updateLogRetentions applies the change to the retentions read from the api server and saves them, the change is
+// retried on conflicts with concurrent updates. Errors of the change should be api errors carrying the response status.
+func updateLogRetentions(change func(retentions []LogRetention) ([]LogRetention, error)) ([]LogRetention, error) {
+	var result []LogRetention
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMapClient := retentionConfigMaps()
+
+		configMap, err := configMapClient.Get(RetentionConfigMapName, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			glog.Errorln(err)
+			return err
+		}
+
+		// If the ConfigMap doesn't exist, a new one is created
+		exists := err == nil
+
+		var retentions []LogRetention
+		if exists {
+			if err := jsonIter.UnmarshalFromString(configMap.Data[RetentionConfigMapData], &retentions); err != nil {
+				glog.Errorln(err)
+				return err
+			}
+		}
+
+		retentions, err = change(retentions)
+		if err != nil {
+			return err
+		}
+
+		data, err := jsonIter.MarshalToString(retentions)
+		if err != nil {
+			glog.Errorln(err)
+			return err
+		}
+
+		if exists {
+			configMap.Data = map[string]string{RetentionConfigMapData: data}
+			_, err = configMapClient.Update(configMap)
+		} else {
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: RetentionConfigMapName,
+				},
+				Data: map[string]string{RetentionConfigMapData: data},
+			}
+			_, err = configMapClient.Create(configMap)
+			// created concurrently
+			if errors.IsAlreadyExists(err) {
+				err = errors.NewConflict(corev1.Resource("configmaps"), RetentionConfigMapName, err)
+			}
+		}
+
+		if err != nil {
+			glog.Errorln(err)
+			return err
+		}
+
+		result = retentions
+		return nil
+	})
+
+	return result, err
+}
+
+// syncFluentbitCRDRetentions routes logs of workspaces with retentions to their own indices, the CRD
+// is updated only if the routing changes
+func syncFluentbitCRDRetentions(retentions []LogRetention) error {
+	outputs, err := GetFluentbitOutputs()
+	if err != nil {
+		glog.Errorln(err)
+		return err
+	}
+
+	crdcs, scheme, err := createCRDClientSet()
+	if err != nil {
+		glog.Errorln(err)
+		return err
+	}
+
+	// Create a CRD client interface
+	crdclient := fb.CrdClient(crdcs, scheme, LoggingNamespace)
+
+	fluentbit, err := crdclient.Get("fluent-bit")
+	if err != nil {
+		glog.Errorln(err)
+		return err
+	}
+
+	filters := mergeRetentionPlugins(fluentbit.Spec.Filter, retentionFilters(retentions, workspaceNamespaces()))
+	outputPlugins := mergeRetentionPlugins(fluentbit.Spec.Output, retentionOutputs(retentions, outputs))
+
+	if reflect.DeepEqual(filters, fluentbit.Spec.Filter) && reflect.DeepEqual(outputPlugins, fluentbit.Spec.Output) {
+		return nil
+	}
+
+	fluentbit.Spec.Filter = filters
+	fluentbit.Spec.Output = outputPlugins
+
+	_, err = crdclient.Update("fluent-bit", fluentbit)
+	if err != nil {
+		glog.Errorln(err)
+		return err
+	}
+
+	return nil
+}
+
+// workspaceNamespaces returns namespaces grouped by workspaces
+func workspaceNamespaces() map[string][]string {
+	namespaces, err := informers.SharedInformerFactory().Core().V1().Namespaces().Lister().List(labels.Everything())
+	if err != nil {
+		glog.Errorln(err)
+	}
+
+	workspaces := make(map[string][]string)
+	for _, namespace := range namespaces {
+		if workspace := namespace.Labels[constants.WorkspaceLabelKey]; workspace != "" {
+			workspaces[workspace] = append(workspaces[workspace], namespace.Name)
+		}
+	}
+
+	for _, names := range workspaces {
+		sort.Strings(names)
+	}
+
+	return workspaces
+}
+
+// retentionFilters retag logs of namespaces in workspaces with retentions, so that they are written by
+// the outputs cloned for workspaces instead of the outputs matching kube.*. The filters are placed after
+// all other filters, re-emitted records are not processed by filters matching kube.* again.
+func retentionFilters(retentions []LogRetention, namespaces map[string][]string) []fb.Plugin {
+	filters := make([]fb.Plugin, 0, len(retentions))
+
+	for _, retention := range retentions {
+		names := namespaces[retention.Workspace]
+		if len(names) == 0 {
+			continue
+		}
+
+		quoted := make([]string, 0, len(names))
+		for _, name := range names {
+			quoted = append(quoted, regexp.QuoteMeta(name))
+		}
+
+		filters = append(filters, fb.Plugin{
+			Type: "fluentbit_filter",
+			Name: retentionPluginPrefix + retention.Workspace,
+			Parameters: []fb.Parameter{
+				{Name: "Name", Value: "rewrite_tag"},
+				{Name: "Match", Value: "kube.*"},
+				{Name: "Rule", Value: fmt.Sprintf("$kubernetes['namespace_name'] ^(%s)$ %s%s.$TAG false", strings.Join(quoted, "|"), workspaceTagPrefix, retention.Workspace)},
+				{Name: "Emitter_Name", Value: "retention_" + strings.Replace(retention.Workspace, "-", "_", -1)},
+			},
+		})
+	}
+
+	return filters
+}
+
+// retentionOutputs clones the enabled outputs for the retagged logs of workspaces, so that they are still written
+// by all outputs matching them before they are retagged. Es outputs write them into indices prefixed with
+// <prefix>-ws-<workspace>, the indices of the first es output are curated.
+func retentionOutputs(retentions []LogRetention, outputs []fb.OutputPlugin) []fb.Plugin {
+	plugins := make([]fb.Plugin, 0, len(retentions)*len(outputs))
+
+	for _, retention := range retentions {
+		tagPrefix := workspaceTagPrefix + retention.Workspace + "."
+
+		for _, output := range outputs {
+			if !output.Enable {
+				continue
+			}
+
+			esConfigs := ParseEsOutputParams(output.Parameters)
+
+			parameters := make([]fb.Parameter, 0, len(output.Parameters)+2)
+			for _, parameter := range output.Parameters {
+				switch parameter.Name {
+				case "Match":
+					parameters = append(parameters, fb.Parameter{Name: "Match", Value: tagPrefix + parameter.Value})
+				case "Match_Regex":
+					parameters = append(parameters, fb.Parameter{Name: "Match_Regex", Value: retentionMatchRegex(tagPrefix, parameter.Value)})
+				case "Logstash_Format", "Logstash_Prefix", "Index":
+					if esConfigs == nil {
+						parameters = append(parameters, parameter)
+					}
+				default:
+					parameters = append(parameters, parameter)
+				}
+			}
+
+			if esConfigs != nil {
+				parameters = append(parameters,
+					fb.Parameter{Name: "Logstash_Format", Value: "On"},
+					fb.Parameter{Name: "Logstash_Prefix", Value: workspaceIndexPrefix(esConfigs.Index, retention.Workspace)})
+			}
+
+			plugins = append(plugins, fb.Plugin{
+				Type:       output.Type,
+				Name:       retentionPluginPrefix + retention.Workspace + "." + strings.TrimPrefix(output.Name, outputPluginPrefix),
+				Parameters: parameters,
+			})
+		}
+	}
+
+	return plugins
+}
+
+// retentionMatchRegex returns the regular expression matching the retagged tags of the tags matching the regex
+func retentionMatchRegex(tagPrefix, regex string) string {
+	if strings.HasPrefix(regex, "^") {
+		return "^" + regexp.QuoteMeta(tagPrefix) + "(?:" + strings.TrimPrefix(regex, "^") + ")"
+	}
+	return "^" + regexp.QuoteMeta(tagPrefix) + ".*(?:" + regex + ")"
+}
+
+// mergeRetentionPlugins replaces the retention plugins, which are placed at the end
+func mergeRetentionPlugins(plugins []fb.Plugin, retentionPlugins []fb.Plugin) []fb.Plugin {
+	merged := make([]fb.Plugin, 0, len(plugins)+len(retentionPlugins))
+	for _, plugin := range plugins {
+		if !strings.HasPrefix(plugin.Name, retentionPluginPrefix) {
+			merged = append(merged, plugin)
+		}
+	}
+	return append(merged, retentionPlugins...)
+}
+
+func retentionPluginsOf(plugins []fb.Plugin) []fb.Plugin {
+	retentionPlugins := make([]fb.Plugin, 0)
+	for _, plugin := range plugins {
+		if strings.HasPrefix(plugin.Name, retentionPluginPrefix) {
+			retentionPlugins = append(retentionPlugins, plugin)
+		}
+	}
+	return retentionPlugins
+}
+
+func esOutput(outputs []fb.OutputPlugin) *fb.OutputPlugin {
+	for i := range outputs {
+		if outputs[i].Enable && ParseEsOutputParams(outputs[i].Parameters) != nil {
+			return &outputs[i]
+		}
+	}
+	return nil
+}
+
+func indexPrefix() (string, error) {
+	outputs, err := GetFluentbitOutputs()
+	if err != nil {
+		return "", err
+	}
+
+	output := esOutput(outputs)
+	if output == nil {
+		return "", fmt.Errorf("no elasticsearch output is enabled")
+	}
+
+	return ParseEsOutputParams(output.Parameters).Index, nil
+}
+
+func workspaceIndexPrefix(prefix, workspace string) string {
+	return prefix + "-ws-" + workspace
+}
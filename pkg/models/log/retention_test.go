@@ -0,0 +1,212 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package log
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	es "kubesphere.io/kubesphere/pkg/simple/client/elasticsearch"
+	fb "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
+)
+
+func TestExpiredIndices(t *testing.T) {
+	now := time.Date(2019, 7, 10, 8, 0, 0, 0, time.UTC)
+
+	indices := func() []es.Index {
+		return []es.Index{
+			{Name: "logstash-ws-demo-2019.07.10", Size: 300},
+			{Name: "logstash-ws-demo-2019.07.01", Size: 100},
+			{Name: "logstash-ws-demo-2019.07.08", Size: 200},
+			{Name: "logstash-ws-demo-2019.07.09", Size: 200},
+		}
+	}
+
+	assert.Equal(t, []string{"logstash-ws-demo-2019.07.01"}, expiredIndices(indices(), LogRetention{Days: 7}, now))
+	assert.Equal(t, []string{"logstash-ws-demo-2019.07.01", "logstash-ws-demo-2019.07.08"}, expiredIndices(indices(), LogRetention{MaxSize: "600"}, now))
+	assert.Equal(t, []string{"logstash-ws-demo-2019.07.01", "logstash-ws-demo-2019.07.08", "logstash-ws-demo-2019.07.09"}, expiredIndices(indices(), LogRetention{Days: 30, MaxSize: "1"}, now))
+	assert.Empty(t, expiredIndices(indices(), LogRetention{Days: 30, MaxSize: "1Ki"}, now))
+
+	filtered := workspaceIndices(append(indices(), es.Index{Name: "logstash-ws-demo-dev-2019.07.01"}), "logstash-ws-demo")
+	assert.Len(t, filtered, 4)
+}
+
+func TestValidateRetention(t *testing.T) {
+	assert.NoError(t, validateRetention(LogRetention{Days: 7}))
+	assert.NoError(t, validateRetention(LogRetention{MaxSize: "50Gi"}))
+	assert.Error(t, validateRetention(LogRetention{}))
+	assert.Error(t, validateRetention(LogRetention{Days: -1}))
+	assert.Error(t, validateRetention(LogRetention{MaxSize: "50G1"}))
+}
+
+func TestRetentionPlugins(t *testing.T) {
+	retentions := []LogRetention{{Workspace: "demo", Days: 7}, {Workspace: "empty", Days: 7}}
+
+	filters := retentionFilters(retentions, map[string][]string{"demo": {"demo-dev", "demo-prod"}})
+	assert.Len(t, filters, 1)
+	assert.Equal(t, "$kubernetes['namespace_name'] ^(demo-dev|demo-prod)$ ws.demo.$TAG false", getParameterValue(filters[0].Parameters, "Rule"))
+
+	outputs := []fb.OutputPlugin{
+		{
+			Plugin: fb.Plugin{Type: "fluentbit_output", Name: "fluentbit-output-es", Parameters: []fb.Parameter{
+				{Name: "Name", Value: "es"},
+				{Name: "Match", Value: "kube.*"},
+				{Name: "Host", Value: "elasticsearch"},
+				{Name: "Logstash_Format", Value: "On"},
+				{Name: "Logstash_Prefix", Value: "ks-logstash-log"},
+			}},
+			Enable: true,
+		},
+		{
+			Plugin: fb.Plugin{Type: "fluentbit_output", Name: "fluentbit-output-kafka", Parameters: []fb.Parameter{
+				{Name: "Name", Value: "kafka"},
+				{Name: "Match_Regex", Value: `^kube\.var\.log\.containers\..*`},
+				{Name: "Brokers", Value: "kafka:9092"},
+			}},
+			Enable: true,
+		},
+		{
+			Plugin: fb.Plugin{Type: "fluentbit_output", Name: "fluentbit-output-archive", Parameters: []fb.Parameter{
+				{Name: "Name", Value: "es"},
+				{Name: "Match", Value: "*"},
+				{Name: "Host", Value: "archive"},
+				{Name: "Index", Value: "archive"},
+			}},
+			Enable: true,
+		},
+		{
+			Plugin: fb.Plugin{Type: "fluentbit_output", Name: "fluentbit-output-forward", Parameters: []fb.Parameter{
+				{Name: "Name", Value: "forward"},
+				{Name: "Match", Value: "kube.*"},
+			}},
+		},
+	}
+
+	// logs of workspaces are written by all enabled outputs
+	plugins := retentionOutputs(retentions, outputs)
+	assert.Len(t, plugins, 6)
+
+	assert.Equal(t, retentionPluginPrefix+"demo.es", plugins[0].Name)
+	assert.Equal(t, "ws.demo.kube.*", getParameterValue(plugins[0].Parameters, "Match"))
+	assert.Equal(t, "ks-logstash-log-ws-demo", getParameterValue(plugins[0].Parameters, "Logstash_Prefix"))
+	assert.Equal(t, "elasticsearch", getParameterValue(plugins[0].Parameters, "Host"))
+
+	assert.Equal(t, retentionPluginPrefix+"demo.kafka", plugins[1].Name)
+	assert.Equal(t, `^ws\.demo\.(?:kube\.var\.log\.containers\..*)`, getParameterValue(plugins[1].Parameters, "Match_Regex"))
+	assert.Equal(t, "kafka:9092", getParameterValue(plugins[1].Parameters, "Brokers"))
+	assert.Equal(t, "", getParameterValue(plugins[1].Parameters, "Logstash_Prefix"))
+
+	assert.Equal(t, "ws.demo.*", getParameterValue(plugins[2].Parameters, "Match"))
+	assert.Equal(t, "archive-ws-demo", getParameterValue(plugins[2].Parameters, "Logstash_Prefix"))
+	assert.Equal(t, "On", getParameterValue(plugins[2].Parameters, "Logstash_Format"))
+	assert.Equal(t, "", getParameterValue(plugins[2].Parameters, "Index"))
+
+	assert.Equal(t, retentionPluginPrefix+"empty.es", plugins[3].Name)
+
+	assert.Equal(t, `^ws\.demo\..*(?:containers)`, retentionMatchRegex("ws.demo.", "containers"))
+
+	merged := mergeRetentionPlugins([]fb.Plugin{{Name: retentionPluginPrefix + "old"}, outputs[0].Plugin}, plugins)
+	assert.Len(t, merged, 7)
+	assert.Equal(t, "fluentbit-output-es", merged[0].Name)
+	assert.Len(t, retentionPluginsOf(merged), 6)
+}
+
+// fakeConfigMaps keeps a single ConfigMap, the first updates fail with conflicts
+type fakeConfigMaps struct {
+	typedcorev1.ConfigMapInterface
+	configMap *corev1.ConfigMap
+	getErr    error
+	conflicts int
+	updates   int
+}
+
+func (f *fakeConfigMaps) Get(name string, options metav1.GetOptions) (*corev1.ConfigMap, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	if f.configMap == nil {
+		return nil, errors.NewNotFound(corev1.Resource("configmaps"), name)
+	}
+	return f.configMap.DeepCopy(), nil
+}
+
+func (f *fakeConfigMaps) Create(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	if f.configMap != nil {
+		return nil, errors.NewAlreadyExists(corev1.Resource("configmaps"), configMap.Name)
+	}
+	f.configMap = configMap.DeepCopy()
+	return configMap, nil
+}
+
+func (f *fakeConfigMaps) Update(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	f.updates++
+	if f.conflicts > 0 {
+		f.conflicts--
+		// changed concurrently
+		f.configMap.Data = map[string]string{RetentionConfigMapData: `[{"workspace":"other","days":3}]`}
+		return nil, errors.NewConflict(corev1.Resource("configmaps"), configMap.Name, nil)
+	}
+	f.configMap = configMap.DeepCopy()
+	return configMap, nil
+}
+
+func TestUpdateLogRetentions(t *testing.T) {
+	configMaps := &fakeConfigMaps{}
+	original := retentionConfigMaps
+	retentionConfigMaps = func() typedcorev1.ConfigMapInterface { return configMaps }
+	defer func() { retentionConfigMaps = original }()
+
+	set := func(workspace string) func([]LogRetention) ([]LogRetention, error) {
+		return func(retentions []LogRetention) ([]LogRetention, error) {
+			return append(retentions, LogRetention{Workspace: workspace, Days: 7}), nil
+		}
+	}
+
+	// the ConfigMap is created
+	retentions, err := updateLogRetentions(set("demo"))
+	assert.NoError(t, err)
+	assert.Len(t, retentions, 1)
+	assert.NotNil(t, configMaps.configMap)
+
+	// the change is applied again to the concurrent update
+	configMaps.conflicts = 1
+	retentions, err = updateLogRetentions(set("dev"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, configMaps.updates)
+	assert.Len(t, retentions, 2)
+	assert.Equal(t, "other", retentions[0].Workspace)
+	assert.Equal(t, "dev", retentions[1].Workspace)
+
+	_, err = updateLogRetentions(func([]LogRetention) ([]LogRetention, error) {
+		return nil, pipelineError(http.StatusNotFound, "not set")
+	})
+	assert.Equal(t, http.StatusNotFound, errorStatus(err))
+
+	// errors other than NotFound are not taken as empty retentions
+	configMaps.getErr = errors.NewForbidden(corev1.Resource("configmaps"), RetentionConfigMapName, nil)
+	_, err = updateLogRetentions(set("test"))
+	assert.Equal(t, http.StatusForbidden, errorStatus(err))
+	assert.Equal(t, 2, configMaps.updates)
+}
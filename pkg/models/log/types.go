@@ -20,6 +20,7 @@ package log
 
 import (
 	fb "kubesphere.io/kubesphere/pkg/simple/client/fluentbit"
+	"time"
 )
 
 type FluentbitCRDResult struct {
@@ -52,3 +53,204 @@ type FluentbitOutputsResult struct {
 	Error   string            `json:"error,omitempty" description:"debug information"`
 	Outputs []fb.OutputPlugin `json:"outputs,omitempty" description:"array of fluent bit output plugins"`
 }
+
+type FluentbitParser struct {
+	Name       string    `json:"name" description:"parser name, a DNS-1123 label"`
+	Format     string    `json:"format" description:"parser format, one of json, regex"`
+	Regex      string    `json:"regex,omitempty" description:"regular expression with named groups for fields, required by the regex format, eg. ^(?<time>[^ ]+) (?<level>[A-Z]+) (?<message>.*)$"`
+	TimeKey    string    `json:"timeKey,omitempty" description:"field of the log timestamp"`
+	TimeFormat string    `json:"timeFormat,omitempty" description:"strptime format of the log timestamp, eg. %Y-%m-%dT%H:%M:%S.%L"`
+	Namespace  string    `json:"namespace" description:"namespace of the workload"`
+	Workload   string    `json:"workload,omitempty" description:"deployment, stateful set, daemon set, job or cron job whose logs are parsed, pods are matched by the suffixes of their generated names, logs of all workloads in the namespace are parsed if it's empty"`
+	Container  string    `json:"container,omitempty" description:"container whose logs are parsed, logs of all containers are parsed if it's empty"`
+	Updatetime time.Time `json:"updatetime,omitempty" description:"last updatetime"`
+}
+
+type FluentbitParsersResult struct {
+	Status  int               `json:"status" description:"response status"`
+	Error   string            `json:"error,omitempty" description:"debug information"`
+	Parsers []FluentbitParser `json:"parsers,omitempty" description:"array of fluent bit parsers"`
+}
+
+type LogRetention struct {
+	Workspace  string    `json:"workspace" description:"workspace name"`
+	Days       int       `json:"days,omitempty" description:"days logs are kept for, older indices are deleted, unlimited if 0"`
+	MaxSize    string    `json:"maxSize,omitempty" description:"max storage of logs, the oldest indices are deleted once it's exceeded, unlimited if empty, eg. 50Gi"`
+	Updatetime time.Time `json:"updatetime,omitempty" description:"last updatetime"`
+}
+
+type LogRetentionStatus struct {
+	LogRetention
+	IndexPrefix string `json:"indexPrefix,omitempty" description:"prefix of the indices logs of the workspace are written into"`
+	Indices     int    `json:"indices" description:"number of indices"`
+	Storage     int64  `json:"storage" description:"storage consumed by the indices in bytes"`
+}
+
+type LogRetentionsResult struct {
+	Status     int                  `json:"status" description:"response status"`
+	Error      string               `json:"error,omitempty" description:"debug information"`
+	Retentions []LogRetentionStatus `json:"retentions,omitempty" description:"array of log retentions of workspaces"`
+}
+
+// FluentbitPipeline is the typed configuration of the Fluent Bit pipeline. It's stored along with the
+// Fluent Bit CRD, whose inputs, filters and outputs are rendered from it.
+type FluentbitPipeline struct {
+	Inputs  []PipelineInput   `json:"inputs,omitempty" description:"inputs collecting logs, inputs deployed with Fluent Bit are kept if it's empty"`
+	Parsers []FluentbitParser `json:"parsers,omitempty" description:"parsers of structured logs"`
+	Filters []PipelineFilter  `json:"filters,omitempty" description:"filters applied to container logs in order, after kubernetes metadata is lifted into kubernetes_* fields"`
+	Outputs []PipelineOutput  `json:"outputs,omitempty" description:"outputs logs are written into"`
+}
+
+type PipelineInput struct {
+	Name    string        `json:"name" description:"input name, a DNS-1123 label"`
+	Tag     string        `json:"tag" description:"tag of the records collected, eg. kube.*"`
+	Tail    *TailInput    `json:"tail,omitempty" description:"tail input, reads log files"`
+	Systemd *SystemdInput `json:"systemd,omitempty" description:"systemd input, reads the systemd journal"`
+}
+
+type TailInput struct {
+	Path            string `json:"path" description:"pattern of the log files, eg. /var/log/containers/*.log" fluentbit:"Path"`
+	ExcludePath     string `json:"excludePath,omitempty" description:"comma separated patterns of log files to exclude" fluentbit:"Exclude_Path"`
+	Parser          string `json:"parser,omitempty" description:"parser of the lines, eg. docker" fluentbit:"Parser"`
+	DB              string `json:"db,omitempty" description:"database file keeping the offsets of log files" fluentbit:"DB"`
+	RefreshInterval int    `json:"refreshInterval,omitempty" description:"interval of refreshing the list of log files in seconds" fluentbit:"Refresh_Interval"`
+	MemBufLimit     string `json:"memBufLimit,omitempty" description:"memory limit of the input, eg. 5MB" fluentbit:"Mem_Buf_Limit"`
+	SkipLongLines   bool   `json:"skipLongLines,omitempty" description:"skip lines exceeding the buffer instead of stopping the file" fluentbit:"Skip_Long_Lines"`
+	DockerMode      bool   `json:"dockerMode,omitempty" description:"recombine lines split by docker" fluentbit:"Docker_Mode"`
+}
+
+type SystemdInput struct {
+	Path             string   `json:"path,omitempty" description:"path of the journal directory" fluentbit:"Path"`
+	SystemdFilter    []string `json:"systemdFilter,omitempty" description:"filters of journal entries, eg. _SYSTEMD_UNIT=kubelet.service" fluentbit:"Systemd_Filter,repeat"`
+	DB               string   `json:"db,omitempty" description:"database file keeping the journal cursor" fluentbit:"DB"`
+	ReadFromTail     bool     `json:"readFromTail,omitempty" description:"read new entries only" fluentbit:"Read_From_Tail"`
+	StripUnderscores bool     `json:"stripUnderscores,omitempty" description:"remove the leading underscores of fields" fluentbit:"Strip_Underscores"`
+}
+
+type PipelineFilter struct {
+	Name           string                `json:"name" description:"filter name, a DNS-1123 label"`
+	Match          string                `json:"match" description:"pattern of the tags of records to filter, eg. kube.*"`
+	Grep           *GrepFilter           `json:"grep,omitempty" description:"grep filter, keeps or drops records by regular expressions"`
+	Modify         *ModifyFilter         `json:"modify,omitempty" description:"modify filter, sets, renames, copies or removes fields"`
+	RecordModifier *RecordModifierFilter `json:"recordModifier,omitempty" description:"record_modifier filter, appends fields or removes fields by keys"`
+	Lua            *LuaFilter            `json:"lua,omitempty" description:"lua filter, modifies records with a lua script"`
+	Throttle       *ThrottleFilter       `json:"throttle,omitempty" description:"throttle filter, limits the rate of records"`
+}
+
+// PipelineKeyValue is a pair of field and value
+type PipelineKeyValue struct {
+	Key   string `json:"key" description:"field name"`
+	Value string `json:"value" description:"value, regular expression or new field name depending on the operation"`
+}
+
+type GrepFilter struct {
+	Regex   []PipelineKeyValue `json:"regex,omitempty" description:"keep records whose field matches the regular expression" fluentbit:"Regex,repeat"`
+	Exclude []PipelineKeyValue `json:"exclude,omitempty" description:"drop records whose field matches the regular expression" fluentbit:"Exclude,repeat"`
+}
+
+type ModifyFilter struct {
+	Set    []PipelineKeyValue `json:"set,omitempty" description:"set fields, overwriting existing values" fluentbit:"Set,repeat"`
+	Add    []PipelineKeyValue `json:"add,omitempty" description:"add fields not existing yet" fluentbit:"Add,repeat"`
+	Rename []PipelineKeyValue `json:"rename,omitempty" description:"rename fields to new names" fluentbit:"Rename,repeat"`
+	Copy   []PipelineKeyValue `json:"copy,omitempty" description:"copy fields to new fields" fluentbit:"Copy,repeat"`
+	Remove []string           `json:"remove,omitempty" description:"remove fields" fluentbit:"Remove,repeat"`
+}
+
+type RecordModifierFilter struct {
+	Records       []PipelineKeyValue `json:"records,omitempty" description:"append fields" fluentbit:"Record,repeat"`
+	RemoveKeys    []string           `json:"removeKeys,omitempty" description:"remove fields" fluentbit:"Remove_key,repeat"`
+	WhitelistKeys []string           `json:"whitelistKeys,omitempty" description:"keep only these fields" fluentbit:"Whitelist_key,repeat"`
+}
+
+type LuaFilter struct {
+	Script      string `json:"script" description:"absolute path of the lua script mounted into Fluent Bit" fluentbit:"script"`
+	Call        string `json:"call" description:"lua function to call" fluentbit:"call"`
+	TimeAsTable bool   `json:"timeAsTable,omitempty" description:"pass the timestamp as a table of sec and nsec" fluentbit:"time_as_table"`
+}
+
+type ThrottleFilter struct {
+	Rate        int    `json:"rate" description:"max number of records per interval on average" fluentbit:"Rate"`
+	Window      int    `json:"window" description:"number of intervals the rate is averaged over" fluentbit:"Window"`
+	Interval    string `json:"interval" description:"length of the interval, eg. 1s, 1m" fluentbit:"Interval"`
+	PrintStatus bool   `json:"printStatus,omitempty" description:"print the status of the throttle" fluentbit:"Print_Status"`
+}
+
+type PipelineOutput struct {
+	Id         string         `json:"id,omitempty" description:"output uuid"`
+	Name       string         `json:"name" description:"output name, a DNS-1123 label"`
+	Match      string         `json:"match" description:"pattern of the tags of records to write, eg. kube.*"`
+	Enable     bool           `json:"enable" description:"active status, one of true, false"`
+	RetryLimit string         `json:"retryLimit,omitempty" description:"max retries of writing a chunk, False for unlimited retries"`
+	Updatetime time.Time      `json:"updatetime,omitempty" description:"last updatetime"`
+	Es         *ESOutput      `json:"es,omitempty" description:"elasticsearch output"`
+	Kafka      *KafkaOutput   `json:"kafka,omitempty" description:"kafka output"`
+	Forward    *ForwardOutput `json:"forward,omitempty" description:"forward output, writes to fluentd or Fluent Bit"`
+	HTTP       *HTTPOutput    `json:"http,omitempty" description:"http output"`
+	Stdout     *StdoutOutput  `json:"stdout,omitempty" description:"stdout output, for debugging"`
+}
+
+// SecretValue is a plain value or a value read from a secret
+type SecretValue struct {
+	Value     string        `json:"value,omitempty" description:"plain value"`
+	ValueFrom *fb.ValueFrom `json:"valueFrom,omitempty" description:"secret key the value is read from"`
+}
+
+type ESOutput struct {
+	Host           string       `json:"host" description:"elasticsearch host" fluentbit:"Host"`
+	Port           int          `json:"port,omitempty" description:"elasticsearch port, default to 9200" fluentbit:"Port"`
+	Index          string       `json:"index,omitempty" description:"index name, ignored if logstash format is on" fluentbit:"Index"`
+	Type           string       `json:"type,omitempty" description:"document type" fluentbit:"Type"`
+	LogstashFormat bool         `json:"logstashFormat,omitempty" description:"write into daily indices <logstashPrefix>-YYYY.MM.DD" fluentbit:"Logstash_Format"`
+	LogstashPrefix string       `json:"logstashPrefix,omitempty" description:"prefix of daily indices" fluentbit:"Logstash_Prefix"`
+	TimeKey        string       `json:"timeKey,omitempty" description:"field of the timestamp, default to @timestamp" fluentbit:"Time_Key"`
+	HTTPUser       string       `json:"httpUser,omitempty" description:"basic auth username" fluentbit:"HTTP_User"`
+	HTTPPasswd     *SecretValue `json:"httpPasswd,omitempty" description:"basic auth password" fluentbit:"HTTP_Passwd"`
+	GenerateID     bool         `json:"generateID,omitempty" description:"generate document ids to avoid duplicated records" fluentbit:"Generate_ID"`
+	ReplaceDots    bool         `json:"replaceDots,omitempty" description:"replace dots in field names with underscores" fluentbit:"Replace_Dots"`
+	BufferSize     string       `json:"bufferSize,omitempty" description:"buffer size of responses, eg. 512KB, False for unlimited" fluentbit:"Buffer_Size"`
+	TLS            bool         `json:"tls,omitempty" description:"enable tls" fluentbit:"tls"`
+}
+
+type KafkaOutput struct {
+	Brokers      []string `json:"brokers" description:"kafka brokers, eg. kafka-0:9092" fluentbit:"Brokers,comma"`
+	Topics       []string `json:"topics" description:"kafka topics" fluentbit:"Topics,comma"`
+	Format       string   `json:"format,omitempty" description:"message format, one of json, msgpack, gelf" fluentbit:"Format"`
+	MessageKey   string   `json:"messageKey,omitempty" description:"message key" fluentbit:"Message_Key"`
+	TimestampKey string   `json:"timestampKey,omitempty" description:"field of the timestamp" fluentbit:"Timestamp_Key"`
+}
+
+type ForwardOutput struct {
+	Host         string       `json:"host" description:"fluentd or Fluent Bit host" fluentbit:"Host"`
+	Port         int          `json:"port,omitempty" description:"port, default to 24224" fluentbit:"Port"`
+	SharedKey    *SecretValue `json:"sharedKey,omitempty" description:"shared key of the secure forward protocol" fluentbit:"Shared_Key"`
+	SelfHostname string       `json:"selfHostname,omitempty" description:"hostname of the client in the secure forward protocol" fluentbit:"Self_Hostname"`
+	TLS          bool         `json:"tls,omitempty" description:"enable tls" fluentbit:"tls"`
+}
+
+type HTTPOutput struct {
+	Host           string             `json:"host" description:"http host" fluentbit:"Host"`
+	Port           int                `json:"port,omitempty" description:"http port, default to 80" fluentbit:"Port"`
+	URI            string             `json:"uri,omitempty" description:"request uri, eg. /logs" fluentbit:"URI"`
+	Format         string             `json:"format,omitempty" description:"body format, one of msgpack, json, json_lines, json_stream, gelf" fluentbit:"Format"`
+	HTTPUser       string             `json:"httpUser,omitempty" description:"basic auth username" fluentbit:"HTTP_User"`
+	HTTPPasswd     *SecretValue       `json:"httpPasswd,omitempty" description:"basic auth password" fluentbit:"HTTP_Passwd"`
+	Headers        []PipelineKeyValue `json:"headers,omitempty" description:"request headers" fluentbit:"Header,repeat"`
+	JSONDateKey    string             `json:"jsonDateKey,omitempty" description:"field of the timestamp in json formats" fluentbit:"json_date_key"`
+	JSONDateFormat string             `json:"jsonDateFormat,omitempty" description:"timestamp format in json formats, one of double, iso8601, epoch" fluentbit:"json_date_format"`
+	TLS            bool               `json:"tls,omitempty" description:"enable tls" fluentbit:"tls"`
+}
+
+type StdoutOutput struct {
+	Format         string `json:"format,omitempty" description:"output format, one of msgpack, json, json_lines, json_stream" fluentbit:"Format"`
+	JSONDateKey    string `json:"jsonDateKey,omitempty" description:"field of the timestamp in json formats" fluentbit:"json_date_key"`
+	JSONDateFormat string `json:"jsonDateFormat,omitempty" description:"timestamp format in json formats, one of double, iso8601, epoch" fluentbit:"json_date_format"`
+}
+
+type FluentbitPipelineResult struct {
+	Status        int                `json:"status" description:"response status"`
+	Error         string             `json:"error,omitempty" description:"debug information"`
+	Pipeline      *FluentbitPipeline `json:"pipeline,omitempty" description:"fluent bit pipeline"`
+	Config        string             `json:"config,omitempty" description:"fluent bit configuration rendered from the pipeline"`
+	ParsersConfig string             `json:"parsersConfig,omitempty" description:"fluent bit parsers file rendered from the pipeline"`
+	Unmigrated    []string           `json:"unmigrated,omitempty" description:"settings of the legacy outputs and parsers ConfigMaps and inputs of the Fluent Bit CRD which can't be imported into the pipeline, the pipeline can't be changed until they are removed"`
+}
@@ -0,0 +1,73 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package metrics
+
+import (
+	"fmt"
+
+	"kubesphere.io/kubesphere/pkg/models/workspaces"
+	client "kubesphere.io/kubesphere/pkg/simple/client/prometheus"
+)
+
+const (
+	// label of series which namespace they belong to
+	namespaceLabel = "namespace"
+
+	MetricNameCustom = "custom"
+)
+
+// MonitorCustomMetric evaluates the PromQL query against the namespace of the request, or all namespaces of
+// the workspace of the request, series of other namespaces are never returned
+func MonitorCustomMetric(monitoringRequest *client.MonitoringRequestParams, query string, metricName string) (*FormatedMetric, error) {
+	if metricName == "" {
+		metricName = MetricNameCustom
+	}
+
+	namespaces, err := requestNamespaces(monitoringRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return monitorScopedQuery(monitoringRequest, query, metricName, namespaces)
+}
+
+// requestNamespaces returns the namespaces queries of the request are scoped to
+func requestNamespaces(monitoringRequest *client.MonitoringRequestParams) ([]string, error) {
+	if monitoringRequest.NsName != "" {
+		return []string{monitoringRequest.NsName}, nil
+	}
+	if monitoringRequest.WsName != "" {
+		return workspaces.WorkspaceNamespaces(monitoringRequest.WsName)
+	}
+	return nil, fmt.Errorf("namespace or workspace is required")
+}
+
+func monitorScopedQuery(monitoringRequest *client.MonitoringRequestParams, query string, metricName string, namespaces []string) (*FormatedMetric, error) {
+	// nothing could be selected
+	if len(namespaces) == 0 {
+		return &FormatedMetric{MetricName: metricName, Status: MetricStatusSuccess, Data: FormatedMetricData{Result: []map[string]interface{}{}, ResultType: ResultTypeVector}}, nil
+	}
+
+	rule, err := EnforceLabelMatcher(query, namespaceLabel, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	params := makeRequestParamString(rule, monitoringRequest.Params)
+	return QueryMetric(monitoringRequest.Context, client.Primary(), monitoringRequest.QueryType, params, metricName, nil), nil
+}
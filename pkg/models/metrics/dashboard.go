@@ -0,0 +1,157 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package metrics
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	monitoringv1alpha1 "kubesphere.io/kubesphere/pkg/apis/monitoring/v1alpha1"
+	"kubesphere.io/kubesphere/pkg/models/workspaces"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+	client "kubesphere.io/kubesphere/pkg/simple/client/prometheus"
+)
+
+var (
+	DashboardGroupVersionResource          = monitoringv1alpha1.SchemeGroupVersion.WithResource("dashboards")
+	WorkspaceDashboardGroupVersionResource = monitoringv1alpha1.SchemeGroupVersion.WithResource("workspacedashboards")
+)
+
+type DashboardMetrics struct {
+	Name        string         `json:"name" description:"name of the dashboard"`
+	Title       string         `json:"title,omitempty" description:"title of the dashboard"`
+	Description string         `json:"description,omitempty" description:"description of the dashboard"`
+	Panels      []PanelMetrics `json:"panels" description:"panels with their metrics evaluated"`
+}
+
+type PanelMetrics struct {
+	Title   string           `json:"title" description:"title of the panel"`
+	Type    string           `json:"type" description:"chart type, one of line, bar, gauge, table"`
+	Unit    string           `json:"unit,omitempty" description:"unit of values"`
+	Results []FormatedMetric `json:"results" description:"results of the panel metrics in order, metric_name is the legend"`
+}
+
+// MonitorNamespaceDashboard evaluates the panels of the dashboard in the namespace of the request
+func MonitorNamespaceDashboard(monitoringRequest *client.MonitoringRequestParams, name string) (*DashboardMetrics, error) {
+	dashboard := &monitoringv1alpha1.Dashboard{}
+	if err := getDashboard(DashboardGroupVersionResource, monitoringRequest.NsName, name, dashboard); err != nil {
+		return nil, err
+	}
+
+	return monitorDashboard(monitoringRequest, name, &dashboard.Spec, []string{monitoringRequest.NsName}, MetricLevelNamespace), nil
+}
+
+// MonitorWorkspaceDashboard evaluates the panels of the workspace dashboard against all namespaces of the workspace
+// of the request
+func MonitorWorkspaceDashboard(monitoringRequest *client.MonitoringRequestParams, name string) (*DashboardMetrics, error) {
+	dashboard := &monitoringv1alpha1.WorkspaceDashboard{}
+	if err := getDashboard(WorkspaceDashboardGroupVersionResource, "", name, dashboard); err != nil {
+		return nil, err
+	}
+
+	// dashboards of other workspaces are invisible
+	if dashboard.Spec.Workspace != monitoringRequest.WsName {
+		return nil, errors.NewNotFound(WorkspaceDashboardGroupVersionResource.GroupResource(), name)
+	}
+
+	namespaces, err := workspaces.WorkspaceNamespaces(monitoringRequest.WsName)
+	if err != nil {
+		return nil, err
+	}
+
+	return monitorDashboard(monitoringRequest, name, &dashboard.Spec.DashboardSpec, namespaces, MetricLevelWorkspace), nil
+}
+
+func getDashboard(gvr schema.GroupVersionResource, namespace, name string, dashboard interface{}) error {
+	u, err := k8s.DynamicClient().Resource(gvr).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, dashboard)
+}
+
+func monitorDashboard(monitoringRequest *client.MonitoringRequestParams, name string, spec *monitoringv1alpha1.DashboardSpec, namespaces []string, level string) *DashboardMetrics {
+	result := &DashboardMetrics{Name: name, Title: spec.Title, Description: spec.Description, Panels: make([]PanelMetrics, len(spec.Panels))}
+
+	var wg sync.WaitGroup
+
+	for i, panel := range spec.Panels {
+		panelType := panel.Type
+		if panelType == "" {
+			panelType = monitoringv1alpha1.PanelTypeLine
+		}
+		result.Panels[i] = PanelMetrics{Title: panel.Title, Type: panelType, Unit: panel.Unit, Results: make([]FormatedMetric, len(panel.Metrics))}
+
+		for j, metric := range panel.Metrics {
+			wg.Add(1)
+			go func(res *FormatedMetric, metric monitoringv1alpha1.PanelMetric) {
+				*res = *monitorPanelMetric(monitoringRequest, metric, namespaces, level)
+				wg.Done()
+			}(&result.Panels[i].Results[j], metric)
+		}
+	}
+
+	wg.Wait()
+
+	return result
+}
+
+// monitorPanelMetric evaluates a metric of panels, errors are reported in the result so other panels are still shown
+func monitorPanelMetric(monitoringRequest *client.MonitoringRequestParams, metric monitoringv1alpha1.PanelMetric, namespaces []string, level string) *FormatedMetric {
+	legend := metric.Legend
+	if legend == "" {
+		legend = metric.Name + metric.Expr
+	}
+
+	var res *FormatedMetric
+	var err error
+
+	switch {
+	case metric.Name != "" && metric.Expr != "":
+		err = fmt.Errorf("only one of name and expr could be set")
+	case metric.Expr != "":
+		res, err = monitorScopedQuery(monitoringRequest, metric.Expr, legend, namespaces)
+	case level == MetricLevelNamespace && contains(NamespaceMetricsNames, metric.Name):
+		queryType, params := AssembleNamespaceMetricRequestInfoByNamesapce(monitoringRequest, monitoringRequest.NsName, metric.Name)
+		res = QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, legend, map[string]string{MetricLevelNamespace: monitoringRequest.NsName})
+	case level == MetricLevelWorkspace && contains(WorkspaceMetricsNames, metric.Name):
+		queryType, params := AssembleSpecificWorkspaceMetricRequestInfo(monitoringRequest, namespaces, monitoringRequest.WsName, metric.Name)
+		res = QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, legend, map[string]string{ResultItemMetricResourceName: monitoringRequest.WsName})
+	default:
+		err = fmt.Errorf("unknown %s metric %s", level, metric.Name)
+	}
+
+	if err != nil {
+		return &FormatedMetric{MetricName: legend, Status: MetricStatusError, Error: err.Error()}
+	}
+	return res
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -19,6 +19,7 @@
 package metrics
 
 import (
+	"context"
 	"github.com/golang/glog"
 	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/simple/client/kubesphere"
@@ -55,6 +56,8 @@ type FormatedMetric struct {
 	MetricName string             `json:"metric_name,omitempty" description:"metric name, eg. scheduler_up_sum"`
 	Status     string             `json:"status" description:"result status, one of error, success"`
 	Data       FormatedMetricData `json:"data,omitempty" description:"actual metric result"`
+	Error      string             `json:"error,omitempty" description:"error message, only set if the status is error"`
+	ErrorType  string             `json:"errorType,omitempty" description:"error type, only set if the status is error, eg. bad_data, timeout"`
 }
 
 type FormatedMetricData struct {
@@ -131,30 +134,23 @@ func getAllWorkspaceNames(formatedMetric *FormatedMetric) map[string]int {
 	return wsMap
 }
 
-func getAllWorkspaces() map[string]int {
+func getAllWorkspaces(ctx context.Context) map[string]int {
 
 	paramValues := make(url.Values)
 	paramValues.Set("query", WorkspaceNamespaceLabelRule)
 	params := paramValues.Encode()
-	res := client.SendMonitoringRequest(client.PrometheusEndpoint, client.DefaultQueryType, params)
 
-	metric := ReformatJson(res, "", map[string]string{"workspace": "workspace"})
+	metric := QueryMetric(ctx, client.Primary(), client.DefaultQueryType, params, "", map[string]string{"workspace": "workspace"})
 
 	return getAllWorkspaceNames(metric)
 }
 
-func getPodNameRegexInWorkload(res, filter string) string {
+func getPodNameRegexInWorkload(res *client.QueryResult, filter string) string {
 
-	data := []byte(res)
-	var dat CommonMetricsResult
-	jsonErr := jsonIter.Unmarshal(data, &dat)
-	if jsonErr != nil {
-		glog.Errorln("json parse failed", jsonErr.Error(), res)
-	}
 	var podNames []string
 
-	for _, item := range dat.Data.Result {
-		podName := item.KubePodMetric.Pod
+	for _, item := range res.Samples {
+		podName := item.Metric["pod"]
 
 		if filter != "" {
 			if bol, _ := regexp.MatchString(filter, podName); bol {
@@ -189,7 +185,7 @@ func unifyMetricHistoryTimeRange(fmtMetrics *FormatedMetric) {
 				if sure {
 					for j := range valueArray {
 						timeAndValue := valueArray[j].([]interface{})
-						timestampMap[float64(timeAndValue[0].(uint64))] = true
+						timestampMap[timeAndValue[0].(float64)] = true
 					}
 				}
 			}
@@ -216,8 +212,12 @@ func unifyMetricHistoryTimeRange(fmtMetrics *FormatedMetric) {
 					j := 0
 
 					for k := range timestampArray {
+						if j >= len(valueArray) {
+							formatValueArray[k] = []interface{}{int64(timestampArray[k]), "-1"}
+							continue
+						}
 						valueItem, sure := valueArray[j].([]interface{})
-						if sure && float64(valueItem[0].(uint64)) == timestampArray[k] {
+						if sure && valueItem[0].(float64) == timestampArray[k] {
 							formatValueArray[k] = []interface{}{int64(timestampArray[k]), valueItem[1]}
 							j++
 						} else {
@@ -241,7 +241,16 @@ func AssembleSpecificWorkloadMetricRequestInfo(monitoringRequest *client.Monitor
 	paramValues := monitoringRequest.Params
 	params := makeRequestParamString(rule, paramValues)
 
-	res := client.SendMonitoringRequest(client.PrometheusEndpoint, client.DefaultQueryType, params)
+	values, err := url.ParseQuery(params)
+	if err != nil {
+		glog.Errorln(err)
+		return "", "", true
+	}
+	res, err := client.Primary().QueryParams(monitoringRequest.Context, client.DefaultQueryType, values)
+	if err != nil {
+		glog.Errorln("query pods of workload failed", err)
+		return "", "", true
+	}
 
 	podNamesFilter := getPodNameRegexInWorkload(res, podsFilter)
 
@@ -307,8 +316,7 @@ func AddNodeAddressMetric(nodeMetric *FormatedMetric, nodeAddress *map[string][]
 
 func MonitorContainer(monitoringRequest *client.MonitoringRequestParams, metricName string) *FormatedMetric {
 	queryType, params := AssembleContainerMetricRequestInfo(monitoringRequest, metricName)
-	metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-	res := ReformatJson(metricsStr, metricName, map[string]string{MetricLevelContainerName: ""})
+	res := QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{MetricLevelContainerName: ""})
 	return res
 }
 
@@ -421,7 +429,7 @@ func MonitorAllWorkspaces(monitoringRequest *client.MonitoringRequestParams) *Fo
 	var wgAll sync.WaitGroup
 	var wsAllch = make(chan *[]FormatedMetric, ChannelMaxCapacityWorkspaceMetric)
 
-	wsMap := getAllWorkspaces()
+	wsMap := getAllWorkspaces(monitoringRequest.Context)
 
 	for ws := range wsMap {
 		// Only execute Prometheus queries for specific metrics on specific workspaces
@@ -481,8 +489,7 @@ func collectWorkspaceMetric(monitoringRequest *client.MonitoringRequestParams, w
 		go func(metricName string) {
 
 			queryType, params := AssembleSpecificWorkspaceMetricRequestInfo(monitoringRequest, namespaceArray, ws, metricName)
-			metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-			ch <- ReformatJson(metricsStr, metricName, map[string]string{ResultItemMetricResourceName: ws})
+			ch <- QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{ResultItemMetricResourceName: ws})
 			wg.Done()
 		}(metricName)
 	}
@@ -523,8 +530,7 @@ func GetClusterLevelMetrics(monitoringRequest *client.MonitoringRequestParams) *
 			wg.Add(1)
 			go func(metricName string) {
 				queryType, params := AssembleClusterMetricRequestInfo(monitoringRequest, metricName)
-				metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-				ch <- ReformatJson(metricsStr, metricName, map[string]string{MetricLevelCluster: "local"})
+				ch <- QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{MetricLevelCluster: "local"})
 				wg.Done()
 			}(metricName)
 		}
@@ -562,8 +568,7 @@ func GetNodeLevelMetrics(monitoringRequest *client.MonitoringRequestParams) *For
 			wg.Add(1)
 			go func(metricName string) {
 				queryType, params := AssembleNodeMetricRequestInfo(monitoringRequest, metricName)
-				metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-				ch <- ReformatJson(metricsStr, metricName, map[string]string{MetricLevelNode: ""})
+				ch <- QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{MetricLevelNode: ""})
 				wg.Done()
 			}(metricName)
 		}
@@ -625,8 +630,7 @@ func GetWorkspaceLevelMetrics(monitoringRequest *client.MonitoringRequestParams)
 						go func(metricName string, namespace string) {
 
 							queryType, params := AssembleNamespaceMetricRequestInfoByNamesapce(monitoringRequest, namespace, metricName)
-							metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-							chForOneMetric <- ReformatJson(metricsStr, metricName, map[string]string{ResultItemMetricResourceName: namespace})
+							chForOneMetric <- QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{ResultItemMetricResourceName: namespace})
 							wgForOneMetric.Done()
 						}(metricName, ns)
 					}
@@ -669,8 +673,7 @@ func GetWorkspaceLevelMetrics(monitoringRequest *client.MonitoringRequestParams)
 					wg.Add(1)
 					go func(metricName string, workspace string) {
 						queryType, params := AssembleSpecificWorkspaceMetricRequestInfo(monitoringRequest, namespaceArray, workspace, metricName)
-						metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-						ch <- ReformatJson(metricsStr, metricName, map[string]string{ResultItemMetricResourceName: workspace})
+						ch <- QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{ResultItemMetricResourceName: workspace})
 						wg.Done()
 					}(metricName, workspace)
 				}
@@ -686,8 +689,7 @@ func GetWorkspaceLevelMetrics(monitoringRequest *client.MonitoringRequestParams)
 
 				go func(metricName string) {
 					queryType, params := AssembleAllWorkspaceMetricRequestInfo(monitoringRequest, nil, metricName)
-					metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-					ch <- ReformatJson(metricsStr, metricName, map[string]string{MetricLevelWorkspace: "workspaces"})
+					ch <- QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{MetricLevelWorkspace: "workspaces"})
 
 					wg.Done()
 				}(metricName)
@@ -728,9 +730,7 @@ func GetNamespaceLevelMetrics(monitoringRequest *client.MonitoringRequestParams)
 			go func(metricName string) {
 
 				queryType, params := AssembleNamespaceMetricRequestInfo(monitoringRequest, metricName)
-				metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-
-				rawResult := ReformatJson(metricsStr, metricName, map[string]string{MetricLevelNamespace: ""})
+				rawResult := QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{MetricLevelNamespace: ""})
 				ch <- rawResult
 
 				wg.Done()
@@ -771,8 +771,7 @@ func GetWorkloadLevelMetrics(monitoringRequest *client.MonitoringRequestParams)
 				wg.Add(1)
 				go func(metricName string) {
 					queryType, params := AssembleAllWorkloadMetricRequestInfo(monitoringRequest, metricName)
-					metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-					reformattedResult := ReformatJson(metricsStr, metricName, map[string]string{MetricLevelWorkload: ""})
+					reformattedResult := QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{MetricLevelWorkload: ""})
 					// no need to append a null result
 					ch <- reformattedResult
 					wg.Done()
@@ -788,8 +787,7 @@ func GetWorkloadLevelMetrics(monitoringRequest *client.MonitoringRequestParams)
 					metricName = strings.TrimLeft(metricName, "workload_")
 					queryType, params, nullRule := AssembleSpecificWorkloadMetricRequestInfo(monitoringRequest, metricName)
 					if !nullRule {
-						metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-						fmtMetrics := ReformatJson(metricsStr, metricName, map[string]string{MetricLevelPodName: ""})
+						fmtMetrics := QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{MetricLevelPodName: ""})
 						unifyMetricHistoryTimeRange(fmtMetrics)
 						ch <- fmtMetrics
 					}
@@ -832,8 +830,7 @@ func GetPodLevelMetrics(monitoringRequest *client.MonitoringRequestParams) *Form
 			go func(metricName string) {
 				queryType, params, nullRule := AssemblePodMetricRequestInfo(monitoringRequest, metricName)
 				if !nullRule {
-					metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-					ch <- ReformatJson(metricsStr, metricName, map[string]string{MetricLevelPodName: ""})
+					ch <- QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{MetricLevelPodName: ""})
 				} else {
 					ch <- nil
 				}
@@ -874,8 +871,7 @@ func GetContainerLevelMetrics(monitoringRequest *client.MonitoringRequestParams)
 			wg.Add(1)
 			go func(metricName string) {
 				queryType, params := AssembleContainerMetricRequestInfo(monitoringRequest, metricName)
-				metricsStr := client.SendMonitoringRequest(client.PrometheusEndpoint, queryType, params)
-				ch <- ReformatJson(metricsStr, metricName, map[string]string{MetricLevelContainerName: ""})
+				ch <- QueryMetric(monitoringRequest.Context, client.Primary(), queryType, params, metricName, map[string]string{MetricLevelContainerName: ""})
 				wg.Done()
 			}(metricName)
 		}
@@ -913,8 +909,7 @@ func GetComponentLevelMetrics(monitoringRequest *client.MonitoringRequestParams)
 			wg.Add(1)
 			go func(metricName string) {
 				queryType, params := AssembleComponentRequestInfo(monitoringRequest, metricName)
-				metricsStr := client.SendMonitoringRequest(client.SecondaryPrometheusEndpoint, queryType, params)
-				formattedJson := ReformatJson(metricsStr, metricName, map[string]string{ResultItemMetricResourceName: monitoringRequest.ComponentName})
+				formattedJson := QueryMetric(monitoringRequest.Context, client.Secondary(), queryType, params, metricName, map[string]string{ResultItemMetricResourceName: monitoringRequest.ComponentName})
 
 				if metricName == EtcdServerList {
 
@@ -0,0 +1,347 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	tokenIdentifier = iota
+	tokenString
+	tokenNumber
+	tokenPunctuation
+)
+
+type promQLToken struct {
+	kind  int
+	text  string
+	start int
+	end   int
+}
+
+var (
+	// aggregation operators are followed by ( or by/without, otherwise they are metric names
+	promQLAggregations = map[string]bool{
+		"sum": true, "min": true, "max": true, "avg": true, "group": true, "stddev": true, "stdvar": true,
+		"count": true, "count_values": true, "bottomk": true, "topk": true, "quantile": true,
+	}
+	// keywords following operands, otherwise they are metric names
+	promQLBinaryKeywords = map[string]bool{"and": true, "or": true, "unless": true, "atan2": true, "offset": true}
+	// keywords following the labels of vector matching
+	promQLMatchingModifiers = map[string]bool{"group_left": true, "group_right": true}
+	// keywords followed by lists of label names
+	promQLGroupingKeywords = map[string]bool{
+		"by": true, "without": true, "on": true, "ignoring": true, "group_left": true, "group_right": true,
+	}
+	promQLComparisons = map[string]bool{"==": true, "!=": true, "<=": true, ">=": true, "<": true, ">": true}
+	// operators of two characters
+	promQLOperators      = map[string]bool{"==": true, "!=": true, "<=": true, ">=": true, "=~": true, "!~": true}
+	promQLMatchOperators = map[string]bool{"=": true, "!=": true, "=~": true, "!~": true}
+
+	labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// EnforceLabelMatcher scopes the query to the values of the label by adding a matcher of the label to every
+// vector selector of the query, eg. rate(http_requests_total[5m]) is rewritten into
+// rate(http_requests_total{namespace="default"}[5m]). Matchers of the label in the query are kept, a series must
+// match both of them.
+func EnforceLabelMatcher(query, label string, values []string) (string, error) {
+	if !labelNamePattern.MatchString(label) {
+		return "", fmt.Errorf("invalid label name %s", label)
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("no values of label %s to scope the query", label)
+	}
+
+	var matcher string
+	if len(values) == 1 {
+		matcher = label + "=" + strconv.Quote(values[0])
+	} else {
+		quoted := make([]string, 0, len(values))
+		for _, value := range values {
+			quoted = append(quoted, regexp.QuoteMeta(value))
+		}
+		sort.Strings(quoted)
+		matcher = label + "=~" + strconv.Quote(strings.Join(quoted, "|"))
+	}
+
+	tokens, err := lexPromQL(query)
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("empty query")
+	}
+
+	// text inserted at the offsets of the query
+	insertions := make(map[int]string)
+	selectors := 0
+	depth := 0
+	// depth of the parentheses enclosing a list of label names, -1 if not in a list
+	grouping := -1
+	groupingKeyword := ""
+	// whether the previous token ends an operand, keywords of binary operations follow operands
+	operand := false
+	// whether the previous token ends the labels of vector matching
+	matching := false
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		previous := ""
+		if i > 0 {
+			previous = tokens[i-1].text
+		}
+		afterMatching := matching
+		matching = false
+
+		switch token.kind {
+		case tokenString, tokenNumber:
+			operand = true
+
+		case tokenPunctuation:
+			operand = token.text == ")" || token.text == "]"
+
+			switch token.text {
+			case "(":
+				depth++
+			case ")":
+				if depth == 0 {
+					return "", fmt.Errorf("unexpected ) at %d", token.start)
+				}
+				if depth == grouping {
+					grouping = -1
+					// aggregations end with their labels, labels of vector matching are followed by modifiers or operands
+					operand = groupingKeyword == "by" || groupingKeyword == "without"
+					matching = groupingKeyword == "on" || groupingKeyword == "ignoring"
+				}
+				depth--
+			case "{":
+				// label matchers without a metric name
+				end, err := matchersEnd(tokens, i)
+				if err != nil {
+					return "", err
+				}
+				insertions[token.end] = selectorMatcher(matcher, i, end)
+				selectors++
+				operand = true
+				i = end
+			case "}":
+				return "", fmt.Errorf("unexpected } at %d", token.start)
+			}
+
+		case tokenIdentifier:
+			if grouping >= 0 {
+				continue
+			}
+
+			name := strings.ToLower(token.text)
+			next := nextToken(tokens, i)
+			nextText := ""
+			if next != nil {
+				nextText = strings.ToLower(next.text)
+			}
+
+			if isPromQLKeyword(name, operand, afterMatching, previous, nextText) {
+				if promQLGroupingKeywords[name] && nextText == "(" {
+					grouping = depth + 1
+					groupingKeyword = name
+				}
+				operand = false
+				continue
+			}
+
+			// function calls and aggregations
+			if nextText == "(" || (promQLAggregations[name] && (nextText == "by" || nextText == "without")) {
+				operand = false
+				continue
+			}
+
+			operand = true
+
+			if next != nil && next.text == "{" {
+				end, err := matchersEnd(tokens, i+1)
+				if err != nil {
+					return "", err
+				}
+				insertions[next.end] = selectorMatcher(matcher, i+1, end)
+				i = end
+			} else {
+				insertions[token.end] = "{" + matcher + "}"
+			}
+			selectors++
+		}
+	}
+
+	if depth != 0 {
+		return "", fmt.Errorf("unclosed (")
+	}
+	if selectors == 0 {
+		return "", fmt.Errorf("the query doesn't select any series")
+	}
+
+	var rewritten strings.Builder
+	last := 0
+	for _, token := range tokens {
+		if text, ok := insertions[token.end]; ok {
+			rewritten.WriteString(query[last:token.end])
+			rewritten.WriteString(text)
+			last = token.end
+		}
+	}
+	rewritten.WriteString(query[last:])
+
+	return rewritten.String(), nil
+}
+
+// isPromQLKeyword checks whether the name is a keyword at its position, keywords are also valid metric names
+func isPromQLKeyword(name string, operand, afterMatching bool, previous, next string) bool {
+	switch {
+	case promQLBinaryKeywords[name]:
+		return operand
+	case promQLMatchingModifiers[name]:
+		return afterMatching
+	case name == "bool":
+		return promQLComparisons[previous]
+	case promQLGroupingKeywords[name]:
+		return next == "("
+	default:
+		return false
+	}
+}
+
+// selectorMatcher returns the matcher inserted after the { of the selector
+func selectorMatcher(matcher string, open, close int) string {
+	if close == open+1 {
+		return matcher
+	}
+	return matcher + ","
+}
+
+// matchersEnd validates the label matchers starting with the { at open, and returns the index of the closing }
+func matchersEnd(tokens []promQLToken, open int) (int, error) {
+	i := open + 1
+	for i < len(tokens) {
+		if tokens[i].text == "}" {
+			return i, nil
+		}
+
+		if i+2 >= len(tokens) || tokens[i].kind != tokenIdentifier || !promQLMatchOperators[tokens[i+1].text] || tokens[i+2].kind != tokenString {
+			return 0, fmt.Errorf("invalid label matcher at %d", tokens[i].start)
+		}
+		i += 3
+
+		if i < len(tokens) && tokens[i].text == "," {
+			i++
+		} else if i < len(tokens) && tokens[i].text != "}" {
+			return 0, fmt.Errorf("unexpected %s at %d", tokens[i].text, tokens[i].start)
+		}
+	}
+	return 0, fmt.Errorf("unclosed {")
+}
+
+func nextToken(tokens []promQLToken, i int) *promQLToken {
+	if i+1 < len(tokens) {
+		return &tokens[i+1]
+	}
+	return nil
+}
+
+// lexPromQL splits the query into tokens, whitespaces and comments are dropped
+func lexPromQL(query string) ([]promQLToken, error) {
+	var tokens []promQLToken
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			continue
+
+		case c == '"' || c == '\'' || c == '`':
+			i++
+			for i < len(query) && query[i] != c {
+				if query[i] == '\\' && c != '`' {
+					i++
+				}
+				i++
+			}
+			if i >= len(query) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+			tokens = append(tokens, promQLToken{kind: tokenString, text: query[start:i], start: start, end: i})
+
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			// numbers, eg. 1.5e+3, 0x1f, and durations, eg. 1h30m
+			for i < len(query) && (isAlphanumeric(query[i]) || query[i] == '.') {
+				if (query[i] == 'e' || query[i] == 'E') && i+1 < len(query) && (query[i+1] == '+' || query[i+1] == '-') && !strings.HasPrefix(strings.ToLower(query[start:]), "0x") {
+					i++
+				}
+				i++
+			}
+			tokens = append(tokens, promQLToken{kind: tokenNumber, text: query[start:i], start: start, end: i})
+
+		// colons only appear inside of metric names, a leading colon separates the range and resolution of subqueries
+		case isAlpha(c) || c == '_':
+			for i < len(query) && (isAlphanumeric(query[i]) || query[i] == '_' || query[i] == ':') {
+				i++
+			}
+			kind := tokenIdentifier
+			if name := strings.ToLower(query[start:i]); name == "inf" || name == "nan" {
+				kind = tokenNumber
+			}
+			tokens = append(tokens, promQLToken{kind: kind, text: query[start:i], start: start, end: i})
+
+		default:
+			i++
+			if i < len(query) && promQLOperators[query[start:i+1]] {
+				i++
+			} else if !strings.Contains("(){}[],+-*/%^=<>@:", query[start:i]) {
+				return nil, fmt.Errorf("unexpected %s at %d", query[start:i], start)
+			}
+			tokens = append(tokens, promQLToken{kind: tokenPunctuation, text: query[start:i], start: start, end: i})
+		}
+	}
+
+	return tokens, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlphanumeric(c byte) bool {
+	return isAlpha(c) || isDigit(c)
+}
@@ -0,0 +1,76 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnforceLabelMatcher(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{`up`, `up{namespace="demo"}`},
+		{`up{}`, `up{namespace="demo"}`},
+		{`up{job="web"}`, `up{namespace="demo",job="web"}`},
+		{`{__name__=~"http_.*"}`, `{namespace="demo",__name__=~"http_.*"}`},
+		{`rate(http_requests_total[5m] offset 1h)`, `rate(http_requests_total{namespace="demo"}[5m] offset 1h)`},
+		{`sum by (namespace, code) (rate(http_requests_total{code=~"5.."}[5m]))`, `sum by (namespace, code) (rate(http_requests_total{namespace="demo",code=~"5.."}[5m]))`},
+		{`sum(rate(a[5m])) without (pod) / ignoring(pod) group_left(node) b`, `sum(rate(a{namespace="demo"}[5m])) without (pod) / ignoring(pod) group_left(node) b{namespace="demo"}`},
+		{`a AND ON (job) b > bool 0.5e+3`, `a{namespace="demo"} AND ON (job) b{namespace="demo"} > bool 0.5e+3`},
+		{`max_over_time(namespace:container_cpu_usage:sum[1h:5m])`, `max_over_time(namespace:container_cpu_usage:sum{namespace="demo"}[1h:5m])`},
+		{`label_replace(up, "dst", "$1", "src", "(.*)") # up{}`, `label_replace(up{namespace="demo"}, "dst", "$1", "src", "(.*)") # up{}`},
+		{`topk(3, count_values("version", build_info)) * Inf`, `topk(3, count_values("version", build_info{namespace="demo"})) * Inf`},
+		// keywords, functions and aggregations are metric names at the positions of selectors
+		{`count_over_time(sum[5m])`, `count_over_time(sum{namespace="demo"}[5m])`},
+		{`rate`, `rate{namespace="demo"}`},
+		{`sum by (job) (sum)`, `sum by (job) (sum{namespace="demo"})`},
+		{`sum(up) by (job) and offset`, `sum(up{namespace="demo"}) by (job) and offset{namespace="demo"}`},
+		{`a / on(job) and`, `a{namespace="demo"} / on(job) and{namespace="demo"}`},
+		{`a * on(job) group_left b`, `a{namespace="demo"} * on(job) group_left b{namespace="demo"}`},
+		{`bool > bool bool`, `bool{namespace="demo"} > bool bool{namespace="demo"}`},
+		{`group_left or by`, `group_left{namespace="demo"} or by{namespace="demo"}`},
+		{`max_over_time(topk[1h]) unless without`, `max_over_time(topk{namespace="demo"}[1h]) unless without{namespace="demo"}`},
+	}
+
+	for _, test := range tests {
+		rewritten, err := EnforceLabelMatcher(test.query, "namespace", []string{"demo"})
+		assert.NoError(t, err, test.query)
+		assert.Equal(t, test.expected, rewritten)
+	}
+
+	rewritten, err := EnforceLabelMatcher(`up{namespace="kube-system"}`, "namespace", []string{"demo", "a.b"})
+	assert.NoError(t, err)
+	assert.Equal(t, `up{namespace=~"a\\.b|demo",namespace="kube-system"}`, rewritten)
+}
+
+func TestEnforceLabelMatcherErrors(t *testing.T) {
+	for _, query := range []string{``, `1 + 1`, `rate(up[5m]`, `up{job="web"`, `up{job=web}`, `up{job="web}`, `up)`, `up{job!}`, `sum(up) }`} {
+		_, err := EnforceLabelMatcher(query, "namespace", []string{"demo"})
+		assert.Error(t, err, query)
+	}
+
+	_, err := EnforceLabelMatcher(`up`, "namespace", nil)
+	assert.Error(t, err)
+
+	_, err = EnforceLabelMatcher(`up`, "name-space", []string{"demo"})
+	assert.Error(t, err)
+}
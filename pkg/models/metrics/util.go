@@ -19,7 +19,9 @@
 package metrics
 
 import (
+	"context"
 	"math"
+	"net/url"
 	"sort"
 	"strconv"
 	"unicode"
@@ -27,6 +29,8 @@ import (
 	"runtime/debug"
 
 	"github.com/golang/glog"
+
+	client "kubesphere.io/kubesphere/pkg/simple/client/prometheus"
 )
 
 const (
@@ -254,52 +258,97 @@ func Page(pageNum string, limitNum string, fmtLevelMetric *FormatedLevelMetric,
 	}
 }
 
-// maybe this function is time consuming
-// The metric param is the result from Prometheus HTTP query
-func ReformatJson(metric string, metricsName string, needAddParams map[string]string, needDelParams ...string) *FormatedMetric {
-	var formatMetric FormatedMetric
-
-	err := jsonIter.Unmarshal([]byte(metric), &formatMetric)
+// QueryMetric sends the query of the encoded params to prometheus and reformats the result, labels of needAddParams
+// are renamed to resource_name, or resource_name is set to their values if the labels don't exist.
+// Errors of the query are returned in the status, error and errorType of the metric.
+func QueryMetric(ctx context.Context, c *client.Client, queryType string, params string, metricsName string, needAddParams map[string]string, needDelParams ...string) *FormatedMetric {
+	formatMetric := &FormatedMetric{MetricName: metricsName}
 
+	values, err := url.ParseQuery(params)
 	if err != nil {
-		glog.Errorln("Unmarshal metric json failed", err.Error(), metric)
+		formatMetric.Status = MetricStatusError
+		formatMetric.ErrorType = client.ErrorTypeBadData
+		formatMetric.Error = err.Error()
+		return formatMetric
 	}
-	if formatMetric.MetricName == "" {
-		if metricsName != "" {
-			formatMetric.MetricName = metricsName
+
+	result, err := c.QueryParams(ctx, queryType, values)
+	if err != nil {
+		glog.Errorln("query prometheus failed", err.Error(), params)
+		formatMetric.Status = MetricStatusError
+		formatMetric.ErrorType = client.ErrorTypeInternal
+		if e, ok := err.(*client.Error); ok {
+			formatMetric.ErrorType = e.Type
 		}
+		formatMetric.Error = err.Error()
+		return formatMetric
 	}
-	// retrive metrics success
-	if formatMetric.Status == MetricStatusSuccess {
-		result := formatMetric.Data.Result
-		for _, res := range result {
-			metric, exist := res[ResultItemMetric]
-			// Prometheus query result format: .data.result[].metric
-			// metricMap is the value of .data.result[].metric
-			metricMap, sure := metric.(map[string]interface{})
-			if exist && sure {
-				delete(metricMap, "__name__")
-			}
-			if len(needDelParams) > 0 {
-				for _, p := range needDelParams {
-					delete(metricMap, p)
-				}
-			}
 
-			if needAddParams != nil && len(needAddParams) > 0 {
-				for n := range needAddParams {
-					if v, ok := metricMap[n]; ok {
-						delete(metricMap, n)
-						metricMap[ResultItemMetricResourceName] = v
-					} else {
-						metricMap[ResultItemMetricResourceName] = needAddParams[n]
-					}
-				}
+	formatMetric.Status = MetricStatusSuccess
+	formatMetric.Data = formatQueryResult(result)
+	reformatMetric(formatMetric, needAddParams, needDelParams...)
+
+	return formatMetric
+}
+
+// formatQueryResult converts typed results into the format of Prometheus HTTP query results,
+// .data.result[].metric and .data.result[].value or .data.result[].values
+func formatQueryResult(result *client.QueryResult) FormatedMetricData {
+	data := FormatedMetricData{ResultType: result.ResultType, Result: make([]map[string]interface{}, 0, len(result.Samples))}
+
+	if result.Scalar != nil {
+		data.Result = append(data.Result, map[string]interface{}{
+			ResultItemMetric: map[string]interface{}{},
+			ResultItemValue:  []interface{}{result.Scalar.Timestamp, result.Scalar.Value},
+		})
+		return data
+	}
+
+	for _, sample := range result.Samples {
+		metric := make(map[string]interface{}, len(sample.Metric))
+		for k, v := range sample.Metric {
+			metric[k] = v
+		}
+		item := map[string]interface{}{ResultItemMetric: metric}
+		if sample.Value != nil {
+			item[ResultItemValue] = []interface{}{sample.Value.Timestamp, sample.Value.Value}
+		}
+		if sample.Values != nil {
+			values := make([]interface{}, len(sample.Values))
+			for i, p := range sample.Values {
+				values[i] = []interface{}{p.Timestamp, p.Value}
 			}
+			item[ResultItemValues] = values
 		}
+		data.Result = append(data.Result, item)
 	}
 
-	return &formatMetric
+	return data
+}
+
+func reformatMetric(formatMetric *FormatedMetric, needAddParams map[string]string, needDelParams ...string) {
+	for _, res := range formatMetric.Data.Result {
+		metric, exist := res[ResultItemMetric]
+		// Prometheus query result format: .data.result[].metric
+		// metricMap is the value of .data.result[].metric
+		metricMap, sure := metric.(map[string]interface{})
+		if !exist || !sure {
+			continue
+		}
+		delete(metricMap, "__name__")
+		for _, p := range needDelParams {
+			delete(metricMap, p)
+		}
+
+		for n := range needAddParams {
+			if v, ok := metricMap[n]; ok {
+				delete(metricMap, n)
+				metricMap[ResultItemMetricResourceName] = v
+			} else {
+				metricMap[ResultItemMetricResourceName] = needAddParams[n]
+			}
+		}
+	}
 }
 
 func ReformatNodeStatusField(nodeMetric *FormatedMetric) *FormatedMetric {
@@ -0,0 +1,66 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	client "kubesphere.io/kubesphere/pkg/simple/client/prometheus"
+)
+
+func TestQueryMetric(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "up":
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up","node":"node-1","job":"node-exporter"},"value":[1546300800,"1"]}]}}`))
+		case "range":
+			w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"pod":"web-0"},"values":[[1546300800,"1"],[1546300860,"2"]]},{"metric":{"pod":"web-1"},"values":[[1546300860,"3"]]}]}}`))
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"status":"error","errorType":"execution","error":"many-to-many matching not allowed"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := client.NewClient(server.URL+"/api/v1/", 10, 0)
+
+	res := QueryMetric(context.Background(), c, client.DefaultQueryType, url.Values{"query": {"up"}}.Encode(), "node_up", map[string]string{MetricLevelNode: ""}, "job")
+	assert.Equal(t, MetricStatusSuccess, res.Status)
+	assert.Equal(t, "node_up", res.MetricName)
+	assert.Equal(t, []map[string]interface{}{{
+		ResultItemMetric: map[string]interface{}{ResultItemMetricResourceName: "node-1"},
+		ResultItemValue:  []interface{}{float64(1546300800), "1"},
+	}}, res.Data.Result)
+
+	params := url.Values{"query": {"range"}, "start": {"1546300800"}, "end": {"1546300860"}, "step": {"1m"}}
+	res = QueryMetric(context.Background(), c, client.RangeQueryType, params.Encode(), "pod_cpu_usage", nil)
+	assert.Equal(t, ResultTypeMatrix, res.Data.ResultType)
+	unifyMetricHistoryTimeRange(res)
+	assert.Equal(t, [][]interface{}{{int64(1546300800), "-1"}, {int64(1546300860), "3"}}, res.Data.Result[1][ResultItemValues])
+
+	res = QueryMetric(context.Background(), c, client.DefaultQueryType, url.Values{"query": {"a * on() b"}}.Encode(), "custom", nil)
+	assert.Equal(t, MetricStatusError, res.Status)
+	assert.Equal(t, client.ErrorTypeExecution, res.ErrorType)
+	assert.Equal(t, http.StatusUnprocessableEntity, client.HTTPStatus(res.ErrorType))
+}
@@ -0,0 +1,171 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sinformers "k8s.io/client-go/informers"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+const (
+	FacetStatus    = "status"
+	FacetOwnerKind = "ownerKind"
+	FacetNode      = "node"
+	FacetNamespace = "namespace"
+	FacetWorkspace = "workspace"
+	// FacetLabelPrefix groups resources by the value of a label, e.g. label:app
+	FacetLabelPrefix = "label:"
+)
+
+// Aggregation is the counts of resources grouped by the facets, resources without
+// value of the facet e.g. pods not scheduled in the node facet are not counted
+type Aggregation struct {
+	TotalCount int                       `json:"total_count" description:"total count of the resources"`
+	Facets     map[string]map[string]int `json:"facets,omitempty" description:"resource count of each value of the facets, e.g. {\"status\": {\"running\": 3}}"`
+}
+
+// ParseFacets parses comma separated facets, e.g. status,namespace,label:app
+func ParseFacets(facetsStr string) ([]string, error) {
+	facets := make([]string, 0)
+
+	if facetsStr == "" {
+		return facets, nil
+	}
+
+	for _, facet := range strings.Split(facetsStr, ",") {
+		switch facet {
+		case FacetStatus, FacetOwnerKind, FacetNode, FacetNamespace, FacetWorkspace:
+		default:
+			if !strings.HasPrefix(facet, FacetLabelPrefix) || facet == FacetLabelPrefix {
+				return nil, fmt.Errorf("invalid facet %s", facet)
+			}
+		}
+		facets = append(facets, facet)
+	}
+
+	return facets, nil
+}
+
+// AggregateResources counts the resources matching the conditions by the facets,
+// resources in all namespaces are aggregated if namespace is empty
+func AggregateResources(namespace, resource string, conditions *params.Conditions, facets []string) (*Aggregation, error) {
+	return HostCluster().AggregateResources(namespace, resource, conditions, facets)
+}
+
+func (c *ClusterResources) AggregateResources(namespace, resource string, conditions *params.Conditions, facets []string) (*Aggregation, error) {
+	items, err := c.searchResources(namespace, resource, conditions, "", false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aggregation := &Aggregation{TotalCount: len(items), Facets: make(map[string]map[string]int)}
+	workspaces := make(map[string]string)
+
+	for _, facet := range facets {
+		counts := make(map[string]int)
+
+		for _, item := range items {
+			if value, ok := facetValue(c.informers, item, facet, workspaces); ok {
+				counts[value]++
+			}
+		}
+
+		aggregation.Facets[facet] = counts
+	}
+
+	return aggregation, nil
+}
+
+func facetValue(factory k8sinformers.SharedInformerFactory, item interface{}, facet string, workspaces map[string]string) (string, bool) {
+	accessor, err := meta.Accessor(item)
+
+	if err != nil {
+		return "", false
+	}
+
+	switch facet {
+	case FacetStatus:
+		return itemStatus(item)
+	case FacetOwnerKind:
+		if owner := metav1.GetControllerOf(accessor); owner != nil {
+			return owner.Kind, true
+		}
+	case FacetNode:
+		switch item := item.(type) {
+		case *corev1.Pod:
+			return item.Spec.NodeName, item.Spec.NodeName != ""
+		case *unstructured.Unstructured:
+			node, ok, _ := unstructured.NestedString(item.Object, "spec", "nodeName")
+			return node, ok && node != ""
+		}
+	case FacetNamespace:
+		return accessor.GetNamespace(), accessor.GetNamespace() != ""
+	case FacetWorkspace:
+		if _, ok := item.(*corev1.Namespace); ok {
+			workspace := accessor.GetLabels()[constants.WorkspaceLabelKey]
+			return workspace, workspace != ""
+		}
+		if accessor.GetNamespace() == "" {
+			return "", false
+		}
+		workspace, ok := workspaces[accessor.GetNamespace()]
+		if !ok {
+			if ns, err := factory.Core().V1().Namespaces().Lister().Get(accessor.GetNamespace()); err == nil {
+				workspace = ns.Labels[constants.WorkspaceLabelKey]
+			}
+			workspaces[accessor.GetNamespace()] = workspace
+		}
+		return workspace, workspace != ""
+	default:
+		if strings.HasPrefix(facet, FacetLabelPrefix) {
+			value, ok := accessor.GetLabels()[strings.TrimPrefix(facet, FacetLabelPrefix)]
+			return value, ok
+		}
+	}
+
+	return "", false
+}
+
+// itemStatus returns the status used by the status condition of the searchers,
+// the phase is used for other resources
+func itemStatus(item interface{}) (string, bool) {
+	if status, ok := resourceStatus(item); ok {
+		return status, true
+	}
+
+	switch item := item.(type) {
+	case *corev1.Pod:
+		return string(item.Status.Phase), item.Status.Phase != ""
+	case *corev1.Namespace:
+		return string(item.Status.Phase), item.Status.Phase != ""
+	case *unstructured.Unstructured:
+		phase, ok, _ := unstructured.NestedString(item.Object, "status", "phase")
+		return phase, ok && phase != ""
+	}
+
+	return "", false
+}
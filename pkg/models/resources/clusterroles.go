@@ -18,8 +18,8 @@
 package resources
 
 import (
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
-	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/utils/k8sutil"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
@@ -33,8 +33,8 @@ import (
 type clusterRoleSearcher struct {
 }
 
-func (*clusterRoleSearcher) get(namespace, name string) (interface{}, error) {
-	return informers.SharedInformerFactory().Rbac().V1().ClusterRoles().Lister().Get(name)
+func (*clusterRoleSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
+	return factory.Rbac().V1().ClusterRoles().Lister().Get(name)
 }
 
 // exactly Match
@@ -110,8 +110,8 @@ func (*clusterRoleSearcher) compare(a, b *rbac.ClusterRole, orderBy string) bool
 	}
 }
 
-func (s *clusterRoleSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
-	clusterRoles, err := informers.SharedInformerFactory().Rbac().V1().ClusterRoles().Lister().List(labels.Everything())
+func (s *clusterRoleSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+	clusterRoles, err := factory.Rbac().V1().ClusterRoles().Lister().List(labels.Everything())
 
 	if err != nil {
 		return nil, err
@@ -18,8 +18,8 @@
 package resources
 
 import (
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
-	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"sort"
@@ -32,8 +32,8 @@ import (
 type configMapSearcher struct {
 }
 
-func (*configMapSearcher) get(namespace, name string) (interface{}, error) {
-	return informers.SharedInformerFactory().Core().V1().ConfigMaps().Lister().ConfigMaps(namespace).Get(name)
+func (*configMapSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
+	return factory.Core().V1().ConfigMaps().Lister().ConfigMaps(namespace).Get(name)
 }
 
 // exactly Match
@@ -99,8 +99,8 @@ func (*configMapSearcher) compare(a, b *v1.ConfigMap, orderBy string) bool {
 	}
 }
 
-func (s *configMapSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
-	configMaps, err := informers.SharedInformerFactory().Core().V1().ConfigMaps().Lister().ConfigMaps(namespace).List(labels.Everything())
+func (s *configMapSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+	configMaps, err := factory.Core().V1().ConfigMaps().Lister().ConfigMaps(namespace).List(labels.Everything())
 
 	if err != nil {
 		return nil, err
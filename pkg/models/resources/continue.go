@@ -0,0 +1,244 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+	"kubesphere.io/kubesphere/pkg/models"
+	"kubesphere.io/kubesphere/pkg/params"
+	"kubesphere.io/kubesphere/pkg/utils/idutils"
+)
+
+const (
+	snapshotTTL  = 5 * time.Minute
+	maxSnapshots = 1000
+	defaultLimit = 10
+)
+
+var (
+	ErrInvalidContinueToken = errors.New("invalid continue token")
+	// ErrContinueTokenExpired is returned if the listing snapshot is expired, the list should be restarted
+	ErrContinueTokenExpired = errors.New("continue token is expired, restart the list")
+)
+
+// snapshot is the sorted result of a listing, continued pages are sliced from the
+// snapshot so that items don't shift while paging and the list is only sorted once.
+// Snapshots are kept by each replica and can only be continued by the user of the listing.
+type snapshot struct {
+	cluster         string
+	namespace       string
+	resource        string
+	username        string
+	items           []interface{}
+	resourceVersion string
+	expireAt        time.Time
+}
+
+// continueToken refers to the snapshot and the position of the next page, the key of the last item
+// is used to find the position in a new listing if the snapshot isn't kept by the replica
+type continueToken struct {
+	Snapshot string `json:"s"`
+	Offset   int    `json:"o"`
+	Limit    int    `json:"l"`
+	Last     string `json:"k"`
+}
+
+var snapshots = struct {
+	sync.Mutex
+	items map[string]*snapshot
+}{items: make(map[string]*snapshot)}
+
+func saveSnapshot(s *snapshot) string {
+	snapshots.Lock()
+	defer snapshots.Unlock()
+
+	now := time.Now()
+	var oldest string
+
+	for id, item := range snapshots.items {
+		if item.expireAt.Before(now) {
+			delete(snapshots.items, id)
+		} else if oldest == "" || item.expireAt.Before(snapshots.items[oldest].expireAt) {
+			oldest = id
+		}
+	}
+
+	if len(snapshots.items) >= maxSnapshots {
+		delete(snapshots.items, oldest)
+	}
+
+	id := idutils.GetUuid("")
+	s.expireAt = now.Add(snapshotTTL)
+	snapshots.items[id] = s
+
+	return id
+}
+
+func loadSnapshot(id string) *snapshot {
+	snapshots.Lock()
+	defer snapshots.Unlock()
+
+	s, ok := snapshots.items[id]
+
+	if !ok || s.expireAt.Before(time.Now()) {
+		delete(snapshots.items, id)
+		return nil
+	}
+
+	return s
+}
+
+func encodeContinueToken(token continueToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeContinueToken(s string) (*continueToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return nil, ErrInvalidContinueToken
+	}
+
+	token := &continueToken{}
+
+	if err := json.Unmarshal(data, token); err != nil || token.Offset < 0 || token.Snapshot == "" {
+		return nil, ErrInvalidContinueToken
+	}
+
+	return token, nil
+}
+
+// ListResourcesWithContinue lists at most limit resources for the user, the next page can be retrieved
+// with the continue token in the response. Pages are retrieved from the snapshot of the first listing
+// if the replica keeps it, otherwise the resources are listed again and the next page starts after the
+// last item of the previous page. Continued requests should have the conditions and ordering of the first listing.
+func ListResourcesWithContinue(namespace, resource string, conditions *params.Conditions, orderBy string, reverse bool, limit int, continueStr, username string) (*models.PageableResponse, error) {
+	return HostCluster().ListResourcesWithContinue(namespace, resource, conditions, orderBy, reverse, limit, continueStr, username)
+}
+
+func (c *ClusterResources) ListResourcesWithContinue(namespace, resource string, conditions *params.Conditions, orderBy string, reverse bool, limit int, continueStr, username string) (*models.PageableResponse, error) {
+	var s *snapshot
+	var snapshotId string
+	var token *continueToken
+	offset := 0
+
+	if continueStr != "" {
+		var err error
+		token, err = decodeContinueToken(continueStr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		snapshotId = token.Snapshot
+		s = loadSnapshot(snapshotId)
+
+		if s != nil && (s.cluster != c.cluster || s.namespace != namespace || s.resource != resource || s.username != username) {
+			return nil, ErrInvalidContinueToken
+		}
+
+		offset = token.Offset
+
+		if limit <= 0 {
+			limit = token.Limit
+		}
+	}
+
+	if s == nil {
+		// the resource version is read before listing, changes during the listing are sent to the watches started from it
+		version := c.listResourceVersion(resource)
+
+		items, err := c.searchResources(namespace, resource, conditions, orderBy, reverse)
+
+		if err != nil {
+			return nil, err
+		}
+
+		s = &snapshot{cluster: c.cluster, namespace: namespace, resource: resource, username: username, items: items, resourceVersion: version}
+		snapshotId = ""
+
+		if token != nil {
+			offset = indexOfKey(items, token.Last) + 1
+
+			// the position can't be found if the last item is deleted
+			if offset == 0 {
+				return nil, ErrContinueTokenExpired
+			}
+		}
+	}
+
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	result := &models.PageableResponse{TotalCount: len(s.items), Items: make([]interface{}, 0), ResourceVersion: s.resourceVersion}
+
+	for i := offset; i < len(s.items) && i < offset+limit; i++ {
+		result.Items = append(result.Items, injector.addExtraAnnotations(c.informers, s.items[i]))
+	}
+
+	if offset+limit < len(s.items) {
+		if snapshotId == "" {
+			snapshotId = saveSnapshot(s)
+		}
+		result.Continue = encodeContinueToken(continueToken{Snapshot: snapshotId, Offset: offset + limit, Limit: limit, Last: keyOf(s.items[offset+limit-1])})
+	}
+
+	return result, nil
+}
+
+func keyOf(item interface{}) string {
+	key, _ := cache.MetaNamespaceKeyFunc(item)
+	return key
+}
+
+// indexOfKey returns the index of the item of the key, or -1 if it's not found
+func indexOfKey(items []interface{}, key string) int {
+	if key == "" {
+		return -1
+	}
+
+	for i, item := range items {
+		if keyOf(item) == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func resourceVersion(item interface{}) uint64 {
+	accessor, err := meta.Accessor(item)
+
+	if err != nil {
+		return 0
+	}
+
+	version, _ := strconv.ParseUint(accessor.GetResourceVersion(), 10, 64)
+
+	return version
+}
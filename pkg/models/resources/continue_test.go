@@ -0,0 +1,102 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sinformers "k8s.io/client-go/informers"
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+func newConfigMap(namespace, name, resourceVersion string) *v1.ConfigMap {
+	return &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, ResourceVersion: resourceVersion}}
+}
+
+// newTestCluster returns a member cluster with the config maps in the informer store, the informers are not started
+func newTestCluster(configMaps ...*v1.ConfigMap) (*ClusterResources, k8sinformers.SharedInformerFactory) {
+	factory := k8sinformers.NewSharedInformerFactory(nil, 0)
+	indexer := factory.Core().V1().ConfigMaps().Informer().GetIndexer()
+	for _, item := range configMaps {
+		indexer.Add(item)
+	}
+	return MemberCluster("test", factory), factory
+}
+
+func itemNames(items []interface{}) []string {
+	names := make([]string, 0)
+	for _, item := range items {
+		names = append(names, item.(*v1.ConfigMap).Name)
+	}
+	return names
+}
+
+func TestListResourcesWithContinue(t *testing.T) {
+	c, factory := newTestCluster(newConfigMap("default", "a", "1"), newConfigMap("default", "b", "2"), newConfigMap("default", "c", "3"))
+	conditions := &params.Conditions{}
+
+	first, err := c.ListResourcesWithContinue("default", ConfigMaps, conditions, Name, false, 2, "", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, itemNames(first.Items))
+	assert.Equal(t, 3, first.TotalCount)
+	assert.NotEmpty(t, first.Continue)
+
+	next, err := c.ListResourcesWithContinue("default", ConfigMaps, conditions, Name, false, 0, first.Continue, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, itemNames(next.Items))
+	assert.Empty(t, next.Continue)
+
+	_, err = c.ListResourcesWithContinue("default", ConfigMaps, conditions, Name, false, 0, first.Continue, "other")
+	assert.Equal(t, ErrInvalidContinueToken, err)
+
+	_, err = c.ListResourcesWithContinue("kube-system", ConfigMaps, conditions, Name, false, 0, first.Continue, "admin")
+	assert.Equal(t, ErrInvalidContinueToken, err)
+
+	_, err = c.ListResourcesWithContinue("default", ConfigMaps, conditions, Name, false, 0, "invalid", "admin")
+	assert.Equal(t, ErrInvalidContinueToken, err)
+
+	// the snapshot is kept by another replica
+	token, err := decodeContinueToken(first.Continue)
+	assert.NoError(t, err)
+	snapshots.Lock()
+	delete(snapshots.items, token.Snapshot)
+	snapshots.Unlock()
+
+	factory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(newConfigMap("default", "aa", "4"))
+
+	next, err = c.ListResourcesWithContinue("default", ConfigMaps, conditions, Name, false, 0, first.Continue, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, itemNames(next.Items))
+
+	// the position is lost if the last item of the page is deleted
+	factory.Core().V1().ConfigMaps().Informer().GetIndexer().Delete(newConfigMap("default", "b", "2"))
+
+	_, err = c.ListResourcesWithContinue("default", ConfigMaps, conditions, Name, false, 0, first.Continue, "admin")
+	assert.Equal(t, ErrContinueTokenExpired, err)
+}
+
+func TestIndexOfKey(t *testing.T) {
+	items := []interface{}{newConfigMap("default", "a", "1"), newConfigMap("kube-system", "a", "2")}
+
+	assert.Equal(t, 1, indexOfKey(items, "kube-system/a"))
+	assert.Equal(t, -1, indexOfKey(items, "default/b"))
+	assert.Equal(t, -1, indexOfKey(items, ""))
+}
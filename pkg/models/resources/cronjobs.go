@@ -18,8 +18,8 @@
 package resources
 
 import (
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
-	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"sort"
@@ -33,8 +33,8 @@ import (
 type cronJobSearcher struct {
 }
 
-func (*cronJobSearcher) get(namespace, name string) (interface{}, error) {
-	return informers.SharedInformerFactory().Batch().V1beta1().CronJobs().Lister().CronJobs(namespace).Get(name)
+func (*cronJobSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
+	return factory.Batch().V1beta1().CronJobs().Lister().CronJobs(namespace).Get(name)
 }
 
 func cronJobStatus(item *v1beta1.CronJob) string {
@@ -120,8 +120,8 @@ func (*cronJobSearcher) compare(a, b *v1beta1.CronJob, orderBy string) bool {
 	}
 }
 
-func (s *cronJobSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
-	cronJobs, err := informers.SharedInformerFactory().Batch().V1beta1().CronJobs().Lister().CronJobs(namespace).List(labels.Everything())
+func (s *cronJobSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+	cronJobs, err := factory.Batch().V1beta1().CronJobs().Lister().CronJobs(namespace).List(labels.Everything())
 
 	if err != nil {
 		return nil, err
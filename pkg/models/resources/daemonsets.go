@@ -18,8 +18,8 @@
 package resources
 
 import (
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
-	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"sort"
@@ -32,8 +32,8 @@ import (
 type daemonSetSearcher struct {
 }
 
-func (*daemonSetSearcher) get(namespace, name string) (interface{}, error) {
-	return informers.SharedInformerFactory().Apps().V1().DaemonSets().Lister().DaemonSets(namespace).Get(name)
+func (*daemonSetSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
+	return factory.Apps().V1().DaemonSets().Lister().DaemonSets(namespace).Get(name)
 }
 
 func daemonSetStatus(item *v1.DaemonSet) string {
@@ -114,8 +114,8 @@ func (*daemonSetSearcher) compare(a, b *v1.DaemonSet, orderBy string) bool {
 	}
 }
 
-func (s *daemonSetSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
-	daemonSets, err := informers.SharedInformerFactory().Apps().V1().DaemonSets().Lister().DaemonSets(namespace).List(labels.Everything())
+func (s *daemonSetSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+	daemonSets, err := factory.Apps().V1().DaemonSets().Lister().DaemonSets(namespace).List(labels.Everything())
 
 	if err != nil {
 		return nil, err
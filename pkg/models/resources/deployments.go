@@ -18,8 +18,8 @@
 package resources
 
 import (
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
-	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"sort"
@@ -33,8 +33,8 @@ import (
 type deploymentSearcher struct {
 }
 
-func (*deploymentSearcher) get(namespace, name string) (interface{}, error) {
-	return informers.SharedInformerFactory().Apps().V1().Deployments().Lister().Deployments(namespace).Get(name)
+func (*deploymentSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
+	return factory.Apps().V1().Deployments().Lister().Deployments(namespace).Get(name)
 }
 
 func deploymentStatus(item *v1.Deployment) string {
@@ -118,8 +118,8 @@ func (*deploymentSearcher) compare(a, b *v1.Deployment, orderBy string) bool {
 	}
 }
 
-func (s *deploymentSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
-	deployments, err := informers.SharedInformerFactory().Apps().V1().Deployments().Lister().Deployments(namespace).List(labels.Everything())
+func (s *deploymentSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+	deployments, err := factory.Apps().V1().Deployments().Lister().Deployments(namespace).List(labels.Everything())
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,347 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	k8sinformers "k8s.io/client-go/informers"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/cache"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/informers"
+	"kubesphere.io/kubesphere/pkg/params"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+const (
+	dynamicResourceDiscoveryPeriod = time.Minute
+	dynamicResourceSyncPeriod      = time.Second
+)
+
+var dynamicResources = struct {
+	sync.RWMutex
+	// searchers are registered with the qualified name like virtualservices.networking.istio.io,
+	// and the plural name if it is not used by other resources
+	searchers map[string]*dynamicSearcher
+	// pending are the searchers of the resources whose informers are not synced yet
+	pending map[string]*dynamicSearcher
+}{searchers: make(map[string]*dynamicSearcher), pending: make(map[string]*dynamicSearcher)}
+
+// informers of the dynamic resources, replaced in tests
+var (
+	dynamicInformerFor = func(gvr schema.GroupVersionResource) cache.SharedIndexInformer {
+		return informers.DynamicSharedInformerFactory().ForResource(gvr)
+	}
+	stopDynamicInformer = func(gvr schema.GroupVersionResource) {
+		informers.DynamicSharedInformerFactory().Remove(gvr)
+	}
+)
+
+// dynamicSearcher searches unstructured objects of any resources with dynamic informers
+type dynamicSearcher struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+	informer   cache.SharedIndexInformer
+}
+
+func (s *dynamicSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
+	key := name
+	if s.namespaced {
+		key = namespace + "/" + name
+	}
+
+	obj, exists, err := s.informer.GetIndexer().GetByKey(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, errors.NewNotFound(s.gvr.GroupResource(), name)
+	}
+
+	return obj, nil
+}
+
+// exactly Match
+func (*dynamicSearcher) match(match map[string]string, item *unstructured.Unstructured) bool {
+	for k, v := range match {
+		switch k {
+		case Name:
+			names := strings.Split(v, "|")
+			if !sliceutil.HasString(names, item.GetName()) {
+				return false
+			}
+		case Keyword:
+			if !strings.Contains(item.GetName(), v) && !searchFuzzy(item.GetLabels(), "", v) && !searchFuzzy(item.GetAnnotations(), "", v) {
+				return false
+			}
+		default:
+			if item.GetLabels()[k] != v {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (*dynamicSearcher) fuzzy(fuzzy map[string]string, item *unstructured.Unstructured) bool {
+	for k, v := range fuzzy {
+		switch k {
+		case Name:
+			if !strings.Contains(item.GetName(), v) && !strings.Contains(item.GetAnnotations()[constants.DisplayNameAnnotationKey], v) {
+				return false
+			}
+		case Label:
+			if !searchFuzzy(item.GetLabels(), "", v) {
+				return false
+			}
+		case annotation:
+			if !searchFuzzy(item.GetAnnotations(), "", v) {
+				return false
+			}
+		case app:
+			if !strings.Contains(item.GetLabels()[chart], v) && !strings.Contains(item.GetLabels()[release], v) {
+				return false
+			}
+		default:
+			if !searchFuzzy(item.GetLabels(), k, v) && !searchFuzzy(item.GetAnnotations(), k, v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (*dynamicSearcher) compare(a, b *unstructured.Unstructured, orderBy string) bool {
+	switch orderBy {
+	case CreateTime:
+		return a.GetCreationTimestamp().Time.Before(b.GetCreationTimestamp().Time)
+	case Name:
+		fallthrough
+	default:
+		return strings.Compare(a.GetName(), b.GetName()) <= 0
+	}
+}
+
+func (s *dynamicSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+	var objects []interface{}
+	var err error
+
+	if namespace != "" && s.namespaced {
+		objects, err = s.informer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		objects = s.informer.GetIndexer().List()
+	}
+
+	result := make([]*unstructured.Unstructured, 0)
+
+	for _, obj := range objects {
+		if item, ok := obj.(*unstructured.Unstructured); ok && s.match(conditions.Match, item) && s.fuzzy(conditions.Fuzzy, item) {
+			result = append(result, item)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if reverse {
+			tmp := i
+			i = j
+			j = tmp
+		}
+		return s.compare(result[i], result[j], orderBy)
+	})
+
+	r := make([]interface{}, 0)
+	for _, i := range result {
+		r = append(r, i)
+	}
+	return r, nil
+}
+
+func getDynamicSearcher(resource string) (*dynamicSearcher, bool) {
+	dynamicResources.RLock()
+	defer dynamicResources.RUnlock()
+	searcher, ok := dynamicResources.searchers[resource]
+	return searcher, ok
+}
+
+// allowed checks whether the resource is in the allow list, entries of the list are
+// qualified resource names like virtualservices.networking.istio.io, or wildcards
+// like *.servicemesh.kubesphere.io or *
+func allowed(allowList []string, gvr schema.GroupVersionResource) bool {
+	for _, item := range allowList {
+		if item == "*" || item == gvr.GroupResource().String() || item == "*."+gvr.Group {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverDynamicResources registers searchers for the allowed resources served by the api server,
+// searchers of the resources removed from the api server or the allow list are unregistered.
+func discoverDynamicResources(allowList []string, stopCh <-chan struct{}) {
+	resourceLists, err := k8s.Client().Discovery().ServerPreferredResources()
+
+	if err != nil {
+		// resources of the available groups are still returned
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			glog.Errorln("discover resources", err)
+			return
+		}
+		glog.Warningln("discover resources", err)
+	}
+
+	updateDynamicResources(allowedResources(resourceLists, allowList), stopCh)
+}
+
+// allowedResources returns the resources in the allow list which can be listed and watched, custom resources
+// are served only if they are allowed as well
+func allowedResources(resourceLists []*metav1.APIResourceList, allowList []string) map[schema.GroupVersionResource]bool {
+	result := make(map[schema.GroupVersionResource]bool)
+
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+
+		if err != nil {
+			continue
+		}
+
+		for _, resource := range resourceList.APIResources {
+			gvr := gv.WithResource(resource.Name)
+
+			// subresources and resources can't be watched are skipped
+			if strings.Contains(resource.Name, "/") || !allowed(allowList, gvr) ||
+				!sliceutil.HasString(resource.Verbs, "list") || !sliceutil.HasString(resource.Verbs, "watch") {
+				continue
+			}
+
+			result[gvr] = resource.Namespaced
+		}
+	}
+
+	return result
+}
+
+// updateDynamicResources unregisters the searchers of the resources not discovered and starts the informers
+// of the discovered resources, the searchers are registered once the informers are synced
+func updateDynamicResources(discovered map[schema.GroupVersionResource]bool, stopCh <-chan struct{}) {
+	dynamicResources.Lock()
+	defer dynamicResources.Unlock()
+
+	for name, searcher := range dynamicResources.searchers {
+		if namespaced, ok := discovered[searcher.gvr]; !ok || namespaced != searcher.namespaced {
+			delete(dynamicResources.searchers, name)
+			if name == searcher.gvr.GroupResource().String() {
+				glog.Infoln("unregister dynamic resource", name)
+				removeDynamicInformer(searcher)
+			}
+		}
+	}
+
+	for name, searcher := range dynamicResources.pending {
+		if namespaced, ok := discovered[searcher.gvr]; !ok || namespaced != searcher.namespaced {
+			delete(dynamicResources.pending, name)
+			removeDynamicInformer(searcher)
+		}
+	}
+
+	for gvr, namespaced := range discovered {
+		qualifiedName := gvr.GroupResource().String()
+
+		if _, ok := dynamicResources.searchers[qualifiedName]; ok {
+			continue
+		}
+
+		if _, ok := dynamicResources.pending[qualifiedName]; ok {
+			continue
+		}
+
+		searcher := &dynamicSearcher{gvr: gvr, namespaced: namespaced, informer: dynamicInformerFor(gvr)}
+		dynamicResources.pending[qualifiedName] = searcher
+
+		go waitForDynamicResource(qualifiedName, searcher, stopCh)
+	}
+}
+
+// waitForDynamicResource registers the searcher once its informer is synced, unless it's removed before
+func waitForDynamicResource(qualifiedName string, searcher *dynamicSearcher, stopCh <-chan struct{}) {
+	isPending := func() bool {
+		dynamicResources.RLock()
+		defer dynamicResources.RUnlock()
+		return dynamicResources.pending[qualifiedName] == searcher
+	}
+
+	err := wait.PollUntil(dynamicResourceSyncPeriod, func() (bool, error) {
+		return !isPending() || searcher.informer.HasSynced(), nil
+	}, stopCh)
+
+	if err != nil {
+		return
+	}
+
+	dynamicResources.Lock()
+	defer dynamicResources.Unlock()
+
+	if dynamicResources.pending[qualifiedName] != searcher {
+		return
+	}
+
+	delete(dynamicResources.pending, qualifiedName)
+
+	glog.Infoln("register dynamic resource", qualifiedName)
+
+	dynamicResources.searchers[qualifiedName] = searcher
+
+	// plural names of typed resources and resources in other groups are not overridden
+	if _, ok := resources[searcher.gvr.Resource]; !ok {
+		if existing, ok := dynamicResources.searchers[searcher.gvr.Resource]; !ok || existing.gvr.GroupResource() == searcher.gvr.GroupResource() {
+			dynamicResources.searchers[searcher.gvr.Resource] = searcher
+		}
+	}
+}
+
+// removeDynamicInformer stops the informer of the searcher and closes the watches of the resource
+func removeDynamicInformer(searcher *dynamicSearcher) {
+	stopDynamicInformer(searcher.gvr)
+	removeBroadcaster(searcher.informer)
+}
+
+// StartDynamicResourceDiscovery registers searchers of the allowed resources periodically
+func StartDynamicResourceDiscovery(allowList []string, stopCh <-chan struct{}) {
+	if len(allowList) == 0 {
+		return
+	}
+
+	go wait.Until(func() {
+		discoverDynamicResources(allowList, stopCh)
+	}, dynamicResourceDiscoveryPeriod, stopCh)
+}
@@ -0,0 +1,229 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+var virtualServices = schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1alpha3", Resource: "virtualservices"}
+
+// fakeInformer is an informer not started, it's synced once markSynced is called
+type fakeInformer struct {
+	cache.SharedIndexInformer
+	lock   sync.Mutex
+	synced bool
+}
+
+func newFakeInformer(objects ...*unstructured.Unstructured) *fakeInformer {
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &unstructured.Unstructured{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objects {
+		informer.GetIndexer().Add(obj)
+	}
+	return &fakeInformer{SharedIndexInformer: informer}
+}
+
+func (i *fakeInformer) HasSynced() bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.synced
+}
+
+func (i *fakeInformer) markSynced() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.synced = true
+}
+
+func newVirtualService(namespace, name string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("networking.istio.io/v1alpha3")
+	obj.SetKind("VirtualService")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func unstructuredNames(items []interface{}) []string {
+	names := make([]string, 0)
+	for _, item := range items {
+		names = append(names, item.(*unstructured.Unstructured).GetName())
+	}
+	return names
+}
+
+func TestDynamicSearcher(t *testing.T) {
+	searcher := &dynamicSearcher{gvr: virtualServices, namespaced: true, informer: newFakeInformer(
+		newVirtualService("default", "reviews", map[string]string{"app": "reviews"}),
+		newVirtualService("default", "ratings", map[string]string{"app": "ratings"}),
+		newVirtualService("istio-system", "details", nil),
+	)}
+
+	items, err := searcher.search(nil, "default", &params.Conditions{}, Name, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ratings", "reviews"}, unstructuredNames(items))
+
+	items, err = searcher.search(nil, "", &params.Conditions{}, Name, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reviews", "ratings", "details"}, unstructuredNames(items))
+
+	items, err = searcher.search(nil, "", &params.Conditions{Match: map[string]string{"app": "reviews"}}, Name, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reviews"}, unstructuredNames(items))
+
+	items, err = searcher.search(nil, "", &params.Conditions{Fuzzy: map[string]string{Name: "tai"}}, Name, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"details"}, unstructuredNames(items))
+
+	obj, err := searcher.get(nil, "default", "reviews")
+	assert.NoError(t, err)
+	assert.Equal(t, "reviews", obj.(*unstructured.Unstructured).GetName())
+
+	_, err = searcher.get(nil, "istio-system", "reviews")
+	assert.Error(t, err)
+}
+
+func TestAllowed(t *testing.T) {
+	assert.True(t, allowed([]string{"*"}, virtualServices))
+	assert.True(t, allowed([]string{"virtualservices.networking.istio.io"}, virtualServices))
+	assert.True(t, allowed([]string{"*.networking.istio.io"}, virtualServices))
+	assert.False(t, allowed([]string{"destinationrules.networking.istio.io", "*.istio.io"}, virtualServices))
+	assert.False(t, allowed(nil, virtualServices))
+}
+
+func TestAllowedResources(t *testing.T) {
+	resourceLists := []*metav1.APIResourceList{
+		{
+			GroupVersion: "networking.istio.io/v1alpha3",
+			APIResources: []metav1.APIResource{
+				{Name: "virtualservices", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+				{Name: "virtualservices/status", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+				{Name: "destinationrules", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+			},
+		},
+		{
+			// custom resources outside the allow list aren't served
+			GroupVersion: "servicemesh.kubesphere.io/v1alpha2",
+			APIResources: []metav1.APIResource{
+				{Name: "strategies", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+			},
+		},
+		{
+			GroupVersion: "metrics.k8s.io/v1beta1",
+			APIResources: []metav1.APIResource{
+				{Name: "pods", Namespaced: true, Verbs: []string{"get", "list"}},
+			},
+		},
+	}
+
+	assert.Equal(t, map[schema.GroupVersionResource]bool{virtualServices: true},
+		allowedResources(resourceLists, []string{"virtualservices.networking.istio.io", "*.metrics.k8s.io"}))
+	assert.Empty(t, allowedResources(resourceLists, nil))
+}
+
+func waitUntil(t *testing.T, condition func() bool) {
+	for start := time.Now(); !condition(); time.Sleep(100 * time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("timed out waiting for the condition")
+		}
+	}
+}
+
+func TestUpdateDynamicResources(t *testing.T) {
+	informer := newFakeInformer(newVirtualService("default", "reviews", nil))
+	var stopped []schema.GroupVersionResource
+
+	informerFor, stopInformer := dynamicInformerFor, stopDynamicInformer
+	dynamicInformerFor = func(gvr schema.GroupVersionResource) cache.SharedIndexInformer {
+		if gvr == virtualServices {
+			return informer
+		}
+		return newFakeInformer()
+	}
+	stopDynamicInformer = func(gvr schema.GroupVersionResource) {
+		stopped = append(stopped, gvr)
+	}
+	defer func() {
+		dynamicInformerFor, stopDynamicInformer = informerFor, stopInformer
+		dynamicResources.Lock()
+		dynamicResources.searchers = make(map[string]*dynamicSearcher)
+		dynamicResources.pending = make(map[string]*dynamicSearcher)
+		dynamicResources.Unlock()
+	}()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	strategies := schema.GroupVersionResource{Group: "servicemesh.kubesphere.io", Version: "v1alpha2", Resource: "strategies"}
+
+	updateDynamicResources(map[schema.GroupVersionResource]bool{virtualServices: true, strategies: true}, stopCh)
+
+	// searchers are registered once the informers are synced
+	_, ok := getDynamicSearcher("virtualservices.networking.istio.io")
+	assert.False(t, ok)
+
+	informer.markSynced()
+
+	waitUntil(t, func() bool {
+		_, ok := getDynamicSearcher("virtualservices.networking.istio.io")
+		return ok
+	})
+
+	_, ok = getDynamicSearcher("virtualservices")
+	assert.True(t, ok)
+	_, ok = getDynamicSearcher("strategies")
+	assert.False(t, ok)
+
+	c := &ClusterResources{}
+	w, err := c.WatchResources("default", "virtualservices", nil, "")
+	assert.NoError(t, err)
+	defer w.Stop()
+
+	event := nextEvent(t, w)
+	assert.Equal(t, "reviews", event.Object.(*unstructured.Unstructured).GetName())
+
+	// the resources are removed from the api server
+	updateDynamicResources(map[schema.GroupVersionResource]bool{}, stopCh)
+
+	_, ok = getDynamicSearcher("virtualservices")
+	assert.False(t, ok)
+	assert.ElementsMatch(t, []schema.GroupVersionResource{virtualServices, strategies}, stopped)
+
+	// watches of the removed resources are closed
+	select {
+	case _, ok := <-w.ResultChan():
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch is not closed")
+	}
+
+	broadcasters.Lock()
+	_, ok = broadcasters.items[informer]
+	broadcasters.Unlock()
+	assert.False(t, ok)
+}
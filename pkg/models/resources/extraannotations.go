@@ -22,28 +22,28 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"kubesphere.io/kubesphere/pkg/informers"
+	k8sinformers "k8s.io/client-go/informers"
 	"strconv"
 )
 
 type extraAnnotationInjector struct {
 }
 
-func (i extraAnnotationInjector) addExtraAnnotations(item interface{}) interface{} {
+func (i extraAnnotationInjector) addExtraAnnotations(factory k8sinformers.SharedInformerFactory, item interface{}) interface{} {
 
 	switch item.(type) {
 	case *corev1.PersistentVolumeClaim:
-		return i.injectPersistentVolumeClaim(item.(*corev1.PersistentVolumeClaim))
+		return i.injectPersistentVolumeClaim(factory, item.(*corev1.PersistentVolumeClaim))
 	case *storagev1.StorageClass:
-		return i.injectStorageClass(item.(*storagev1.StorageClass))
+		return i.injectStorageClass(factory, item.(*storagev1.StorageClass))
 	}
 
 	return item
 }
 
-func (i extraAnnotationInjector) injectStorageClass(item *storagev1.StorageClass) *storagev1.StorageClass {
+func (i extraAnnotationInjector) injectStorageClass(factory k8sinformers.SharedInformerFactory, item *storagev1.StorageClass) *storagev1.StorageClass {
 
-	count, err := countPvcByStorageClass(item.Name)
+	count, err := countPvcByStorageClass(factory, item.Name)
 
 	if err != nil {
 		glog.Errorf("inject annotation failed %+v", err)
@@ -61,8 +61,8 @@ func (i extraAnnotationInjector) injectStorageClass(item *storagev1.StorageClass
 	return item
 }
 
-func (i extraAnnotationInjector) injectPersistentVolumeClaim(item *corev1.PersistentVolumeClaim) *corev1.PersistentVolumeClaim {
-	podLister := informers.SharedInformerFactory().Core().V1().Pods().Lister()
+func (i extraAnnotationInjector) injectPersistentVolumeClaim(factory k8sinformers.SharedInformerFactory, item *corev1.PersistentVolumeClaim) *corev1.PersistentVolumeClaim {
+	podLister := factory.Core().V1().Pods().Lister()
 	pods, err := podLister.Pods(item.Namespace).List(labels.Everything())
 	if err != nil {
 		glog.Errorf("inject annotation failed %+v", err)
@@ -97,8 +97,8 @@ func isPvcInUse(pods []*corev1.Pod, pvcName string) bool {
 	return false
 }
 
-func countPvcByStorageClass(scName string) (int, error) {
-	persistentVolumeClaimLister := informers.SharedInformerFactory().Core().V1().PersistentVolumeClaims().Lister()
+func countPvcByStorageClass(factory k8sinformers.SharedInformerFactory, scName string) (int, error) {
+	persistentVolumeClaimLister := factory.Core().V1().PersistentVolumeClaims().Lister()
 	all, err := persistentVolumeClaimLister.List(labels.Everything())
 
 	if err != nil {
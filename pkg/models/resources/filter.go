@@ -0,0 +1,152 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"strings"
+
+	"github.com/golang/glog"
+	s2iv1alpha1 "github.com/kubesphere/s2ioperator/pkg/apis/devops/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+// fieldAliases maps the fields used in conditions to the paths of the objects
+var fieldAliases = map[string]string{
+	Name:          "metadata.name",
+	"namespace":   "metadata.namespace",
+	CreateTime:    "metadata.creationTimestamp",
+	Label:         "metadata.labels",
+	"labels":      "metadata.labels",
+	annotation:    "metadata.annotations",
+	"annotations": "metadata.annotations",
+}
+
+// resourceStatus returns the status computed by the searchers, e.g. running or stopped
+func resourceStatus(item interface{}) (string, bool) {
+	switch item := item.(type) {
+	case *appsv1.Deployment:
+		return deploymentStatus(item), true
+	case *appsv1.StatefulSet:
+		return statefulSetStatus(item), true
+	case *appsv1.DaemonSet:
+		return daemonSetStatus(item), true
+	case *batchv1.Job:
+		return jobStatus(item), true
+	case *v1beta1.CronJob:
+		return cronJobStatus(item), true
+	case *corev1.PersistentVolumeClaim:
+		return pvcStatus(item), true
+	case *s2iv1alpha1.S2iRun:
+		return string(item.Status.RunState), true
+	default:
+		return "", false
+	}
+}
+
+func filterResources(items []interface{}, filter params.Expression) []interface{} {
+	result := make([]interface{}, 0)
+
+	for _, item := range items {
+		if matchesFilter(item, filter) {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+func matchesFilter(item interface{}, filter params.Expression) bool {
+	return (&filterable{item: item}).matches(filter)
+}
+
+// filterable is an object matched against filters, it's converted to unstructured content at most once
+// so that the conversion is shared by the filters of all of the watchers of a change. It is not safe for
+// concurrent use.
+type filterable struct {
+	item      interface{}
+	converted bool
+	content   map[string]interface{}
+}
+
+// newFilterable returns nil if item is nil
+func newFilterable(item interface{}) *filterable {
+	if item == nil {
+		return nil
+	}
+	return &filterable{item: item}
+}
+
+func (f *filterable) unstructuredContent() map[string]interface{} {
+	if f.converted {
+		return f.content
+	}
+
+	f.converted = true
+
+	if unstructured, ok := f.item.(runtime.Unstructured); ok {
+		f.content = unstructured.UnstructuredContent()
+	} else {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(f.item)
+
+		if err != nil {
+			glog.Errorln("convert resource", err)
+			return nil
+		}
+
+		f.content = content
+	}
+
+	return f.content
+}
+
+func (f *filterable) matches(filter params.Expression) bool {
+	if filter == nil {
+		return true
+	}
+
+	obj := f.unstructuredContent()
+
+	if obj == nil {
+		return false
+	}
+
+	resolve := func(field string) (interface{}, bool) {
+		if field == Status {
+			if status, ok := resourceStatus(f.item); ok {
+				return status, true
+			}
+		}
+
+		path := params.SplitFieldPath(field)
+
+		if len(path) > 0 {
+			if alias, ok := fieldAliases[path[0]]; ok {
+				path = append(strings.Split(alias, "."), path[1:]...)
+			}
+		}
+
+		return params.LookupField(obj, path)
+	}
+
+	return filter.Evaluate(resolve)
+}
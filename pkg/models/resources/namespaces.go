@@ -18,8 +18,8 @@
 package resources
 
 import (
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
-	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"sort"
@@ -32,8 +32,8 @@ import (
 type namespaceSearcher struct {
 }
 
-func (*namespaceSearcher) get(namespace, name string) (interface{}, error) {
-	return informers.SharedInformerFactory().Core().V1().Namespaces().Lister().Get(name)
+func (*namespaceSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
+	return factory.Core().V1().Namespaces().Lister().Get(name)
 }
 
 // exactly Match
@@ -99,8 +99,8 @@ func (*namespaceSearcher) compare(a, b *v1.Namespace, orderBy string) bool {
 	}
 }
 
-func (s *namespaceSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
-	namespaces, err := informers.SharedInformerFactory().Core().V1().Namespaces().Lister().List(labels.Everything())
+func (s *namespaceSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+	namespaces, err := factory.Core().V1().Namespaces().Lister().List(labels.Everything())
 
 	if err != nil {
 		return nil, err
@@ -19,8 +19,8 @@ package resources
 
 import (
 	"fmt"
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
-	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"sort"
@@ -33,8 +33,8 @@ import (
 type nodeSearcher struct {
 }
 
-func (*nodeSearcher) get(namespace, name string) (interface{}, error) {
-	return informers.SharedInformerFactory().Core().V1().Nodes().Lister().Get(name)
+func (*nodeSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
+	return factory.Core().V1().Nodes().Lister().Get(name)
 }
 
 // exactly Match
@@ -105,8 +105,8 @@ func (*nodeSearcher) compare(a, b *v1.Node, orderBy string) bool {
 	}
 }
 
-func (s *nodeSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
-	nodes, err := informers.SharedInformerFactory().Core().V1().Nodes().Lister().List(labels.Everything())
+func (s *nodeSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+	nodes, err := factory.Core().V1().Nodes().Lister().List(labels.Everything())
 
 	if err != nil {
 		return nil, err
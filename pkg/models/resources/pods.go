@@ -19,8 +19,8 @@ package resources
 
 import (
 	appsv1 "k8s.io/api/apps/v1"
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
-	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"sort"
@@ -33,14 +33,14 @@ import (
 type podSearcher struct {
 }
 
-func (*podSearcher) get(namespace, name string) (interface{}, error) {
-	return informers.SharedInformerFactory().Core().V1().Pods().Lister().Pods(namespace).Get(name)
+func (*podSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
+	return factory.Core().V1().Pods().Lister().Pods(namespace).Get(name)
 }
 
-func podBelongTo(item *v1.Pod, kind string, name string) bool {
+func podBelongTo(factory k8sinformers.SharedInformerFactory, item *v1.Pod, kind string, name string) bool {
 	switch kind {
 	case "Deployment":
-		if podBelongToDeployment(item, name) {
+		if podBelongToDeployment(factory, item, name) {
 			return true
 		}
 	case "ReplicaSet":
@@ -108,8 +108,8 @@ func podBelongToStatefulSet(item *v1.Pod, statefulSetName string) bool {
 	return false
 }
 
-func podBelongToDeployment(item *v1.Pod, deploymentName string) bool {
-	replicas, err := informers.SharedInformerFactory().Apps().V1().ReplicaSets().Lister().ReplicaSets(item.Namespace).List(labels.Everything())
+func podBelongToDeployment(factory k8sinformers.SharedInformerFactory, item *v1.Pod, deploymentName string) bool {
+	replicas, err := factory.Apps().V1().ReplicaSets().Lister().ReplicaSets(item.Namespace).List(labels.Everything())
 	if err != nil {
 		return false
 	}
@@ -133,8 +133,8 @@ func podBindPVC(item *v1.Pod, pvcName string) bool {
 	return false
 }
 
-func podBelongToService(item *v1.Pod, serviceName string) bool {
-	service, err := informers.SharedInformerFactory().Core().V1().Services().Lister().Services(item.Namespace).Get(serviceName)
+func podBelongToService(factory k8sinformers.SharedInformerFactory, item *v1.Pod, serviceName string) bool {
+	service, err := factory.Core().V1().Services().Lister().Services(item.Namespace).Get(serviceName)
 	if err != nil {
 		return false
 	}
@@ -147,7 +147,7 @@ func podBelongToService(item *v1.Pod, serviceName string) bool {
 }
 
 // exactly Match
-func (*podSearcher) match(match map[string]string, item *v1.Pod) bool {
+func (*podSearcher) match(factory k8sinformers.SharedInformerFactory, match map[string]string, item *v1.Pod) bool {
 	for k, v := range match {
 		switch k {
 		case OwnerKind:
@@ -155,7 +155,7 @@ func (*podSearcher) match(match map[string]string, item *v1.Pod) bool {
 		case OwnerName:
 			kind := match[OwnerKind]
 			name := match[OwnerName]
-			if !podBelongTo(item, kind, name) {
+			if !podBelongTo(factory, item, kind, name) {
 				return false
 			}
 		case "nodeName":
@@ -167,7 +167,7 @@ func (*podSearcher) match(match map[string]string, item *v1.Pod) bool {
 				return false
 			}
 		case "serviceName":
-			if !podBelongToService(item, v) {
+			if !podBelongToService(factory, item, v) {
 				return false
 			}
 		case Name:
@@ -229,9 +229,9 @@ func (*podSearcher) compare(a, b *v1.Pod, orderBy string) bool {
 	}
 }
 
-func (s *podSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+func (s *podSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
 
-	pods, err := informers.SharedInformerFactory().Core().V1().Pods().Lister().Pods(namespace).List(labels.Everything())
+	pods, err := factory.Core().V1().Pods().Lister().Pods(namespace).List(labels.Everything())
 
 	if err != nil {
 		return nil, err
@@ -243,7 +243,7 @@ func (s *podSearcher) search(namespace string, conditions *params.Conditions, or
 		result = pods
 	} else {
 		for _, item := range pods {
-			if s.match(conditions.Match, item) && s.fuzzy(conditions.Fuzzy, item) {
+			if s.match(factory, conditions.Match, item) && s.fuzzy(conditions.Fuzzy, item) {
 				result = append(result, item)
 			}
 		}
@@ -18,14 +18,20 @@
 package resources
 
 import (
+	"errors"
 	"fmt"
 	"github.com/golang/glog"
+	k8sinformers "k8s.io/client-go/informers"
+	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/models"
 	"kubesphere.io/kubesphere/pkg/params"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"strings"
 )
 
+// ErrResourceNotFound is returned if the resource isn't searchable in the namespace or the cluster
+var ErrResourceNotFound = errors.New("resource not found")
+
 func init() {
 	resources[ConfigMaps] = &configMapSearcher{}
 	resources[CronJobs] = &cronJobSearcher{}
@@ -54,6 +60,8 @@ var (
 	injector         = extraAnnotationInjector{}
 	resources        = make(map[string]resourceSearchInterface)
 	clusterResources = []string{Nodes, Workspaces, Namespaces, ClusterRoles, StorageClasses, S2iBuilderTemplates}
+	// resources of kubesphere are only searched in the host cluster
+	hostResources = []string{Workspaces, S2iBuilders, S2iRuns, S2iBuilderTemplates}
 )
 
 const (
@@ -108,14 +116,85 @@ const (
 	S2iRuns                = "s2iruns"
 )
 
+// resourceSearchInterface searches the resources in the informers of a cluster,
+// searchers of kubesphere and dynamic resources always search the host cluster
 type resourceSearchInterface interface {
-	get(namespace, name string) (interface{}, error)
-	search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error)
+	get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error)
+	search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error)
 }
 
-func GetResource(namespace, resource, name string) (interface{}, error) {
+// ClusterResources searches the resources in the informers of a cluster, only kubernetes resources
+// are searched in member clusters
+type ClusterResources struct {
+	// cluster is empty for the host cluster
+	cluster   string
+	informers k8sinformers.SharedInformerFactory
+}
+
+// HostCluster returns the resources of the host cluster
+func HostCluster() *ClusterResources {
+	return &ClusterResources{informers: informers.SharedInformerFactory()}
+}
+
+// MemberCluster returns the resources of the member cluster in its informers
+func MemberCluster(cluster string, factory k8sinformers.SharedInformerFactory) *ClusterResources {
+	return &ClusterResources{cluster: cluster, informers: factory}
+}
+
+// RegisterMemberInformers registers the informers of the resources searched in member clusters,
+// they should be started by the factory
+func RegisterMemberInformers(factory k8sinformers.SharedInformerFactory) {
+	for resource, informerFunc := range resourceInformers {
+		if !sliceutil.HasString(hostResources, resource) {
+			informerFunc(factory)
+		}
+	}
+	// listers used by the searchers and the extra annotations
+	factory.Apps().V1().ReplicaSets().Informer()
+}
+
+// IsMemberResource returns true if the resource is searched in the informers of member clusters
+func IsMemberResource(resource string) bool {
+	_, ok := resources[resource]
+	return ok && !sliceutil.HasString(hostResources, resource)
+}
+
+// getSearcher returns the typed searcher or the dynamic searcher of the resource,
+// searchers of cluster resources are not returned if namespace is specified
+func (c *ClusterResources) getSearcher(namespace, resource string) (resourceSearchInterface, bool) {
+	if c.cluster != "" && sliceutil.HasString(hostResources, resource) {
+		return nil, false
+	}
+
 	if searcher, ok := resources[resource]; ok {
-		resource, err := searcher.get(namespace, name)
+		// none namespace resource
+		if namespace != "" && sliceutil.HasString(clusterResources, resource) {
+			return nil, false
+		}
+		return searcher, true
+	}
+
+	if c.cluster != "" {
+		return nil, false
+	}
+
+	if searcher, ok := getDynamicSearcher(resource); ok {
+		if namespace != "" && !searcher.namespaced {
+			return nil, false
+		}
+		return searcher, true
+	}
+
+	return nil, false
+}
+
+func GetResource(namespace, resource, name string) (interface{}, error) {
+	return HostCluster().GetResource(namespace, resource, name)
+}
+
+func (c *ClusterResources) GetResource(namespace, resource, name string) (interface{}, error) {
+	if searcher, ok := c.getSearcher(namespace, resource); ok {
+		resource, err := searcher.get(c.informers, namespace, name)
 		if err != nil {
 			glog.Errorln("get resource", namespace, resource, name, err)
 			return nil, err
@@ -126,21 +205,36 @@ func GetResource(namespace, resource, name string) (interface{}, error) {
 }
 
 func ListResources(namespace, resource string, conditions *params.Conditions, orderBy string, reverse bool, limit, offset int) (*models.PageableResponse, error) {
+	return HostCluster().ListResources(namespace, resource, conditions, orderBy, reverse, limit, offset)
+}
+
+func (c *ClusterResources) ListResources(namespace, resource string, conditions *params.Conditions, orderBy string, reverse bool, limit, offset int) (*models.PageableResponse, error) {
 	items := make([]interface{}, 0)
-	var err error
-	var result []interface{}
 
-	// none namespace resource
-	if namespace != "" && sliceutil.HasString(clusterResources, resource) {
-		glog.Errorln("resources not found", resource)
-		return nil, fmt.Errorf("not found")
+	result, err := c.searchResources(namespace, resource, conditions, orderBy, reverse)
+
+	if err != nil {
+		return nil, err
 	}
 
-	if searcher, ok := resources[resource]; ok {
-		result, err = searcher.search(namespace, conditions, orderBy, reverse)
+	for i, item := range result {
+		if i >= offset && (limit == -1 || len(items) < limit) {
+			items = append(items, injector.addExtraAnnotations(c.informers, item))
+		}
+	}
+
+	return &models.PageableResponse{TotalCount: len(result), Items: items}, nil
+}
+
+func (c *ClusterResources) searchResources(namespace, resource string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+	var err error
+	var result []interface{}
+
+	if searcher, ok := c.getSearcher(namespace, resource); ok {
+		result, err = searcher.search(c.informers, namespace, conditions, orderBy, reverse)
 	} else {
 		glog.Errorln("resources not found", resource)
-		return nil, fmt.Errorf("not found")
+		return nil, ErrResourceNotFound
 	}
 
 	if err != nil {
@@ -148,13 +242,11 @@ func ListResources(namespace, resource string, conditions *params.Conditions, or
 		return nil, err
 	}
 
-	for i, item := range result {
-		if i >= offset && (limit == -1 || len(items) < limit) {
-			items = append(items, injector.addExtraAnnotations(item))
-		}
+	if conditions.Filter != nil {
+		result = filterResources(result, conditions.Filter)
 	}
 
-	return &models.PageableResponse{TotalCount: len(result), Items: items}, nil
+	return result, nil
 }
 
 func searchFuzzy(m map[string]string, key, value string) bool {
@@ -21,6 +21,7 @@ package resources
 import (
 	"github.com/kubesphere/s2ioperator/pkg/apis/devops/v1alpha1"
 	"k8s.io/apimachinery/pkg/labels"
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
 	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
@@ -32,7 +33,7 @@ import (
 type s2iBuilderSearcher struct {
 }
 
-func (*s2iBuilderSearcher) get(namespace, name string) (interface{}, error) {
+func (*s2iBuilderSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
 	return informers.S2iSharedInformerFactory().Devops().V1alpha1().S2iBuilders().Lister().S2iBuilders(namespace).Get(name)
 }
 
@@ -99,7 +100,7 @@ func (*s2iBuilderSearcher) compare(a, b *v1alpha1.S2iBuilder, orderBy string) bo
 	}
 }
 
-func (s *s2iBuilderSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+func (s *s2iBuilderSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
 	s2iBuilders, err := informers.S2iSharedInformerFactory().Devops().V1alpha1().S2iBuilders().Lister().S2iBuilders(namespace).List(labels.Everything())
 
 	if err != nil {
@@ -19,6 +19,7 @@ package resources
 
 import (
 	"github.com/kubesphere/s2ioperator/pkg/apis/devops/v1alpha1"
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
 	"kubesphere.io/kubesphere/pkg/informers"
 	"kubesphere.io/kubesphere/pkg/params"
@@ -32,7 +33,7 @@ import (
 type s2iBuilderTemplateSearcher struct {
 }
 
-func (*s2iBuilderTemplateSearcher) get(namespace, name string) (interface{}, error) {
+func (*s2iBuilderTemplateSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
 	return informers.S2iSharedInformerFactory().Devops().V1alpha1().S2iBuilderTemplates().Lister().Get(name)
 }
 
@@ -95,7 +96,7 @@ func (*s2iBuilderTemplateSearcher) compare(a, b *v1alpha1.S2iBuilderTemplate, or
 	}
 }
 
-func (s *s2iBuilderTemplateSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+func (s *s2iBuilderTemplateSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
 	builderTemplates, err := informers.S2iSharedInformerFactory().Devops().V1alpha1().S2iBuilderTemplates().Lister().List(labels.Everything())
 
 	if err != nil {
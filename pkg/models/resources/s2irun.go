@@ -19,6 +19,7 @@
 package resources
 
 import (
+	k8sinformers "k8s.io/client-go/informers"
 	"kubesphere.io/kubesphere/pkg/constants"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 	"sort"
@@ -35,7 +36,7 @@ import (
 type s2iRunSearcher struct {
 }
 
-func (*s2iRunSearcher) get(namespace, name string) (interface{}, error) {
+func (*s2iRunSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
 	return informers.S2iSharedInformerFactory().Devops().V1alpha1().S2iRuns().Lister().S2iRuns(namespace).Get(name)
 }
 
@@ -106,7 +107,7 @@ func (*s2iRunSearcher) compare(a, b *v1alpha1.S2iRun, orderBy string) bool {
 	}
 }
 
-func (s *s2iRunSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+func (s *s2iRunSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
 	s2iRuns, err := informers.S2iSharedInformerFactory().Devops().V1alpha1().S2iRuns().Lister().S2iRuns(namespace).List(labels.Everything())
 
 	if err != nil {
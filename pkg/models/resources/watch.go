@@ -0,0 +1,544 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	k8sinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"kubesphere.io/kubesphere/pkg/informers"
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+const (
+	// watchers are dropped if they can't keep up with the events
+	watchBufferSize = 1024
+	// number of recent events of each informer kept for watches started from a resource version
+	historySize = 1024
+)
+
+// ErrResourceVersionTooOld is returned if the changes after the resource version are no longer kept,
+// the list should be restarted
+var ErrResourceVersionTooOld = errors.New("resource version is too old, restart the list")
+
+// resourceInformers returns the informers of the resources in the informer factory of a cluster,
+// informers of kubesphere resources are always the ones of the host cluster
+var resourceInformers = map[string]func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer{
+	ConfigMaps: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Core().V1().ConfigMaps().Informer()
+	},
+	CronJobs: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Batch().V1beta1().CronJobs().Informer()
+	},
+	DaemonSets: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Apps().V1().DaemonSets().Informer()
+	},
+	Deployments: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Apps().V1().Deployments().Informer()
+	},
+	Ingresses: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Extensions().V1beta1().Ingresses().Informer()
+	},
+	Jobs: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Batch().V1().Jobs().Informer()
+	},
+	PersistentVolumeClaims: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Core().V1().PersistentVolumeClaims().Informer()
+	},
+	Secrets: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Core().V1().Secrets().Informer()
+	},
+	Services: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Core().V1().Services().Informer()
+	},
+	StatefulSets: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Apps().V1().StatefulSets().Informer()
+	},
+	Pods: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Core().V1().Pods().Informer()
+	},
+	Roles: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Rbac().V1().Roles().Informer()
+	},
+	S2iBuilders: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return informers.S2iSharedInformerFactory().Devops().V1alpha1().S2iBuilders().Informer()
+	},
+	S2iRuns: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return informers.S2iSharedInformerFactory().Devops().V1alpha1().S2iRuns().Informer()
+	},
+	Nodes: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Core().V1().Nodes().Informer()
+	},
+	Namespaces: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Core().V1().Namespaces().Informer()
+	},
+	ClusterRoles: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Rbac().V1().ClusterRoles().Informer()
+	},
+	StorageClasses: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return factory.Storage().V1().StorageClasses().Informer()
+	},
+	S2iBuilderTemplates: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return informers.S2iSharedInformerFactory().Devops().V1alpha1().S2iBuilderTemplates().Informer()
+	},
+	Workspaces: func(factory k8sinformers.SharedInformerFactory) cache.SharedIndexInformer {
+		return informers.KsSharedInformerFactory().Tenant().V1alpha1().Workspaces().Informer()
+	},
+}
+
+// broadcaster dispatches the events of an informer to the watchers, event handlers
+// can't be removed from shared informers so only one handler is added for each informer.
+// Recent events are kept so that watches can start from the resource version of a listing.
+type broadcaster struct {
+	sync.Mutex
+	informer cache.SharedIndexInformer
+	watchers map[*resourceWatcher]struct{}
+	// history of the recent events ordered by resource version
+	history []historyEvent
+	// oldest is the resource version the history starts after, the resource version of the informer
+	// when the broadcaster is created or of the last event dropped from the history
+	oldest uint64
+	// latest is the resource version of the last event
+	latest uint64
+}
+
+type historyEvent struct {
+	resourceVersion uint64
+	oldObj          interface{}
+	newObj          interface{}
+}
+
+// resourceEvent is an event of the filtered list with the resource version of the change
+type resourceEvent struct {
+	resourceVersion uint64
+	event           watch.Event
+}
+
+// broadcasters are keyed by the informers, the informers of member clusters are
+// different instances from the ones of the host cluster
+var broadcasters = struct {
+	sync.Mutex
+	items map[cache.SharedIndexInformer]*broadcaster
+}{items: make(map[cache.SharedIndexInformer]*broadcaster)}
+
+func (c *ClusterResources) getBroadcaster(resource string) (*broadcaster, error) {
+	var informer cache.SharedIndexInformer
+
+	if informerFunc, ok := resourceInformers[resource]; ok {
+		informer = informerFunc(c.informers)
+	} else if searcher, ok := getDynamicSearcher(resource); ok && c.cluster == "" {
+		informer = searcher.informer
+	} else {
+		return nil, fmt.Errorf("watching %s is not supported", resource)
+	}
+
+	broadcasters.Lock()
+	defer broadcasters.Unlock()
+
+	if b, ok := broadcasters.items[informer]; ok {
+		return b, nil
+	}
+
+	b := newBroadcaster(informer)
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			b.dispatch(nil, obj, false)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			b.dispatch(oldObj, newObj, false)
+		},
+		DeleteFunc: func(obj interface{}) {
+			tombstone := false
+			if t, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = t.Obj
+				tombstone = true
+			}
+			b.dispatch(obj, nil, tombstone)
+		},
+	})
+
+	broadcasters.items[informer] = b
+
+	return b, nil
+}
+
+func newBroadcaster(informer cache.SharedIndexInformer) *broadcaster {
+	version, _ := strconv.ParseUint(informer.LastSyncResourceVersion(), 10, 64)
+	return &broadcaster{informer: informer, watchers: make(map[*resourceWatcher]struct{}), oldest: version, latest: version}
+}
+
+// removeBroadcaster closes the watches of the informer, it's called once the informer is stopped
+func removeBroadcaster(informer cache.SharedIndexInformer) {
+	broadcasters.Lock()
+	b, ok := broadcasters.items[informer]
+	delete(broadcasters.items, informer)
+	broadcasters.Unlock()
+
+	if !ok {
+		return
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	for w := range b.watchers {
+		b.removeLocked(w)
+	}
+}
+
+// listResourceVersion returns the resource version of the listing of the resource, a watch started from it
+// receives the changes after the listing. It's empty if the resource can't be watched.
+func (c *ClusterResources) listResourceVersion(resource string) string {
+	b, err := c.getBroadcaster(resource)
+
+	if err != nil {
+		return ""
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.latest == 0 {
+		return ""
+	}
+
+	return strconv.FormatUint(b.latest, 10)
+}
+
+// dispatch records the change in the history and sends it to the watchers. The objects are converted and
+// matched against the filters of the watchers outside of the lock, events are dispatched by a single goroutine
+// of the informer so they are still sent in order.
+func (b *broadcaster) dispatch(oldObj, newObj interface{}, tombstone bool) {
+	version, watchers, ok := b.record(oldObj, newObj, tombstone)
+
+	if !ok || len(watchers) == 0 {
+		return
+	}
+
+	oldItem, newItem := newFilterable(oldObj), newFilterable(newObj)
+
+	events := make(map[*resourceWatcher]watch.Event, len(watchers))
+
+	for _, w := range watchers {
+		if event, ok := w.event(oldItem, newItem); ok {
+			events[w] = event
+		}
+	}
+
+	if len(events) == 0 {
+		return
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	for w, event := range events {
+		// stopped since the watchers were listed
+		if _, ok := b.watchers[w]; !ok {
+			continue
+		}
+
+		select {
+		case w.incoming <- resourceEvent{resourceVersion: version, event: event}:
+		default:
+			glog.Warningln("resource watcher is too slow, dropped")
+			b.removeLocked(w)
+		}
+	}
+}
+
+// record adds the change to the history and returns its resource version and the watchers it's sent to,
+// changes not to be sent are ignored
+func (b *broadcaster) record(oldObj, newObj interface{}, tombstone bool) (uint64, []*resourceWatcher, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	var version uint64
+
+	if newObj != nil {
+		version = resourceVersion(newObj)
+		// objects existing before the handler is added, or resyncs without changes
+		if (oldObj == nil && version <= b.oldest) || (oldObj != nil && version == resourceVersion(oldObj)) {
+			return 0, nil, false
+		}
+	} else {
+		// deleted objects carry the resource version of the deletion, unless the deletion is
+		// found by relisting, which is at the resource version of the informer
+		version = resourceVersion(oldObj)
+		if tombstone {
+			if synced, _ := strconv.ParseUint(b.informer.LastSyncResourceVersion(), 10, 64); synced > version {
+				version = synced
+			}
+		}
+	}
+
+	// the resource versions of events are kept in order
+	if version < b.latest {
+		version = b.latest
+	}
+
+	b.latest = version
+
+	if b.oldest == 0 {
+		b.oldest = version
+	}
+
+	b.history = append(b.history, historyEvent{resourceVersion: version, oldObj: oldObj, newObj: newObj})
+
+	if len(b.history) > historySize {
+		b.oldest = b.history[0].resourceVersion
+		b.history = b.history[1:]
+	}
+
+	watchers := make([]*resourceWatcher, 0, len(b.watchers))
+
+	for w := range b.watchers {
+		watchers = append(watchers, w)
+	}
+
+	return version, watchers, true
+}
+
+// subscribe adds the watcher and returns the events after the resource version, the resource version of the
+// last event is used if resourceVersion is nil. ErrResourceVersionTooOld is returned if the events after the
+// resource version are no longer kept.
+func (b *broadcaster) subscribe(w *resourceWatcher, resourceVersion *uint64) ([]historyEvent, uint64, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if resourceVersion == nil {
+		b.watchers[w] = struct{}{}
+		return nil, b.latest, nil
+	}
+
+	from := *resourceVersion
+
+	if b.oldest == 0 || from < b.oldest {
+		return nil, 0, ErrResourceVersionTooOld
+	}
+
+	var events []historyEvent
+
+	for _, event := range b.history {
+		if event.resourceVersion > from {
+			events = append(events, event)
+		}
+	}
+
+	b.watchers[w] = struct{}{}
+
+	return events, from, nil
+}
+
+func (b *broadcaster) remove(w *resourceWatcher) {
+	b.Lock()
+	defer b.Unlock()
+	b.removeLocked(w)
+}
+
+func (b *broadcaster) removeLocked(w *resourceWatcher) {
+	if _, ok := b.watchers[w]; ok {
+		delete(b.watchers, w)
+		close(w.incoming)
+	}
+}
+
+// resourceWatcher implements watch.Interface for the filtered resources
+type resourceWatcher struct {
+	informers       k8sinformers.SharedInformerFactory
+	namespace       string
+	filter          params.Expression
+	resourceVersion uint64
+	broadcaster     *broadcaster
+	incoming        chan resourceEvent
+	result          chan watch.Event
+	stopCh          chan struct{}
+	stopOnce        sync.Once
+}
+
+func (w *resourceWatcher) matches(obj *filterable) bool {
+	if obj == nil {
+		return false
+	}
+
+	if w.namespace != "" {
+		accessor, err := meta.Accessor(obj.item)
+		if err != nil || accessor.GetNamespace() != w.namespace {
+			return false
+		}
+	}
+
+	return obj.matches(w.filter)
+}
+
+// event converts the changes of objects to the events of the filtered list, objects
+// no longer matching the filter are deleted from the list
+func (w *resourceWatcher) event(oldObj, newObj *filterable) (watch.Event, bool) {
+	oldMatched := w.matches(oldObj)
+	newMatched := w.matches(newObj)
+
+	switch {
+	case newMatched && oldMatched:
+		return watch.Event{Type: watch.Modified, Object: toRuntimeObject(injector.addExtraAnnotations(w.informers, newObj.item))}, true
+	case newMatched:
+		return watch.Event{Type: watch.Added, Object: toRuntimeObject(injector.addExtraAnnotations(w.informers, newObj.item))}, true
+	case oldMatched:
+		if newObj == nil {
+			return watch.Event{Type: watch.Deleted, Object: toRuntimeObject(oldObj.item)}, true
+		}
+		return watch.Event{Type: watch.Deleted, Object: toRuntimeObject(newObj.item)}, true
+	default:
+		return watch.Event{}, false
+	}
+}
+
+// run sends the initial resources as ADDED events and the events in history, followed by the
+// events received after the subscription
+func (w *resourceWatcher) run(initial []interface{}, history []historyEvent) {
+	defer close(w.result)
+
+	send := func(event watch.Event) bool {
+		select {
+		case w.result <- event:
+			return true
+		case <-w.stopCh:
+			return false
+		}
+	}
+
+	for _, item := range initial {
+		if !send(watch.Event{Type: watch.Added, Object: toRuntimeObject(injector.addExtraAnnotations(w.informers, item))}) {
+			return
+		}
+	}
+
+	for _, item := range history {
+		if event, ok := w.event(newFilterable(item.oldObj), newFilterable(item.newObj)); ok {
+			if !send(event) {
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case item, ok := <-w.incoming:
+			if !ok {
+				return
+			}
+			// changes before the start of the watch
+			if item.resourceVersion <= w.resourceVersion {
+				continue
+			}
+			if !send(item.event) {
+				return
+			}
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+func toRuntimeObject(item interface{}) runtime.Object {
+	obj, _ := item.(runtime.Object)
+	return obj
+}
+
+func (w *resourceWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		w.broadcaster.remove(w)
+		close(w.stopCh)
+	})
+}
+
+func (w *resourceWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// WatchResources watches the changes of resources matching the filter. The existing
+// resources are sent as ADDED events first if resourceVersion is empty, otherwise only
+// changes after the resourceVersion of a previous listing are sent. ErrResourceVersionTooOld
+// is returned if the changes after the resourceVersion are no longer kept, the list should be restarted.
+func WatchResources(namespace, resource string, filter params.Expression, resourceVersion string) (watch.Interface, error) {
+	return HostCluster().WatchResources(namespace, resource, filter, resourceVersion)
+}
+
+func (c *ClusterResources) WatchResources(namespace, resource string, filter params.Expression, resourceVersion string) (watch.Interface, error) {
+	if _, ok := c.getSearcher(namespace, resource); !ok {
+		return nil, fmt.Errorf("not found")
+	}
+
+	b, err := c.getBroadcaster(resource)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var from *uint64
+
+	if resourceVersion != "" {
+		version, err := strconv.ParseUint(resourceVersion, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resource version %s", resourceVersion)
+		}
+		from = &version
+	}
+
+	w := &resourceWatcher{
+		informers:   c.informers,
+		namespace:   namespace,
+		filter:      filter,
+		broadcaster: b,
+		incoming:    make(chan resourceEvent, watchBufferSize),
+		result:      make(chan watch.Event),
+		stopCh:      make(chan struct{}),
+	}
+
+	// subscribe before listing so that no changes are missed
+	history, version, err := b.subscribe(w, from)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w.resourceVersion = version
+
+	var initial []interface{}
+
+	if from == nil {
+		initial, err = c.searchResources(namespace, resource, &params.Conditions{Filter: filter}, CreateTime, false)
+
+		if err != nil {
+			b.remove(w)
+			return nil, err
+		}
+	}
+
+	go w.run(initial, history)
+
+	return w, nil
+}
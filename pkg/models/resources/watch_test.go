@@ -0,0 +1,132 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package resources
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+func nextEvent(t *testing.T, w watch.Interface) watch.Event {
+	select {
+	case event := <-w.ResultChan():
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the event")
+		return watch.Event{}
+	}
+}
+
+func TestWatchFromListResourceVersion(t *testing.T) {
+	c, factory := newTestCluster(newConfigMap("default", "a", "1"), newConfigMap("default", "b", "2"))
+	indexer := factory.Core().V1().ConfigMaps().Informer().GetIndexer()
+
+	b, err := c.getBroadcaster(ConfigMaps)
+	assert.NoError(t, err)
+
+	added := newConfigMap("default", "c", "3")
+	indexer.Add(added)
+	b.dispatch(nil, added, false)
+
+	list, err := c.ListResourcesWithContinue("default", ConfigMaps, &params.Conditions{}, Name, false, 10, "", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", list.ResourceVersion)
+
+	// changed between the listing and the watch
+	updated := newConfigMap("default", "a", "4")
+	b.dispatch(newConfigMap("default", "a", "1"), updated, false)
+	b.dispatch(nil, newConfigMap("kube-system", "d", "5"), false)
+
+	w, err := c.WatchResources("default", ConfigMaps, nil, list.ResourceVersion)
+	assert.NoError(t, err)
+	defer w.Stop()
+
+	event := nextEvent(t, w)
+	assert.Equal(t, watch.Modified, event.Type)
+	assert.Equal(t, "a", event.Object.(*v1.ConfigMap).Name)
+
+	b.dispatch(newConfigMap("default", "b", "2"), nil, false)
+
+	event = nextEvent(t, w)
+	assert.Equal(t, watch.Deleted, event.Type)
+	assert.Equal(t, "b", event.Object.(*v1.ConfigMap).Name)
+
+	_, err = c.WatchResources("default", ConfigMaps, nil, "2")
+	assert.Equal(t, ErrResourceVersionTooOld, err)
+
+	_, err = c.WatchResources("default", ConfigMaps, nil, "invalid")
+	assert.Error(t, err)
+}
+
+func TestBroadcasterHistory(t *testing.T) {
+	_, factory := newTestCluster()
+	b := newBroadcaster(factory.Core().V1().ConfigMaps().Informer())
+
+	for i := 1; i <= historySize+10; i++ {
+		b.dispatch(nil, newConfigMap("default", "a", strconv.Itoa(i)), false)
+	}
+
+	assert.Len(t, b.history, historySize)
+	assert.Equal(t, uint64(historySize+10), b.latest)
+	assert.Equal(t, uint64(10), b.oldest)
+
+	// resyncs and objects existing before the broadcaster are not events
+	b.dispatch(newConfigMap("default", "a", "5"), newConfigMap("default", "a", "5"), false)
+	b.dispatch(nil, newConfigMap("default", "b", "3"), false)
+	assert.Equal(t, uint64(historySize+10), b.latest)
+
+	// resource versions of events are kept in order
+	b.dispatch(newConfigMap("default", "c", "3"), nil, false)
+	assert.Equal(t, uint64(historySize+10), b.history[len(b.history)-1].resourceVersion)
+}
+
+func TestDispatchToStoppedWatchers(t *testing.T) {
+	c, _ := newTestCluster()
+
+	b, err := c.getBroadcaster(ConfigMaps)
+	assert.NoError(t, err)
+
+	var watchers []watch.Interface
+	for i := 0; i < 10; i++ {
+		w, err := c.WatchResources("default", ConfigMaps, nil, "")
+		assert.NoError(t, err)
+		watchers = append(watchers, w)
+	}
+
+	// watchers stopped while events are dispatched outside of the lock are skipped
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 100; i++ {
+			b.dispatch(nil, newConfigMap("default", "a", strconv.Itoa(i)), false)
+		}
+	}()
+
+	for _, w := range watchers {
+		w.Stop()
+	}
+
+	<-done
+	assert.Empty(t, b.watchers)
+}
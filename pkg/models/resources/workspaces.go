@@ -18,6 +18,7 @@
 package resources
 
 import (
+	k8sinformers "k8s.io/client-go/informers"
 	tenantv1alpha1 "kubesphere.io/kubesphere/pkg/apis/tenant/v1alpha1"
 	"kubesphere.io/kubesphere/pkg/constants"
 	"kubesphere.io/kubesphere/pkg/informers"
@@ -32,7 +33,7 @@ import (
 type workspaceSearcher struct {
 }
 
-func (*workspaceSearcher) get(namespace, name string) (interface{}, error) {
+func (*workspaceSearcher) get(factory k8sinformers.SharedInformerFactory, namespace, name string) (interface{}, error) {
 	return informers.KsSharedInformerFactory().Tenant().V1alpha1().Workspaces().Lister().Get(name)
 }
 
@@ -99,7 +100,7 @@ func (*workspaceSearcher) compare(a, b *tenantv1alpha1.Workspace, orderBy string
 	}
 }
 
-func (s *workspaceSearcher) search(namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
+func (s *workspaceSearcher) search(factory k8sinformers.SharedInformerFactory, namespace string, conditions *params.Conditions, orderBy string, reverse bool) ([]interface{}, error) {
 
 	workspaces, err := informers.KsSharedInformerFactory().Tenant().V1alpha1().Workspaces().Lister().List(labels.Everything())
 
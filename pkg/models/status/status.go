@@ -19,9 +19,7 @@ package status
 
 import (
 	"github.com/golang/glog"
-	"kubesphere.io/kubesphere/pkg/models"
 	"kubesphere.io/kubesphere/pkg/params"
-	"strings"
 
 	"kubesphere.io/kubesphere/pkg/models/resources"
 )
@@ -34,28 +32,28 @@ type WorkLoadStatus struct {
 
 func GetNamespacesResourceStatus(namespace string) (*WorkLoadStatus, error) {
 	res := WorkLoadStatus{Count: make(map[string]int), Namespace: namespace, Items: make(map[string]interface{})}
-	var notReadyList *models.PageableResponse
-	var err error
 	for _, resource := range []string{resources.Deployments, resources.StatefulSets, resources.DaemonSets, resources.PersistentVolumeClaims, resources.Jobs} {
-		var notReadyStatus string
+		var notReadyStatus []string
 
 		switch resource {
 		case resources.PersistentVolumeClaims:
-			notReadyStatus = strings.Join([]string{resources.StatusPending, resources.StatusLost}, "|")
+			notReadyStatus = []string{resources.StatusPending, resources.StatusLost}
 		case resources.Jobs:
-			notReadyStatus = resources.StatusFailed
+			notReadyStatus = []string{resources.StatusFailed}
 		default:
-			notReadyStatus = resources.StatusUpdating
+			notReadyStatus = []string{resources.StatusUpdating}
 		}
 
-		notReadyList, err = resources.ListResources(namespace, resource, &params.Conditions{Match: map[string]string{resources.Status: notReadyStatus}}, "", false, -1, 0)
+		aggregation, err := resources.AggregateResources(namespace, resource, &params.Conditions{}, []string{resources.FacetStatus})
 
 		if err != nil {
-			glog.Errorf("list resources failed: %+v", err)
+			glog.Errorf("aggregate resources failed: %+v", err)
 			return nil, err
 		}
 
-		res.Count[resource] = notReadyList.TotalCount
+		for _, status := range notReadyStatus {
+			res.Count[resource] += aggregation.Facets[resources.FacetStatus][status]
+		}
 	}
 
 	return &res, nil
@@ -23,7 +23,6 @@ import (
 	"github.com/gocraft/dbr"
 	"github.com/golang/glog"
 	"kubesphere.io/kubesphere/pkg/db"
-	"kubesphere.io/kubesphere/pkg/gojenkins"
 	"kubesphere.io/kubesphere/pkg/gojenkins/utils"
 	"kubesphere.io/kubesphere/pkg/models"
 	"kubesphere.io/kubesphere/pkg/models/devops"
@@ -31,14 +30,8 @@ import (
 	"kubesphere.io/kubesphere/pkg/simple/client/admin_jenkins"
 	"kubesphere.io/kubesphere/pkg/simple/client/devops_mysql"
 	"net/http"
-	"sync"
 )
 
-type DevOpsProjectRoleResponse struct {
-	ProjectRole *gojenkins.ProjectRole
-	Err         error
-}
-
 func ListDevopsProjects(workspace, username string, conditions *params.Conditions, orderBy string, reverse bool, limit int, offset int) (*models.PageableResponse, error) {
 
 	dbconn := devops_mysql.OpenDatabase()
@@ -111,48 +104,14 @@ func DeleteDevOpsProject(projectId, username string) error {
 		glog.Errorf("%+v", err)
 		return restful.NewError(http.StatusForbidden, err.Error())
 	}
-	gojenkins := admin_jenkins.Client()
-	if gojenkins == nil {
+	jenkinsClient := admin_jenkins.Client()
+	if jenkinsClient == nil {
 		err := fmt.Errorf("could not connect to jenkins")
 		glog.Error(err)
 		return restful.NewError(http.StatusServiceUnavailable, err.Error())
 	}
-	devopsdb := devops_mysql.OpenDatabase()
-	_, err = gojenkins.DeleteJob(projectId)
 
-	if err != nil && utils.GetJenkinsStatusCode(err) != http.StatusNotFound {
-		glog.Errorf("%+v", err)
-		return restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-
-	roleNames := make([]string, 0)
-	for role := range devops.JenkinsProjectPermissionMap {
-		roleNames = append(roleNames, devops.GetProjectRoleName(projectId, role))
-		roleNames = append(roleNames, devops.GetPipelineRoleName(projectId, role))
-	}
-	err = gojenkins.DeleteProjectRoles(roleNames...)
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-	_, err = devopsdb.DeleteFrom(devops.DevOpsProjectMembershipTableName).
-		Where(db.Eq(devops.DevOpsProjectMembershipProjectIdColumn, projectId)).Exec()
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-	_, err = devopsdb.Update(devops.DevOpsProjectTableName).
-		Set(devops.StatusColumn, devops.StatusDeleted).
-		Where(db.Eq(devops.DevOpsProjectIdColumn, projectId)).Exec()
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-	project := &devops.DevOpsProject{}
-	err = devopsdb.Select(devops.DevOpsProjectColumns...).
-		From(devops.DevOpsProjectTableName).
-		Where(db.Eq(devops.DevOpsProjectIdColumn, projectId)).
-		LoadOne(project)
+	err = devops.DeleteProject(jenkinsClient, devops_mysql.OpenDatabase(), projectId)
 	if err != nil {
 		glog.Errorf("%+v", err)
 		return restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
@@ -168,98 +127,13 @@ func CreateDevopsProject(username string, workspace string, req *devops.DevOpsPr
 		glog.Error(err)
 		return nil, restful.NewError(http.StatusServiceUnavailable, err.Error())
 	}
-	devopsdb := devops_mysql.OpenDatabase()
-	project := devops.NewDevOpsProject(req.Name, req.Description, username, req.Extra, workspace)
-	_, err := jenkinsClient.CreateFolder(project.ProjectId, project.Description)
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return nil, restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-
-	var addRoleCh = make(chan *DevOpsProjectRoleResponse, 8)
-	var addRoleWg sync.WaitGroup
-	for role, permission := range devops.JenkinsProjectPermissionMap {
-		addRoleWg.Add(1)
-		go func(role string, permission gojenkins.ProjectPermissionIds) {
-			_, err := jenkinsClient.AddProjectRole(devops.GetProjectRoleName(project.ProjectId, role),
-				devops.GetProjectRolePattern(project.ProjectId), permission, true)
-			addRoleCh <- &DevOpsProjectRoleResponse{nil, err}
-			addRoleWg.Done()
-		}(role, permission)
-	}
-	for role, permission := range devops.JenkinsPipelinePermissionMap {
-		addRoleWg.Add(1)
-		go func(role string, permission gojenkins.ProjectPermissionIds) {
-			_, err := jenkinsClient.AddProjectRole(devops.GetPipelineRoleName(project.ProjectId, role),
-				devops.GetPipelineRolePattern(project.ProjectId), permission, true)
-			addRoleCh <- &DevOpsProjectRoleResponse{nil, err}
-			addRoleWg.Done()
-		}(role, permission)
-	}
-	addRoleWg.Wait()
-	close(addRoleCh)
-	for addRoleResponse := range addRoleCh {
-		if addRoleResponse.Err != nil {
-			glog.Errorf("%+v", addRoleResponse.Err)
-			return nil, restful.NewError(utils.GetJenkinsStatusCode(addRoleResponse.Err), addRoleResponse.Err.Error())
-		}
-	}
 
-	globalRole, err := jenkinsClient.GetGlobalRole(devops.JenkinsAllUserRoleName)
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return nil, restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-	if globalRole == nil {
-		_, err := jenkinsClient.AddGlobalRole(devops.JenkinsAllUserRoleName, gojenkins.GlobalPermissionIds{
-			GlobalRead: true,
-		}, true)
-		if err != nil {
-			glog.Error("failed to create jenkins global role")
-			return nil, restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-		}
-	}
-	err = globalRole.AssignRole(username)
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return nil, restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-
-	projectRole, err := jenkinsClient.GetProjectRole(devops.GetProjectRoleName(project.ProjectId, devops.ProjectOwner))
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return nil, restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-	err = projectRole.AssignRole(username)
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return nil, restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-
-	pipelineRole, err := jenkinsClient.GetProjectRole(devops.GetPipelineRoleName(project.ProjectId, devops.ProjectOwner))
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return nil, restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
-	}
-	err = pipelineRole.AssignRole(username)
+	project := devops.NewDevOpsProject(req.Name, req.Description, username, req.Extra, workspace)
+	err := devops.CreateProject(jenkinsClient, devops_mysql.OpenDatabase(), project)
 	if err != nil {
 		glog.Errorf("%+v", err)
 		return nil, restful.NewError(utils.GetJenkinsStatusCode(err), err.Error())
 	}
-	_, err = devopsdb.InsertInto(devops.DevOpsProjectTableName).
-		Columns(devops.DevOpsProjectColumns...).Record(project).Exec()
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
-	}
-
-	projectMembership := devops.NewDevOpsProjectMemberShip(username, project.ProjectId, devops.ProjectOwner, username)
-	_, err = devopsdb.InsertInto(devops.DevOpsProjectMembershipTableName).
-		Columns(devops.DevOpsProjectMembershipColumns...).Record(projectMembership).Exec()
-	if err != nil {
-		glog.Errorf("%+v", err)
-		return nil, restful.NewError(http.StatusInternalServerError, err.Error())
-	}
 	return project, nil
 }
 
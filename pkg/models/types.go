@@ -27,6 +27,9 @@ import (
 type PageableResponse struct {
 	Items      []interface{} `json:"items" description:"paging data"`
 	TotalCount int           `json:"total_count" description:"total count"`
+	// Continue is set if there are more items when listing with continue tokens
+	Continue        string `json:"continue,omitempty" description:"token to retrieve the next page, empty if there are no more items"`
+	ResourceVersion string `json:"resourceVersion,omitempty" description:"resource version of the listing snapshot, can be used to start a watch"`
 }
 
 type Workspace struct {
@@ -98,7 +101,9 @@ type PodInfo struct {
 }
 
 type Token struct {
-	Token string `json:"access_token" description:"access token"`
+	Token        string `json:"access_token" description:"access token"`
+	RefreshToken string `json:"refresh_token,omitempty" description:"refresh token, used to obtain a new access token"`
+	ExpiresIn    int64  `json:"expires_in,omitempty" description:"lifetime in seconds of the access token"`
 }
 
 type ResourceQuota struct {
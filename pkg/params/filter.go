@@ -0,0 +1,546 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package params
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const FilterParam = "filter"
+
+type Operator string
+
+const (
+	OperatorEqual          Operator = "="
+	OperatorNotEqual       Operator = "!="
+	OperatorContains       Operator = "~"
+	OperatorNotContains    Operator = "!~"
+	OperatorRegex          Operator = "=~"
+	OperatorGreaterThan    Operator = ">"
+	OperatorGreaterOrEqual Operator = ">="
+	OperatorLessThan       Operator = "<"
+	OperatorLessOrEqual    Operator = "<="
+	OperatorIn             Operator = "in"
+	OperatorNotIn          Operator = "notin"
+	OperatorExists         Operator = "exists"
+)
+
+// FieldResolver returns the value of the field, ok is false if the field does not exist.
+// Lists are matched if any of the elements matches.
+type FieldResolver func(field string) (value interface{}, ok bool)
+
+// Expression is the AST of a parsed filter
+type Expression interface {
+	Evaluate(resolve FieldResolver) bool
+}
+
+type AndExpression struct {
+	Left, Right Expression
+}
+
+type OrExpression struct {
+	Left, Right Expression
+}
+
+type NotExpression struct {
+	Expression Expression
+}
+
+type Comparison struct {
+	Field    string
+	Operator Operator
+	Values   []string
+
+	// compiled pattern of regex comparisons
+	pattern *regexp.Regexp
+}
+
+func (e *AndExpression) Evaluate(resolve FieldResolver) bool {
+	return e.Left.Evaluate(resolve) && e.Right.Evaluate(resolve)
+}
+
+func (e *OrExpression) Evaluate(resolve FieldResolver) bool {
+	return e.Left.Evaluate(resolve) || e.Right.Evaluate(resolve)
+}
+
+func (e *NotExpression) Evaluate(resolve FieldResolver) bool {
+	return !e.Expression.Evaluate(resolve)
+}
+
+func (c *Comparison) Evaluate(resolve FieldResolver) bool {
+	value, ok := resolve(c.Field)
+
+	switch c.Operator {
+	case OperatorExists:
+		return ok && value != nil
+	case OperatorNotEqual:
+		return !(&Comparison{Field: c.Field, Operator: OperatorEqual, Values: c.Values}).Evaluate(resolve)
+	case OperatorNotContains:
+		return !(&Comparison{Field: c.Field, Operator: OperatorContains, Values: c.Values}).Evaluate(resolve)
+	case OperatorNotIn:
+		return !(&Comparison{Field: c.Field, Operator: OperatorIn, Values: c.Values}).Evaluate(resolve)
+	}
+
+	if !ok || value == nil {
+		return false
+	}
+
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			if c.matches(item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return c.matches(value)
+}
+
+func (c *Comparison) matches(value interface{}) bool {
+	for _, operand := range c.Values {
+		switch c.Operator {
+		case OperatorContains:
+			if strings.Contains(fmt.Sprint(value), operand) {
+				return true
+			}
+		case OperatorRegex:
+			if c.pattern != nil && c.pattern.MatchString(fmt.Sprint(value)) {
+				return true
+			}
+		case OperatorEqual, OperatorIn:
+			if result, ok := compareValue(value, operand); ok && result == 0 {
+				return true
+			}
+		case OperatorGreaterThan:
+			if result, ok := compareValue(value, operand); ok && result > 0 {
+				return true
+			}
+		case OperatorGreaterOrEqual:
+			if result, ok := compareValue(value, operand); ok && result >= 0 {
+				return true
+			}
+		case OperatorLessThan:
+			if result, ok := compareValue(value, operand); ok && result < 0 {
+				return true
+			}
+		case OperatorLessOrEqual:
+			if result, ok := compareValue(value, operand); ok && result <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func compareFloat(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// compareValue compares the field value with the operand as numbers, booleans, times or strings,
+// ok is false if they are not comparable.
+func compareValue(value interface{}, operand string) (result int, ok bool) {
+	var number float64
+	switch v := value.(type) {
+	case int64:
+		number = float64(v)
+	case int32:
+		number = float64(v)
+	case int:
+		number = float64(v)
+	case float64:
+		number = v
+	case bool:
+		b, err := strconv.ParseBool(operand)
+		if err != nil || b != v {
+			return 1, err == nil
+		}
+		return 0, true
+	case string:
+		if t, ok := parseTime(v); ok {
+			if o, ok := parseTime(operand); ok {
+				if t.Before(o) {
+					return -1, true
+				} else if t.After(o) {
+					return 1, true
+				}
+				return 0, true
+			}
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			if o, err := strconv.ParseFloat(operand, 64); err == nil {
+				return compareFloat(f, o), true
+			}
+		}
+		return strings.Compare(v, operand), true
+	default:
+		return 0, false
+	}
+
+	o, err := strconv.ParseFloat(operand, 64)
+	if err != nil {
+		return 0, false
+	}
+	return compareFloat(number, o), true
+}
+
+// SplitFieldPath splits field paths like spec.template.metadata.labels[app.kubernetes.io/name]
+// into segments, keys containing dots should be enclosed in brackets.
+func SplitFieldPath(field string) []string {
+	path := make([]string, 0)
+	var segment strings.Builder
+	for i := 0; i < len(field); i++ {
+		switch field[i] {
+		case '.':
+			if segment.Len() > 0 {
+				path = append(path, segment.String())
+				segment.Reset()
+			}
+		case '[':
+			if segment.Len() > 0 {
+				path = append(path, segment.String())
+				segment.Reset()
+			}
+			end := strings.IndexByte(field[i:], ']')
+			if end < 0 {
+				end = len(field) - i
+			}
+			path = append(path, field[i+1:i+end])
+			i += end
+		default:
+			segment.WriteByte(field[i])
+		}
+	}
+	if segment.Len() > 0 {
+		path = append(path, segment.String())
+	}
+	return path
+}
+
+// LookupField returns the value of the path in an unstructured object, the remaining path
+// is looked up in every element of lists unless the segment is an index.
+func LookupField(obj interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return obj, true
+	}
+
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		value, ok := v[path[0]]
+		if !ok {
+			return nil, false
+		}
+		return LookupField(value, path[1:])
+	case []interface{}:
+		if index, err := strconv.Atoi(path[0]); err == nil {
+			if index < 0 || index >= len(v) {
+				return nil, false
+			}
+			return LookupField(v[index], path[1:])
+		}
+		values := make([]interface{}, 0)
+		for _, item := range v {
+			if value, ok := LookupField(item, path); ok {
+				if list, ok := value.([]interface{}); ok {
+					values = append(values, list...)
+				} else {
+					values = append(values, value)
+				}
+			}
+		}
+		return values, len(values) > 0
+	default:
+		return nil, false
+	}
+}
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenNot
+	tokenAnd
+	tokenOr
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type token struct {
+	typ   tokenType
+	value string
+	pos   int
+}
+
+const specialChars = "()!=<>~,&|\"'"
+
+func tokenize(input string) ([]token, error) {
+	tokens := make([]token, 0)
+
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLeftParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRightParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case strings.HasPrefix(input[i:], "&&"):
+			tokens = append(tokens, token{tokenAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(input[i:], "||"):
+			tokens = append(tokens, token{tokenOr, "||", i})
+			i += 2
+		case strings.HasPrefix(input[i:], "=~"), strings.HasPrefix(input[i:], "!="), strings.HasPrefix(input[i:], "!~"),
+			strings.HasPrefix(input[i:], ">="), strings.HasPrefix(input[i:], "<="), strings.HasPrefix(input[i:], "=="):
+			op := input[i : i+2]
+			if op == "==" {
+				op = string(OperatorEqual)
+			}
+			tokens = append(tokens, token{tokenOperator, op, i})
+			i += 2
+		case c == '=' || c == '~' || c == '>' || c == '<':
+			tokens = append(tokens, token{tokenOperator, string(c), i})
+			i++
+		case c == '!':
+			tokens = append(tokens, token{tokenNot, "!", i})
+			i++
+		case c == '"' || c == '\'':
+			var value strings.Builder
+			j := i + 1
+			for ; j < len(input) && input[j] != c; j++ {
+				if input[j] == '\\' && j+1 < len(input) {
+					j++
+				}
+				value.WriteByte(input[j])
+			}
+			if j >= len(input) {
+				return nil, fmt.Errorf("invalid filter: unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{tokenString, value.String(), i})
+			i = j + 1
+		case c == '&' || c == '|':
+			return nil, fmt.Errorf("invalid filter: unexpected %q at %d", c, i)
+		default:
+			j := i
+			for j < len(input) && !unicode.IsSpace(rune(input[j])) && !strings.ContainsRune(specialChars, rune(input[j])) {
+				// brackets may contain any characters of label keys
+				if input[j] == '[' {
+					if end := strings.IndexByte(input[j:], ']'); end > 0 {
+						j += end
+					}
+				}
+				j++
+			}
+			word := input[i:j]
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, token{tokenAnd, word, i})
+			case "or":
+				tokens = append(tokens, token{tokenOr, word, i})
+			case "not":
+				tokens = append(tokens, token{tokenNot, word, i})
+			default:
+				tokens = append(tokens, token{tokenWord, word, i})
+			}
+			i = j
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "", len(input)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseFilter parses filter expressions like `(createTime>2019-01-01 AND spec.replicas>=3) OR NOT labels[app] in (web, "api server")`.
+// Conditions can be combined with AND (&&, or comma), OR (||) and NOT (!), a field
+// without operator checks the existence of the field like label selectors, =~ matches regular expressions.
+func ParseFilter(filter string) (Expression, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, fmt.Errorf("invalid filter: unexpected %q at %d", t.value, t.pos)
+	}
+
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().typ == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &OrExpression{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().typ == tokenAnd || p.peek().typ == tokenComma {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &AndExpression{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expression, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenNot:
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpression{Expression: expr}, nil
+	case tokenLeftParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.typ != tokenRightParen {
+			return nil, fmt.Errorf("invalid filter: expected ) at %d", t.pos)
+		}
+		return expr, nil
+	case tokenWord:
+		return p.parseComparison(t.value)
+	default:
+		return nil, fmt.Errorf("invalid filter: expected field at %d", t.pos)
+	}
+}
+
+func (p *parser) parseComparison(field string) (Expression, error) {
+	t := p.peek()
+
+	if t.typ == tokenOperator {
+		p.next()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		comparison := &Comparison{Field: field, Operator: Operator(t.value), Values: []string{value}}
+		if comparison.Operator == OperatorRegex {
+			if comparison.pattern, err = regexp.Compile(value); err != nil {
+				return nil, fmt.Errorf("invalid filter: %s at %d", err, t.pos)
+			}
+		}
+		return comparison, nil
+	}
+
+	if t.typ == tokenWord && (strings.ToLower(t.value) == string(OperatorIn) || strings.ToLower(t.value) == string(OperatorNotIn)) {
+		p.next()
+		if t := p.next(); t.typ != tokenLeftParen {
+			return nil, fmt.Errorf("invalid filter: expected ( at %d", t.pos)
+		}
+		values := make([]string, 0)
+		for {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if next := p.next(); next.typ == tokenRightParen {
+				break
+			} else if next.typ != tokenComma {
+				return nil, fmt.Errorf("invalid filter: expected , or ) at %d", next.pos)
+			}
+		}
+		return &Comparison{Field: field, Operator: Operator(strings.ToLower(t.value)), Values: values}, nil
+	}
+
+	return &Comparison{Field: field, Operator: OperatorExists}, nil
+}
+
+func (p *parser) parseValue() (string, error) {
+	t := p.next()
+	if t.typ != tokenWord && t.typ != tokenString {
+		return "", fmt.Errorf("invalid filter: expected value at %d", t.pos)
+	}
+	return t.value, nil
+}
@@ -0,0 +1,93 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package params
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":              "nginx-web",
+			"creationTimestamp": "2019-06-01T08:00:00Z",
+			"labels": map[string]interface{}{
+				"app.kubernetes.io/name": "nginx",
+				"tier":                   "web",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"paused":   false,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "nginx", "image": "nginx:1.14"},
+						map[string]interface{}{"name": "sidecar", "image": "envoy:1.10"},
+					},
+				},
+			},
+		},
+	}
+
+	resolve := func(field string) (interface{}, bool) {
+		return LookupField(obj, SplitFieldPath(field))
+	}
+
+	for _, test := range []struct {
+		filter  string
+		matched bool
+	}{
+		{"metadata.name=nginx-web", true},
+		{"metadata.name==\"nginx-web\"", true},
+		{"metadata.name!=nginx-web", false},
+		{"metadata.name~web", true},
+		{"metadata.name!~web", false},
+		{"metadata.name=~\"^nginx-(web|api)$\"", true},
+		{"!metadata.name=~'^web'", true},
+		{"spec.replicas>=3", true},
+		{"spec.replicas>3", false},
+		{"spec.replicas<10 && spec.paused=false", true},
+		{"metadata.creationTimestamp>2019-01-01", true},
+		{"metadata.creationTimestamp<'2019-06-01 07:00:00'", false},
+		{"metadata.labels[app.kubernetes.io/name] in (nginx, apache)", true},
+		{"metadata.labels.tier notin (web)", false},
+		{"metadata.labels.tier", true},
+		{"!metadata.labels.env", true},
+		{"metadata.labels.env!=prod", true},
+		{"metadata.labels.env=prod", false},
+		{"spec.template.spec.containers.image~envoy", true},
+		{"spec.template.spec.containers.1.name=nginx", false},
+		{"metadata.name=foo OR spec.replicas=3", true},
+		{"metadata.name=foo or spec.replicas=3 and spec.paused=true", false},
+		{"(metadata.name=foo OR spec.replicas=3) AND NOT spec.paused=true", true},
+		{"metadata.name=foo, spec.replicas=3", false},
+	} {
+		expr, err := ParseFilter(test.filter)
+		if assert.NoError(t, err, test.filter) {
+			assert.Equal(t, test.matched, expr.Evaluate(resolve), test.filter)
+		}
+	}
+
+	for _, filter := range []string{"name=", "(name=a", "name in a", "name in (a b)", "=a", "name=a)", "name=\"a", "name=a & b=c", "name=~\"(a\""} {
+		_, err := ParseFilter(filter)
+		assert.Error(t, err, filter)
+	}
+}
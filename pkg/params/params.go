@@ -26,11 +26,16 @@ import (
 )
 
 const (
-	PagingParam     = "paging"
-	OrderByParam    = "orderBy"
-	ConditionsParam = "conditions"
-	ReverseParam    = "reverse"
-	NameParam       = "name"
+	PagingParam          = "paging"
+	OrderByParam         = "orderBy"
+	ConditionsParam      = "conditions"
+	ReverseParam         = "reverse"
+	NameParam            = "name"
+	LimitParam           = "limit"
+	ContinueParam        = "continue"
+	WatchParam           = "watch"
+	ResourceVersionParam = "resourceVersion"
+	TimeoutParam         = "timeoutSeconds"
 )
 
 func ParsePaging(paging string) (limit, offset int) {
@@ -85,4 +90,6 @@ func ParseReverse(req *restful.Request) bool {
 type Conditions struct {
 	Match map[string]string
 	Fuzzy map[string]string
+	// Filter is evaluated in addition to Match and Fuzzy if not nil
+	Filter Expression
 }
@@ -0,0 +1,178 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package alertmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	alertmanagerEndpoint string
+
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+func init() {
+	flag.StringVar(&alertmanagerEndpoint, "alertmanager-endpoint", "http://alertmanager-main.kubesphere-monitoring-system.svc:9093/api/v2/", "alertmanager api v2 endpoint, alerts of alert policies are listed and silenced by it")
+}
+
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+}
+
+type AlertStatus struct {
+	// State is one of unprocessed, active, suppressed
+	State       string   `json:"state"`
+	SilencedBy  []string `json:"silencedBy"`
+	InhibitedBy []string `json:"inhibitedBy"`
+}
+
+type Alert struct {
+	Fingerprint string            `json:"fingerprint"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+	Status      AlertStatus       `json:"status"`
+}
+
+type SilenceStatus struct {
+	// State is one of active, pending, expired
+	State string `json:"state"`
+}
+
+type Silence struct {
+	ID        string         `json:"id,omitempty"`
+	Matchers  []Matcher      `json:"matchers"`
+	StartsAt  time.Time      `json:"startsAt"`
+	EndsAt    time.Time      `json:"endsAt"`
+	CreatedBy string         `json:"createdBy"`
+	Comment   string         `json:"comment"`
+	Status    *SilenceStatus `json:"status,omitempty"`
+}
+
+// ListAlerts returns alerts matching all the label values, silenced and inhibited alerts included
+func ListAlerts(labels map[string]string) ([]Alert, error) {
+	var alerts []Alert
+	err := request(http.MethodGet, "alerts?"+filter(labels), nil, &alerts)
+	return alerts, err
+}
+
+// ListSilences returns silences whose matchers are the label values, expired silences included
+func ListSilences(labels map[string]string) ([]Silence, error) {
+	var silences []Silence
+	err := request(http.MethodGet, "silences?"+filter(labels), nil, &silences)
+	return silences, err
+}
+
+// CreateSilence creates the silence and returns its id
+func CreateSilence(silence *Silence) (string, error) {
+	var result struct {
+		SilenceID string `json:"silenceID"`
+	}
+	err := request(http.MethodPost, "silences", silence, &result)
+	return result.SilenceID, err
+}
+
+func GetSilence(id string) (*Silence, error) {
+	silence := &Silence{}
+	err := request(http.MethodGet, "silence/"+url.PathEscape(id), nil, silence)
+	return silence, err
+}
+
+// ExpireSilence expires the silence, expired silences are kept by alertmanager for a while
+func ExpireSilence(id string) error {
+	return request(http.MethodDelete, "silence/"+url.PathEscape(id), nil, nil)
+}
+
+// filter returns the query of matchers of equality, e.g. filter=a="1"&filter=b="2"
+func filter(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(url.Values)
+	for _, name := range names {
+		values.Add("filter", fmt.Sprintf("%s=%q", name, labels[name]))
+	}
+	return values.Encode()
+}
+
+func request(method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, alertmanagerEndpoint+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// Error is returned if alertmanager responds with an error status
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("alertmanager returned %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,466 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package clickhouse
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/params"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+// Logs are stored in a table like
+//
+//   CREATE TABLE logs (
+//     time      DateTime64(3),
+//     log       String,
+//     namespace LowCardinality(String),
+//     pod       String,
+//     container LowCardinality(String),
+//     host      LowCardinality(String)
+//   ) ENGINE = MergeTree() PARTITION BY toDate(time) ORDER BY (namespace, time)
+//
+// structured fields are extracted from json log messages at query time.
+
+const exportBatchSize = 1000
+
+var (
+	server   string
+	database string
+	table    string
+	username string
+	password string
+)
+
+func init() {
+	flag.StringVar(&server, "clickhouse-server", "http://clickhouse.kubesphere-logging-system.svc:8123", "clickhouse http server address, used if the logging backend is clickhouse")
+	flag.StringVar(&database, "clickhouse-database", "logging", "clickhouse database of logs")
+	flag.StringVar(&table, "clickhouse-table", "logs", "clickhouse table of logs")
+	flag.StringVar(&username, "clickhouse-username", "default", "clickhouse username")
+	flag.StringVar(&password, "clickhouse-password", "", "clickhouse password")
+}
+
+type backend struct{}
+
+// Backend queries logs stored in ClickHouse with SQL over the http interface
+var Backend logging.Backend = backend{}
+
+// columns of log records, cursor is the unique key of logs with the same timestamp
+const columns = "toUnixTimestamp64Milli(time) AS time, log, namespace, pod, container, host, toString(cityHash64(namespace, pod, container, log)) AS cursor"
+
+type row struct {
+	Time      int64  `json:"time"`
+	Log       string `json:"log"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Host      string `json:"host"`
+	Cursor    string `json:"cursor"`
+}
+
+func (r row) record() logging.LogRecord {
+	return logging.LogRecord{
+		Time:      r.Time,
+		Log:       r.Log,
+		Namespace: r.Namespace,
+		Pod:       r.Pod,
+		Container: r.Container,
+		Host:      r.Host,
+	}
+}
+
+func (backend) Query(param logging.QueryParameters) *logging.QueryResult {
+	result, err := query(param)
+	if err != nil {
+		return &logging.QueryResult{Status: http.StatusInternalServerError, Error: err.Error()}
+	}
+	result.Status = http.StatusOK
+	result.Workspace = param.Workspace
+	return result
+}
+
+func query(param logging.QueryParameters) (*logging.QueryResult, error) {
+	where, err := whereClause(param)
+	if err != nil {
+		return nil, err
+	}
+
+	switch param.Operation {
+	case logging.OperationStatistics:
+		var rows []struct {
+			Logs       int64 `json:"logs"`
+			Containers int64 `json:"containers"`
+		}
+		if err := execute(fmt.Sprintf("SELECT count() AS logs, uniqExact(namespace, pod, container) AS containers FROM %s WHERE %s", from(), where), &rows); err != nil {
+			return nil, err
+		}
+		statistics := &logging.StatisticsResult{}
+		if len(rows) > 0 {
+			statistics.Logs, statistics.Containers = rows[0].Logs, rows[0].Containers
+		}
+		return &logging.QueryResult{Statistics: statistics}, nil
+	case logging.OperationHistogram:
+		interval := param.Interval
+		if interval == "" {
+			interval = logging.DefaultInterval
+		}
+		step, err := logging.ParseInterval(interval)
+		if err != nil {
+			return nil, err
+		}
+		var rows []logging.HistogramRecord
+		stepMillis := int64(step / time.Millisecond)
+		if err := execute(fmt.Sprintf("SELECT intDiv(toUnixTimestamp64Milli(time), %d) * %d AS time, count() AS count FROM %s WHERE %s GROUP BY time ORDER BY time",
+			stepMillis, stepMillis, from(), where), &rows); err != nil {
+			return nil, err
+		}
+		histogram := &logging.HistogramResult{Interval: interval, Histograms: []logging.HistogramRecord{}}
+		if start, ok := logging.ParseTime(param.StartTime); ok {
+			histogram.StartTime = logging.Millis(start)
+		}
+		if end, ok := logging.ParseTime(param.EndTime); ok {
+			histogram.EndTime = logging.Millis(end)
+		}
+		for _, r := range rows {
+			histogram.Total += r.Count
+			histogram.Histograms = append(histogram.Histograms, r)
+		}
+		return &logging.QueryResult{Histogram: histogram}, nil
+	default:
+		var total []struct {
+			Total int64 `json:"total"`
+		}
+		if err := execute(fmt.Sprintf("SELECT count() AS total FROM %s WHERE %s", from(), where), &total); err != nil {
+			return nil, err
+		}
+		var rows []row
+		if err := execute(fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time %s LIMIT %d OFFSET %d",
+			columns, from(), where, order(param.Sort), param.Size, param.From), &rows); err != nil {
+			return nil, err
+		}
+		read := &logging.ReadResult{From: param.From, Size: param.Size}
+		if len(total) > 0 {
+			read.Total = total[0].Total
+		}
+		for _, r := range rows {
+			read.Records = append(read.Records, r.record())
+		}
+		return &logging.QueryResult{Read: read}, nil
+	}
+}
+
+func (backend) Tail(param logging.QueryParameters, cursor []interface{}) ([]logging.LogRecord, []interface{}, error) {
+	param.Sort = "asc"
+	rows, err := page(param, cursor, param.Size)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	records := make([]logging.LogRecord, 0, len(rows))
+	for _, r := range rows {
+		records = append(records, r.record())
+		cursor = []interface{}{r.Time, r.Cursor}
+	}
+
+	return records, cursor, nil
+}
+
+func (backend) Export(param logging.QueryParameters, fn func(record logging.LogRecord) error) error {
+	var cursor []interface{}
+	for {
+		rows, err := page(param, cursor, exportBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range rows {
+			if err := fn(r.record()); err != nil {
+				return err
+			}
+			cursor = []interface{}{r.Time, r.Cursor}
+		}
+
+		if len(rows) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+// page returns logs after the cursor in the order of param.Sort, the cursor is the time and
+// the hash of the last log
+func page(param logging.QueryParameters, cursor []interface{}, size int64) ([]row, error) {
+	where, err := whereClause(param)
+	if err != nil {
+		return nil, err
+	}
+
+	order := order(param.Sort)
+
+	if len(cursor) == 2 {
+		comparator := ">"
+		if order == "DESC" {
+			comparator = "<"
+		}
+		where = fmt.Sprintf("%s AND (toUnixTimestamp64Milli(time), cityHash64(namespace, pod, container, log)) %s (%d, toUInt64(%s))",
+			where, comparator, cursor[0], quote(fmt.Sprint(cursor[1])))
+	}
+
+	var rows []row
+	err = execute(fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time %s, cityHash64(namespace, pod, container, log) %s LIMIT %d",
+		columns, from(), where, order, order, size), &rows)
+	return rows, err
+}
+
+func order(sort string) string {
+	if strings.ToLower(sort) == "asc" {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+func from() string {
+	return quoteIdentifier(database) + "." + quoteIdentifier(table)
+}
+
+func quoteIdentifier(identifier string) string {
+	return "`" + strings.Replace(strings.Replace(identifier, `\`, `\\`, -1), "`", "\\`", -1) + "`"
+}
+
+// quote returns the escaped string literal of the value
+func quote(value string) string {
+	return "'" + strings.Replace(strings.Replace(value, `\`, `\\`, -1), `'`, `\'`, -1) + "'"
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, quote(value))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// keywords splits keywords separated by comma or space
+func keywords(value string) []string {
+	return strings.Fields(strings.Replace(value, ",", " ", -1))
+}
+
+func whereClause(param logging.QueryParameters) (string, error) {
+	conditions := make([]string, 0)
+
+	if param.NamespaceFilled {
+		// logs of namespaces before their creation are not returned in case namespaces are recreated
+		namespaces := make([]string, 0, len(param.NamespaceWithCreationTime))
+		for namespace, creationTime := range param.NamespaceWithCreationTime {
+			condition := "namespace = " + quote(namespace)
+			if t, ok := logging.ParseTime(creationTime); ok {
+				condition = fmt.Sprintf("(%s AND time >= fromUnixTimestamp64Milli(toInt64(%d)))", condition, logging.Millis(t))
+			}
+			namespaces = append(namespaces, condition)
+		}
+		if len(namespaces) == 0 {
+			return "0", nil
+		}
+		sort.Strings(namespaces)
+		if len(namespaces) == 1 {
+			conditions = append(conditions, namespaces[0])
+		} else {
+			conditions = append(conditions, "("+strings.Join(namespaces, " OR ")+")")
+		}
+	}
+
+	if param.PodFilled {
+		if len(param.Pods) == 0 {
+			return "0", nil
+		}
+		conditions = append(conditions, fmt.Sprintf("pod IN (%s)", quoteAll(param.Pods)))
+	}
+
+	if param.ContainerFilled {
+		if len(param.Containers) == 0 {
+			return "0", nil
+		}
+		conditions = append(conditions, fmt.Sprintf("container IN (%s)", quoteAll(param.Containers)))
+	}
+
+	for _, query := range []struct{ column, keywords string }{
+		{"namespace", param.NamespaceQuery},
+		{"pod", param.PodQuery},
+		{"container", param.ContainerQuery},
+		{"log", param.LogQuery},
+	} {
+		if words := keywords(query.keywords); len(words) > 0 {
+			conditions = append(conditions, fmt.Sprintf("multiSearchAnyCaseInsensitive(%s, [%s])", query.column, quoteAll(words)))
+		}
+	}
+
+	if start, ok := logging.ParseTime(param.StartTime); ok {
+		conditions = append(conditions, fmt.Sprintf("time >= fromUnixTimestamp64Milli(toInt64(%d))", logging.Millis(start)))
+	}
+
+	if end, ok := logging.ParseTime(param.EndTime); ok {
+		conditions = append(conditions, fmt.Sprintf("time <= fromUnixTimestamp64Milli(toInt64(%d))", logging.Millis(end)))
+	}
+
+	if param.Filter != nil {
+		filter, err := filterCondition(param.Filter)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, filter)
+	}
+
+	if len(conditions) == 0 {
+		return "1", nil
+	}
+
+	return strings.Join(conditions, " AND "), nil
+}
+
+var columnAliases = map[string]string{
+	"namespace": "namespace",
+	"pod":       "pod",
+	"container": "container",
+	"host":      "host",
+	"log":       "log",
+}
+
+// filterCondition compiles the filter expression to SQL, fields other than columns are extracted
+// from json log messages
+func filterCondition(expr params.Expression) (string, error) {
+	switch e := expr.(type) {
+	case *params.AndExpression:
+		return binaryCondition("AND", e.Left, e.Right)
+	case *params.OrExpression:
+		return binaryCondition("OR", e.Left, e.Right)
+	case *params.NotExpression:
+		condition, err := filterCondition(e.Expression)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT %s", condition), nil
+	case *params.Comparison:
+		return comparisonCondition(e)
+	default:
+		return "", fmt.Errorf("unsupported filter expression %T", expr)
+	}
+}
+
+func binaryCondition(operator string, left, right params.Expression) (string, error) {
+	l, err := filterCondition(left)
+	if err != nil {
+		return "", err
+	}
+	r, err := filterCondition(right)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(%s %s %s)", l, operator, r), nil
+}
+
+func comparisonCondition(c *params.Comparison) (string, error) {
+	column, isColumn := columnAliases[c.Field]
+
+	path := ""
+	if !isColumn {
+		path = quoteAll(params.SplitFieldPath(c.Field))
+		column = fmt.Sprintf("JSONExtractString(log, %s)", path)
+	}
+
+	switch c.Operator {
+	case params.OperatorExists:
+		if isColumn {
+			return fmt.Sprintf("%s != ''", column), nil
+		}
+		return fmt.Sprintf("JSONHas(log, %s)", path), nil
+	case params.OperatorEqual, params.OperatorNotEqual, params.OperatorIn, params.OperatorNotIn:
+		condition := fmt.Sprintf("%s IN (%s)", column, quoteAll(c.Values))
+		if !isColumn {
+			// numbers and booleans are matched against raw values
+			condition = fmt.Sprintf("(%s OR JSONExtractRaw(log, %s) IN (%s))", condition, path, quoteAll(c.Values))
+		}
+		if c.Operator == params.OperatorNotEqual || c.Operator == params.OperatorNotIn {
+			return "NOT " + condition, nil
+		}
+		return condition, nil
+	case params.OperatorContains:
+		return fmt.Sprintf("position(%s, %s) > 0", column, quote(c.Values[0])), nil
+	case params.OperatorNotContains:
+		return fmt.Sprintf("position(%s, %s) = 0", column, quote(c.Values[0])), nil
+	case params.OperatorRegex:
+		return fmt.Sprintf("match(%s, %s)", column, quote(c.Values[0])), nil
+	case params.OperatorGreaterThan, params.OperatorGreaterOrEqual, params.OperatorLessThan, params.OperatorLessOrEqual:
+		if _, err := strconv.ParseFloat(c.Values[0], 64); err == nil && !isColumn {
+			return fmt.Sprintf("(JSONHas(log, %s) AND JSONExtractFloat(log, %s) %s %s)", path, path, c.Operator, c.Values[0]), nil
+		}
+		return fmt.Sprintf("%s %s %s", column, c.Operator, quote(c.Values[0])), nil
+	default:
+		return "", fmt.Errorf("unsupported operator %s", c.Operator)
+	}
+}
+
+// Fore more info, refer to https://clickhouse.com/docs/en/interfaces/http
+type response struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// execute runs the query and decodes the rows into result
+func execute(query string, result interface{}) error {
+	values := url.Values{}
+	values.Set("database", database)
+	values.Set("output_format_json_quote_64bit_integers", "0")
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(server, "/")+"/?"+values.Encode(), strings.NewReader(query+" FORMAT JSON"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-ClickHouse-User", username)
+	if password != "" {
+		req.Header.Set("X-ClickHouse-Key", password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("clickhouse returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var r response
+	if err := json.Unmarshal(body, &r); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(r.Data, result)
+}
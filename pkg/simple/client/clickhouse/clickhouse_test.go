@@ -0,0 +1,119 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package clickhouse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kubesphere.io/kubesphere/pkg/params"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+func TestFilterCondition(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected string
+	}{
+		{`namespace=default`, `namespace IN ('default')`},
+		{`http.status>=500 AND NOT level=debug`, `((JSONHas(log, 'http', 'status') AND JSONExtractFloat(log, 'http', 'status') >= 500) AND NOT (JSONExtractString(log, 'level') IN ('debug') OR JSONExtractRaw(log, 'level') IN ('debug')))`},
+		{`msg~"it's" OR trace_id`, `(position(JSONExtractString(log, 'msg'), 'it\'s') > 0 OR JSONHas(log, 'trace_id'))`},
+		{`log=~"^time(out)?"`, `match(log, '^time(out)?')`},
+	}
+
+	for _, test := range tests {
+		expr, err := params.ParseFilter(test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		condition, err := filterCondition(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if condition != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.filter, test.expected, condition)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	var queries []string
+
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		query := string(body)
+		queries = append(queries, query)
+
+		switch {
+		case strings.HasPrefix(query, "SELECT count() AS total"):
+			fmt.Fprint(w, `{"data":[{"total":2}],"rows":1}`)
+		case strings.HasPrefix(query, "SELECT toUnixTimestamp64Milli(time) AS time"):
+			fmt.Fprint(w, `{"data":[
+{"time":1559347201000,"log":"second","namespace":"default","pod":"nginx","container":"nginx","host":"node1","cursor":"2"},
+{"time":1559347200000,"log":"first","namespace":"default","pod":"nginx","container":"nginx","host":"node1","cursor":"1"}],"rows":2}`)
+		default:
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+		}
+	}))
+	defer stub.Close()
+
+	defer func(s string) { server = s }(server)
+	server = stub.URL
+
+	result := Backend.Query(logging.QueryParameters{
+		Operation:                 logging.OperationQuery,
+		NamespaceFilled:           true,
+		NamespaceWithCreationTime: map[string]string{"default": "1559347200000"},
+		LogQuery:                  "timeout",
+		Size:                      10,
+	})
+
+	if result.Status != http.StatusOK {
+		t.Fatalf("unexpected error %s", result.Error)
+	}
+
+	if result.Read.Total != 2 || len(result.Read.Records) != 2 || result.Read.Records[0].Log != "second" || result.Read.Records[1].Host != "node1" {
+		t.Errorf("unexpected result %+v", result.Read)
+	}
+
+	expected := "SELECT count() AS total FROM `logging`.`logs` WHERE (namespace = 'default' AND time >= fromUnixTimestamp64Milli(toInt64(1559347200000))) AND multiSearchAnyCaseInsensitive(log, ['timeout']) FORMAT JSON"
+	if queries[0] != expected {
+		t.Errorf("expected %s, got %s", expected, queries[0])
+	}
+
+	_, cursor, err := Backend.Tail(logging.QueryParameters{Size: 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cursor) != 2 || cursor[0] != int64(1559347200000) || cursor[1] != "1" {
+		t.Errorf("expected the cursor of the last record, got %v", cursor)
+	}
+
+	if _, _, err := Backend.Tail(logging.QueryParameters{Size: 10}, cursor); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(queries[len(queries)-1], "(toUnixTimestamp64Milli(time), cityHash64(namespace, pod, container, log)) > (1559347200000, toUInt64('1'))") {
+		t.Errorf("expected logs after the cursor, got %s", queries[len(queries)-1])
+	}
+}
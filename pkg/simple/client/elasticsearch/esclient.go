@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"github.com/golang/glog"
 	"io/ioutil"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
 	"net/http"
 	"strconv"
 	"strings"
@@ -57,7 +58,8 @@ func (configs *ESConfigs) WriteESConfigs() {
 type Request struct {
 	From          int64         `json:"from"`
 	Size          int64         `json:"size"`
-	Sorts         []Sort        `json:"sort,omitempty"`
+	Sorts         []interface{} `json:"sort,omitempty"`
+	SearchAfter   []interface{} `json:"search_after,omitempty"`
 	MainQuery     BoolQuery     `json:"query"`
 	Aggs          interface{}   `json:"aggs,omitempty"`
 	MainHighLight MainHighLight `json:"highlight,omitempty"`
@@ -67,6 +69,11 @@ type Sort struct {
 	Order Order `json:"time"`
 }
 
+// IDSort breaks the ties of logs with the same timestamp, so that search_after cursors are unique
+type IDSort struct {
+	Order Order `json:"_id"`
+}
+
 type Order struct {
 	Order string `json:"order"`
 }
@@ -163,7 +170,18 @@ type DateHistogram struct {
 	Interval string `json:"interval"`
 }
 
-func createQueryRequest(param QueryParameters) (int, []byte, error) {
+func createQueryRequest(param logging.QueryParameters) (int, []byte, error) {
+	operation, request, err := buildQueryRequest(param)
+	if err != nil {
+		return operation, nil, err
+	}
+
+	queryRequest, err := json.Marshal(request)
+
+	return operation, queryRequest, err
+}
+
+func buildQueryRequest(param logging.QueryParameters) (int, *Request, error) {
 	var request Request
 	var mainBoolQuery BoolMusts
 
@@ -235,6 +253,14 @@ func createQueryRequest(param QueryParameters) (int, []byte, error) {
 		mainBoolQuery.Musts = append(mainBoolQuery.Musts, match)
 	}
 
+	if param.Filter != nil {
+		query, err := fieldQuery(param.Filter)
+		if err != nil {
+			return OperationQuery, nil, err
+		}
+		mainBoolQuery.Musts = append(mainBoolQuery.Musts, query)
+	}
+
 	rangeQuery := RangeQuery{RangeSpec{TimeRange{param.StartTime, param.EndTime}}}
 	mainBoolQuery.Musts = append(mainBoolQuery.Musts, rangeQuery)
 
@@ -280,9 +306,7 @@ func createQueryRequest(param QueryParameters) (int, []byte, error) {
 
 	request.MainQuery = BoolQuery{mainBoolQuery}
 
-	queryRequest, err := json.Marshal(request)
-
-	return operation, queryRequest, err
+	return operation, &request, nil
 }
 
 // Fore more info, refer to https://www.elastic.co/guide/en/elasticsearch/reference/current/getting-started-search-API.html
@@ -308,9 +332,9 @@ type Hits struct {
 }
 
 type Hit struct {
-	Source    Source    `json:"_source"`
-	HighLight HighLight `json:"highlight"`
-	Sort      []int64   `json:"sort"`
+	Source    Source            `json:"_source"`
+	HighLight logging.HighLight `json:"highlight"`
+	Sort      []interface{}     `json:"sort"`
 }
 
 type Source struct {
@@ -326,30 +350,6 @@ type Kubernetes struct {
 	Host      string `json:"host"`
 }
 
-type HighLight struct {
-	LogHighLights       []string `json:"log,omitempty" description:"log messages to highlight"`
-	NamespaceHighLights []string `json:"kubernetes.namespace_name.keyword,omitempty" description:"namespaces to highlight"`
-	PodHighLights       []string `json:"kubernetes.pod_name.keyword,omitempty" description:"pods to highlight"`
-	ContainerHighLights []string `json:"kubernetes.container_name.keyword,omitempty" description:"containers to highlight"`
-}
-
-type LogRecord struct {
-	Time      int64     `json:"time,omitempty" description:"log timestamp"`
-	Log       string    `json:"log,omitempty" description:"log message"`
-	Namespace string    `json:"namespace,omitempty" description:"namespace"`
-	Pod       string    `json:"pod,omitempty" description:"pod name"`
-	Container string    `json:"container,omitempty" description:"container name"`
-	Host      string    `json:"host,omitempty" description:"node id"`
-	HighLight HighLight `json:"highlight,omitempty" description:"highlighted log fragment"`
-}
-
-type ReadResult struct {
-	Total   int64       `json:"total" description:"total number of matched results"`
-	From    int64       `json:"from" description:"the offset from the result set"`
-	Size    int64       `json:"size" description:"the amount of hits to be returned"`
-	Records []LogRecord `json:"records,omitempty" description:"actual array of results"`
-}
-
 // StatisticsResponseAggregations, the struct for `aggregations` of type Reponse, holds return results from the aggregation StatisticsAggs
 type StatisticsResponseAggregations struct {
 	ContainerCount ContainerCount `json:"containers"`
@@ -372,34 +372,6 @@ type HistogramStatistics struct {
 	Count int64 `json:"doc_count"`
 }
 
-type HistogramRecord struct {
-	Time  int64 `json:"time" description:"time point"`
-	Count int64 `json:"count" description:"total number of logs at intervals"`
-}
-
-type StatisticsResult struct {
-	Containers int64 `json:"containers" description:"total number of containers"`
-	Logs       int64 `json:"logs" description:"total number of logs"`
-}
-
-type HistogramResult struct {
-	Total      int64             `json:"total" description:"total number of logs"`
-	StartTime  int64             `json:"start_time" description:"start time"`
-	EndTime    int64             `json:"end_time" description:"end time"`
-	Interval   string            `json:"interval" description:"interval"`
-	Histograms []HistogramRecord `json:"histograms" description:"actual array of histogram results"`
-}
-
-// Wrap elasticsearch response
-type QueryResult struct {
-	Status     int               `json:"status,omitempty" description:"query status"`
-	Error      string            `json:"error,omitempty" description:"debug information"`
-	Workspace  string            `json:"workspace,omitempty" description:"workspace the query was performed against"`
-	Read       *ReadResult       `json:"query,omitempty" description:"query results"`
-	Statistics *StatisticsResult `json:"statistics,omitempty" description:"statistics results"`
-	Histogram  *HistogramResult  `json:"histogram,omitempty" description:"histogram results"`
-}
-
 const (
 	OperationQuery int = iota
 	OperationStatistics
@@ -427,8 +399,8 @@ func calcTimestamp(input string) int64 {
 	return ret
 }
 
-func parseQueryResult(operation int, param QueryParameters, body []byte, query []byte) *QueryResult {
-	var queryResult QueryResult
+func parseQueryResult(operation int, param logging.QueryParameters, body []byte, query []byte) *logging.QueryResult {
+	var queryResult logging.QueryResult
 
 	var response Response
 	err := jsonIter.Unmarshal(body, &response)
@@ -456,12 +428,12 @@ func parseQueryResult(operation int, param QueryParameters, body []byte, query [
 
 	switch operation {
 	case OperationQuery:
-		var readResult ReadResult
+		var readResult logging.ReadResult
 		readResult.Total = response.Hits.Total
 		readResult.From = param.From
 		readResult.Size = param.Size
 		for _, hit := range response.Hits.Hits {
-			var logRecord LogRecord
+			var logRecord logging.LogRecord
 			logRecord.Time = calcTimestamp(hit.Source.Time)
 			logRecord.Log = hit.Source.Log
 			logRecord.Namespace = hit.Source.Kubernetes.Namespace
@@ -482,10 +454,10 @@ func parseQueryResult(operation int, param QueryParameters, body []byte, query [
 			queryResult.Error = err.Error()
 			return &queryResult
 		}
-		queryResult.Statistics = &StatisticsResult{Containers: statisticsResponse.ContainerCount.Value, Logs: response.Hits.Total}
+		queryResult.Statistics = &logging.StatisticsResult{Containers: statisticsResponse.ContainerCount.Value, Logs: response.Hits.Total}
 
 	case OperationHistogram:
-		var histogramResult HistogramResult
+		var histogramResult logging.HistogramResult
 		histogramResult.Total = response.Hits.Total
 		histogramResult.StartTime = calcTimestamp(param.StartTime)
 		histogramResult.EndTime = calcTimestamp(param.EndTime)
@@ -500,7 +472,7 @@ func parseQueryResult(operation int, param QueryParameters, body []byte, query [
 			return &queryResult
 		}
 		for _, histogram := range histogramAggregations.HistogramAggregation.Histograms {
-			var histogramRecord HistogramRecord
+			var histogramRecord logging.HistogramRecord
 			histogramRecord.Time = histogram.Time
 			histogramRecord.Count = histogram.Count
 
@@ -516,92 +488,262 @@ func parseQueryResult(operation int, param QueryParameters, body []byte, query [
 	return &queryResult
 }
 
-type QueryParameters struct {
-	NamespaceFilled           bool
-	Namespaces                []string
-	NamespaceWithCreationTime map[string]string
-	PodFilled                 bool
-	Pods                      []string
-	ContainerFilled           bool
-	Containers                []string
-
-	NamespaceQuery string
-	PodQuery       string
-	ContainerQuery string
-
-	Workspace string
-
-	Operation string
-	LogQuery  string
-	Interval  string
-	StartTime string
-	EndTime   string
-	Sort      string
-	From      int64
-	Size      int64
-}
-
-func stubResult() *QueryResult {
-	var queryResult QueryResult
+func stubResult() *logging.QueryResult {
+	var queryResult logging.QueryResult
 
 	queryResult.Status = http.StatusOK
 
 	return &queryResult
 }
 
-func Query(param QueryParameters) *QueryResult {
-	var queryResult *QueryResult
-
-	client := &http.Client{}
+func Query(param logging.QueryParameters) *logging.QueryResult {
+	var queryResult *logging.QueryResult
 
 	operation, query, err := createQueryRequest(param)
 	if err != nil {
-		queryResult = new(QueryResult)
+		queryResult = new(logging.QueryResult)
 		queryResult.Status = http.StatusNotFound
 		queryResult.Error = err.Error()
 		return queryResult
 	}
 
-	es := readESConfigs()
-	if es == nil {
-		queryResult = new(QueryResult)
+	body, err := search(query)
+	if err != nil {
+		glog.Errorln(err)
+		queryResult = new(logging.QueryResult)
 		queryResult.Status = http.StatusNotFound
-		queryResult.Error = "Elasticsearch configurations not found. Please check if they are properly configured."
+		queryResult.Error = err.Error()
 		return queryResult
 	}
 
-	url := fmt.Sprintf("http://%s:%s/%s*/_search", es.Host, es.Port, es.Index)
+	queryResult = parseQueryResult(operation, param, body, query)
+
+	return queryResult
+}
+
+// Configured reports whether the elasticsearch configurations are set
+func Configured() bool {
+	return readESConfigs() != nil
+}
+
+// Tail returns the logs after the cursor in ascending order of time and the cursor of the last one,
+// logs are searched from the start time of the parameters if the cursor is nil
+func Tail(param logging.QueryParameters, cursor []interface{}) ([]logging.LogRecord, []interface{}, error) {
+	param.Operation = logging.OperationQuery
 
-	request, err := http.NewRequest("GET", url, bytes.NewBuffer(query))
+	_, request, err := buildQueryRequest(param)
 	if err != nil {
-		glog.Errorln(err)
-		queryResult = new(QueryResult)
-		queryResult.Status = http.StatusNotFound
-		queryResult.Error = err.Error()
-		return queryResult
+		return nil, cursor, err
+	}
+
+	request.From = 0
+	request.Sorts = []interface{}{Sort{Order{"asc"}}, IDSort{Order{"asc"}}}
+	request.SearchAfter = cursor
+	request.MainHighLight = MainHighLight{}
+
+	query, err := json.Marshal(request)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	body, err := search(query)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	hits, err := parseHits(body)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	records := make([]logging.LogRecord, 0, len(hits))
+
+	for _, hit := range hits {
+		records = append(records, hitRecord(hit))
+		cursor = hit.Sort
+	}
+
+	return records, cursor, nil
+}
+
+const (
+	exportBatchSize = 1000
+	scrollKeepAlive = "1m"
+)
+
+// Export scrolls all logs matching the parameters
+func Export(param logging.QueryParameters, fn func(record logging.LogRecord) error) error {
+	param.Operation = logging.OperationQuery
+
+	_, request, err := buildQueryRequest(param)
+	if err != nil {
+		return err
+	}
+
+	request.From = 0
+	request.Size = exportBatchSize
+	request.MainHighLight = MainHighLight{}
+
+	query, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	es := readESConfigs()
+	if es == nil {
+		return logging.ErrNotConfigured
+	}
+
+	body, err := doRequest(http.MethodPost, fmt.Sprintf("http://%s:%s/%s*/_search?scroll=%s", es.Host, es.Port, es.Index, scrollKeepAlive), query)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var scroll struct {
+			ScrollId string `json:"_scroll_id"`
+		}
+		if err := jsonIter.Unmarshal(body, &scroll); err != nil {
+			return err
+		}
+
+		hits, err := parseHits(body)
+		if err == nil {
+			for _, hit := range hits {
+				if err = fn(hitRecord(hit)); err != nil {
+					break
+				}
+			}
+		}
+
+		if err != nil || len(hits) == 0 {
+			if scroll.ScrollId != "" {
+				clear, _ := json.Marshal(map[string]interface{}{"scroll_id": scroll.ScrollId})
+				doRequest(http.MethodDelete, fmt.Sprintf("http://%s:%s/_search/scroll", es.Host, es.Port), clear)
+			}
+			return err
+		}
+
+		next, _ := json.Marshal(map[string]interface{}{"scroll": scrollKeepAlive, "scroll_id": scroll.ScrollId})
+		body, err = doRequest(http.MethodPost, fmt.Sprintf("http://%s:%s/_search/scroll", es.Host, es.Port), next)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func parseHits(body []byte) ([]Hit, error) {
+	var response Response
+	err := jsonIter.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Status != 0 {
+		return nil, fmt.Errorf("the query failed with status %d", response.Status)
+	}
+
+	return response.Hits.Hits, nil
+}
+
+func hitRecord(hit Hit) logging.LogRecord {
+	return logging.LogRecord{
+		Time:      calcTimestamp(hit.Source.Time),
+		Log:       hit.Source.Log,
+		Namespace: hit.Source.Kubernetes.Namespace,
+		Pod:       hit.Source.Kubernetes.Pod,
+		Container: hit.Source.Kubernetes.Container,
+		Host:      hit.Source.Kubernetes.Host,
+	}
+}
+
+func search(query []byte) ([]byte, error) {
+	es := readESConfigs()
+	if es == nil {
+		return nil, fmt.Errorf("Elasticsearch configurations not found. Please check if they are properly configured.")
+	}
+
+	return doRequest(http.MethodGet, fmt.Sprintf("http://%s:%s/%s*/_search", es.Host, es.Port, es.Index), query)
+}
+
+func doRequest(method, url string, body []byte) ([]byte, error) {
+	request, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
 	}
 	request.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	response, err := client.Do(request)
+	response, err := http.DefaultClient.Do(request)
 	if err != nil {
-		glog.Errorln(err)
-		queryResult = new(QueryResult)
-		queryResult.Status = http.StatusNotFound
-		queryResult.Error = err.Error()
-		return queryResult
+		return nil, err
 	}
 	defer response.Body.Close()
 
-	body, err := ioutil.ReadAll(response.Body)
+	return ioutil.ReadAll(response.Body)
+}
+
+// Index is an elasticsearch index of logs
+type Index struct {
+	Name string `json:"index"`
+	// Size is the store size in bytes
+	Size int64 `json:"store.size,string"`
+}
+
+// Indices returns indices matching the pattern, e.g. logstash-*
+func Indices(pattern string) ([]Index, error) {
+	es := readESConfigs()
+	if es == nil {
+		return nil, logging.ErrNotConfigured
+	}
+
+	body, err := doRequest(http.MethodGet, fmt.Sprintf("http://%s:%s/_cat/indices/%s?format=json&bytes=b&h=index,store.size", es.Host, es.Port, pattern), nil)
 	if err != nil {
-		glog.Errorln(err)
-		queryResult = new(QueryResult)
-		queryResult.Status = http.StatusNotFound
-		queryResult.Error = err.Error()
-		return queryResult
+		return nil, err
 	}
 
-	queryResult = parseQueryResult(operation, param, body, query)
+	var indices []Index
+	if err := jsonIter.Unmarshal(body, &indices); err != nil {
+		return nil, fmt.Errorf("list indices %s: %s", pattern, body)
+	}
 
-	return queryResult
+	return indices, nil
+}
+
+func DeleteIndex(name string) error {
+	es := readESConfigs()
+	if es == nil {
+		return logging.ErrNotConfigured
+	}
+
+	body, err := doRequest(http.MethodDelete, fmt.Sprintf("http://%s:%s/%s", es.Host, es.Port, name), nil)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Acknowledged bool `json:"acknowledged"`
+	}
+	if err := jsonIter.Unmarshal(body, &result); err != nil || !result.Acknowledged {
+		return fmt.Errorf("delete index %s: %s", name, body)
+	}
+
+	return nil
+}
+
+type backend struct{}
+
+// Backend queries logs stored in elasticsearch, the configurations are discovered from
+// the es output of Fluent Bit
+var Backend logging.Backend = backend{}
+
+func (backend) Query(param logging.QueryParameters) *logging.QueryResult {
+	return Query(param)
+}
+
+func (backend) Tail(param logging.QueryParameters, cursor []interface{}) ([]logging.LogRecord, []interface{}, error) {
+	return Tail(param, cursor)
+}
+
+func (backend) Export(param logging.QueryParameters, fn func(record logging.LogRecord) error) error {
+	return Export(param, fn)
 }
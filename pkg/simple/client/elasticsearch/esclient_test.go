@@ -0,0 +1,82 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package esclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+func TestTail(t *testing.T) {
+	var requests []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		request := make(map[string]interface{})
+		json.Unmarshal(body, &request)
+		requests = append(requests, request)
+
+		fmt.Fprint(w, `{"_shards":{"total":1,"successful":1},"hits":{"total":2,"hits":[
+{"_source":{"log":"first","time":"2019-06-01T00:00:00Z","kubernetes":{"namespace_name":"default","pod_name":"nginx","container_name":"nginx"}},"sort":[1559347200000,"a"]},
+{"_source":{"log":"second","time":"2019-06-01T00:00:00Z","kubernetes":{"namespace_name":"default","pod_name":"nginx","container_name":"nginx"}},"sort":[1559347200000,"b"]}]}}`)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+	(&ESConfigs{Host: host, Port: port, Index: "logstash"}).WriteESConfigs()
+	defer (*ESConfigs)(nil).WriteESConfigs()
+
+	param := logging.QueryParameters{StartTime: "1559347200000", Size: 100}
+
+	records, cursor, err := Tail(param, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 || records[0].Log != "first" || records[1].Time != 1559347200000 {
+		t.Errorf("unexpected records %+v", records)
+	}
+
+	if _, ok := requests[0]["search_after"]; ok {
+		t.Errorf("search_after of the first request should be empty")
+	}
+
+	_, _, err = Tail(param, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	searchAfter, _ := json.Marshal(requests[1]["search_after"])
+	if string(searchAfter) != `[1559347200000,"b"]` {
+		t.Errorf("expected search_after of the last record, got %s", searchAfter)
+	}
+
+	sorts, _ := json.Marshal(requests[1]["sort"])
+	if string(sorts) != `[{"time":{"order":"asc"}},{"_id":{"order":"asc"}}]` {
+		t.Errorf("unexpected sort %s", sorts)
+	}
+}
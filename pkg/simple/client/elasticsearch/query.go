@@ -0,0 +1,312 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package esclient
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+// fieldAliases maps the fields of log filters to the fields of log records in elasticsearch,
+// other fields are parsed from structured logs by Fluent Bit parsers
+var fieldAliases = map[string]string{
+	"namespace": "kubernetes.namespace_name",
+	"pod":       "kubernetes.pod_name",
+	"container": "kubernetes.container_name",
+	"host":      "kubernetes.host",
+}
+
+// fieldQuery compiles the filter expression, e.g. `level=error AND (http.status>=500 OR trace_id=~"^a1")`,
+// to an elasticsearch query. Strings are matched against the keyword sub field, and regular
+// expressions are matched against whole values.
+func fieldQuery(expr params.Expression) (interface{}, error) {
+	switch e := expr.(type) {
+	case nil:
+		return nil, nil
+	case *params.AndExpression:
+		left, err := fieldQuery(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := fieldQuery(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return boolQuery("must", left, right), nil
+	case *params.OrExpression:
+		left, err := fieldQuery(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := fieldQuery(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return boolQuery("should", left, right), nil
+	case *params.NotExpression:
+		query, err := fieldQuery(e.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return boolQuery("must_not", query), nil
+	case *params.Comparison:
+		return comparisonQuery(e)
+	default:
+		return nil, fmt.Errorf("unsupported filter expression %T", expr)
+	}
+}
+
+func boolQuery(occur string, queries ...interface{}) interface{} {
+	query := map[string]interface{}{occur: queries}
+	if occur == "should" {
+		query["minimum_should_match"] = 1
+	}
+	return map[string]interface{}{"bool": query}
+}
+
+func comparisonQuery(c *params.Comparison) (interface{}, error) {
+	field := logField(c.Field)
+
+	switch c.Operator {
+	case params.OperatorExists:
+		return map[string]interface{}{"exists": map[string]interface{}{"field": field}}, nil
+	case params.OperatorEqual:
+		return equalQuery(field, c.Values[0]), nil
+	case params.OperatorNotEqual:
+		return boolQuery("must_not", equalQuery(field, c.Values[0])), nil
+	case params.OperatorIn, params.OperatorNotIn:
+		queries := make([]interface{}, 0, len(c.Values))
+		for _, value := range c.Values {
+			queries = append(queries, equalQuery(field, value))
+		}
+		if c.Operator == params.OperatorNotIn {
+			return boolQuery("must_not", queries...), nil
+		}
+		return boolQuery("should", queries...), nil
+	case params.OperatorContains:
+		return MatchPhrase{MatchPhrase: map[string]interface{}{field: c.Values[0]}}, nil
+	case params.OperatorNotContains:
+		return boolQuery("must_not", MatchPhrase{MatchPhrase: map[string]interface{}{field: c.Values[0]}}), nil
+	case params.OperatorRegex:
+		pattern, err := luceneRegex(c.Values[0])
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"regexp": map[string]interface{}{field + ".keyword": pattern}}, nil
+	case params.OperatorGreaterThan, params.OperatorGreaterOrEqual, params.OperatorLessThan, params.OperatorLessOrEqual:
+		ranges := map[params.Operator]string{
+			params.OperatorGreaterThan:    "gt",
+			params.OperatorGreaterOrEqual: "gte",
+			params.OperatorLessThan:       "lt",
+			params.OperatorLessOrEqual:    "lte",
+		}
+		return map[string]interface{}{"range": map[string]interface{}{field: map[string]interface{}{ranges[c.Operator]: rangeValue(c.Values[0])}}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", c.Operator)
+	}
+}
+
+// logField returns the path of the field in elasticsearch documents, e.g. labels[app] is labels.app
+func logField(field string) string {
+	if alias, ok := fieldAliases[field]; ok {
+		return alias
+	}
+	return strings.Join(params.SplitFieldPath(field), ".")
+}
+
+// equalQuery matches numbers and booleans exactly, strings are matched against the keyword sub field
+func equalQuery(field, value string) interface{} {
+	if _, err := strconv.ParseFloat(value, 64); err == nil || value == "true" || value == "false" {
+		return map[string]interface{}{"term": map[string]interface{}{field: value}}
+	}
+	return map[string]interface{}{"term": map[string]interface{}{field + ".keyword": value}}
+}
+
+func rangeValue(value string) interface{} {
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number
+	}
+	return value
+}
+
+// luceneRegex converts the unanchored regular expression to lucene regular expression, which always matches the
+// whole value. The expression is parsed and written in the lucene syntax, so that perl classes like \d and \w and
+// case insensitive matching are supported, anchors other than the leading ^ and the trailing $, word boundaries and
+// lookarounds are not supported by lucene.
+func luceneRegex(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("invalid regular expression %s: %s", pattern, err)
+	}
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+
+	prefix, suffix := ".*", ".*"
+	if len(subs) > 0 && subs[0].Op == syntax.OpBeginText {
+		prefix, subs = "", subs[1:]
+	}
+	if len(subs) > 0 && subs[len(subs)-1].Op == syntax.OpEndText {
+		suffix, subs = "", subs[:len(subs)-1]
+	}
+
+	var lucene strings.Builder
+	lucene.WriteString(prefix)
+	for _, sub := range subs {
+		if err := writeLuceneRegex(&lucene, sub, true); err != nil {
+			return "", fmt.Errorf("unsupported regular expression %s: %s", pattern, err)
+		}
+	}
+	lucene.WriteString(suffix)
+
+	return lucene.String(), nil
+}
+
+// reserved characters of lucene regular expressions, including the optional operators enabled by elasticsearch
+const luceneReserved = `.?+*|{}[]()"\#@&<>~`
+
+// writeLuceneRegex writes the expression in the lucene syntax, it's grouped unless it's a single atom or inConcat is
+// true and it's a concatenation
+func writeLuceneRegex(w *strings.Builder, re *syntax.Regexp, inConcat bool) error {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if len(re.Rune) > 1 && !inConcat {
+			w.WriteString("(")
+			defer w.WriteString(")")
+		}
+		for _, r := range re.Rune {
+			writeLuceneLiteral(w, r, re.Flags&syntax.FoldCase != 0)
+		}
+	case syntax.OpCharClass:
+		writeLuceneClass(w, re.Rune)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		w.WriteString(".")
+	case syntax.OpEmptyMatch:
+		w.WriteString(`""`)
+	case syntax.OpCapture:
+		return writeLuceneRegex(w, re.Sub[0], false)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		if err := writeLuceneRegex(w, re.Sub[0], false); err != nil {
+			return err
+		}
+		switch {
+		case re.Op == syntax.OpStar:
+			w.WriteString("*")
+		case re.Op == syntax.OpPlus:
+			w.WriteString("+")
+		case re.Op == syntax.OpQuest:
+			w.WriteString("?")
+		case re.Max < 0:
+			fmt.Fprintf(w, "{%d,}", re.Min)
+		case re.Min == re.Max:
+			fmt.Fprintf(w, "{%d}", re.Min)
+		default:
+			fmt.Fprintf(w, "{%d,%d}", re.Min, re.Max)
+		}
+	case syntax.OpConcat:
+		if !inConcat {
+			w.WriteString("(")
+			defer w.WriteString(")")
+		}
+		for _, sub := range re.Sub {
+			if err := writeLuceneRegex(w, sub, true); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		w.WriteString("(")
+		for i, sub := range re.Sub {
+			if i > 0 {
+				w.WriteString("|")
+			}
+			if err := writeLuceneRegex(w, sub, true); err != nil {
+				return err
+			}
+		}
+		w.WriteString(")")
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText:
+		return fmt.Errorf("anchors are only supported at the start and the end")
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return fmt.Errorf("word boundaries are not supported")
+	default:
+		return fmt.Errorf("%s is not supported", re)
+	}
+	return nil
+}
+
+func writeLuceneLiteral(w *strings.Builder, r rune, foldCase bool) {
+	if foldCase && unicode.SimpleFold(r) != r {
+		w.WriteString("[")
+		for f := r; ; {
+			writeLuceneClassChar(w, f)
+			if f = unicode.SimpleFold(f); f == r {
+				break
+			}
+		}
+		w.WriteString("]")
+		return
+	}
+	if strings.ContainsRune(luceneReserved, r) {
+		w.WriteString(`\`)
+	}
+	w.WriteRune(r)
+}
+
+// writeLuceneClass writes the ranges of the class, classes including the first and the last
+// characters are written as negated classes, e.g. [^a] is parsed as [\x00-`b-\x{10FFFF}]
+func writeLuceneClass(w *strings.Builder, ranges []rune) {
+	negated := len(ranges) > 0 && ranges[0] == 0 && ranges[len(ranges)-1] == unicode.MaxRune
+	if negated {
+		complement := make([]rune, 0, len(ranges))
+		for i := 1; i+1 < len(ranges); i += 2 {
+			complement = append(complement, ranges[i]+1, ranges[i+1]-1)
+		}
+		ranges = complement
+		if len(ranges) == 0 {
+			w.WriteString(".")
+			return
+		}
+	}
+
+	w.WriteString("[")
+	if negated {
+		w.WriteString("^")
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		writeLuceneClassChar(w, ranges[i])
+		if ranges[i+1] != ranges[i] {
+			w.WriteString("-")
+			writeLuceneClassChar(w, ranges[i+1])
+		}
+	}
+	w.WriteString("]")
+}
+
+func writeLuceneClassChar(w *strings.Builder, r rune) {
+	if strings.ContainsRune(`[]^-\`, r) || strings.ContainsRune(luceneReserved, r) {
+		w.WriteString(`\`)
+	}
+	w.WriteRune(r)
+}
@@ -0,0 +1,79 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package esclient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+func TestFieldQuery(t *testing.T) {
+	for _, test := range []struct {
+		filter string
+		query  string
+	}{
+		{"level=error", `{"term":{"level.keyword":"error"}}`},
+		{"http.status>=500", `{"range":{"http.status":{"gte":500}}}`},
+		{"pod=nginx-0", `{"term":{"kubernetes.pod_name.keyword":"nginx-0"}}`},
+		{"trace_id", `{"exists":{"field":"trace_id"}}`},
+		{"trace_id=~'^a1'", `{"regexp":{"trace_id.keyword":"a1.*"}}`},
+		{"log~timeout", `{"match_phrase":{"log":"timeout"}}`},
+		{"level in (error, fatal) AND NOT http.status=404", `{"bool":{"must":[{"bool":{"minimum_should_match":1,"should":[{"term":{"level.keyword":"error"}},{"term":{"level.keyword":"fatal"}}]}},{"bool":{"must_not":[{"term":{"http.status":"404"}}]}}]}}`},
+	} {
+		expr, err := params.ParseFilter(test.filter)
+		if !assert.NoError(t, err, test.filter) {
+			continue
+		}
+		query, err := fieldQuery(expr)
+		if assert.NoError(t, err, test.filter) {
+			data, _ := json.Marshal(query)
+			assert.Equal(t, test.query, string(data), test.filter)
+		}
+	}
+}
+
+func TestLuceneRegex(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		lucene  string
+	}{
+		{"^a1", "a1.*"},
+		{"timeout$", ".*timeout"},
+		{`^\d{3}$`, "[0-9]{3}"},
+		{`\w+@example\.com`, `.*[0-9A-Z_a-z]+\@example\.com.*`},
+		{"(?i)error", ".*[Ee][Rr][Rr][Oo][Rr].*"},
+		{"^(GET|POST) /api", `(GET|POST) /api.*`},
+		{"^(ab)+c?x{2,}$", "(ab)+c?x{2,}"},
+		{"^[^0-9-]", `[^\-0-9].*`},
+		{"a.b", ".*a.b.*"},
+	} {
+		lucene, err := luceneRegex(test.pattern)
+		if assert.NoError(t, err, test.pattern) {
+			assert.Equal(t, test.lucene, lucene, test.pattern)
+		}
+	}
+
+	for _, pattern := range []string{"(?=a)b", `a\bb`, "a^b", "a$b", "(a"} {
+		_, err := luceneRegex(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
@@ -37,9 +37,11 @@ var (
 	MasterURL      string
 )
 
-func init() {
-	flag.StringVar(&kubeConfigFile, "kubeconfig", "", "path to kubeconfig file")
-	flag.StringVar(&MasterURL, "master-url", "", "kube-apiserver url, only needed when out of cluster")
+// AddFlags registers the kubeconfig and master-url flags, they are not registered in init
+// as controller-runtime registers the same kubeconfig flag in the binaries importing both
+func AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&kubeConfigFile, "kubeconfig", "", "path to kubeconfig file")
+	fs.StringVar(&MasterURL, "master-url", "", "kube-apiserver url, only needed when out of cluster")
 }
 
 func Client() *kubernetes.Clientset {
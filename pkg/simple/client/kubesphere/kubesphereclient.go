@@ -27,7 +27,9 @@ import (
 	"kubesphere.io/kubesphere/pkg/constants"
 	"kubesphere.io/kubesphere/pkg/models"
 	"kubesphere.io/kubesphere/pkg/models/devops"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 )
@@ -47,6 +49,7 @@ type Interface interface {
 	ListUsers() (*models.PageableResponse, error)
 	ListWorkspaceDevOpsProjects(workspace string) (*devops.PageableDevOpsProject, error)
 	DeleteWorkspaceDevOpsProjects(workspace, devops string) error
+	QueryNamespaceLogs(namespace string, values url.Values) (*logging.QueryResult, error)
 }
 
 type client struct {
@@ -331,6 +334,43 @@ func (c client) DeleteWorkspaceDevOpsProjects(workspace, devops string) error {
 	return nil
 }
 
+func (c client) QueryNamespaceLogs(namespace string, values url.Values) (*logging.QueryResult, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/kapis/logging.kubesphere.io/v1alpha2/namespaces/%s?%s", ksAPIServer, namespace, values.Encode()), nil)
+
+	if err != nil {
+		glog.Error(err)
+		return nil, err
+	}
+	req.Header.Add(constants.UserNameHeader, constants.AdminUserName)
+
+	resp, err := c.client.Do(req)
+
+	if err != nil {
+		glog.Error(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		glog.Error(err)
+		return nil, err
+	}
+	if resp.StatusCode > http.StatusOK {
+		glog.Error(req.Method, req.URL, resp.StatusCode, string(data))
+		return nil, Error{resp.StatusCode, string(data)}
+	}
+
+	var result logging.QueryResult
+	err = json.Unmarshal(data, &result)
+
+	if err != nil {
+		glog.Error(err)
+		return nil, err
+	}
+	return &result, nil
+}
+
 func IsNotFound(err error) bool {
 	if e, ok := err.(Error); ok {
 		if e.status == http.StatusNotFound {
@@ -0,0 +1,191 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package logging
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/params"
+)
+
+const (
+	BackendElasticsearch = "elasticsearch"
+	BackendLoki          = "loki"
+	BackendClickHouse    = "clickhouse"
+
+	OperationQuery      = "query"
+	OperationStatistics = "statistics"
+	OperationHistogram  = "histogram"
+	OperationExport     = "export"
+
+	// DefaultInterval of histograms
+	DefaultInterval = "15m"
+)
+
+// BackendType is the store of logs, one of elasticsearch, loki, clickhouse
+var BackendType string
+
+func init() {
+	flag.StringVar(&BackendType, "logging-backend", BackendElasticsearch, "log storage backend, one of elasticsearch, loki, clickhouse")
+}
+
+var ErrNotConfigured = errors.New("Log backend configurations not found. Please check if they are properly configured.")
+
+// ErrQueryRangeTooLong is returned if the time range of the query exceeds the max query length of the backend
+var ErrQueryRangeTooLong = errors.New("time range of the query exceeds the max query length of the log backend")
+
+// Backend stores logs collected from containers
+type Backend interface {
+	// Query performs the query, statistics or histogram operation of the parameters
+	Query(param QueryParameters) *QueryResult
+	// Tail returns the logs after the cursor in ascending order of time and the cursor of the last one,
+	// logs are searched from the start time of the parameters if the cursor is nil
+	Tail(param QueryParameters, cursor []interface{}) ([]LogRecord, []interface{}, error)
+	// Export calls fn with all logs matching the parameters in the order of param.Sort,
+	// exporting is stopped once fn returns an error
+	Export(param QueryParameters, fn func(record LogRecord) error) error
+}
+
+type QueryParameters struct {
+	NamespaceFilled           bool
+	Namespaces                []string
+	NamespaceWithCreationTime map[string]string
+	PodFilled                 bool
+	Pods                      []string
+	ContainerFilled           bool
+	Containers                []string
+
+	NamespaceQuery string
+	PodQuery       string
+	ContainerQuery string
+
+	Workspace string
+
+	// Filter of logs on structured fields, e.g. fields parsed from json logs
+	Filter params.Expression
+
+	Operation string
+	LogQuery  string
+	Interval  string
+	StartTime string
+	EndTime   string
+	Sort      string
+	From      int64
+	Size      int64
+}
+
+type HighLight struct {
+	LogHighLights       []string `json:"log,omitempty" description:"log messages to highlight"`
+	NamespaceHighLights []string `json:"kubernetes.namespace_name.keyword,omitempty" description:"namespaces to highlight"`
+	PodHighLights       []string `json:"kubernetes.pod_name.keyword,omitempty" description:"pods to highlight"`
+	ContainerHighLights []string `json:"kubernetes.container_name.keyword,omitempty" description:"containers to highlight"`
+}
+
+type LogRecord struct {
+	Time      int64     `json:"time,omitempty" description:"log timestamp"`
+	Log       string    `json:"log,omitempty" description:"log message"`
+	Namespace string    `json:"namespace,omitempty" description:"namespace"`
+	Pod       string    `json:"pod,omitempty" description:"pod name"`
+	Container string    `json:"container,omitempty" description:"container name"`
+	Host      string    `json:"host,omitempty" description:"node id"`
+	HighLight HighLight `json:"highlight,omitempty" description:"highlighted log fragment"`
+}
+
+type ReadResult struct {
+	Total   int64       `json:"total" description:"total number of matched results"`
+	From    int64       `json:"from" description:"the offset from the result set"`
+	Size    int64       `json:"size" description:"the amount of hits to be returned"`
+	Records []LogRecord `json:"records,omitempty" description:"actual array of results"`
+}
+
+type HistogramRecord struct {
+	Time  int64 `json:"time" description:"time point"`
+	Count int64 `json:"count" description:"total number of logs at intervals"`
+}
+
+type StatisticsResult struct {
+	Containers int64 `json:"containers" description:"total number of containers"`
+	Logs       int64 `json:"logs" description:"total number of logs"`
+}
+
+type HistogramResult struct {
+	Total      int64             `json:"total" description:"total number of logs"`
+	StartTime  int64             `json:"start_time" description:"start time"`
+	EndTime    int64             `json:"end_time" description:"end time"`
+	Interval   string            `json:"interval" description:"interval"`
+	Histograms []HistogramRecord `json:"histograms" description:"actual array of histogram results"`
+}
+
+// Wrap log backend response
+type QueryResult struct {
+	Status     int               `json:"status,omitempty" description:"query status"`
+	Error      string            `json:"error,omitempty" description:"debug information"`
+	Workspace  string            `json:"workspace,omitempty" description:"workspace the query was performed against"`
+	Read       *ReadResult       `json:"query,omitempty" description:"query results"`
+	Statistics *StatisticsResult `json:"statistics,omitempty" description:"statistics results"`
+	Histogram  *HistogramResult  `json:"histogram,omitempty" description:"histogram results"`
+}
+
+// ParseTime parses the start or end time of queries, in epoch milliseconds or RFC3339
+func ParseTime(value string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)), true
+	}
+
+	return time.Time{}, false
+}
+
+// ParseInterval parses intervals of histograms like 30s, 15m, 1h, 1d, 1w
+func ParseInterval(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("invalid interval %s", interval)
+	}
+
+	n, err := strconv.ParseInt(interval[:len(interval)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid interval %s", interval)
+	}
+
+	switch interval[len(interval)-1] {
+	case 's':
+		return time.Duration(n) * time.Second, nil
+	case 'm':
+		return time.Duration(n) * time.Minute, nil
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unsupported interval %s, the unit must be one of s, m, h, d, w", interval)
+	}
+}
+
+// Millis returns the time in epoch milliseconds
+func Millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
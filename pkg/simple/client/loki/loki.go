@@ -0,0 +1,663 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package loki
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/params"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+// labels of log streams, attached by the Loki output of Fluent Bit
+const (
+	LabelNamespace = "namespace"
+	LabelPod       = "pod"
+	LabelContainer = "container"
+	LabelHost      = "host"
+)
+
+const (
+	// max query length of Loki, logs are searched in the last 30 days by default
+	maxQueryRange   = 720 * time.Hour
+	exportBatchSize = 1000
+)
+
+var lokiServer string
+
+func init() {
+	flag.StringVar(&lokiServer, "loki-server", "http://loki.kubesphere-logging-system.svc:3100", "loki server address, used if the logging backend is loki")
+}
+
+type backend struct{}
+
+// Backend queries logs stored in Loki with LogQL
+var Backend logging.Backend = backend{}
+
+type entry struct {
+	timestamp int64
+	labels    map[string]string
+	line      string
+}
+
+func (e entry) record() logging.LogRecord {
+	return logging.LogRecord{
+		Time:      e.timestamp / int64(time.Millisecond),
+		Log:       e.line,
+		Namespace: e.labels[LabelNamespace],
+		Pod:       e.labels[LabelPod],
+		Container: e.labels[LabelContainer],
+		Host:      e.labels[LabelHost],
+	}
+}
+
+func (backend) Query(param logging.QueryParameters) *logging.QueryResult {
+	result, err := query(param)
+	if err == logging.ErrQueryRangeTooLong {
+		return &logging.QueryResult{Status: http.StatusBadRequest, Error: err.Error()}
+	} else if err != nil {
+		return &logging.QueryResult{Status: http.StatusInternalServerError, Error: err.Error()}
+	}
+	result.Status = http.StatusOK
+	result.Workspace = param.Workspace
+	return result
+}
+
+func query(param logging.QueryParameters) (*logging.QueryResult, error) {
+	selector, ok := streamSelector(param)
+	pipeline, err := pipeline(param)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := timeRange(param)
+	if err != nil {
+		return nil, err
+	}
+
+	switch param.Operation {
+	case logging.OperationStatistics:
+		if !ok {
+			return &logging.QueryResult{Statistics: &logging.StatisticsResult{}}, nil
+		}
+		logs, err := instantQuery(fmt.Sprintf("sum(count_over_time(%s%s [%ds]))", selector, pipeline, rangeSeconds(start, end)), end)
+		if err != nil {
+			return nil, err
+		}
+		containers, err := instantQuery(fmt.Sprintf("count(sum by (%s, %s, %s) (count_over_time(%s%s [%ds])))",
+			LabelNamespace, LabelPod, LabelContainer, selector, pipeline, rangeSeconds(start, end)), end)
+		if err != nil {
+			return nil, err
+		}
+		return &logging.QueryResult{Statistics: &logging.StatisticsResult{Logs: logs, Containers: containers}}, nil
+	case logging.OperationHistogram:
+		interval := param.Interval
+		if interval == "" {
+			interval = logging.DefaultInterval
+		}
+		step, err := logging.ParseInterval(interval)
+		if err != nil {
+			return nil, err
+		}
+		histogram := &logging.HistogramResult{StartTime: logging.Millis(start), EndTime: logging.Millis(end), Interval: interval, Histograms: []logging.HistogramRecord{}}
+		if !ok {
+			return &logging.QueryResult{Histogram: histogram}, nil
+		}
+		// points count logs in the preceding interval, which are the buckets starting at t - interval
+		points, err := rangeMetricQuery(fmt.Sprintf("sum(count_over_time(%s%s [%ds]))", selector, pipeline, int64(step/time.Second)), start.Truncate(step).Add(step), end, step)
+		if err != nil {
+			return nil, err
+		}
+		for _, point := range points {
+			histogram.Total += point.count
+			histogram.Histograms = append(histogram.Histograms, logging.HistogramRecord{Time: logging.Millis(point.time.Add(-step)), Count: point.count})
+		}
+		return &logging.QueryResult{Histogram: histogram}, nil
+	default:
+		read := &logging.ReadResult{From: param.From, Size: param.Size}
+		if !ok {
+			return &logging.QueryResult{Read: read}, nil
+		}
+		total, err := instantQuery(fmt.Sprintf("sum(count_over_time(%s%s [%ds]))", selector, pipeline, rangeSeconds(start, end)), end)
+		if err != nil {
+			return nil, err
+		}
+		read.Total = total
+		// Loki doesn't support offsets, logs before from are skipped
+		entries, err := queryRange(selector+pipeline, start, end, param.From+param.Size, direction(param.Sort))
+		if err != nil {
+			return nil, err
+		}
+		for i := param.From; i < int64(len(entries)); i++ {
+			read.Records = append(read.Records, entries[i].record())
+		}
+		return &logging.QueryResult{Read: read}, nil
+	}
+}
+
+func (backend) Tail(param logging.QueryParameters, cursor []interface{}) ([]logging.LogRecord, []interface{}, error) {
+	selector, ok := streamSelector(param)
+	if !ok {
+		return nil, cursor, nil
+	}
+
+	pipeline, err := pipeline(param)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	// followed logs are caught up from the max query length if they started earlier
+	start, _, err := timeRange(param)
+	if err == logging.ErrQueryRangeTooLong {
+		start = time.Now().Add(-maxQueryRange)
+	}
+	if len(cursor) == 1 {
+		if last, ok := cursor[0].(int64); ok {
+			start = time.Unix(0, last+1)
+		}
+	}
+
+	entries, err := queryRange(selector+pipeline, start, time.Now(), param.Size, "forward")
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	records := make([]logging.LogRecord, 0, len(entries))
+	for _, e := range entries {
+		records = append(records, e.record())
+		cursor = []interface{}{e.timestamp}
+	}
+
+	return records, cursor, nil
+}
+
+func (backend) Export(param logging.QueryParameters, fn func(record logging.LogRecord) error) error {
+	selector, ok := streamSelector(param)
+	if !ok {
+		return nil
+	}
+
+	pipeline, err := pipeline(param)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := timeRange(param)
+	if err != nil {
+		return err
+	}
+	direction := direction(param.Sort)
+
+	// logs at the boundary of batches are returned again by the next query
+	var boundary int64
+	exported := make(map[string]bool)
+
+	for {
+		entries, err := queryRange(selector+pipeline, start, end, exportBatchSize, direction)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for _, e := range entries {
+			key := fmt.Sprintf("%v%s", e.labels, e.line)
+			if e.timestamp == boundary && exported[key] {
+				continue
+			}
+			if e.timestamp != boundary {
+				boundary = e.timestamp
+				exported = make(map[string]bool)
+			}
+			exported[key] = true
+			count++
+
+			if err := fn(e.record()); err != nil {
+				return err
+			}
+		}
+
+		if count == 0 || len(entries) < exportBatchSize {
+			return nil
+		}
+
+		if direction == "forward" {
+			start = time.Unix(0, boundary)
+		} else {
+			end = time.Unix(0, boundary)
+		}
+	}
+}
+
+func direction(sort string) string {
+	if strings.ToLower(sort) == "asc" {
+		return "forward"
+	}
+	return "backward"
+}
+
+// timeRange returns the time range of the query, logs of namespaces before their creation are
+// not returned in case namespaces are recreated. Logs are searched in the max query length before
+// the end if the start isn't given, ErrQueryRangeTooLong is returned if the given range is longer
+func timeRange(param logging.QueryParameters) (time.Time, time.Time, error) {
+	end, ok := logging.ParseTime(param.EndTime)
+	if !ok {
+		end = time.Now()
+	}
+
+	start, ok := logging.ParseTime(param.StartTime)
+	if !ok {
+		start = end.Add(-maxQueryRange)
+	} else if end.Sub(start) > maxQueryRange {
+		return start, end, logging.ErrQueryRangeTooLong
+	}
+
+	if param.NamespaceFilled && len(param.NamespaceWithCreationTime) > 0 {
+		var earliest time.Time
+		for _, creationTime := range param.NamespaceWithCreationTime {
+			if t, ok := logging.ParseTime(creationTime); ok && (earliest.IsZero() || t.Before(earliest)) {
+				earliest = t
+			}
+		}
+		if earliest.After(start) {
+			start = earliest
+		}
+	}
+
+	return start, end, nil
+}
+
+func rangeSeconds(start, end time.Time) int64 {
+	seconds := int64(end.Sub(start) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// streamSelector returns the selector of log streams, ok is false if no streams are matched
+func streamSelector(param logging.QueryParameters) (string, bool) {
+	matchers := make([]string, 0)
+
+	if param.NamespaceFilled {
+		namespaces := make([]string, 0, len(param.NamespaceWithCreationTime))
+		for namespace := range param.NamespaceWithCreationTime {
+			namespaces = append(namespaces, namespace)
+		}
+		if len(namespaces) == 0 {
+			return "", false
+		}
+		sort.Strings(namespaces)
+		matchers = append(matchers, matchAny(LabelNamespace, namespaces))
+	} else {
+		// at least one matcher must not match empty values
+		matchers = append(matchers, LabelNamespace+`=~".+"`)
+	}
+
+	if param.PodFilled {
+		if len(param.Pods) == 0 {
+			return "", false
+		}
+		matchers = append(matchers, matchAny(LabelPod, param.Pods))
+	}
+
+	if param.ContainerFilled {
+		if len(param.Containers) == 0 {
+			return "", false
+		}
+		matchers = append(matchers, matchAny(LabelContainer, param.Containers))
+	}
+
+	for label, keywords := range map[string]string{LabelNamespace: param.NamespaceQuery, LabelPod: param.PodQuery, LabelContainer: param.ContainerQuery} {
+		if keywords != "" {
+			matchers = append(matchers, fmt.Sprintf("%s=~%s", label, strconv.Quote("(?i).*("+keywordsRegex(keywords)+").*")))
+		}
+	}
+
+	sort.Strings(matchers[1:])
+
+	return "{" + strings.Join(matchers, ", ") + "}", true
+}
+
+func matchAny(label string, values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, regexp.QuoteMeta(value))
+	}
+	return fmt.Sprintf("%s=~%s", label, strconv.Quote(strings.Join(quoted, "|")))
+}
+
+// keywordsRegex matches any of the keywords separated by comma or space
+func keywordsRegex(keywords string) string {
+	words := make([]string, 0)
+	for _, word := range strings.Fields(strings.Replace(keywords, ",", " ", -1)) {
+		words = append(words, regexp.QuoteMeta(word))
+	}
+	return strings.Join(words, "|")
+}
+
+// pipeline filters log lines by keywords and structured fields parsed from json logs
+func pipeline(param logging.QueryParameters) (string, error) {
+	var pipeline strings.Builder
+
+	if param.LogQuery != "" {
+		fmt.Fprintf(&pipeline, " |~ %s", strconv.Quote("(?i)("+keywordsRegex(param.LogQuery)+")"))
+	}
+
+	if param.Filter != nil {
+		filter, err := labelFilter(param.Filter, false)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&pipeline, " | json | %s", filter)
+	}
+
+	return pipeline.String(), nil
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// label returns the label extracted by the json parser, nested fields are joined by _
+func label(field string) (string, error) {
+	switch field {
+	case "namespace":
+		return LabelNamespace, nil
+	case "pod":
+		return LabelPod, nil
+	case "container":
+		return LabelContainer, nil
+	case "host":
+		return LabelHost, nil
+	case "log":
+		return "", fmt.Errorf("log messages can't be filtered by fields in loki, use log_query instead")
+	}
+	return invalidLabelChars.ReplaceAllString(strings.Join(params.SplitFieldPath(field), "_"), "_"), nil
+}
+
+var negatedOperators = map[params.Operator]params.Operator{
+	params.OperatorEqual:          params.OperatorNotEqual,
+	params.OperatorNotEqual:       params.OperatorEqual,
+	params.OperatorContains:       params.OperatorNotContains,
+	params.OperatorNotContains:    params.OperatorContains,
+	params.OperatorIn:             params.OperatorNotIn,
+	params.OperatorNotIn:          params.OperatorIn,
+	params.OperatorGreaterThan:    params.OperatorLessOrEqual,
+	params.OperatorLessOrEqual:    params.OperatorGreaterThan,
+	params.OperatorGreaterOrEqual: params.OperatorLessThan,
+	params.OperatorLessThan:       params.OperatorGreaterOrEqual,
+}
+
+// labelFilter compiles the filter expression to LogQL label filters, which don't support NOT,
+// negations are pushed down to comparisons
+func labelFilter(expr params.Expression, negate bool) (string, error) {
+	switch e := expr.(type) {
+	case *params.AndExpression, *params.OrExpression:
+		var left, right params.Expression
+		operator := "and"
+		if and, ok := e.(*params.AndExpression); ok {
+			left, right = and.Left, and.Right
+		} else {
+			or := e.(*params.OrExpression)
+			left, right, operator = or.Left, or.Right, "or"
+		}
+		if negate {
+			operator = map[string]string{"and": "or", "or": "and"}[operator]
+		}
+		l, err := labelFilter(left, negate)
+		if err != nil {
+			return "", err
+		}
+		r, err := labelFilter(right, negate)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s %s %s)", l, operator, r), nil
+	case *params.NotExpression:
+		return labelFilter(e.Expression, !negate)
+	case *params.Comparison:
+		return comparisonFilter(e, negate)
+	default:
+		return "", fmt.Errorf("unsupported filter expression %T", expr)
+	}
+}
+
+func comparisonFilter(c *params.Comparison, negate bool) (string, error) {
+	name, err := label(c.Field)
+	if err != nil {
+		return "", err
+	}
+
+	operator := c.Operator
+	if negate {
+		switch operator {
+		case params.OperatorExists:
+			return fmt.Sprintf(`%s=""`, name), nil
+		case params.OperatorRegex:
+			return fmt.Sprintf("%s!~%s", name, strconv.Quote(anchoredRegex(c.Values[0]))), nil
+		}
+		operator = negatedOperators[operator]
+	}
+
+	switch operator {
+	case params.OperatorExists:
+		return fmt.Sprintf(`%s!=""`, name), nil
+	case params.OperatorEqual:
+		return fmt.Sprintf("%s=%s", name, strconv.Quote(c.Values[0])), nil
+	case params.OperatorNotEqual:
+		return fmt.Sprintf("%s!=%s", name, strconv.Quote(c.Values[0])), nil
+	case params.OperatorIn, params.OperatorNotIn:
+		filters := make([]string, 0, len(c.Values))
+		for _, value := range c.Values {
+			if operator == params.OperatorIn {
+				filters = append(filters, fmt.Sprintf("%s=%s", name, strconv.Quote(value)))
+			} else {
+				filters = append(filters, fmt.Sprintf("%s!=%s", name, strconv.Quote(value)))
+			}
+		}
+		if operator == params.OperatorIn {
+			return "(" + strings.Join(filters, " or ") + ")", nil
+		}
+		return "(" + strings.Join(filters, " and ") + ")", nil
+	case params.OperatorContains:
+		return fmt.Sprintf("%s=~%s", name, strconv.Quote(".*"+regexp.QuoteMeta(c.Values[0])+".*")), nil
+	case params.OperatorNotContains:
+		return fmt.Sprintf("%s!~%s", name, strconv.Quote(".*"+regexp.QuoteMeta(c.Values[0])+".*")), nil
+	case params.OperatorRegex:
+		return fmt.Sprintf("%s=~%s", name, strconv.Quote(anchoredRegex(c.Values[0]))), nil
+	case params.OperatorGreaterThan, params.OperatorGreaterOrEqual, params.OperatorLessThan, params.OperatorLessOrEqual:
+		if _, err := strconv.ParseFloat(c.Values[0], 64); err != nil {
+			return "", fmt.Errorf("%s must be compared with numbers in loki", c.Field)
+		}
+		return fmt.Sprintf("%s%s%s", name, operator, c.Values[0]), nil
+	default:
+		return "", fmt.Errorf("unsupported operator %s", c.Operator)
+	}
+}
+
+// anchoredRegex converts the unanchored regular expression to the anchored regular expression of LogQL
+func anchoredRegex(pattern string) string {
+	if strings.HasPrefix(pattern, "^") {
+		pattern = strings.TrimPrefix(pattern, "^")
+	} else {
+		pattern = ".*" + pattern
+	}
+	if strings.HasSuffix(pattern, "$") {
+		pattern = strings.TrimSuffix(pattern, "$")
+	} else {
+		pattern = pattern + ".*"
+	}
+	return pattern
+}
+
+// Fore more info, refer to https://grafana.com/docs/loki/latest/api/
+type response struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type sample struct {
+	Value [2]interface{} `json:"value"`
+}
+
+type series struct {
+	Values [][2]interface{} `json:"values"`
+}
+
+type point struct {
+	time  time.Time
+	count int64
+}
+
+func get(path string, values url.Values) (*response, error) {
+	resp, err := http.Get(strings.TrimSuffix(lokiServer, "/") + path + "?" + values.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("loki returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result response
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// queryRange returns the log entries sorted in the direction
+func queryRange(query string, start, end time.Time, limit int64, direction string) ([]entry, error) {
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	values.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	values.Set("limit", strconv.FormatInt(limit, 10))
+	values.Set("direction", direction)
+
+	result, err := get("/loki/api/v1/query_range", values)
+	if err != nil {
+		return nil, err
+	}
+
+	var streams []stream
+	if err := json.Unmarshal(result.Data.Result, &streams); err != nil {
+		return nil, err
+	}
+
+	entries := make([]entry, 0)
+	for _, s := range streams {
+		for _, value := range s.Values {
+			timestamp, _ := strconv.ParseInt(value[0], 10, 64)
+			entries = append(entries, entry{timestamp: timestamp, labels: s.Stream, line: value[1]})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if direction == "forward" {
+			return entries[i].timestamp < entries[j].timestamp
+		}
+		return entries[i].timestamp > entries[j].timestamp
+	})
+
+	if int64(len(entries)) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries, nil
+}
+
+// instantQuery returns the value of the metric query returning a single sample
+func instantQuery(query string, t time.Time) (int64, error) {
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("time", strconv.FormatInt(t.UnixNano(), 10))
+
+	result, err := get("/loki/api/v1/query", values)
+	if err != nil {
+		return 0, err
+	}
+
+	var samples []sample
+	if err := json.Unmarshal(result.Data.Result, &samples); err != nil {
+		return 0, err
+	}
+
+	if len(samples) == 0 {
+		return 0, nil
+	}
+
+	return parseCount(samples[0].Value[1]), nil
+}
+
+func rangeMetricQuery(query string, start, end time.Time, step time.Duration) ([]point, error) {
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	values.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	values.Set("step", strconv.FormatInt(int64(step/time.Second), 10))
+
+	result, err := get("/loki/api/v1/query_range", values)
+	if err != nil {
+		return nil, err
+	}
+
+	var matrix []series
+	if err := json.Unmarshal(result.Data.Result, &matrix); err != nil {
+		return nil, err
+	}
+
+	points := make([]point, 0)
+	if len(matrix) > 0 {
+		for _, value := range matrix[0].Values {
+			seconds, _ := value[0].(float64)
+			points = append(points, point{time: time.Unix(int64(seconds), 0), count: parseCount(value[1])})
+		}
+	}
+
+	return points, nil
+}
+
+func parseCount(value interface{}) int64 {
+	s, _ := value.(string)
+	count, _ := strconv.ParseFloat(s, 64)
+	return int64(count)
+}
@@ -0,0 +1,183 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package loki
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/params"
+	"kubesphere.io/kubesphere/pkg/simple/client/logging"
+)
+
+func TestLabelFilter(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected string
+	}{
+		{`level=error`, `level="error"`},
+		{`http.status>=500 AND NOT level=debug`, `(http_status>=500 and level!="debug")`},
+		{`NOT (level in (error,warn) OR trace_id)`, `((level!="error" and level!="warn") and trace_id="")`},
+		{`msg~"a.b"`, `msg=~".*a\\.b.*"`},
+		{`trace_id=~"^a1"`, `trace_id=~"a1.*"`},
+		{`namespace=kube-system`, `namespace="kube-system"`},
+	}
+
+	for _, test := range tests {
+		expr, err := params.ParseFilter(test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filter, err := labelFilter(expr, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filter != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.filter, test.expected, filter)
+		}
+	}
+
+	expr, _ := params.ParseFilter(`log~error`)
+	if _, err := labelFilter(expr, false); err == nil {
+		t.Errorf("filters on log messages should be rejected")
+	}
+}
+
+func TestStreamSelector(t *testing.T) {
+	selector, ok := streamSelector(logging.QueryParameters{
+		NamespaceFilled:           true,
+		NamespaceWithCreationTime: map[string]string{"default": "0", "kube-system": "0"},
+		ContainerFilled:           true,
+		Containers:                []string{"nginx"},
+		PodQuery:                  "web,api",
+	})
+	expected := `{namespace=~"default|kube-system", container=~"nginx", pod=~"(?i).*(web|api).*"}`
+	if !ok || selector != expected {
+		t.Errorf("expected %s, got %s", expected, selector)
+	}
+
+	if _, ok := streamSelector(logging.QueryParameters{PodFilled: true}); ok {
+		t.Errorf("no streams should be matched if pods are filled but empty")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	var queries []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Path+" "+r.URL.Query().Get("query"))
+
+		switch r.URL.Path {
+		case "/loki/api/v1/query":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1559347200,"3"]}]}}`)
+		case "/loki/api/v1/query_range":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"streams","result":[
+{"stream":{"namespace":"default","pod":"nginx","container":"nginx"},"values":[["1559347202000000000","third"],["1559347200000000000","first"]]},
+{"stream":{"namespace":"default","pod":"redis","container":"redis"},"values":[["1559347201000000000","second"]]}]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(server string) { lokiServer = server }(lokiServer)
+	lokiServer = server.URL
+
+	filter, _ := params.ParseFilter(`level=error`)
+	result := Backend.Query(logging.QueryParameters{
+		Operation: logging.OperationQuery,
+		LogQuery:  "timeout",
+		Filter:    filter,
+		From:      1,
+		Size:      10,
+	})
+
+	if result.Status != http.StatusOK {
+		t.Fatalf("unexpected error %s", result.Error)
+	}
+
+	if result.Read.Total != 3 || len(result.Read.Records) != 2 || result.Read.Records[0].Log != "second" || result.Read.Records[1].Pod != "nginx" {
+		t.Errorf("unexpected result %+v", result.Read)
+	}
+
+	expected := `/loki/api/v1/query_range {namespace=~".+"} |~ "(?i)(timeout)" | json | level="error"`
+	if queries[1] != expected {
+		t.Errorf("expected %s, got %s", expected, queries[1])
+	}
+
+	records, cursor, err := Backend.Tail(logging.QueryParameters{Size: 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 3 || records[0].Log != "first" || cursor[0] != int64(1559347202000000000) {
+		t.Errorf("unexpected records %+v, cursor %v", records, cursor)
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	tests := []struct {
+		param logging.QueryParameters
+		start string
+		end   string
+		err   error
+	}{
+		{
+			param: logging.QueryParameters{EndTime: "2019-07-01T00:00:00Z"},
+			start: "2019-06-01T00:00:00Z",
+			end:   "2019-07-01T00:00:00Z",
+		},
+		{
+			param: logging.QueryParameters{StartTime: "2019-06-01T00:00:00Z", EndTime: "2019-07-01T00:00:00Z"},
+			start: "2019-06-01T00:00:00Z",
+			end:   "2019-07-01T00:00:00Z",
+		},
+		{
+			param: logging.QueryParameters{StartTime: "2019-05-31T23:59:59Z", EndTime: "2019-07-01T00:00:00Z"},
+			err:   logging.ErrQueryRangeTooLong,
+		},
+		{
+			param: logging.QueryParameters{StartTime: "2019-06-01T00:00:00Z", EndTime: "2019-07-01T00:00:00Z",
+				NamespaceFilled: true, NamespaceWithCreationTime: map[string]string{"default": "2019-06-15T00:00:00Z"}},
+			start: "2019-06-15T00:00:00Z",
+			end:   "2019-07-01T00:00:00Z",
+		},
+	}
+
+	for i, test := range tests {
+		start, end, err := timeRange(test.param)
+		if err != test.err {
+			t.Errorf("case %d: expected error %v, got %v", i, test.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if start.UTC().Format(time.RFC3339) != test.start || end.UTC().Format(time.RFC3339) != test.end {
+			t.Errorf("case %d: expected %s - %s, got %s - %s", i, test.start, test.end, start, end)
+		}
+	}
+
+	result := Backend.Query(logging.QueryParameters{StartTime: "2019-05-01T00:00:00Z", EndTime: "2019-07-01T00:00:00Z"})
+	if result.Status != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, result.Status)
+	}
+}
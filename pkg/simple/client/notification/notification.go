@@ -0,0 +1,185 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	smtpServer   string
+	smtpFrom     string
+	smtpUsername string
+	smtpPassword string
+
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+func init() {
+	flag.StringVar(&smtpServer, "smtp-server", "", "smtp server address of email notifications, e.g. smtp.example.com:25")
+	flag.StringVar(&smtpFrom, "smtp-from", "", "sender of email notifications")
+	flag.StringVar(&smtpUsername, "smtp-username", "", "smtp username, plain auth is used if set")
+	flag.StringVar(&smtpPassword, "smtp-password", "", "smtp password")
+}
+
+// Notification is the message sent to receivers
+type Notification interface {
+	// Title is the subject of emails and the headline of chat messages
+	Title() string
+	// Text is the body of the message
+	Text() string
+	// Timestamp is when the notified event happened
+	Timestamp() time.Time
+}
+
+// Alert is sent to receivers when alerting rules fire or resolve
+type Alert struct {
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace,omitempty"`
+	Severity  string    `json:"severity"`
+	State     string    `json:"state"`
+	Summary   string    `json:"summary"`
+	Time      time.Time `json:"time"`
+}
+
+func (a Alert) Title() string {
+	name := a.Name
+	if a.Namespace != "" {
+		name = a.Namespace + "/" + a.Name
+	}
+	return fmt.Sprintf("[%s] %s %s", strings.ToUpper(a.Severity), name, a.State)
+}
+
+func (a Alert) Text() string {
+	return a.Summary
+}
+
+func (a Alert) Timestamp() time.Time {
+	return a.Time
+}
+
+// Notifier sends notifications to a receiver
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+type webhook struct {
+	url string
+}
+
+// NewWebhook returns the notifier posting notifications as json to the url
+func NewWebhook(url string) Notifier {
+	return &webhook{url: url}
+}
+
+func (w *webhook) Notify(n Notification) error {
+	return postJSON(w.url, n)
+}
+
+type slack struct {
+	url     string
+	channel string
+}
+
+// NewSlack returns the notifier posting notifications to the incoming webhook of Slack, the channel of
+// the webhook is used if channel is empty
+func NewSlack(url, channel string) Notifier {
+	return &slack{url: url, channel: channel}
+}
+
+func (s *slack) Notify(n Notification) error {
+	message := map[string]interface{}{
+		"text": fmt.Sprintf("*%s*\n%s", n.Title(), n.Text()),
+	}
+	if s.channel != "" {
+		message["channel"] = s.channel
+	}
+	return postJSON(s.url, message)
+}
+
+type email struct {
+	to []string
+}
+
+// NewEmail returns the notifier sending notifications by the smtp server configured by flags
+func NewEmail(to []string) Notifier {
+	return &email{to: to}
+}
+
+func (e *email) Notify(n Notification) error {
+	if smtpServer == "" {
+		return fmt.Errorf("smtp server is not configured")
+	}
+
+	var auth smtp.Auth
+	if smtpUsername != "" {
+		host, _, err := net.SplitHostPort(smtpServer)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", smtpUsername, smtpPassword, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", smtpFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", n.Title())
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\nTime: %s\r\n", n.Text(), n.Timestamp().Format(time.RFC3339))
+
+	return smtp.SendMail(smtpServer, auth, smtpFrom, e.to, msg.Bytes())
+}
+
+// ValidateURL checks that the url of webhook and slack receivers is an http or https url with the host
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url [%s]", rawURL)
+	}
+	return nil
+}
+
+// postJSON posts the body to the url, responses are not included in errors as the errors are
+// reported to the users configuring the receivers
+func postJSON(url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+
+	return nil
+}
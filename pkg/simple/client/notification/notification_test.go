@@ -0,0 +1,88 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package notification
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	var bodies []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body := make(map[string]interface{})
+		json.Unmarshal(data, &body)
+		bodies = append(bodies, body)
+	}))
+	defer server.Close()
+
+	alert := Alert{Name: "nginx-errors", Namespace: "default", Severity: "critical", State: "firing", Summary: "120 logs matched in the last 5m0s", Time: time.Now()}
+
+	if err := NewWebhook(server.URL).Notify(alert); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewSlack(server.URL, "#ops").Notify(alert); err != nil {
+		t.Fatal(err)
+	}
+
+	if bodies[0]["name"] != "nginx-errors" || bodies[0]["state"] != "firing" {
+		t.Errorf("unexpected webhook body %v", bodies[0])
+	}
+
+	if bodies[1]["text"] != "*[CRITICAL] default/nginx-errors firing*\n120 logs matched in the last 5m0s" || bodies[1]["channel"] != "#ops" {
+		t.Errorf("unexpected slack body %v", bodies[1])
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	// responses aren't included in the errors reported to the users
+	if err := NewWebhook(failing.URL).Notify(alert); err == nil {
+		t.Errorf("expected errors of failed requests")
+	} else if strings.Contains(err.Error(), "unavailable") {
+		t.Errorf("unexpected response in error %v", err)
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://hooks.slack.com/services/T0/B0/X": true,
+		"http://alertmanager:9093/api/v1/alerts":   true,
+		"file:///etc/passwd":                       false,
+		"gopher://redis:6379/_FLUSHALL":            false,
+		"http:///path":                             false,
+		"alertmanager:9093":                        false,
+		"":                                         false,
+	}
+
+	for rawURL, valid := range tests {
+		if err := ValidateURL(rawURL); (err == nil) != valid {
+			t.Errorf("%s: expected valid %v, got %v", rawURL, valid, err)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	ResultTypeVector = "vector"
+	ResultTypeMatrix = "matrix"
+	ResultTypeScalar = "scalar"
+	ResultTypeString = "string"
+
+	// error types of the Prometheus api, and of failures to reach Prometheus
+	ErrorTypeBadData     = "bad_data"
+	ErrorTypeTimeout     = "timeout"
+	ErrorTypeCanceled    = "canceled"
+	ErrorTypeExecution   = "execution"
+	ErrorTypeInternal    = "internal"
+	ErrorTypeUnavailable = "unavailable"
+	ErrorTypeNotFound    = "not_found"
+)
+
+// Error is returned if the query fails, Type is one of the error types
+type Error struct {
+	Type    string `json:"errorType"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return e.Type + ": " + e.Message
+}
+
+// HTTPStatus returns the status code responding to requests failed by errors of the type
+func HTTPStatus(errorType string) int {
+	switch errorType {
+	case ErrorTypeBadData:
+		return http.StatusBadRequest
+	case ErrorTypeExecution:
+		return http.StatusUnprocessableEntity
+	case ErrorTypeTimeout:
+		return http.StatusGatewayTimeout
+	case ErrorTypeUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrorTypeNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Point is a value at a time, it's encoded as [timestamp, "value"] as Prometheus does
+type Point struct {
+	Timestamp float64
+	Value     string
+}
+
+func (p *Point) UnmarshalJSON(data []byte) error {
+	var raw []interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 2 {
+		return fmt.Errorf("invalid point %s", data)
+	}
+
+	var ok bool
+	if p.Timestamp, ok = raw[0].(float64); !ok {
+		return fmt.Errorf("invalid timestamp of point %s", data)
+	}
+	if p.Value, ok = raw[1].(string); !ok {
+		return fmt.Errorf("invalid value of point %s", data)
+	}
+	return nil
+}
+
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{p.Timestamp, p.Value})
+}
+
+// Sample is a series of vector or matrix results, Value is set for vectors and Values for matrices
+type Sample struct {
+	Metric map[string]string `json:"metric"`
+	Value  *Point            `json:"value,omitempty"`
+	Values []Point           `json:"values,omitempty"`
+}
+
+type QueryResult struct {
+	ResultType string
+
+	// Samples of vector or matrix results
+	Samples []Sample
+
+	// Scalar is the value of scalar or string results
+	Scalar *Point
+}
+
+func (r *QueryResult) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	r.ResultType = raw.ResultType
+
+	switch raw.ResultType {
+	case ResultTypeVector, ResultTypeMatrix:
+		return json.Unmarshal(raw.Result, &r.Samples)
+	case ResultTypeScalar, ResultTypeString:
+		r.Scalar = &Point{}
+		return json.Unmarshal(raw.Result, r.Scalar)
+	default:
+		return fmt.Errorf("unknown result type %s", raw.ResultType)
+	}
+}
+
+type Range struct {
+	Start time.Time
+	End   time.Time
+	Step  time.Duration
+}
+
+// Client queries the Prometheus http api. Queries in flight are limited by the client and by the context they're
+// sent with, successful results are cached by the query, time and step for a while.
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	semaphore  chan struct{}
+	cache      *resultCache
+}
+
+// NewClient returns a client of the api endpoint, e.g. http://prometheus:9090/api/v1/, results aren't cached if
+// cacheTTL is 0
+func NewClient(endpoint string, maxConcurrentQueries int, cacheTTL time.Duration) *Client {
+	if maxConcurrentQueries <= 0 {
+		maxConcurrentQueries = 1
+	}
+	c := &Client{
+		endpoint:   strings.TrimSuffix(endpoint, "/") + "/",
+		httpClient: &http.Client{},
+		semaphore:  make(chan struct{}, maxConcurrentQueries),
+	}
+	if cacheTTL > 0 {
+		c.cache = newResultCache(cacheTTL, maxCachedResults)
+	}
+	return c
+}
+
+// Query evaluates the instant query at the time, at the current time if it's zero
+func (c *Client) Query(ctx context.Context, query string, ts time.Time) (*QueryResult, error) {
+	values := url.Values{"query": []string{query}}
+	if !ts.IsZero() {
+		values.Set("time", formatTime(ts))
+	}
+	result := &QueryResult{}
+	return result, c.get(ctx, "query", values, result, true)
+}
+
+// QueryRange evaluates the query over the range of time
+func (c *Client) QueryRange(ctx context.Context, query string, r Range) (*QueryResult, error) {
+	if r.Step <= 0 {
+		return nil, &Error{Type: ErrorTypeBadData, Message: "step must be positive"}
+	}
+	values := url.Values{
+		"query": []string{query},
+		"start": []string{formatTime(r.Start)},
+		"end":   []string{formatTime(r.End)},
+		"step":  []string{strconv.FormatFloat(r.Step.Seconds(), 'f', -1, 64)},
+	}
+	result := &QueryResult{}
+	return result, c.get(ctx, "query_range", values, result, true)
+}
+
+// Series returns label sets of series matching any of the selectors between start and end
+func (c *Client) Series(ctx context.Context, matchers []string, start, end time.Time) ([]map[string]string, error) {
+	values := url.Values{"match[]": matchers}
+	if !start.IsZero() {
+		values.Set("start", formatTime(start))
+	}
+	if !end.IsZero() {
+		values.Set("end", formatTime(end))
+	}
+	var series []map[string]string
+	return series, c.get(ctx, "series", values, &series, false)
+}
+
+// LabelValues returns all values of the label
+func (c *Client) LabelValues(ctx context.Context, label string) ([]string, error) {
+	var labelValues []string
+	return labelValues, c.get(ctx, "label/"+url.PathEscape(label)+"/values", nil, &labelValues, false)
+}
+
+// QueryParams evaluates the query of the params of monitoring requests, it's a range query of the start, end and
+// step of the params if queryType is RangeQueryType, otherwise an instant query at the time of the params.
+// The timeout of the params is applied to the query.
+func (c *Client) QueryParams(ctx context.Context, queryType string, params url.Values) (*QueryResult, error) {
+	if timeout, err := time.ParseDuration(params.Get("timeout")); err == nil && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(contextOrBackground(ctx), timeout)
+		defer cancel()
+	}
+
+	query := params.Get("query")
+
+	if queryType == RangeQueryType {
+		start, err := parseTime(params.Get("start"))
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseTime(params.Get("end"))
+		if err != nil {
+			return nil, err
+		}
+		step, err := parseDuration(params.Get("step"))
+		if err != nil {
+			return nil, err
+		}
+		return c.QueryRange(ctx, query, Range{Start: start, End: end, Step: step})
+	}
+
+	var ts time.Time
+	if s := params.Get("time"); s != "" {
+		var err error
+		if ts, err = parseTime(s); err != nil {
+			return nil, err
+		}
+	}
+	return c.Query(ctx, query, ts)
+}
+
+// get requests the api at the path and decodes the data of the response into result
+func (c *Client) get(ctx context.Context, path string, values url.Values, result interface{}, cacheable bool) error {
+	ctx = contextOrBackground(ctx)
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, queryTimeout)
+		defer cancel()
+	}
+
+	u := c.endpoint + path
+	if len(values) > 0 {
+		u += "?" + values.Encode()
+	}
+
+	if cacheable && c.cache != nil {
+		if data, ok := c.cache.get(u); ok {
+			return json.Unmarshal(data, result)
+		}
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return &Error{Type: ErrorTypeBadData, Message: err.Error()}
+	}
+
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+
+	var response struct {
+		Status    string          `json:"status"`
+		Data      json.RawMessage `json:"data"`
+		ErrorType string          `json:"errorType"`
+		Error     string          `json:"error"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		if resp.StatusCode >= http.StatusBadRequest {
+			return &Error{Type: ErrorTypeUnavailable, Message: fmt.Sprintf("prometheus returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))}
+		}
+		return &Error{Type: ErrorTypeInternal, Message: "invalid response of prometheus: " + err.Error()}
+	}
+
+	if response.Status != "success" {
+		errorType := response.ErrorType
+		if errorType == "" {
+			errorType = ErrorTypeInternal
+		}
+		return &Error{Type: errorType, Message: response.Error}
+	}
+
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		return &Error{Type: ErrorTypeInternal, Message: "invalid response of prometheus: " + err.Error()}
+	}
+
+	if cacheable && c.cache != nil {
+		c.cache.set(u, response.Data)
+	}
+
+	return nil
+}
+
+// acquire waits for a slot of the client and the context, the returned function releases the slots
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	limiter, _ := ctx.Value(limiterKey{}).(chan struct{})
+
+	if limiter != nil {
+		select {
+		case limiter <- struct{}{}:
+		case <-ctx.Done():
+			return nil, contextError(ctx, ctx.Err())
+		}
+	}
+
+	select {
+	case c.semaphore <- struct{}{}:
+	case <-ctx.Done():
+		if limiter != nil {
+			<-limiter
+		}
+		return nil, contextError(ctx, ctx.Err())
+	}
+
+	return func() {
+		<-c.semaphore
+		if limiter != nil {
+			<-limiter
+		}
+	}, nil
+}
+
+type limiterKey struct{}
+
+// WithConcurrencyLimit returns a context limiting queries sent with it and its children to n in flight,
+// e.g. queries of an api request
+func WithConcurrencyLimit(ctx context.Context, n int) context.Context {
+	if n <= 0 {
+		return ctx
+	}
+	return context.WithValue(contextOrBackground(ctx), limiterKey{}, make(chan struct{}, n))
+}
+
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+func contextError(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return &Error{Type: ErrorTypeTimeout, Message: "query timed out"}
+	case context.Canceled:
+		return &Error{Type: ErrorTypeCanceled, Message: "query canceled"}
+	default:
+		return &Error{Type: ErrorTypeUnavailable, Message: err.Error()}
+	}
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', -1, 64)
+}
+
+func parseTime(s string) (time.Time, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, &Error{Type: ErrorTypeBadData, Message: fmt.Sprintf("invalid time %s", s)}
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*float64(time.Second))), nil
+}
+
+// parseDuration parses durations in seconds or in the format of Prometheus, e.g. 10m
+func parseDuration(s string) (time.Duration, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	d, err := model.ParseDuration(s)
+	if err != nil {
+		return 0, &Error{Type: ErrorTypeBadData, Message: err.Error()}
+	}
+	return time.Duration(d), nil
+}
+
+const maxCachedResults = 1000
+
+// resultCache keeps data of responses by their urls for the ttl
+type resultCache struct {
+	sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+func newResultCache(ttl time.Duration, maxEntries int) *resultCache {
+	return &resultCache{ttl: ttl, maxEntries: maxEntries, entries: make(map[string]cacheEntry)}
+}
+
+func (c *resultCache) get(key string) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func (c *resultCache) set(key string, data []byte) {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+
+	if len(c.entries) >= c.maxEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// results are dropped rather than evicting fresh ones
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+
+	c.entries[key] = cacheEntry{data: data, expires: now.Add(c.ttl)}
+}
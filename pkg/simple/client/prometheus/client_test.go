@@ -0,0 +1,217 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	vectorResponse = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up","job":"web"},"value":[1546300800.5,"1"]}]}}`
+	matrixResponse = `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"job":"web"},"values":[[1546300800,"1"],[1546300860,"0"]]}]}}`
+	scalarResponse = `{"status":"success","data":{"resultType":"scalar","result":[1546300800,"2"]}}`
+	errorResponse  = `{"status":"error","errorType":"bad_data","error":"parse error at char 3"}`
+)
+
+func newStub(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*httptest.Server, *Client) {
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	return server, NewClient(server.URL+"/api/v1/", 10, 0)
+}
+
+func TestQuery(t *testing.T) {
+	server, client := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query", r.URL.Path)
+		assert.Equal(t, "up", r.URL.Query().Get("query"))
+		assert.Equal(t, "1546300800", r.URL.Query().Get("time"))
+		w.Write([]byte(vectorResponse))
+	})
+	defer server.Close()
+
+	result, err := client.Query(context.Background(), "up", time.Unix(1546300800, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, ResultTypeVector, result.ResultType)
+	assert.Len(t, result.Samples, 1)
+	assert.Equal(t, "web", result.Samples[0].Metric["job"])
+	assert.Equal(t, &Point{Timestamp: 1546300800.5, Value: "1"}, result.Samples[0].Value)
+}
+
+func TestQueryRange(t *testing.T) {
+	server, client := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query_range", r.URL.Path)
+		assert.Equal(t, "1546300800", r.URL.Query().Get("start"))
+		assert.Equal(t, "1546300860", r.URL.Query().Get("end"))
+		assert.Equal(t, "60", r.URL.Query().Get("step"))
+		w.Write([]byte(matrixResponse))
+	})
+	defer server.Close()
+
+	result, err := client.QueryRange(context.Background(), "up", Range{Start: time.Unix(1546300800, 0), End: time.Unix(1546300860, 0), Step: time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, ResultTypeMatrix, result.ResultType)
+	assert.Equal(t, []Point{{Timestamp: 1546300800, Value: "1"}, {Timestamp: 1546300860, Value: "0"}}, result.Samples[0].Values)
+
+	_, err = client.QueryRange(context.Background(), "up", Range{})
+	assert.Equal(t, ErrorTypeBadData, err.(*Error).Type)
+}
+
+func TestQueryParams(t *testing.T) {
+	server, client := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/query_range":
+			assert.Equal(t, "600", r.URL.Query().Get("step"))
+			w.Write([]byte(matrixResponse))
+		default:
+			w.Write([]byte(scalarResponse))
+		}
+	})
+	defer server.Close()
+
+	params := url.Values{"query": {"up"}, "start": {"1546300800"}, "end": {"1546304400"}, "step": {"10m"}, "timeout": {"10s"}}
+	result, err := client.QueryParams(context.Background(), RangeQueryType, params)
+	assert.NoError(t, err)
+	assert.Equal(t, ResultTypeMatrix, result.ResultType)
+
+	result, err = client.QueryParams(context.Background(), DefaultQueryType, url.Values{"query": {"scalar(up)"}})
+	assert.NoError(t, err)
+	assert.Equal(t, &Point{Timestamp: 1546300800, Value: "2"}, result.Scalar)
+
+	params.Set("step", "10x")
+	_, err = client.QueryParams(context.Background(), RangeQueryType, params)
+	assert.Equal(t, ErrorTypeBadData, err.(*Error).Type)
+}
+
+func TestSeriesAndLabelValues(t *testing.T) {
+	server, client := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/series":
+			assert.Equal(t, []string{"up", `kube_pod_info{namespace="demo"}`}, r.URL.Query()["match[]"])
+			w.Write([]byte(`{"status":"success","data":[{"__name__":"up","job":"web"}]}`))
+		case "/api/v1/label/namespace/values":
+			w.Write([]byte(`{"status":"success","data":["default","demo"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	series, err := client.Series(context.Background(), []string{"up", `kube_pod_info{namespace="demo"}`}, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"__name__": "up", "job": "web"}}, series)
+
+	values, err := client.LabelValues(context.Background(), "namespace")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "demo"}, values)
+}
+
+func TestErrors(t *testing.T) {
+	server, client := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(vectorResponse))
+		case "down":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("bad gateway"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(errorResponse))
+		}
+	})
+	defer server.Close()
+
+	_, err := client.Query(context.Background(), "up{", time.Time{})
+	assert.Equal(t, &Error{Type: ErrorTypeBadData, Message: "parse error at char 3"}, err)
+	assert.Equal(t, http.StatusBadRequest, HTTPStatus(err.(*Error).Type))
+
+	_, err = client.Query(context.Background(), "down", time.Time{})
+	assert.Equal(t, ErrorTypeUnavailable, err.(*Error).Type)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.Query(ctx, "slow", time.Time{})
+	assert.Equal(t, ErrorTypeTimeout, err.(*Error).Type)
+	assert.Equal(t, http.StatusGatewayTimeout, HTTPStatus(err.(*Error).Type))
+
+	_, err = client.QueryParams(context.Background(), DefaultQueryType, url.Values{"query": {"slow"}, "timeout": {"50ms"}})
+	assert.Equal(t, ErrorTypeTimeout, err.(*Error).Type)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server, client := newStub(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(vectorResponse))
+	})
+	defer server.Close()
+
+	ctx := WithConcurrencyLimit(context.Background(), 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Query(ctx, "up", time.Time{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), maxInFlight)
+}
+
+func TestCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(matrixResponse))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", 10, time.Minute)
+	r := Range{Start: time.Unix(1546300800, 0), End: time.Unix(1546300860, 0), Step: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		result, err := client.QueryRange(context.Background(), "up", r)
+		assert.NoError(t, err)
+		assert.Len(t, result.Samples, 1)
+	}
+	assert.Equal(t, int32(1), requests)
+
+	// the step is a part of the key
+	r.Step = 30 * time.Second
+	client.QueryRange(context.Background(), "up", r)
+	assert.Equal(t, int32(2), requests)
+}
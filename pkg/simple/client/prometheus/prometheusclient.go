@@ -18,13 +18,13 @@
 package prometheus
 
 import (
+	"context"
 	"flag"
-	"io/ioutil"
 	"kubesphere.io/kubesphere/pkg/informers"
-	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/emicklei/go-restful"
@@ -44,12 +44,44 @@ var (
 	SecondaryPrometheusEndpoint string // For monitoring components including etcd, apiserver, coredns, etc.
 )
 
+var (
+	queryTimeout            time.Duration
+	maxConcurrentQueries    int
+	maxRequestConcurrency   int
+	cacheTTL                time.Duration
+	primaryClient           *Client
+	secondaryClient         *Client
+	primaryOnce, secondOnce sync.Once
+)
+
 func init() {
 	flag.StringVar(&PrometheusEndpoint, "prometheus-endpoint", "http://prometheus-k8s.kubesphere-monitoring-system.svc:9090/api/v1/", "For physical and k8s resource monitoring, including node, namespace, pod, etc.")
 	flag.StringVar(&SecondaryPrometheusEndpoint, "secondary-prometheus-endpoint", "http://prometheus-k8s-system.kubesphere-monitoring-system.svc:9090/api/v1/", "For k8s component monitoring, including etcd, apiserver, coredns, etc.")
+	flag.DurationVar(&queryTimeout, "prometheus-query-timeout", 30*time.Second, "Timeout of queries to prometheus without a timeout of their own.")
+	flag.IntVar(&maxConcurrentQueries, "prometheus-max-concurrent-queries", 50, "Maximum number of queries in flight to each prometheus server.")
+	flag.IntVar(&maxRequestConcurrency, "prometheus-max-request-concurrency", 10, "Maximum number of queries in flight for a single monitoring request.")
+	flag.DurationVar(&cacheTTL, "prometheus-cache-ttl", 10*time.Second, "How long results of prometheus queries are cached, 0 disables caching.")
+}
+
+// Primary returns the client of the prometheus server monitoring node, namespace, pod ... level resources
+func Primary() *Client {
+	primaryOnce.Do(func() {
+		primaryClient = NewClient(PrometheusEndpoint, maxConcurrentQueries, cacheTTL)
+	})
+	return primaryClient
+}
+
+// Secondary returns the client of the prometheus server monitoring components
+func Secondary() *Client {
+	secondOnce.Do(func() {
+		secondaryClient = NewClient(SecondaryPrometheusEndpoint, maxConcurrentQueries, cacheTTL)
+	})
+	return secondaryClient
 }
 
 type MonitoringRequestParams struct {
+	// Context of the api request, queries of the request are canceled with it
+	Context         context.Context
 	Params          url.Values
 	QueryType       string
 	SortMetricName  string
@@ -70,26 +102,6 @@ type MonitoringRequestParams struct {
 	ComponentName   string
 }
 
-var client = &http.Client{}
-
-func SendMonitoringRequest(prometheusEndpoint string, queryType string, params string) string {
-	epurl := prometheusEndpoint + queryType + params
-	response, err := client.Get(epurl)
-	if err != nil {
-		glog.Error(err)
-	} else {
-		defer response.Body.Close()
-
-		contents, err := ioutil.ReadAll(response.Body)
-
-		if err != nil {
-			glog.Error(err)
-		}
-		return string(contents)
-	}
-	return ""
-}
-
 func ParseMonitoringRequestParams(request *restful.Request) *MonitoringRequestParams {
 	instantTime := strings.Trim(request.QueryParameter("time"), " ")
 	start := strings.Trim(request.QueryParameter("start"), " ")
@@ -118,6 +130,7 @@ func ParseMonitoringRequestParams(request *restful.Request) *MonitoringRequestPa
 	componentName := strings.Trim(request.PathParameter("component"), " ")
 
 	var requestParams = MonitoringRequestParams{
+		Context:         WithConcurrencyLimit(request.Request.Context(), maxRequestConcurrency),
 		SortMetricName:  sortMetricName,
 		SortType:        sortType,
 		PageNum:         pageNum,
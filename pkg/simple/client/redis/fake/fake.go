@@ -0,0 +1,299 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package fake
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Client is an in-memory redis.Cmdable for tests, it implements the string, key and sorted set
+// commands used by KubeSphere, other commands panic.
+type Client struct {
+	redis.Cmdable
+	mutex   sync.Mutex
+	strings map[string]string
+	zsets   map[string]map[string]float64
+	expires map[string]time.Time
+}
+
+var _ redis.Cmdable = &Client{}
+
+func NewClient() *Client {
+	return &Client{
+		strings: make(map[string]string),
+		zsets:   make(map[string]map[string]float64),
+		expires: make(map[string]time.Time),
+	}
+}
+
+// expire deletes the key if it has expired, the caller must hold the mutex
+func (c *Client) expire(key string) {
+	if t, ok := c.expires[key]; ok && !time.Now().Before(t) {
+		c.del(key)
+	}
+}
+
+func (c *Client) del(key string) bool {
+	_, isString := c.strings[key]
+	_, isZSet := c.zsets[key]
+	delete(c.strings, key)
+	delete(c.zsets, key)
+	delete(c.expires, key)
+	return isString || isZSet
+}
+
+func (c *Client) exists(key string) bool {
+	c.expire(key)
+	_, isString := c.strings[key]
+	_, isZSet := c.zsets[key]
+	return isString || isZSet
+}
+
+// TTL returns the remaining time to live of the key, or -1 if the key has no expiration
+func (c *Client) TTL(key string) *redis.DurationCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.exists(key) {
+		return redis.NewDurationResult(-2, nil)
+	}
+	if t, ok := c.expires[key]; ok {
+		return redis.NewDurationResult(time.Until(t), nil)
+	}
+	return redis.NewDurationResult(-1, nil)
+}
+
+func (c *Client) Get(key string) *redis.StringCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.expire(key)
+	value, ok := c.strings[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (c *Client) Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.del(key)
+	c.strings[key] = toString(value)
+	if expiration > 0 {
+		c.expires[key] = time.Now().Add(expiration)
+	}
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (c *Client) Del(keys ...string) *redis.IntCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var n int64
+	for _, key := range keys {
+		c.expire(key)
+		if c.del(key) {
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (c *Client) Exists(keys ...string) *redis.IntCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var n int64
+	for _, key := range keys {
+		if c.exists(key) {
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (c *Client) Keys(pattern string) *redis.StringSliceCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	keys := make([]string, 0)
+	for _, m := range []map[string]string{c.strings, zsetKeys(c.zsets)} {
+		for key := range m {
+			if matched, _ := filepath.Match(pattern, key); matched && c.exists(key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return redis.NewStringSliceResult(keys, nil)
+}
+
+func (c *Client) ExpireAt(key string, tm time.Time) *redis.BoolCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.exists(key) {
+		return redis.NewBoolResult(false, nil)
+	}
+	c.expires[key] = tm
+	c.expire(key)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (c *Client) ZAdd(key string, members ...redis.Z) *redis.IntCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.expire(key)
+	zset, ok := c.zsets[key]
+	if !ok {
+		zset = make(map[string]float64)
+		c.zsets[key] = zset
+	}
+	var n int64
+	for _, member := range members {
+		m := toString(member.Member)
+		if _, ok := zset[m]; !ok {
+			n++
+		}
+		zset[m] = member.Score
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (c *Client) ZRangeByScoreWithScores(key string, opt redis.ZRangeBy) *redis.ZSliceCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	result := make([]redis.Z, 0)
+	for _, z := range c.zrange(key) {
+		if inRange(z.Score, opt.Min, opt.Max) {
+			result = append(result, z)
+		}
+	}
+	return redis.NewZSliceCmdResult(result, nil)
+}
+
+func (c *Client) ZRevRangeWithScores(key string, start, stop int64) *redis.ZSliceCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	members := c.zrange(key)
+	for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+		members[i], members[j] = members[j], members[i]
+	}
+	n := int64(len(members))
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return redis.NewZSliceCmdResult([]redis.Z{}, nil)
+	}
+	return redis.NewZSliceCmdResult(members[start:stop+1], nil)
+}
+
+func (c *Client) ZRemRangeByScore(key, min, max string) *redis.IntCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var n int64
+	for _, z := range c.zrange(key) {
+		if inRange(z.Score, min, max) {
+			delete(c.zsets[key], z.Member.(string))
+			n++
+		}
+	}
+	if zset, ok := c.zsets[key]; ok && len(zset) == 0 {
+		c.del(key)
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+// zrange returns the members of the sorted set ordered by score, the caller must hold the mutex
+func (c *Client) zrange(key string) []redis.Z {
+	c.expire(key)
+	members := make([]redis.Z, 0)
+	for member, score := range c.zsets[key] {
+		members = append(members, redis.Z{Score: score, Member: member})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score == members[j].Score {
+			return members[i].Member.(string) < members[j].Member.(string)
+		}
+		return members[i].Score < members[j].Score
+	})
+	return members
+}
+
+func zsetKeys(zsets map[string]map[string]float64) map[string]string {
+	keys := make(map[string]string, len(zsets))
+	for key := range zsets {
+		keys[key] = ""
+	}
+	return keys
+}
+
+// inRange reports whether the score is in the range of min and max, which are
+// formatted as the arguments of ZRANGEBYSCORE
+func inRange(score float64, min, max string) bool {
+	return compare(score, min, true) && compare(score, max, false)
+}
+
+func compare(score float64, bound string, lower bool) bool {
+	switch bound {
+	case "-inf":
+		return lower
+	case "+inf", "inf":
+		return !lower
+	}
+	exclusive := strings.HasPrefix(bound, "(")
+	value, err := strconv.ParseFloat(strings.TrimPrefix(bound, "("), 64)
+	if err != nil {
+		panic(err)
+	}
+	if lower {
+		return score > value || (!exclusive && score == value)
+	}
+	return score < value || (!exclusive && score == value)
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		panic("unsupported value")
+	}
+}
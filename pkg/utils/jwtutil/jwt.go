@@ -18,21 +18,106 @@
 package jwtutil
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
 	"fmt"
+	"sync"
+
 	"github.com/dgrijalva/jwt-go"
+	"gopkg.in/square/go-jose.v2"
+	"k8s.io/client-go/util/cert"
 )
 
 const secretEnv = "JWT_SECRET"
 
-var secret []byte
+var (
+	mutex  sync.RWMutex
+	secret []byte
+	// signingKey is used to sign new tokens if configured, otherwise tokens are signed with the HMAC secret
+	signingKey *jose.JSONWebKey
+	// verificationKeys contains the public part of the signing key and of retired signing keys
+	// which are still accepted during key rotation
+	verificationKeys []jose.JSONWebKey
+)
 
 func Setup(key string) {
+	mutex.Lock()
+	defer mutex.Unlock()
 	secret = []byte(key)
 }
 
+// SetupSigningKeys loads the PEM encoded private key used to sign tokens and the PEM encoded
+// public keys that are still accepted for verification, keys are identified by their RFC 7638 thumbprint.
+func SetupSigningKeys(signingKeyFile string, verificationKeyFiles []string) error {
+	privateKey, err := cert.PrivateKeyFromFile(signingKeyFile)
+
+	if err != nil {
+		return err
+	}
+
+	key, err := newJSONWebKey(privateKey)
+
+	if err != nil {
+		return err
+	}
+
+	keys := []jose.JSONWebKey{key.Public()}
+
+	for _, file := range verificationKeyFiles {
+		publicKeys, err := cert.PublicKeysFromFile(file)
+
+		if err != nil {
+			return err
+		}
+
+		for _, publicKey := range publicKeys {
+			k, err := newJSONWebKey(publicKey)
+
+			if err != nil {
+				return err
+			}
+
+			if k.KeyID != key.KeyID {
+				keys = append(keys, *k)
+			}
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	signingKey = key
+	verificationKeys = keys
+
+	return nil
+}
+
+// JSONWebKeySet returns the public keys used to verify tokens
+func JSONWebKeySet() jose.JSONWebKeySet {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	keys := make([]jose.JSONWebKey, len(verificationKeys))
+	copy(keys, verificationKeys)
+	return jose.JSONWebKeySet{Keys: keys}
+}
+
 func MustSigned(claims jwt.MapClaims) string {
-	uToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token, err := uToken.SignedString(secret)
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if signingKey == nil {
+		uToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token, err := uToken.SignedString(secret)
+		if err != nil {
+			panic(err)
+		}
+		return token
+	}
+
+	uToken := jwt.NewWithClaims(jwt.GetSigningMethod(signingKey.Algorithm), claims)
+	uToken.Header["kid"] = signingKey.KeyID
+	token, err := uToken.SignedString(signingKey.Key)
 	if err != nil {
 		panic(err)
 	}
@@ -40,10 +125,19 @@ func MustSigned(claims jwt.MapClaims) string {
 }
 
 func provideKey(token *jwt.Token) (interface{}, error) {
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("token signed with HMAC is not accepted")
+		}
 		return secret, nil
-	} else {
-		return nil, fmt.Errorf("expect token signed with HMAC but got %v", token.Header["alg"])
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		return lookupKey(token, verificationKeys)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
 	}
 }
 
@@ -61,3 +155,65 @@ func ValidateToken(uToken string) (*jwt.Token, error) {
 
 	return token, nil
 }
+
+func lookupKey(token *jwt.Token, keys []jose.JSONWebKey) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	alg, _ := token.Header["alg"].(string)
+
+	for _, key := range keys {
+		if key.KeyID == kid {
+			if key.Algorithm != "" && key.Algorithm != alg {
+				return nil, fmt.Errorf("key %s can not be used with %s", kid, alg)
+			}
+			return key.Key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("verification key %s not found", kid)
+}
+
+func newJSONWebKey(key interface{}) (*jose.JSONWebKey, error) {
+	var algorithm string
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		algorithm = jwt.SigningMethodRS256.Alg()
+	case *ecdsa.PrivateKey:
+		algorithm = ecdsaAlgorithm(k.Params().BitSize)
+	case *ecdsa.PublicKey:
+		algorithm = ecdsaAlgorithm(k.Params().BitSize)
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, only RSA and ECDSA keys are supported", key)
+	}
+
+	if algorithm == "" {
+		return nil, fmt.Errorf("unsupported elliptic curve")
+	}
+
+	jwk := &jose.JSONWebKey{Key: key, Algorithm: algorithm, Use: "sig"}
+
+	public := jwk.Public()
+
+	thumbprint, err := public.Thumbprint(crypto.SHA256)
+
+	if err != nil {
+		return nil, err
+	}
+
+	jwk.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	return jwk, nil
+}
+
+func ecdsaAlgorithm(bitSize int) string {
+	switch bitSize {
+	case 256:
+		return jwt.SigningMethodES256.Alg()
+	case 384:
+		return jwt.SigningMethodES384.Alg()
+	case 521:
+		return jwt.SigningMethodES512.Alg()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,145 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package jwtutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func writeKeys(t *testing.T, dir, name string, key interface{}) (privateKeyFile, publicKeyFile string) {
+	var block *pem.Block
+	var public interface{}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+		public = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		data, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: data}
+		public = &k.PublicKey
+	}
+
+	publicData, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateKeyFile = filepath.Join(dir, name+".key")
+	publicKeyFile = filepath.Join(dir, name+".pub")
+
+	if err := ioutil.WriteFile(privateKeyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicData}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return privateKeyFile, publicKeyFile
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwtutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKey, oldPublicKey := writeKeys(t, dir, "old", rsaKey)
+	newKey, _ := writeKeys(t, dir, "new", ecKey)
+
+	defer Setup("")
+	Setup("")
+
+	if err := SetupSigningKeys(oldKey, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	oldToken := MustSigned(jwt.MapClaims{"username": "admin"})
+
+	if err := SetupSigningKeys(newKey, []string{oldPublicKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	newToken := MustSigned(jwt.MapClaims{"username": "admin"})
+
+	token, err := ValidateToken(newToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Method.Alg() != "ES256" {
+		t.Errorf("expected token signed with ES256, got %s", token.Method.Alg())
+	}
+
+	token, err = ValidateToken(oldToken)
+	if err != nil {
+		t.Fatalf("token signed by retired key should be accepted: %s", err)
+	}
+	if token.Method.Alg() != "RS256" {
+		t.Errorf("expected token signed with RS256, got %s", token.Method.Alg())
+	}
+
+	keySet := JSONWebKeySet()
+	if len(keySet.Keys) != 2 {
+		t.Fatalf("expected 2 keys in key set, got %d", len(keySet.Keys))
+	}
+	for _, key := range keySet.Keys {
+		if !key.IsPublic() {
+			t.Errorf("key %s in key set must be public", key.KeyID)
+		}
+	}
+
+	if err := SetupSigningKeys(newKey, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ValidateToken(oldToken); err == nil {
+		t.Error("token signed by removed key should be rejected")
+	}
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "admin"}).SignedString([]byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(hmacToken); err == nil {
+		t.Error("token signed with HMAC should be rejected without secret")
+	}
+}
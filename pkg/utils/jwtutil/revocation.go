@@ -0,0 +1,149 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package jwtutil
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-redis/redis"
+)
+
+const (
+	TokenTypeClaim   = "token_type"
+	RefreshTokenType = "refresh"
+
+	revokedTokenKey = "kubesphere:tokens:revoked:%s"
+	// ids of the user's tokens scored by their expiration time
+	userTokensKey = "kubesphere:users:%s:tokens"
+)
+
+// Revoke adds the token id (jti) to the revocation list until the token expires
+func Revoke(client redis.Cmdable, claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+
+	if jti == "" {
+		return fmt.Errorf("token id not found")
+	}
+
+	ttl := time.Until(time.Unix(int64Claim(claims, "exp"), 0))
+
+	if ttl <= 0 {
+		return nil
+	}
+
+	return client.Set(fmt.Sprintf(revokedTokenKey, jti), "", ttl).Err()
+}
+
+// Track records the token id (jti) of the user's token until the token expires,
+// tracked tokens are revoked by RevokeUserTokens
+func Track(client redis.Cmdable, claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	username, _ := claims["username"].(string)
+
+	if jti == "" || username == "" {
+		return fmt.Errorf("token id not found")
+	}
+
+	key := fmt.Sprintf(userTokensKey, username)
+	exp := int64Claim(claims, "exp")
+
+	// ids of expired tokens are dropped
+	if err := client.ZRemRangeByScore(key, "-inf", strconv.FormatInt(time.Now().Unix(), 10)).Err(); err != nil {
+		return err
+	}
+
+	if err := client.ZAdd(key, redis.Z{Score: float64(exp), Member: jti}).Err(); err != nil {
+		return err
+	}
+
+	// the set expires with the last token
+	last, err := client.ZRevRangeWithScores(key, 0, 0).Result()
+
+	if err != nil {
+		return err
+	}
+
+	if len(last) > 0 && int64(last[0].Score) > exp {
+		exp = int64(last[0].Score)
+	}
+
+	return client.ExpireAt(key, time.Unix(exp, 0)).Err()
+}
+
+// RevokeUserTokens revokes all of the user's tracked tokens which have not expired
+func RevokeUserTokens(client redis.Cmdable, username string) error {
+	key := fmt.Sprintf(userTokensKey, username)
+
+	tokens, err := client.ZRangeByScoreWithScores(key, redis.ZRangeBy{Min: "(" + strconv.FormatInt(time.Now().Unix(), 10), Max: "+inf"}).Result()
+
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		jti, _ := token.Member.(string)
+		ttl := time.Until(time.Unix(int64(token.Score), 0))
+
+		if jti == "" || ttl <= 0 {
+			continue
+		}
+
+		if err := client.Set(fmt.Sprintf(revokedTokenKey, jti), "", ttl).Err(); err != nil {
+			return err
+		}
+	}
+
+	return client.Del(key).Err()
+}
+
+// IsRevoked returns true if the token is revoked, tokens without ids can't be revoked
+func IsRevoked(client redis.Cmdable, claims jwt.MapClaims) (bool, error) {
+	jti, _ := claims["jti"].(string)
+
+	if jti == "" {
+		return false, nil
+	}
+
+	n, err := client.Exists(fmt.Sprintf(revokedTokenKey, jti)).Result()
+
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func IsRefreshToken(claims jwt.MapClaims) bool {
+	return claims[TokenTypeClaim] == RefreshTokenType
+}
+
+func int64Claim(claims jwt.MapClaims, name string) int64 {
+	switch value := claims[name].(type) {
+	case float64:
+		return int64(value)
+	case int64:
+		return value
+	case int:
+		return int64(value)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,121 @@
+/*
+
+ Copyright 2019 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+package jwtutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/redis/fake"
+)
+
+func newClaims(jti, username string, lifetime time.Duration) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{"jti": jti, "username": username, "iat": now.Unix(), "exp": now.Add(lifetime).Unix()}
+}
+
+func TestRevoke(t *testing.T) {
+	client := fake.NewClient()
+	claims := newClaims("a", "admin", time.Hour)
+	other := newClaims("b", "admin", time.Hour)
+
+	if err := Revoke(client, claims); err != nil {
+		t.Fatal(err)
+	}
+
+	if revoked, err := IsRevoked(client, claims); err != nil || !revoked {
+		t.Errorf("expected token to be revoked, got %v, %v", revoked, err)
+	}
+
+	if revoked, err := IsRevoked(client, other); err != nil || revoked {
+		t.Errorf("expected other token not to be revoked, got %v, %v", revoked, err)
+	}
+
+	if ttl := client.TTL("kubesphere:tokens:revoked:a").Val(); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("expected revocation to expire with the token, got ttl %v", ttl)
+	}
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	client := fake.NewClient()
+
+	// tokens issued in the same second as the revocation must be revoked too
+	access := newClaims("access", "admin", time.Hour)
+	refresh := newClaims("refresh", "admin", 24*time.Hour)
+	expired := newClaims("expired", "admin", -time.Hour)
+	another := newClaims("another", "tester", time.Hour)
+
+	for _, claims := range []jwt.MapClaims{access, refresh, expired, another} {
+		if err := Track(client, claims); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ttl := client.TTL("kubesphere:users:admin:tokens").Val(); ttl <= time.Hour || ttl > 24*time.Hour {
+		t.Errorf("expected tracked tokens to expire with the last token, got ttl %v", ttl)
+	}
+
+	if err := RevokeUserTokens(client, "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		claims  jwt.MapClaims
+		revoked bool
+	}{
+		{access, true},
+		{refresh, true},
+		{expired, false},
+		{another, false},
+	}
+
+	for _, test := range tests {
+		revoked, err := IsRevoked(client, test.claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != test.revoked {
+			t.Errorf("token %s: expected revoked %v, got %v", test.claims["jti"], test.revoked, revoked)
+		}
+	}
+
+	// tokens issued after the revocation are not revoked
+	issued := newClaims("issued", "admin", time.Hour)
+
+	if err := Track(client, issued); err != nil {
+		t.Fatal(err)
+	}
+
+	if revoked, err := IsRevoked(client, issued); err != nil || revoked {
+		t.Errorf("expected token issued after revocation not to be revoked, got %v, %v", revoked, err)
+	}
+}
+
+func TestTrackWithoutTokenID(t *testing.T) {
+	client := fake.NewClient()
+
+	if err := Track(client, jwt.MapClaims{"username": "admin"}); err == nil {
+		t.Error("expected error tracking token without id")
+	}
+
+	if revoked, err := IsRevoked(client, jwt.MapClaims{"username": "admin"}); err != nil || revoked {
+		t.Errorf("expected token without id not to be revoked, got %v, %v", revoked, err)
+	}
+}